@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// recordAudit adds an event to the audit log. The action goes ahead even
+// when the log cannot be written, which is only logged.
+func recordAudit(event config.AuditEvent) {
+	if err := config.RecordAudit(event); err != nil {
+		slog.Warn("failed to write the audit log", "action", event.Action, "host", event.Host, "error", err)
+	}
+}
+
+// auditCommand implements "xssh audit"
+func auditCommand() *Command {
+	cmd := newCommand("audit", "", "Show the audit log")
+	cmd.Description = `List who connected to which host, ran remote commands, installed keys, added,
+changed or deleted hosts and opened or closed tunnels, newest first. The log
+is only ever appended to, in audit.jsonl in the config directory, and can
+also be sent to syslog with "syslog" in the [audit] section of config.toml.`
+	cmd.Examples = []string{
+		"xssh audit",
+		"xssh audit --host web1 --since 7d",
+		"xssh audit --action host-deleted --user alice",
+		"xssh audit --json -n 0",
+	}
+	host := cmd.Flags.String("host", "", "only events about this host")
+	action := cmd.Flags.String("action", "", "only events of this action: "+strings.Join(config.AuditActions, ", "))
+	user := cmd.Flags.String("user", "", "only events of this local user")
+	var since time.Duration
+	cmd.Flags.Func("since", "only events within this time, such as 12h or 7d", func(value string) error {
+		var err error
+		since, err = config.ParseAge(value)
+		return err
+	})
+	limit := cmd.Flags.Int("n", 50, "number of events to show, 0 for all")
+	asJSON := cmd.Flags.Bool("json", false, "print the events as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("audit takes no arguments")
+		}
+		if *action != "" && !slices.Contains(config.AuditActions, *action) {
+			return cmd.usagef("unknown action %q, expected one of %s", *action, strings.Join(config.AuditActions, ", "))
+		}
+		events, err := config.LoadAudit()
+		if err != nil {
+			return errorf(exitConfig, "failed to load the audit log: %v", err)
+		}
+
+		var selected []config.AuditEvent
+		for _, event := range events {
+			if *host != "" && event.Host != *host {
+				continue
+			}
+			if *action != "" && event.Action != *action {
+				continue
+			}
+			if *user != "" && event.User != *user && !strings.HasPrefix(event.User, *user+" ") {
+				continue
+			}
+			if since > 0 && time.Since(event.Time) > since {
+				continue
+			}
+			selected = append(selected, event)
+		}
+		if *limit > 0 && len(selected) > *limit {
+			selected = selected[len(selected)-*limit:]
+		}
+		slices.Reverse(selected)
+
+		if *asJSON {
+			if selected == nil {
+				selected = []config.AuditEvent{}
+			}
+			return printJSON(selected)
+		}
+		if len(selected) == 0 {
+			fmt.Println("No audit events recorded.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tUSER\tACTION\tHOST\tDETAIL")
+		for _, event := range selected {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				event.Time.Local().Format("2006-01-02 15:04:05"),
+				event.User,
+				event.Action,
+				event.Host,
+				event.Detail,
+			)
+		}
+		return w.Flush()
+	}
+	return cmd
+}
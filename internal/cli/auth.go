@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"xssh/internal/config"
+	"xssh/internal/secrets"
+	"xssh/internal/ssh"
+)
+
+// hostAuth returns the secrets for logging in to host that are known before
+// connecting. With passwordStdin the first line of stdin is the passphrase
+// of the host's key when it is encrypted, and the login password otherwise.
+// Without it the secrets referenced in the host's metadata are resolved, and
+// the passphrase of an encrypted key is asked on the terminal when none is
+// referenced.
+func hostAuth(host config.SSHHost, passwordStdin bool) (ssh.Auth, error) {
+	var auth ssh.Auth
+	encrypted := host.Identity != "" && ssh.KeyNeedsPassphrase(host.Identity)
+	if passwordStdin {
+		secret, err := readPasswordStdin()
+		if err != nil {
+			return auth, err
+		}
+		if encrypted {
+			auth.KeyPassword = secret
+		} else {
+			auth.Password = secret
+		}
+		return auth, nil
+	}
+	auth, err := referencedAuth(host)
+	if err != nil {
+		return auth, errorf(exitAuth, "%v", err)
+	}
+	if encrypted && auth.KeyPassword == "" {
+		keyPassword, err := promptPassword(fmt.Sprintf("Passphrase for %s: ", host.Identity))
+		if err != nil {
+			return auth, err
+		}
+		auth.KeyPassword = keyPassword
+	}
+	return auth, nil
+}
+
+// withPasswordPrompt runs login with auth and, when the host refuses the
+// login and nothing was read from stdin, asks for the password on the
+// terminal and runs login again with it
+func withPasswordPrompt(host config.SSHHost, auth *ssh.Auth, passwordStdin bool, login func(ssh.Auth) error) error {
+	err := login(*auth)
+	if err == nil || passwordStdin || auth.Password != "" || !term.IsTerminal(os.Stdin.Fd()) ||
+		ssh.ClassifyError(err) != ssh.ProbeAuth {
+		return err
+	}
+	password, promptErr := promptPassword(fmt.Sprintf("%s@%s's password: ", host.User, host.Host))
+	if promptErr != nil {
+		return promptErr
+	}
+	auth.Password = password
+	return login(*auth)
+}
+
+// printAskpassPassword answers ssh when xssh runs as the SSH_ASKPASS program
+// set up by "xssh connect --password-stdin" or for secret references, and
+// reports whether it did. ssh passes the prompt as the argument; prompts
+// for a key passphrase get the passphrase when one is set.
+func printAskpassPassword() bool {
+	password, found := os.LookupEnv(ssh.AskpassPasswordEnv)
+	if !found || os.Getenv("SSH_ASKPASS_REQUIRE") != "force" {
+		return false
+	}
+	passphrase := os.Getenv(ssh.AskpassPassphraseEnv)
+	if passphrase != "" && len(os.Args) > 1 && strings.Contains(strings.ToLower(os.Args[1]), "passphrase") {
+		password = passphrase
+	}
+	fmt.Println(password)
+	return true
+}
+
+// referencedAuth resolves the password and passphrase references kept in
+// the metadata of host
+func referencedAuth(host config.SSHHost) (ssh.Auth, error) {
+	metadata, _ := config.LoadMetadata()
+	return secrets.HostAuth(metadata, host.Name)
+}
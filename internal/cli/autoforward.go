@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+)
+
+// parseAutoForwards parses the rules of "xssh set --auto-forward", separated
+// by commas: empty for none
+func parseAutoForwards(value string) ([]string, error) {
+	var rules []string
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if _, err := parseForwardingRule(rule); err != nil {
+			return nil, fmt.Errorf("invalid forwarding rule %q: %v", rule, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// setAutoForwards saves the forwarding rules opened while connected to a
+// host
+func setAutoForwards(name string, rules []string) error {
+	metadata, err := config.LoadMetadata()
+	if err != nil {
+		return errorf(exitConfig, "failed to load metadata: %v", err)
+	}
+	old := metadata.AutoForwards(name)
+	metadata.SetAutoForwards(name, rules)
+	if err := metadata.Save(); err != nil {
+		return errorf(exitConfig, "failed to save metadata: %v", err)
+	}
+	infof("Updated forwardings of '%s'\n", name)
+	infof("  Auto-forward: %s -> %s\n", displayValue(strings.Join(old, ", ")), displayValue(strings.Join(rules, ", ")))
+	return nil
+}
+
+// autoForwardRule returns the forwarding a rule of the host's metadata
+// opens. Its ID names the host, so the sessions of several hosts can be
+// told apart.
+func autoForwardRule(hostName, value string) (*forwarding.ForwardingRule, error) {
+	rule, err := parseForwardingRule(value)
+	if err != nil {
+		return nil, err
+	}
+	port := rule.LocalPort
+	if rule.Type.ListensRemotely() {
+		port = rule.RemotePort
+	}
+	rule.ID = fmt.Sprintf("%s-%s-%d", hostName, strings.ToLower(rule.Type.String()), port)
+	rule.PortRetries = forwarding.DefaultPortRetries
+	return rule, nil
+}
+
+// startAutoForwards opens the forwardings declared for the host with "xssh
+// set --auto-forward" and returns the manager holding them, to be shut down
+// when the session ends, or nil when there are none. They authenticate with
+// keys, the agent or the referenced secrets, and one that fails to open is
+// reported without holding up the session.
+func startAutoForwards(host config.SSHHost) *forwarding.ForwardingManager {
+	metadata, _ := config.LoadMetadata()
+	rules := metadata.AutoForwards(host.Name)
+	if len(rules) == 0 {
+		return nil
+	}
+	auth, err := referencedAuth(host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not opening the forwardings of %s: %v\n", host.Name, err)
+		return nil
+	}
+
+	manager := forwarding.NewManager()
+	for _, value := range rules {
+		rule, err := autoForwardRule(host.Name, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: invalid forwarding rule %q of %s: %v\n", value, host.Name, err)
+			continue
+		}
+		session, err := manager.StartForwarding(context.Background(), *rule, host, auth)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to start port forwarding %s: %v\n", rule.Description, err)
+			continue
+		}
+		infof("Forwarding %s while connected\n", session.Rule.Description)
+		if note := session.Rule.PortSubstitution(); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", rule.Description, note)
+		}
+	}
+	return manager
+}
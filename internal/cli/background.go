@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+)
+
+// startBackgroundForwarding starts the rules under the daemon, which keeps
+// them open among its tunnels, and returns once they are open, printing
+// their session IDs. Without a daemon each rule runs in a detached xssh
+// process instead.
+func startBackgroundForwarding(rules []forwarding.ForwardingRule, hostAlias string, passwordStdin bool) error {
+	host, err := resolveHost(hostAlias)
+	if err != nil {
+		return err
+	}
+	auth, err := hostAuth(host, passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		var tunnel sessionJSON
+		err := withPasswordPrompt(host, &auth, passwordStdin, func(auth ssh.Auth) error {
+			return daemonRequest(http.MethodPost, "/v1/tunnels", newTunnelRequest(rule, hostAlias, auth), &tunnel)
+		})
+		if i == 0 && exitCode(err) == exitDaemon {
+			slog.Debug("no daemon, forwarding in background processes", "error", err)
+			return startForwardWorkers(rules, host, hostAlias, auth, passwordStdin)
+		}
+		if err != nil {
+			return err
+		}
+		infof("Started %s in the xssh daemon\n", rule.Description)
+		rule.LocalPort, rule.RemotePort, rule.RequestedPort = tunnel.LocalPort, tunnel.RemotePort, tunnel.RequestedPort
+		if note := rule.PortSubstitution(); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", rule.Description, note)
+		}
+		fmt.Println(tunnel.ID)
+	}
+	infof("Use 'xssh forward list' to see them and 'xssh forward stop <id>' to stop them.\n")
+	return nil
+}
+
+// newTunnelRequest returns the request starting rule through host under the
+// daemon. Certificate files are made absolute, as the daemon runs elsewhere.
+func newTunnelRequest(rule forwarding.ForwardingRule, hostAlias string, auth ssh.Auth) tunnelRequest {
+	forwardingType, spec := rule.Spec()
+	if rule.TLS != nil && rule.TLS.CertFile != "" {
+		listenerTLS := *rule.TLS
+		listenerTLS.CertFile, _ = filepath.Abs(listenerTLS.CertFile)
+		listenerTLS.KeyFile, _ = filepath.Abs(listenerTLS.KeyFile)
+		rule.TLS = &listenerTLS
+	}
+	return tunnelRequest{
+		Host:        hostAlias,
+		Type:        forwardingType,
+		Spec:        spec,
+		TLS:         rule.TLS.String(),
+		Resolve:     resolveJSON(rule),
+		Expire:      rule.Expiry.String(),
+		Via:         rule.Via,
+		Capture:     rule.Capture.String(),
+		PortRetries: &rule.PortRetries,
+		Labels:      rule.Labels,
+		Password:    auth.Password,
+		KeyPassword: auth.KeyPassword,
+	}
+}
+
+// startForwardWorkers starts each rule in a detached xssh process and
+// returns once the forwardings are open, printing their session IDs
+func startForwardWorkers(rules []forwarding.ForwardingRule, host config.SSHHost, hostAlias string, auth ssh.Auth, passwordStdin bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find the xssh binary: %v", err)
+	}
+
+	running, err := forwarding.BackgroundSessions()
+	if err != nil {
+		return err
+	}
+	taken := map[string]bool{}
+	for _, session := range running {
+		taken[session.Rule.ID] = true
+	}
+
+	for _, rule := range rules {
+		// Session IDs name the records of background sessions, so they
+		// must not clash with those of the running ones
+		base := rule.ID
+		for n := 2; taken[rule.ID]; n++ {
+			rule.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[rule.ID] = true
+		var id string
+		err := withPasswordPrompt(host, &auth, passwordStdin, func(auth ssh.Auth) (err error) {
+			id, err = startForwardWorker(executable, rule, hostAlias, auth)
+			return err
+		})
+		if err != nil {
+			return codedError{code: exitCode(err), err: fmt.Errorf("failed to start port forwarding %s: %w", rule.Description, err)}
+		}
+		infof("Started %s in the background\n", rule.Description)
+		if note := backgroundPortSubstitution(id); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", rule.Description, note)
+		}
+		fmt.Println(id)
+	}
+	infof("Use 'xssh forward list' to see them and 'xssh forward stop <id>' to stop them.\n")
+	return nil
+}
+
+// backgroundPortSubstitution describes the port the background session id
+// took because its own was in use, empty when it took its own
+func backgroundPortSubstitution(id string) string {
+	sessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		return ""
+	}
+	for _, session := range sessions {
+		if session.Rule.ID == id {
+			return session.Rule.PortSubstitution()
+		}
+	}
+	return ""
+}
+
+// startForwardWorker runs "xssh forward-worker" for one rule in a new session,
+// detached from the terminal, and waits until it reports on the pipe given
+// as its fd 3 that the forwarding is open. The worker reads auth from its
+// stdin, keeping the secrets off its command line.
+func startForwardWorker(executable string, rule forwarding.ForwardingRule, hostAlias string, auth ssh.Auth) (string, error) {
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+	authJSON, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+	logPath, err := forwarding.BackgroundLogPath(rule.ID)
+	if err != nil {
+		return "", err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer logFile.Close()
+
+	ready, readyWriter, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	defer ready.Close()
+
+	worker := exec.Command(executable, "forward-worker", hostAlias, string(ruleJSON))
+	worker.Stdin = bytes.NewReader(authJSON)
+	worker.Stdout, worker.Stderr = logFile, logFile
+	worker.ExtraFiles = []*os.File{readyWriter}
+	detachProcess(worker)
+	err = worker.Start()
+	readyWriter.Close()
+	if err != nil {
+		return "", err
+	}
+
+	// The pipe closes when the worker reports or exits
+	report, _ := io.ReadAll(ready)
+	status := strings.TrimSpace(string(report))
+	if status != "ok" {
+		worker.Wait()
+		os.Remove(logPath)
+		return "", workerError(status)
+	}
+	worker.Process.Release()
+	return rule.ID, nil
+}
+
+// workerError turns the "<exit code> <message>" a worker reports when it
+// fails into an error with that exit code
+func workerError(status string) error {
+	codeText, message, _ := strings.Cut(status, " ")
+	code, err := strconv.Atoi(codeText)
+	if err != nil || message == "" {
+		return fmt.Errorf("the background process exited without opening the forwarding")
+	}
+	return codedError{code: code, err: errors.New(message)}
+}
+
+// forwardWorkerCommand implements the hidden "xssh forward-worker", the
+// detached process behind "xssh forward --background"
+func forwardWorkerCommand() *Command {
+	cmd := newCommand("forward-worker", "<alias> <rule>", "Keep a background forwarding open")
+	cmd.Hidden = true
+	cmd.Run = func(args []string) error {
+		if len(args) != 2 {
+			return cmd.usagef("forward-worker takes a host alias and a rule")
+		}
+		// The parent waits on fd 3 for "ok", or the exit code and message
+		// of the error that kept the forwarding from starting
+		ready := os.NewFile(3, "ready")
+		report := func(err error) error {
+			if ready != nil {
+				if err != nil {
+					fmt.Fprintf(ready, "%d %v\n", exitCode(err), err)
+				} else {
+					fmt.Fprintln(ready, "ok")
+				}
+				ready.Close()
+				ready = nil
+			}
+			return err
+		}
+
+		var rule forwarding.ForwardingRule
+		if err := json.Unmarshal([]byte(args[1]), &rule); err != nil {
+			return report(fmt.Errorf("invalid rule: %v", err))
+		}
+		var auth ssh.Auth
+		if err := json.NewDecoder(os.Stdin).Decode(&auth); err != nil && err != io.EOF {
+			return report(fmt.Errorf("invalid credentials: %v", err))
+		}
+		host, err := resolveHost(args[0])
+		if err != nil {
+			return report(err)
+		}
+
+		signal.Ignore(syscall.SIGHUP)
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// "xssh forward renew" sends SIGUSR1
+		renew := make(chan os.Signal, 1)
+		notifyRenew(renew)
+		defer signal.Stop(renew)
+
+		manager := forwarding.NewManager()
+		started, err := manager.StartForwarding(ctx, rule, host, auth)
+		if err != nil {
+			return report(connectionError(err))
+		}
+		session := forwarding.BackgroundSession{Rule: started.Rule, Host: host.Name, PID: os.Getpid(), Started: time.Now(), Expires: started.ExpiresAt()}
+		if err := forwarding.RegisterBackground(session); err != nil {
+			shutdownForwarding(manager)
+			return report(fmt.Errorf("failed to record the session: %v", err))
+		}
+		report(nil)
+
+		// Run until stopped or expired, recording renewed expiries
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-ticker.C:
+				if len(manager.GetAllSessions()) == 0 {
+					break wait
+				}
+			case <-renew:
+				if expires, err := manager.RenewForwarding(rule.ID); err == nil {
+					session.Expires = expires
+					forwarding.RegisterBackground(session)
+				}
+			}
+		}
+		shutdownForwarding(manager)
+		return forwarding.UnregisterBackground(rule.ID)
+	}
+	return cmd
+}
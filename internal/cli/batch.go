@@ -0,0 +1,513 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+)
+
+// batchOp is one operation of a batch file
+type batchOp struct {
+	line int    // Line number in the file
+	text string // The line as written, for the report
+	name string // add, rm, tag, exec or forward
+
+	host   config.SSHHost              // add
+	alias  string                      // Host the operation applies to; a tag:name target for exec
+	tags   []string                    // tag: tags to add, prefixed with + or -
+	remote string                      // exec: command to run
+	rules  []forwarding.ForwardingRule // forward
+}
+
+// batchCommand implements "xssh batch <file>"
+func batchCommand() *Command {
+	cmd := newCommand("batch", "<file | ->", "Apply a list of operations from a file")
+	cmd.Description = `Read operations from a file, or stdin with "-", one per line, and apply them
+as one change. Blank lines and lines starting with # are ignored; words are
+split like a shell does, with quotes.
+
+  add <alias> [user@]host[:port] [--identity FILE] [--jump HOSTS]
+  rm <alias>
+  tag <alias> [+]tag... [-tag...]      Add tags, or remove those given as -tag
+  exec <alias | tag:NAME> <command>    Run a command on the host, or every host with the tag
+  forward <alias> -L|-R|-D <spec>...   Start forwardings, kept open until Ctrl+C
+
+The changes to hosts and tags are applied in order and saved together, after
+all of them succeed; if one fails, nothing is saved. Then the commands run,
+and last the forwardings start. A summary of every operation is printed at
+the end. With --dry-run the whole batch is checked and reported without
+changing or running anything, so a batch can be reviewed before it is
+applied.`
+	cmd.Examples = []string{
+		"xssh batch --dry-run fleet.txt",
+		"xssh batch fleet.txt",
+		"printf 'rm old1\\nrm old2\\n' | xssh batch -",
+	}
+	dryRun := cmd.Flags.Bool("dry-run", false, "check and report the operations without applying them")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("batch takes exactly one file, or - for stdin")
+		}
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			r = file
+		}
+		ops, err := parseBatch(r)
+		if err != nil {
+			return err
+		}
+		return runBatch(ops, *dryRun)
+	}
+	return cmd
+}
+
+// parseBatch reads the operations of a batch file, reporting every line that
+// cannot be parsed
+func parseBatch(r io.Reader) ([]batchOp, error) {
+	var ops []batchOp
+	var problems []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		op, err := parseBatchOp(text)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", n, err))
+			continue
+		}
+		op.line = n
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, errorf(exitUsage, "invalid batch, nothing was done:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return ops, nil
+}
+
+// parseBatchOp parses one line of a batch file
+func parseBatchOp(text string) (batchOp, error) {
+	words, err := splitWords(text)
+	if err != nil {
+		return batchOp{}, err
+	}
+	op := batchOp{text: text, name: words[0]}
+	flags := flag.NewFlagSet(op.name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	switch op.name {
+	case "add":
+		identity := flags.String("identity", "", "")
+		jump := flags.String("jump", "", "")
+		args, err := parseInterspersed(flags, words[1:])
+		if err != nil {
+			return op, err
+		}
+		if len(args) != 2 {
+			return op, fmt.Errorf("add takes an alias and [user@]host[:port]")
+		}
+		if op.host, err = ssh.ParseTarget(args[1]); err != nil {
+			return op, err
+		}
+		op.host.Name = args[0]
+		op.host.Identity = *identity
+		op.host.ProxyJump = *jump
+		if op.host.Port == "" {
+			op.host.Port = "22"
+		}
+		if err := op.host.Validate(); err != nil {
+			return op, err
+		}
+		op.alias = op.host.Name
+
+	case "rm":
+		if len(words) != 2 {
+			return op, fmt.Errorf("rm takes exactly one host alias")
+		}
+		op.alias = words[1]
+
+	case "tag":
+		if len(words) < 3 {
+			return op, fmt.Errorf("tag takes a host alias and at least one tag")
+		}
+		op.alias = words[1]
+		for _, word := range words[2:] {
+			sign := "+"
+			if strings.HasPrefix(word, "-") || strings.HasPrefix(word, "+") {
+				sign, word = word[:1], word[1:]
+			}
+			tag := config.NormalizeTag(word)
+			if tag == "" {
+				return op, fmt.Errorf("invalid tag %q", word)
+			}
+			op.tags = append(op.tags, sign+tag)
+		}
+
+	case "exec":
+		if len(words) < 3 {
+			return op, fmt.Errorf("exec takes a host alias or tag:NAME and a command")
+		}
+		op.alias = words[1]
+		op.remote = strings.Join(words[2:], " ")
+
+	case "forward":
+		flags.Var(specFlag{forwarding.LocalForward, &op.rules}, "L", "")
+		flags.Var(specFlag{forwarding.RemoteForward, &op.rules}, "R", "")
+		flags.Var(specFlag{forwarding.DynamicForward, &op.rules}, "D", "")
+		args, err := parseInterspersed(flags, words[1:])
+		if err != nil {
+			return op, err
+		}
+		if len(args) != 1 || len(op.rules) == 0 {
+			return op, fmt.Errorf("forward takes a host alias and at least one -L, -R or -D")
+		}
+		op.alias = args[0]
+
+	default:
+		return op, fmt.Errorf("unknown operation %q, expected add, rm, tag, exec or forward", op.name)
+	}
+	return op, nil
+}
+
+// parseInterspersed parses flags given before, between or after the
+// positional arguments and returns the positional arguments
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// splitWords splits a line into words like a shell: words are separated by
+// spaces, and quotes or a backslash keep spaces in a word
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// batchReport collects the outcome of each operation for the summary
+type batchReport struct {
+	lines  []string
+	failed int
+}
+
+func (r *batchReport) add(op batchOp, outcome string) {
+	r.lines = append(r.lines, fmt.Sprintf("  line %d: %s: %s", op.line, op.text, outcome))
+}
+
+// print prints the summary on stdout
+func (r *batchReport) print() {
+	fmt.Println("Batch summary:")
+	for _, line := range r.lines {
+		fmt.Println(line)
+	}
+}
+
+// runBatch applies the changes of a batch to the config and saves them if
+// all succeed, then runs its commands and starts its forwardings
+func runBatch(ops []batchOp, dryRun bool) error {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+	metadata, err := config.LoadMetadata()
+	if err != nil {
+		return errorf(exitConfig, "failed to load host metadata: %v", err)
+	}
+
+	report := &batchReport{}
+	if err := applyBatchChanges(ops, sshConfig, metadata, report, dryRun); err != nil {
+		report.print()
+		return fmt.Errorf("batch stopped, nothing was changed: %w", err)
+	}
+
+	changes := 0
+	for _, op := range ops {
+		if op.name == "add" || op.name == "rm" || op.name == "tag" {
+			changes++
+		}
+	}
+	if changes > 0 && !dryRun {
+		if err := sshConfig.Backup(); err != nil {
+			return errorf(exitConfig, "failed to back up config: %v", err)
+		}
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "failed to save config: %v", err)
+		}
+		if err := metadata.Save(); err != nil {
+			return errorf(exitConfig, "hosts saved, but failed to save host metadata: %v", err)
+		}
+	}
+
+	// Ctrl+C interrupts the command running and skips the remaining ones and
+	// the forwardings, or stops the forwardings once they run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	for _, op := range ops {
+		if op.name != "exec" {
+			continue
+		}
+		if dryRun {
+			report.add(op, fmt.Sprintf("would run on %s", strings.Join(hostNames(execTargets(op, sshConfig, metadata)), ", ")))
+			continue
+		}
+		for _, host := range execTargets(op, sshConfig, metadata) {
+			if ctx.Err() != nil {
+				report.add(op, "skipped on "+host.Name+", interrupted")
+				report.failed++
+				continue
+			}
+			if err := runBatchExec(ctx, host, op.remote); err != nil {
+				report.add(op, fmt.Sprintf("failed on %s: %v", host.Name, err))
+				report.failed++
+			} else {
+				report.add(op, "ok on "+host.Name)
+			}
+		}
+	}
+
+	var forwards []batchOp
+	for _, op := range ops {
+		if op.name == "forward" {
+			forwards = append(forwards, op)
+		}
+	}
+	if dryRun {
+		for _, op := range forwards {
+			report.add(op, "would start")
+		}
+		report.print()
+		fmt.Printf("Dry run: %d changes, nothing was saved or run\n", changes)
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		for _, op := range forwards {
+			report.add(op, "skipped, interrupted")
+		}
+		forwards = nil
+	}
+	manager := forwarding.NewManager()
+	for _, op := range forwards {
+		host, _ := sshConfig.ResolveHost(op.alias)
+		for _, rule := range op.rules {
+			if _, err := manager.StartForwarding(ctx, rule, host, ssh.Auth{}); err != nil {
+				shutdownForwarding(manager)
+				report.add(op, fmt.Sprintf("failed: %v", err))
+				report.print()
+				return connectionError(fmt.Errorf("failed to start port forwarding %s: %w", rule.Description, err))
+			}
+		}
+		report.add(op, "started")
+	}
+
+	report.print()
+	if changes > 0 {
+		fmt.Printf("Saved %d changes, the previous config is in %s\n", changes, sshConfig.BackupPath())
+	}
+	if len(forwards) > 0 {
+		infof("Port forwarding active. Press Ctrl+C to stop.\n")
+		<-ctx.Done()
+		infof("\nShutting down port forwarding...\n")
+		shutdownForwarding(manager)
+	}
+	if report.failed > 0 {
+		return fmt.Errorf("%d command(s) failed", report.failed)
+	}
+	return nil
+}
+
+// applyBatchChanges applies the add, rm and tag operations to the config and
+// metadata in memory, and checks that the hosts of exec and forward
+// operations exist once they are applied. It stops at the first failure.
+func applyBatchChanges(ops []batchOp, sshConfig *config.SSHConfig, metadata *config.Metadata, report *batchReport, dryRun bool) error {
+	outcome := "ok"
+	if dryRun {
+		outcome = "would apply"
+	}
+	for _, op := range ops {
+		var err error
+		switch op.name {
+		case "add":
+			if sshConfig.FindHost(op.alias) >= 0 {
+				err = fmt.Errorf("host '%s' already exists", op.alias)
+			} else {
+				sshConfig.AddHost(op.host)
+			}
+		case "rm":
+			if sshConfig.FindHost(op.alias) < 0 {
+				err = fmt.Errorf("host '%s' not found", op.alias)
+			} else {
+				sshConfig.RemoveHost(op.alias)
+				metadata.RemoveHost(op.alias)
+			}
+		case "tag":
+			if sshConfig.FindHost(op.alias) < 0 {
+				err = fmt.Errorf("host '%s' not found", op.alias)
+				break
+			}
+			for _, tag := range op.tags {
+				if tag[0] == '-' {
+					metadata.RemoveTag(op.alias, tag[1:])
+				} else {
+					metadata.AddTag(op.alias, tag[1:])
+				}
+			}
+		case "exec":
+			if len(execTargets(op, sshConfig, metadata)) == 0 {
+				err = fmt.Errorf("no host matches '%s'", op.alias)
+			}
+		case "forward":
+			if _, found := sshConfig.ResolveHost(op.alias); !found {
+				err = fmt.Errorf("host '%s' not found", op.alias)
+			}
+		}
+		if err != nil {
+			report.add(op, "failed: "+err.Error())
+			return err
+		}
+		// Commands and forwardings are reported when they run
+		if op.name != "exec" && op.name != "forward" {
+			report.add(op, outcome)
+		}
+	}
+	return nil
+}
+
+// execTargets returns the hosts an exec operation runs on: the host called
+// alias, or every host with the tag of a tag:NAME target
+func execTargets(op batchOp, sshConfig *config.SSHConfig, metadata *config.Metadata) []config.SSHHost {
+	tag, byTag := strings.CutPrefix(op.alias, "tag:")
+	if !byTag {
+		if host, found := sshConfig.ResolveHost(op.alias); found {
+			return []config.SSHHost{host}
+		}
+		return nil
+	}
+	var hosts []config.SSHHost
+	for _, host := range sshConfig.Hosts {
+		if metadata.HasTag(host.Name, tag) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// hostNames returns the aliases of hosts
+func hostNames(hosts []config.SSHHost) []string {
+	names := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	return names
+}
+
+// runBatchExec runs a command on a host with ssh, without prompting, and
+// passes its output through, recording the run in the session store. The
+// command is interrupted when ctx is canceled.
+func runBatchExec(ctx context.Context, host config.SSHHost, command string) error {
+	cmd, err := ssh.CommandContext(ctx, host)
+	if err != nil {
+		return err
+	}
+	// Options must come before the destination, the command after it
+	destination := cmd.Args[len(cmd.Args)-1]
+	cmd.Args = append(append(cmd.Args[:len(cmd.Args)-1], "-o", "BatchMode=yes", destination), command)
+	output := &countingWriter{w: os.Stdout}
+	cmd.Stdout, cmd.Stderr = output, os.Stderr
+	infof("==> %s: %s\n", host.Name, command)
+
+	record := config.SessionRecord{Kind: config.SessionExec, Host: host.Name, Start: time.Now(), Detail: command}
+	recordAudit(config.AuditEvent{Action: config.AuditExec, Host: host.Name, Detail: command})
+	err = cmd.Run()
+	record.End = time.Now()
+	record.BytesReceived = output.n
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		record.ExitCode = exit.ExitCode()
+	} else if err != nil {
+		record.ExitCode = -1
+		record.Error = err.Error()
+	}
+	recordSession(record)
+	return err
+}
+
+// countingWriter passes writes on to w, counting the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
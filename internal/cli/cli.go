@@ -1,113 +1,497 @@
 package cli
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"xssh/internal/config"
+	"xssh/internal/crash"
 	"xssh/internal/forwarding"
+	"xssh/internal/logging"
+	"xssh/internal/ssh"
 )
 
-// CLIOptions holds all command-line options
-type CLIOptions struct {
-	ShowHelp          bool
-	ShowVersion       bool
-	ForwardingRule    *forwarding.ForwardingRule
-	HostAlias         string
-	ListHosts         bool
-	ListForwarding    bool
-	StopForwarding    string
-	Interactive       bool
-	ConnectOnly       bool
-}
-
-// ParseArgs parses command line arguments and returns CLIOptions
-func ParseArgs() (*CLIOptions, error) {
-	opts := &CLIOptions{
-		Interactive: true, // Default to interactive mode
-	}
-
-	// Custom flag handling since we want to support both -f and --forward formats
-	args := os.Args[1:]
-	
-	for i := 0; i < len(args); i++ {
-		arg := args[i]
-		
-		switch {
-		case arg == "-h" || arg == "--help":
-			opts.ShowHelp = true
-			opts.Interactive = false
-			return opts, nil
-			
-		case arg == "-v" || arg == "--version":
-			opts.ShowVersion = true
-			opts.Interactive = false
-			return opts, nil
-			
-		case arg == "-l" || arg == "--list":
-			opts.ListHosts = true
-			opts.Interactive = false
-			
-		case arg == "--list-forwarding":
-			opts.ListForwarding = true
-			opts.Interactive = false
-			
-		case arg == "--stop-forwarding":
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("option %s requires an argument", arg)
+// Command is a subcommand of xssh, e.g. "list" or "forward"
+type Command struct {
+	Name        string
+	Aliases     []string
+	Args        string // Positional arguments in the usage line, e.g. "<alias>"
+	Summary     string // One line for the command list
+	Description string // Paragraph shown by "xssh help <command>"
+	Examples    []string
+	Flags       *flag.FlagSet
+	Run         func(args []string) error
+
+	// TUI marks commands that draw the TUI, so logs must not go to the
+	// terminal
+	TUI bool
+
+	// Hidden commands are left out of the help and the docs; xssh runs them
+	// itself
+	Hidden bool
+
+	// BrokenConfigOK commands still run when config.toml cannot be read,
+	// with a warning, as they inspect or repair it. Other commands stop
+	// rather than run without its bastion, retry and audit settings.
+	BrokenConfigOK bool
+
+	// Passthrough keeps the arguments after "--" in Extra, to be passed on
+	// as they are, instead of treating them as positional arguments
+	Passthrough bool
+	Extra       []string
+}
+
+// newCommand returns a command with an empty flag set; flags are declared on
+// it by the constructor of each command
+func newCommand(name, args, summary string) *Command {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return &Command{Name: name, Args: args, Summary: summary, Flags: flags}
+}
+
+// Options are the global flags, given before the command
+type Options struct {
+	Inline    bool // Run the TUI without the alternate screen
+	Verbosity int  // 1 with --verbose, 2 with --debug
+	Quiet     bool // Print only results and errors
+	NoColor   bool // Print and draw without colors, as with NO_COLOR
+}
+
+// quiet suppresses the informational messages of commands, see infof
+var quiet bool
+
+// infof prints an informational message, such as progress or confirmation
+// of a change, unless --quiet is given. Results and errors are always printed.
+func infof(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// commands returns every subcommand, in the order of the help
+func commands(opts *Options) []*Command {
+	return []*Command{
+		listCommand(),
+		showCommand(),
+		searchCommand(),
+		connectCommand(),
+		tmuxCommand(),
+		containersCommand(),
+		pickCommand(opts),
+		forwardCommand(),
+		addCommand(),
+		removeCommand(),
+		setCommand(),
+		copyIDCommand(),
+		importCommand(),
+		exportCommand(),
+		batchCommand(),
+		testCommand(),
+		historyCommand(),
+		statsCommand(),
+		auditCommand(),
+		replayCommand(),
+		daemonCommand(),
+		configCommand(),
+		envCommand(),
+		doctorCommand(),
+		debugBundleCommand(),
+		versionCommand(),
+		selfUpdateCommand(),
+		genDocsCommand(opts),
+		forwardWorkerCommand(),
+	}
+}
+
+// listedCommands returns the commands shown in the help and the docs
+func listedCommands(cmds []*Command) []*Command {
+	var listed []*Command
+	for _, cmd := range cmds {
+		if !cmd.Hidden {
+			listed = append(listed, cmd)
+		}
+	}
+	return listed
+}
+
+// findCommand returns the command called name or one of its aliases
+func findCommand(cmds []*Command, name string) *Command {
+	for _, cmd := range cmds {
+		if cmd.Name == name {
+			return cmd
+		}
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd
 			}
-			i++
-			opts.StopForwarding = args[i]
-			opts.Interactive = false
-			
-		case arg == "-c" || arg == "--connect":
-			opts.ConnectOnly = true
-			opts.Interactive = false
-			
-		case arg == "-f" || arg == "--forward":
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("option %s requires an argument", arg)
+		}
+	}
+	return nil
+}
+
+// legacyFlags maps the options of the flag-only CLI to the commands that
+// replaced them, so existing scripts keep working
+var legacyFlags = map[string][]string{
+	"-l":                {"list"},
+	"--list":            {"list"},
+	"-c":                {"connect"},
+	"--connect":         {"connect"},
+	"-f":                {"forward"},
+	"--forward":         {"forward"},
+	"--list-forwarding": {"forward", "list"},
+	"--stop-forwarding": {"forward", "stop"},
+	"-v":                {"version"},
+	"--version":         {"version"},
+}
+
+// Run runs xssh with the arguments following the program name and returns
+// the exit code
+func Run(args []string) int {
+	err := run(args)
+	if err == nil {
+		return 0
+	}
+	var exit exitError
+	if errors.As(err, &exit) {
+		// The command has reported the failure itself
+		return exit.code
+	}
+	code := exitCode(err)
+	reportError(err, code)
+	return code
+}
+
+// exitError ends xssh with a specific exit code after the command printed
+// why
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// usageError reports arguments a command does not accept
+type usageError struct {
+	command string
+	err     error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+// usagef returns a usage error of cmd
+func (cmd *Command) usagef(format string, args ...any) error {
+	return usageError{command: cmd.Name, err: fmt.Errorf(format, args...)}
+}
+
+func run(args []string) error {
+	if printAskpassPassword() {
+		return nil
+	}
+	opts := &Options{}
+
+	// Global options come before the command
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "--inline":
+			opts.Inline = true
+		case "--verbose":
+			opts.Verbosity++
+		case "--debug":
+			opts.Verbosity += 2
+		case "-q", "--quiet":
+			opts.Quiet = true
+		case "--no-color":
+			opts.NoColor = true
+		case "--error-format":
+			if len(args) < 2 || (args[1] != "text" && args[1] != "json") {
+				return usageError{command: "", err: fmt.Errorf("--error-format takes text or json")}
 			}
-			i++
-			rule, err := parseForwardingRule(args[i])
-			if err != nil {
-				return nil, fmt.Errorf("invalid forwarding rule: %v", err)
+			errorFormat = args[1]
+			args = args[1:]
+		case "-h", "--help":
+			showHelp(commands(opts))
+			return nil
+		default:
+			legacy, ok := legacyFlags[args[0]]
+			if !ok {
+				return usageError{command: "", err: fmt.Errorf("unknown option: %s", args[0])}
 			}
-			opts.ForwardingRule = rule
-			opts.Interactive = false
-			
-			// Next argument might be host alias
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				i++
-				opts.HostAlias = args[i]
+			args = append(append([]string{}, legacy...), args[1:]...)
+			continue
+		}
+		args = args[1:]
+	}
+
+	quiet = opts.Quiet
+	appConfig, configErr := config.LoadAppConfig()
+	applySettings(appConfig)
+	if opts.NoColor {
+		// The TUI themes and anything xssh runs follow NO_COLOR
+		os.Setenv("NO_COLOR", "1")
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	// No command starts the interactive TUI, which shows a config error
+	if len(args) == 0 {
+		defer setupLogging(opts, true)()
+		slog.Debug("starting the TUI", "inline", opts.Inline)
+		return runTUI(opts)
+	}
+
+	cmds := commands(opts)
+	name := args[0]
+	if name == "help" {
+		return runHelp(cmds, args[1:])
+	}
+	cmd := findCommand(cmds, name)
+	if cmd == nil {
+		// "xssh <alias>" is a shortcut for "xssh connect <alias>"
+		cmd = findCommand(cmds, "connect")
+	} else {
+		args = args[1:]
+	}
+	if configErr != nil {
+		if !cmd.BrokenConfigOK {
+			return errorf(exitConfig, "failed to load xssh config %s: %v", appConfig.Path, configErr)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: failed to load xssh config %s, using the defaults: %v\n", appConfig.Path, configErr)
+	}
+
+	defer setupLogging(opts, cmd.TUI)()
+	slog.Debug("running command", "command", cmd.Name, "args", args)
+	err := cmd.run(args)
+	if err != nil {
+		slog.Error("command failed", "command", cmd.Name, "error", err)
+	}
+	return err
+}
+
+// applySettings hands the settings of the xssh config to the packages that
+// use them outside the TUI, for commands and the TUI alike
+func applySettings(appConfig *config.AppConfig) {
+	ssh.SetKeepAlive(appConfig.Connection.KeepAliveInterval, appConfig.Connection.KeepAliveCountMax)
+	forwarding.DefaultBindAddress = appConfig.Forwarding.BindAddress
+	forwarding.DefaultPortRetries = appConfig.Forwarding.PortRetries
+	ssh.SetBastionPolicy(appConfig.Bastions)
+	ssh.SetRetryConfig(appConfig.Retry)
+	config.SetAudit(appConfig.Audit)
+	crash.SetReportURL(appConfig.Crash.ReportURL)
+}
+
+// setupLogging starts the diagnostic log and returns the function closing
+// it. A broken log setting is reported but does not stop xssh.
+func setupLogging(opts *Options, tui bool) func() {
+	appConfig, _ := config.LoadAppConfig()
+	closeLog, err := logging.Setup(logging.Options{
+		Config:    appConfig.Log,
+		Verbosity: opts.Verbosity,
+		Terminal:  !tui,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return closeLog
+}
+
+// run parses the flags of the command, wherever they appear among its
+// arguments, and runs it with the remaining positional arguments
+func (cmd *Command) run(args []string) error {
+	var positional, rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	for {
+		if err := cmd.Flags.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				showCommandHelp(cmd)
+				return nil
 			}
-			
-		case !strings.HasPrefix(arg, "-"):
-			// This is likely a host alias
-			opts.HostAlias = arg
-			opts.Interactive = false
-			
-		default:
-			return nil, fmt.Errorf("unknown option: %s", arg)
+			return cmd.usagef("%v", err)
 		}
+		args = cmd.Flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if cmd.Passthrough {
+		cmd.Extra = rest
+	} else {
+		positional = append(positional, rest...)
 	}
-	
-	return opts, nil
+	return cmd.Run(positional)
+}
+
+// runHelp implements "xssh help [command]"
+func runHelp(cmds []*Command, args []string) error {
+	if len(args) == 0 {
+		showHelp(cmds)
+		return nil
+	}
+	cmd := findCommand(cmds, args[0])
+	if cmd == nil {
+		return usageError{command: "", err: fmt.Errorf("unknown command: %s", args[0])}
+	}
+	showCommandHelp(cmd)
+	return nil
+}
+
+// globalOption is an option given before the command, as listed by the help
+// and the generated docs
+type globalOption struct {
+	Synopsis string
+	Usage    string
+}
+
+// globalOptions are the options parsed by run, in the order of the help
+var globalOptions = []globalOption{
+	{"--inline", "Run the TUI in place, without the full screen"},
+	{"-q, --quiet", "Print only results and errors"},
+	{"--no-color", "Print and draw without colors (also NO_COLOR=1)"},
+	{"--verbose", "Log connections and forwardings (--debug for more)"},
+	{"--error-format text|json", "Print errors as text, or as JSON with their kind"},
+	{"-h, --help", "Show this help message"},
+}
+
+// showHelp displays the overview of xssh and its commands
+func showHelp(cmds []*Command) {
+	fmt.Println("xssh - SSH Connection Manager with Port Forwarding")
+	fmt.Println()
+	fmt.Println("USAGE:")
+	fmt.Println("  xssh [--inline]                Start the interactive TUI")
+	fmt.Println("  xssh <alias> [-- ssh args]     Connect to a host")
+	fmt.Println("  xssh <command> [arguments]")
+	fmt.Println()
+	fmt.Println("COMMANDS:")
+	for _, cmd := range listedCommands(cmds) {
+		names := strings.Join(append([]string{cmd.Name}, cmd.Aliases...), ", ")
+		fmt.Printf("  %-30s %s\n", names, cmd.Summary)
+	}
+	fmt.Printf("  %-30s %s\n", "help [command]", "Show help for xssh or a command")
+	fmt.Println()
+	fmt.Println("OPTIONS:")
+	for _, option := range globalOptions {
+		fmt.Printf("  %-30s %s\n", option.Synopsis, option.Usage)
+	}
+	fmt.Println()
+	fmt.Println("Logs go to stderr, or to ~/.config/xssh/xssh.log while the TUI runs. The")
+	fmt.Println("[log] section of the config file sets the level and file for every run.")
+	fmt.Println()
+	fmt.Println("EXIT STATUS:")
+	for _, status := range exitStatuses {
+		fmt.Printf("  %-4d %s\n", status.Code, status.Meaning)
+	}
+	fmt.Println()
+	fmt.Println("The options of the previous versions (-l, -c, -f, --list-forwarding,")
+	fmt.Println("--stop-forwarding, -v, --version) still work and run the matching command.")
+	fmt.Println()
+	fmt.Println("Use 'xssh help <command>' for the options of a command.")
+}
+
+// commandUsage returns the usage line of a command, e.g.
+// "xssh show [options] <alias>"
+func commandUsage(cmd *Command) string {
+	usage := "xssh " + cmd.Name
+	if hasFlags(cmd.Flags) {
+		usage += " [options]"
+	}
+	if cmd.Args != "" {
+		usage += " " + cmd.Args
+	}
+	return usage
+}
+
+// showCommandHelp displays the usage, options and examples of a command
+func showCommandHelp(cmd *Command) {
+	fmt.Println("USAGE:")
+	fmt.Println("  " + commandUsage(cmd))
+	fmt.Println()
+	if cmd.Description != "" {
+		fmt.Println(cmd.Description)
+	} else {
+		fmt.Println(cmd.Summary)
+	}
+	if len(cmd.Aliases) > 0 {
+		fmt.Println()
+		fmt.Println("ALIASES:")
+		fmt.Println("  " + strings.Join(cmd.Aliases, ", "))
+	}
+	if hasFlags(cmd.Flags) {
+		fmt.Println()
+		fmt.Println("OPTIONS:")
+		cmd.Flags.VisitAll(func(f *flag.Flag) {
+			fmt.Printf("  %-30s %s\n", flagSynopsis(f), flagUsage(f))
+		})
+	}
+	if len(cmd.Examples) > 0 {
+		fmt.Println()
+		fmt.Println("EXAMPLES:")
+		for _, example := range cmd.Examples {
+			fmt.Println("  " + example)
+		}
+	}
+}
+
+// hasFlags reports whether a flag set declares any flag
+func hasFlags(flags *flag.FlagSet) bool {
+	found := false
+	flags.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// flagSynopsis returns how a flag is written, e.g. "--port PORT"
+func flagSynopsis(f *flag.Flag) string {
+	name, _ := flag.UnquoteUsage(f)
+	synopsis := "--" + f.Name
+	if len(f.Name) == 1 {
+		synopsis = "-" + f.Name
+	}
+	if name != "" {
+		synopsis += " " + strings.ToUpper(name)
+	}
+	return synopsis
+}
+
+// flagUsage returns the description of a flag with its default, if any
+func flagUsage(f *flag.Flag) string {
+	_, usage := flag.UnquoteUsage(f)
+	if f.DefValue != "" && f.DefValue != "false" {
+		usage += fmt.Sprintf(" (default %s)", f.DefValue)
+	}
+	return usage
 }
 
 // parseForwardingRule parses a forwarding rule string
 // Supports formats:
 // - "8080:localhost:80" (local forwarding)
-// - "R:8080:localhost:80" (remote forwarding)  
+// - "5432:/var/run/postgresql/.s.PGSQL.5432" (local forwarding to a remote unix socket)
+// - "R:8080:localhost:80" (remote forwarding)
 // - "D:1080" (dynamic forwarding/SOCKS proxy)
+// - "R:1080" (remote dynamic forwarding/SOCKS proxy on the remote host)
 func parseForwardingRule(ruleStr string) (*forwarding.ForwardingRule, error) {
 	parts := strings.Split(ruleStr, ":")
-	
+
 	rule := &forwarding.ForwardingRule{
 		ID: fmt.Sprintf("cli-%d", len(ruleStr)), // Simple ID generation
 	}
-	
+
 	if len(parts) == 2 && strings.ToUpper(parts[0]) == "D" {
 		// Dynamic forwarding: D:1080
 		port, err := strconv.Atoi(parts[1])
@@ -115,12 +499,25 @@ func parseForwardingRule(ruleStr string) (*forwarding.ForwardingRule, error) {
 			return nil, fmt.Errorf("invalid port number: %s", parts[1])
 		}
 		rule.Type = forwarding.DynamicForward
-		rule.LocalHost = "localhost"
+		rule.LocalHost = forwarding.DefaultBindAddress
 		rule.LocalPort = port
 		rule.Description = fmt.Sprintf("SOCKS proxy on port %d", port)
 		return rule, nil
 	}
-	
+
+	if len(parts) == 2 && strings.ToUpper(parts[0]) == "R" {
+		// Remote dynamic forwarding: R:1080
+		port, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid port number: %s", parts[1])
+		}
+		rule.Type = forwarding.RemoteDynamicForward
+		rule.RemoteHost = "localhost"
+		rule.RemotePort = port
+		rule.Description = fmt.Sprintf("Remote SOCKS proxy on port %d", port)
+		return rule, nil
+	}
+
 	if len(parts) == 4 && strings.ToUpper(parts[0]) == "R" {
 		// Remote forwarding: R:8080:localhost:80
 		localPort, err := strconv.Atoi(parts[1])
@@ -131,7 +528,7 @@ func parseForwardingRule(ruleStr string) (*forwarding.ForwardingRule, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid remote port: %s", parts[3])
 		}
-		
+
 		rule.Type = forwarding.RemoteForward
 		rule.LocalHost = "localhost"
 		rule.LocalPort = localPort
@@ -140,7 +537,22 @@ func parseForwardingRule(ruleStr string) (*forwarding.ForwardingRule, error) {
 		rule.Description = fmt.Sprintf("Remote %d -> %s:%d", localPort, parts[2], remotePort)
 		return rule, nil
 	}
-	
+
+	if len(parts) == 2 && strings.HasPrefix(parts[1], "/") {
+		// Local forwarding to a unix socket: 5432:/var/run/postgresql/.s.PGSQL.5432
+		localPort, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid local port: %s", parts[0])
+		}
+
+		rule.Type = forwarding.LocalForward
+		rule.LocalHost = forwarding.DefaultBindAddress
+		rule.LocalPort = localPort
+		rule.RemoteSocket = parts[1]
+		rule.Description = fmt.Sprintf("Local %d -> %s", localPort, parts[1])
+		return rule, nil
+	}
+
 	if len(parts) == 3 {
 		// Local forwarding: 8080:localhost:80
 		localPort, err := strconv.Atoi(parts[0])
@@ -151,88 +563,15 @@ func parseForwardingRule(ruleStr string) (*forwarding.ForwardingRule, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid remote port: %s", parts[2])
 		}
-		
+
 		rule.Type = forwarding.LocalForward
-		rule.LocalHost = "localhost"
+		rule.LocalHost = forwarding.DefaultBindAddress
 		rule.LocalPort = localPort
 		rule.RemoteHost = parts[1]
 		rule.RemotePort = remotePort
 		rule.Description = fmt.Sprintf("Local %d -> %s:%d", localPort, parts[1], remotePort)
 		return rule, nil
 	}
-	
-	return nil, fmt.Errorf("invalid forwarding rule format. Use: [R:]local_port:remote_host:remote_port or D:port")
-}
 
-// ShowHelp displays help information
-func ShowHelp() {
-	fmt.Println("xssh - SSH Connection Manager with Port Forwarding")
-	fmt.Println()
-	fmt.Println("USAGE:")
-	fmt.Println("  xssh [OPTIONS] [HOST_ALIAS]")
-	fmt.Println()
-	fmt.Println("OPTIONS:")
-	fmt.Println("  -h, --help                     Show this help message")
-	fmt.Println("  -v, --version                  Show version information")
-	fmt.Println("  -l, --list                     List all configured SSH hosts")
-	fmt.Println("  -c, --connect HOST             Connect to specified host")
-	fmt.Println("  -f, --forward RULE [HOST]      Start port forwarding with specified rule")
-	fmt.Println("  --list-forwarding              List all active port forwarding sessions")
-	fmt.Println("  --stop-forwarding ID           Stop a specific forwarding session")
-	fmt.Println()
-	fmt.Println("PORT FORWARDING RULES:")
-	fmt.Println("  Local forwarding:    8080:localhost:80")
-	fmt.Println("                      Forward local port 8080 to remote localhost:80")
-	fmt.Println()
-	fmt.Println("  Remote forwarding:   R:8080:localhost:80")
-	fmt.Println("                      Forward remote port 8080 to local localhost:80")
-	fmt.Println()
-	fmt.Println("  Dynamic forwarding:  D:1080")
-	fmt.Println("                      Create SOCKS5 proxy on local port 1080")
-	fmt.Println()
-	fmt.Println("EXAMPLES:")
-	fmt.Println("  xssh                           # Start interactive mode")
-	fmt.Println("  xssh myserver                  # Connect to 'myserver' host")
-	fmt.Println("  xssh -c myserver               # Connect to 'myserver' host")
-	fmt.Println("  xssh -l                        # List all configured hosts")
-	fmt.Println("  xssh -f 8080:localhost:80 web  # Forward port 8080 to web server")
-	fmt.Println("  xssh -f R:9000:db:5432 proxy   # Remote forward port 9000 to database")
-	fmt.Println("  xssh -f D:1080 gateway         # Create SOCKS proxy through gateway")
-	fmt.Println("  xssh --list-forwarding         # Show active forwarding sessions")
-	fmt.Println("  xssh --stop-forwarding cli-123 # Stop forwarding session")
-}
-
-// ShowVersion displays version information
-func ShowVersion() {
-	fmt.Println("xssh v1.0.0")
-	fmt.Println("SSH Connection Manager with Port Forwarding")
-	fmt.Println("Built with Go and Bubbletea TUI framework")
-}
-
-// ListHosts displays all configured SSH hosts
-func ListHosts() error {
-	sshConfig, err := config.LoadSSHConfig()
-	if err != nil {
-		return fmt.Errorf("failed to load SSH config: %v", err)
-	}
-	
-	if len(sshConfig.Hosts) == 0 {
-		fmt.Println("No SSH hosts configured.")
-		fmt.Println("Run 'xssh' to enter interactive mode and add hosts.")
-		return nil
-	}
-	
-	fmt.Println("Configured SSH Hosts:")
-	fmt.Println()
-	
-	for _, host := range sshConfig.Hosts {
-		fmt.Printf("  %s\n", host.Name)
-		fmt.Printf("    Host: %s@%s:%s\n", host.User, host.Host, host.Port)
-		if host.Identity != "" {
-			fmt.Printf("    Key:  %s\n", host.Identity)
-		}
-		fmt.Println()
-	}
-	
-	return nil
-}
\ No newline at end of file
+	return nil, fmt.Errorf("invalid forwarding rule format. Use: [R:]local_port:remote_host:remote_port, local_port:/remote/socket or D:port")
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/inventory"
+)
+
+// tagFilterFlag collects the repeatable --tag-filter key=value options of
+// "xssh import"
+type tagFilterFlag map[string]string
+
+func (f tagFilterFlag) String() string {
+	return ""
+}
+
+func (f tagFilterFlag) Set(filter string) error {
+	key, value, ok := strings.Cut(filter, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", filter)
+	}
+	f[key] = value
+	return nil
+}
+
+// importCloud imports the instances of a cloud provider, and again after
+// every interval when it is not zero
+func importCloud(provider string, cloud inventory.CloudOptions, opts importOptions, every time.Duration) error {
+	for {
+		entries, err := inventory.FetchCloud(provider, cloud)
+		if err != nil {
+			if every == 0 {
+				return err
+			}
+			// A failed sync retires nothing; the next one may succeed
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", time.Now().Format("15:04:05"), err)
+		} else {
+			infof("Found %d hosts in %s\n", len(entries), provider)
+			if err := importHosts(entries, opts); err != nil {
+				return err
+			}
+		}
+		if every == 0 {
+			return nil
+		}
+		infof("Next sync at %s\n", time.Now().Add(every).Format("15:04:05"))
+		time.Sleep(every)
+	}
+}
+
+// sourceAlias returns the alias host was imported under by an earlier
+// import of source, found by its address, or its own name for a host new
+// to source
+func sourceAlias(sshConfig *config.SSHConfig, metadata *config.Metadata, source string, host config.SSHHost) string {
+	if index := sshConfig.FindHost(host.Name); index >= 0 && metadata.Source(host.Name) == source {
+		return host.Name
+	}
+	for _, existing := range sshConfig.Hosts {
+		if existing.Host == host.Host && metadata.Source(existing.Name) == source {
+			return existing.Name
+		}
+	}
+	return host.Name
+}
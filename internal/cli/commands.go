@@ -0,0 +1,1468 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/secrets"
+	"xssh/internal/ssh"
+)
+
+// findHost loads the SSH config and returns the host called alias
+func findHost(alias string) (*config.SSHConfig, config.SSHHost, error) {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return nil, config.SSHHost{}, errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+	for _, host := range sshConfig.Hosts {
+		if host.Name == alias {
+			return sshConfig, host, nil
+		}
+	}
+	return sshConfig, config.SSHHost{}, errorf(exitHostNotFound, "host '%s' not found in SSH config", alias)
+}
+
+// resolveHost returns the settings for connecting to alias, which may be
+// a configured alias or a name matched by a pattern such as "Host web-*".
+// Commands that change the config use findHost, as only configured aliases
+// can be edited.
+func resolveHost(alias string) (config.SSHHost, error) {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return config.SSHHost{}, errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+	if host, found := sshConfig.ResolveHost(alias); found {
+		return host, nil
+	}
+	return config.SSHHost{}, errorf(exitHostNotFound, "host '%s' not found in SSH config", alias)
+}
+
+// listCommand implements "xssh list"
+func listCommand() *Command {
+	cmd := newCommand("list", "", "List all configured SSH hosts")
+	cmd.Aliases = []string{"ls"}
+	asJSON := cmd.Flags.Bool("json", false, "print the hosts as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("list takes no arguments")
+		}
+		if *asJSON {
+			return listHostsJSON()
+		}
+		return ListHosts()
+	}
+	return cmd
+}
+
+// listHostsJSON prints every host with its metadata as a JSON array
+func listHostsJSON() error {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+	metadata, _ := config.LoadMetadata()
+	history, _ := config.LoadConnectionHistory()
+	lastConnections := config.LastConnections(history)
+
+	hosts := make([]hostJSON, 0, len(sshConfig.Hosts))
+	for _, host := range sshConfig.Hosts {
+		hosts = append(hosts, newHostJSON(host, metadata, lastConnections[host.Name]))
+	}
+	return printJSON(hosts)
+}
+
+// ListHosts displays all configured SSH hosts
+func ListHosts() error {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+
+	if len(sshConfig.Hosts) == 0 {
+		fmt.Println("No SSH hosts configured.")
+		fmt.Println("Run 'xssh' to enter interactive mode and add hosts.")
+		return nil
+	}
+
+	fmt.Println("Configured SSH Hosts:")
+	fmt.Println()
+
+	for _, host := range sshConfig.Hosts {
+		fmt.Printf("  %s\n", host.Name)
+		fmt.Printf("    Host: %s@%s:%s\n", host.User, host.Host, host.Port)
+		if host.Identity != "" {
+			fmt.Printf("    Key:  %s\n", host.Identity)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// connectCommand implements "xssh connect <alias>", also run by "xssh <alias>"
+func connectCommand() *Command {
+	cmd := newCommand("connect", "<alias> [-- ssh args]", "Connect to a host")
+	cmd.Aliases = []string{"c"}
+	cmd.Description = `Connect to a host with ssh. Arguments after "--" are passed to ssh as they
+are, after the destination: a remote command, -v, or any option xssh does not
+manage.
+
+A name that is not a configured alias is matched against the patterns of
+Host lines such as "Host web-*": the settings of the matching entries are
+combined, the first one winning as in ssh, and %h in their HostName stands
+for the name. "show", "test" and "forward" resolve names the same way.
+
+ssh asks for passwords and key passphrases on the terminal. For scripts,
+--password-stdin reads the password from the first line of stdin and gives
+it to ssh through SSH_ASKPASS, which needs OpenSSH 8.4 or later. Passwords
+and passphrases referenced with "xssh set --password-ref/--passphrase-ref"
+are fetched from their secret manager and given to ssh the same way.
+
+--container opens a shell in a running Docker container of the host, running
+"docker exec -it <container> sh" right after connecting; "xssh containers"
+lists them.
+
+The forwardings set with "xssh set --auto-forward" open before ssh starts
+and close when the session ends, also when connecting from the TUI.`
+	cmd.Examples = []string{
+		"xssh connect myserver",
+		"xssh myserver                  # Same as above",
+		"xssh myserver -- -t 'tmux attach'",
+		"xssh myserver -- -v",
+		"xssh web-prod-03               # Matched by 'Host web-*'",
+		"echo \"$PASSWORD\" | xssh connect --password-stdin web1 -- uptime",
+		"xssh connect web1 --container api --shell bash",
+	}
+	cmd.Passthrough = true
+	passwordStdin := cmd.Flags.Bool("password-stdin", false, "read the password or key passphrase from the first line of stdin")
+	container := cmd.Flags.String("container", "", "open a shell in the Docker container `name` or ID")
+	shell := cmd.Flags.String("shell", ssh.DefaultContainerShell, "shell `program` run in the container")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("connect takes exactly one host alias")
+		}
+		host, err := resolveHost(args[0])
+		if err != nil {
+			return err
+		}
+		extra, err := containerShellArgs(*container, *shell, cmd.Extra)
+		if err != nil {
+			return err
+		}
+		if !*passwordStdin {
+			return connect(host, extra...)
+		}
+
+		// ssh reads passwords from the terminal only, so xssh answers
+		// its prompts as the SSH_ASKPASS program
+		password, err := readPasswordStdin()
+		if err != nil {
+			return err
+		}
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot find the xssh binary: %v", err)
+		}
+		return connectEnv(host, ssh.AskpassEnv(executable, password), extra...)
+	}
+	return cmd
+}
+
+// connect runs an ssh session to the host, passing extraArgs on to ssh.
+// Secrets referenced in the host's metadata are resolved and given to ssh
+// through SSH_ASKPASS, as with --password-stdin.
+func connect(host config.SSHHost, extraArgs ...string) error {
+	auth, err := referencedAuth(host)
+	if err != nil {
+		return errorf(exitAuth, "%v", err)
+	}
+	if auth.Password == "" && auth.KeyPassword == "" {
+		return connectEnv(host, os.Environ(), extraArgs...)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot find the xssh binary: %v", err)
+	}
+	return connectEnv(host, ssh.AskpassAuthEnv(executable, auth), extraArgs...)
+}
+
+// connectEnv connects like connect, running ssh with the environment env
+func connectEnv(host config.SSHHost, env []string, extraArgs ...string) error {
+	slog.Info("connecting", "host", host.Name, "address", host.Host, "port", host.Port, "user", host.User, "jump", host.ProxyJump)
+	infof("%s\n", connectionBanner(host))
+	hooks := loadHooks(host)
+	if err := hooks.run(config.HookPreConnect, host); err != nil {
+		return err
+	}
+	if manager := startAutoForwards(host); manager != nil {
+		defer shutdownForwarding(manager)
+	}
+	return connectAndWait(host, hooks, env, extraArgs...)
+}
+
+// showCommand implements "xssh show <alias>"
+func showCommand() *Command {
+	cmd := newCommand("show", "<alias>", "Show the details of a host")
+	cmd.Description = `Show the connection settings of a host together with what xssh stores about
+it: tags, color label, notes, hooks, secret references, the forwardings
+opened while connected and the last connection.`
+	asJSON := cmd.Flags.Bool("json", false, "print the host as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("show takes exactly one host alias")
+		}
+		host, err := resolveHost(args[0])
+		if err != nil {
+			return err
+		}
+		metadata, _ := config.LoadMetadata()
+		history, _ := config.LoadConnectionHistory()
+		details := newHostJSON(host, metadata, config.LastConnections(history)[host.Name])
+		if *asJSON {
+			return printJSON(details)
+		}
+
+		fmt.Printf("%s\n", details.Name)
+		fmt.Printf("  Host:      %s\n", details.Host)
+		fmt.Printf("  User:      %s\n", details.User)
+		fmt.Printf("  Port:      %s\n", details.Port)
+		if details.Identity != "" {
+			fmt.Printf("  Key:       %s\n", details.Identity)
+		}
+		if details.ProxyJump != "" {
+			fmt.Printf("  Jump:      %s\n", details.ProxyJump)
+		}
+		if appConfig, err := config.LoadAppConfig(); err == nil {
+			if err := appConfig.Bastions.Check(host); err != nil {
+				fmt.Printf("  Policy:    %v\n", err)
+			} else if bastion := appConfig.Bastions.Bastion(host); bastion != "" && host.ProxyJump == "" {
+				fmt.Printf("  Jump:      %s (bastion policy)\n", bastion)
+			}
+		}
+		if len(details.Tags) > 0 {
+			fmt.Printf("  Tags:      %s\n", strings.Join(details.Tags, ", "))
+		}
+		if details.Color != "" {
+			fmt.Printf("  Color:     %s\n", details.Color)
+		}
+		if !details.Managed {
+			fmt.Printf("  Managed:   no\n")
+		}
+		if details.PasswordRef != "" {
+			fmt.Printf("  Password:  %s\n", details.PasswordRef)
+		}
+		if details.PassphraseRef != "" {
+			fmt.Printf("  Key pass:  %s\n", details.PassphraseRef)
+		}
+		if len(details.AutoForwards) > 0 {
+			fmt.Printf("  Forwards:  %s (while connected)\n", strings.Join(details.AutoForwards, ", "))
+		}
+		for _, event := range config.HookEvents {
+			if command := details.Hooks[event]; command != "" {
+				fmt.Printf("  Hook:      %s: %s\n", event, command)
+			}
+		}
+		if details.LastConnected != nil {
+			fmt.Printf("  Last used: %s\n", details.LastConnected.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("  Command:   %s\n", ssh.BuildSSHCommand(host))
+		if details.Notes != "" {
+			fmt.Printf("\n%s\n", details.Notes)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// forwardCommand implements "xssh forward", which starts a forwarding in the
+// foreground, lists the active ones or stops one
+func forwardCommand() *Command {
+	cmd := newCommand("forward", "<rule> <alias> | <alias> -L|-R|-D <spec>... | list | stop|renew <id|pattern|all>", "Start, list or stop port forwardings")
+	cmd.Aliases = []string{"fwd"}
+	cmd.Description = `Start port forwardings through a host and keep them open until Ctrl+C,
+list the active forwarding sessions, or stop them. "stop" takes a session ID,
+a glob pattern such as 'Local-80*', or "all".
+
+Rules:
+  8080:localhost:80     Forward local port 8080 to localhost:80 on the remote side
+  R:8080:localhost:80   Forward remote port 8080 to localhost:80 on this machine
+  D:1080                Create a SOCKS5 proxy on local port 1080
+  R:1080                Create a SOCKS5 proxy on remote port 1080, connecting from this machine
+
+A local rule may end in the path of a unix socket on the remote side instead
+of host:port, as in 5432:/var/run/postgresql/.s.PGSQL.5432.
+
+--tls makes the local forwardings listen with TLS and forward the decrypted
+traffic, so a plain HTTP service opens as https://. "self-signed" uses a
+certificate for localhost kept in ~/.config/xssh/tls, which browsers warn
+about until trusted; cert.pem,key.pem uses your own.
+
+The SSH server resolves the target host of a local forwarding, as with ssh.
+--resolve local resolves it on this machine instead and has the server
+connect to the address, for names only your DNS or /etc/hosts knows. The
+error log of a forwarding tells which one resolved a target it failed to
+connect to.
+
+--via tunnels through other hosts before the host, in order, as ssh -J does:
+"--via bastion1,bastion2" reaches the host through bastion1 and bastion2 and
+tears the hops down after the forwarding, innermost first. The hops log in
+with their key, like ProxyJump hosts.
+
+--capture dumps the traffic of the forwardings to a file per forwarding in
+~/.config/xssh/captures, in hex with the time, connection and direction of
+each read, to look into protocol issues inside the tunnel. "all" keeps every
+byte, "headers" each direction up to its first blank line, as HTTP headers
+end, and a size such as 64k caps each direction of a connection; "headers"
+and a size combine as headers,4k.
+
+--label tags the forwardings with labels such as dev,db, which "forward list
+--filter label=dev" selects them by once many tunnels are open. A filter
+holds words that must all match: label=, host=, type=, id= and port= narrow
+a word to one field, other words match the ID, description, host or labels.
+
+"forward list" prints the plain ssh -N -L/-R/-D command opening the same
+forwarding for each session, ssh_command with --json, spelling the host and
+its jump hosts by address so it runs for someone without xssh.
+
+"forward list --watch" redraws the list every second, like top, with a line
+per session and its connections and transfer rate, read from the daemon.
+Without the daemon it shows the background forwardings, without traffic.
+
+When the port a forwarding listens on is in use, it fails unless
+--port-retries, or [forwarding] port_retries in config.toml, lets it take
+the first free one of the following ports; the port taken is reported. A
+local port in use is found before connecting, and the error names what holds
+it: an xssh session with its ID and PID, or another program with its PID.
+
+--health serves the health of the forwardings over HTTP on an address such
+as 127.0.0.1:8099, so other tools can wait for a tunnel: GET /health checks
+all of them and GET /health/<id> one, answering 200 when the SSH connection
+answers and the target accepts a connection, and 503 otherwise.
+
+--expire stops the forwardings on their own, after a duration such as 2h or
+at a time of day such as 18:00, so a tunnel left open does not stay open.
+"renew" pushes the expiry back: a duration starts over, a time of day moves
+to the next day.
+
+The -L, -R and -D options take the same specs as ssh and can be repeated to
+start several forwardings at once; -R with only a port is a SOCKS5 proxy on
+the server, as with ssh.
+
+With --background the command returns once the forwardings are open and
+prints their session IDs. They are started in the xssh daemon when it is
+running, and otherwise each runs in a detached xssh process whose output goes
+to a log in ~/.config/xssh/forwards. Either way they show up in "forward list"
+of any xssh and stay open until "forward stop" stops them.
+
+The passphrase of an encrypted key is asked on the terminal, and so is the
+password when the host does not accept the key. --password-stdin reads
+either from the first line of stdin instead.`
+	cmd.Examples = []string{
+		"xssh forward 8080:localhost:80 web",
+		"xssh forward R:9000:db:5432 proxy",
+		"xssh forward 5432:/var/run/postgresql/.s.PGSQL.5432 db",
+		"xssh forward 8443:localhost:80 web --tls self-signed",
+		"xssh forward 8080:localhost:80 web --expire 2h",
+		"xssh forward bastion -L 8080:db.internal:5432 --resolve local",
+		"xssh forward db -L 5432:localhost:5432 --via bastion1,bastion2",
+		"xssh forward 8080:localhost:80 web --capture headers",
+		"xssh forward 8080:localhost:80 web --health 127.0.0.1:8099",
+		"xssh forward 8080:localhost:80 web --port-retries 10",
+		"xssh forward D:1080 gateway",
+		"xssh forward R:1080 gateway",
+		"xssh forward bastion -L 8080:db:5432 -L 9090:grafana:3000 -D 1080",
+		"xssh forward bastion -L 8080:db:5432 --background",
+		"echo \"$PASSWORD\" | xssh forward bastion -L 8080:db:5432 --password-stdin",
+		"xssh forward list",
+		"xssh forward 8080:localhost:80 web --label dev,web",
+		"xssh forward list --filter label=dev",
+		"xssh forward list --watch",
+		"xssh forward stop cli-123",
+		"xssh forward stop 'Local-80*'",
+		"xssh forward stop all",
+		"xssh forward renew 'Local-80*'",
+	}
+	var rules []forwarding.ForwardingRule
+	cmd.Flags.Var(specFlag{forwarding.LocalForward, &rules}, "L", "local forwarding `[bind:]port:host:hostport`, or [bind:]port:/remote/socket, as ssh -L")
+	cmd.Flags.Var(specFlag{forwarding.RemoteForward, &rules}, "R", "remote forwarding `[bind:]port:host:hostport`, or a SOCKS proxy on the server with only [bind:]port, as ssh -R")
+	cmd.Flags.Var(specFlag{forwarding.DynamicForward, &rules}, "D", "SOCKS proxy on `[bind:]port`, as ssh -D")
+	asJSON := cmd.Flags.Bool("json", false, "print the sessions of 'forward list' as JSON")
+	background := cmd.Flags.Bool("background", false, "keep the forwardings open in the daemon or a detached process and return")
+	passwordStdin := cmd.Flags.Bool("password-stdin", false, "read the password or key passphrase from the first line of stdin")
+	tlsOption := cmd.Flags.String("tls", "", "serve local forwardings over TLS with a `self-signed` certificate or cert.pem,key.pem")
+	resolve := cmd.Flags.String("resolve", "", "resolve the targets of local forwardings on the SSH server (`remote`, the default) or on this machine (local)")
+	expire := cmd.Flags.String("expire", "", "stop the forwardings after a `duration` such as 2h, or at a time such as 18:00")
+	viaOption := cmd.Flags.String("via", "", "tunnel through the `hosts` bastion1,bastion2 in order before the host, as ssh -J")
+	captureOption := cmd.Flags.String("capture", "", "dump the forwarded traffic to a file: `all`, headers, or a size such as 64k per direction")
+	health := cmd.Flags.String("health", "", "serve the health of the forwardings over HTTP on `address`, such as 127.0.0.1:8099")
+	portRetries := cmd.Flags.Int("port-retries", -1, "try up to `n` following ports when the port is in use (default [forwarding] port_retries)")
+	labelOption := cmd.Flags.String("label", "", "tag the forwardings with the `labels` dev,db to filter them by")
+	filter := cmd.Flags.String("filter", "", "list only the sessions matching `words` such as label=dev or host=bastion")
+	watch := cmd.Flags.Bool("watch", false, "redraw 'forward list' every second with a line per session, from the daemon")
+	start := func(rules []forwarding.ForwardingRule, hostAlias string) error {
+		if err := applyListenerTLS(rules, *tlsOption); err != nil {
+			return err
+		}
+		if err := applyResolve(rules, *resolve); err != nil {
+			return err
+		}
+		expiry, err := forwarding.ParseExpiry(*expire)
+		if err != nil {
+			return errorf(exitUsage, "%v", err)
+		}
+		via, err := forwarding.ParseVia(*viaOption)
+		if err != nil {
+			return errorf(exitUsage, "%v", err)
+		}
+		capture, err := forwarding.ParseCapture(*captureOption)
+		if err != nil {
+			return errorf(exitUsage, "%v", err)
+		}
+		labels, err := forwarding.ParseLabels(*labelOption)
+		if err != nil {
+			return errorf(exitUsage, "%v", err)
+		}
+		retries := forwarding.DefaultPortRetries
+		if *portRetries >= 0 {
+			retries = *portRetries
+		}
+		for i := range rules {
+			rules[i].Expiry = expiry
+			rules[i].Via = via
+			rules[i].Capture = capture
+			rules[i].PortRetries = retries
+			rules[i].Labels = labels
+		}
+		if *background {
+			if *health != "" {
+				return errorf(exitUsage, "--health applies to forwardings in the foreground only")
+			}
+			return startBackgroundForwarding(rules, hostAlias, *passwordStdin)
+		}
+		return handlePortForwarding(rules, hostAlias, *passwordStdin, *health)
+	}
+	cmd.Run = func(args []string) error {
+		if *watch && (len(args) != 1 || args[0] != "list") {
+			return cmd.usagef("--watch applies to 'forward list' only")
+		}
+		if len(rules) > 0 {
+			if len(args) != 1 {
+				return cmd.usagef("-L, -R and -D take exactly one host alias")
+			}
+			return start(rules, args[0])
+		}
+
+		switch {
+		case len(args) == 1 && args[0] == "list":
+			sessionFilter := forwarding.ParseSessionFilter(*filter)
+			if *watch {
+				if *asJSON {
+					return cmd.usagef("--watch and --json cannot be combined")
+				}
+				return watchForwarding(sessionFilter)
+			}
+			if *asJSON {
+				return listActiveForwardingJSON(sessionFilter)
+			}
+			return listActiveForwarding(sessionFilter)
+		case len(args) == 2 && args[0] == "stop":
+			return stopForwardingSession(args[1])
+		case len(args) == 2 && args[0] == "renew":
+			return renewForwardingSession(args[1])
+		case len(args) == 1:
+			return cmd.usagef("host alias is required for port forwarding")
+		case len(args) != 2:
+			return cmd.usagef("forward takes a rule and a host alias")
+		}
+		rule, err := parseForwardingRule(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid forwarding rule: %v", err)
+		}
+		return start([]forwarding.ForwardingRule{*rule}, args[1])
+	}
+	return cmd
+}
+
+// applyListenerTLS sets the --tls option of "xssh forward" on the local
+// forwardings among rules
+func applyListenerTLS(rules []forwarding.ForwardingRule, option string) error {
+	listenerTLS, err := forwarding.ParseListenerTLS(option)
+	if err != nil || listenerTLS == nil {
+		return err
+	}
+	applied := false
+	for i := range rules {
+		if rules[i].Type == forwarding.LocalForward {
+			rules[i].TLS = listenerTLS
+			applied = true
+		}
+	}
+	if !applied {
+		return errorf(exitUsage, "--tls applies to local forwardings only")
+	}
+	return nil
+}
+
+// applyResolve sets the --resolve option of "xssh forward" on the local
+// forwardings among rules
+func applyResolve(rules []forwarding.ForwardingRule, option string) error {
+	resolveLocally, err := forwarding.ParseResolve(option)
+	if err != nil {
+		return errorf(exitUsage, "%v", err)
+	}
+	if !resolveLocally {
+		return nil
+	}
+	applied := false
+	for i := range rules {
+		if rules[i].Type == forwarding.LocalForward {
+			rules[i].ResolveLocally = true
+			applied = true
+		}
+	}
+	if !applied {
+		return errorf(exitUsage, "--resolve applies to local forwardings only")
+	}
+	return nil
+}
+
+// specFlag collects the repeatable -L, -R and -D options of "xssh forward"
+type specFlag struct {
+	forwardingType forwarding.ForwardingType
+	rules          *[]forwarding.ForwardingRule
+}
+
+func (f specFlag) String() string {
+	return ""
+}
+
+func (f specFlag) Set(spec string) error {
+	rule, err := forwarding.ParseSpec(f.forwardingType, spec)
+	if err != nil {
+		return err
+	}
+	port := rule.LocalPort
+	if rule.Type.ListensRemotely() {
+		port = rule.RemotePort
+	}
+	rule.ID = fmt.Sprintf("%s-%d-%d", rule.Type.String(), port, time.Now().Unix())
+	*f.rules = append(*f.rules, rule)
+	return nil
+}
+
+// keepMatching returns the items whose forwarding matches filter, given by
+// the rule and host alias fields returns for each
+func keepMatching[T any](filter forwarding.SessionFilter, items []T, fields func(T) (forwarding.ForwardingRule, string)) []T {
+	if len(filter) == 0 {
+		return items
+	}
+	var kept []T
+	for _, item := range items {
+		if rule, host := fields(item); filter.Matches(rule, host) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// sessionFilterFields returns what a session filter looks at in a session
+// of this process
+func sessionFilterFields(session *forwarding.ForwardingSession) (forwarding.ForwardingRule, string) {
+	return session.Rule, session.Host().Name
+}
+
+// backgroundFilterFields returns what a session filter looks at in a
+// background session
+func backgroundFilterFields(session forwarding.BackgroundSession) (forwarding.ForwardingRule, string) {
+	return session.Rule, session.Host
+}
+
+// listActiveForwarding lists the active port forwarding sessions matching
+// filter
+func listActiveForwarding(filter forwarding.SessionFilter) error {
+	manager := forwarding.NewManager()
+	sessions := keepMatching(filter, manager.GetAllSessions(), sessionFilterFields)
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		return err
+	}
+	backgroundSessions = keepMatching(filter, backgroundSessions, backgroundFilterFields)
+	daemonTunnels := keepMatching(filter, listDaemonTunnels(), sessionJSON.filterFields)
+	profiles := keepMatching(filter, listDaemonSchedule(), scheduleJSON.filterFields)
+
+	if len(sessions) == 0 && len(backgroundSessions) == 0 && len(daemonTunnels) == 0 && len(profiles) == 0 {
+		if len(filter) > 0 {
+			fmt.Println("No port forwarding sessions match the filter.")
+			return nil
+		}
+		fmt.Println("No active port forwarding sessions.")
+		return nil
+	}
+
+	fmt.Println("Active Port Forwarding Sessions:")
+	fmt.Println()
+
+	for _, session := range sessions {
+		fmt.Printf("  %s (%s)\n", session.Rule.ID, session.Rule.Type.String())
+		fmt.Printf("    %s\n", session.Rule.Description)
+		fmt.Printf("    Active: %v, Uptime: %v\n", session.IsActive(), session.GetUptime().Round(time.Second))
+		if len(session.Rule.Via) > 0 {
+			fmt.Printf("    Via: %s\n", session.Rule.ViaString())
+		}
+		if len(session.Rule.Labels) > 0 {
+			fmt.Printf("    Labels: %s\n", session.Rule.LabelsString())
+		}
+		if note := session.Rule.PortSubstitution(); note != "" {
+			fmt.Printf("    Note: %s\n", note)
+		}
+		fmt.Printf("    Command: %s\n", session.Rule.SSHCommand(session.Host()))
+		if capturePath := session.CapturePath(); capturePath != "" {
+			fmt.Printf("    Capturing traffic to %s\n", capturePath)
+		}
+		if expiresAt := session.ExpiresAt(); !expiresAt.IsZero() {
+			fmt.Printf("    %s\n", expiryLine(expiresAt))
+		}
+		if session.Degraded() {
+			fmt.Println("    SSH connection lost, reconnecting")
+		}
+		fmt.Printf("    Connections: %d active, %d total\n",
+			session.Stats.ActiveConnections, session.Stats.ConnectionCount)
+		if session.Stats.BytesReceived > 0 || session.Stats.BytesSent > 0 {
+			fmt.Printf("    Data: %d bytes received, %d bytes sent\n",
+				session.Stats.BytesReceived, session.Stats.BytesSent)
+			rxRate, txRate := session.GetCurrentRate()
+			avgRxRate, avgTxRate := session.GetTransferRate()
+			fmt.Printf("    Rate: now %s, avg %s\n", formatRates(rxRate, txRate), formatRates(avgRxRate, avgTxRate))
+		}
+		if session.Resumed() {
+			totals := session.Totals()
+			fmt.Printf("    Since %s: %d connections, %s\n", totals.Since.Format("2006-01-02"),
+				totals.Connections, formatTraffic(totals.BytesSent, totals.BytesReceived))
+		}
+		fmt.Println()
+	}
+
+	for _, session := range backgroundSessions {
+		fmt.Printf("  %s (%s, background)\n", session.Rule.ID, session.Rule.Type.String())
+		fmt.Printf("    %s\n", session.Rule.Description)
+		fmt.Printf("    Host: %s, PID: %d, Uptime: %v\n", session.Host, session.PID, time.Since(session.Started).Round(time.Second))
+		if len(session.Rule.Labels) > 0 {
+			fmt.Printf("    Labels: %s\n", session.Rule.LabelsString())
+		}
+		if note := session.Rule.PortSubstitution(); note != "" {
+			fmt.Printf("    Note: %s\n", note)
+		}
+		if host, err := resolveHost(session.Host); err == nil {
+			fmt.Printf("    Command: %s\n", session.Rule.SSHCommand(host))
+		}
+		if !session.Expires.IsZero() {
+			fmt.Printf("    %s\n", expiryLine(session.Expires))
+		}
+		fmt.Println()
+	}
+
+	for _, tunnel := range daemonTunnels {
+		fmt.Printf("  %s (%s, daemon)\n", tunnel.ID, tunnel.Type)
+		fmt.Printf("    %s\n", tunnel.Description)
+		fmt.Printf("    Host: %s, Uptime: %v\n", tunnel.Host, time.Since(tunnel.StartTime).Round(time.Second))
+		if len(tunnel.Labels) > 0 {
+			fmt.Printf("    Labels: %s\n", strings.Join(tunnel.Labels, ","))
+		}
+		if tunnel.SSHCommand != "" {
+			fmt.Printf("    Command: %s\n", tunnel.SSHCommand)
+		}
+		if tunnel.ExpiresAt != nil {
+			fmt.Printf("    %s\n", expiryLine(*tunnel.ExpiresAt))
+		}
+		if tunnel.Degraded {
+			fmt.Println("    SSH connection lost, reconnecting")
+		}
+		fmt.Printf("    Connections: %d active, %d total\n", tunnel.ActiveConnections, tunnel.ConnectionCount)
+		if tunnel.BytesReceived > 0 || tunnel.BytesSent > 0 {
+			fmt.Printf("    Rate: now %s, avg %s\n", formatRates(tunnel.ReceivedPerSecond, tunnel.SentPerSecond),
+				formatRates(tunnel.AvgReceivedPerSecond, tunnel.AvgSentPerSecond))
+		}
+		if tunnel.Totals != nil {
+			fmt.Printf("    Since %s: %d connections, %s\n", tunnel.Totals.Since.Format("2006-01-02"),
+				tunnel.Totals.ConnectionCount, formatTraffic(tunnel.Totals.BytesSent, tunnel.Totals.BytesReceived))
+		}
+		fmt.Println()
+	}
+
+	if len(profiles) > 0 {
+		fmt.Println("Scheduled tunnels of the daemon:")
+		for _, profile := range profiles {
+			fmt.Printf("  %s (%s via %s): %s\n", profile.Name, profile.Rule, profile.Host, scheduleLine(profile))
+			if len(profile.Labels) > 0 {
+				fmt.Printf("    Labels: %s\n", strings.Join(profile.Labels, ","))
+			}
+			if profile.LastError != "" {
+				fmt.Printf("    Failed to start: %s\n", profile.LastError)
+			}
+		}
+		fmt.Println()
+	}
+
+	if clients := listDaemonClients(); len(clients) > 0 {
+		fmt.Println("SSH connections of the daemon:")
+		for _, client := range clients {
+			fmt.Printf("  %s (%s): %d sessions, %d open connections, up %v\n", client.Address, client.Host,
+				client.Sessions, client.Connections, time.Since(client.Opened).Round(time.Second))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// expiryLine describes when a forwarding stops on its own
+func expiryLine(expiresAt time.Time) string {
+	return fmt.Sprintf("Expires in %v, at %s", time.Until(expiresAt).Round(time.Second), expiresAt.Format("2006-01-02 15:04"))
+}
+
+// listDaemonClients returns the SSH connections the tunnels of the xssh
+// daemon share, or none when it is not running
+func listDaemonClients() []clientJSON {
+	var clients []clientJSON
+	if err := daemonRequest(http.MethodGet, "/v1/clients", nil, &clients); err != nil {
+		return nil
+	}
+	return clients
+}
+
+// listDaemonTunnels returns the forwardings held by the xssh daemon, or none
+// when it is not running
+func listDaemonTunnels() []sessionJSON {
+	var tunnels []sessionJSON
+	if err := daemonRequest(http.MethodGet, "/v1/tunnels", nil, &tunnels); err != nil {
+		return nil
+	}
+	// Background sessions are listed from their records already
+	var held []sessionJSON
+	for _, tunnel := range tunnels {
+		if !tunnel.Background {
+			held = append(held, tunnel)
+		}
+	}
+	return held
+}
+
+// listActiveForwardingJSON prints the active forwarding sessions matching
+// filter as a JSON array
+func listActiveForwardingJSON(filter forwarding.SessionFilter) error {
+	sessions := keepMatching(filter, forwarding.NewManager().GetAllSessions(), sessionFilterFields)
+	list := make([]sessionJSON, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, newSessionJSON(session))
+	}
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		return err
+	}
+	for _, session := range keepMatching(filter, backgroundSessions, backgroundFilterFields) {
+		list = append(list, newBackgroundSessionJSON(session))
+	}
+	list = append(list, keepMatching(filter, listDaemonTunnels(), sessionJSON.filterFields)...)
+	return printJSON(list)
+}
+
+// stopForwardingSession stops the port forwarding sessions selected by a
+// session ID, a glob pattern or "all"
+func stopForwardingSession(pattern string) error {
+	manager := forwarding.NewManager()
+
+	stopped, err := manager.StopMatching(pattern)
+	if err != nil {
+		return err
+	}
+	// The daemon stops matching background sessions too. It not running,
+	// or holding no matching session, is no error.
+	var stoppedByDaemon struct {
+		Stopped []string `json:"stopped"`
+	}
+	err = daemonRequest(http.MethodDelete, "/v1/tunnels/"+url.PathEscape(pattern), nil, &stoppedByDaemon)
+	if err != nil && exitCode(err) != exitDaemon && exitCode(err) != exitFailure {
+		return err
+	}
+	stopped = append(stopped, stoppedByDaemon.Stopped...)
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		return err
+	}
+	for _, session := range backgroundSessions {
+		if matched, _ := forwarding.MatchID(pattern, session.Rule.ID); !matched {
+			continue
+		}
+		if err := session.Stop(); err != nil {
+			return err
+		}
+		stopped = append(stopped, session.Rule.ID)
+	}
+	if len(stopped) == 0 {
+		if pattern == "all" {
+			infof("No active port forwarding sessions.\n")
+			return nil
+		}
+		return fmt.Errorf("no forwarding session matches '%s'", pattern)
+	}
+
+	for _, id := range stopped {
+		infof("Stopped port forwarding session: %s\n", id)
+	}
+	return nil
+}
+
+// renewForwardingSession pushes back the expiry of the forwarding sessions
+// selected by a session ID, a glob pattern or "all"
+func renewForwardingSession(pattern string) error {
+	// The daemon renews matching background sessions too; renewing them
+	// again here would push back a time of day twice
+	var renewed struct {
+		Renewed []string `json:"renewed"`
+	}
+	err := daemonRequest(http.MethodPost, "/v1/tunnels/"+url.PathEscape(pattern)+"/renew", nil, &renewed)
+	if err != nil && exitCode(err) != exitDaemon && exitCode(err) != exitFailure {
+		return err
+	}
+	if exitCode(err) == exitDaemon {
+		backgroundSessions, err := forwarding.BackgroundSessions()
+		if err != nil {
+			return err
+		}
+		for _, session := range backgroundSessions {
+			if matched, _ := forwarding.MatchID(pattern, session.Rule.ID); !matched || session.Rule.Expiry == nil {
+				continue
+			}
+			if err := session.Renew(); err != nil {
+				return err
+			}
+			renewed.Renewed = append(renewed.Renewed, session.Rule.ID)
+		}
+	}
+	if len(renewed.Renewed) == 0 {
+		if pattern == "all" {
+			infof("No expiring port forwarding sessions.\n")
+			return nil
+		}
+		return fmt.Errorf("no expiring forwarding session matches '%s'", pattern)
+	}
+
+	for _, id := range renewed.Renewed {
+		infof("Renewed port forwarding session: %s\n", id)
+	}
+	return nil
+}
+
+// handlePortForwarding starts port forwarding sessions and keeps them open
+// until xssh is interrupted
+func handlePortForwarding(rules []forwarding.ForwardingRule, hostAlias string, passwordStdin bool, healthAddress string) error {
+	targetHost, err := resolveHost(hostAlias)
+	if err != nil {
+		return err
+	}
+	auth, err := hostAuth(targetHost, passwordStdin)
+	if err != nil {
+		return err
+	}
+	hooks := loadHooks(targetHost)
+	if err := hooks.run(config.HookPreConnect, targetHost); err != nil {
+		return err
+	}
+	started := time.Now()
+
+	// Ctrl+C gives up connecting, or stops the forwardings once they run
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start port forwarding
+	manager := forwarding.NewManager()
+	if healthAddress != "" {
+		healthServer, err := serveHealth(manager, healthAddress)
+		if err != nil {
+			return err
+		}
+		defer healthServer.Close()
+	}
+	infof("Connecting to %s@%s:%s\n", targetHost.User, targetHost.Host, targetHost.Port)
+	for _, rule := range rules {
+		infof("Starting port forwarding: %s\n", rule.Description)
+		var session *forwarding.ForwardingSession
+		err := withPasswordPrompt(targetHost, &auth, passwordStdin, func(auth ssh.Auth) (err error) {
+			session, err = manager.StartForwarding(ctx, rule, targetHost, auth)
+			return err
+		})
+		if err != nil {
+			// Leave nothing half started
+			shutdownForwarding(manager)
+			err = connectionError(fmt.Errorf("failed to start port forwarding %s: %w", rule.Description, err))
+			hooks.disconnected(targetHost, exitCode(err), started)
+			return err
+		}
+		if note := session.Rule.PortSubstitution(); note != "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", rule.Description, note)
+		}
+	}
+
+	infof("Port forwarding active. Press Ctrl+C to stop.\n")
+	if waitForwarding(ctx, manager) {
+		infof("All port forwardings expired\n")
+	} else {
+		infof("\nShutting down port forwarding...\n")
+	}
+	shutdownForwarding(manager)
+
+	hooks.disconnected(targetHost, 0, started)
+	return nil
+}
+
+// expiryCheckInterval is how often a forwarding process checks whether its
+// forwardings have all expired
+const expiryCheckInterval = time.Second
+
+// waitForwarding waits until ctx is done or every forwarding of manager has
+// stopped, as expired ones do, and reports whether they all stopped
+func waitForwarding(ctx context.Context, manager *forwarding.ForwardingManager) bool {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			if len(manager.GetAllSessions()) == 0 {
+				return true
+			}
+		}
+	}
+}
+
+// shutdownTimeout is how long forwarded connections have to finish when
+// xssh stops forwarding
+const shutdownTimeout = 5 * time.Second
+
+// shutdownForwarding stops every forwarding of manager, giving their open
+// connections shutdownTimeout to finish
+func shutdownForwarding(manager *forwarding.ForwardingManager) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	manager.Shutdown(ctx)
+}
+
+// addCommand implements "xssh add"
+func addCommand() *Command {
+	cmd := newCommand("add", "[[user@]host[:port]]", "Add a host to ~/.ssh/config")
+	cmd.Description = `Add a host to ~/.ssh/config. The address can be given as [user@]host[:port]
+or with the options, which take precedence.
+
+With --copy-id, xssh logs in with the password, installs the public key of
+--identity, or of the default key, and checks that the key works before saving
+the host with it, like "xssh copy-id". The password is asked on the terminal,
+or read from the first line of stdin with --password-stdin.`
+	cmd.Examples = []string{
+		"xssh add --alias web1 deploy@10.0.0.1:2222",
+		"xssh add --alias web1 --host 10.0.0.1 --user deploy --identity ~/.ssh/id_ed25519",
+		"echo \"$PASSWORD\" | xssh add --alias web1 deploy@10.0.0.1 --copy-id --password-stdin",
+	}
+	alias := cmd.Flags.String("alias", "", "`name` of the host entry (required)")
+	hostName := cmd.Flags.String("host", "", "host name or IP `address`")
+	user := cmd.Flags.String("user", "", "login `user`")
+	port := cmd.Flags.String("port", "", "SSH `port`")
+	identity := cmd.Flags.String("identity", "", "private key `file`")
+	jump := cmd.Flags.String("jump", "", "ProxyJump `hosts`")
+	copyID := cmd.Flags.Bool("copy-id", false, "log in with a password and install an SSH key on the host")
+	passwordStdin := cmd.Flags.Bool("password-stdin", false, "read the password for --copy-id from stdin")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.usagef("add takes at most one [user@]host[:port] address")
+		}
+
+		var host config.SSHHost
+		if len(args) == 1 {
+			target, err := ssh.ParseTarget(args[0])
+			if err != nil {
+				return err
+			}
+			host = target
+		}
+		host.Name = *alias
+		setIfGiven(&host.Host, *hostName)
+		setIfGiven(&host.User, *user)
+		setIfGiven(&host.Port, *port)
+		setIfGiven(&host.Identity, *identity)
+		setIfGiven(&host.ProxyJump, *jump)
+		if host.Port == "" {
+			host.Port = "22"
+		}
+		if host.Name == "" {
+			return cmd.usagef("--alias is required")
+		}
+		if *passwordStdin && !*copyID {
+			return cmd.usagef("--password-stdin requires --copy-id")
+		}
+		if err := host.Validate(); err != nil {
+			return fmt.Errorf("cannot add host: %v", err)
+		}
+
+		sshConfig, _, err := findHost(host.Name)
+		if err == nil {
+			return fmt.Errorf("host '%s' already exists", host.Name)
+		}
+		if sshConfig == nil {
+			return err
+		}
+		if *copyID {
+			if host.Identity == "" {
+				host.Identity = ssh.DefaultIdentity()
+			}
+			if err := copyKey(host, host.Identity, *passwordStdin); err != nil {
+				return err
+			}
+		}
+		sshConfig.AddHost(host)
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "failed to save config: %v", err)
+		}
+		infof("Added host '%s'\n", host.Name)
+		return nil
+	}
+	return cmd
+}
+
+// copyKey logs in to host with a password, installs the public key of the
+// private key at keyPath, which is generated if missing, and checks that the
+// key then authenticates
+func copyKey(host config.SSHHost, keyPath string, passwordStdin bool) error {
+	var password string
+	var err error
+	if passwordStdin {
+		password, err = readPasswordStdin()
+	} else {
+		password, err = promptPassword(fmt.Sprintf("%s@%s's password: ", host.User, host.Host))
+	}
+	if err != nil {
+		return err
+	}
+
+	// An encrypted key needs its passphrase to verify the login
+	var keyPassword string
+	if ssh.KeyNeedsPassphrase(keyPath) {
+		if keyPassword, err = promptPassword(fmt.Sprintf("Passphrase for %s: ", keyPath)); err != nil {
+			return err
+		}
+	}
+
+	// Ctrl+C aborts the setup at any step
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	infof("Installing %s.pub on %s\n", keyPath, host.Name)
+	result := ssh.InstallKey(ctx, host, password, keyPath, keyPassword, func(step ssh.SetupStep) {
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "%s...\n", step)
+		}
+	})
+	if !result.Success {
+		err := fmt.Errorf("key setup failed: %s", result.Message)
+		if result.Error != nil {
+			return codedError{code: probeExitCodes[ssh.ClassifyError(result.Error)], err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// copyIDCommand implements "xssh copy-id <alias>"
+func copyIDCommand() *Command {
+	cmd := newCommand("copy-id", "<alias>", "Install an SSH key on a host and use it")
+	cmd.Description = `Log in to a host with its password, append a public key to its authorized_keys
+and check that the key works, then set it as the host's IdentityFile.
+
+The key is --identity, else the host's IdentityFile, else the first of
+~/.ssh/id_ed25519, id_ecdsa and id_rsa that exists. A key that does not exist
+yet is generated without a passphrase.`
+	cmd.Examples = []string{
+		"xssh copy-id web1",
+		"xssh copy-id --identity ~/.ssh/deploy_ed25519 web1",
+		"echo \"$PASSWORD\" | xssh copy-id --password-stdin web1",
+	}
+	identity := cmd.Flags.String("identity", "", "private key `file` whose public key is installed")
+	passwordStdin := cmd.Flags.Bool("password-stdin", false, "read the password from stdin")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("copy-id takes exactly one host alias")
+		}
+		sshConfig, host, err := findHost(args[0])
+		if err != nil {
+			return err
+		}
+
+		keyPath := *identity
+		if keyPath == "" {
+			keyPath = host.Identity
+		}
+		if keyPath == "" {
+			keyPath = ssh.DefaultIdentity()
+		}
+		// Log in with the password even if the host has a key already
+		loginHost := host
+		loginHost.Identity = ""
+		if err := copyKey(loginHost, keyPath, *passwordStdin); err != nil {
+			return err
+		}
+
+		if host.Identity == keyPath {
+			infof("Key installed, %s already uses %s\n", host.Name, keyPath)
+			return nil
+		}
+		updated := host
+		updated.Identity = keyPath
+		if err := sshConfig.Backup(); err != nil {
+			return errorf(exitConfig, "key installed, but failed to back up config: %v", err)
+		}
+		sshConfig.UpdateHost(host.Name, updated)
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "key installed, but failed to save config: %v", err)
+		}
+		infof("Key installed, %s now uses %s\n", host.Name, keyPath)
+		return nil
+	}
+	return cmd
+}
+
+// readPasswordStdin reads a password from the first line of stdin
+func readPasswordStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password from stdin: %v", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("no password on stdin")
+	}
+	return password, nil
+}
+
+// promptPassword asks for a password on the terminal without echoing it
+func promptPassword(prompt string) (string, error) {
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("stdin is not a terminal, use --password-stdin")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %v", err)
+	}
+	return string(password), nil
+}
+
+// setIfGiven sets field to value unless the value is empty
+func setIfGiven(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+// removeCommand implements "xssh rm <alias>"
+func removeCommand() *Command {
+	cmd := newCommand("rm", "<alias>", "Remove a host from ~/.ssh/config")
+	cmd.Aliases = []string{"remove"}
+	cmd.Description = `Remove a host from ~/.ssh/config after asking for confirmation, and forget its
+tags, color, hooks and other metadata. The previous config is kept as a backup
+next to it.`
+	yes := cmd.Flags.Bool("yes", false, "remove without asking for confirmation")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("rm takes exactly one host alias")
+		}
+		sshConfig, host, err := findHost(args[0])
+		if err != nil {
+			return err
+		}
+		if !*yes && !confirm(fmt.Sprintf("Remove host '%s' (%s)?", host.Name, host.Host)) {
+			infof("Canceled.\n")
+			return nil
+		}
+
+		if err := sshConfig.Backup(); err != nil {
+			return errorf(exitConfig, "failed to back up config: %v", err)
+		}
+		sshConfig.RemoveHost(host.Name)
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "failed to save config: %v", err)
+		}
+		infof("Removed host '%s', the previous config is in %s\n", host.Name, sshConfig.BackupPath())
+
+		// A host added later under the alias starts without its tags,
+		// hooks and forwardings
+		metadata, err := config.LoadMetadata()
+		if err != nil {
+			return errorf(exitConfig, "host removed, but failed to load host metadata: %v", err)
+		}
+		metadata.RemoveHost(host.Name)
+		if err := metadata.Save(); err != nil {
+			return errorf(exitConfig, "host removed, but failed to save host metadata: %v", err)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// setCommand implements "xssh set <alias>", which changes fields of a host
+func setCommand() *Command {
+	cmd := newCommand("set", "<alias>", "Change fields of a host")
+	cmd.Description = `Change the fields of a host given as options and leave the others as they are.
+An empty value clears an optional field. Renaming a host with --name also
+updates the hosts that jump through it, its tags, notes and history.
+
+--password-ref and --passphrase-ref point the login password and the key
+passphrase of the host at a password manager entry, one of
+` + secrets.Formats + `. xssh keeps only the reference and
+runs op, pass or vault to read the secret each time it connects.
+
+--auto-forward takes forwarding rules, as "xssh forward" does, separated by
+commas. xssh opens them whenever it connects to the host, from the command
+line or the TUI, and closes them when the session ends. They log in with
+keys, the agent or the secret references; "" removes them.`
+	cmd.Examples = []string{
+		"xssh set web1 --port 2222 --user root",
+		"xssh set web1 --jump \"\"              # Connect directly",
+		"xssh set web1 --name web-prod",
+		"xssh set web1 --password-ref op://Infra/web1/password",
+		"xssh set web1 --passphrase-ref pass:ssh/web1",
+		"xssh set db-bastion --auto-forward 5432:db:5432,D:1080",
+	}
+	name := cmd.Flags.String("name", "", "new `alias` of the host")
+	hostName := cmd.Flags.String("host", "", "host name or IP `address`")
+	user := cmd.Flags.String("user", "", "login `user`")
+	port := cmd.Flags.String("port", "", "SSH `port`")
+	identity := cmd.Flags.String("identity", "", "private key `file`")
+	jump := cmd.Flags.String("jump", "", "ProxyJump `hosts`")
+	passwordRef := cmd.Flags.String("password-ref", "", "read the login password from `reference`")
+	passphraseRef := cmd.Flags.String("passphrase-ref", "", "read the key passphrase from `reference`")
+	autoForward := cmd.Flags.String("auto-forward", "", "open the forwarding `rules` while connected")
+
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("set takes exactly one host alias")
+		}
+		given := map[string]bool{}
+		cmd.Flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+		if len(given) == 0 {
+			return cmd.usagef("nothing to change, give at least one option")
+		}
+
+		for _, ref := range []string{"password-ref", "passphrase-ref"} {
+			value := cmd.Flags.Lookup(ref).Value.String()
+			if given[ref] && value != "" {
+				if err := secrets.Check(value); err != nil {
+					return cmd.usagef("--%s: %v", ref, err)
+				}
+			}
+		}
+		autoForwards, err := parseAutoForwards(*autoForward)
+		if err != nil {
+			return cmd.usagef("--auto-forward: %v", err)
+		}
+
+		sshConfig, host, err := findHost(args[0])
+		if err != nil {
+			return err
+		}
+		if given["password-ref"] || given["passphrase-ref"] {
+			if err := setSecretRefs(host.Name, given, *passwordRef, *passphraseRef); err != nil {
+				return err
+			}
+			delete(given, "password-ref")
+			delete(given, "passphrase-ref")
+		}
+		if given["auto-forward"] {
+			if err := setAutoForwards(host.Name, autoForwards); err != nil {
+				return err
+			}
+			delete(given, "auto-forward")
+		}
+		if len(given) == 0 {
+			return nil
+		}
+		updated := host
+		for _, field := range []struct {
+			flag  string
+			value *string
+			set   *string
+		}{
+			{"name", name, &updated.Name},
+			{"host", hostName, &updated.Host},
+			{"user", user, &updated.User},
+			{"port", port, &updated.Port},
+			{"identity", identity, &updated.Identity},
+			{"jump", jump, &updated.ProxyJump},
+		} {
+			if given[field.flag] {
+				*field.set = *field.value
+			}
+		}
+		if updated.Port == "" {
+			updated.Port = "22"
+		}
+		if err := updated.Validate(); err != nil {
+			return fmt.Errorf("cannot update host: %v", err)
+		}
+		renamed := updated.Name != host.Name
+		if renamed && sshConfig.FindHost(updated.Name) >= 0 {
+			return fmt.Errorf("host '%s' already exists", updated.Name)
+		}
+
+		if err := sshConfig.Backup(); err != nil {
+			return errorf(exitConfig, "failed to back up config: %v", err)
+		}
+		sshConfig.UpdateHost(host.Name, updated)
+		if renamed {
+			sshConfig.RenameJumpHost(host.Name, updated.Name)
+		}
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "failed to save config: %v", err)
+		}
+
+		infof("Updated host '%s'\n", updated.Name)
+		if renamed {
+			infof("  Alias: %s -> %s\n", host.Name, updated.Name)
+		}
+		for _, diff := range config.DiffHosts(host, updated) {
+			if diff.Changed() {
+				infof("  %s: %s -> %s\n", diff.Field, displayValue(diff.Old), displayValue(diff.New))
+			}
+		}
+		if renamed {
+			return renameReferences(host.Name, updated.Name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// setSecretRefs saves the secret references of the given flags for a host
+func setSecretRefs(name string, given map[string]bool, passwordRef, passphraseRef string) error {
+	metadata, err := config.LoadMetadata()
+	if err != nil {
+		return errorf(exitConfig, "failed to load metadata: %v", err)
+	}
+	oldPassword, oldPassphrase := metadata.SecretRefs(name)
+	infof("Updated secret references of '%s'\n", name)
+	if given["password-ref"] {
+		metadata.SetPasswordRef(name, passwordRef)
+		infof("  Password: %s -> %s\n", displayValue(oldPassword), displayValue(passwordRef))
+	}
+	if given["passphrase-ref"] {
+		metadata.SetPassphraseRef(name, passphraseRef)
+		infof("  Passphrase: %s -> %s\n", displayValue(oldPassphrase), displayValue(passphraseRef))
+	}
+	if err := metadata.Save(); err != nil {
+		return errorf(exitConfig, "failed to save metadata: %v", err)
+	}
+	return nil
+}
+
+// displayValue shows an empty field as "(none)"
+func displayValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// renameReferences moves the metadata, connection history and config file
+// references of a host to its new alias, as renaming in the TUI does
+func renameReferences(oldName, newName string) error {
+	metadata, err := config.LoadMetadata()
+	if err != nil {
+		return errorf(exitConfig, "host renamed, but failed to load host metadata: %v", err)
+	}
+	metadata.RenameHost(oldName, newName)
+	if err := metadata.Save(); err != nil {
+		return errorf(exitConfig, "host renamed, but failed to save host metadata: %v", err)
+	}
+	if err := config.RenameConnectionHistory(oldName, newName); err != nil {
+		return fmt.Errorf("host renamed, but failed to update connection history: %v", err)
+	}
+	if err := config.RenameConfigHost(oldName, newName); err != nil {
+		return errorf(exitConfig, "host renamed, but failed to update the tunnels and bastions of config.toml: %v", err)
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// configCommand implements "xssh config path|show|edit"
+func configCommand() *Command {
+	cmd := newCommand("config", "path | show | edit", "Show or edit the xssh config file")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Work with xssh's own config file (~/.config/xssh/config.toml):
+
+  path   Print the location of the file
+  show   Print the file
+  edit   Open the file in $EDITOR`
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("config takes one of path, show or edit")
+		}
+		configPath, err := config.AppConfigPath()
+		if err != nil {
+			return err
+		}
+
+		switch args[0] {
+		case "path":
+			fmt.Println(configPath)
+		case "show":
+			data, err := os.ReadFile(configPath)
+			if os.IsNotExist(err) {
+				if !quiet {
+					fmt.Fprintf(os.Stderr, "%s does not exist, the defaults are used\n", configPath)
+				}
+				return nil
+			} else if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+		case "edit":
+			editor := os.Getenv("EDITOR")
+			if editor == "" {
+				editor = "vi"
+			}
+			if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+				return err
+			}
+			edit := exec.Command(editor, configPath)
+			edit.Stdin, edit.Stdout, edit.Stderr = os.Stdin, os.Stdout, os.Stderr
+			return edit.Run()
+		default:
+			return cmd.usagef("unknown config action: %s", args[0])
+		}
+		return nil
+	}
+	return cmd
+}
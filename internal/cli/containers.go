@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// containersCommand implements "xssh containers <alias>"
+func containersCommand() *Command {
+	cmd := newCommand("containers", "<alias>", "List the running Docker containers of a host")
+	cmd.Description = `List the running Docker containers of a host, running "docker ps" over ssh
+without prompting. Open a shell in one with "xssh connect <alias> --container
+<name>", or with the i key of the TUI.`
+	cmd.Examples = []string{
+		"xssh containers web1",
+		"xssh containers --json web1",
+		"xssh connect web1 --container api --shell bash",
+	}
+	asJSON := cmd.Flags.Bool("json", false, "print the containers as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("containers takes exactly one host alias")
+		}
+		host, err := resolveHost(args[0])
+		if err != nil {
+			return err
+		}
+		containers, err := listContainers(host)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			if containers == nil {
+				containers = []ssh.Container{}
+			}
+			return printJSON(containers)
+		}
+		if len(containers) == 0 {
+			infof("No running containers on %s\n", host.Name)
+			return nil
+		}
+		for _, container := range containers {
+			fmt.Printf("%-14s %-24s %-30s %s\n", shortContainerID(container.ID), container.Name, container.Image, container.Status)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// listContainers runs "docker ps" on a host with ssh, without prompting
+func listContainers(host config.SSHHost) ([]ssh.Container, error) {
+	cmd, err := ssh.Command(host)
+	if err != nil {
+		return nil, err
+	}
+	// Options must come before the destination, the command after it
+	destination := cmd.Args[len(cmd.Args)-1]
+	cmd.Args = append(append(cmd.Args[:len(cmd.Args)-1], "-o", "BatchMode=yes", destination), ssh.ListContainersCommand)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("docker ps on %s failed: %s", host.Name, message)
+		}
+		return nil, fmt.Errorf("docker ps on %s failed: %v", host.Name, err)
+	}
+	return ssh.ParseContainers(output)
+}
+
+// shortContainerID shortens a container ID as docker ps does
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// containerShellArgs returns the ssh arguments opening a shell in the
+// container of --container, or extra when none is given
+func containerShellArgs(container, shell string, extra []string) ([]string, error) {
+	if container == "" {
+		return extra, nil
+	}
+	if len(extra) > 0 {
+		return nil, errorf(exitUsage, "--container cannot be combined with ssh arguments")
+	}
+	for _, word := range []string{container, shell} {
+		if strings.ContainsAny(word, " '\"`$;&|<>") {
+			return nil, errorf(exitUsage, "invalid container or shell name %q", word)
+		}
+	}
+	return ssh.ContainerShellArgs(container, shell), nil
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"xssh/internal/crash"
+)
+
+// crashReport remembers the report written for a panic of the TUI, which
+// Bubbletea turns into an error once it has restored the terminal
+var crashReport struct {
+	sync.Mutex
+	path string
+}
+
+// writeCrashReport writes the report of a recovered panic and logs where
+func writeCrashReport(component string, value any) string {
+	path, err := crash.Write(component, value, debug.Stack())
+	if err != nil {
+		slog.Error("failed to write crash report", "component", component, "error", err)
+		return ""
+	}
+	slog.Error("crashed", "component", component, "panic", fmt.Sprint(value), "report", path)
+	return path
+}
+
+// crashGuard wraps the TUI model so a panic in it, or in a command it
+// returns, is written to a crash report before Bubbletea restores the
+// terminal
+type crashGuard struct {
+	tea.Model
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	defer recoverTUI()
+	return guardCmd(g.Model.Init())
+}
+
+func (g crashGuard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer recoverTUI()
+	model, cmd := g.Model.Update(msg)
+	return crashGuard{model}, guardCmd(cmd)
+}
+
+func (g crashGuard) View() string {
+	defer recoverTUI()
+	return g.Model.View()
+}
+
+// guardCmd runs cmd under recoverTUI, as Bubbletea runs commands in
+// goroutines of their own
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer recoverTUI()
+		return cmd()
+	}
+}
+
+// recoverTUI writes the report of a panic of the TUI and panics again, so
+// Bubbletea restores the terminal and returns ErrProgramPanic
+func recoverTUI() {
+	value := recover()
+	if value == nil {
+		return
+	}
+	crashReport.Lock()
+	if crashReport.path == "" {
+		crashReport.path = writeCrashReport("tui", value)
+	}
+	crashReport.Unlock()
+	panic(value)
+}
+
+// tuiCrashError describes the error of a TUI that panicked
+func tuiCrashError(err error) error {
+	crashReport.Lock()
+	path := crashReport.path
+	crashReport.Unlock()
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("xssh crashed, a report was written to %s\nPlease attach it to a bug report, with the output of 'xssh debug-bundle'", path)
+}
+
+// recoverDaemon writes the report of a panic of the daemon and panics again
+func recoverDaemon() {
+	value := recover()
+	if value == nil {
+		return
+	}
+	if path := writeCrashReport("daemon", value); path != "" {
+		fmt.Fprintf(os.Stderr, "xssh daemon crashed, a report was written to %s\n", path)
+	}
+	panic(value)
+}
+
+// recoverHandler writes the report of a panic in an API handler and answers
+// with an error, keeping the daemon and its tunnels running
+func recoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				// net/http's way of dropping the connection
+				panic(value)
+			}
+			writeCrashReport("daemon", value)
+			writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("internal error: %v", value))
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,856 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+	"xssh/internal/version"
+)
+
+// daemonCommand implements "xssh daemon"
+func daemonCommand() *Command {
+	cmd := newCommand("daemon", "[run | status | reload | stop | token | dashboard]", "Run the xssh daemon and its control API")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Run xssh as a daemon that keeps port forwardings open and can be driven over
+a local HTTP API, by editor plugins, launchers and scripts:
+
+  run        Run the daemon in the foreground until it is stopped (the default)
+  status     Print whether the daemon runs, its PID and its tunnels
+  reload     Re-read config.toml without dropping the tunnels it leaves as they are
+  stop       Stop the daemon and the forwardings it holds
+  token      Print the token clients authenticate with
+  dashboard  Print the address of the web dashboard, logging the browser in
+
+The API listens on the socket set by [daemon] socket in config.toml,
+~/.config/xssh/daemon.sock by default, and also on the TCP address of
+[daemon] listen when it is set. Every request must carry the token in
+~/.config/xssh/daemon.token as "Authorization: Bearer <token>".
+
+  GET    /v1/status                 Daemon PID, version and start time
+  GET    /v1/hosts                  Configured hosts, as "xssh list --json"
+  POST   /v1/hosts/{alias}/connect  Open a terminal connected to the host
+  GET    /v1/terminals              Terminals opened by connect that are still open
+  DELETE /v1/terminals/{id}         Hang up a terminal and its connection
+  GET    /v1/tunnels                Forwardings, as "xssh forward list --json"; ?filter=label=dev
+                                    lists those matching, as its --filter
+  POST   /v1/tunnels                Start a forwarding: {"host": "bastion", "rule": "8080:db:5432"}
+                                    or {"host": "bastion", "type": "L", "spec": "8080:db:5432"},
+                                    optionally with "tls", "resolve": "local",
+                                    "expire": "2h" or "18:00", "via": ["bastion1"],
+                                    "capture": "all", "headers" or "64k",
+                                    "port_retries": 10 and "labels": ["dev"]
+  DELETE /v1/tunnels/{pattern}      Stop forwardings by ID, glob pattern or "all"
+  POST   /v1/tunnels/{id}/restart   Reopen a forwarding of the daemon
+  POST   /v1/tunnels/{pattern}/renew
+                                    Push back the expiry of forwardings by ID,
+                                    glob pattern or "all"
+  POST   /v1/tunnels/{id}/capture   Dump the traffic of a forwarding of the daemon to a file:
+                                    {"capture": "headers"}, or an empty body for all
+  DELETE /v1/tunnels/{id}/capture   Stop dumping its traffic
+  GET    /v1/traffic                Throughput samples of the last hour, by forwarding
+  GET    /v1/schedule               The [tunnels] profiles, whether they run and when
+                                    they next start or stop
+  POST   /v1/reload                 Re-read config.toml, as "xssh daemon reload"
+  POST   /v1/shutdown               Stop the daemon
+
+The daemon also opens the forwardings of the [tunnels.<name>] sections of
+config.toml, each with a host, a rule as in "xssh forward <rule> <alias>" and
+optional windows such as ["Mon-Fri 09:00-18:00"]: a forwarding with windows
+is started when one opens and stopped when it closes, and one without runs
+as long as the daemon. "xssh forward list" shows when they next start or
+stop.
+
+"xssh daemon reload", SIGHUP or POST /v1/reload re-read config.toml while
+the daemon runs. New [tunnels] profiles start, the forwardings of removed
+ones stop, and those whose host, rule, via or labels changed are reopened;
+the other tunnels stay open. Settings such as keepalives, [forwarding] and
+[daemon] terminal apply from then on, while [daemon] socket, listen,
+dashboard and health take a restart. A config that fails to load is
+reported and the daemon keeps the one it had.
+
+With [daemon] dashboard = true the daemon also serves a web page at
+/dashboard showing its forwardings with their throughput, connections and
+errors, with buttons to stop and restart them. Browsers cannot use the
+socket, so the dashboard is served on [daemon] listen, 127.0.0.1:7878 unless
+set; "xssh daemon dashboard" prints its address.
+
+With [daemon] health = "127.0.0.1:7879" the daemon serves the health of its
+forwardings on that address, without the token, for tools to wait on a
+tunnel: GET /health checks all of them and GET /health/<id> one, answering
+200 when the SSH connection answers and the target accepts a connection,
+and 503 otherwise.
+
+Errors are returned as {"error": ..., "kind": ..., "code": ...} with the
+kinds and codes of the exit codes. Connecting needs [daemon] terminal, the
+command opening a terminal window, such as "kitty" or "wezterm start --".`
+	cmd.Examples = []string{
+		"xssh daemon",
+		"xssh daemon status",
+		"xssh daemon reload",
+		`curl --unix-socket ~/.config/xssh/daemon.sock -H "Authorization: Bearer $(xssh daemon token)" http://xssh/v1/tunnels`,
+	}
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.usagef("daemon takes one of run, status, reload, stop, token or dashboard")
+		}
+		action := "run"
+		if len(args) == 1 {
+			action = args[0]
+		}
+		switch action {
+		case "run":
+			return runDaemon()
+		case "status":
+			return daemonStatus()
+		case "stop":
+			if err := daemonRequest(http.MethodPost, "/v1/shutdown", nil, nil); err != nil {
+				return err
+			}
+			infof("Daemon stopped\n")
+			return nil
+		case "reload":
+			return reloadDaemon()
+		case "dashboard":
+			return printDashboardURL()
+		case "token":
+			token, err := config.DaemonToken(true)
+			if err != nil {
+				return errorf(exitConfig, "cannot read the daemon token: %v", err)
+			}
+			fmt.Println(token)
+			return nil
+		default:
+			return cmd.usagef("unknown daemon action: %s", action)
+		}
+	}
+	return cmd
+}
+
+// daemonServer serves the control API of the daemon
+type daemonServer struct {
+	manager      *forwarding.ForwardingManager
+	token        string
+	mu           sync.Mutex              // Guards config and terminals
+	config       config.DaemonConfig     // [daemon] settings, replaced on reload
+	terminals    map[int]*daemonTerminal // Terminals opened by connect, until they close
+	nextTerminal int
+	reloadMu     sync.Mutex // Serializes reloads
+	web          bool       // Whether the dashboard is served
+	samples      *trafficRecorder
+	scheduler    *tunnelScheduler // Opens the [tunnels] profiles within their windows
+	started      time.Time
+	stop         chan struct{} // Closed when a client asks the daemon to stop
+	stopOnce     sync.Once
+}
+
+// daemonStatusJSON is the state of the daemon as served by /v1/status
+type daemonStatusJSON struct {
+	PID     int          `json:"pid"`
+	Version version.Info `json:"version"`
+	Started time.Time    `json:"started"`
+	Tunnels int          `json:"tunnels"`
+}
+
+// tunnelRequest is the body of POST /v1/tunnels. A forwarding is given
+// either by rule, as in "xssh forward <rule> <alias>", or by type and spec,
+// as in "xssh forward <alias> -L <spec>".
+type tunnelRequest struct {
+	Host        string   `json:"host"`
+	Rule        string   `json:"rule,omitempty"`
+	Type        string   `json:"type,omitempty"` // "L", "R" or "D"
+	Spec        string   `json:"spec,omitempty"`
+	TLS         string   `json:"tls,omitempty"`          // "self-signed" or "cert.pem,key.pem", for local forwardings
+	Resolve     string   `json:"resolve,omitempty"`      // "remote" or "local", for local forwardings
+	Expire      string   `json:"expire,omitempty"`       // Duration such as "2h" or time of day such as "18:00"
+	Via         []string `json:"via,omitempty"`          // Hosts to tunnel through before the host, in order
+	Capture     string   `json:"capture,omitempty"`      // Traffic dump, "all", "headers" or a size such as "64k"
+	PortRetries *int     `json:"port_retries,omitempty"` // Following ports tried when the port is in use, [forwarding] port_retries if unset
+	Labels      []string `json:"labels,omitempty"`       // Labels to filter sessions by, such as "dev"
+	Password    string   `json:"password,omitempty"`
+	KeyPassword string   `json:"key_password,omitempty"`
+}
+
+// connectJSON answers POST /v1/hosts/{alias}/connect
+type connectJSON struct {
+	Terminal int      `json:"terminal"` // ID of the terminal in /v1/terminals
+	Command  []string `json:"command"`  // Command connecting to the host
+}
+
+// runDaemon serves the API until the daemon is stopped by a client or a
+// signal, then stops the forwardings it started. A panic is written to a
+// crash report.
+func runDaemon() error {
+	defer recoverDaemon()
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load xssh config: %v", err)
+	}
+	socketPath, err := appConfig.DaemonSocket()
+	if err != nil {
+		return errorf(exitConfig, "cannot find the daemon socket: %v", err)
+	}
+	token, err := config.DaemonToken(true)
+	if err != nil {
+		return errorf(exitConfig, "cannot create the daemon token: %v", err)
+	}
+
+	listeners, err := daemonListeners(socketPath, daemonAddress(appConfig))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	d := &daemonServer{
+		manager: forwarding.NewManager(),
+		token:   token,
+		config:  appConfig.Daemon,
+		web:     appConfig.Daemon.Dashboard,
+		samples: newTrafficRecorder(),
+		started: time.Now(),
+		stop:    make(chan struct{}),
+	}
+	d.scheduler = newTunnelScheduler(d.manager, appConfig.Tunnels)
+	server := &http.Server{Handler: recoverHandler(d.handler()), ReadHeaderTimeout: 10 * time.Second}
+	for _, listener := range listeners {
+		go server.Serve(listener)
+		infof("xssh daemon listening on %s\n", listener.Addr())
+	}
+	if appConfig.Daemon.Health != "" {
+		healthServer, err := serveHealth(d.manager, appConfig.Daemon.Health)
+		if err != nil {
+			return err
+		}
+		defer healthServer.Close()
+	}
+	// Sampling and scheduling end with the daemon
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer recoverDaemon()
+		d.samples.run(d.manager, stopped)
+	}()
+	go func() {
+		defer recoverDaemon()
+		d.scheduler.run(stopped)
+	}()
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go d.reloadOnSignal(hangups)
+	slog.Info("daemon started", "socket", socketPath, "listen", daemonAddress(appConfig))
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-sigChan:
+	case <-d.stop:
+	}
+	slog.Info("daemon stopping")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Tunnels first, so connecting for a tunnel being started is given up
+	// rather than holding up the API
+	d.manager.Shutdown(ctx)
+	server.Shutdown(ctx)
+	return nil
+}
+
+// daemonAddress returns the TCP address the daemon listens on besides its
+// socket, or an empty string for none
+func daemonAddress(appConfig *config.AppConfig) string {
+	if appConfig.Daemon.Listen == "" && appConfig.Daemon.Dashboard {
+		return defaultDashboardAddress
+	}
+	return appConfig.Daemon.Listen
+}
+
+// daemonListeners opens the control socket, readable by the user only, and
+// the TCP address when one is set. A socket left behind by a daemon that
+// died is replaced; one a running daemon answers on is an error.
+func daemonListeners(socketPath, address string) ([]net.Listener, error) {
+	if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("the xssh daemon is already running on %s", socketPath)
+	}
+	os.Remove(socketPath)
+
+	socket, err := listenPrivate(socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %v", socketPath, err)
+	}
+	listeners := []net.Listener{socket}
+
+	if address != "" {
+		tcp, err := net.Listen("tcp", address)
+		if err != nil {
+			socket.Close()
+			return nil, connectionError(fmt.Errorf("cannot listen on %s: %w", address, err))
+		}
+		listeners = append(listeners, tcp)
+	}
+	return listeners, nil
+}
+
+// handler routes the API requests, all of which must be authenticated with
+// the token, sent in the Authorization header or, by the dashboard, in a
+// cookie
+func (d *daemonServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", d.status)
+	mux.HandleFunc("GET /v1/hosts", d.listHosts)
+	mux.HandleFunc("POST /v1/hosts/{alias}/connect", d.connect)
+	mux.HandleFunc("GET /v1/terminals", d.listTerminals)
+	mux.HandleFunc("DELETE /v1/terminals/{id}", d.closeTerminal)
+	mux.HandleFunc("GET /v1/tunnels", d.listTunnels)
+	mux.HandleFunc("POST /v1/tunnels", d.startTunnel)
+	mux.HandleFunc("DELETE /v1/tunnels/{pattern}", d.stopTunnels)
+	mux.HandleFunc("POST /v1/tunnels/{id}/restart", d.restartTunnel)
+	mux.HandleFunc("POST /v1/tunnels/{pattern}/renew", d.renewTunnels)
+	mux.HandleFunc("POST /v1/tunnels/{id}/capture", d.startCapture)
+	mux.HandleFunc("DELETE /v1/tunnels/{id}/capture", d.stopCapture)
+	mux.HandleFunc("GET /v1/traffic", d.traffic)
+	mux.HandleFunc("GET /v1/clients", d.listClients)
+	mux.HandleFunc("GET /v1/schedule", d.schedule)
+	mux.HandleFunc("POST /v1/reload", d.reloadConfig)
+	mux.HandleFunc("POST /v1/shutdown", d.shutdown)
+	if d.web {
+		mux.HandleFunc("GET /dashboard", d.dashboard)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.web && r.URL.Path == "/dashboard" && d.validToken(r.URL.Query().Get("token")) {
+			d.dashboardLogin(w, r)
+			return
+		}
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if cookie, err := r.Cookie(tokenCookie); token == "" && err == nil {
+			token = cookie.Value
+		}
+		if !d.validToken(token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errorf(exitUsage, "missing or wrong token, see 'xssh daemon token'"))
+			return
+		}
+		slog.Debug("daemon request", "method", r.Method, "path", r.URL.Path)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// validToken reports whether token is the daemon's token
+func (d *daemonServer) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(d.token)) == 1
+}
+
+// status serves GET /v1/status
+func (d *daemonServer) status(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, daemonStatusJSON{
+		PID:     os.Getpid(),
+		Version: version.Get(),
+		Started: d.started,
+		Tunnels: len(d.manager.GetAllSessions()),
+	})
+}
+
+// listHosts serves GET /v1/hosts
+func (d *daemonServer) listHosts(w http.ResponseWriter, r *http.Request) {
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		writeAPIErrorCode(w, errorf(exitConfig, "failed to load SSH config: %v", err))
+		return
+	}
+	metadata, _ := config.LoadMetadata()
+	history, _ := config.LoadConnectionHistory()
+	lastConnections := config.LastConnections(history)
+
+	hosts := make([]hostJSON, 0, len(sshConfig.Hosts))
+	for _, host := range sshConfig.Hosts {
+		hosts = append(hosts, newHostJSON(host, metadata, lastConnections[host.Name]))
+	}
+	writeAPIJSON(w, http.StatusOK, hosts)
+}
+
+// connect serves POST /v1/hosts/{alias}/connect, opening a terminal that
+// runs "xssh connect <alias>"
+func (d *daemonServer) connect(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+	if _, err := resolveHost(alias); err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	d.mu.Lock()
+	terminalCommand := d.config.Terminal
+	d.mu.Unlock()
+	if terminalCommand == "" {
+		writeAPIErrorCode(w, errorf(exitConfig, "set [daemon] terminal in config.toml to open connections"))
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		writeAPIErrorCode(w, fmt.Errorf("cannot find the xssh binary: %v", err))
+		return
+	}
+
+	command := append(strings.Fields(terminalCommand), executable, "connect", alias)
+	terminal := exec.Command(command[0], command[1:]...)
+	detachProcess(terminal)
+	if err := terminal.Start(); err != nil {
+		writeAPIErrorCode(w, fmt.Errorf("cannot open a terminal: %v", err))
+		return
+	}
+	id := d.trackTerminal(alias, command, terminal)
+	slog.Info("daemon opened a connection", "host", alias, "command", command, "terminal", id)
+	writeAPIJSON(w, http.StatusAccepted, connectJSON{Terminal: id, Command: command})
+}
+
+// listTunnels serves GET /v1/tunnels: the forwardings of the daemon and the
+// background forwardings of "xssh forward --background", those matching the
+// filter parameter when given
+func (d *daemonServer) listTunnels(w http.ResponseWriter, r *http.Request) {
+	filter := forwarding.ParseSessionFilter(r.URL.Query().Get("filter"))
+	tunnels := []sessionJSON{}
+	for _, session := range keepMatching(filter, d.manager.GetAllSessions(), sessionFilterFields) {
+		tunnel := newSessionJSON(session)
+		tunnel.Host = session.Host().Name
+		tunnels = append(tunnels, tunnel)
+	}
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	for _, session := range keepMatching(filter, backgroundSessions, backgroundFilterFields) {
+		tunnels = append(tunnels, newBackgroundSessionJSON(session))
+	}
+	writeAPIJSON(w, http.StatusOK, tunnels)
+}
+
+// listClients serves GET /v1/clients, the SSH connections the daemon's
+// tunnels share
+func (d *daemonServer) listClients(w http.ResponseWriter, r *http.Request) {
+	clients := []clientJSON{}
+	for _, client := range d.manager.Clients() {
+		clients = append(clients, newClientJSON(client))
+	}
+	writeAPIJSON(w, http.StatusOK, clients)
+}
+
+// startTunnel serves POST /v1/tunnels
+func (d *daemonServer) startTunnel(w http.ResponseWriter, r *http.Request) {
+	var request tunnelRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&request); err != nil {
+		writeAPIErrorCode(w, errorf(exitUsage, "invalid request: %v", err))
+		return
+	}
+	rule, err := request.rule()
+	if err != nil {
+		writeAPIErrorCode(w, errorf(exitUsage, "invalid forwarding rule: %v", err))
+		return
+	}
+	host, err := resolveHost(request.Host)
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+
+	// IDs must not clash with running sessions, here or in the background
+	taken := map[string]bool{}
+	for _, session := range d.manager.GetAllSessions() {
+		taken[session.Rule.ID] = true
+	}
+	backgroundSessions, _ := forwarding.BackgroundSessions()
+	for _, session := range backgroundSessions {
+		taken[session.Rule.ID] = true
+	}
+	base := rule.ID
+	for n := 2; taken[rule.ID]; n++ {
+		rule.ID = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	auth := ssh.Auth{Password: request.Password, KeyPassword: request.KeyPassword}
+	if auth.Password == "" && auth.KeyPassword == "" {
+		// Fall back on the secrets referenced in the host's metadata
+		if auth, err = referencedAuth(host); err != nil {
+			writeAPIErrorCode(w, errorf(exitAuth, "%v", err))
+			return
+		}
+	}
+	session, err := d.manager.StartForwarding(r.Context(), rule, host, auth)
+	if err != nil {
+		writeAPIErrorCode(w, connectionError(fmt.Errorf("failed to start port forwarding %s: %w", rule.Description, err)))
+		return
+	}
+	tunnel := newSessionJSON(session)
+	tunnel.Host = host.Name
+	writeAPIJSON(w, http.StatusCreated, tunnel)
+}
+
+// rule builds the forwarding rule of the request, with a new session ID
+func (t tunnelRequest) rule() (forwarding.ForwardingRule, error) {
+	if t.Host == "" {
+		return forwarding.ForwardingRule{}, errors.New("host is required")
+	}
+	var rule forwarding.ForwardingRule
+	switch {
+	case t.Rule != "" && t.Spec == "":
+		parsed, err := parseForwardingRule(t.Rule)
+		if err != nil {
+			return rule, err
+		}
+		rule = *parsed
+	case t.Rule == "" && t.Spec != "":
+		types := map[string]forwarding.ForwardingType{
+			"L": forwarding.LocalForward,
+			"R": forwarding.RemoteForward,
+			"D": forwarding.DynamicForward,
+		}
+		forwardingType, ok := types[strings.ToUpper(strings.TrimPrefix(t.Type, "-"))]
+		if !ok {
+			return rule, fmt.Errorf("type must be L, R or D, got %q", t.Type)
+		}
+		parsed, err := forwarding.ParseSpec(forwardingType, t.Spec)
+		if err != nil {
+			return rule, err
+		}
+		rule = parsed
+	default:
+		return rule, errors.New("give either rule, or type and spec")
+	}
+	if t.TLS != "" {
+		if rule.Type != forwarding.LocalForward {
+			return rule, errors.New("tls applies to local forwardings only")
+		}
+		listenerTLS, err := forwarding.ParseListenerTLS(t.TLS)
+		if err != nil {
+			return rule, err
+		}
+		rule.TLS = listenerTLS
+	}
+	resolveLocally, err := forwarding.ParseResolve(t.Resolve)
+	if err != nil {
+		return rule, err
+	}
+	if resolveLocally && rule.Type != forwarding.LocalForward {
+		return rule, errors.New("resolve applies to local forwardings only")
+	}
+	rule.ResolveLocally = resolveLocally
+	expiry, err := forwarding.ParseExpiry(t.Expire)
+	if err != nil {
+		return rule, err
+	}
+	rule.Expiry = expiry
+	rule.Via, err = forwarding.ParseVia(strings.Join(t.Via, ","))
+	if err != nil {
+		return rule, err
+	}
+	rule.Capture, err = forwarding.ParseCapture(t.Capture)
+	if err != nil {
+		return rule, err
+	}
+	rule.Labels, err = forwarding.ParseLabels(strings.Join(t.Labels, ","))
+	if err != nil {
+		return rule, err
+	}
+	rule.PortRetries = forwarding.DefaultPortRetries
+	if t.PortRetries != nil {
+		if *t.PortRetries < 0 {
+			return rule, fmt.Errorf("port_retries must be zero or positive, got %d", *t.PortRetries)
+		}
+		rule.PortRetries = *t.PortRetries
+	}
+
+	port := rule.LocalPort
+	if rule.Type.ListensRemotely() {
+		port = rule.RemotePort
+	}
+	rule.ID = fmt.Sprintf("%s-%d-%d", rule.Type.String(), port, time.Now().Unix())
+	return rule, nil
+}
+
+// stopTunnels serves DELETE /v1/tunnels/{pattern}
+func (d *daemonServer) stopTunnels(w http.ResponseWriter, r *http.Request) {
+	pattern := r.PathValue("pattern")
+	stopped, err := d.manager.StopMatching(pattern)
+	if err != nil {
+		writeAPIErrorCode(w, errorf(exitUsage, "%v", err))
+		return
+	}
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	for _, session := range backgroundSessions {
+		if matched, _ := forwarding.MatchID(pattern, session.Rule.ID); !matched {
+			continue
+		}
+		if err := session.Stop(); err != nil {
+			writeAPIErrorCode(w, err)
+			return
+		}
+		stopped = append(stopped, session.Rule.ID)
+	}
+	if len(stopped) == 0 && pattern != "all" {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no forwarding session matches '%s'", pattern))
+		return
+	}
+	if stopped == nil {
+		stopped = []string{}
+	}
+	writeAPIJSON(w, http.StatusOK, map[string][]string{"stopped": stopped})
+}
+
+// renewTunnels serves POST /v1/tunnels/{pattern}/renew, which pushes back
+// the expiry of the matching tunnels, of the daemon or in the background
+func (d *daemonServer) renewTunnels(w http.ResponseWriter, r *http.Request) {
+	pattern := r.PathValue("pattern")
+	renewed, err := d.manager.RenewMatching(pattern)
+	if err != nil {
+		writeAPIErrorCode(w, errorf(exitUsage, "%v", err))
+		return
+	}
+	backgroundSessions, err := forwarding.BackgroundSessions()
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	for _, session := range backgroundSessions {
+		if matched, _ := forwarding.MatchID(pattern, session.Rule.ID); !matched || session.Rule.Expiry == nil {
+			continue
+		}
+		if err := session.Renew(); err != nil {
+			writeAPIErrorCode(w, err)
+			return
+		}
+		renewed = append(renewed, session.Rule.ID)
+	}
+	if len(renewed) == 0 && pattern != "all" {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no expiring forwarding session matches '%s'", pattern))
+		return
+	}
+	if renewed == nil {
+		renewed = []string{}
+	}
+	writeAPIJSON(w, http.StatusOK, map[string][]string{"renewed": renewed})
+}
+
+// captureRequest is the body of POST /v1/tunnels/{id}/capture
+type captureRequest struct {
+	Capture string `json:"capture,omitempty"` // "all", "headers" or a size such as "64k"; empty for all
+}
+
+// captureJSON answers the capture requests with the dump file of a tunnel
+type captureJSON struct {
+	ID   string `json:"id"`
+	File string `json:"file,omitempty"`
+}
+
+// startCapture serves POST /v1/tunnels/{id}/capture, which dumps the traffic
+// of a tunnel of the daemon to a file
+func (d *daemonServer) startCapture(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	session, found := d.manager.GetSession(id)
+	if !found {
+		writeAPIError(w, http.StatusNotFound, errorf(exitFailure, "the daemon holds no forwarding session %s", id))
+		return
+	}
+	var request captureRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&request); err != nil && err != io.EOF {
+		writeAPIErrorCode(w, errorf(exitUsage, "invalid request: %v", err))
+		return
+	}
+	options := &forwarding.Capture{}
+	if request.Capture != "" {
+		var err error
+		if options, err = forwarding.ParseCapture(request.Capture); err != nil {
+			writeAPIErrorCode(w, errorf(exitUsage, "%v", err))
+			return
+		}
+	}
+	path, err := session.StartCapture(*options)
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, captureJSON{ID: id, File: path})
+}
+
+// stopCapture serves DELETE /v1/tunnels/{id}/capture
+func (d *daemonServer) stopCapture(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	session, found := d.manager.GetSession(id)
+	if !found {
+		writeAPIError(w, http.StatusNotFound, errorf(exitFailure, "the daemon holds no forwarding session %s", id))
+		return
+	}
+	path := session.StopCapture()
+	if path == "" {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("forwarding session %s is not capturing", id))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, captureJSON{ID: id, File: path})
+}
+
+// shutdown serves POST /v1/shutdown
+func (d *daemonServer) shutdown(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+// writeAPIJSON answers a request with v as JSON
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// apiStatuses maps exit codes to the HTTP status of an API error
+var apiStatuses = map[int]int{
+	exitUsage:        http.StatusBadRequest,
+	exitConfig:       http.StatusInternalServerError,
+	exitHostNotFound: http.StatusNotFound,
+	exitAuth:         http.StatusBadGateway,
+	exitHostKey:      http.StatusBadGateway,
+	exitDNS:          http.StatusBadGateway,
+	exitUnreachable:  http.StatusBadGateway,
+	exitTimeout:      http.StatusGatewayTimeout,
+	exitPortInUse:    http.StatusConflict,
+}
+
+// writeAPIErrorCode answers a request with err, the HTTP status following
+// from its exit code
+func writeAPIErrorCode(w http.ResponseWriter, err error) {
+	status, ok := apiStatuses[exitCode(err)]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeAPIError(w, status, err)
+}
+
+// writeAPIError answers a request with err as an errorJSON
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	code := exitCode(err)
+	writeAPIJSON(w, status, errorJSON{Error: err.Error(), Kind: exitKinds[code], Code: code})
+}
+
+// daemonRequest sends a request to the running daemon over its socket and
+// decodes the JSON answer into out, unless out is nil. Errors of the API
+// keep their exit codes; a daemon that cannot be reached, or is turned off
+// with XSSH_NO_DAEMON, is exitDaemon.
+func daemonRequest(method, path string, body, out any) error {
+	if config.NoDaemon() {
+		return errorf(exitDaemon, "the xssh daemon is not used while %s is set", config.EnvNoDaemon)
+	}
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load xssh config: %v", err)
+	}
+	socketPath, err := appConfig.DaemonSocket()
+	if err != nil {
+		return errorf(exitConfig, "cannot find the daemon socket: %v", err)
+	}
+	token, err := config.DaemonToken(false)
+	if err != nil {
+		return errorf(exitDaemon, "the xssh daemon is not running (start it with 'xssh daemon')")
+	}
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	// The host is ignored, requests go to the socket
+	request, err := http.NewRequest(method, "http://xssh"+path, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Timeout: time.Minute,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return errorf(exitDaemon, "the xssh daemon is not running on %s (start it with 'xssh daemon')", socketPath)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 400 {
+		var apiErr errorJSON
+		if json.NewDecoder(response.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			return errorf(exitDaemon, "the xssh daemon answered %s", response.Status)
+		}
+		return codedError{code: apiErr.Code, err: errors.New(apiErr.Error)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// printDashboardURL prints the address of the dashboard, with the token
+// that logs the browser in
+func printDashboardURL() error {
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load xssh config: %v", err)
+	}
+	if !appConfig.Daemon.Dashboard {
+		return errorf(exitConfig, "the dashboard is off, set [daemon] dashboard = true in config.toml and restart the daemon")
+	}
+	token, err := config.DaemonToken(false)
+	if err != nil {
+		return errorf(exitDaemon, "the xssh daemon is not running (start it with 'xssh daemon')")
+	}
+	fmt.Printf("http://%s/dashboard?token=%s\n", daemonAddress(appConfig), url.QueryEscape(token))
+	return nil
+}
+
+// daemonStatus prints the state of the daemon and its tunnels
+func daemonStatus() error {
+	var status daemonStatusJSON
+	if err := daemonRequest(http.MethodGet, "/v1/status", nil, &status); err != nil {
+		return err
+	}
+	fmt.Printf("xssh daemon %s running, PID %d, up %v\n",
+		status.Version.Version, status.PID, time.Since(status.Started).Round(time.Second))
+
+	var tunnels []sessionJSON
+	if err := daemonRequest(http.MethodGet, "/v1/tunnels", nil, &tunnels); err != nil {
+		return err
+	}
+	for _, tunnel := range tunnels {
+		if !tunnel.Background {
+			fmt.Printf("  %s  %s  via %s\n", tunnel.ID, tunnel.Description, tunnel.Host)
+		}
+	}
+	return nil
+}
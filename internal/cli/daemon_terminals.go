@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xssh/internal/ui"
+)
+
+// daemonTerminal is a terminal the daemon opened to connect to a host
+type daemonTerminal struct {
+	id      int
+	host    string
+	command []string
+	started time.Time
+	process *exec.Cmd
+}
+
+// terminalJSON is a terminal of the daemon as served by /v1/terminals
+type terminalJSON struct {
+	ID      int       `json:"id"`
+	Host    string    `json:"host"`
+	Command []string  `json:"command"`
+	Started time.Time `json:"started"`
+	PID     int       `json:"pid"`
+}
+
+// trackTerminal registers a started terminal until it closes and returns
+// its ID
+func (d *daemonServer) trackTerminal(host string, command []string, process *exec.Cmd) int {
+	d.mu.Lock()
+	d.nextTerminal++
+	terminal := &daemonTerminal{
+		id:      d.nextTerminal,
+		host:    host,
+		command: command,
+		started: time.Now(),
+		process: process,
+	}
+	if d.terminals == nil {
+		d.terminals = map[int]*daemonTerminal{}
+	}
+	d.terminals[terminal.id] = terminal
+	d.mu.Unlock()
+
+	// Reap the terminal when it closes
+	go func() {
+		process.Wait()
+		d.mu.Lock()
+		delete(d.terminals, terminal.id)
+		d.mu.Unlock()
+		slog.Info("daemon terminal closed", "id", terminal.id, "host", host)
+	}()
+	return terminal.id
+}
+
+// listTerminals serves GET /v1/terminals: the terminals the daemon opened
+// that are still open, oldest first
+func (d *daemonServer) listTerminals(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	terminals := []terminalJSON{}
+	for _, terminal := range d.terminals {
+		terminals = append(terminals, terminalJSON{
+			ID:      terminal.id,
+			Host:    terminal.host,
+			Command: terminal.command,
+			Started: terminal.started,
+			PID:     terminal.process.Process.Pid,
+		})
+	}
+	d.mu.Unlock()
+	sort.Slice(terminals, func(i, j int) bool { return terminals[i].ID < terminals[j].ID })
+	writeAPIJSON(w, http.StatusOK, terminals)
+}
+
+// closeTerminal serves DELETE /v1/terminals/{id}, hanging up the terminal
+// and the connection running in it
+func (d *daemonServer) closeTerminal(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	d.mu.Lock()
+	terminal, found := d.terminals[id]
+	d.mu.Unlock()
+	if err != nil || !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("the daemon holds no terminal %s", r.PathValue("id")))
+		return
+	}
+	// The terminal leads its own session, so its group holds the connection
+	if err := hangUpGroup(terminal.process.Process.Pid); err != nil {
+		writeAPIErrorCode(w, fmt.Errorf("cannot close terminal %d: %v", id, err))
+		return
+	}
+	slog.Info("daemon terminal hung up", "id", id, "host", terminal.host)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// daemonTerminals shows the terminals of the daemon in the sessions view of
+// the TUI. Without the daemon there are none.
+type daemonTerminals struct{}
+
+// Terminals implements ui.TerminalSource
+func (daemonTerminals) Terminals() ([]ui.Terminal, error) {
+	var listed []terminalJSON
+	if err := daemonRequest(http.MethodGet, "/v1/terminals", nil, &listed); err != nil {
+		if exitCode(err) == exitDaemon {
+			return nil, nil
+		}
+		return nil, err
+	}
+	terminals := make([]ui.Terminal, 0, len(listed))
+	for _, terminal := range listed {
+		terminals = append(terminals, ui.Terminal{
+			ID:      terminal.ID,
+			Host:    terminal.Host,
+			Command: strings.Join(terminal.Command, " "),
+			Started: terminal.Started,
+		})
+	}
+	return terminals, nil
+}
+
+// CloseTerminal implements ui.TerminalSource
+func (daemonTerminals) CloseTerminal(id int) error {
+	return daemonRequest(http.MethodDelete, "/v1/terminals/"+strconv.Itoa(id), nil, nil)
+}
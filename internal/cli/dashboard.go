@@ -0,0 +1,167 @@
+package cli
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"xssh/internal/forwarding"
+)
+
+// dashboardPage is the web dashboard served by the daemon at /dashboard
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// defaultDashboardAddress is where the daemon listens when the dashboard is
+// on and [daemon] listen is not set, as browsers cannot use the socket
+const defaultDashboardAddress = "127.0.0.1:7878"
+
+// tokenCookie carries the daemon token for the dashboard in the browser
+const tokenCookie = "xssh_token"
+
+// trafficInterval is how often the daemon samples the traffic of its tunnels
+const trafficInterval = 5 * time.Second
+
+// maxTrafficSamples is the number of samples kept per tunnel, an hour
+const maxTrafficSamples = 720
+
+// trafficSample is the traffic of a tunnel during one sampling interval
+type trafficSample struct {
+	Time              time.Time `json:"time"`
+	ReceivedPerSecond float64   `json:"received_per_second"`
+	SentPerSecond     float64   `json:"sent_per_second"`
+	ActiveConnections int64     `json:"active_connections"`
+	ErrorCount        int64     `json:"error_count"`
+}
+
+// trafficRecorder keeps recent traffic samples of the daemon's tunnels
+type trafficRecorder struct {
+	mu       sync.Mutex
+	samples  map[string][]trafficSample // Oldest first, by session ID
+	received map[string]int64           // Counters at the previous sample
+	sent     map[string]int64
+	last     time.Time
+}
+
+// newTrafficRecorder returns a recorder without samples
+func newTrafficRecorder() *trafficRecorder {
+	return &trafficRecorder{
+		samples:  map[string][]trafficSample{},
+		received: map[string]int64{},
+		sent:     map[string]int64{},
+	}
+}
+
+// run samples the tunnels of manager until stop is closed
+func (t *trafficRecorder) run(manager *forwarding.ForwardingManager, stop <-chan struct{}) {
+	ticker := time.NewTicker(trafficInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			t.sample(manager.GetAllSessions(), now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// sample adds a sample for every session and forgets the tunnels that have
+// stopped
+func (t *trafficRecorder) sample(sessions []*forwarding.ForwardingSession, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	live := map[string]bool{}
+	for _, session := range sessions {
+		id := session.Rule.ID
+		live[id] = true
+		received := atomic.LoadInt64(&session.Stats.BytesReceived)
+		sent := atomic.LoadInt64(&session.Stats.BytesSent)
+		sample := trafficSample{
+			Time:              now,
+			ActiveConnections: atomic.LoadInt64(&session.Stats.ActiveConnections),
+			ErrorCount:        atomic.LoadInt64(&session.Stats.ErrorCount),
+		}
+		if previous, ok := t.received[id]; ok && !t.last.IsZero() {
+			elapsed := now.Sub(t.last).Seconds()
+			sample.ReceivedPerSecond = float64(received-previous) / elapsed
+			sample.SentPerSecond = float64(sent-t.sent[id]) / elapsed
+		}
+		t.received[id], t.sent[id] = received, sent
+
+		samples := append(t.samples[id], sample)
+		if len(samples) > maxTrafficSamples {
+			samples = samples[len(samples)-maxTrafficSamples:]
+		}
+		t.samples[id] = samples
+	}
+	for id := range t.samples {
+		if !live[id] {
+			delete(t.samples, id)
+			delete(t.received, id)
+			delete(t.sent, id)
+		}
+	}
+	t.last = now
+}
+
+// snapshot returns a copy of the samples, by session ID
+func (t *trafficRecorder) snapshot() map[string][]trafficSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	samples := make(map[string][]trafficSample, len(t.samples))
+	for id, list := range t.samples {
+		samples[id] = append([]trafficSample(nil), list...)
+	}
+	return samples
+}
+
+// traffic serves GET /v1/traffic, the recent samples of every tunnel of the
+// daemon
+func (d *daemonServer) traffic(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, d.samples.snapshot())
+}
+
+// restartTunnel serves POST /v1/tunnels/{id}/restart, which reopens a tunnel
+// of the daemon with its rule, keeping its ID and statistics
+func (d *daemonServer) restartTunnel(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	session, found := d.manager.GetSession(id)
+	if !found {
+		writeAPIError(w, http.StatusNotFound, errorf(exitFailure, "the daemon holds no forwarding session %s", id))
+		return
+	}
+	if err := d.manager.UpdateForwarding(id, session.Rule); err != nil {
+		writeAPIErrorCode(w, connectionError(fmt.Errorf("failed to restart %s: %w", id, err)))
+		return
+	}
+	tunnel := newSessionJSON(session)
+	tunnel.Host = session.Host().Name
+	writeAPIJSON(w, http.StatusOK, tunnel)
+}
+
+// dashboard serves the dashboard page
+func (d *daemonServer) dashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'unsafe-inline'; script-src 'unsafe-inline'")
+	w.Write(dashboardPage)
+}
+
+// dashboardLogin answers a request for the dashboard that carries the token
+// in its query, as in the URL printed by "xssh daemon dashboard": the token
+// is stored in a cookie and the browser sent to the dashboard without it
+func (d *daemonServer) dashboardLogin(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokenCookie,
+		Value:    d.token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/crash"
+	"xssh/internal/logging"
+	"xssh/internal/version"
+)
+
+// Limits of what goes into a debug bundle
+const (
+	bundleLogBytes   = 1 << 20 // The end of the log
+	bundleCrashFiles = 5       // The newest crash reports
+)
+
+// redacted replaces values left out of a debug bundle
+const redacted = "<redacted>"
+
+// sensitiveKeys are parts of config.toml key names whose values are left
+// out of debug bundles
+var sensitiveKeys = []string{"token", "password", "passphrase", "secret", "url"}
+
+// debugBundleCommand implements "xssh debug-bundle"
+func debugBundleCommand() *Command {
+	cmd := newCommand("debug-bundle", "", "Collect logs and sanitized config for a bug report")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Write a .tar.gz to attach to a bug report, with the version of xssh, the
+results of "xssh doctor", the XSSH_ environment variables, config.toml,
+~/.ssh/config and hosts.json, the end of the log and the latest crash reports.
+
+Secrets and addresses are left out: values of config.toml keys naming a
+token, password, secret or URL, the [hooks] commands, the [bastions] patterns
+spelling a domain or an IP and the host, rule and via of [tunnels.*];
+HostName, User, ProxyJump, proxy commands and the Host and Match patterns
+spelling a domain or an IP of ~/.ssh/config, whose key files are reduced to
+their names; notes, hooks, secret references and forwarding rules of
+hosts.json. Logs and crash reports are included as they are, so look through
+the bundle before sharing it.`
+	cmd.Examples = []string{
+		"xssh debug-bundle",
+		"xssh debug-bundle -o /tmp/xssh-debug.tar.gz",
+	}
+	output := cmd.Flags.String("o", "", "write the bundle to `file` instead of xssh-debug-<time>.tar.gz")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("debug-bundle takes no arguments")
+		}
+		path := *output
+		if path == "" {
+			path = fmt.Sprintf("xssh-debug-%s.tar.gz", time.Now().Format("20060102-150405"))
+		}
+		files, err := debugBundleFiles()
+		if err != nil {
+			return err
+		}
+		if err := writeBundle(path, files); err != nil {
+			return fmt.Errorf("cannot write the debug bundle: %v", err)
+		}
+
+		infof("Wrote debug bundle to %s\n", path)
+		for _, file := range files {
+			infof("  %s\n", file.name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// bundleFile is a file of a debug bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// debugBundleFiles collects the files of a debug bundle. Files xssh has not
+// written yet are left out.
+func debugBundleFiles() ([]bundleFile, error) {
+	var files []bundleFile
+	addJSON := func(name string, v any) error {
+		data, err := bundleJSON(v)
+		if err != nil {
+			return err
+		}
+		files = append(files, bundleFile{name, data})
+		return nil
+	}
+	addFile := func(name, path string, sanitize func([]byte) []byte) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return
+		}
+		if sanitize != nil {
+			data = sanitize(data)
+		}
+		files = append(files, bundleFile{name, data})
+	}
+
+	if err := addJSON("version.json", version.Get()); err != nil {
+		return nil, err
+	}
+	if err := addJSON("doctor.json", runDoctorChecks()); err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	for _, v := range config.EnvVars {
+		if value, set := os.LookupEnv(v.Name); set {
+			env[v.Name] = value
+		}
+	}
+	if err := addJSON("env.json", env); err != nil {
+		return nil, err
+	}
+
+	if path, err := config.AppConfigPath(); err == nil {
+		addFile("config.toml", path, sanitizeAppConfig)
+	}
+	if path, err := config.SSHConfigPath(); err == nil {
+		addFile("ssh_config", path, sanitizeSSHConfig)
+	}
+	if path, err := config.MetadataPath(); err == nil {
+		addFile("hosts.json", path, sanitizeMetadata)
+	}
+
+	logFile, err := logging.DefaultPath()
+	if appConfig, configErr := config.LoadAppConfig(); configErr == nil && appConfig.Log.File != "" {
+		logFile, err = appConfig.Log.File, nil
+	}
+	if err == nil {
+		addFile("xssh.log", logFile, func(data []byte) []byte {
+			if len(data) > bundleLogBytes {
+				data = data[len(data)-bundleLogBytes:]
+			}
+			return data
+		})
+	}
+
+	reports, _ := crash.List()
+	for i, path := range reports {
+		if i == bundleCrashFiles {
+			break
+		}
+		addFile("crashes/"+filepath.Base(path), path, nil)
+	}
+	return files, nil
+}
+
+// writeBundle writes files to a gzipped tar archive at path
+func writeBundle(path string, files []bundleFile) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+	now := time.Now()
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    "xssh-debug/" + f.name,
+			Mode:    0600,
+			Size:    int64(len(f.data)),
+			ModTime: now,
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// sanitizeAppConfig redacts the values of sensitive keys, the hook
+// commands, the address patterns of [bastions] and the targets of
+// [tunnels.*] in config.toml
+func sanitizeAppConfig(data []byte) []byte {
+	open := 0 // Brackets a redacted array leaves open; its lines are dropped
+	redact := func(key, value string) string {
+		open = strings.Count(value, "[") - strings.Count(value, "]")
+		return key + "= \"" + redacted + "\""
+	}
+	return mapLines(data, nil, func(section, line string) string {
+		if open > 0 {
+			open += strings.Count(line, "[") - strings.Count(line, "]")
+			return ""
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return line
+		}
+		name := strings.ToLower(strings.Trim(strings.TrimSpace(key), `"`))
+		for _, sensitive := range sensitiveKeys {
+			if strings.Contains(name, sensitive) {
+				return redact(key, value)
+			}
+		}
+
+		switch {
+		case section == "hooks" || strings.HasPrefix(section, "hooks."):
+			return redact(key, value)
+		case strings.HasPrefix(section, "tunnels."):
+			if name == "host" || name == "rule" || name == "via" {
+				return redact(key, value)
+			}
+		case section == "bastions":
+			if addressLike(name) {
+				key = strings.Replace(key, strings.TrimSpace(key), `"`+redacted+`"`, 1)
+			}
+			patterns := strings.Fields(strings.Trim(strings.TrimSpace(value), `"'`))
+			for i, pattern := range patterns {
+				if addressLike(pattern) {
+					patterns[i] = redacted
+				}
+			}
+			return key + "= \"" + strings.Join(patterns, " ") + "\""
+		}
+		return line
+	})
+}
+
+// sanitizeSSHConfig redacts the addresses, users and commands of an SSH
+// config and reduces its key files to their names
+func sanitizeSSHConfig(data []byte) []byte {
+	return mapLines(data, func(line string) string {
+		indent, keyword, args := sshDirective(line)
+		if len(args) == 0 {
+			return line
+		}
+		switch strings.ToLower(keyword) {
+		case "hostname", "user", "proxyjump", "proxycommand", "localcommand", "remotecommand":
+			return indent + keyword + " " + redacted
+		case "identityfile", "certificatefile":
+			return indent + keyword + " " + filepath.Base(args[0])
+		case "host", "match":
+			fields := append([]string{keyword}, args...)
+			for i := 1; i < len(fields); i++ {
+				previous := strings.ToLower(fields[i-1])
+				if addressLike(fields[i]) || previous == "user" || previous == "localuser" {
+					fields[i] = redacted
+				}
+			}
+			return indent + strings.Join(fields, " ")
+		}
+		return line
+	}, nil)
+}
+
+// sshDirective splits an SSH config line into its indentation, keyword and
+// arguments. The keyword ends at whitespace or "=", as ssh accepts both
+// "HostName example.com" and "HostName=example.com".
+func sshDirective(line string) (indent, keyword string, args []string) {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent = line[:len(line)-len(trimmed)]
+	end := strings.IndexAny(trimmed, " \t=")
+	if end < 0 {
+		return indent, trimmed, nil
+	}
+	rest := strings.TrimLeft(trimmed[end:], " \t")
+	rest = strings.TrimPrefix(rest, "=")
+	return indent, trimmed[:end], strings.Fields(rest)
+}
+
+// addressLike reports whether a Host or Match pattern holds a domain name or
+// an IP address rather than an alias, going by its dots and colons
+func addressLike(pattern string) bool {
+	return strings.ContainsAny(pattern, ".:")
+}
+
+// sanitizeMetadata redacts the notes, hooks, secret references and
+// forwarding rules of hosts.json
+func sanitizeMetadata(data []byte) []byte {
+	var hosts map[string]map[string]any
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return []byte("hosts.json could not be parsed: " + err.Error() + "\n")
+	}
+	for _, host := range hosts {
+		for _, key := range []string{"notes", "hooks", "password_ref", "passphrase_ref", "auto_forwards"} {
+			if _, ok := host[key]; ok {
+				host[key] = redacted
+			}
+		}
+	}
+	sanitized, err := bundleJSON(hosts)
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+// bundleJSON formats v as indented JSON, leaving "<" and ">" as they are
+func bundleJSON(v any) ([]byte, error) {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// mapLines applies line, when given, to every line of data and section,
+// when given, to the lines of TOML sections, with the name of the section.
+// Lines they turn into empty strings are left out.
+func mapLines(data []byte, line func(string) string, section func(string, string) string) []byte {
+	var out bytes.Buffer
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			current = strings.Trim(trimmed, "[] ")
+		} else if text != "" {
+			if line != nil {
+				text = line(text)
+			}
+			if section != nil {
+				text = section(current, text)
+			}
+			if text == "" {
+				continue
+			}
+		}
+		io.WriteString(&out, text+"\n")
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/version"
+)
+
+// docsSummary is the one-line description of xssh in the generated docs
+const docsSummary = "SSH connection manager with port forwarding"
+
+// genDocsCommand implements "xssh gen-docs"
+func genDocsCommand(opts *Options) *Command {
+	cmd := newCommand("gen-docs", "", "Write man pages or a markdown command reference")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Write the documentation of every command, generated from the same definitions
+as the help, into --dir:
+
+  man        xssh.1 and an xssh-<command>.1 page per command
+  markdown   xssh.md, the whole command reference in one file
+
+Packages install the man pages under share/man/man1, so "man xssh" works.`
+	cmd.Examples = []string{
+		"xssh gen-docs --dir /usr/local/share/man/man1",
+		"xssh gen-docs --format markdown --dir docs",
+	}
+	format := cmd.Flags.String("format", "man", "`format` of the docs: man or markdown")
+	dir := cmd.Flags.String("dir", ".", "`directory` to write the files to")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("gen-docs takes no arguments")
+		}
+		if *format != "man" && *format != "markdown" {
+			return cmd.usagef("unknown format %q, expected man or markdown", *format)
+		}
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			return err
+		}
+
+		files := map[string]string{}
+		cmds := listedCommands(commands(opts))
+		if *format == "man" {
+			files["xssh.1"] = manPage(cmds)
+			for _, c := range cmds {
+				files["xssh-"+c.Name+".1"] = commandManPage(c)
+			}
+		} else {
+			files["xssh.md"] = markdownReference(cmds)
+		}
+
+		for name, content := range files {
+			path := filepath.Join(*dir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return err
+			}
+			infof("Wrote %s\n", path)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// textBlock is a paragraph of a command description, or a block of
+// indented lines such as a table, which keeps its layout
+type textBlock struct {
+	preformatted bool
+	lines        []string
+}
+
+// descriptionBlocks splits a description into paragraphs and indented
+// blocks
+func descriptionBlocks(text string) []textBlock {
+	var blocks []textBlock
+	var current *textBlock
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		preformatted := strings.HasPrefix(line, "  ")
+		if current == nil || current.preformatted != preformatted {
+			blocks = append(blocks, textBlock{preformatted: preformatted})
+			current = &blocks[len(blocks)-1]
+		}
+		current.lines = append(current.lines, line)
+	}
+	return blocks
+}
+
+// manDate returns the date shown in the footer of the man pages: the build
+// date, so the pages of a release do not change when generated again
+func manDate() string {
+	if date, err := time.Parse(time.RFC3339, version.Get().Date); err == nil {
+		return date.Format("2006-01-02")
+	}
+	return time.Now().Format("2006-01-02")
+}
+
+// roff escapes text for a man page
+func roff(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// manHeader starts a man page
+func manHeader(b *strings.Builder, name, summary string) {
+	fmt.Fprintf(b, ".TH %s 1 %q %q \"User Commands\"\n", strings.ToUpper(name), manDate(), "xssh "+version.Version)
+	b.WriteString(".SH NAME\n")
+	fmt.Fprintf(b, "%s \\- %s\n", roff(name), roff(summary))
+}
+
+// manBlocks writes a description as man page paragraphs
+func manBlocks(b *strings.Builder, text string) {
+	for i, block := range descriptionBlocks(text) {
+		if i > 0 {
+			b.WriteString(".PP\n")
+		}
+		if block.preformatted {
+			b.WriteString(".nf\n")
+		}
+		for _, line := range block.lines {
+			b.WriteString(roff(line) + "\n")
+		}
+		if block.preformatted {
+			b.WriteString(".fi\n")
+		}
+	}
+}
+
+// manPage returns the xssh(1) page: usage, commands, global options, exit
+// status, environment and files
+func manPage(cmds []*Command) string {
+	var b strings.Builder
+	manHeader(&b, "xssh", docsSummary)
+
+	b.WriteString(".SH SYNOPSIS\n")
+	b.WriteString(".B xssh\n[\\fIoptions\\fR]\n.br\n")
+	b.WriteString(".B xssh\n[\\fIoptions\\fR] \\fIalias\\fR [\\fB\\-\\-\\fR \\fIssh args\\fR]\n.br\n")
+	b.WriteString(".B xssh\n[\\fIoptions\\fR] \\fIcommand\\fR [\\fIarguments\\fR]\n")
+
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString("Without a command, xssh starts an interactive host list of ~/.ssh/config. " +
+		"Given a host alias, it connects to the host with ssh. " +
+		"The commands manage hosts and port forwardings from the command line.\n")
+
+	b.WriteString(".SH COMMANDS\n")
+	for _, c := range cmds {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roff(strings.TrimPrefix(commandUsage(c), "xssh ")), roff(c.Summary))
+		fmt.Fprintf(&b, "See \\fBxssh\\-%s\\fR(1).\n", roff(c.Name))
+	}
+
+	b.WriteString(".SH OPTIONS\n")
+	b.WriteString("These options come before the command.\n")
+	for _, option := range globalOptions {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roff(option.Synopsis), roff(option.Usage))
+	}
+
+	b.WriteString(".SH EXIT STATUS\n")
+	for _, status := range exitStatuses {
+		fmt.Fprintf(&b, ".TP\n.B %d\n%s\n", status.Code, roff(status.Meaning))
+	}
+
+	b.WriteString(".SH ENVIRONMENT\n")
+	for _, v := range config.EnvVars {
+		fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roff(v.Name), roff(v.Description))
+	}
+
+	b.WriteString(".SH FILES\n")
+	for _, file := range []struct{ path, description string }{
+		{"~/.ssh/config", "the SSH hosts"},
+		{"~/.config/xssh/config.toml", "settings of xssh"},
+		{"~/.config/xssh/hosts.json", "tags, colors and notes of the hosts"},
+		{"~/.config/xssh/xssh.log", "diagnostic log"},
+	} {
+		fmt.Fprintf(&b, ".TP\n.I %s\n%s\n", roff(file.path), roff(file.description))
+	}
+
+	b.WriteString(".SH SEE ALSO\n")
+	var refs []string
+	for _, c := range cmds {
+		refs = append(refs, fmt.Sprintf("\\fBxssh\\-%s\\fR(1)", roff(c.Name)))
+	}
+	refs = append(refs, "\\fBssh\\fR(1)", "\\fBssh_config\\fR(5)")
+	b.WriteString(strings.Join(refs, ",\n") + "\n")
+	return b.String()
+}
+
+// commandManPage returns the xssh-<command>(1) page of a command
+func commandManPage(cmd *Command) string {
+	var b strings.Builder
+	manHeader(&b, "xssh-"+cmd.Name, cmd.Summary)
+
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roff(commandUsage(cmd)))
+
+	b.WriteString(".SH DESCRIPTION\n")
+	description := cmd.Description
+	if description == "" {
+		description = cmd.Summary
+	}
+	manBlocks(&b, description)
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(&b, ".PP\nAlso available as %s.\n", roff(strings.Join(cmd.Aliases, ", ")))
+	}
+
+	if hasFlags(cmd.Flags) {
+		b.WriteString(".SH OPTIONS\n")
+		cmd.Flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roff(flagSynopsis(f)), roff(flagUsage(f)))
+		})
+	}
+	if len(cmd.Examples) > 0 {
+		b.WriteString(".SH EXAMPLES\n.nf\n")
+		for _, example := range cmd.Examples {
+			b.WriteString(roff(example) + "\n")
+		}
+		b.WriteString(".fi\n")
+	}
+	b.WriteString(".SH SEE ALSO\n\\fBxssh\\fR(1)\n")
+	return b.String()
+}
+
+// markdownReference returns the command reference as one markdown document
+func markdownReference(cmds []*Command) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# xssh\n\n%s.\n\n", docsSummary)
+	b.WriteString("```\nxssh [options]\nxssh [options] <alias> [-- ssh args]\nxssh [options] <command> [arguments]\n```\n\n")
+
+	b.WriteString("## Global options\n\n| Option | Description |\n|--------|-------------|\n")
+	for _, option := range globalOptions {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", markdownCell(option.Synopsis), markdownCell(option.Usage))
+	}
+
+	b.WriteString("\n## Commands\n\n")
+	for _, c := range cmds {
+		fmt.Fprintf(&b, "- [`%s`](#xssh-%s): %s\n", c.Name, c.Name, c.Summary)
+	}
+
+	for _, c := range cmds {
+		fmt.Fprintf(&b, "\n### xssh %s\n\n", c.Name)
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", commandUsage(c))
+		description := c.Description
+		if description == "" {
+			description = c.Summary
+		}
+		for _, block := range descriptionBlocks(description) {
+			if block.preformatted {
+				fmt.Fprintf(&b, "```\n%s\n```\n\n", strings.Join(block.lines, "\n"))
+			} else {
+				fmt.Fprintf(&b, "%s\n\n", strings.Join(block.lines, "\n"))
+			}
+		}
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(&b, "Aliases: `%s`\n\n", strings.Join(c.Aliases, "`, `"))
+		}
+		if hasFlags(c.Flags) {
+			b.WriteString("| Option | Description |\n|--------|-------------|\n")
+			c.Flags.VisitAll(func(f *flag.Flag) {
+				fmt.Fprintf(&b, "| `%s` | %s |\n", markdownCell(flagSynopsis(f)), markdownCell(flagUsage(f)))
+			})
+			b.WriteString("\n")
+		}
+		if len(c.Examples) > 0 {
+			fmt.Fprintf(&b, "Examples:\n\n```\n%s\n```\n", strings.Join(c.Examples, "\n"))
+		}
+	}
+
+	b.WriteString("\n## Exit status\n\n| Code | Meaning |\n|------|---------|\n")
+	for _, status := range exitStatuses {
+		fmt.Fprintf(&b, "| %d | %s |\n", status.Code, markdownCell(status.Meaning))
+	}
+
+	b.WriteString("\n## Environment\n\n| Variable | Description |\n|----------|-------------|\n")
+	for _, v := range config.EnvVars {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", v.Name, markdownCell(v.Description))
+	}
+	return b.String()
+}
+
+// markdownCell escapes text for a markdown table cell
+func markdownCell(text string) string {
+	return strings.ReplaceAll(text, "|", `\|`)
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// Results of a doctor check
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkError   = "error"
+)
+
+// doctorCheck is the result of one check of "xssh doctor"
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // checkOK, checkWarning or checkError
+	Message string `json:"message"`
+}
+
+// doctorCommand implements "xssh doctor"
+func doctorCommand() *Command {
+	cmd := newCommand("doctor", "", "Check the setup for common problems")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Check that ssh is installed, that ~/.ssh/config and xssh's own files can be
+read, that every host is valid and follows the [bastions] policy of
+config.toml, and that ssh-agent is reachable. Exits with status 1 when a check
+fails.`
+	asJSON := cmd.Flags.Bool("json", false, "print the checks as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("doctor takes no arguments")
+		}
+
+		checks := runDoctorChecks()
+		if *asJSON {
+			if err := printJSON(checks); err != nil {
+				return err
+			}
+		} else {
+			for _, check := range checks {
+				fmt.Printf("%-9s %-12s %s\n", "["+check.Status+"]", check.Name, check.Message)
+			}
+		}
+
+		failed := 0
+		for _, check := range checks {
+			if check.Status == checkError {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// runDoctorChecks runs every check, continuing past failures
+func runDoctorChecks() []doctorCheck {
+	var checks []doctorCheck
+	add := func(name, status, format string, args ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if path, err := exec.LookPath("ssh"); err != nil {
+		add("ssh", checkError, "ssh was not found in PATH")
+	} else {
+		add("ssh", checkOK, "%s", path)
+	}
+
+	var hosts []config.SSHHost
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		add("ssh-config", checkError, "%v", err)
+	} else {
+		add("ssh-config", checkOK, "%s (%d hosts)", sshConfig.Path, len(sshConfig.Hosts))
+		hosts = sshConfig.Hosts
+
+		invalid := 0
+		for _, host := range sshConfig.Hosts {
+			if err := host.Validate(); err != nil {
+				add("host", checkWarning, "%s: %v", host.Name, err)
+				invalid++
+			}
+		}
+		if invalid == 0 {
+			add("hosts", checkOK, "all %d hosts are valid", len(sshConfig.Hosts))
+		}
+	}
+
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		add("xssh-config", checkError, "%v", err)
+	} else {
+		add("xssh-config", checkOK, "%s", appConfig.Path)
+	}
+
+	if len(appConfig.Bastions) > 0 {
+		violations := 0
+		for _, host := range hosts {
+			if config.IsPattern(host.Name) {
+				continue
+			}
+			if err := appConfig.Bastions.Check(host); err != nil {
+				add("bastion", checkWarning, "%s: %v", host.Name, err)
+				violations++
+			}
+		}
+		if violations == 0 {
+			add("bastions", checkOK, "all hosts follow the bastion policy")
+		}
+	}
+
+	if metadata, err := config.LoadMetadata(); err != nil {
+		add("metadata", checkError, "%v", err)
+	} else {
+		add("metadata", checkOK, "%s", metadata.Path)
+	}
+
+	if keys, err := ssh.ListAgentKeys(); err != nil {
+		add("ssh-agent", checkWarning, "%v", err)
+	} else {
+		add("ssh-agent", checkOK, "%d keys loaded", len(keys))
+	}
+
+	return checks
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"xssh/internal/config"
+	"xssh/internal/logging"
+)
+
+// envJSON is an environment variable as printed by "xssh env --json"
+type envJSON struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Set         bool   `json:"set"`
+	Description string `json:"description"`
+}
+
+// envCommand implements "xssh env"
+func envCommand() *Command {
+	cmd := newCommand("env", "", "Show the environment variables xssh reads")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `List the environment variables xssh reads with their current values, and the
+files xssh uses as they resolve with them. The XSSH_ variables take
+precedence over the config file, for the CLI and the TUI alike.`
+	cmd.Examples = []string{
+		"xssh env",
+		"XSSH_SSH_CONFIG=./ssh_config xssh list",
+	}
+	asJSON := cmd.Flags.Bool("json", false, "print the variables as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("env takes no arguments")
+		}
+
+		vars := make([]envJSON, 0, len(config.EnvVars))
+		for _, v := range config.EnvVars {
+			value, set := os.LookupEnv(v.Name)
+			vars = append(vars, envJSON{Name: v.Name, Value: value, Set: set, Description: v.Description})
+		}
+		if *asJSON {
+			return printJSON(vars)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, v := range vars {
+			value := "(unset)"
+			if v.Set {
+				value = v.Value
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Name, value, v.Description)
+		}
+		w.Flush()
+
+		fmt.Println()
+		fmt.Println("Files:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, file := range resolvedFiles() {
+			fmt.Fprintf(w, "  %s\t%s\n", file.name, file.path)
+		}
+		w.Flush()
+		return nil
+	}
+	return cmd
+}
+
+// resolvedFile is a file xssh uses, at the location the environment and the
+// config file select
+type resolvedFile struct {
+	name string
+	path string
+}
+
+// resolvedFiles returns the files xssh uses; a location that cannot be
+// resolved is shown as the error
+func resolvedFiles() []resolvedFile {
+	pathOf := func(path string, err error) string {
+		if err != nil {
+			return "(" + err.Error() + ")"
+		}
+		return path
+	}
+
+	logFile := ""
+	if appConfig, err := config.LoadAppConfig(); err == nil && appConfig.Log.File != "" {
+		logFile = appConfig.Log.File
+	} else {
+		logFile = pathOf(logging.DefaultPath())
+	}
+	return []resolvedFile{
+		{"config", pathOf(config.AppConfigPath())},
+		{"ssh config", pathOf(config.SSHConfigPath())},
+		{"metadata", pathOf(config.MetadataPath())},
+		{"log", logFile},
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"xssh/internal/ssh"
+)
+
+// Exit codes of xssh, the same for every command. Scripts branch on them, so
+// a code is never reused for another kind of failure.
+const (
+	exitFailure      = 1  // Any failure without a code of its own
+	exitUsage        = 2  // Invalid command line
+	exitConfig       = 3  // A config file cannot be read or saved
+	exitHostNotFound = 4  // No host has the given alias
+	exitAuth         = 5  // The host rejected the password or every key
+	exitHostKey      = 6  // The host key differs from the one in known_hosts
+	exitDNS          = 7  // The host name does not resolve
+	exitUnreachable  = 8  // The connection was refused, or the host is unreachable
+	exitTimeout      = 9  // The host did not answer in time
+	exitPortInUse    = 10 // A forwarding port is already taken
+	exitDaemon       = 11 // The xssh daemon cannot be reached
+)
+
+// exitKinds names the exit codes in --error-format json output
+var exitKinds = map[int]string{
+	exitFailure:      "error",
+	exitUsage:        "usage",
+	exitConfig:       "config",
+	exitHostNotFound: "host_not_found",
+	exitAuth:         "auth",
+	exitHostKey:      "host_key",
+	exitDNS:          "dns",
+	exitUnreachable:  "unreachable",
+	exitTimeout:      "timeout",
+	exitPortInUse:    "port_in_use",
+	exitDaemon:       "daemon_unreachable",
+}
+
+// exitStatuses describes the exit codes, for the help and the generated docs
+var exitStatuses = []struct {
+	Code    int
+	Meaning string
+}{
+	{0, "success"},
+	{exitFailure, "any other error"},
+	{exitUsage, "invalid command line"},
+	{exitConfig, "a config file cannot be read or saved"},
+	{exitHostNotFound, "no host has the given alias"},
+	{exitAuth, "authentication failed"},
+	{exitHostKey, "the host key differs from the one in known_hosts"},
+	{exitDNS, "the host name does not resolve"},
+	{exitUnreachable, "the connection was refused, or the host is unreachable"},
+	{exitTimeout, "the host did not answer in time"},
+	{exitPortInUse, "a forwarding port is already in use"},
+	{exitDaemon, "the xssh daemon cannot be reached"},
+}
+
+// probeExitCodes maps the failures of a connection to exit codes
+var probeExitCodes = map[ssh.ProbeFailure]int{
+	ssh.ProbeOther:   exitFailure,
+	ssh.ProbeDNS:     exitDNS,
+	ssh.ProbeRefused: exitUnreachable,
+	ssh.ProbeTimeout: exitTimeout,
+	ssh.ProbeAuth:    exitAuth,
+	ssh.ProbeHostKey: exitHostKey,
+}
+
+// errorFormat is how Run reports errors: "text" or "json"
+var errorFormat = "text"
+
+// codedError is an error with the exit code of its kind
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e codedError) Unwrap() error {
+	return e.err
+}
+
+// errorf returns an error that ends xssh with code
+func errorf(code int, format string, args ...any) error {
+	return codedError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// connectionError gives err, from connecting to a host or forwarding
+// through it, the exit code of its cause
+func connectionError(err error) error {
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return codedError{code: exitPortInUse, err: err}
+	}
+	return codedError{code: probeExitCodes[ssh.ClassifyError(err)], err: err}
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var coded codedError
+	var usage usageError
+	switch {
+	case errors.As(err, &coded):
+		return coded.code
+	case errors.As(err, &usage):
+		return exitUsage
+	}
+	return exitFailure
+}
+
+// errorJSON is an error as printed with --error-format json
+type errorJSON struct {
+	Error   string `json:"error"`
+	Kind    string `json:"kind"`
+	Code    int    `json:"code"`
+	Command string `json:"command,omitempty"` // Command whose usage was wrong
+}
+
+// reportError prints the error a command failed with on stderr
+func reportError(err error, code int) {
+	var usage usageError
+	isUsage := errors.As(err, &usage)
+
+	if errorFormat == "json" {
+		report := errorJSON{Error: err.Error(), Kind: exitKinds[code], Code: code}
+		if isUsage {
+			report.Command = usage.command
+		}
+		encoder := json.NewEncoder(os.Stderr)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(report)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if isUsage {
+		// Errors before a command is known point at the overall help
+		help := strings.TrimSpace("xssh help " + usage.command)
+		fmt.Fprintf(os.Stderr, "Use '%s' for usage information.\n", help)
+	}
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"xssh/internal/forwarding"
+)
+
+// healthJSON is the health of a forwarding, as served by the health endpoint
+type healthJSON struct {
+	ID      string `json:"id"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"` // Why it is unhealthy
+}
+
+// healthHandler serves the health of the forwardings of manager, for local
+// tools to wait on a tunnel: GET /health checks them all and GET
+// /health/{id} one. Both answer 200 when healthy and 503 otherwise, with
+// the checks as JSON; /health with no forwarding is unhealthy.
+func healthHandler(manager *forwarding.ForwardingManager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		sessions := manager.GetAllSessions()
+		checks := make([]healthJSON, len(sessions))
+		var wg sync.WaitGroup
+		for i, session := range sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				checks[i] = checkHealth(r.Context(), manager, session.Rule.ID)
+			}()
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if len(checks) == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		for _, check := range checks {
+			if !check.Healthy {
+				status = http.StatusServiceUnavailable
+			}
+		}
+		writeAPIJSON(w, status, checks)
+	})
+	mux.HandleFunc("GET /health/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if _, found := manager.GetSession(id); !found {
+			writeAPIError(w, http.StatusNotFound, fmt.Errorf("no forwarding session %s", id))
+			return
+		}
+		check := checkHealth(r.Context(), manager, id)
+		status := http.StatusOK
+		if !check.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+		writeAPIJSON(w, status, check)
+	})
+	return mux
+}
+
+// checkHealth checks one forwarding for the health endpoint
+func checkHealth(ctx context.Context, manager *forwarding.ForwardingManager, id string) healthJSON {
+	check := healthJSON{ID: id, Healthy: true}
+	if err := manager.CheckHealth(ctx, id); err != nil {
+		check.Healthy = false
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// serveHealth serves the health endpoint of manager on address until the
+// returned server is shut down
+func serveHealth(manager *forwarding.ForwardingManager, address string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errorf(exitPortInUse, "cannot listen for health checks on %s: %v", address, err)
+	}
+	server := &http.Server{Handler: healthHandler(manager), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	infof("Serving the health of the forwardings on http://%s/health\n", listener.Addr())
+	return server, nil
+}
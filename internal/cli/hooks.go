@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// hostHooks holds the hook commands for a connection to one host, keyed by
+// hook event
+type hostHooks map[string][]string
+
+// loadHooks collects the hooks of the xssh config and of the host's
+// metadata. Files that cannot be read contribute no hooks.
+func loadHooks(host config.SSHHost) hostHooks {
+	appConfig, _ := config.LoadAppConfig()
+	metadata, _ := config.LoadMetadata()
+	hooks := hostHooks{}
+	for _, event := range config.HookEvents {
+		if commands := config.HookCommands(appConfig, metadata, event, host.Name); len(commands) > 0 {
+			hooks[event] = commands
+		}
+	}
+	return hooks
+}
+
+// has reports whether any command is registered for event
+func (h hostHooks) has(event string) bool {
+	return len(h[event]) > 0
+}
+
+// run runs the commands of event one after the other with sh, stopping at
+// the first that fails. They see the host in XSSH_HOST, XSSH_HOSTNAME,
+// XSSH_USER, XSSH_PORT, XSSH_IDENTITY and XSSH_PROXY_JUMP, the event in
+// XSSH_HOOK, and env. Their output goes to stderr, keeping stdout for the
+// session.
+func (h hostHooks) run(event string, host config.SSHHost, env ...string) error {
+	for _, command := range h[event] {
+		slog.Info("running hook", "event", event, "host", host.Name, "command", command)
+		hook := exec.Command("/bin/sh", "-c", command)
+		hook.Env = append(os.Environ(),
+			"XSSH_HOOK="+event,
+			"XSSH_HOST="+host.Name,
+			"XSSH_HOSTNAME="+host.Host,
+			"XSSH_USER="+host.User,
+			"XSSH_PORT="+host.Port,
+			"XSSH_IDENTITY="+host.Identity,
+			"XSSH_PROXY_JUMP="+host.ProxyJump,
+		)
+		hook.Env = append(hook.Env, env...)
+		hook.Stdin, hook.Stdout, hook.Stderr = os.Stdin, os.Stderr, os.Stderr
+		if err := hook.Run(); err != nil {
+			return fmt.Errorf("%s hook failed: %v", event, err)
+		}
+	}
+	return nil
+}
+
+// disconnected runs the post_disconnect hooks of a connection that started
+// at started and ended with the exit code code. A failing hook is only
+// reported, as the connection is over anyway.
+func (h hostHooks) disconnected(host config.SSHHost, code int, started time.Time) {
+	err := h.run(config.HookPostDisconnect, host,
+		"XSSH_EXIT_CODE="+strconv.Itoa(code),
+		fmt.Sprintf("XSSH_DURATION=%d", int(time.Since(started).Seconds())),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// connectAndWait runs ssh as a child process instead of replacing xssh with
+// it, so the session can be recorded and the post_disconnect hooks run when
+// it ends. The hooks get the exit code of ssh in XSSH_EXIT_CODE and the
+// length of the session in seconds in XSSH_DURATION. xssh exits with the
+// code of ssh.
+func connectAndWait(host config.SSHHost, hooks hostHooks, env []string, extraArgs ...string) error {
+	session, err := ssh.Command(host, extraArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	session.Env = env
+	session.Stdin, session.Stdout, session.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// Ctrl+C belongs to the session, ssh gets it from the terminal, and a
+	// closed terminal hangs up ssh too. The signals are caught rather than
+	// ignored, which ssh would inherit, so the session is still recorded.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGHUP)
+	started := time.Now()
+	recordAudit(config.AuditEvent{Action: config.AuditConnect, Host: host.Name, Detail: strings.Join(extraArgs, " ")})
+	err = session.Run()
+	signal.Stop(sigChan)
+
+	code := 0
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	} else if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+
+	recordSession(config.SessionRecord{
+		Kind:     config.SessionConnect,
+		Host:     host.Name,
+		Start:    started,
+		End:      time.Now(),
+		ExitCode: code,
+		Detail:   strings.Join(extraArgs, " "),
+	})
+	hooks.disconnected(host, code, started)
+	if code != 0 {
+		// ssh has said what went wrong
+		return exitError{code: code}
+	}
+	return nil
+}
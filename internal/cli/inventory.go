@@ -0,0 +1,349 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"xssh/internal/config"
+	"xssh/internal/inventory"
+)
+
+// conflictStrategies are the values of import --on-conflict
+var conflictStrategies = []string{"skip", "overwrite", "merge", "rename"}
+
+// importCommand implements "xssh import <file>"
+func importCommand() *Command {
+	cmd := newCommand("import", "<file | - | aws | gcp | hetzner | k8s>", "Add hosts from a file, a cloud provider or a cluster")
+	cmd.Description = fmt.Sprintf(`Add the hosts of a file to ~/.ssh/config, with their tags, color and notes.
+The format is taken from the extension (.csv, .json, .reg) unless --format
+is given, which is required when reading stdin with "-".
+
+  csv     A header row naming the columns %s;
+          tags are separated by ";". Only name and host are required.
+  json    An array of objects with the same fields, as "xssh export" writes
+  putty   A registry export of PuTTY's saved SSH sessions (.reg)
+
+--on-conflict decides what happens when an alias is already configured:
+skip leaves the configured host alone, overwrite replaces it, merge takes the
+fields set in the file and keeps the others, rename adds the host under a
+new alias like web1-copy. Hosts that are invalid or identical to the
+configured ones are skipped. With --dry-run nothing is saved.
+
+Instead of a file, "aws", "gcp" or "hetzner" imports the running instances
+of a cloud account through its command line tool (aws, gcloud or hcloud),
+which must be installed and logged in. Each instance becomes a host named
+after the instance, at its public address (--private for the private one),
+tagged with the provider, zone and the instance's tags or labels. AWS hosts
+use the key pair's private key when ~/.ssh/<key pair>.pem or --key-dir has
+it. --profile selects the AWS profile, gcloud configuration or hcloud
+context, and --tag-filter key=value, which can be repeated, the instances.
+
+"k8s" imports the nodes of a Kubernetes cluster with kubectl, from the
+current context of the kubeconfig or the one --profile names, tagged with the
+cluster name, zone and node roles. --bastion sets the jump host of the
+imported hosts, which are then reached at their private (internal) addresses.
+
+Imported hosts remember where they came from. --sync imports again from the
+same provider, profile and filters, updating those hosts and removing the
+ones whose instance is gone; --every repeats the sync until interrupted.`, strings.Join(inventory.Columns, ", "))
+	cmd.Examples = []string{
+		"xssh import hosts.csv --dry-run",
+		"xssh import hosts.json --on-conflict merge",
+		"xssh import putty.reg --on-conflict rename",
+		"cat hosts.csv | xssh import --format csv -",
+		"xssh import aws --profile prod --tag-filter env=prod --user ec2-user",
+		"xssh import gcp --project my-project --private",
+		"xssh import hetzner --sync --every 1h",
+		"xssh import k8s --profile prod-cluster --bastion jump-prod --user ubuntu --sync",
+	}
+	format := cmd.Flags.String("format", "", "`format` of the file: "+strings.Join(inventory.Formats, ", "))
+	dryRun := cmd.Flags.Bool("dry-run", false, "show what would change without saving")
+	onConflict := cmd.Flags.String("on-conflict", "skip", "`strategy` for aliases that exist: "+strings.Join(conflictStrategies, ", "))
+	cloud := inventory.CloudOptions{Filters: map[string]string{}}
+	cmd.Flags.StringVar(&cloud.Profile, "profile", "", "AWS `profile`, gcloud configuration, hcloud context or kubeconfig context")
+	cmd.Flags.StringVar(&cloud.Kubeconfig, "kubeconfig", "", "kubeconfig `file` of k8s (default kubectl's)")
+	cmd.Flags.StringVar(&cloud.Region, "region", "", "AWS `region` or GCP zone")
+	cmd.Flags.StringVar(&cloud.Project, "project", "", "GCP `project`")
+	cmd.Flags.Var(tagFilterFlag(cloud.Filters), "tag-filter", "import only instances with the tag or label `key=value`")
+	cmd.Flags.StringVar(&cloud.User, "user", "", "login `user` of the imported instances")
+	cmd.Flags.StringVar(&cloud.KeyDir, "key-dir", "", "`directory` of the key pairs' private keys (default ~/.ssh)")
+	cmd.Flags.BoolVar(&cloud.Private, "private", false, "connect to the private addresses of the instances")
+	cmd.Flags.StringVar(&cloud.Bastion, "bastion", "", "reach the imported hosts through the jump `host`")
+	sync := cmd.Flags.Bool("sync", false, "also remove the hosts of earlier imports whose instance is gone")
+	every := cmd.Flags.Duration("every", 0, "sync again after each `interval`, such as 1h, until interrupted")
+
+	cmd.Run = func(args []string) error {
+		if len(args) != 1 {
+			return cmd.usagef("import takes exactly one file, or - for stdin")
+		}
+		if !slices.Contains(conflictStrategies, *onConflict) {
+			return cmd.usagef("unknown --on-conflict strategy %q, expected one of %s", *onConflict, strings.Join(conflictStrategies, ", "))
+		}
+		path := args[0]
+		if _, err := os.Stat(path); os.IsNotExist(err) && slices.Contains(inventory.CloudProviders, path) {
+			if *every > 0 {
+				*sync = true
+			}
+			if cloud.Bastion != "" {
+				if _, err := resolveHost(cloud.Bastion); err != nil {
+					return err
+				}
+			}
+			return importCloud(path, cloud, importOptions{
+				strategy: *onConflict,
+				dryRun:   *dryRun,
+				source:   cloud.Source(path),
+				sync:     *sync,
+			}, *every)
+		}
+		if *format == "" {
+			if path == "-" {
+				return cmd.usagef("--format is required when reading stdin")
+			}
+			if *format = inventory.DetectFormat(path); *format == "" {
+				return cmd.usagef("cannot tell the format of %s, give --format", path)
+			}
+		}
+
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			r = file
+		}
+		entries, err := inventory.Read(*format, r)
+		if err != nil {
+			return err
+		}
+		return importHosts(entries, importOptions{strategy: *onConflict, dryRun: *dryRun})
+	}
+	return cmd
+}
+
+// importOptions control how importHosts adds hosts
+type importOptions struct {
+	strategy string // What to do with aliases that exist, one of conflictStrategies
+	dryRun   bool
+	source   string // Cloud import the hosts come from, recorded in their metadata
+	sync     bool   // Remove the hosts of source that are not imported again
+}
+
+// importHosts adds entries to the SSH config, resolving aliases that exist
+// with the strategy of opts, and prints what was done with each host. Hosts
+// of the same source are updated in place, whatever the strategy.
+func importHosts(entries []inventory.Entry, opts importOptions) error {
+	strategy, dryRun := opts.strategy, opts.dryRun
+	sshConfig, err := config.LoadSSHConfig()
+	if err != nil {
+		return errorf(exitConfig, "failed to load SSH config: %v", err)
+	}
+	metadata, err := config.LoadMetadata()
+	if err != nil {
+		return errorf(exitConfig, "failed to load host metadata: %v", err)
+	}
+
+	verb := func(done, planned string) string {
+		if dryRun {
+			return "would " + planned
+		}
+		return done
+	}
+	var created, updated, skipped, retired int
+	imported := map[string]bool{}
+	for _, entry := range entries {
+		host := entry.Host
+		if host.Port == "" {
+			host.Port = "22"
+		}
+		if opts.source != "" {
+			// The host may have been imported under another alias
+			host.Name = sourceAlias(sshConfig, metadata, opts.source, host)
+			imported[host.Name] = true
+		}
+		if err := host.Validate(); err != nil {
+			infof("  %s %s: %v\n", verb("skipped", "skip"), displayValue(host.Name), err)
+			skipped++
+			continue
+		}
+		if entry.Color != "" && !slices.Contains(config.LabelColors, entry.Color) {
+			infof("  %s %s: unknown label color %q\n", verb("skipped", "skip"), host.Name, entry.Color)
+			skipped++
+			continue
+		}
+
+		index := sshConfig.FindHost(host.Name)
+		switch {
+		case index < 0:
+			sshConfig.AddHost(host)
+			infof("  %s %s\n", verb("created", "create"), host.Name)
+			created++
+
+		case hostsEqual(sshConfig.Hosts[index], host):
+			infof("  %s %s: unchanged\n", verb("skipped", "skip"), host.Name)
+			skipped++
+			continue
+
+		case opts.source != "" && metadata.Source(host.Name) == opts.source:
+			sshConfig.UpdateHost(host.Name, host)
+			infof("  %s %s\n", verb("updated", "update"), host.Name)
+			updated++
+
+		case strategy == "skip":
+			infof("  %s %s: already exists\n", verb("skipped", "skip"), host.Name)
+			skipped++
+			continue
+
+		case strategy == "overwrite":
+			sshConfig.UpdateHost(host.Name, host)
+			infof("  %s %s\n", verb("overwrote", "overwrite"), host.Name)
+			updated++
+
+		case strategy == "merge":
+			sshConfig.UpdateHost(host.Name, config.MergeHosts(sshConfig.Hosts[index], host))
+			infof("  %s %s\n", verb("merged", "merge"), host.Name)
+			updated++
+
+		case strategy == "rename":
+			alias := copyAlias(sshConfig, host.Name)
+			infof("  %s %s as %s\n", verb("created", "create"), host.Name, alias)
+			host.Name = alias
+			imported[alias] = true
+			sshConfig.AddHost(host)
+			created++
+		}
+
+		for _, tag := range entry.Tags {
+			if tag = config.NormalizeTag(tag); tag != "" {
+				metadata.AddTag(host.Name, tag)
+			}
+		}
+		if entry.Color != "" {
+			metadata.SetColor(host.Name, entry.Color)
+		}
+		if entry.Notes != "" {
+			metadata.SetNotes(host.Name, entry.Notes)
+		}
+		if opts.source != "" {
+			metadata.SetSource(host.Name, opts.source)
+		}
+	}
+
+	if opts.sync {
+		for _, host := range slices.Clone(sshConfig.Hosts) {
+			if metadata.Source(host.Name) != opts.source || imported[host.Name] {
+				continue
+			}
+			sshConfig.RemoveHost(host.Name)
+			metadata.RemoveHost(host.Name)
+			infof("  %s %s: instance is gone\n", verb("removed", "remove"), host.Name)
+			retired++
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Dry run: would create %d, update %d, remove %d, skip %d hosts\n", created, updated, retired, skipped)
+		return nil
+	}
+	if created+updated+retired > 0 {
+		if err := sshConfig.Backup(); err != nil {
+			return errorf(exitConfig, "failed to back up config: %v", err)
+		}
+		if err := sshConfig.Save(); err != nil {
+			return errorf(exitConfig, "failed to save config: %v", err)
+		}
+		if err := metadata.Save(); err != nil {
+			return errorf(exitConfig, "hosts imported, but failed to save host metadata: %v", err)
+		}
+	}
+	fmt.Printf("Created %d, updated %d, removed %d, skipped %d hosts\n", created, updated, retired, skipped)
+	return nil
+}
+
+// hostsEqual reports whether two hosts have the same fields
+func hostsEqual(a, b config.SSHHost) bool {
+	for _, diff := range config.DiffHosts(a, b) {
+		if diff.Changed() {
+			return false
+		}
+	}
+	return true
+}
+
+// copyAlias returns a free alias for a copy of the host called name, named
+// like the copies the TUI makes
+func copyAlias(sshConfig *config.SSHConfig, name string) string {
+	alias := name + "-copy"
+	for n := 2; sshConfig.FindHost(alias) >= 0; n++ {
+		alias = fmt.Sprintf("%s-copy-%d", name, n)
+	}
+	return alias
+}
+
+// exportCommand implements "xssh export"
+func exportCommand() *Command {
+	cmd := newCommand("export", "", "Write the hosts to a CSV, JSON or PuTTY file")
+	cmd.Description = `Write every host of ~/.ssh/config with its tags, color and notes, in a
+format "xssh import" reads. The output goes to stdout unless -o is given; the
+format is taken from the extension of the output file unless --format is
+given, and is JSON otherwise. The PuTTY format has no tags, colors, notes or
+jump hosts.`
+	cmd.Examples = []string{
+		"xssh export > hosts.json",
+		"xssh export -o hosts.csv",
+		"xssh export --format putty -o putty.reg",
+	}
+	format := cmd.Flags.String("format", "", "`format` to write: "+strings.Join(inventory.Formats, ", "))
+	output := cmd.Flags.String("o", "", "write to `file` instead of stdout")
+
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("export takes no arguments")
+		}
+		if *format == "" {
+			*format = inventory.DetectFormat(*output)
+		}
+		if *format == "" {
+			*format = "json"
+		}
+
+		sshConfig, err := config.LoadSSHConfig()
+		if err != nil {
+			return errorf(exitConfig, "failed to load SSH config: %v", err)
+		}
+		metadata, _ := config.LoadMetadata()
+		entries := make([]inventory.Entry, 0, len(sshConfig.Hosts))
+		for _, host := range sshConfig.Hosts {
+			entry := inventory.Entry{Host: host}
+			if metadata != nil {
+				entry.Tags = metadata.Tags(host.Name)
+				entry.Color = metadata.Color(host.Name)
+				entry.Notes = metadata.Notes(host.Name)
+			}
+			entries = append(entries, entry)
+		}
+
+		if *output == "" {
+			return inventory.Write(*format, os.Stdout, entries)
+		}
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		if err := inventory.Write(*format, file, entries); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		infof("Exported %d hosts to %s\n", len(entries), *output)
+		return nil
+	}
+	return cmd
+}
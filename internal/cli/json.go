@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+)
+
+// hostJSON is a host as printed by --json
+type hostJSON struct {
+	Name          string            `json:"name"`
+	Host          string            `json:"host"`
+	User          string            `json:"user,omitempty"`
+	Port          string            `json:"port"`
+	Identity      string            `json:"identity,omitempty"`
+	ProxyJump     string            `json:"proxy_jump,omitempty"`
+	Tags          []string          `json:"tags"`
+	Color         string            `json:"color,omitempty"`
+	Notes         string            `json:"notes,omitempty"`
+	Hooks         map[string]string `json:"hooks,omitempty"`
+	Managed       bool              `json:"managed"`
+	PasswordRef   string            `json:"password_ref,omitempty"`
+	PassphraseRef string            `json:"passphrase_ref,omitempty"`
+	AutoForwards  []string          `json:"auto_forwards,omitempty"`
+	LastConnected *time.Time        `json:"last_connected,omitempty"`
+}
+
+// newHostJSON combines a host with its metadata and last connection, which
+// may be zero
+func newHostJSON(host config.SSHHost, metadata *config.Metadata, lastConnected time.Time) hostJSON {
+	h := hostJSON{
+		Name:      host.Name,
+		Host:      host.Host,
+		User:      host.User,
+		Port:      host.Port,
+		Identity:  host.Identity,
+		ProxyJump: host.ProxyJump,
+		Tags:      []string{},
+		Managed:   true,
+	}
+	if metadata != nil {
+		h.Tags = append(h.Tags, metadata.Tags(host.Name)...)
+		h.Color = metadata.Color(host.Name)
+		h.Notes = metadata.Notes(host.Name)
+		h.Hooks = metadata.Hooks(host.Name)
+		h.Managed = metadata.Managed(host.Name)
+		h.PasswordRef, h.PassphraseRef = metadata.SecretRefs(host.Name)
+		h.AutoForwards = metadata.AutoForwards(host.Name)
+	}
+	if !lastConnected.IsZero() {
+		h.LastConnected = &lastConnected
+	}
+	return h
+}
+
+// sessionJSON is a forwarding session as printed by --json
+type sessionJSON struct {
+	ID                   string      `json:"id"`
+	Type                 string      `json:"type"`
+	Description          string      `json:"description"`
+	LocalHost            string      `json:"local_host"`
+	LocalPort            int         `json:"local_port"`
+	RequestedPort        int         `json:"requested_port,omitempty"` // Port asked for when it was in use and a following one was taken
+	RemoteHost           string      `json:"remote_host,omitempty"`
+	RemotePort           int         `json:"remote_port,omitempty"`
+	RemoteSocket         string      `json:"remote_socket,omitempty"` // Unix socket a local forwarding connects to
+	TLS                  string      `json:"tls,omitempty"`           // "self-signed" or the certificate and key files
+	Resolve              string      `json:"resolve,omitempty"`       // Where a local forwarding resolves its target, "remote" or "local"
+	Expiry               string      `json:"expiry,omitempty"`        // Duration or time of day the forwarding stops after
+	Via                  []string    `json:"via,omitempty"`           // Hosts tunneled through before the host, in order
+	Labels               []string    `json:"labels,omitempty"`
+	SSHCommand           string      `json:"ssh_command,omitempty"`  // Plain ssh command opening the same forwarding
+	CaptureFile          string      `json:"capture_file,omitempty"` // Dump file of the traffic, while capturing
+	ExpiresAt            *time.Time  `json:"expires_at,omitempty"`
+	Active               bool        `json:"active"`
+	Degraded             bool        `json:"degraded,omitempty"` // Reconnecting after its SSH connection died
+	ReconnectCount       int64       `json:"reconnect_count,omitempty"`
+	StartTime            time.Time   `json:"start_time"`
+	UptimeSeconds        int64       `json:"uptime_seconds"`
+	ActiveConnections    int64       `json:"active_connections"`
+	ConnectionCount      int64       `json:"connection_count"`
+	BytesReceived        int64       `json:"bytes_received"`
+	BytesSent            int64       `json:"bytes_sent"`
+	ReceivedPerSecond    float64     `json:"received_per_second"` // Over the last 10 seconds
+	SentPerSecond        float64     `json:"sent_per_second"`
+	AvgReceivedPerSecond float64     `json:"avg_received_per_second"` // Since the forwarding started
+	AvgSentPerSecond     float64     `json:"avg_sent_per_second"`
+	ErrorCount           int64       `json:"error_count"`
+	LastError            string      `json:"last_error,omitempty"`
+	Totals               *totalsJSON `json:"totals,omitempty"` // Over all runs of the tunnel, when it ran before
+	Background           bool        `json:"background,omitempty"`
+	Host                 string      `json:"host,omitempty"`
+	PID                  int         `json:"pid,omitempty"`
+}
+
+// newSessionJSON converts a forwarding session for --json
+func newSessionJSON(session *forwarding.ForwardingSession) sessionJSON {
+	rule := session.Rule
+	tunnel := sessionJSON{
+		ID:                rule.ID,
+		Type:              rule.Type.String(),
+		Description:       rule.Description,
+		LocalHost:         rule.LocalHost,
+		LocalPort:         rule.LocalPort,
+		RemoteHost:        rule.RemoteHost,
+		RemotePort:        rule.RemotePort,
+		RemoteSocket:      rule.RemoteSocket,
+		TLS:               rule.TLS.String(),
+		Resolve:           resolveJSON(rule),
+		Active:            session.IsActive(),
+		Degraded:          session.Degraded(),
+		ReconnectCount:    session.Stats.ReconnectCount,
+		StartTime:         session.Stats.StartTime,
+		UptimeSeconds:     int64(session.GetUptime().Seconds()),
+		ActiveConnections: session.Stats.ActiveConnections,
+		ConnectionCount:   session.Stats.ConnectionCount,
+		BytesReceived:     session.Stats.BytesReceived,
+		BytesSent:         session.Stats.BytesSent,
+		ErrorCount:        session.Stats.ErrorCount,
+		LastError:         session.LastError(),
+		Expiry:            rule.Expiry.String(),
+		Via:               rule.Via,
+		Labels:            rule.Labels,
+		CaptureFile:       session.CapturePath(),
+		RequestedPort:     rule.RequestedPort,
+		SSHCommand:        rule.SSHCommand(session.Host()),
+	}
+	if expiresAt := session.ExpiresAt(); !expiresAt.IsZero() {
+		tunnel.ExpiresAt = &expiresAt
+	}
+	tunnel.ReceivedPerSecond, tunnel.SentPerSecond = session.GetCurrentRate()
+	tunnel.AvgReceivedPerSecond, tunnel.AvgSentPerSecond = session.GetTransferRate()
+	if session.Resumed() {
+		totals := session.Totals()
+		tunnel.Totals = &totalsJSON{
+			Since:           totals.Since,
+			ConnectionCount: totals.Connections,
+			BytesReceived:   totals.BytesReceived,
+			BytesSent:       totals.BytesSent,
+		}
+	}
+	return tunnel
+}
+
+// filterFields returns what a session filter looks at in a listed session
+func (s sessionJSON) filterFields() (forwarding.ForwardingRule, string) {
+	rule := forwarding.ForwardingRule{
+		ID:          s.ID,
+		LocalPort:   s.LocalPort,
+		RemotePort:  s.RemotePort,
+		Labels:      s.Labels,
+		Description: s.Description,
+	}
+	for _, forwardingType := range []forwarding.ForwardingType{forwarding.LocalForward, forwarding.RemoteForward,
+		forwarding.DynamicForward, forwarding.RemoteDynamicForward} {
+		if forwardingType.String() == s.Type {
+			rule.Type = forwardingType
+		}
+	}
+	return rule, s.Host
+}
+
+// resolveJSON returns where a local forwarding resolves its target, empty
+// for other types
+func resolveJSON(rule forwarding.ForwardingRule) string {
+	if rule.Type != forwarding.LocalForward || rule.RemoteSocket != "" {
+		return ""
+	}
+	return rule.ResolveString()
+}
+
+// totalsJSON is the statistics of a forwarding over all its runs
+type totalsJSON struct {
+	Since           time.Time `json:"since"`
+	ConnectionCount int64     `json:"connection_count"`
+	BytesReceived   int64     `json:"bytes_received"`
+	BytesSent       int64     `json:"bytes_sent"`
+}
+
+// clientJSON is an SSH connection shared by forwarding sessions
+type clientJSON struct {
+	Address     string    `json:"address"`
+	Host        string    `json:"host"`
+	Opened      time.Time `json:"opened"`
+	Sessions    int       `json:"sessions"`
+	Connections int       `json:"connections"`
+}
+
+// newClientJSON converts the state of an SSH connection for --json
+func newClientJSON(client forwarding.ClientInfo) clientJSON {
+	return clientJSON{
+		Address:     client.Address,
+		Host:        client.Host,
+		Opened:      client.Opened,
+		Sessions:    client.Sessions,
+		Connections: client.Connections,
+	}
+}
+
+// newBackgroundSessionJSON converts a background session for --json. Its
+// traffic counters live in another process and are left at zero.
+func newBackgroundSessionJSON(session forwarding.BackgroundSession) sessionJSON {
+	rule := session.Rule
+	tunnel := sessionJSON{
+		ID:            rule.ID,
+		Type:          rule.Type.String(),
+		Description:   rule.Description,
+		LocalHost:     rule.LocalHost,
+		LocalPort:     rule.LocalPort,
+		RemoteHost:    rule.RemoteHost,
+		RemotePort:    rule.RemotePort,
+		RemoteSocket:  rule.RemoteSocket,
+		TLS:           rule.TLS.String(),
+		Resolve:       resolveJSON(rule),
+		Active:        true,
+		StartTime:     session.Started,
+		UptimeSeconds: int64(time.Since(session.Started).Seconds()),
+		Background:    true,
+		Host:          session.Host,
+		PID:           session.PID,
+		Expiry:        rule.Expiry.String(),
+		Via:           rule.Via,
+		Labels:        rule.Labels,
+		RequestedPort: rule.RequestedPort,
+	}
+	if !session.Expires.IsZero() {
+		tunnel.ExpiresAt = &session.Expires
+	}
+	if host, err := resolveHost(session.Host); err == nil {
+		tunnel.SSHCommand = rule.SSHCommand(host)
+	}
+	return tunnel
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
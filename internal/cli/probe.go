@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"xssh/internal/ssh"
+)
+
+// probeJSON is the result for one host as printed by "xssh test --json"
+type probeJSON struct {
+	Host       string `json:"host"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// testCommand implements "xssh test <alias>..."
+func testCommand() *Command {
+	cmd := newCommand("test", "<alias>...", "Check that hosts are reachable and accept a key")
+	cmd.Description = `Connect and authenticate to each host without opening a shell, using the
+host's key (or the default keys) and ssh-agent, never a password. The exit
+status tells the first failure apart, with the codes of every xssh command:
+
+  0  every host passed
+  1  another error
+  4  no host has the alias
+  5  authentication failed
+  6  the host key differs from the one in known_hosts
+  7  the host name does not resolve
+  8  the connection was refused, or the host is unreachable
+  9  the connection timed out`
+	cmd.Examples = []string{
+		"xssh test web1",
+		"xssh test --timeout 5s web1 web2 db",
+		"xssh test --json web1",
+	}
+	timeout := cmd.Flags.Duration("timeout", 10*time.Second, "give up on a host after `duration`")
+	asJSON := cmd.Flags.Bool("json", false, "print the results as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) == 0 {
+			return cmd.usagef("test takes at least one host alias")
+		}
+
+		// Ctrl+C aborts the remaining hosts
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		var results []probeJSON
+		firstCode := 0
+		for _, alias := range args {
+			result := probeJSON{Host: alias}
+			code := 0
+			if host, err := resolveHost(alias); err != nil {
+				result.Status = ssh.ProbeOther.String()
+				result.Error = err.Error()
+				code = exitCode(err)
+				if code == exitHostNotFound {
+					result.Status = "not-found"
+				}
+			} else {
+				probe := ssh.Probe(ctx, host, *timeout)
+				result.Status = probe.Failure.String()
+				result.DurationMS = probe.Duration.Milliseconds()
+				if probe.Err != nil {
+					result.Error = probe.Err.Error()
+					code = probeExitCodes[probe.Failure]
+				}
+			}
+			results = append(results, result)
+			if firstCode == 0 {
+				firstCode = code
+			}
+
+			if !*asJSON {
+				printProbeResult(result)
+			}
+		}
+
+		if *asJSON {
+			if err := printJSON(results); err != nil {
+				return err
+			}
+		}
+		if firstCode != 0 {
+			return exitError{code: firstCode}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// printProbeResult prints the result for one host, failures on stderr
+func printProbeResult(result probeJSON) {
+	if result.Status == ssh.ProbeOK.String() {
+		fmt.Printf("%s: ok (%dms)\n", result.Host, result.DurationMS)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s: %s\n", result.Host, result.Status, result.Error)
+}
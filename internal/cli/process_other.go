@@ -0,0 +1,34 @@
+//go:build !windows
+
+package cli
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// detachProcess starts cmd in a session of its own, so it outlives the
+// terminal of xssh and its process group can be signaled as a whole
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// hangUpGroup sends SIGHUP to the process group led by pid
+func hangUpGroup(pid int) error {
+	return syscall.Kill(-pid, syscall.SIGHUP)
+}
+
+// notifyRenew relays SIGUSR1, which "xssh forward renew" sends, to c
+func notifyRenew(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
+
+// listenPrivate listens on a Unix socket only the user can connect to
+func listenPrivate(socketPath string) (net.Listener, error) {
+	oldMask := syscall.Umask(0077)
+	defer syscall.Umask(oldMask)
+	return net.Listen("unix", socketPath)
+}
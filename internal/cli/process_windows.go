@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess starts cmd in a process group of its own, so Ctrl+C in the
+// console of xssh does not reach it
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// hangUpGroup ends the process pid; Windows has no hangup signal
+func hangUpGroup(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	defer process.Release()
+	return process.Kill()
+}
+
+// notifyRenew does nothing, as Windows has no signal for "xssh forward
+// renew" to send
+func notifyRenew(c chan<- os.Signal) {}
+
+// listenPrivate listens on a Unix socket, which is private to the user
+// through the permissions of its directory
+func listenPrivate(socketPath string) (net.Listener, error) {
+	return net.Listen("unix", socketPath)
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// reloadJSON is the outcome of reloading the config of the daemon, as
+// served by POST /v1/reload
+type reloadJSON struct {
+	Added         []string `json:"added,omitempty"`          // [tunnels] profiles new to the config
+	Changed       []string `json:"changed,omitempty"`        // Profiles whose forwarding was reopened with its new settings
+	Removed       []string `json:"removed,omitempty"`        // Profiles gone from the config, whose forwarding was stopped
+	RestartNeeded []string `json:"restart_needed,omitempty"` // Changed settings the daemon only reads when it starts
+}
+
+// reload re-reads config.toml: it applies the settings shared with the
+// other commands, such as keepalives and [forwarding], takes [daemon]
+// terminal, and updates the [tunnels] profiles. Forwardings the changes do
+// not touch stay open. A config that fails to load is reported and the
+// daemon keeps running with the one it had.
+func (d *daemonServer) reload() (reloadJSON, error) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	appConfig, err := config.LoadAppConfig()
+	if err != nil {
+		return reloadJSON{}, errorf(exitConfig, "failed to load xssh config, keeping the current one: %v", err)
+	}
+	applySettings(appConfig)
+
+	var result reloadJSON
+	d.mu.Lock()
+	previous := d.config
+	d.config = appConfig.Daemon
+	d.mu.Unlock()
+	// The API and health listeners are opened once
+	if appConfig.Daemon.Socket != previous.Socket {
+		result.RestartNeeded = append(result.RestartNeeded, "daemon.socket")
+	}
+	if appConfig.Daemon.Listen != previous.Listen {
+		result.RestartNeeded = append(result.RestartNeeded, "daemon.listen")
+	}
+	if appConfig.Daemon.Dashboard != previous.Dashboard {
+		result.RestartNeeded = append(result.RestartNeeded, "daemon.dashboard")
+	}
+	if appConfig.Daemon.Health != previous.Health {
+		result.RestartNeeded = append(result.RestartNeeded, "daemon.health")
+	}
+
+	result.Added, result.Changed, result.Removed = d.scheduler.reload(appConfig.Tunnels, time.Now())
+	slog.Info("daemon config reloaded", "added", result.Added, "changed", result.Changed,
+		"removed", result.Removed, "restart_needed", result.RestartNeeded)
+	return result, nil
+}
+
+// reloadConfig serves POST /v1/reload
+func (d *daemonServer) reloadConfig(w http.ResponseWriter, r *http.Request) {
+	result, err := d.reload()
+	if err != nil {
+		writeAPIErrorCode(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, result)
+}
+
+// reloadOnSignal reloads the config whenever the daemon receives SIGHUP
+func (d *daemonServer) reloadOnSignal(signals <-chan os.Signal) {
+	defer recoverDaemon()
+	for range signals {
+		if _, err := d.reload(); err != nil {
+			slog.Error("daemon config not reloaded", "error", err)
+		}
+	}
+}
+
+// reloadDaemon implements "xssh daemon reload"
+func reloadDaemon() error {
+	var result reloadJSON
+	if err := daemonRequest(http.MethodPost, "/v1/reload", nil, &result); err != nil {
+		return err
+	}
+	infof("Daemon config reloaded\n")
+	for _, change := range []struct {
+		what  string
+		names []string
+	}{
+		{"New tunnels", result.Added},
+		{"Reopened tunnels", result.Changed},
+		{"Removed tunnels", result.Removed},
+	} {
+		if len(change.names) > 0 {
+			infof("  %s: %s\n", change.what, strings.Join(change.names, ", "))
+		}
+	}
+	if len(result.RestartNeeded) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: restart the daemon to apply %s\n", strings.Join(result.RestartNeeded, ", "))
+	}
+	return nil
+}
+
+// sameForwarding reports whether two versions of a [tunnels] profile open
+// the same forwarding, which then stays open through a reload. Windows are
+// left out, the schedule applies them.
+func sameForwarding(a, b config.TunnelProfile) bool {
+	return a.Host == b.Host && a.Rule == b.Rule && slices.Equal(a.Via, b.Via) && slices.Equal(a.Labels, b.Labels)
+}
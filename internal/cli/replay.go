@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/recording"
+)
+
+// replayCommand implements "xssh replay [file]"
+func replayCommand() *Command {
+	cmd := newCommand("replay", "[file]", "Play back a recorded session")
+	cmd.Description = `Play back a session recording with its original timing. Without a file, list
+the recordings in the recordings directory, newest first; a file name without
+a directory is looked up there.
+
+The output of remote commands run from the TUI is recorded for the hosts
+matched by "hosts" in the [recording] section of config.toml, as asciicast v2
+(also played by asciinema) or as a typescript with a scriptreplay timing
+file.`
+	cmd.Examples = []string{
+		"xssh replay",
+		"xssh replay web1-20261016-101500.000.cast",
+		"xssh replay --speed 2 --idle-limit 1s ~/recordings/web1.cast",
+	}
+	speed := cmd.Flags.Float64("speed", 1, "playback speed factor")
+	idleLimit := cmd.Flags.Duration("idle-limit", 0, "longest pause to play, such as 2s; 0 keeps the recorded pauses")
+	instant := cmd.Flags.Bool("instant", false, "print the output at once, without pauses")
+	cmd.Run = func(args []string) error {
+		if len(args) > 1 {
+			return cmd.usagef("replay takes at most one file")
+		}
+		if *speed <= 0 {
+			return cmd.usagef("--speed must be positive")
+		}
+		appConfig, err := config.LoadAppConfig()
+		if err != nil {
+			return errorf(exitConfig, "failed to load xssh config: %v", err)
+		}
+		dir, err := appConfig.RecordingDir()
+		if err != nil {
+			return errorf(exitConfig, "cannot find the recordings directory: %v", err)
+		}
+		if len(args) == 0 {
+			return listRecordings(dir)
+		}
+
+		path := args[0]
+		if _, err := os.Stat(path); os.IsNotExist(err) && filepath.Base(path) == path {
+			path = filepath.Join(dir, path)
+		}
+		err = recording.Replay(path, os.Stdout, recording.ReplayOptions{
+			Speed:     *speed,
+			IdleLimit: *idleLimit,
+			Instant:   *instant,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot replay %s: %v", path, err)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// listRecordings prints the recordings in dir, newest first
+func listRecordings(dir string) error {
+	entries, err := recording.List(dir)
+	if err != nil {
+		return fmt.Errorf("cannot list recordings: %v", err)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No recordings in %s.\n", dir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RECORDED\tSIZE\tFILE")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			entry.ModTime.Local().Format("2006-01-02 15:04"),
+			forwarding.FormatBytes(entry.Size),
+			filepath.Base(entry.Path),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	infof("\nRecordings are in %s\n", dir)
+	return nil
+}
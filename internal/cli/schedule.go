@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+)
+
+// scheduleInterval is how often the daemon checks the windows of the
+// [tunnels] profiles
+const scheduleInterval = 15 * time.Second
+
+// tunnelScheduler opens the [tunnels] profiles of the config in the daemon
+// when their windows start and stops them when they end
+type tunnelScheduler struct {
+	manager  *forwarding.ForwardingManager
+	mu       sync.Mutex
+	profiles []config.TunnelProfile
+	running  map[string]bool   // Whether each profile was last started or stopped, by name
+	errors   map[string]string // Why each profile last failed to start, by name
+}
+
+// scheduleJSON is a [tunnels] profile as served by GET /v1/schedule
+type scheduleJSON struct {
+	Name           string     `json:"name"`
+	Host           string     `json:"host"`
+	Rule           string     `json:"rule"`
+	Windows        string     `json:"windows,omitempty"` // Empty for always
+	Labels         []string   `json:"labels,omitempty"`
+	Active         bool       `json:"active"`                    // Whether one of its windows is open
+	Running        bool       `json:"running"`                   // Whether its forwarding is open
+	NextTransition *time.Time `json:"next_transition,omitempty"` // When it next starts or stops
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// newTunnelScheduler returns a scheduler of profiles for manager
+func newTunnelScheduler(manager *forwarding.ForwardingManager, profiles []config.TunnelProfile) *tunnelScheduler {
+	return &tunnelScheduler{
+		manager:  manager,
+		profiles: profiles,
+		running:  map[string]bool{},
+		errors:   map[string]string{},
+	}
+}
+
+// run applies the schedule now and every scheduleInterval until stop is
+// closed
+func (s *tunnelScheduler) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		s.apply(time.Now())
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// apply starts the profiles whose window is open and stops those whose
+// window closed. A profile is only started or stopped when its window opens
+// or closes, so a tunnel stopped by hand stays stopped until the next one; a
+// profile that failed to start is tried again.
+func (s *tunnelScheduler) apply(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, profile := range s.profiles {
+		active := profile.Windows.Active(now)
+		if running, applied := s.running[profile.Name]; applied && running == active {
+			continue
+		}
+		if !active {
+			if s.manager.StopForwarding(profile.Name) == nil {
+				slog.Info("scheduled forwarding stopped", "name", profile.Name, "next", profile.Windows.Next(now))
+			}
+			s.running[profile.Name] = false
+			continue
+		}
+		if err := s.start(profile); err != nil {
+			if s.errors[profile.Name] != err.Error() {
+				slog.Warn("scheduled forwarding failed to start", "name", profile.Name, "error", err)
+			}
+			s.errors[profile.Name] = err.Error()
+			continue
+		}
+		delete(s.errors, profile.Name)
+		s.running[profile.Name] = true
+		slog.Info("scheduled forwarding started", "name", profile.Name, "host", profile.Host)
+	}
+}
+
+// reload replaces the profiles with those of a reloaded config and applies
+// the schedule at now. The forwardings of removed profiles are stopped, and
+// those of profiles whose forwarding changed are reopened; the others stay
+// open. It returns the names of the profiles added, changed and removed.
+func (s *tunnelScheduler) reload(profiles []config.TunnelProfile, now time.Time) (added, changed, removed []string) {
+	s.mu.Lock()
+	previous := map[string]config.TunnelProfile{}
+	for _, profile := range s.profiles {
+		previous[profile.Name] = profile
+	}
+	for _, profile := range profiles {
+		old, found := previous[profile.Name]
+		delete(previous, profile.Name)
+		switch {
+		case !found:
+			added = append(added, profile.Name)
+		case !sameForwarding(old, profile):
+			changed = append(changed, profile.Name)
+			s.forget(profile.Name)
+		}
+	}
+	for _, profile := range s.profiles {
+		if _, gone := previous[profile.Name]; gone {
+			removed = append(removed, profile.Name)
+			s.forget(profile.Name)
+		}
+	}
+	s.profiles = profiles
+	s.mu.Unlock()
+
+	s.apply(now)
+	return added, changed, removed
+}
+
+// forget stops the forwarding of a profile if the schedule started it and
+// drops its state, so the next apply starts it anew when its window is
+// open. Callers hold s.mu.
+func (s *tunnelScheduler) forget(name string) {
+	if s.running[name] && s.manager.StopForwarding(name) == nil {
+		slog.Info("scheduled forwarding stopped for a config change", "name", name)
+	}
+	delete(s.running, name)
+	delete(s.errors, name)
+}
+
+// start opens the forwarding of a profile, with the profile's name as ID
+func (s *tunnelScheduler) start(profile config.TunnelProfile) error {
+	rule, err := parseForwardingRule(profile.Rule)
+	if err != nil {
+		return fmt.Errorf("invalid forwarding rule: %v", err)
+	}
+	rule.ID = profile.Name
+	rule.Via = profile.Via
+	rule.PortRetries = forwarding.DefaultPortRetries
+	if rule.Labels, err = forwarding.ParseLabels(strings.Join(profile.Labels, ",")); err != nil {
+		return err
+	}
+	host, err := resolveHost(profile.Host)
+	if err != nil {
+		return err
+	}
+	auth, err := referencedAuth(host)
+	if err != nil {
+		return err
+	}
+	_, err = s.manager.StartForwarding(context.Background(), *rule, host, auth)
+	return err
+}
+
+// filterFields returns what a session filter looks at in a scheduled
+// tunnel: its name as the ID, and the ports and type of its rule
+func (p scheduleJSON) filterFields() (forwarding.ForwardingRule, string) {
+	rule := forwarding.ForwardingRule{}
+	if parsed, err := parseForwardingRule(p.Rule); err == nil {
+		rule = *parsed
+	}
+	rule.ID = p.Name
+	rule.Labels = p.Labels
+	return rule, p.Host
+}
+
+// list returns the profiles with their state at now
+func (s *tunnelScheduler) list(now time.Time) []scheduleJSON {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []scheduleJSON{}
+	for _, profile := range s.profiles {
+		entry := scheduleJSON{
+			Name:      profile.Name,
+			Host:      profile.Host,
+			Rule:      profile.Rule,
+			Windows:   profile.Windows.String(),
+			Labels:    profile.Labels,
+			LastError: s.errors[profile.Name],
+		}
+		entry.Active = profile.Windows.Active(now)
+		_, entry.Running = s.manager.GetSession(profile.Name)
+		if next := profile.Windows.Next(now); !next.IsZero() {
+			entry.NextTransition = &next
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// schedule serves GET /v1/schedule, the [tunnels] profiles of the daemon
+func (d *daemonServer) schedule(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, d.scheduler.list(time.Now()))
+}
+
+// listDaemonSchedule returns the [tunnels] profiles of the xssh daemon, or
+// none when it is not running
+func listDaemonSchedule() []scheduleJSON {
+	var profiles []scheduleJSON
+	if err := daemonRequest(http.MethodGet, "/v1/schedule", nil, &profiles); err != nil {
+		return nil
+	}
+	return profiles
+}
+
+// scheduleLine describes the state of a [tunnels] profile and its next
+// transition
+func scheduleLine(profile scheduleJSON) string {
+	state := "stopped"
+	if profile.Running {
+		state = "running"
+	}
+	if profile.Windows == "" {
+		return state + ", always on"
+	}
+	line := fmt.Sprintf("%s, %s", state, profile.Windows)
+	if profile.NextTransition != nil {
+		next := "starts"
+		if profile.Active {
+			next = "stops"
+		}
+		line += fmt.Sprintf(", %s %s", next, profile.NextTransition.Format("Mon 2006-01-02 15:04"))
+	}
+	return line
+}
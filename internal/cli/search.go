@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"xssh/internal/config"
+)
+
+// searchCommand implements "xssh search <query>"
+func searchCommand() *Command {
+	cmd := newCommand("search", "<query>...", "Print the hosts matching a search")
+	cmd.Description = `Print the hosts matching a search, the same way "/" filters the host list in
+the TUI. Every word must match the name, host, user, port, key, tags or notes
+of a host; a word can be restricted to a field with name:, host:, user:,
+port:, key:, tag:, note: or color:. Hosts hidden from the TUI are left out
+unless --all is given. The exit status is 1 when no host matches.`
+	cmd.Examples = []string{
+		"xssh search prod db",
+		"xssh search tag:prod user:root --json",
+		"xssh connect $(xssh search --names web | head -n1)",
+	}
+	asJSON := cmd.Flags.Bool("json", false, "print the hosts as JSON")
+	names := cmd.Flags.Bool("names", false, "print only the aliases, one per line")
+	all := cmd.Flags.Bool("all", false, "include hosts not managed by xssh")
+
+	cmd.Run = func(args []string) error {
+		if len(args) == 0 {
+			return cmd.usagef("search takes at least one word to search for")
+		}
+		if *asJSON && *names {
+			return cmd.usagef("--json and --names cannot be used together")
+		}
+		sshConfig, err := config.LoadSSHConfig()
+		if err != nil {
+			return errorf(exitConfig, "failed to load SSH config: %v", err)
+		}
+		metadata, err := config.LoadMetadata()
+		if err != nil {
+			return errorf(exitConfig, "failed to load host metadata: %v", err)
+		}
+
+		terms := config.ParseSearchQuery(strings.Join(args, " "))
+		var matches []config.SSHHost
+		for _, host := range sshConfig.Hosts {
+			if (*all || metadata.Managed(host.Name)) && metadata.HostMatches(host, terms) {
+				matches = append(matches, host)
+			}
+		}
+
+		switch {
+		case *asJSON:
+			history, _ := config.LoadConnectionHistory()
+			lastConnections := config.LastConnections(history)
+			hosts := make([]hostJSON, 0, len(matches))
+			for _, host := range matches {
+				hosts = append(hosts, newHostJSON(host, metadata, lastConnections[host.Name]))
+			}
+			if err := printJSON(hosts); err != nil {
+				return err
+			}
+		case *names:
+			for _, host := range matches {
+				fmt.Println(host.Name)
+			}
+		case len(matches) > 0:
+			printHostTable(matches, metadata)
+		}
+
+		if len(matches) == 0 {
+			return exitError{code: 1}
+		}
+		return nil
+	}
+	return cmd
+}
+
+// printHostTable prints hosts as aligned columns with a header
+func printHostTable(hosts []config.SSHHost, metadata *config.Metadata) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tHOST\tUSER\tPORT\tTAGS")
+	for _, host := range hosts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", host.Name, host.Host, displayValue(host.User),
+			host.Port, strings.Join(metadata.Tags(host.Name), ","))
+	}
+	w.Flush()
+}
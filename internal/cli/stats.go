@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+)
+
+// recordSession adds a finished session to the session store. The store
+// only feeds the history and the statistics, so failing to write it is only
+// logged.
+func recordSession(record config.SessionRecord) {
+	if err := config.RecordSession(record); err != nil {
+		slog.Warn("failed to record session", "kind", record.Kind, "host", record.Host, "error", err)
+	}
+}
+
+// sessionFilter selects recorded sessions by host, kind and age
+type sessionFilter struct {
+	host  string
+	kind  string
+	since time.Duration
+}
+
+// addFlags registers the --host, --kind and --since flags of cmd
+func (f *sessionFilter) addFlags(cmd *Command) {
+	cmd.Flags.StringVar(&f.host, "host", "", "only sessions with this host")
+	cmd.Flags.StringVar(&f.kind, "kind", "", "only sessions of this kind: connect, exec or forward")
+	cmd.Flags.Func("since", "only sessions started within this time, such as 12h or 7d", func(value string) error {
+		since, err := config.ParseAge(value)
+		f.since = since
+		return err
+	})
+}
+
+// load reads the recorded sessions the filter selects, oldest first
+func (f *sessionFilter) load() ([]config.SessionRecord, error) {
+	switch f.kind {
+	case "", config.SessionConnect, config.SessionExec, config.SessionForward:
+	default:
+		return nil, errorf(exitUsage, "unknown session kind %q, expected connect, exec or forward", f.kind)
+	}
+	sessions, err := config.LoadSessions()
+	if err != nil {
+		return nil, errorf(exitConfig, "failed to load the session history: %v", err)
+	}
+
+	var selected []config.SessionRecord
+	for _, session := range sessions {
+		if f.host != "" && session.Host != f.host {
+			continue
+		}
+		if f.kind != "" && session.Kind != f.kind {
+			continue
+		}
+		if f.since > 0 && time.Since(session.Start) > f.since {
+			continue
+		}
+		selected = append(selected, session)
+	}
+	return selected, nil
+}
+
+// historyCommand implements "xssh history"
+func historyCommand() *Command {
+	cmd := newCommand("history", "", "Show the recorded sessions")
+	cmd.Description = `List the connections, remote commands and port forwardings made from xssh,
+newest first, with how long they lasted, how they ended and the bytes they
+moved. Interactive sessions run ssh directly, so their traffic is not known.`
+	cmd.Examples = []string{
+		"xssh history",
+		"xssh history --host web1 --since 7d",
+		"xssh history --kind forward -n 50",
+	}
+	var filter sessionFilter
+	filter.addFlags(cmd)
+	limit := cmd.Flags.Int("n", 20, "number of sessions to show, 0 for all")
+	asJSON := cmd.Flags.Bool("json", false, "print the sessions as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("history takes no arguments")
+		}
+		sessions, err := filter.load()
+		if err != nil {
+			return err
+		}
+		if *limit > 0 && len(sessions) > *limit {
+			sessions = sessions[len(sessions)-*limit:]
+		}
+
+		// Newest first
+		for i, j := 0, len(sessions)-1; i < j; i, j = i+1, j-1 {
+			sessions[i], sessions[j] = sessions[j], sessions[i]
+		}
+		if *asJSON {
+			if sessions == nil {
+				sessions = []config.SessionRecord{}
+			}
+			return printJSON(sessions)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No sessions recorded.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STARTED\tKIND\tHOST\tDURATION\tRESULT\tTRAFFIC\tDETAIL")
+		for _, session := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				session.Start.Local().Format("2006-01-02 15:04"),
+				session.Kind,
+				session.Host,
+				session.Duration().Round(time.Second),
+				sessionResult(session),
+				formatTraffic(session.BytesSent, session.BytesReceived),
+				session.Detail,
+			)
+		}
+		return w.Flush()
+	}
+	return cmd
+}
+
+// sessionResult describes how a recorded session ended
+func sessionResult(session config.SessionRecord) string {
+	switch {
+	case session.ExitCode > 0:
+		return fmt.Sprintf("exit %d", session.ExitCode)
+	case session.Error != "" && session.Kind == config.SessionForward:
+		// A forwarding outlives the errors of its connections
+		return "ok, with errors"
+	case session.Error != "":
+		return "failed"
+	default:
+		return "ok"
+	}
+}
+
+// statsJSON is the summary of one host as printed by "xssh stats --json"
+type statsJSON struct {
+	Host            string    `json:"host"`
+	Connections     int       `json:"connections"`
+	Commands        int       `json:"commands"`
+	Forwards        int       `json:"forwards"`
+	Failures        int       `json:"failures"`
+	DurationSeconds int64     `json:"duration_seconds"`
+	BytesSent       int64     `json:"bytes_sent"`
+	BytesReceived   int64     `json:"bytes_received"`
+	LastUsed        time.Time `json:"last_used"`
+}
+
+// statsCommand implements "xssh stats"
+func statsCommand() *Command {
+	cmd := newCommand("stats", "", "Show usage statistics per host")
+	cmd.Description = `Sum up the recorded sessions per host, the most recently used first: the
+number of connections, remote commands and port forwardings, how many of them
+failed, the time spent in them and the bytes they moved.`
+	cmd.Examples = []string{
+		"xssh stats",
+		"xssh stats --since 30d",
+		"xssh stats --kind forward --json",
+	}
+	var filter sessionFilter
+	filter.addFlags(cmd)
+	asJSON := cmd.Flags.Bool("json", false, "print the statistics as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("stats takes no arguments")
+		}
+		sessions, err := filter.load()
+		if err != nil {
+			return err
+		}
+		stats := config.SummarizeSessions(sessions)
+
+		if *asJSON {
+			hosts := make([]statsJSON, 0, len(stats))
+			for _, host := range stats {
+				hosts = append(hosts, statsJSON{
+					Host:            host.Host,
+					Connections:     host.Connections,
+					Commands:        host.Commands,
+					Forwards:        host.Forwards,
+					Failures:        host.Failures,
+					DurationSeconds: int64(host.Duration.Seconds()),
+					BytesSent:       host.BytesSent,
+					BytesReceived:   host.BytesReceived,
+					LastUsed:        host.Last,
+				})
+			}
+			return printJSON(hosts)
+		}
+		if len(stats) == 0 {
+			fmt.Println("No sessions recorded.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "HOST\tCONNECT\tEXEC\tFORWARD\tFAILED\tTIME\tTRAFFIC\tLAST USED")
+		for _, host := range stats {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n",
+				host.Host,
+				host.Connections,
+				host.Commands,
+				host.Forwards,
+				host.Failures,
+				host.Duration.Round(time.Second),
+				formatTraffic(host.BytesSent, host.BytesReceived),
+				host.Last.Local().Format("2006-01-02 15:04"),
+			)
+		}
+		return w.Flush()
+	}
+	return cmd
+}
+
+// formatTraffic shows bytes sent and received, or "-" when none are known
+func formatTraffic(sent, received int64) string {
+	if sent == 0 && received == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("↑%s ↓%s", forwarding.FormatBytes(sent), forwarding.FormatBytes(received))
+}
+
+// formatRates shows bytes per second received and sent
+func formatRates(received, sent float64) string {
+	return fmt.Sprintf("↓%s/s ↑%s/s", forwarding.FormatBytes(int64(received)), forwarding.FormatBytes(int64(sent)))
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"xssh/internal/tmux"
+)
+
+// tmuxCommand implements "xssh tmux <alias...>"
+func tmuxCommand() *Command {
+	cmd := newCommand("tmux", "<alias...>", "Open hosts in tmux windows or panes")
+	cmd.Description = `Connect to each host in a tmux window of its own, or in panes with --layout:
+
+  windows  A new window per host (the default)
+  panes    Split the current window into a pane per host
+  tiled    One new window with a pane per host, tiled
+
+Inside tmux the windows are added to the current session. Outside tmux, a new
+session is created with them and attached, "panes" acting as "tiled".
+--sync types into all panes at once, as cluster ssh tools do.
+
+In the TUI, w opens the marked hosts, or the selected one, in windows and W
+opens them tiled.`
+	cmd.Examples = []string{
+		"xssh tmux web1 web2 db1",
+		"xssh tmux --layout tiled --sync web1 web2 web3",
+		"xssh tmux --layout panes web-prod-03  # Matched by 'Host web-*'",
+	}
+	layoutName := cmd.Flags.String("layout", "windows", "windows, panes or tiled")
+	sync := cmd.Flags.Bool("sync", false, "send typed keys to all panes of the window")
+	cmd.Run = func(args []string) error {
+		if len(args) == 0 {
+			return cmd.usagef("tmux takes at least one host alias")
+		}
+		layout, err := tmux.ParseLayout(*layoutName)
+		if err != nil {
+			return cmd.usagef("%v", err)
+		}
+		targets, err := tmuxTargets(args)
+		if err != nil {
+			return err
+		}
+		session, err := tmux.Open(targets, tmux.Options{Layout: layout, Synchronize: *sync})
+		if err != nil {
+			return err
+		}
+		if session == "" {
+			infof("Opened %d host(s) in tmux\n", len(targets))
+			return nil
+		}
+		return tmux.Attach(session)
+	}
+	return cmd
+}
+
+// tmuxTargets resolves the aliases and returns the xssh connect commands
+// opening them
+func tmuxTargets(aliases []string) ([]tmux.Target, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the xssh binary: %v", err)
+	}
+	targets := make([]tmux.Target, 0, len(aliases))
+	for _, alias := range aliases {
+		if _, err := resolveHost(alias); err != nil {
+			return nil, err
+		}
+		targets = append(targets, tmux.Target{
+			Name:    alias,
+			Command: []string{executable, "connect", alias},
+		})
+	}
+	return targets, nil
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+	"xssh/internal/ui"
+)
+
+// runTUI starts the interactive TUI and connects to the host picked in it
+func runTUI(opts *Options) error {
+	selectedHost, selectedArgs, err := selectHost(opts, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if selectedHost != nil {
+		return connect(*selectedHost, selectedArgs...)
+	}
+	return nil
+}
+
+// selectHost runs the TUI, drawing it on output, and returns the host picked
+// in it, or nil when the user quit, with the ssh arguments picked for it
+func selectHost(opts *Options, output io.Writer) (*config.SSHHost, []string, error) {
+	// Inline mode renders in place so the picker and the selection stay in
+	// the scrollback
+	var p *tea.Program
+	if opts.Inline {
+		p = tea.NewProgram(crashGuard{ui.NewModel().WithInline().WithTerminals(daemonTerminals{})}, tea.WithOutput(output))
+	} else {
+		p = tea.NewProgram(crashGuard{ui.NewModel().WithTerminals(daemonTerminals{})}, tea.WithAltScreen(), tea.WithOutput(output))
+	}
+
+	model, err := p.Run()
+	if errors.Is(err, tea.ErrProgramPanic) {
+		return nil, nil, tuiCrashError(err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("alas, there's been an error: %v", err)
+	}
+
+	// Check if we need to connect to a host
+	if guard, ok := model.(crashGuard); ok {
+		model = guard.Model
+	}
+	if finalModel, ok := model.(ui.Model); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		finalModel.Shutdown(ctx)
+		return finalModel.GetSelectedHost(), finalModel.GetSelectedArgs(), nil
+	}
+	return nil, nil, nil
+}
+
+// pickCommand implements "xssh pick", which runs the TUI only to choose a
+// host and prints it for the shell
+func pickCommand(opts *Options) *Command {
+	cmd := newCommand("pick", "", "Choose a host in the TUI and print it")
+	cmd.Description = `Run the TUI as a selector: the chosen alias, or its ssh command with
+--print-command, is printed to stdout instead of connecting. The TUI is drawn
+on stderr so the output can be captured. Exits with status 1 when no host was
+chosen.`
+	cmd.Examples = []string{
+		"ssh $(xssh pick)",
+		"eval \"$(xssh pick --print-command)\"",
+		"xssh --inline pick             # In place, e.g. in a tmux popup",
+	}
+	cmd.TUI = true
+	printCommand := cmd.Flags.Bool("print-command", false, "print the ssh command instead of the alias")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("pick takes no arguments")
+		}
+
+		// Stdout may be a pipe, so colors are decided by the terminal the
+		// TUI is drawn on
+		lipgloss.SetDefaultRenderer(lipgloss.NewRenderer(os.Stderr))
+		selectedHost, _, err := selectHost(opts, os.Stderr)
+		if err != nil {
+			return err
+		}
+		if selectedHost == nil {
+			return fmt.Errorf("no host selected")
+		}
+
+		if *printCommand {
+			fmt.Println(ssh.BuildSSHCommand(*selectedHost))
+		} else {
+			fmt.Println(selectedHost.Name)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// connectionBanner returns the line printed before connecting, colored with
+// the host's label
+func connectionBanner(host config.SSHHost) string {
+	metadata, _ := config.LoadMetadata()
+	appConfig, _ := config.LoadAppConfig()
+	return ui.ResolveTheme(appConfig.Theme).Glyphs(ui.ConnectionBanner(host, metadata.Color(host.Name)))
+}
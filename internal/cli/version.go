@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"xssh/internal/update"
+	"xssh/internal/version"
+)
+
+// versionCommand implements "xssh version"
+func versionCommand() *Command {
+	cmd := newCommand("version", "", "Show version information")
+	cmd.BrokenConfigOK = true
+	cmd.Description = `Show the version, commit and build time of xssh. "xssh -v" and
+"xssh --version" run this command too, taking --json after them.`
+	cmd.Examples = []string{
+		"xssh version",
+		"xssh -v --json",
+	}
+	asJSON := cmd.Flags.Bool("json", false, "print the version as JSON")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("version takes no arguments")
+		}
+		if *asJSON {
+			return printJSON(version.Get())
+		}
+		ShowVersion()
+		return nil
+	}
+	return cmd
+}
+
+// ShowVersion displays version information
+func ShowVersion() {
+	info := version.Get()
+	fmt.Printf("xssh %s\n", info.Version)
+	fmt.Println("SSH Connection Manager with Port Forwarding")
+	if info.Commit != "" {
+		fmt.Printf("Commit:   %s\n", info.Commit)
+	}
+	if info.Date != "" {
+		fmt.Printf("Built:    %s\n", info.Date)
+	}
+	fmt.Printf("Go:       %s (%s)\n", info.GoVersion, info.Platform)
+}
+
+// selfUpdateCommand implements "xssh self-update"
+func selfUpdateCommand() *Command {
+	cmd := newCommand("self-update", "", "Update xssh to the latest release")
+	cmd.BrokenConfigOK = true
+	cmd.Description = fmt.Sprintf(`Check GitHub for the latest release of xssh and, when it is newer than this
+binary, download %s, verify it against the release checksums and
+replace the running binary with it. The old binary stays in place if
+anything fails.
+
+A development build, without a release version, is only replaced with
+--force.`, update.AssetName())
+	cmd.Examples = []string{
+		"xssh self-update --check",
+		"xssh self-update",
+	}
+	check := cmd.Flags.Bool("check", false, "only report whether a newer release exists")
+	force := cmd.Flags.Bool("force", false, "install the latest release even if it is not newer")
+	cmd.Run = func(args []string) error {
+		if len(args) > 0 {
+			return cmd.usagef("self-update takes no arguments")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+
+		release, err := update.Latest(ctx)
+		if err != nil {
+			return err
+		}
+		newer := release.Newer(version.Version)
+		if *check {
+			if newer {
+				fmt.Printf("xssh %s is available (this is %s): %s\n", release.Tag, version.Version, release.URL)
+			} else {
+				fmt.Printf("xssh %s is the latest release (this is %s)\n", release.Tag, version.Version)
+			}
+			return nil
+		}
+		if !newer && !*force {
+			if !version.IsRelease() {
+				return fmt.Errorf("this is a development build, use --force to replace it with %s", release.Tag)
+			}
+			infof("xssh %s is up to date\n", version.Version)
+			return nil
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("cannot find the running binary: %v", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+			executable = resolved
+		}
+		infof("Updating %s from %s to %s\n", executable, version.Version, release.Tag)
+		if err := update.Install(ctx, release, executable); err != nil {
+			return err
+		}
+		infof("Updated xssh to %s\n", release.Tag)
+		return nil
+	}
+	return cmd
+}
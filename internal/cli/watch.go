@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"xssh/internal/forwarding"
+)
+
+// watchInterval is how often "forward list --watch" refreshes
+const watchInterval = time.Second
+
+// Terminal controls of the watch mode
+const (
+	clearScreen    = "\033[2J"
+	cursorHome     = "\033[H"
+	clearLineEnd   = "\033[K"
+	clearScreenEnd = "\033[J"
+	hideCursor     = "\033[?25l"
+	showCursor     = "\033[?25h"
+)
+
+// watchForwarding shows the forwardings matching filter one line each,
+// redrawn in place every second like top until Ctrl+C. The forwardings and
+// their traffic come from the daemon; without it only the background
+// forwardings are shown, without traffic.
+func watchForwarding(filter forwarding.SessionFilter) error {
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		return errorf(exitUsage, "--watch redraws a terminal; use --json to poll the sessions")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Print(hideCursor + clearScreen)
+	defer fmt.Print(showCursor)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		frame := watchFrame(filter, time.Now())
+		// Overwrite the previous frame line by line rather than clearing
+		// the screen first, which flickers
+		frame = strings.ReplaceAll(frame, "\n", clearLineEnd+"\n")
+		fmt.Print(cursorHome + frame + clearScreenEnd)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchFrame renders one refresh of the watch mode at now
+func watchFrame(filter forwarding.SessionFilter, now time.Time) string {
+	var frame bytes.Buffer
+	fmt.Fprintf(&frame, "xssh forwardings at %s, every %v (Ctrl+C to quit)\n", now.Format("15:04:05"), watchInterval)
+
+	var tunnels []sessionJSON
+	if err := daemonRequest(http.MethodGet, "/v1/tunnels", nil, &tunnels); err != nil {
+		fmt.Fprintf(&frame, "Background forwardings only, without traffic: %v\n", err)
+		// The records of background sessions are readable without it
+		backgroundSessions, _ := forwarding.BackgroundSessions()
+		for _, session := range backgroundSessions {
+			tunnels = append(tunnels, newBackgroundSessionJSON(session))
+		}
+	}
+	tunnels = keepMatching(filter, tunnels, sessionJSON.filterFields)
+	sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].ID < tunnels[j].ID })
+	fmt.Fprintln(&frame)
+
+	if len(tunnels) == 0 {
+		if len(filter) > 0 {
+			fmt.Fprintln(&frame, "No port forwarding sessions match the filter.")
+		} else {
+			fmt.Fprintln(&frame, "No active port forwarding sessions.")
+		}
+		return frame.String()
+	}
+
+	w := tabwriter.NewWriter(&frame, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tHOST\tPORT\tCONNS\tRATE\tTRAFFIC\tSTATE")
+	var active, connections int64
+	var received, sent float64
+	for _, tunnel := range tunnels {
+		port := tunnel.LocalPort
+		if tunnel.Type == forwarding.RemoteForward.String() || tunnel.Type == forwarding.RemoteDynamicForward.String() {
+			port = tunnel.RemotePort
+		}
+		if tunnel.Background {
+			// Its counters live in another process
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t-\t-\t-\t%s\n", tunnel.ID, tunnel.Type, tunnel.Host, port, watchState(tunnel, now))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d/%d\t%s\t%s\t%s\n", tunnel.ID, tunnel.Type, tunnel.Host, port,
+			tunnel.ActiveConnections, tunnel.ConnectionCount,
+			formatRates(tunnel.ReceivedPerSecond, tunnel.SentPerSecond),
+			formatTraffic(tunnel.BytesSent, tunnel.BytesReceived), watchState(tunnel, now))
+		active += tunnel.ActiveConnections
+		connections += tunnel.ConnectionCount
+		received += tunnel.ReceivedPerSecond
+		sent += tunnel.SentPerSecond
+	}
+	w.Flush()
+	fmt.Fprintf(&frame, "\n%d sessions, %d connections open, %d in total, %s\n",
+		len(tunnels), active, connections, formatRates(received, sent))
+	return frame.String()
+}
+
+// watchState sums up the state of a forwarding in the watch mode
+func watchState(tunnel sessionJSON, now time.Time) string {
+	var states []string
+	switch {
+	case tunnel.Background:
+		states = append(states, fmt.Sprintf("background, pid %d", tunnel.PID))
+	case tunnel.Degraded:
+		states = append(states, "reconnecting")
+	case !tunnel.Active:
+		states = append(states, "stopped")
+	default:
+		states = append(states, "up "+now.Sub(tunnel.StartTime).Round(time.Second).String())
+	}
+	if tunnel.ErrorCount > 0 {
+		states = append(states, fmt.Sprintf("%d errors", tunnel.ErrorCount))
+	}
+	if tunnel.ExpiresAt != nil {
+		states = append(states, "expires in "+tunnel.ExpiresAt.Sub(now).Round(time.Second).String())
+	}
+	return strings.Join(states, ", ")
+}
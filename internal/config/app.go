@@ -0,0 +1,642 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppConfig holds settings for xssh itself, as opposed to the SSH hosts
+// stored in ~/.ssh/config
+type AppConfig struct {
+	Theme      ThemeConfig
+	List       ListConfig
+	Onboarding OnboardingConfig
+	Filters    FiltersConfig
+	Log        LogConfig
+	Forwarding ForwardingConfig
+	Connection ConnectionConfig
+	Keys       map[string]string // Keys of host list actions, keyed by action name
+	Hooks      map[string]string // Shell commands run for every host, keyed by hook event
+	Daemon     DaemonConfig
+	Recording  RecordingConfig
+	Bastions   BastionPolicy // Jump hosts required for matching hosts
+	Retry      RetryConfig
+	Discovery  DiscoveryConfig
+	Audit      AuditConfig
+	Crash      CrashConfig
+	Tunnels    []TunnelProfile // Forwardings the daemon opens, ordered by name
+	Path       string
+}
+
+// ThemeConfig selects a built-in theme and optional per-color overrides
+type ThemeConfig struct {
+	Name   string            // Built-in theme name ("dark", "light", "solarized", "high-contrast", "mono")
+	Colors map[string]string // Color overrides keyed by theme color name
+	ASCII  bool              // Draw with ASCII characters only, no box drawing or symbols
+}
+
+// ListConfig holds settings of the host list
+type ListConfig struct {
+	Columns []string // Columns of the host table, in order; empty for the default set
+}
+
+// OnboardingConfig records the first-run review of ~/.ssh/config
+type OnboardingConfig struct {
+	Done bool // Whether the onboarding wizard has been completed
+}
+
+// FiltersConfig holds the named host filters and the filter that was active
+// when xssh last exited
+type FiltersConfig struct {
+	Saved map[string]string // Filter queries keyed by name
+	Last  string
+}
+
+// LogConfig controls the diagnostic log
+type LogConfig struct {
+	Level string // "off", "error", "warn", "info" or "debug"; empty for off
+	File  string // Log file; empty for xssh.log in the config directory
+}
+
+// ForwardingConfig holds defaults of port forwardings
+type ForwardingConfig struct {
+	BindAddress string // Address forwardings listen on when a rule names none
+	PortRetries int    // Following ports tried when the port of a forwarding is in use; 0 fails at once
+}
+
+// ConnectionConfig holds settings of the connections xssh makes, and of the
+// ssh commands it runs
+type ConnectionConfig struct {
+	KeepAliveInterval time.Duration // Time between keepalive requests; 0 leaves it to ssh
+	KeepAliveCountMax int           // Unanswered keepalives before the connection is dropped
+}
+
+// DaemonConfig holds settings of the xssh daemon
+type DaemonConfig struct {
+	Socket    string // Control socket; empty for daemon.sock in the config directory
+	Listen    string // TCP address the API also listens on, such as "127.0.0.1:7878"; empty for none
+	Terminal  string // Command opening a terminal, followed by the xssh connect command to run in it
+	Dashboard bool   // Whether the web dashboard is served on the TCP address
+	Health    string // TCP address serving the health of the forwardings without the token; empty for none
+}
+
+// RecordingConfig selects the hosts whose remote command runs are recorded,
+// and where and how long recordings are kept
+type RecordingConfig struct {
+	Hosts    string        // Host patterns, as on a Host line; empty records nothing
+	Format   string        // "asciicast" or "typescript"
+	Dir      string        // Directory of the recordings; empty for recordings in the config directory
+	MaxAge   time.Duration // Recordings older than this are deleted; 0 keeps them
+	MaxFiles int           // Only the newest recordings are kept; 0 keeps them all
+}
+
+// DiscoveryConfig names where the host list finds hosts in DNS. Discovered
+// hosts are listed apart from the configured ones until they are added.
+type DiscoveryConfig struct {
+	SRV   []string // SRV records to look up, such as "_ssh._tcp.example.com"
+	Files []string // Zone files, such as saved "dig axfr" output, or lists of host names
+}
+
+// AuditConfig controls the audit log of connections and config changes
+type AuditConfig struct {
+	Enabled bool   // Whether events are written to audit.jsonl
+	Syslog  string // "local", or a udp:// or tcp:// address events are also sent to; empty for none
+}
+
+// CrashConfig controls what happens to crash reports besides being written
+// to the crashes directory
+type CrashConfig struct {
+	ReportURL string // Endpoint reports are posted to as JSON; empty sends nothing
+}
+
+// Records reports whether sessions with the host called name are recorded
+func (c RecordingConfig) Records(name string) bool {
+	return c.Hosts != "" && MatchHostPatterns(c.Hosts, name)
+}
+
+// ParseAge parses an age such as "12h" or "30d": a Go duration, or a whole
+// number of days
+func ParseAge(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return age, nil
+}
+
+// RecordingDir returns the directory of the session recordings
+func (c *AppConfig) RecordingDir() (string, error) {
+	if c.Recording.Dir != "" {
+		return expandPath(c.Recording.Dir), nil
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "recordings"), nil
+}
+
+// DefaultAppConfig returns the configuration used when no config file exists
+func DefaultAppConfig() *AppConfig {
+	return &AppConfig{
+		Theme: ThemeConfig{
+			Name:   "dark",
+			Colors: map[string]string{},
+		},
+		Filters: FiltersConfig{
+			Saved: map[string]string{},
+		},
+		Forwarding: ForwardingConfig{
+			BindAddress: "localhost",
+		},
+		Connection: ConnectionConfig{
+			KeepAliveCountMax: 3,
+		},
+		Recording: RecordingConfig{
+			Format: "asciicast",
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+		},
+		Retry: RetryConfig{
+			Default: DefaultRetryPolicy(),
+		},
+		Keys:  map[string]string{},
+		Hooks: map[string]string{},
+	}
+}
+
+// DaemonSocket returns the control socket of the xssh daemon
+func (c *AppConfig) DaemonSocket() (string, error) {
+	if c.Daemon.Socket != "" {
+		return expandPath(c.Daemon.Socket), nil
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "daemon.sock"), nil
+}
+
+// DaemonToken returns the token clients of the daemon API authenticate
+// with, kept in daemon.token in the config directory. With create, a
+// missing token is generated.
+func DaemonToken(create bool) (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	tokenPath := filepath.Join(configDir, "daemon.token")
+	data, err := os.ReadFile(tokenPath)
+	if err == nil || !os.IsNotExist(err) || !create {
+		return strings.TrimSpace(string(data)), err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(secret)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return "", err
+	}
+	return token, os.WriteFile(tokenPath, []byte(token+"\n"), 0600)
+}
+
+// ConfigDir returns the directory holding xssh's own files, ~/.config/xssh
+// unless XSSH_CONFIG_DIR is set
+func ConfigDir() (string, error) {
+	if dir := envPath(EnvConfigDir); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".config", "xssh"), nil
+}
+
+// AppConfigPath returns the location of the xssh config file, config.toml
+// in ConfigDir unless XSSH_CONFIG is set
+func AppConfigPath() (string, error) {
+	if path := envPath(EnvConfig); path != "" {
+		return path, nil
+	}
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "config.toml"), nil
+}
+
+// LoadAppConfig reads ~/.config/xssh/config.toml, falling back to defaults
+// for anything the file does not set. The XSSH_ environment variables take
+// precedence over the file.
+func LoadAppConfig() (*AppConfig, error) {
+	appConfig := DefaultAppConfig()
+	defer appConfig.applyEnv()
+
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return appConfig, err
+	}
+	appConfig.Path = configPath
+
+	file, err := os.Open(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return appConfig, nil
+		}
+		return appConfig, err
+	}
+	defer file.Close()
+
+	doc, err := parseTOML(file)
+	if err != nil {
+		return appConfig, err
+	}
+
+	if name, ok, err := doc.String("theme", "name"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Theme.Name = name
+	}
+
+	if ascii, ok, err := doc.Bool("theme", "ascii"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Theme.ASCII = ascii
+	}
+
+	for _, key := range doc.Keys("theme.colors") {
+		color, _, err := doc.String("theme.colors", key)
+		if err != nil {
+			return appConfig, err
+		}
+		appConfig.Theme.Colors[key] = color
+	}
+
+	if columns, ok, err := doc.StringArray("list", "columns"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.List.Columns = columns
+	}
+
+	if done, ok, err := doc.Bool("onboarding", "done"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Onboarding.Done = done
+	}
+
+	if last, ok, err := doc.String("filters", "last"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Filters.Last = last
+	}
+
+	if level, ok, err := doc.String("log", "level"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Log.Level = level
+	}
+
+	if file, ok, err := doc.String("log", "file"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Log.File = file
+	}
+
+	if address, ok, err := doc.String("forwarding", "bind_address"); err != nil {
+		return appConfig, err
+	} else if ok && address != "" {
+		appConfig.Forwarding.BindAddress = address
+	}
+
+	if retries, ok, err := doc.Int("forwarding", "port_retries"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if retries < 0 {
+			return appConfig, fmt.Errorf("forwarding.port_retries: expected zero or a positive integer, got %d", retries)
+		}
+		appConfig.Forwarding.PortRetries = retries
+	}
+
+	if interval, ok, err := doc.String("connection", "keepalive_interval"); err != nil {
+		return appConfig, err
+	} else if ok {
+		duration, err := time.ParseDuration(interval)
+		if err != nil || duration < 0 {
+			return appConfig, fmt.Errorf("connection.keepalive_interval: expected a duration like \"30s\", got %q", interval)
+		}
+		appConfig.Connection.KeepAliveInterval = duration
+	}
+
+	if count, ok, err := doc.Int("connection", "keepalive_count_max"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if count < 1 {
+			return appConfig, fmt.Errorf("connection.keepalive_count_max: expected a positive integer, got %d", count)
+		}
+		appConfig.Connection.KeepAliveCountMax = count
+	}
+
+	for _, action := range doc.Keys("keys") {
+		key, _, err := doc.String("keys", action)
+		if err != nil {
+			return appConfig, err
+		}
+		appConfig.Keys[action] = key
+	}
+
+	for _, event := range doc.Keys("hooks") {
+		if !slices.Contains(HookEvents, event) {
+			return appConfig, fmt.Errorf("hooks.%s: unknown hook event, expected one of %s", event, strings.Join(HookEvents, ", "))
+		}
+		command, _, err := doc.String("hooks", event)
+		if err != nil {
+			return appConfig, err
+		}
+		appConfig.Hooks[event] = command
+	}
+
+	if socket, ok, err := doc.String("daemon", "socket"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Daemon.Socket = socket
+	}
+
+	if listen, ok, err := doc.String("daemon", "listen"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Daemon.Listen = listen
+	}
+
+	if terminal, ok, err := doc.String("daemon", "terminal"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Daemon.Terminal = terminal
+	}
+
+	if dashboard, ok, err := doc.Bool("daemon", "dashboard"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Daemon.Dashboard = dashboard
+	}
+
+	if health, ok, err := doc.String("daemon", "health"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Daemon.Health = health
+	}
+
+	if hosts, ok, err := doc.String("recording", "hosts"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Recording.Hosts = hosts
+	}
+
+	if format, ok, err := doc.String("recording", "format"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if format != "asciicast" && format != "typescript" {
+			return appConfig, fmt.Errorf("recording.format: expected \"asciicast\" or \"typescript\", got %q", format)
+		}
+		appConfig.Recording.Format = format
+	}
+
+	if dir, ok, err := doc.String("recording", "dir"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Recording.Dir = dir
+	}
+
+	if maxAge, ok, err := doc.String("recording", "max_age"); err != nil {
+		return appConfig, err
+	} else if ok {
+		duration, err := ParseAge(maxAge)
+		if err != nil {
+			return appConfig, fmt.Errorf("recording.max_age: expected a duration like \"720h\" or \"30d\", got %q", maxAge)
+		}
+		appConfig.Recording.MaxAge = duration
+	}
+
+	if maxFiles, ok, err := doc.Int("recording", "max_files"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if maxFiles < 0 {
+			return appConfig, fmt.Errorf("recording.max_files: expected a positive integer, got %d", maxFiles)
+		}
+		appConfig.Recording.MaxFiles = maxFiles
+	}
+
+	bastions := doc.Keys("bastions")
+	sort.Strings(bastions)
+	for _, bastion := range bastions {
+		patterns, _, err := doc.String("bastions", bastion)
+		if err != nil {
+			return appConfig, err
+		}
+		if strings.TrimSpace(patterns) == "" {
+			return appConfig, fmt.Errorf("bastions.%s: expected host patterns like \"10.1.*.*\"", bastion)
+		}
+		appConfig.Bastions = append(appConfig.Bastions, BastionRule{Bastion: bastion, Patterns: patterns})
+	}
+
+	for _, key := range []string{"attempts", "jitter", "breaker_threshold"} {
+		if n, ok, err := doc.Int("retry", key); err != nil {
+			return appConfig, err
+		} else if ok {
+			if err := appConfig.Retry.Default.setRetryValue(key, strconv.Itoa(n)); err != nil {
+				return appConfig, fmt.Errorf("retry.%v", err)
+			}
+		}
+	}
+	for _, key := range []string{"backoff", "max_backoff", "breaker_cooldown"} {
+		if value, ok, err := doc.String("retry", key); err != nil {
+			return appConfig, err
+		} else if ok {
+			if err := appConfig.Retry.Default.setRetryValue(key, value); err != nil {
+				return appConfig, fmt.Errorf("retry.%v", err)
+			}
+		}
+	}
+
+	retryPatterns := doc.Keys("retry.hosts")
+	sort.Strings(retryPatterns)
+	for _, patterns := range retryPatterns {
+		spec, _, err := doc.String("retry.hosts", patterns)
+		if err != nil {
+			return appConfig, err
+		}
+		policy, err := parseRetryOverrides(appConfig.Retry.Default, spec)
+		if err != nil {
+			return appConfig, fmt.Errorf("retry.hosts.%s: %v", patterns, err)
+		}
+		appConfig.Retry.Hosts = append(appConfig.Retry.Hosts, RetryRule{Patterns: patterns, Policy: policy})
+	}
+
+	if srv, ok, err := doc.StringArray("discovery", "srv"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Discovery.SRV = srv
+	}
+
+	if files, ok, err := doc.StringArray("discovery", "files"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Discovery.Files = files
+	}
+
+	if enabled, ok, err := doc.Bool("audit", "enabled"); err != nil {
+		return appConfig, err
+	} else if ok {
+		appConfig.Audit.Enabled = enabled
+	}
+
+	if target, ok, err := doc.String("audit", "syslog"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if target != "" {
+			if _, _, err := ParseSyslogTarget(target); err != nil {
+				return appConfig, fmt.Errorf("audit.syslog: %v", err)
+			}
+		}
+		appConfig.Audit.Syslog = target
+	}
+
+	if reportURL, ok, err := doc.String("crash", "report_url"); err != nil {
+		return appConfig, err
+	} else if ok {
+		if reportURL != "" && !strings.HasPrefix(reportURL, "https://") && !strings.HasPrefix(reportURL, "http://") {
+			return appConfig, fmt.Errorf("crash.report_url: expected an http:// or https:// URL, got %q", reportURL)
+		}
+		appConfig.Crash.ReportURL = reportURL
+	}
+
+	if appConfig.Tunnels, err = loadTunnelProfiles(doc); err != nil {
+		return appConfig, err
+	}
+
+	for _, name := range doc.Keys("filters.saved") {
+		query, _, err := doc.String("filters.saved", name)
+		if err != nil {
+			return appConfig, err
+		}
+		appConfig.Filters.Saved[name] = query
+	}
+
+	return appConfig, nil
+}
+
+// SaveListColumns stores the columns of the host table in the config file,
+// leaving the rest of the file untouched
+func SaveListColumns(columns []string) error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	return setTOMLValue(configPath, "list", "columns", encodeTOMLStringArray(columns))
+}
+
+// SaveOnboardingDone records in the config file that the onboarding wizard
+// has been completed, so it is not shown again
+func SaveOnboardingDone() error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	return setTOMLValue(configPath, "onboarding", "done", "true")
+}
+
+// SaveLastFilter stores the active host filter, restored on the next run
+func SaveLastFilter(query string) error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	return setTOMLValue(configPath, "filters", "last", encodeTOMLString(query))
+}
+
+// SaveFilter stores a named host filter, replacing one of the same name
+func SaveFilter(name, query string) error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	return setTOMLValue(configPath, "filters.saved", name, encodeTOMLString(query))
+}
+
+// DeleteFilter removes a named host filter
+func DeleteFilter(name string) error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	return removeTOMLValue(configPath, "filters.saved", name)
+}
+
+// RenameConfigHost moves the references of the config file to the host
+// oldName to newName: the host and via of [tunnels.<name>] sections and the
+// [bastions] key of the host. The rest of the file is left untouched.
+func RenameConfigHost(oldName, newName string) error {
+	configPath, err := AppConfigPath()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	doc, err := parseTOML(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+
+	for _, name := range doc.Subsections("tunnels") {
+		section := "tunnels." + name
+		if host, _, _ := doc.String(section, "host"); host == oldName {
+			if err := setTOMLValue(configPath, section, "host", encodeTOMLString(newName)); err != nil {
+				return err
+			}
+		}
+		via, _, _ := doc.StringArray(section, "via")
+		if !slices.Contains(via, oldName) {
+			continue
+		}
+		for i, hop := range via {
+			if hop == oldName {
+				via[i] = newName
+			}
+		}
+		if err := setTOMLValue(configPath, section, "via", encodeTOMLStringArray(via)); err != nil {
+			return err
+		}
+	}
+
+	if patterns, ok := doc["bastions"][oldName]; ok {
+		if err := removeTOMLValue(configPath, "bastions", oldName); err != nil {
+			return err
+		}
+		return setTOMLValue(configPath, "bastions", newName, patterns)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppConfigRoundTrip(t *testing.T) {
+	useTempConfig(t)
+	path, err := AppConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := "# Local settings\n[list]\ncolumns = [\"name\"]\n\n[theme]\nname = \"dracula\" # kept\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SaveListColumns([]string{"name", "host", "user"}); err != nil {
+		t.Fatalf("SaveListColumns: %v", err)
+	}
+	if err := SaveFilter("prod", `tag:prod "db server"`); err != nil {
+		t.Fatalf("SaveFilter: %v", err)
+	}
+	if err := SaveFilter("dev", "tag:dev"); err != nil {
+		t.Fatalf("SaveFilter: %v", err)
+	}
+	if err := SaveLastFilter("web"); err != nil {
+		t.Fatalf("SaveLastFilter: %v", err)
+	}
+	if err := DeleteFilter("dev"); err != nil {
+		t.Fatalf("DeleteFilter: %v", err)
+	}
+
+	appConfig, err := LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig: %v", err)
+	}
+	if want := []string{"name", "host", "user"}; !reflect.DeepEqual(appConfig.List.Columns, want) {
+		t.Errorf("columns = %q, want %q", appConfig.List.Columns, want)
+	}
+	if want := map[string]string{"prod": `tag:prod "db server"`}; !reflect.DeepEqual(appConfig.Filters.Saved, want) {
+		t.Errorf("saved filters = %q, want %q", appConfig.Filters.Saved, want)
+	}
+	if appConfig.Filters.Last != "web" {
+		t.Errorf("last filter = %q, want %q", appConfig.Filters.Last, "web")
+	}
+
+	// Settings the edits did not touch are left as they were
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"# Local settings", `name = "dracula" # kept`} {
+		if !slices.Contains(strings.Split(string(data), "\n"), line) {
+			t.Errorf("config.toml lost %q:\n%s", line, data)
+		}
+	}
+}
+
+func TestAppConfigMultiLineArray(t *testing.T) {
+	useTempConfig(t)
+	path, err := AppConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := `[tunnels.db]
+host = "bastion"
+rule = "db"
+windows = [
+  "Mon-Fri 09:00-18:00", # office hours
+  "Sat 10:00-12:00",
+]
+
+[list]
+columns = ["name"]
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	appConfig, err := LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig: %v", err)
+	}
+	if len(appConfig.Tunnels) != 1 || len(appConfig.Tunnels[0].Windows) != 2 {
+		t.Fatalf("tunnels = %+v, want db with two windows", appConfig.Tunnels)
+	}
+
+	// Replacing the array replaces all of its lines
+	if err := setTOMLValue(path, "tunnels.db", "windows", encodeTOMLStringArray([]string{"daily 08:00-20:00"})); err != nil {
+		t.Fatalf("setTOMLValue: %v", err)
+	}
+	if appConfig, err = LoadAppConfig(); err != nil {
+		t.Fatalf("LoadAppConfig after the edit: %v", err)
+	}
+	if windows := appConfig.Tunnels[0].Windows; len(windows) != 1 {
+		t.Errorf("windows = %v, want one", windows)
+	}
+	if err := removeTOMLValue(path, "tunnels.db", "windows"); err != nil {
+		t.Fatalf("removeTOMLValue: %v", err)
+	}
+	if err := SaveListColumns([]string{"name", "host"}); err != nil {
+		t.Fatalf("SaveListColumns: %v", err)
+	}
+	if appConfig, err = LoadAppConfig(); err != nil {
+		t.Fatalf("LoadAppConfig after the removal: %v", err)
+	}
+	if len(appConfig.Tunnels[0].Windows) != 0 || len(appConfig.List.Columns) != 2 {
+		t.Errorf("windows = %v, columns = %v", appConfig.Tunnels[0].Windows, appConfig.List.Columns)
+	}
+
+	// An array left open is named
+	if err := os.WriteFile(path, []byte("[list]\ncolumns = [\n  \"name\",\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAppConfig(); err == nil || !strings.Contains(err.Error(), "list.columns is not closed") {
+		t.Errorf("LoadAppConfig of an open array: %v", err)
+	}
+}
+
+func TestRenameConfigHost(t *testing.T) {
+	useTempConfig(t)
+	path, err := AppConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := `[bastions]
+jump = "10.1.*.*" # office network
+edge = "10.2.*.*"
+
+[tunnels.db]
+host = "jump"
+rule = "5432:db:5432"
+via = [
+  "edge",
+  "jump",
+]
+
+[tunnels.web]
+host = "web"
+rule = "8080:localhost:80"
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameConfigHost("jump", "gateway"); err != nil {
+		t.Fatalf("RenameConfigHost: %v", err)
+	}
+
+	appConfig, err := LoadAppConfig()
+	if err != nil {
+		t.Fatalf("LoadAppConfig: %v", err)
+	}
+	db, web := appConfig.Tunnels[0], appConfig.Tunnels[1]
+	if db.Host != "gateway" || !slices.Equal(db.Via, []string{"edge", "gateway"}) {
+		t.Errorf("tunnels.db host = %q, via = %q", db.Host, db.Via)
+	}
+	if web.Host != "web" {
+		t.Errorf("tunnels.web host = %q, want it untouched", web.Host)
+	}
+	want := BastionPolicy{{Bastion: "edge", Patterns: "10.2.*.*"}, {Bastion: "gateway", Patterns: "10.1.*.*"}}
+	if !slices.Equal(appConfig.Bastions, want) {
+		t.Errorf("bastions = %+v, want %+v", appConfig.Bastions, want)
+	}
+
+	// A missing config file has nothing to rename
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameConfigHost("gateway", "jump"); err != nil {
+		t.Errorf("RenameConfigHost without a config file: %v", err)
+	}
+}
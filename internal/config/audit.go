@@ -0,0 +1,262 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditConnect      = "connect"       // Interactive ssh session started
+	AuditExec         = "exec"          // Remote command run
+	AuditKeyInstalled = "key-installed" // Public key added to authorized_keys
+	AuditHostAdded    = "host-added"
+	AuditHostUpdated  = "host-updated"
+	AuditHostDeleted  = "host-deleted"
+	AuditTunnelOpened = "tunnel-opened"
+	AuditTunnelClosed = "tunnel-closed"
+)
+
+// AuditActions lists the actions of the audit log, for messages and flags
+var AuditActions = []string{
+	AuditConnect, AuditExec, AuditKeyInstalled, AuditHostAdded, AuditHostUpdated,
+	AuditHostDeleted, AuditTunnelOpened, AuditTunnelClosed,
+}
+
+// AuditEvent is one entry of the audit log: who did what, when, to which
+// host
+type AuditEvent struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`              // Local user running xssh
+	Machine string    `json:"machine,omitempty"` // Host name of the machine running xssh
+	Action  string    `json:"action"`
+	Host    string    `json:"host,omitempty"`   // Host alias
+	Detail  string    `json:"detail,omitempty"` // Command, forwarding rule or changed fields
+}
+
+// String formats the event as a syslog message
+func (e AuditEvent) String() string {
+	message := fmt.Sprintf("user=%s action=%s", e.User, e.Action)
+	if e.Host != "" {
+		message += " host=" + e.Host
+	}
+	if e.Detail != "" {
+		message += fmt.Sprintf(" detail=%q", e.Detail)
+	}
+	return message
+}
+
+// auditWriter forwards audit events to syslog
+type auditWriter interface {
+	Info(message string) error
+	Close() error
+}
+
+// audit holds the settings of the audit log, handed over by SetAudit, and
+// the connection to syslog once one is made
+var audit = struct {
+	sync.Mutex
+	config AuditConfig
+	syslog auditWriter
+}{config: AuditConfig{Enabled: true}}
+
+// SetAudit sets whether the audit log is written and where it is forwarded
+func SetAudit(c AuditConfig) {
+	audit.Lock()
+	defer audit.Unlock()
+	if audit.syslog != nil && audit.config.Syslog != c.Syslog {
+		audit.syslog.Close()
+		audit.syslog = nil
+	}
+	audit.config = c
+}
+
+// AuditPath returns the location of the audit log, a file with one JSON
+// event per line
+func AuditPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "audit.jsonl"), nil
+}
+
+// RecordAudit appends an event to the audit log, filling in the time and
+// the user, and forwards it to syslog when [audit] syslog is set. Unlike the
+// session store the log is never compacted or rewritten.
+func RecordAudit(event AuditEvent) error {
+	audit.Lock()
+	defer audit.Unlock()
+	if !audit.config.Enabled {
+		return nil
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.User == "" {
+		event.User = auditUser()
+	}
+	if event.Machine == "" {
+		event.Machine, _ = os.Hostname()
+	}
+
+	auditPath, err := AuditPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0700); err != nil {
+		return err
+	}
+	// Keep "->" and the like readable for whoever reads the raw log
+	var line bytes.Buffer
+	encoder := json.NewEncoder(&line)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(event); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(auditPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if audit.config.Syslog == "" {
+		return nil
+	}
+	if audit.syslog == nil {
+		network, address, err := ParseSyslogTarget(audit.config.Syslog)
+		if err != nil {
+			return err
+		}
+		if audit.syslog, err = dialAuditSyslog(network, address); err != nil {
+			return fmt.Errorf("cannot connect to syslog: %v", err)
+		}
+	}
+	return audit.syslog.Info(event.String())
+}
+
+// auditUser names the user running xssh. Under sudo the invoking user is
+// named too, as on a shared jump box that is who acted.
+func auditUser() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" && sudoUser != name {
+		return fmt.Sprintf("%s (sudo %s)", sudoUser, name)
+	}
+	return name
+}
+
+// ParseSyslogTarget parses the [audit] syslog setting: "local" for the
+// local syslog daemon, or a udp:// or tcp:// address such as
+// "udp://logs.example.com:514"
+func ParseSyslogTarget(target string) (network, address string, err error) {
+	if target == "local" {
+		return "", "", nil
+	}
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "udp" && parsed.Scheme != "tcp") || parsed.Host == "" {
+		return "", "", fmt.Errorf("invalid syslog target %q, expected \"local\", udp://host:port or tcp://host:port", target)
+	}
+	address = parsed.Host
+	if parsed.Port() == "" {
+		address += ":514"
+	}
+	return parsed.Scheme, address, nil
+}
+
+// LoadAudit reads the audit log, oldest first. A missing log yields no
+// events, and lines that cannot be parsed are skipped.
+func LoadAudit() ([]AuditEvent, error) {
+	auditPath, err := AuditPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var event AuditEvent
+		if json.Unmarshal(scanner.Bytes(), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// auditHostChanges records the hosts added, changed and removed between the
+// saved and the new host list of an SSH config
+func auditHostChanges(saved, hosts []SSHHost) error {
+	old := make(map[string]SSHHost, len(saved))
+	for _, host := range saved {
+		old[host.Name] = host
+	}
+
+	var events []AuditEvent
+	for _, host := range hosts {
+		previous, existed := old[host.Name]
+		delete(old, host.Name)
+		if !existed {
+			events = append(events, AuditEvent{Action: AuditHostAdded, Host: host.Name, Detail: hostSummary(host)})
+			continue
+		}
+		var changes []string
+		for _, diff := range DiffHosts(previous, host) {
+			if diff.Changed() {
+				changes = append(changes, fmt.Sprintf("%s: %s -> %s", diff.Field, diff.Old, diff.New))
+			}
+		}
+		if len(changes) > 0 {
+			events = append(events, AuditEvent{Action: AuditHostUpdated, Host: host.Name, Detail: strings.Join(changes, ", ")})
+		}
+	}
+	for _, host := range saved {
+		if _, removed := old[host.Name]; removed {
+			events = append(events, AuditEvent{Action: AuditHostDeleted, Host: host.Name, Detail: hostSummary(host)})
+		}
+	}
+
+	for _, event := range events {
+		if err := RecordAudit(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hostSummary describes a host in an audit event
+func hostSummary(host SSHHost) string {
+	summary := host.Host
+	if host.User != "" {
+		summary = host.User + "@" + summary
+	}
+	if host.Port != "" && host.Port != "22" {
+		summary += ":" + host.Port
+	}
+	return summary
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package config
+
+import "log/syslog"
+
+// dialAuditSyslog connects to the syslog daemon at address, or to the local
+// one when network is empty, logging as xssh to the auth facility
+func dialAuditSyslog(network, address string) (auditWriter, error) {
+	return syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, "xssh")
+}
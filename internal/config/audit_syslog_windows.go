@@ -0,0 +1,9 @@
+package config
+
+import "errors"
+
+// dialAuditSyslog fails, as Windows has no syslog; the audit log file is
+// still written
+func dialAuditSyslog(network, address string) (auditWriter, error) {
+	return nil, errors.New("syslog is not available on Windows")
+}
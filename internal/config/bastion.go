@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BastionRule requires the hosts matched by Patterns to be reached through
+// the jump host Bastion
+type BastionRule struct {
+	Bastion  string // Alias of the jump host
+	Patterns string // Host patterns, as on a Host line, matched against aliases and addresses
+}
+
+// BastionPolicy is the [bastions] section of the xssh config, ordered by
+// bastion alias. The first rule matching a host applies.
+type BastionPolicy []BastionRule
+
+// Bastion returns the jump host the policy requires for host, or "" when no
+// rule matches it. A bastion is never required for itself.
+func (p BastionPolicy) Bastion(host SSHHost) string {
+	for _, rule := range p {
+		if host.Name == rule.Bastion {
+			continue
+		}
+		if MatchHostPatterns(rule.Patterns, host.Name) || MatchHostPatterns(rule.Patterns, host.Host) {
+			return rule.Bastion
+		}
+	}
+	return ""
+}
+
+// Apply returns host with the jump host the policy requires when the host
+// sets none. Hosts with a ProxyJump of their own, including "none", are
+// returned as they are and reported by Check.
+func (p BastionPolicy) Apply(host SSHHost) SSHHost {
+	if host.ProxyJump == "" {
+		host.ProxyJump = p.Bastion(host)
+	}
+	return host
+}
+
+// Check reports a host whose ProxyJump bypasses the bastion the policy
+// requires for it. Hosts without a ProxyJump comply, as Apply adds it.
+func (p BastionPolicy) Check(host SSHHost) error {
+	bastion := p.Bastion(host)
+	if bastion == "" || host.ProxyJump == "" {
+		return nil
+	}
+	for _, hop := range strings.Split(host.ProxyJump, ",") {
+		if strings.TrimSpace(hop) == bastion {
+			return nil
+		}
+	}
+	return fmt.Errorf("jumps through %s, but the bastion policy requires %s", host.ProxyJump, bastion)
+}
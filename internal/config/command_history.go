@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// maxCommandHistory is the number of remote commands remembered
+const maxCommandHistory = 100
+
+// CommandHistoryPath returns the location of the remote command history
+func CommandHistoryPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "command_history.json"), nil
+}
+
+// LoadCommandHistory reads the remote commands run from xssh, oldest first.
+// A missing history file yields an empty history.
+func LoadCommandHistory() ([]string, error) {
+	historyPath, err := CommandHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []string
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// SaveCommandHistory writes the remote command history
+func SaveCommandHistory(history []string) error {
+	historyPath, err := CommandHistoryPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath, data, 0600)
+}
+
+// AppendCommandHistory adds a command as the newest history entry, dropping
+// an earlier copy of it and the oldest entries beyond the history limit
+func AppendCommandHistory(history []string, command string) []string {
+	updated := make([]string, 0, len(history)+1)
+	for _, previous := range history {
+		if previous != command {
+			updated = append(updated, previous)
+		}
+	}
+	updated = append(updated, command)
+
+	if len(updated) > maxCommandHistory {
+		updated = updated[len(updated)-maxCommandHistory:]
+	}
+	return updated
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// ConnectionRecord is one connection made to a configured host
+type ConnectionRecord struct {
+	Host string    `json:"host"` // Host alias
+	Time time.Time `json:"time"`
+}
+
+// ConnectionHistoryPath returns the location of the connection history
+// written by earlier versions of xssh. Connections are now recorded in the
+// session store.
+func ConnectionHistoryPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "connection_history.json"), nil
+}
+
+// LoadConnectionHistory reads the connections made from xssh, oldest first:
+// the interactive sessions in the session store, after those of the older
+// history file
+func LoadConnectionHistory() ([]ConnectionRecord, error) {
+	history, err := loadLegacyConnectionHistory()
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := LoadSessions()
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		if session.Kind == SessionConnect {
+			history = append(history, ConnectionRecord{Host: session.Host, Time: session.Start})
+		}
+	}
+	// Sessions are stored when they end, so a long session is stored after
+	// shorter ones started later
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Time.Before(history[j].Time)
+	})
+	return history, nil
+}
+
+// loadLegacyConnectionHistory reads the older history file. A missing file
+// yields an empty history.
+func loadLegacyConnectionHistory() ([]ConnectionRecord, error) {
+	historyPath, err := ConnectionHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(historyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []ConnectionRecord
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// RenameConnectionHistory moves the connections recorded for the host oldName
+// to newName, in the session store, the forwarding totals and the older
+// history file
+func RenameConnectionHistory(oldName, newName string) error {
+	if err := RenameSessions(oldName, newName); err != nil {
+		return err
+	}
+	if err := RenameForwardTotals(oldName, newName); err != nil {
+		return err
+	}
+	history, err := loadLegacyConnectionHistory()
+	if err != nil {
+		return err
+	}
+
+	renamed := false
+	for i := range history {
+		if history[i].Host == oldName {
+			history[i].Host = newName
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil
+	}
+	return saveConnectionHistory(history)
+}
+
+// saveConnectionHistory writes the connection history
+func saveConnectionHistory(history []ConnectionRecord) error {
+	historyPath, err := ConnectionHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(historyPath, data, 0600)
+}
+
+// RecentHosts returns up to limit distinct host aliases from the history,
+// most recently connected first
+func RecentHosts(history []ConnectionRecord, limit int) []string {
+	var recent []string
+	for i := len(history) - 1; i >= 0 && len(recent) < limit; i-- {
+		if !slices.Contains(recent, history[i].Host) {
+			recent = append(recent, history[i].Host)
+		}
+	}
+	return recent
+}
+
+// LastConnections returns when each host in the history was last connected to
+func LastConnections(history []ConnectionRecord) map[string]time.Time {
+	last := make(map[string]time.Time)
+	for _, record := range history {
+		if record.Time.After(last[record.Host]) {
+			last[record.Host] = record.Time
+		}
+	}
+	return last
+}
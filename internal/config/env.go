@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables that override where xssh keeps its files and the
+// settings of the config file. They are resolved here, so the CLI and the
+// TUI see the same values.
+const (
+	EnvConfig    = "XSSH_CONFIG"     // xssh config file
+	EnvConfigDir = "XSSH_CONFIG_DIR" // Directory of hosts.json, history and the log
+	EnvSSHConfig = "XSSH_SSH_CONFIG" // SSH config file holding the hosts
+	EnvTheme     = "XSSH_THEME"      // Overrides [theme] name
+	EnvLogLevel  = "XSSH_LOG_LEVEL"  // Overrides [log] level
+	EnvLogFile   = "XSSH_LOG_FILE"   // Overrides [log] file
+	EnvNoDaemon  = "XSSH_NO_DAEMON"  // Keeps the CLI away from the daemon
+)
+
+// EnvVar describes an environment variable xssh reads, for "xssh env"
+type EnvVar struct {
+	Name        string
+	Description string
+}
+
+// EnvVars lists the environment variables xssh reads, its own first
+var EnvVars = []EnvVar{
+	{EnvConfig, "xssh config file, instead of config.toml in the config directory"},
+	{EnvConfigDir, "directory of xssh's own files, instead of ~/.config/xssh"},
+	{EnvSSHConfig, "SSH config file holding the hosts, instead of ~/.ssh/config; passed to ssh with -F"},
+	{EnvTheme, "theme name, overriding [theme] name"},
+	{EnvLogLevel, "log level (off, error, warn, info, debug), overriding [log] level"},
+	{EnvLogFile, "log file, overriding [log] file"},
+	{EnvNoDaemon, "never ask the xssh daemon when set to anything; --background forwards in worker processes"},
+	{"NO_COLOR", "draw and print without colors when set to anything"},
+	{"EDITOR", "editor of \"xssh config edit\", vi when unset"},
+	{"SSH_AUTH_SOCK", "ssh-agent socket used for keys, jump hosts and forwarding"},
+}
+
+// envPath returns the path in an environment variable with a leading ~
+// expanded, or an empty string when the variable is unset
+func envPath(name string) string {
+	return expandPath(os.Getenv(name))
+}
+
+// expandPath replaces a leading ~ in a path with the user's home directory
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// SSHConfigOverride returns the SSH config file given by XSSH_SSH_CONFIG,
+// which ssh must be told about with -F, or an empty string when unset
+func SSHConfigOverride() string {
+	return envPath(EnvSSHConfig)
+}
+
+// NoDaemon reports whether XSSH_NO_DAEMON tells the CLI to act as if no
+// daemon were running
+func NoDaemon() bool {
+	return os.Getenv(EnvNoDaemon) != ""
+}
+
+// applyEnv overrides the settings of the config file with the environment
+func (c *AppConfig) applyEnv() {
+	if theme := os.Getenv(EnvTheme); theme != "" {
+		c.Theme.Name = theme
+	}
+	if level := os.Getenv(EnvLogLevel); level != "" {
+		c.Log.Level = level
+	}
+	if file := envPath(EnvLogFile); file != "" {
+		c.Log.File = file
+	}
+}
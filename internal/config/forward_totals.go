@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// forwardTotalsExpiry is how long the totals of a tunnel that no longer runs
+// are kept
+const forwardTotalsExpiry = 90 * 24 * time.Hour
+
+// forwardTotalsMu serializes the updates of the forwarding totals of this
+// process
+var forwardTotalsMu sync.Mutex
+
+// ForwardTotals are the cumulative statistics of a port forwarding over all
+// the times it ran, so restarting a tunnel does not start its counts over
+type ForwardTotals struct {
+	Host          string    `json:"host"`   // Host alias
+	Tunnel        string    `json:"tunnel"` // Type and addresses of the forwarding, see ForwardTotalsKey
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	Connections   int64     `json:"connections"`
+	Since         time.Time `json:"since"`   // When the tunnel first ran
+	Updated       time.Time `json:"updated"` // When the totals were last stored
+}
+
+// ForwardTotalsKey returns the key of the totals of tunnel on host
+func ForwardTotalsKey(host, tunnel string) string {
+	return host + " " + tunnel
+}
+
+// ForwardTotalsPath returns the location of the forwarding totals
+func ForwardTotalsPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "forward_totals.json"), nil
+}
+
+// LoadForwardTotals reads the forwarding totals, keyed by ForwardTotalsKey. A
+// missing file yields no totals.
+func LoadForwardTotals() (map[string]ForwardTotals, error) {
+	totalsPath, err := ForwardTotalsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(totalsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]ForwardTotals{}, nil
+		}
+		return nil, err
+	}
+	totals := map[string]ForwardTotals{}
+	if err := json.Unmarshal(data, &totals); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// SaveForwardTotals stores totals, replacing the stored totals of the same
+// tunnels and keeping the others, so xssh processes running side by side
+// keep each other's tunnels. Totals not updated for 90 days are dropped.
+func SaveForwardTotals(totals []ForwardTotals) error {
+	forwardTotalsMu.Lock()
+	defer forwardTotalsMu.Unlock()
+	stored, err := LoadForwardTotals()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, t := range totals {
+		t.Updated = now
+		stored[ForwardTotalsKey(t.Host, t.Tunnel)] = t
+	}
+	for key, t := range stored {
+		if now.Sub(t.Updated) > forwardTotalsExpiry {
+			delete(stored, key)
+		}
+	}
+	return saveForwardTotals(stored)
+}
+
+// RenameForwardTotals moves the totals of the tunnels of the host oldName to
+// newName
+func RenameForwardTotals(oldName, newName string) error {
+	forwardTotalsMu.Lock()
+	defer forwardTotalsMu.Unlock()
+	stored, err := LoadForwardTotals()
+	if err != nil {
+		return err
+	}
+
+	renamed := false
+	for key, t := range stored {
+		if t.Host == oldName {
+			delete(stored, key)
+			t.Host = newName
+			stored[ForwardTotalsKey(t.Host, t.Tunnel)] = t
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil
+	}
+	return saveForwardTotals(stored)
+}
+
+// saveForwardTotals replaces the stored totals
+func saveForwardTotals(totals map[string]ForwardTotals) error {
+	totalsPath, err := ForwardTotalsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(totalsPath), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(totals, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write next to the file and rename, so a crash leaves either the old
+	// or the new totals behind
+	tmpPath := totalsPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, totalsPath)
+}
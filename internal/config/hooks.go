@@ -0,0 +1,27 @@
+package config
+
+// Hook events, the keys of [hooks] in the xssh config and of "hooks" in the
+// host metadata
+const (
+	HookPreConnect     = "pre_connect"     // Before connecting; a failure cancels the connection
+	HookPostDisconnect = "post_disconnect" // After the connection ends
+)
+
+// HookEvents lists the hook events in the order they happen
+var HookEvents = []string{HookPreConnect, HookPostDisconnect}
+
+// HookCommands returns the shell commands to run for event around a
+// connection to the host called name: the one of the xssh config, then the
+// one of the host's metadata
+func HookCommands(appConfig *AppConfig, metadata *Metadata, event, name string) []string {
+	var commands []string
+	if appConfig != nil && appConfig.Hooks[event] != "" {
+		commands = append(commands, appConfig.Hooks[event])
+	}
+	if metadata != nil {
+		if command := metadata.Hooks(name)[event]; command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
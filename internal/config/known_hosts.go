@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// KnownHostEntry is one key line of a known_hosts file
+type KnownHostEntry struct {
+	Line        int      // 1-based line number in the file
+	Marker      string   // "@cert-authority", "@revoked" or empty
+	Hosts       []string // Host patterns, or the hashed host for hashed entries
+	Hashed      bool     // Whether the host is stored as |1|salt|hash
+	KeyType     string
+	Fingerprint string // SHA256 fingerprint as printed by ssh-keygen -l
+	Comment     string
+}
+
+// KnownHostsPath returns the location of the user's known_hosts file
+func KnownHostsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ssh", "known_hosts"), nil
+}
+
+// LoadKnownHosts parses a known_hosts file. Comments, blank lines and lines
+// that cannot be parsed are skipped. A missing file yields no entries.
+func LoadKnownHosts(path string) ([]KnownHostEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []KnownHostEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if entry, ok := parseKnownHostLine(scanner.Text()); ok {
+			entry.Line = lineNumber
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parseKnownHostLine parses "[marker] hosts keytype key [comment]"
+func parseKnownHostLine(line string) (KnownHostEntry, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return KnownHostEntry{}, false
+	}
+
+	fields := strings.Fields(line)
+	var entry KnownHostEntry
+	if strings.HasPrefix(fields[0], "@") {
+		entry.Marker = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) < 3 {
+		return KnownHostEntry{}, false
+	}
+
+	keyBytes, err := base64.StdEncoding.DecodeString(fields[2])
+	if err != nil {
+		return KnownHostEntry{}, false
+	}
+	key, err := ssh.ParsePublicKey(keyBytes)
+	if err != nil {
+		return KnownHostEntry{}, false
+	}
+
+	entry.Hosts = strings.Split(fields[0], ",")
+	entry.Hashed = strings.HasPrefix(fields[0], "|1|")
+	entry.KeyType = key.Type()
+	entry.Fingerprint = ssh.FingerprintSHA256(key)
+	entry.Comment = strings.Join(fields[3:], " ")
+	return entry, true
+}
+
+// MatchesHost reports whether the entry applies to host on port, including
+// hashed entries. Wildcard patterns are not expanded.
+func (e KnownHostEntry) MatchesHost(host, port string) bool {
+	if port == "" {
+		port = "22"
+	}
+	address := knownhosts.Normalize(host + ":" + port)
+
+	for _, pattern := range e.Hosts {
+		if strings.HasPrefix(pattern, "|1|") {
+			if hashedHostMatches(pattern, address) {
+				return true
+			}
+			continue
+		}
+		if pattern == address {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedHostMatches checks a |1|salt|hash pattern against a normalized address
+func hashedHostMatches(pattern, address string) bool {
+	parts := strings.Split(pattern, "|")
+	if len(parts) != 4 {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(address))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// RemoveKnownHostLines deletes the given 1-based lines from a known_hosts
+// file. The previous contents are kept in known_hosts.old, as ssh-keygen -R
+// does.
+func RemoveKnownHostLines(path string, lines []int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	remove := map[int]bool{}
+	for _, line := range lines {
+		remove[line] = true
+	}
+
+	var kept []string
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if !remove[i+1] {
+			kept = append(kept, line)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".old", data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(strings.Join(kept, "")), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
@@ -0,0 +1,66 @@
+package config
+
+// FieldDiff compares one field of two versions of a host
+type FieldDiff struct {
+	Field string // Directive name as written in ~/.ssh/config
+	Old   string
+	New   string
+}
+
+// Changed reports whether the two versions differ
+func (d FieldDiff) Changed() bool {
+	return d.Old != d.New
+}
+
+// DiffHosts compares the fields of an existing host with a new version of
+// it, in the order they are written to the config. Unchanged fields are
+// included so the diff can be shown in full.
+func DiffHosts(old, new SSHHost) []FieldDiff {
+	return []FieldDiff{
+		{"HostName", old.Host, new.Host},
+		{"User", old.User, new.User},
+		{"Port", portOrDefault(old.Port), portOrDefault(new.Port)},
+		{"IdentityFile", old.Identity, new.Identity},
+		{"ProxyJump", old.ProxyJump, new.ProxyJump},
+	}
+}
+
+// MergeHosts returns old with every field that is set in new taken from new.
+// The alias of old is kept.
+func MergeHosts(old, new SSHHost) SSHHost {
+	merged := old
+	if new.Host != "" {
+		merged.Host = new.Host
+	}
+	if new.User != "" {
+		merged.User = new.User
+	}
+	if new.Port != "" && new.Port != "22" {
+		merged.Port = new.Port
+	}
+	if new.Identity != "" {
+		merged.Identity = new.Identity
+	}
+	if new.ProxyJump != "" {
+		merged.ProxyJump = new.ProxyJump
+	}
+	return merged
+}
+
+// FindHost returns the index of the host with the given alias, or -1
+func (c *SSHConfig) FindHost(name string) int {
+	for i, host := range c.Hosts {
+		if host.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// portOrDefault returns port, or the SSH default when it is empty
+func portOrDefault(port string) string {
+	if port == "" {
+		return "22"
+	}
+	return port
+}
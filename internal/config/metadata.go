@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// HostMetadata holds what xssh knows about a host beyond ~/.ssh/config
+type HostMetadata struct {
+	Tags  []string `json:"tags,omitempty"`
+	Color string   `json:"color,omitempty"` // Label color, one of LabelColors
+	Notes string   `json:"notes,omitempty"` // Free text, searchable from the host list
+
+	// Shell commands run around connections to the host, keyed by hook
+	// event, after those of the xssh config
+	Hooks map[string]string `json:"hooks,omitempty"`
+
+	// Unmanaged hosts stay in ~/.ssh/config but are hidden from the host list
+	Unmanaged bool `json:"unmanaged,omitempty"`
+
+	// Source is the cloud import the host came from, see
+	// inventory.CloudOptions.Source; a sync of the same source retires
+	// hosts it no longer finds
+	Source string `json:"source,omitempty"`
+
+	// References to the login password and key passphrase in a password
+	// manager, resolved when connecting; the secrets are never stored
+	PasswordRef   string `json:"password_ref,omitempty"`
+	PassphraseRef string `json:"passphrase_ref,omitempty"`
+
+	// Forwarding rules, as "xssh forward" takes them, opened while a
+	// session to the host runs
+	AutoForwards []string `json:"auto_forwards,omitempty"`
+}
+
+// LabelColors are the colors a host can be labeled with
+var LabelColors = []string{"red", "orange", "yellow", "green", "blue", "purple", "gray"}
+
+// empty reports whether there is nothing worth storing for the host
+func (h *HostMetadata) empty() bool {
+	return len(h.Tags) == 0 && h.Color == "" && h.Notes == "" && len(h.Hooks) == 0 && !h.Unmanaged && h.Source == "" &&
+		h.PasswordRef == "" && h.PassphraseRef == "" && len(h.AutoForwards) == 0
+}
+
+// Metadata is the per-host data stored in hosts.json, keyed by host alias
+type Metadata struct {
+	Hosts map[string]*HostMetadata
+	Path  string
+}
+
+// MetadataPath returns the location of the host metadata file
+func MetadataPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "hosts.json"), nil
+}
+
+// LoadMetadata reads the host metadata. A missing file yields empty
+// metadata that can be saved later.
+func LoadMetadata() (*Metadata, error) {
+	metadata := &Metadata{Hosts: map[string]*HostMetadata{}}
+
+	metadataPath, err := MetadataPath()
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Path = metadataPath
+
+	data, err := os.ReadFile(metadataPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return metadata, nil
+		}
+		return metadata, err
+	}
+
+	if err := json.Unmarshal(data, &metadata.Hosts); err != nil {
+		return metadata, err
+	}
+	if metadata.Hosts == nil {
+		metadata.Hosts = map[string]*HostMetadata{}
+	}
+	return metadata, nil
+}
+
+// Save writes the host metadata, leaving out hosts without any
+func (md *Metadata) Save() error {
+	if md.Path == "" {
+		return os.ErrInvalid
+	}
+	if err := os.MkdirAll(filepath.Dir(md.Path), 0700); err != nil {
+		return err
+	}
+
+	hosts := map[string]*HostMetadata{}
+	for name, host := range md.Hosts {
+		if !host.empty() {
+			hosts[name] = host
+		}
+	}
+
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(md.Path, data, 0600)
+}
+
+// host returns the metadata of a host, creating it when missing
+func (md *Metadata) host(name string) *HostMetadata {
+	host, ok := md.Hosts[name]
+	if !ok {
+		host = &HostMetadata{}
+		md.Hosts[name] = host
+	}
+	return host
+}
+
+// Tags returns the tags of a host in the order they were added
+func (md *Metadata) Tags(name string) []string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.Tags
+	}
+	return nil
+}
+
+// HasTag reports whether a host carries a tag
+func (md *Metadata) HasTag(name, tag string) bool {
+	return slices.Contains(md.Tags(name), tag)
+}
+
+// AddTag adds a tag to a host unless it already has it
+func (md *Metadata) AddTag(name, tag string) {
+	if !md.HasTag(name, tag) {
+		host := md.host(name)
+		host.Tags = append(host.Tags, tag)
+	}
+}
+
+// RemoveTag removes a tag from a host
+func (md *Metadata) RemoveTag(name, tag string) {
+	if host, ok := md.Hosts[name]; ok {
+		host.Tags = slices.DeleteFunc(host.Tags, func(t string) bool { return t == tag })
+	}
+}
+
+// AllTags returns every tag in use, sorted
+func (md *Metadata) AllTags() []string {
+	var tags []string
+	for _, host := range md.Hosts {
+		for _, tag := range host.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// Color returns the label color of a host, empty when it has none
+func (md *Metadata) Color(name string) string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.Color
+	}
+	return ""
+}
+
+// Notes returns the notes kept for a host
+func (md *Metadata) Notes(name string) string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.Notes
+	}
+	return ""
+}
+
+// Hooks returns the hook commands of a host, keyed by hook event
+func (md *Metadata) Hooks(name string) map[string]string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.Hooks
+	}
+	return nil
+}
+
+// SetColor sets the label color of a host; an empty color removes the label
+func (md *Metadata) SetColor(name, color string) error {
+	if color != "" && !slices.Contains(LabelColors, color) {
+		return fmt.Errorf("unknown label color %q, expected one of %s", color, strings.Join(LabelColors, ", "))
+	}
+	md.host(name).Color = color
+	return nil
+}
+
+// SetNotes replaces the notes kept for a host
+func (md *Metadata) SetNotes(name, notes string) {
+	md.host(name).Notes = notes
+}
+
+// Managed reports whether a host is managed by xssh and shown in the list
+func (md *Metadata) Managed(name string) bool {
+	if host, ok := md.Hosts[name]; ok {
+		return !host.Unmanaged
+	}
+	return true
+}
+
+// SetManaged marks a host as managed by xssh or not
+func (md *Metadata) SetManaged(name string, managed bool) {
+	md.host(name).Unmanaged = !managed
+}
+
+// Source returns the cloud import a host came from, empty for other hosts
+func (md *Metadata) Source(name string) string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.Source
+	}
+	return ""
+}
+
+// SetSource records the cloud import a host came from
+func (md *Metadata) SetSource(name, source string) {
+	md.host(name).Source = source
+}
+
+// SecretRefs returns the references to the password and key passphrase of a
+// host, empty when it has none
+func (md *Metadata) SecretRefs(name string) (password, passphrase string) {
+	if host, ok := md.Hosts[name]; ok {
+		return host.PasswordRef, host.PassphraseRef
+	}
+	return "", ""
+}
+
+// SetPasswordRef sets the reference to the login password of a host
+func (md *Metadata) SetPasswordRef(name, ref string) {
+	md.host(name).PasswordRef = ref
+}
+
+// SetPassphraseRef sets the reference to the key passphrase of a host
+func (md *Metadata) SetPassphraseRef(name, ref string) {
+	md.host(name).PassphraseRef = ref
+}
+
+// AutoForwards returns the forwarding rules opened while connected to a host
+func (md *Metadata) AutoForwards(name string) []string {
+	if host, ok := md.Hosts[name]; ok {
+		return host.AutoForwards
+	}
+	return nil
+}
+
+// SetAutoForwards replaces the forwarding rules opened while connected to a
+// host; none removes them
+func (md *Metadata) SetAutoForwards(name string, rules []string) {
+	md.host(name).AutoForwards = rules
+}
+
+// RemoveHost forgets everything kept for a host
+func (md *Metadata) RemoveHost(name string) {
+	delete(md.Hosts, name)
+}
+
+// RenameHost moves the metadata of a host to its new alias
+func (md *Metadata) RenameHost(oldName, newName string) {
+	if oldName == newName {
+		return
+	}
+	if host, ok := md.Hosts[oldName]; ok {
+		md.Hosts[newName] = host
+		delete(md.Hosts, oldName)
+	}
+}
+
+// CopyHost gives the host to the tags, color, notes and hooks of the host
+// from, as a copy of it. What ties a host to its own entry, such as its
+// source or password references, is not copied.
+func (md *Metadata) CopyHost(from, to string) {
+	source, ok := md.Hosts[from]
+	if !ok || from == to {
+		return
+	}
+	host := md.host(to)
+	host.Tags = slices.Clone(source.Tags)
+	host.Color = source.Color
+	host.Notes = source.Notes
+	host.Hooks = maps.Clone(source.Hooks)
+}
+
+// NormalizeTag trims a tag and replaces inner whitespace with '-'
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(tag), "-")
+}
@@ -0,0 +1,108 @@
+package config
+
+import "strings"
+
+// IsPattern reports whether a Host line matches hosts by pattern or names
+// several hosts, such as "web-*" or "db1 db2", rather than defining a single
+// host
+func IsPattern(name string) bool {
+	return strings.ContainsAny(name, "*?! \t,")
+}
+
+// MatchHostPatterns reports whether name is matched by the patterns of a Host
+// line, as ssh does: patterns are separated by spaces or commas, "*" and "?"
+// are wildcards, and a name matched by a pattern starting with "!" is never
+// matched
+func MatchHostPatterns(patterns, name string) bool {
+	matched := false
+	for _, pattern := range strings.FieldsFunc(patterns, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	}) {
+		if negated, found := strings.CutPrefix(pattern, "!"); found {
+			if matchWildcard(negated, name) {
+				return false
+			}
+			continue
+		}
+		if matchWildcard(pattern, name) {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchWildcard matches name against a pattern where "*" stands for any
+// run of characters and "?" for exactly one
+func matchWildcard(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchWildcard(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+		default:
+			if name == "" || name[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return name == ""
+}
+
+// ResolveHost returns the settings for connecting to name: the host of that
+// alias, or else a host made from the pattern blocks matching it, the first
+// block setting a field winning as in ssh. A HostName of a pattern block
+// may use %h for the requested name. A bare "Host *" alone does not make a
+// name resolve, so mistyped aliases are still reported.
+func (c *SSHConfig) ResolveHost(name string) (SSHHost, bool) {
+	if index := c.FindHost(name); index >= 0 {
+		return c.Hosts[index], true
+	}
+
+	resolved := SSHHost{Name: name}
+	found := false
+	for _, host := range c.Hosts {
+		if !IsPattern(host.Name) || !MatchHostPatterns(host.Name, name) {
+			continue
+		}
+		if strings.TrimSpace(host.Name) != "*" {
+			found = true
+		}
+		// The parser fills in the block name and port 22 when a block does
+		// not set them
+		if resolved.Host == "" && host.Host != host.Name {
+			resolved.Host = strings.ReplaceAll(host.Host, "%h", name)
+		}
+		if resolved.Port == "" && host.Port != "22" {
+			resolved.Port = host.Port
+		}
+		setIfEmpty(&resolved.User, host.User)
+		setIfEmpty(&resolved.Identity, host.Identity)
+		setIfEmpty(&resolved.ProxyJump, host.ProxyJump)
+	}
+	if !found {
+		return SSHHost{}, false
+	}
+	setIfEmpty(&resolved.Host, name)
+	setIfEmpty(&resolved.Port, "22")
+	return resolved, true
+}
+
+// setIfEmpty sets *field to value unless it is already set
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how often connecting to a host is attempted before
+// giving up, and when to stop trying a host that keeps failing
+type RetryPolicy struct {
+	Attempts         int           // Connection attempts, 1 for no retries
+	Backoff          time.Duration // Wait before the first retry, doubled for every further one
+	MaxBackoff       time.Duration // Longest wait between attempts
+	Jitter           int           // Percentage by which waits are randomly shortened or lengthened
+	BreakerThreshold int           // Failed connections in a row that open the circuit; 0 never opens it
+	BreakerCooldown  time.Duration // Time connections fail fast once the circuit is open
+}
+
+// RetryRule overrides the retry policy for the hosts matched by Patterns
+type RetryRule struct {
+	Patterns string // Host patterns, as on a Host line, matched against aliases and addresses
+	Policy   RetryPolicy
+}
+
+// RetryConfig is the [retry] section of the xssh config, with the rules of
+// [retry.hosts] ordered by their patterns. The first rule matching a host
+// applies.
+type RetryConfig struct {
+	Default RetryPolicy
+	Hosts   []RetryRule
+}
+
+// DefaultRetryPolicy returns the retry policy used when [retry] sets none
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:         3,
+		Backoff:          time.Second,
+		MaxBackoff:       10 * time.Second,
+		Jitter:           20,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+// For returns the retry policy of host
+func (c RetryConfig) For(host SSHHost) RetryPolicy {
+	for _, rule := range c.Hosts {
+		if MatchHostPatterns(rule.Patterns, host.Name) || MatchHostPatterns(rule.Patterns, host.Host) {
+			return rule.Policy
+		}
+	}
+	return c.Default
+}
+
+// Delay returns the wait before retry number retry, counting from 1, without
+// jitter
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// setRetryValue sets one setting of a retry policy, named as in the [retry]
+// section
+func (p *RetryPolicy) setRetryValue(key, value string) error {
+	switch key {
+	case "attempts", "jitter", "breaker_threshold":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || (key == "attempts" && n == 0) || (key == "jitter" && n > 100) {
+			return fmt.Errorf("%s: expected a positive integer, got %q", key, value)
+		}
+		switch key {
+		case "attempts":
+			p.Attempts = n
+		case "jitter":
+			p.Jitter = n
+		default:
+			p.BreakerThreshold = n
+		}
+	case "backoff", "max_backoff", "breaker_cooldown":
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration < 0 {
+			return fmt.Errorf("%s: expected a duration like \"2s\", got %q", key, value)
+		}
+		switch key {
+		case "backoff":
+			p.Backoff = duration
+		case "max_backoff":
+			p.MaxBackoff = duration
+		default:
+			p.BreakerCooldown = duration
+		}
+	default:
+		return fmt.Errorf("unknown setting %q, expected attempts, backoff, max_backoff, jitter, breaker_threshold or breaker_cooldown", key)
+	}
+	return nil
+}
+
+// parseRetryOverrides applies the settings of a [retry.hosts] value, such
+// as "attempts=5 backoff=2s", to a copy of policy
+func parseRetryOverrides(policy RetryPolicy, spec string) (RetryPolicy, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return policy, fmt.Errorf("expected settings like \"attempts=5 backoff=2s\"")
+	}
+	for _, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return policy, fmt.Errorf("expected key=value, got %q", field)
+		}
+		if err := policy.setRetryValue(key, value); err != nil {
+			return policy, err
+		}
+	}
+	return policy, nil
+}
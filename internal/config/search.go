@@ -0,0 +1,96 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// searchFields maps the operators of the host search to the field they
+// restrict a term to
+var searchFields = map[string]string{
+	"name":  "name",
+	"alias": "name",
+	"host":  "host",
+	"user":  "user",
+	"port":  "port",
+	"key":   "key",
+	"tag":   "tag",
+	"note":  "note",
+	"notes": "note",
+	"color": "color",
+}
+
+// SearchTerm is one word of a host search, optionally restricted to a field
+// with an operator such as "tag:prod"
+type SearchTerm struct {
+	Field string // Empty to match any field
+	Value string // Lower case
+}
+
+// ParseSearchQuery splits a search into terms. A word whose prefix is not a
+// known operator is searched for as a whole.
+func ParseSearchQuery(query string) []SearchTerm {
+	var terms []SearchTerm
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		term := SearchTerm{Value: word}
+		if op, value, ok := strings.Cut(word, ":"); ok {
+			if field, known := searchFields[op]; known {
+				term = SearchTerm{Field: field, Value: value}
+			}
+		}
+		if term.Value != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// hostFieldValues returns the values of a host searched by a field, or by
+// any-field terms when field is empty
+func (md *Metadata) hostFieldValues(host SSHHost, field string) []string {
+	switch field {
+	case "name":
+		return []string{host.Name}
+	case "host":
+		return []string{host.Host}
+	case "user":
+		return []string{host.User}
+	case "port":
+		return []string{host.Port}
+	case "key":
+		if host.Identity == "" {
+			return nil
+		}
+		return []string{filepath.Base(host.Identity)}
+	case "tag":
+		return md.Tags(host.Name)
+	case "note":
+		return []string{md.Notes(host.Name)}
+	case "color":
+		return []string{md.Color(host.Name)}
+	}
+
+	values := []string{host.Name, host.Host, host.User, host.Port}
+	for _, field := range []string{"key", "tag", "note"} {
+		values = append(values, md.hostFieldValues(host, field)...)
+	}
+	return values
+}
+
+// HostMatches reports whether a host, with its tags, notes and color,
+// matches every term of a search
+func (md *Metadata) HostMatches(host SSHHost, terms []SearchTerm) bool {
+	for _, term := range terms {
+		matched := false
+		for _, value := range md.hostFieldValues(host, term.Field) {
+			if strings.Contains(strings.ToLower(value), term.Value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
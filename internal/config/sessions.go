@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Kinds of recorded sessions
+const (
+	SessionConnect = "connect" // Interactive ssh session
+	SessionExec    = "exec"    // Remote command run on a host
+	SessionForward = "forward" // Port forwarding
+)
+
+// The store is compacted to its last maxSessionRecords sessions once the
+// file grows past maxSessionsSize bytes
+const (
+	maxSessionRecords = 5000
+	maxSessionsSize   = 2 << 20
+)
+
+// SessionRecord is one connection, remote command or port forwarding made
+// from xssh
+type SessionRecord struct {
+	Kind          string    `json:"kind"`
+	Host          string    `json:"host"` // Host alias
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	ExitCode      int       `json:"exit_code,omitempty"`
+	BytesSent     int64     `json:"bytes_sent,omitempty"`
+	BytesReceived int64     `json:"bytes_received,omitempty"`
+	Detail        string    `json:"detail,omitempty"` // Command run or forwarding rule
+	Error         string    `json:"error,omitempty"`
+	Recording     string    `json:"recording,omitempty"` // Recording of the session's output
+}
+
+// Duration returns how long the session lasted
+func (r SessionRecord) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// SessionsPath returns the location of the session store, a file with one
+// JSON record per line
+func SessionsPath() (string, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "sessions.jsonl"), nil
+}
+
+// RecordSession appends a finished session to the store. Records are only
+// ever appended, so xssh processes running side by side do not lose each
+// other's sessions.
+func RecordSession(record SessionRecord) error {
+	sessionsPath, err := SessionsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(sessionsPath), 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(sessionsPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(sessionsPath); err == nil && info.Size() > maxSessionsSize {
+		return compactSessions()
+	}
+	return nil
+}
+
+// LoadSessions reads the recorded sessions, oldest first. A missing store
+// yields no sessions, and lines that cannot be parsed, such as one cut short
+// by a crash, are skipped.
+func LoadSessions() ([]SessionRecord, error) {
+	sessionsPath, err := SessionsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(sessionsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []SessionRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record SessionRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			sessions = append(sessions, record)
+		}
+	}
+	return sessions, scanner.Err()
+}
+
+// RenameSessions moves the sessions recorded for the host oldName to newName
+func RenameSessions(oldName, newName string) error {
+	sessions, err := LoadSessions()
+	if err != nil {
+		return err
+	}
+
+	renamed := false
+	for i := range sessions {
+		if sessions[i].Host == oldName {
+			sessions[i].Host = newName
+			renamed = true
+		}
+	}
+	if !renamed {
+		return nil
+	}
+	return saveSessions(sessions)
+}
+
+// compactSessions drops the oldest sessions beyond the store limit
+func compactSessions() error {
+	sessions, err := LoadSessions()
+	if err != nil {
+		return err
+	}
+	if len(sessions) <= maxSessionRecords {
+		return nil
+	}
+	return saveSessions(sessions[len(sessions)-maxSessionRecords:])
+}
+
+// saveSessions replaces the store with sessions
+func saveSessions(sessions []SessionRecord) error {
+	sessionsPath, err := SessionsPath()
+	if err != nil {
+		return err
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, record := range sessions {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	// Write next to the store and rename, so a crash leaves either the old
+	// or the new store behind
+	tmpPath := sessionsPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, sessionsPath)
+}
+
+// HostStats sums up the sessions recorded for one host
+type HostStats struct {
+	Host          string
+	Connections   int // Interactive sessions
+	Commands      int // Remote commands run
+	Forwards      int // Port forwardings
+	Failures      int // Sessions that ended with an error or a non-zero exit code
+	Duration      time.Duration
+	BytesSent     int64
+	BytesReceived int64
+	Last          time.Time // When the latest session started
+}
+
+// SummarizeSessions sums up sessions per host, the most recently used host
+// first
+func SummarizeSessions(sessions []SessionRecord) []HostStats {
+	index := make(map[string]int)
+	var stats []HostStats
+	for _, record := range sessions {
+		i, ok := index[record.Host]
+		if !ok {
+			i = len(stats)
+			index[record.Host] = i
+			stats = append(stats, HostStats{Host: record.Host})
+		}
+		host := &stats[i]
+		switch record.Kind {
+		case SessionConnect:
+			host.Connections++
+		case SessionExec:
+			host.Commands++
+		case SessionForward:
+			host.Forwards++
+		}
+		if record.ExitCode != 0 || record.Error != "" {
+			host.Failures++
+		}
+		host.Duration += record.Duration()
+		host.BytesSent += record.BytesSent
+		host.BytesReceived += record.BytesReceived
+		if record.Start.After(host.Last) {
+			host.Last = record.Start
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Last.After(stats[j].Last)
+	})
+	return stats
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -11,27 +12,42 @@ import (
 
 // SSHHost represents a single SSH host configuration
 type SSHHost struct {
-	Name     string
-	Host     string
-	User     string
-	Port     string
-	Identity string
+	Name      string
+	Host      string
+	User      string
+	Port      string
+	Identity  string
+	ProxyJump string // Hosts to connect through, as in the ProxyJump directive
 }
 
 // SSHConfig holds all SSH hosts
 type SSHConfig struct {
 	Hosts []SSHHost
 	Path  string
+
+	saved []SSHHost // Hosts as last read or written, for the audit log
+}
+
+// SSHConfigPath returns the location of the SSH config file, ~/.ssh/config
+// unless XSSH_SSH_CONFIG is set
+func SSHConfigPath() (string, error) {
+	if path := SSHConfigOverride(); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ssh", "config"), nil
 }
 
 // LoadSSHConfig reads and parses SSH config file
 func LoadSSHConfig() (*SSHConfig, error) {
-	homeDir, err := os.UserHomeDir()
+	configPath, err := SSHConfigPath()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(homeDir, ".ssh", "config")
 	file, err := os.Open(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -58,10 +74,11 @@ func LoadSSHConfig() (*SSHConfig, error) {
 	userRegex := regexp.MustCompile(`^\s*User\s+(.+)$`)
 	portRegex := regexp.MustCompile(`^\s*Port\s+(.+)$`)
 	identityRegex := regexp.MustCompile(`^\s*IdentityFile\s+(.+)$`)
+	proxyJumpRegex := regexp.MustCompile(`^\s*ProxyJump\s+(.+)$`)
 
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		
+
 		// Skip comments and empty lines
 		if line == "" || strings.HasPrefix(line, "#") {
 			continue
@@ -72,7 +89,7 @@ func LoadSSHConfig() (*SSHConfig, error) {
 			if currentHost != nil {
 				config.Hosts = append(config.Hosts, *currentHost)
 			}
-			
+
 			// Start new host
 			hostName := strings.TrimSpace(matches[1])
 			currentHost = &SSHHost{
@@ -89,6 +106,8 @@ func LoadSSHConfig() (*SSHConfig, error) {
 				currentHost.Port = strings.TrimSpace(matches[1])
 			} else if matches := identityRegex.FindStringSubmatch(line); matches != nil {
 				currentHost.Identity = strings.TrimSpace(matches[1])
+			} else if matches := proxyJumpRegex.FindStringSubmatch(line); matches != nil {
+				currentHost.ProxyJump = strings.TrimSpace(matches[1])
 			}
 		}
 	}
@@ -97,6 +116,7 @@ func LoadSSHConfig() (*SSHConfig, error) {
 	if currentHost != nil {
 		config.Hosts = append(config.Hosts, *currentHost)
 	}
+	config.saved = append([]SSHHost(nil), config.Hosts...)
 
 	return config, scanner.Err()
 }
@@ -124,9 +144,20 @@ func (c *SSHConfig) Save() error {
 		if host.Identity != "" {
 			fmt.Fprintf(writer, "    IdentityFile %s\n", host.Identity)
 		}
+		if host.ProxyJump != "" {
+			fmt.Fprintf(writer, "    ProxyJump %s\n", host.ProxyJump)
+		}
 		fmt.Fprintln(writer)
 	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
 
+	// The config is saved whether or not the audit log can be written
+	if err := auditHostChanges(c.saved, c.Hosts); err != nil {
+		slog.Warn("failed to write the audit log", "error", err)
+	}
+	c.saved = append([]SSHHost(nil), c.Hosts...)
 	return nil
 }
 
@@ -153,4 +184,64 @@ func (c *SSHConfig) UpdateHost(name string, updatedHost SSHHost) {
 			break
 		}
 	}
-}
\ No newline at end of file
+}
+
+// RenameJumpHost points ProxyJump values that go through the host oldName
+// at newName instead
+func (c *SSHConfig) RenameJumpHost(oldName, newName string) {
+	for i := range c.Hosts {
+		c.Hosts[i].ProxyJump = RenameJumpHop(c.Hosts[i].ProxyJump, oldName, newName)
+	}
+}
+
+// RenameJumpHop replaces the host oldName in a ProxyJump value, a comma
+// separated list of [user@]host[:port] hops, keeping each hop's user and port
+func RenameJumpHop(spec, oldName, newName string) string {
+	if spec == "" || oldName == newName {
+		return spec
+	}
+	hops := strings.Split(spec, ",")
+	for i, hop := range hops {
+		user, hostPort, found := strings.Cut(hop, "@")
+		if !found {
+			user, hostPort = "", hop
+		}
+		name, port, hasPort := strings.Cut(hostPort, ":")
+		if strings.TrimSpace(name) != oldName {
+			continue
+		}
+		hop = newName
+		if found {
+			hop = user + "@" + hop
+		}
+		if hasPort {
+			hop += ":" + port
+		}
+		hops[i] = hop
+	}
+	return strings.Join(hops, ",")
+}
+
+// InsertHost inserts a host at index, clamped to the bounds of the host list
+func (c *SSHConfig) InsertHost(index int, host SSHHost) {
+	index = max(0, min(index, len(c.Hosts)))
+	c.Hosts = append(c.Hosts[:index], append([]SSHHost{host}, c.Hosts[index:]...)...)
+}
+
+// BackupPath returns where Backup keeps the previous config file
+func (c *SSHConfig) BackupPath() string {
+	return c.Path + ".xssh.bak"
+}
+
+// Backup copies the config file as it is on disk to BackupPath. A missing
+// config file is not an error.
+func (c *SSHConfig) Backup() error {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(c.BackupPath(), data, 0600)
+}
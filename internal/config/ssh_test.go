@@ -0,0 +1,117 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempConfig points the SSH config and the config directory at a new
+// temporary directory and returns the SSH config path
+func useTempConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(EnvConfigDir, dir)
+	t.Setenv(EnvConfig, filepath.Join(dir, "config.toml"))
+	path := filepath.Join(dir, "ssh_config")
+	t.Setenv(EnvSSHConfig, path)
+	return path
+}
+
+func TestSSHConfigRoundTrip(t *testing.T) {
+	path := useTempConfig(t)
+	original := `# Managed by hand
+Host web
+    HostName 10.0.0.5
+    User deploy
+    Port 2222
+    IdentityFile ~/.ssh/web_ed25519
+
+Host db
+    HostName db.internal
+    User postgres
+    ProxyJump bastion,web
+
+Host bastion
+    HostName bastion.example.com
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := LoadSSHConfig()
+	if err != nil {
+		t.Fatalf("LoadSSHConfig: %v", err)
+	}
+	want := []SSHHost{
+		{Name: "web", Host: "10.0.0.5", User: "deploy", Port: "2222", Identity: "~/.ssh/web_ed25519"},
+		{Name: "db", Host: "db.internal", User: "postgres", Port: "22", ProxyJump: "bastion,web"},
+		{Name: "bastion", Host: "bastion.example.com", Port: "22"},
+	}
+	if !reflect.DeepEqual(parsed.Hosts, want) {
+		t.Fatalf("parsed hosts = %+v, want %+v", parsed.Hosts, want)
+	}
+
+	if err := parsed.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := LoadSSHConfig()
+	if err != nil {
+		t.Fatalf("LoadSSHConfig after Save: %v", err)
+	}
+	if !reflect.DeepEqual(reparsed.Hosts, want) {
+		t.Fatalf("hosts after Save = %+v, want %+v", reparsed.Hosts, want)
+	}
+
+	// Saving what was read back writes the same file
+	if err := reparsed.Save(); err != nil {
+		t.Fatalf("second Save: %v", err)
+	}
+	rewritten, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rewritten) != string(written) {
+		t.Fatalf("second Save changed the file:\n%s\nwant:\n%s", rewritten, written)
+	}
+}
+
+func TestSSHConfigRoundTripEdits(t *testing.T) {
+	useTempConfig(t)
+	config, err := LoadSSHConfig()
+	if err != nil {
+		t.Fatalf("LoadSSHConfig: %v", err)
+	}
+	config.AddHost(SSHHost{Name: "bastion", Host: "bastion.example.com", User: "ops", Port: "22"})
+	config.AddHost(SSHHost{Name: "app", Host: "10.1.0.7", Port: "22", ProxyJump: "bastion"})
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	config, err = LoadSSHConfig()
+	if err != nil {
+		t.Fatalf("LoadSSHConfig: %v", err)
+	}
+	config.UpdateHost("bastion", SSHHost{Name: "jump", Host: "bastion.example.com", User: "ops", Port: "2200"})
+	config.RenameJumpHost("bastion", "jump")
+	if err := config.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	config, err = LoadSSHConfig()
+	if err != nil {
+		t.Fatalf("LoadSSHConfig: %v", err)
+	}
+	want := []SSHHost{
+		{Name: "app", Host: "10.1.0.7", Port: "22", ProxyJump: "jump"},
+		{Name: "jump", Host: "bastion.example.com", User: "ops", Port: "2200"},
+	}
+	if !reflect.DeepEqual(config.Hosts, want) {
+		t.Fatalf("hosts = %+v, want %+v", config.Hosts, want)
+	}
+}
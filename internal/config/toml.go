@@ -0,0 +1,373 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tomlDocument holds the key/value pairs of a simple TOML file, grouped by
+// section name. Keys that appear before any section header live under "".
+// Values are kept raw and decoded on access.
+type tomlDocument map[string]map[string]string
+
+var (
+	tomlSectionRegex  = regexp.MustCompile(`^\[\s*([A-Za-z0-9_.-]+)\s*\]$`)
+	tomlKeyValueRegex = regexp.MustCompile(`^([A-Za-z0-9_.-]+|"[^"]*")\s*=\s*(.+)$`)
+	tomlBareKeyRegex  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// parseTOML parses the subset of TOML used by the xssh config file:
+// sections, and string, integer, boolean and string-array values. Arrays
+// may span several lines.
+func parseTOML(r io.Reader) (tomlDocument, error) {
+	doc := tomlDocument{"": {}}
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+
+		// Skip comments and empty lines
+		if line == "" {
+			continue
+		}
+
+		if matches := tomlSectionRegex.FindStringSubmatch(line); matches != nil {
+			section = matches[1]
+			if doc[section] == nil {
+				doc[section] = map[string]string{}
+			}
+			continue
+		}
+
+		matches := tomlKeyValueRegex.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, fmt.Errorf("line %d: expected 'key = value' or '[section]'", lineNum)
+		}
+		key := strings.Trim(matches[1], `"`)
+		value := strings.TrimSpace(matches[2])
+		start := lineNum
+		for depth := tomlArrayDepth(value); depth > 0; {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("line %d: the array of %s is not closed", start, qualifiedKey(section, key))
+			}
+			lineNum++
+			next := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+			depth += tomlArrayDepth(next)
+			value += " " + next
+		}
+		doc[section][key] = value
+	}
+
+	return doc, scanner.Err()
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a string
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// tomlArrayDepth returns how many more arrays a line opens than it closes,
+// not counting brackets inside strings
+func tomlArrayDepth(line string) int {
+	depth := 0
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// tomlValueEnd returns the index of the last line of the value that starts
+// on lines[i], further down for an array spanning several lines
+func tomlValueEnd(lines []string, i int, value string) int {
+	depth := tomlArrayDepth(value)
+	for depth > 0 && i+1 < len(lines) {
+		i++
+		depth += tomlArrayDepth(stripTOMLComment(lines[i]))
+	}
+	return i
+}
+
+// String returns the string value of key in section
+func (d tomlDocument) String(section, key string) (string, bool, error) {
+	raw, ok := d[section][key]
+	if !ok {
+		return "", false, nil
+	}
+	value, err := decodeTOMLString(raw)
+	if err != nil {
+		return "", true, fmt.Errorf("%s: %v", qualifiedKey(section, key), err)
+	}
+	return value, true, nil
+}
+
+// Int returns the integer value of key in section
+func (d tomlDocument) Int(section, key string) (int, bool, error) {
+	raw, ok := d[section][key]
+	if !ok {
+		return 0, false, nil
+	}
+	value, err := strconv.Atoi(strings.ReplaceAll(raw, "_", ""))
+	if err != nil {
+		return 0, true, fmt.Errorf("%s: expected an integer, got %s", qualifiedKey(section, key), raw)
+	}
+	return value, true, nil
+}
+
+// Bool returns the boolean value of key in section
+func (d tomlDocument) Bool(section, key string) (bool, bool, error) {
+	raw, ok := d[section][key]
+	if !ok {
+		return false, false, nil
+	}
+	switch raw {
+	case "true":
+		return true, true, nil
+	case "false":
+		return false, true, nil
+	}
+	return false, true, fmt.Errorf("%s: expected true or false, got %s", qualifiedKey(section, key), raw)
+}
+
+// StringArray returns the string-array value of key in section
+func (d tomlDocument) StringArray(section, key string) ([]string, bool, error) {
+	raw, ok := d[section][key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !strings.HasPrefix(raw, "[") || !strings.HasSuffix(raw, "]") {
+		return nil, true, fmt.Errorf("%s: expected an array, got %s", qualifiedKey(section, key), raw)
+	}
+
+	var values []string
+	for _, item := range splitTOMLArray(raw[1 : len(raw)-1]) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		value, err := decodeTOMLString(item)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %v", qualifiedKey(section, key), err)
+		}
+		values = append(values, value)
+	}
+	return values, true, nil
+}
+
+// Keys returns the keys defined in section
+func (d tomlDocument) Keys(section string) []string {
+	var keys []string
+	for key := range d[section] {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Subsections returns the names of the sections below section, "db" for
+// [tunnels.db] below "tunnels"
+func (d tomlDocument) Subsections(section string) []string {
+	var names []string
+	for name := range d {
+		if sub, ok := strings.CutPrefix(name, section+"."); ok && sub != "" {
+			names = append(names, sub)
+		}
+	}
+	return names
+}
+
+// splitTOMLArray splits the inside of an array literal on commas outside strings
+func splitTOMLArray(s string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// decodeTOMLString decodes a basic ("...") or literal ('...') string
+func decodeTOMLString(raw string) (string, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("expected a quoted string, got %s", raw)
+}
+
+func qualifiedKey(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// encodeTOMLString quotes a string as a TOML basic string
+func encodeTOMLString(s string) string {
+	return strconv.Quote(s)
+}
+
+// encodeTOMLKey quotes a key that is not a valid bare key
+func encodeTOMLKey(key string) string {
+	if tomlBareKeyRegex.MatchString(key) {
+		return key
+	}
+	return `"` + key + `"`
+}
+
+// encodeTOMLStringArray writes a string array as a TOML array literal
+func encodeTOMLStringArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = encodeTOMLString(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// setTOMLValue sets key in section of a TOML file to an encoded value. The
+// rest of the file, comments included, is kept as it is. A missing section
+// is appended at the end of the file.
+func setTOMLValue(path, section, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var lines []string
+	if len(data) > 0 {
+		lines = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	}
+	entry := encodeTOMLKey(key) + " = " + value
+
+	current := ""
+	insertAt := -1 // Line after the last line of the section
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if matches := tomlSectionRegex.FindStringSubmatch(trimmed); matches != nil {
+			current = matches[1]
+			if current == section {
+				insertAt = i + 1
+			}
+			continue
+		}
+		matches := tomlKeyValueRegex.FindStringSubmatch(trimmed)
+		end := i
+		if matches != nil {
+			end = tomlValueEnd(lines, i, matches[2])
+		}
+		if current != section {
+			i = end
+			continue
+		}
+		if matches != nil && strings.Trim(matches[1], `"`) == key {
+			lines = append(lines[:i], append([]string{entry}, lines[end+1:]...)...)
+			return writeTOMLLines(path, lines)
+		}
+		if trimmed != "" {
+			insertAt = end + 1
+		}
+		i = end
+	}
+
+	switch {
+	case insertAt >= 0:
+		lines = append(lines[:insertAt], append([]string{entry}, lines[insertAt:]...)...)
+	case section == "":
+		lines = append([]string{entry}, lines...)
+	default:
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+section+"]", entry)
+	}
+	return writeTOMLLines(path, lines)
+}
+
+// removeTOMLValue deletes key from section of a TOML file, keeping the rest
+// of the file as it is. A missing file or key is not an error.
+func removeTOMLValue(path, section, key string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	current := ""
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(stripTOMLComment(lines[i]))
+		if matches := tomlSectionRegex.FindStringSubmatch(trimmed); matches != nil {
+			current = matches[1]
+			continue
+		}
+		matches := tomlKeyValueRegex.FindStringSubmatch(trimmed)
+		if matches == nil {
+			continue
+		}
+		end := tomlValueEnd(lines, i, matches[2])
+		if current == section && strings.Trim(matches[1], `"`) == key {
+			return writeTOMLLines(path, append(lines[:i], lines[end+1:]...))
+		}
+		i = end
+	}
+	return nil
+}
+
+// writeTOMLLines writes the lines of a TOML file, creating its directory
+func writeTOMLLines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
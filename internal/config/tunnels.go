@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TunnelProfile is a forwarding kept in a [tunnels.<name>] section of the
+// xssh config, which the daemon opens on its own within its windows
+type TunnelProfile struct {
+	Name    string         // Name of the section, the session ID of the forwarding
+	Host    string         // Alias of the host the forwarding tunnels through
+	Rule    string         // Forwarding rule, as in "xssh forward <rule> <alias>"
+	Via     []string       // Hosts tunneled through before Host, in order
+	Labels  []string       // Labels of the forwarding, to filter sessions by
+	Windows TunnelSchedule // When the forwarding runs; empty for always
+}
+
+// TunnelSchedule is the weekly windows a scheduled forwarding runs in
+type TunnelSchedule []TunnelWindow
+
+// TunnelWindow is a time of day range on some days of the week, such as
+// "Mon-Fri 09:00-18:00". A range ending at or before its start ends the
+// next day.
+type TunnelWindow struct {
+	Days  [7]bool // Days the window starts on, by time.Weekday
+	Start int     // Minutes after midnight
+	End   int
+}
+
+// weekdayNames are the day names of windows, by time.Weekday
+var weekdayNames = [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// ParseTunnelWindow parses a window: days such as "Mon-Fri", "Sat,Sun",
+// "weekdays", "weekends" or "daily", then a range such as "09:00-18:00".
+// Without days the window is daily.
+func ParseTunnelWindow(value string) (TunnelWindow, error) {
+	var window TunnelWindow
+	fields := strings.Fields(value)
+	days, hours := "daily", ""
+	switch len(fields) {
+	case 1:
+		hours = fields[0]
+	case 2:
+		days, hours = fields[0], fields[1]
+	default:
+		return window, fmt.Errorf("invalid window %q, expected days and hours such as \"Mon-Fri 09:00-18:00\"", value)
+	}
+	if err := window.parseDays(days); err != nil {
+		return window, fmt.Errorf("invalid window %q: %v", value, err)
+	}
+
+	from, to, ok := strings.Cut(hours, "-")
+	start, err := time.Parse("15:04", from)
+	if !ok || err != nil {
+		return window, fmt.Errorf("invalid window %q, expected hours such as 09:00-18:00", value)
+	}
+	end, err := time.Parse("15:04", to)
+	if err != nil {
+		return window, fmt.Errorf("invalid window %q, expected hours such as 09:00-18:00", value)
+	}
+	window.Start = start.Hour()*60 + start.Minute()
+	window.End = end.Hour()*60 + end.Minute()
+	return window, nil
+}
+
+// parseDays sets the days of a window from a list of days and day ranges
+func (w *TunnelWindow) parseDays(days string) error {
+	switch strings.ToLower(days) {
+	case "daily":
+		days = "Sun-Sat"
+	case "weekdays":
+		days = "Mon-Fri"
+	case "weekends":
+		days = "Sat,Sun"
+	}
+	for _, part := range strings.Split(days, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, ok := parseWeekday(from)
+		if !ok {
+			return fmt.Errorf("unknown day %q", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = parseWeekday(to); !ok {
+				return fmt.Errorf("unknown day %q", to)
+			}
+		}
+		// Ranges may wrap around the week, as Fri-Mon
+		for day := first; ; day = (day + 1) % 7 {
+			w.Days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseWeekday parses a day name such as "Mon", "tues" or "Wednesday"
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.HasPrefix(strings.ToLower(day.String()), name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// String returns the window as ParseTunnelWindow reads it
+func (w TunnelWindow) String() string {
+	var days []string
+	for day := 0; day < 7; day++ {
+		if !w.Days[day] {
+			continue
+		}
+		// Join runs of days into ranges
+		last := day
+		for last < 6 && w.Days[last+1] {
+			last++
+		}
+		switch {
+		case day == 0 && last == 6:
+			days = append(days, "daily")
+		case last > day:
+			days = append(days, weekdayNames[day]+"-"+weekdayNames[last])
+		default:
+			days = append(days, weekdayNames[day])
+		}
+		day = last
+	}
+	return fmt.Sprintf("%s %02d:%02d-%02d:%02d", strings.Join(days, ","), w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// length returns how long the window lasts
+func (w TunnelWindow) length() time.Duration {
+	minutes := w.End - w.Start
+	if minutes <= 0 {
+		minutes += 24 * 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// spans returns the start and end of the window's runs starting from the
+// day before t to a week after it
+func (w TunnelWindow) spans(t time.Time) [][2]time.Time {
+	var spans [][2]time.Time
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, t.Location())
+		if !w.Days[day.Weekday()] {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), w.Start/60, w.Start%60, 0, 0, t.Location())
+		spans = append(spans, [2]time.Time{start, start.Add(w.length())})
+	}
+	return spans
+}
+
+// String returns the windows separated by commas
+func (s TunnelSchedule) String() string {
+	windows := make([]string, len(s))
+	for i, window := range s {
+		windows[i] = window.String()
+	}
+	return strings.Join(windows, ", ")
+}
+
+// Active reports whether t falls in one of the windows. A forwarding
+// without windows is always active.
+func (s TunnelSchedule) Active(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, window := range s {
+		for _, span := range window.spans(t) {
+			if !t.Before(span[0]) && t.Before(span[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Next returns when the schedule next turns active or inactive after t, or
+// zero if it never does
+func (s TunnelSchedule) Next(t time.Time) time.Time {
+	var transitions []time.Time
+	for _, window := range s {
+		for _, span := range window.spans(t) {
+			transitions = append(transitions, span[0], span[1])
+		}
+	}
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].Before(transitions[j]) })
+	active := s.Active(t)
+	for _, transition := range transitions {
+		if transition.After(t) && s.Active(transition) != active {
+			return transition
+		}
+	}
+	return time.Time{}
+}
+
+// loadTunnelProfiles reads the [tunnels.<name>] sections, ordered by name
+func loadTunnelProfiles(doc tomlDocument) ([]TunnelProfile, error) {
+	names := doc.Subsections("tunnels")
+	sort.Strings(names)
+	var profiles []TunnelProfile
+	for _, name := range names {
+		section := "tunnels." + name
+		profile := TunnelProfile{Name: name}
+		host, _, err := doc.String(section, "host")
+		if err != nil {
+			return nil, err
+		}
+		rule, _, err := doc.String(section, "rule")
+		if err != nil {
+			return nil, err
+		}
+		if host == "" || rule == "" {
+			return nil, fmt.Errorf("%s: host and rule are required", section)
+		}
+		profile.Host, profile.Rule = host, rule
+		if profile.Via, _, err = doc.StringArray(section, "via"); err != nil {
+			return nil, err
+		}
+		if profile.Labels, _, err = doc.StringArray(section, "labels"); err != nil {
+			return nil, err
+		}
+
+		windows, _, err := doc.StringArray(section, "windows")
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range windows {
+			window, err := ParseTunnelWindow(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s.windows: %v", section, err)
+			}
+			profile.Windows = append(profile.Windows, window)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ValidatePort accepts an empty value or a port number between 1 and 65535
+func ValidatePort(value string) error {
+	if value == "" {
+		return nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("port must be a number")
+	}
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// ValidateHostname accepts an empty value, an IP address or a syntactically
+// valid host name. The name is not resolved.
+func ValidateHostname(value string) error {
+	if value == "" || net.ParseIP(strings.Trim(value, "[]")) != nil {
+		return nil
+	}
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("host must not contain spaces")
+	}
+	if len(value) > 253 {
+		return fmt.Errorf("host name is too long")
+	}
+
+	// A single trailing dot marks a fully qualified name
+	for _, label := range strings.Split(strings.TrimSuffix(value, "."), ".") {
+		if label == "" {
+			return fmt.Errorf("host name has an empty label")
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("host name label %q is too long", label)
+		}
+		if strings.HasPrefix(label, "-") {
+			return fmt.Errorf("host name labels cannot start with '-'")
+		}
+		for _, r := range label {
+			if !isHostnameRune(r) {
+				return fmt.Errorf("host name cannot contain %q", r)
+			}
+		}
+	}
+	return nil
+}
+
+// isHostnameRune reports whether r may appear in a host name label.
+// Underscores are allowed since they are common in internal names.
+func isHostnameRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_')
+}
+
+// ValidateAlias accepts an empty value or a name usable as a Host entry in
+// ~/.ssh/config
+func ValidateAlias(value string) error {
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("alias must not contain spaces")
+	}
+	if strings.ContainsAny(value, "*?!#,") {
+		return fmt.Errorf("alias cannot contain any of * ? ! # ,")
+	}
+	return nil
+}
+
+// ValidateFilterName accepts an empty value or a name a filter can be saved
+// under in the xssh config file
+func ValidateFilterName(value string) error {
+	if strings.ContainsAny(value, "\"\n") {
+		return fmt.Errorf("name cannot contain quotes")
+	}
+	return nil
+}
+
+// ValidateIdentityFile checks that a private key file exists and is a
+// regular file. A leading ~ is expanded.
+func ValidateIdentityFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("no identity file selected")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("identity file %s does not exist", path)
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("identity file %s is not a regular file", path)
+	}
+	return nil
+}
+
+// Validate checks a host before it is written to the config
+func (h SSHHost) Validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("alias is required")
+	}
+	if err := ValidateAlias(h.Name); err != nil {
+		return err
+	}
+	if h.Host == "" {
+		return fmt.Errorf("host address is required")
+	}
+	if err := ValidateHostname(h.Host); err != nil {
+		return err
+	}
+	if err := ValidatePort(h.Port); err != nil {
+		return err
+	}
+	if h.Identity != "" {
+		return ValidateIdentityFile(h.Identity)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package crash writes a report for each panic of the TUI or the daemon to
+// the crashes directory and, when the user opts in with [crash] report_url,
+// sends it to that endpoint too.
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/version"
+)
+
+// maxReports is the number of crash reports kept; older ones are deleted
+// when a new one is written
+const maxReports = 20
+
+// uploadTimeout bounds sending a report, as xssh is about to exit
+const uploadTimeout = 5 * time.Second
+
+// Report is one panic, as written to the crashes directory and sent to the
+// report URL
+type Report struct {
+	Time      time.Time    `json:"time"`
+	Component string       `json:"component"` // Part of xssh that panicked: "tui" or "daemon"
+	Version   version.Info `json:"version"`
+	Panic     string       `json:"panic"`
+	Stack     string       `json:"stack"`
+}
+
+// settings holds the report URL handed over by SetReportURL
+var settings struct {
+	sync.Mutex
+	reportURL string
+}
+
+// SetReportURL sets where reports are sent; empty, the default, keeps them
+// on this machine
+func SetReportURL(url string) {
+	settings.Lock()
+	defer settings.Unlock()
+	settings.reportURL = url
+}
+
+// Dir returns the directory of the crash reports
+func Dir() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "crashes"), nil
+}
+
+// Write records a panic of component with the stack of the goroutine that
+// panicked, as returned by debug.Stack in the deferred function that
+// recovered it, and returns the path of the report. The report is written
+// before it is sent, and failing to send it is only logged.
+func Write(component string, value any, stack []byte) (string, error) {
+	report := Report{
+		Time:      time.Now(),
+		Component: component,
+		Version:   version.Get(),
+		Panic:     fmt.Sprint(value),
+		Stack:     string(stack),
+	}
+
+	path, err := save(report)
+
+	settings.Lock()
+	reportURL := settings.reportURL
+	settings.Unlock()
+	if reportURL != "" {
+		if err := send(reportURL, report); err != nil {
+			slog.Warn("failed to send crash report", "url", reportURL, "error", err)
+		}
+	}
+	return path, err
+}
+
+// save writes the report to the crashes directory, deleting the oldest
+// reports beyond maxReports
+func save(report Report) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s-%s.txt", report.Time.Format("20060102-150405"), report.Component))
+	if err := os.WriteFile(path, []byte(report.String()), 0600); err != nil {
+		return "", err
+	}
+	prune()
+	return path, nil
+}
+
+// String formats the report as written to the crash file
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "xssh crash report\n\n")
+	fmt.Fprintf(&b, "Time:      %s\n", r.Time.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Component: %s\n", r.Component)
+	fmt.Fprintf(&b, "Version:   %s", r.Version.Version)
+	if r.Version.Commit != "" {
+		fmt.Fprintf(&b, " (%s)", r.Version.Commit)
+	}
+	fmt.Fprintf(&b, "\nPlatform:  %s, %s\n\n", r.Version.Platform, r.Version.GoVersion)
+	fmt.Fprintf(&b, "panic: %s\n\n%s", r.Panic, r.Stack)
+	return b.String()
+}
+
+// send posts the report as JSON to url
+func send(url string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("%s", response.Status)
+	}
+	return nil
+}
+
+// List returns the paths of the crash reports, newest first
+func List() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "crash-*.txt"))
+	if err != nil {
+		return nil, err
+	}
+	// The names start with the time of the crash
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// prune deletes the oldest reports beyond maxReports
+func prune() {
+	paths, err := List()
+	if err != nil || len(paths) <= maxReports {
+		return
+	}
+	for _, path := range paths[maxReports:] {
+		os.Remove(path)
+	}
+}
@@ -0,0 +1,129 @@
+package forwarding
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// BackgroundSession is a forwarding kept open by a detached xssh process. It
+// is recorded in a file so other xssh processes can list and stop it.
+type BackgroundSession struct {
+	Rule    ForwardingRule `json:"rule"`
+	Host    string         `json:"host"` // Alias of the host the forwarding tunnels through
+	PID     int            `json:"pid"`
+	Started time.Time      `json:"started"`
+	Expires time.Time      `json:"expires,omitzero"` // When the forwarding stops on its own, zero for never
+}
+
+// backgroundDir returns the directory holding the records of background
+// sessions and their logs
+func backgroundDir() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "forwards"), nil
+}
+
+// BackgroundLogPath returns the file the process of a background session
+// writes its output to
+func BackgroundLogPath(id string) (string, error) {
+	dir, err := backgroundDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".log"), nil
+}
+
+// RegisterBackground records a background session
+func RegisterBackground(session BackgroundSession) error {
+	dir, err := backgroundDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, session.Rule.ID+".json"), data, 0600)
+}
+
+// UnregisterBackground removes the record of a background session and its log
+func UnregisterBackground(id string) error {
+	dir, err := backgroundDir()
+	if err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(dir, id+".log"))
+	if err := os.Remove(filepath.Join(dir, id+".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// BackgroundSessions returns the recorded background sessions, oldest first.
+// Records of processes that are gone are removed.
+func BackgroundSessions() ([]BackgroundSession, error) {
+	dir, err := backgroundDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var sessions []BackgroundSession
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var session BackgroundSession
+		if err := json.Unmarshal(data, &session); err != nil {
+			continue
+		}
+		if !processAlive(session.PID) {
+			UnregisterBackground(session.Rule.ID)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Started.Before(sessions[j].Started) })
+	return sessions, nil
+}
+
+// Stop asks the process of a background session to close the forwarding
+func (s BackgroundSession) Stop() error {
+	if err := terminateProcess(s.PID); err != nil {
+		return err
+	}
+	return UnregisterBackground(s.Rule.ID)
+}
+
+// Renew asks the process of a background session to push back its expiry;
+// it records the new one
+func (s BackgroundSession) Renew() error {
+	if s.Rule.Expiry == nil {
+		return fmt.Errorf("forwarding session %s does not expire", s.Rule.ID)
+	}
+	return renewProcess(s.PID)
+}
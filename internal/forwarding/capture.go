@@ -0,0 +1,326 @@
+package forwarding
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// maxCapturedHeaders is how much of a direction a headers-only capture keeps
+// when it sees no end of headers
+const maxCapturedHeaders = 16 * 1024
+
+// headersEnd ends the headers of HTTP and similar protocols
+var headersEnd = []byte("\r\n\r\n")
+
+// Capture writes the bytes a session forwards to a dump file, to look into
+// protocol issues inside the tunnel
+type Capture struct {
+	Headers bool  `json:",omitempty"` // Keep each direction only up to the end of its headers
+	Limit   int64 `json:",omitempty"` // Bytes kept per direction of a connection, 0 for no limit
+}
+
+// ParseCapture parses the capture option of a rule: empty for none, "all",
+// "headers", a size such as "64k" kept per direction of a connection, or
+// "headers" and a size separated by a comma
+func ParseCapture(value string) (*Capture, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	capture := &Capture{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch part {
+		case "all":
+		case "headers":
+			capture.Headers = true
+		default:
+			limit, err := parseSize(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid capture option %q, expected all, headers or a size such as 64k", value)
+			}
+			capture.Limit = limit
+		}
+	}
+	return capture, nil
+}
+
+// parseSize parses a positive byte count with an optional k, m or g suffix
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier, value = 1<<10, strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "m"):
+		multiplier, value = 1<<20, strings.TrimSuffix(value, "m")
+	case strings.HasSuffix(value, "g"):
+		multiplier, value = 1<<30, strings.TrimSuffix(value, "g")
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * multiplier, nil
+}
+
+// String returns the option as ParseCapture reads it, empty for nil
+func (c *Capture) String() string {
+	if c == nil {
+		return ""
+	}
+	var parts []string
+	if c.Headers {
+		parts = append(parts, "headers")
+	}
+	if c.Limit > 0 {
+		parts = append(parts, formatSize(c.Limit))
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatSize returns a byte count as parseSize reads it
+func formatSize(size int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if size%unit.size == 0 {
+			return strconv.FormatInt(size/unit.size, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// CaptureDir returns the directory of the dump files
+func CaptureDir() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "captures"), nil
+}
+
+// sessionCapture is the dump file of a session. It is closed once the
+// capture is stopped and the connections writing to it are done.
+type sessionCapture struct {
+	options Capture
+	path    string
+
+	mu       sync.Mutex // Guards the fields below and writing to file
+	file     *os.File
+	conns    int  // Connections being captured
+	stopped  bool // No new connection is captured
+	nextConn int  // Number of the last connection
+}
+
+// StartCapture starts writing the traffic of the session's new connections
+// to a dump file in CaptureDir, and returns its path. A capture already
+// running is stopped first.
+func (fs *ForwardingSession) StartCapture(options Capture) (string, error) {
+	captureDir, err := CaptureDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(captureDir, 0700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.dump", strings.ReplaceAll(fs.Rule.ID, "/", "_"), now.Format("20060102-150405"))
+	path := filepath.Join(captureDir, name)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(file, "# xssh capture of %s (%s), started %s, capturing %s\n",
+		fs.Rule.ID, fs.Rule.Description, now.Format(time.RFC3339), options.String())
+	fmt.Fprintf(file, "# > sent through the tunnel, < received from it\n")
+
+	capture := &sessionCapture{options: options, path: path, file: file}
+	fs.captureMu.Lock()
+	previous := fs.capture
+	fs.capture = capture
+	fs.captureMu.Unlock()
+	previous.stop()
+	slog.Info("capturing forwarding traffic", "id", fs.Rule.ID, "file", path, "capture", options.String())
+	return path, nil
+}
+
+// StopCapture stops the capture of the session and returns the path of its
+// dump file, empty when it was not capturing. Connections already being
+// captured finish their writes.
+func (fs *ForwardingSession) StopCapture() string {
+	fs.captureMu.Lock()
+	capture := fs.capture
+	fs.capture = nil
+	fs.captureMu.Unlock()
+	if capture == nil {
+		return ""
+	}
+	capture.stop()
+	slog.Info("stopped capturing forwarding traffic", "id", fs.Rule.ID, "file", capture.path)
+	return capture.path
+}
+
+// CapturePath returns the dump file the session captures to, empty when it
+// is not capturing
+func (fs *ForwardingSession) CapturePath() string {
+	fs.captureMu.Lock()
+	defer fs.captureMu.Unlock()
+	if fs.capture == nil {
+		return ""
+	}
+	return fs.capture.path
+}
+
+// captureConn starts capturing a forwarded connection, given by its end on
+// this machine, returning nil when the session is not capturing
+func (fs *ForwardingSession) captureConn(local net.Conn) *connCapture {
+	fs.captureMu.Lock()
+	capture := fs.capture
+	fs.captureMu.Unlock()
+	if capture == nil {
+		return nil
+	}
+
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	if capture.stopped {
+		return nil
+	}
+	capture.conns++
+	capture.nextConn++
+	conn := &connCapture{capture: capture, number: capture.nextConn}
+	capture.writeLine(fmt.Sprintf("#%d opened, local end %s", conn.number, local.RemoteAddr()))
+	return conn
+}
+
+// stop stops capturing new connections and closes the dump file once the
+// connections being captured are done
+func (c *sessionCapture) stop() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.closeIfDone()
+}
+
+// closeIfDone closes the dump file of a stopped capture no connection writes
+// to anymore. Callers hold c.mu.
+func (c *sessionCapture) closeIfDone() {
+	if c.stopped && c.conns == 0 && c.file != nil {
+		c.file.Close()
+		c.file = nil
+	}
+}
+
+// writeLine writes a record line stamped with the time. Callers hold c.mu.
+func (c *sessionCapture) writeLine(line string) {
+	fmt.Fprintf(c.file, "%s %s\n", time.Now().Format("2006-01-02T15:04:05.000000"), line)
+}
+
+// Directions of the bytes of a connection
+const (
+	captureSent     = ">"
+	captureReceived = "<"
+)
+
+// connCapture writes the traffic of one connection to the dump file of its
+// session. Its methods accept a nil receiver, for connections not captured.
+type connCapture struct {
+	capture  *sessionCapture
+	number   int
+	sent     captureDirection
+	received captureDirection
+}
+
+// captureDirection is how much of one direction of a connection was kept
+type captureDirection struct {
+	kept int64
+	done bool   // Nothing more is kept
+	tail []byte // Last bytes seen, to find the end of headers across reads
+}
+
+// record writes data going in direction to the dump file, as much as the
+// options of the capture keep
+func (c *connCapture) record(direction string, data []byte) {
+	if c == nil || len(data) == 0 {
+		return
+	}
+	state := &c.sent
+	if direction == captureReceived {
+		state = &c.received
+	}
+	capture := c.capture
+
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	if state.done || capture.file == nil {
+		return
+	}
+
+	kept, note := state.take(data, capture.options)
+	if len(kept) > 0 {
+		capture.writeLine(fmt.Sprintf("#%d %s %d bytes", c.number, direction, len(kept)))
+		capture.file.WriteString(hex.Dump(kept))
+	}
+	if note != "" {
+		capture.writeLine(fmt.Sprintf("#%d %s %s", c.number, direction, note))
+	}
+}
+
+// take returns the part of data the options keep, and a note when the
+// direction is kept no further
+func (d *captureDirection) take(data []byte, options Capture) ([]byte, string) {
+	kept, note := data, ""
+	if options.Headers {
+		seen := append(d.tail, data...)
+		if index := bytes.Index(seen, headersEnd); index >= 0 {
+			kept = data[:index+len(headersEnd)-len(d.tail)]
+			note = "end of headers, the rest is not captured"
+		}
+		d.tail = append([]byte(nil), seen[max(0, len(seen)-len(headersEnd)+1):]...)
+	}
+
+	limit := options.Limit
+	if options.Headers && limit == 0 {
+		limit = maxCapturedHeaders
+	}
+	if limit > 0 && d.kept+int64(len(kept)) > limit {
+		kept = kept[:limit-d.kept]
+		note = fmt.Sprintf("limit of %s reached, the rest is not captured", formatSize(limit))
+	}
+	d.kept += int64(len(kept))
+	d.done = note != ""
+	return kept, note
+}
+
+// close records the end of the connection and lets go of the dump file
+func (c *connCapture) close() {
+	if c == nil {
+		return
+	}
+	capture := c.capture
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	if capture.file != nil {
+		capture.writeLine(fmt.Sprintf("#%d closed, %d bytes sent and %d received captured", c.number, c.sent.kept, c.received.kept))
+	}
+	capture.conns--
+	capture.closeIfDone()
+}
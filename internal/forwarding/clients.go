@@ -0,0 +1,211 @@
+package forwarding
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
+)
+
+// sharedClient is an SSH connection shared by the sessions tunneling to one
+// host. It is closed once no session or connection uses it anymore. A
+// connection tunneled through hops holds the connection to the last hop
+// until it is closed, so the hops are torn down after it, innermost first.
+type sharedClient struct {
+	xssh.Conn
+	key    string        // user@host:port, after the key of via; the key in the manager's clients
+	host   string        // Alias of the host it was opened for
+	opened time.Time     // When it was connected
+	via    *sharedClient // Connection to the last hop it is tunneled through, nil for none
+
+	// Guarded by the manager's mu
+	sessions int // Running sessions listening through it
+	conns    int // Forwarded connections open through it
+}
+
+// depth returns the number of hops the connection is tunneled through
+func (c *sharedClient) depth() int {
+	depth := 0
+	for hop := c.via; hop != nil; hop = hop.via {
+		depth++
+	}
+	return depth
+}
+
+// ClientInfo describes an SSH connection of a ForwardingManager
+type ClientInfo struct {
+	Address     string    // user@host:port, after the hops it is tunneled through
+	Host        string    // Alias of the host it was opened for
+	Opened      time.Time // When it was connected
+	Sessions    int       // Forwarding sessions using it
+	Connections int       // Forwarded connections open through it
+}
+
+// Clients returns the SSH connections the manager holds, by address
+func (fm *ForwardingManager) Clients() []ClientInfo {
+	fm.mu.Lock()
+	infos := make([]ClientInfo, 0, len(fm.clients))
+	for _, client := range fm.clients {
+		infos = append(infos, ClientInfo{
+			Address:     client.key,
+			Host:        client.host,
+			Opened:      client.opened,
+			Sessions:    client.sessions,
+			Connections: client.conns,
+		})
+	}
+	fm.mu.Unlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Address < infos[j].Address
+	})
+	return infos
+}
+
+// acquireClient returns the SSH connection to the host for a session,
+// tunneled through the hosts of via in order, reusing the ones already
+// open when they still answer. The hops log in with their key alone, like
+// ProxyJump hosts. The session releases it with releaseClient once it
+// stops listening.
+func (fm *ForwardingManager) acquireClient(ctx context.Context, via []string, host config.SSHHost, auth xssh.Auth) (*sharedClient, error) {
+	var hop *sharedClient
+	for _, spec := range via {
+		next, err := fm.acquireHop(ctx, hop, xssh.ResolveJumpHost(spec), xssh.Auth{})
+		if err != nil {
+			return nil, fmt.Errorf("hop %s: %w", spec, err)
+		}
+		hop = next
+	}
+	return fm.acquireHop(ctx, hop, host, auth)
+}
+
+// acquireHop returns the SSH connection to host through via, or directly
+// when via is nil, taking over the hold of the caller on via: it is kept
+// by a new connection and given up otherwise.
+func (fm *ForwardingManager) acquireHop(ctx context.Context, via *sharedClient, host config.SSHHost, auth xssh.Auth) (*sharedClient, error) {
+	clientKey := fmt.Sprintf("%s@%s:%s", host.User, host.Host, host.Port)
+	if via != nil {
+		clientKey = via.key + " > " + clientKey
+	}
+
+	fm.mu.Lock()
+	client, exists := fm.clients[clientKey]
+	if exists {
+		client.sessions++
+	}
+	fm.mu.Unlock()
+	if exists {
+		if _, _, err := client.SendRequest("keepalive@golang.org", true, nil); err == nil {
+			fm.releaseHold(via)
+			return client, nil
+		}
+		// Connection is dead, remove it. The sessions still holding it
+		// fail on their own and let go of it when they stop.
+		fm.mu.Lock()
+		client.sessions--
+		unused := fm.dropIfUnused(client)
+		if fm.clients[clientKey] == client {
+			delete(fm.clients, clientKey)
+		}
+		fm.mu.Unlock()
+		if unused {
+			fm.closeClient(client)
+		} else {
+			client.Close()
+		}
+	}
+
+	var conn xssh.Conn
+	var err error
+	if via == nil {
+		conn, err = fm.dialer.Dial(ctx, host, auth)
+	} else {
+		conn, err = xssh.DialThrough(ctx, via, host, auth)
+	}
+	if err != nil {
+		fm.releaseHold(via)
+		return nil, err
+	}
+
+	fm.mu.Lock()
+	if existing, raced := fm.clients[clientKey]; raced {
+		// Another session connected meanwhile
+		existing.sessions++
+		fm.mu.Unlock()
+		conn.Close()
+		fm.releaseHold(via)
+		return existing, nil
+	}
+	client = &sharedClient{Conn: conn, key: clientKey, host: host.Name, opened: time.Now(), via: via, sessions: 1}
+	fm.clients[clientKey] = client
+	fm.mu.Unlock()
+	return client, nil
+}
+
+// bindClient records client as the SSH connection session listens through
+func (fm *ForwardingManager) bindClient(session *ForwardingSession, client *sharedClient) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	session.client = client
+}
+
+// releaseClient gives up the hold of a session on client
+func (fm *ForwardingManager) releaseClient(client *sharedClient) {
+	fm.mu.Lock()
+	client.sessions--
+	unused := fm.dropIfUnused(client)
+	fm.mu.Unlock()
+	if unused {
+		fm.closeClient(client)
+	}
+}
+
+// releaseHold releases a hold on a connection to a hop that may be nil
+func (fm *ForwardingManager) releaseHold(client *sharedClient) {
+	if client != nil {
+		fm.releaseClient(client)
+	}
+}
+
+// retainConn holds client for a forwarded connection, which gives it up
+// with releaseConn once done
+func (fm *ForwardingManager) retainConn(client *sharedClient) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	client.conns++
+}
+
+// releaseConn gives up the hold of a forwarded connection on client
+func (fm *ForwardingManager) releaseConn(client *sharedClient) {
+	fm.mu.Lock()
+	client.conns--
+	unused := fm.dropIfUnused(client)
+	fm.mu.Unlock()
+	if unused {
+		fm.closeClient(client)
+	}
+}
+
+// closeClient closes a connection nothing uses anymore, then gives up its
+// hold on the hop it is tunneled through
+func (fm *ForwardingManager) closeClient(client *sharedClient) {
+	client.Close()
+	fm.releaseHold(client.via)
+}
+
+// dropIfUnused removes client from the manager when nothing uses it anymore
+// and reports whether it should be closed. Callers hold fm.mu.
+func (fm *ForwardingManager) dropIfUnused(client *sharedClient) bool {
+	if client.sessions > 0 || client.conns > 0 {
+		return false
+	}
+	if fm.clients[client.key] == client {
+		delete(fm.clients, client.key)
+		slog.Info("SSH connection closed, no forwarding uses it", "address", client.key, "host", client.host)
+	}
+	return true
+}
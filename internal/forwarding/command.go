@@ -0,0 +1,30 @@
+package forwarding
+
+import (
+	"fmt"
+
+	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
+)
+
+// SSHCommand returns the ssh command line that opens the same forwarding
+// through host as the rule, for sharing with someone who does not use xssh.
+// The hops of the rule replace the host's own jump hosts. Settings ssh has
+// no option for, such as TLS, expiry and local resolution, are left out.
+func (r ForwardingRule) SSHCommand(host config.SSHHost) string {
+	var flag, spec string
+	switch r.Type {
+	case LocalForward:
+		flag, spec = "-L", fmt.Sprintf("%s:%d:%s", r.LocalHost, r.LocalPort, r.RemoteTarget())
+	case RemoteForward:
+		flag, spec = "-R", fmt.Sprintf("%d:%s:%d", r.RemotePort, r.LocalHost, r.LocalPort)
+	case DynamicForward:
+		flag, spec = "-D", fmt.Sprintf("%s:%d", r.LocalHost, r.LocalPort)
+	case RemoteDynamicForward:
+		flag, spec = "-R", fmt.Sprint(r.RemotePort)
+	}
+	if len(r.Via) > 0 {
+		host.ProxyJump = r.ViaString()
+	}
+	return xssh.ShareableCommand(host, "-N", flag, spec)
+}
@@ -0,0 +1,131 @@
+package forwarding
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Expiry stops a forwarding on its own, so a tunnel left open is cleaned up:
+// After a duration from its start, or At a time of day
+type Expiry struct {
+	After time.Duration `json:",omitempty"`
+	At    string        `json:",omitempty"` // "15:04", local time
+}
+
+// ParseExpiry parses the expiry of a rule: empty for none, a duration such
+// as "2h" or "90m", or a time of day such as "18:00"
+func ParseExpiry(value string) (*Expiry, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if at, err := time.Parse("15:04", value); err == nil {
+		return &Expiry{At: at.Format("15:04")}, nil
+	}
+	after, err := time.ParseDuration(value)
+	if err != nil || after <= 0 {
+		return nil, fmt.Errorf("invalid expiry %q, expected a duration such as 2h or a time such as 18:00", value)
+	}
+	return &Expiry{After: after}, nil
+}
+
+// String returns the expiry as ParseExpiry reads it, empty for nil
+func (e *Expiry) String() string {
+	switch {
+	case e == nil:
+		return ""
+	case e.At != "":
+		return e.At
+	default:
+		return e.After.String()
+	}
+}
+
+// deadline returns when a forwarding expires that starts, or is renewed,
+// at from: After later, or at the next At strictly after from
+func (e *Expiry) deadline(from time.Time) time.Time {
+	if e.At == "" {
+		return from.Add(e.After)
+	}
+	at, _ := time.Parse("15:04", e.At)
+	deadline := time.Date(from.Year(), from.Month(), from.Day(), at.Hour(), at.Minute(), 0, 0, from.Location())
+	if !deadline.After(from) {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return deadline
+}
+
+// ExpiresAt returns when the session stops on its own, zero if never
+func (fs *ForwardingSession) ExpiresAt() time.Time {
+	fs.expiryMu.Lock()
+	defer fs.expiryMu.Unlock()
+	return fs.expiresAt
+}
+
+// armExpiry sets the session to stop at deadline, or never when deadline
+// is zero, replacing the deadline it had
+func (fm *ForwardingManager) armExpiry(session *ForwardingSession, deadline time.Time) {
+	session.expiryMu.Lock()
+	defer session.expiryMu.Unlock()
+	if session.expiryTimer != nil {
+		session.expiryTimer.Stop()
+		session.expiryTimer = nil
+	}
+	session.expiresAt = deadline
+	if !deadline.IsZero() {
+		session.expiryTimer = time.AfterFunc(time.Until(deadline), func() { fm.expire(session) })
+	}
+}
+
+// expire stops a session whose deadline passed
+func (fm *ForwardingManager) expire(session *ForwardingSession) {
+	session.expiryMu.Lock()
+	expiresAt := session.expiresAt
+	session.expiryMu.Unlock()
+	if expiresAt.IsZero() || time.Now().Before(expiresAt) || !fm.holds(session) {
+		return // Renewed or stopped meanwhile
+	}
+	slog.Info("forwarding expired", "id", session.Rule.ID, "expiry", session.Rule.Expiry.String())
+	fm.StopForwarding(session.Rule.ID)
+}
+
+// RenewForwarding pushes back the expiry of a session and returns its new
+// deadline: a duration runs again from now, and a time of day moves to the
+// next day's
+func (fm *ForwardingManager) RenewForwarding(sessionID string) (time.Time, error) {
+	session, exists := fm.GetSession(sessionID)
+	if !exists {
+		return time.Time{}, fmt.Errorf("session %s not found", sessionID)
+	}
+	expiry := session.Rule.Expiry
+	if expiry == nil {
+		return time.Time{}, fmt.Errorf("forwarding session %s does not expire", sessionID)
+	}
+	from := time.Now()
+	if expiresAt := session.ExpiresAt(); expiry.At != "" && expiresAt.After(from) {
+		from = expiresAt
+	}
+	deadline := expiry.deadline(from)
+	fm.armExpiry(session, deadline)
+	slog.Info("forwarding renewed", "id", sessionID, "expires", deadline.Format(time.DateTime))
+	return deadline, nil
+}
+
+// RenewMatching renews the expiring sessions selected by a session ID, a glob
+// pattern or "all", as MatchSessions does, and returns their IDs. Sessions
+// that never expire are left alone.
+func (fm *ForwardingManager) RenewMatching(pattern string) ([]string, error) {
+	ids, err := fm.MatchSessions(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var renewed []string
+	for _, id := range ids {
+		if _, err := fm.RenewForwarding(id); err == nil {
+			renewed = append(renewed, id)
+		}
+	}
+	return renewed, nil
+}
@@ -0,0 +1,71 @@
+package forwarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// healthTimeout is how long checking the health of a session may take
+const healthTimeout = 5 * time.Second
+
+// CheckHealth checks that a session is usable end to end: it is running,
+// its SSH connection answers a keepalive, and its target accepts a
+// connection, reached through the SSH connection for a local forwarding
+// and from this machine for a remote one. SOCKS proxies have no single
+// target and only need their connection. It returns why the session is
+// unhealthy, or nil.
+func (fm *ForwardingManager) CheckHealth(ctx context.Context, sessionID string) error {
+	session, exists := fm.GetSession(sessionID)
+	if !exists {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	if !session.IsActive() {
+		return errors.New("not running")
+	}
+	if session.Degraded() {
+		return errors.New("SSH connection lost, reconnecting")
+	}
+
+	fm.mu.Lock()
+	client := session.client
+	fm.mu.Unlock()
+	if client == nil {
+		return errors.New("no SSH connection")
+	}
+	if err := checkClient(client); err != nil {
+		return fmt.Errorf("SSH connection not answering: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	rule := session.Rule
+	switch rule.Type {
+	case LocalForward:
+		network := "tcp"
+		if rule.RemoteSocket != "" {
+			network = "unix"
+		}
+		remoteAddr, _, err := fm.dialTarget(rule)
+		if err != nil {
+			return fmt.Errorf("target %s not reachable: %w", rule.RemoteTarget(), err)
+		}
+		conn, err := client.DialContext(ctx, network, remoteAddr)
+		if err != nil {
+			return fmt.Errorf("target %s not reachable: %w", rule.RemoteTarget(), err)
+		}
+		conn.Close()
+	case RemoteForward:
+		target := net.JoinHostPort(rule.LocalHost, strconv.Itoa(rule.LocalPort))
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return fmt.Errorf("target %s not reachable: %w", target, err)
+		}
+		conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package forwarding
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseLabels parses the labels of a forwarding, separated by commas, such
+// as "dev,db": empty for none. Labels are kept in lower case, once each.
+func ParseLabels(value string) ([]string, error) {
+	var labels []string
+	for _, label := range strings.Split(value, ",") {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" {
+			continue
+		}
+		if strings.IndexFunc(label, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+		}) >= 0 {
+			return nil, fmt.Errorf("invalid label %q, expected letters, digits, '-', '_' or '.'", label)
+		}
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// LabelsString returns the labels of the rule as ParseLabels reads them
+func (r ForwardingRule) LabelsString() string {
+	return strings.Join(r.Labels, ",")
+}
+
+// sessionFilterFields maps the operators of a session filter to the field
+// they restrict a term to
+var sessionFilterFields = map[string]string{
+	"label":  "label",
+	"labels": "label",
+	"host":   "host",
+	"type":   "type",
+	"id":     "id",
+	"port":   "port",
+}
+
+// FilterTerm is one word of a session filter, optionally restricted to a
+// field with an operator such as "label=dev" or "label:dev"
+type FilterTerm struct {
+	Field string // Empty to match any field
+	Value string // Lower case
+}
+
+// SessionFilter selects forwarding sessions by all of its terms
+type SessionFilter []FilterTerm
+
+// ParseSessionFilter splits a filter into terms. A word whose prefix is not
+// a known operator is searched for as a whole.
+func ParseSessionFilter(query string) SessionFilter {
+	var filter SessionFilter
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		term := FilterTerm{Value: word}
+		if i := strings.IndexAny(word, "=:"); i > 0 {
+			if field, known := sessionFilterFields[word[:i]]; known {
+				term = FilterTerm{Field: field, Value: word[i+1:]}
+			}
+		}
+		if term.Value != "" {
+			filter = append(filter, term)
+		}
+	}
+	return filter
+}
+
+// Matches reports whether the rule of a session tunneling through the host
+// of that alias matches every term of the filter. Labels and ports match
+// whole, the other fields on part of their value.
+func (f SessionFilter) Matches(rule ForwardingRule, host string) bool {
+	for _, term := range f {
+		if !term.matches(rule, host) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the rule matches one term
+func (t FilterTerm) matches(rule ForwardingRule, host string) bool {
+	switch t.Field {
+	case "label":
+		return slices.ContainsFunc(rule.Labels, func(label string) bool {
+			return strings.EqualFold(label, t.Value)
+		})
+	case "port":
+		return t.Value == strconv.Itoa(rule.LocalPort) || t.Value == strconv.Itoa(rule.RemotePort)
+	case "host":
+		return strings.Contains(strings.ToLower(host), t.Value)
+	case "type":
+		return strings.Contains(strings.ToLower(rule.Type.String()), t.Value)
+	case "id":
+		return strings.Contains(strings.ToLower(rule.ID), t.Value)
+	}
+	values := append([]string{rule.ID, rule.Description, host}, rule.Labels...)
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), t.Value) {
+			return true
+		}
+	}
+	return false
+}
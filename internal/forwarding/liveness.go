@@ -0,0 +1,165 @@
+package forwarding
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Liveness checks of the shared SSH connections
+const (
+	livenessInterval = 15 * time.Second // Time between checks
+	livenessTimeout  = 10 * time.Second // Wait for the answer to a check
+	maxReconnectWait = 30 * time.Second // Longest wait between reconnect attempts
+)
+
+// errCheckTimeout is the failure of a check the server did not answer in time
+var errCheckTimeout = errors.New("no answer to keepalive")
+
+// watchClients checks the shared SSH connections every livenessInterval,
+// and sooner when checkClientsSoon asks for it, until Shutdown starts
+func (fm *ForwardingManager) watchClients() {
+	defer fm.running.Done()
+	ticker := time.NewTicker(livenessInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fm.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-fm.checkSoon:
+		}
+		fm.checkClients()
+	}
+}
+
+// checkClientsSoon wakes the watchdog, as when a remote listener closed
+func (fm *ForwardingManager) checkClientsSoon() {
+	select {
+	case fm.checkSoon <- struct{}{}:
+	default:
+	}
+}
+
+// checkClients sends a keepalive on every shared SSH connection and
+// reconnects the sessions of the ones that do not answer
+func (fm *ForwardingManager) checkClients() {
+	fm.mu.Lock()
+	clients := make([]*sharedClient, 0, len(fm.clients))
+	for _, client := range fm.clients {
+		clients = append(clients, client)
+	}
+	fm.mu.Unlock()
+
+	var checks sync.WaitGroup
+	for _, client := range clients {
+		checks.Add(1)
+		go func() {
+			defer checks.Done()
+			if err := checkClient(client); err != nil {
+				fm.clientDied(client, err)
+			}
+		}()
+	}
+	checks.Wait()
+}
+
+// checkClient sends a keepalive on client, giving up after livenessTimeout
+func checkClient(client *sharedClient) error {
+	answered := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@golang.org", true, nil)
+		answered <- err
+	}()
+	select {
+	case err := <-answered:
+		return err
+	case <-time.After(livenessTimeout):
+		return errCheckTimeout
+	}
+}
+
+// clientDied drops a dead SSH connection and reconnects the sessions using
+// it, which are degraded until they are back
+func (fm *ForwardingManager) clientDied(client *sharedClient, err error) {
+	fm.mu.Lock()
+	if fm.clients[client.key] == client {
+		delete(fm.clients, client.key)
+	}
+	var sessions []*ForwardingSession
+	for _, session := range fm.sessions {
+		if session.client == client {
+			sessions = append(sessions, session)
+		}
+	}
+	fm.mu.Unlock()
+	client.Close()
+
+	slog.Warn("SSH connection died, reconnecting its forwardings", "address", client.key, "host", client.host, "sessions", len(sessions), "error", err)
+	for _, session := range sessions {
+		if !atomic.CompareAndSwapInt32(&session.degraded, 0, 1) {
+			continue // Already reconnecting
+		}
+		session.IncrementErrors(fmt.Sprintf("SSH connection lost: %v", err))
+		fm.running.Add(1)
+		go fm.reconnect(session)
+	}
+}
+
+// reconnect restarts a degraded session on a new SSH connection, which
+// listens again on the remote host for -R sessions, retrying with a growing
+// wait until it is back, stopped or the manager shuts down
+func (fm *ForwardingManager) reconnect(session *ForwardingSession) {
+	defer fm.running.Done()
+	defer atomic.StoreInt32(&session.degraded, 0)
+
+	session.restartMu.Lock()
+	defer session.restartMu.Unlock()
+
+	// Release the listener of the dead connection; the new one binds the
+	// same address
+	session.halt()
+	done := session.rearm()
+
+	wait := time.Second
+	for attempt := 1; ; attempt++ {
+		if !fm.holds(session) {
+			return
+		}
+		err := fm.startSession(fm.ctx, session)
+		if err == nil && !fm.holds(session) {
+			// Stopped while connecting
+			session.halt()
+			return
+		}
+		if err == nil {
+			session.Stats.ReconnectCount++
+			slog.Info("forwarding reconnected", "id", session.Rule.ID, "attempt", attempt)
+			return
+		}
+		session.IncrementErrors(fmt.Sprintf("Reconnect failed (attempt %d): %v", attempt, err))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-fm.ctx.Done():
+			timer.Stop()
+			return
+		case <-done:
+			// Stopped meanwhile
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		wait = min(2*wait, maxReconnectWait)
+	}
+}
+
+// holds reports whether session is still one of the manager's sessions
+func (fm *ForwardingManager) holds(session *ForwardingSession) bool {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	return fm.sessions[session.Rule.ID] == session
+}
@@ -0,0 +1,176 @@
+package forwarding
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"xssh/internal/config"
+	"xssh/internal/testsupport"
+)
+
+// newEchoDialer returns a dialer whose connections write back what is sent
+// through them, failing its first dials with failures
+func newEchoDialer(failures ...error) *testsupport.FakeDialer {
+	return &testsupport.FakeDialer{
+		Failures: failures,
+		NewConn: func(config.SSHHost) *testsupport.FakeConn {
+			return &testsupport.FakeConn{Handler: echoHandler(nil)}
+		},
+	}
+}
+
+// killConn makes the latest SSH connection of dialer die and runs the
+// watchdog, as its next tick would
+func killConn(fm *ForwardingManager, dialer *testsupport.FakeDialer) *testsupport.FakeConn {
+	conns := dialer.Conns()
+	conn := conns[len(conns)-1]
+	conn.Kill()
+	fm.checkClients()
+	return conn
+}
+
+// waitReconnected waits until session is no longer degraded
+func waitReconnected(t *testing.T, session *ForwardingSession) {
+	t.Helper()
+	eventually(t, "the session to reconnect", func() bool {
+		return !session.Degraded()
+	})
+}
+
+func TestReconnectLocalForwarding(t *testing.T) {
+	useTempHome(t)
+	dialer := newEchoDialer()
+	fm := newTestManager(t, dialer)
+
+	rule := ForwardingRule{ID: "local", Type: LocalForward, LocalHost: "127.0.0.1", RemoteHost: "db", RemotePort: 5432}
+	session, err := fm.StartForwarding(context.Background(), rule, fakeHost, testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	dead := killConn(fm, dialer)
+	waitReconnected(t, session)
+
+	if !dead.Closed() {
+		t.Error("the dead connection was not closed")
+	}
+	if dials := len(dialer.Dials()); dials != 2 {
+		t.Errorf("dialed %d times, want 2", dials)
+	}
+	if session.Stats.ReconnectCount != 1 {
+		t.Errorf("ReconnectCount = %d, want 1", session.Stats.ReconnectCount)
+	}
+	if !session.IsActive() {
+		t.Error("the session is not active after reconnecting")
+	}
+
+	// The new listener forwards through the new connection
+	conn, err := net.Dial("tcp", session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	expectEcho(t, conn, "after reconnect")
+}
+
+func TestReconnectRemoteForwarding(t *testing.T) {
+	useTempHome(t)
+	echoHost, echoPort := echoServer(t)
+	dialer := newEchoDialer()
+	fm := newTestManager(t, dialer)
+
+	rule := ForwardingRule{ID: "remote", Type: RemoteForward, LocalHost: echoHost, LocalPort: echoPort, RemoteHost: "127.0.0.1", RemotePort: 9000}
+	session, err := fm.StartForwarding(context.Background(), rule, fakeHost, testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	killConn(fm, dialer)
+	waitReconnected(t, session)
+
+	// The remote port is listened on again, on the new connection
+	conns := dialer.Conns()
+	if len(conns) != 2 {
+		t.Fatalf("%d connections, want 2", len(conns))
+	}
+	listener := conns[1].Listener("127.0.0.1:9000")
+	if listener == nil {
+		t.Fatal("the remote port is not listened on after reconnecting")
+	}
+	conn, err := listener.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer conn.Close()
+	expectEcho(t, conn, "remote after reconnect")
+}
+
+func TestReconnectRetries(t *testing.T) {
+	useTempHome(t)
+	dialer := newEchoDialer()
+	fm := newTestManager(t, dialer)
+
+	rule := ForwardingRule{ID: "local", Type: LocalForward, LocalHost: "127.0.0.1", RemoteHost: "db", RemotePort: 5432}
+	session, err := fm.StartForwarding(context.Background(), rule, fakeHost, testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	// The host is down for the first attempt; no dial runs meanwhile
+	dialer.Failures = []error{errors.New("connection refused")}
+	killConn(fm, dialer)
+	waitReconnected(t, session)
+
+	if dials := len(dialer.Dials()); dials != 3 {
+		t.Errorf("dialed %d times, want 3", dials)
+	}
+	if session.Stats.ReconnectCount != 1 {
+		t.Errorf("ReconnectCount = %d, want 1", session.Stats.ReconnectCount)
+	}
+	var failed bool
+	for _, entry := range session.ErrorLog() {
+		if strings.Contains(entry.Message, "Reconnect failed (attempt 1)") {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Errorf("the failed attempt is not in the error log: %+v", session.ErrorLog())
+	}
+}
+
+func TestReconnectStopsWithSession(t *testing.T) {
+	useTempHome(t)
+	dialer := newEchoDialer()
+	fm := newTestManager(t, dialer)
+
+	rule := ForwardingRule{ID: "local", Type: LocalForward, LocalHost: "127.0.0.1", RemoteHost: "db", RemotePort: 5432}
+	session, err := fm.StartForwarding(context.Background(), rule, fakeHost, testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	// The host stays down
+	down := make([]error, 100)
+	for i := range down {
+		down[i] = errors.New("connection refused")
+	}
+	dialer.Failures = down
+	killConn(fm, dialer)
+	eventually(t, "a reconnect attempt", func() bool {
+		return len(dialer.Dials()) >= 2
+	})
+
+	if err := fm.StopForwarding("local"); err != nil {
+		t.Fatalf("StopForwarding: %v", err)
+	}
+	waitReconnected(t, session)
+	if _, exists := fm.GetSession("local"); exists {
+		t.Error("the stopped session came back")
+	}
+	if dials := len(dialer.Dials()); dials != 2 {
+		t.Errorf("dialed %d times, want 2: reconnecting went on after the stop", dials)
+	}
+}
@@ -1,193 +1,428 @@
 package forwarding
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"log/slog"
+	"net"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
-	"golang.org/x/crypto/ssh"
 	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
 )
 
+// totalsInterval is how often the totals of running sessions are stored
+const totalsInterval = 30 * time.Second
+
+// ErrShutdown is returned when starting a forwarding on a manager that has
+// been shut down
+var ErrShutdown = errors.New("port forwarding is shutting down")
+
 // ForwardingManager manages all port forwarding sessions
 type ForwardingManager struct {
-	sessions sync.Map // map[string]*ForwardingSession
-	sshClients sync.Map // map[string]*ssh.Client for SSH connections
-	mu       sync.RWMutex
+	dialer xssh.Dialer
+
+	mu       sync.Mutex                    // Guards the fields below
+	sessions map[string]*ForwardingSession // Keyed by rule ID, including sessions being started
+	clients  map[string]*sharedClient      // SSH connections, keyed by user@host:port
+	closed   bool                          // Whether Shutdown has been called
+
+	pending  sync.WaitGroup  // Sessions being started or updated, which Shutdown waits for
+	running  sync.WaitGroup  // Accept loops and connection handlers
+	ctx      context.Context // Canceled when Shutdown starts, ending dials in flight
+	cancel   context.CancelFunc
+	abort    context.Context // Canceled when Shutdown stops waiting, cutting open connections
+	abortAll context.CancelFunc
+
+	checkSoon chan struct{} // Wakes the watchdog of the SSH connections
 }
 
-// NewManager creates a new forwarding manager
+// NewManager creates a new forwarding manager connecting with
+// ssh.DefaultDialer
 func NewManager() *ForwardingManager {
-	return &ForwardingManager{}
+	return NewManagerWithDialer(xssh.DefaultDialer)
 }
 
-// StartForwarding starts a new port forwarding session
-func (fm *ForwardingManager) StartForwarding(rule ForwardingRule, host config.SSHHost, keyPassword string) error {
-	// Check if session already exists
-	if _, exists := fm.sessions.Load(rule.ID); exists {
-		return fmt.Errorf("forwarding session %s already exists", rule.ID)
+// NewManagerWithDialer creates a new forwarding manager opening its SSH
+// connections with dialer
+func NewManagerWithDialer(dialer xssh.Dialer) *ForwardingManager {
+	fm := &ForwardingManager{
+		dialer:    dialer,
+		sessions:  map[string]*ForwardingSession{},
+		clients:   map[string]*sharedClient{},
+		checkSoon: make(chan struct{}, 1),
 	}
+	fm.ctx, fm.cancel = context.WithCancel(context.Background())
+	fm.abort, fm.abortAll = context.WithCancel(context.Background())
+	fm.running.Add(2)
+	go fm.saveTotalsPeriodically()
+	go fm.watchClients()
+	return fm
+}
 
-	// Create new session
+// StartForwarding starts a new port forwarding session and returns it,
+// logging in to the host with auth when its key alone is not enough.
+// Canceling ctx, or shutting the manager down, gives up connecting to the
+// host; once started the session runs until it is stopped.
+func (fm *ForwardingManager) StartForwarding(ctx context.Context, rule ForwardingRule, host config.SSHHost, auth xssh.Auth) (*ForwardingSession, error) {
 	session := &ForwardingSession{
 		Rule: rule,
 		Stats: ForwardingStats{
 			StartTime: time.Now(),
 		},
-		done: make(chan struct{}),
+		done:    make(chan struct{}),
+		host:    host,
+		auth:    auth,
+		manager: fm,
 	}
 
-	// Store session
-	fm.sessions.Store(rule.ID, session)
+	// Reserve the ID while connecting
+	fm.mu.Lock()
+	if fm.closed {
+		fm.mu.Unlock()
+		return nil, ErrShutdown
+	}
+	if _, exists := fm.sessions[rule.ID]; exists {
+		fm.mu.Unlock()
+		return nil, fmt.Errorf("forwarding session %s already exists", rule.ID)
+	}
+	fm.sessions[rule.ID] = session
+	fm.pending.Add(1)
+	fm.mu.Unlock()
+	defer fm.pending.Done()
 
-	// Start the appropriate forwarding type
-	var err error
-	switch rule.Type {
-	case LocalForward:
-		err = fm.startLocalForwarding(session, host, keyPassword)
-	case RemoteForward:
-		err = fm.startRemoteForwarding(session, host, keyPassword)
-	case DynamicForward:
-		err = fm.startDynamicForwarding(session, host, keyPassword)
-	default:
-		err = fmt.Errorf("unsupported forwarding type: %v", rule.Type)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(fm.ctx, cancel)()
+
+	session.loadTotals()
+	err := fm.checkLocalPort(rule)
+	if err == nil {
+		err = fm.startSession(ctx, session)
+	}
+	if err != nil {
+		fm.mu.Lock()
+		delete(fm.sessions, rule.ID)
+		fm.mu.Unlock()
+		slog.Warn("forwarding failed to start", "id", rule.ID, "rule", rule.Description, "host", host.Name, "error", err)
+		return nil, err
+	}
+
+	session.SetActive(true)
+	if rule.Expiry != nil {
+		fm.armExpiry(session, rule.Expiry.deadline(session.Stats.StartTime))
+	}
+	if rule.Capture != nil {
+		if _, err := session.StartCapture(*rule.Capture); err != nil {
+			session.IncrementErrors(fmt.Sprintf("Failed to start capturing traffic: %v", err))
+		}
 	}
+	slog.Info("forwarding started", "id", rule.ID, "rule", rule.Description, "host", host.Name)
+	auditForwarding(config.AuditTunnelOpened, session)
+	return session, nil
+}
 
+// UpdateForwarding replaces the rule of a running session and restarts it in
+// place. The session keeps its ID and statistics. If the new rule cannot be
+// started, the previous rule is restored and the error is returned.
+func (fm *ForwardingManager) UpdateForwarding(sessionID string, rule ForwardingRule) error {
+	fm.mu.Lock()
+	session, exists := fm.sessions[sessionID]
+	if fm.closed {
+		fm.mu.Unlock()
+		return ErrShutdown
+	}
+	fm.pending.Add(1)
+	fm.mu.Unlock()
+	defer fm.pending.Done()
+
+	if !exists {
+		return fmt.Errorf("session %s not found", sessionID)
+	}
+	if rule.Type != session.Rule.Type {
+		return fmt.Errorf("cannot change the type of forwarding session %s", sessionID)
+	}
+	rule.ID = sessionID
+
+	session.restartMu.Lock()
+	defer session.restartMu.Unlock()
+
+	// Keep the SSH connection open while no listener holds it
+	fm.mu.Lock()
+	held := session.client
+	fm.mu.Unlock()
+	if held != nil {
+		fm.retainConn(held)
+		defer fm.releaseConn(held)
+	}
+
+	// Release the current listener so the new rule can bind the same address
+	previousRule := session.Rule
+	session.SetActive(false)
+	session.halt()
+
+	session.Rule = rule
+	session.rearm()
+	err := fm.startSession(fm.ctx, session)
+	if err == nil && !fm.holds(session) {
+		// Stopped while restarting; StopForwarding does not wait for
+		// restartMu, so the new listener is left to close here
+		session.halt()
+		return fmt.Errorf("session %s was stopped", sessionID)
+	}
 	if err != nil {
-		fm.sessions.Delete(rule.ID)
+		session.Rule = previousRule
+		session.rearm()
+		if restoreErr := fm.startSession(fm.ctx, session); restoreErr != nil {
+			fm.mu.Lock()
+			delete(fm.sessions, sessionID)
+			fm.mu.Unlock()
+			return fmt.Errorf("%v (restoring the previous rule also failed: %v)", err, restoreErr)
+		}
+		if !fm.holds(session) {
+			session.halt()
+			return err
+		}
+		session.SetActive(true)
+		slog.Warn("forwarding update failed, previous rule restored", "id", sessionID, "error", err)
 		return err
 	}
 
+	session.Stats.RestartCount++
 	session.SetActive(true)
+	if rule.Expiry.String() != previousRule.Expiry.String() {
+		// A new expiry counts from now
+		var deadline time.Time
+		if rule.Expiry != nil {
+			deadline = rule.Expiry.deadline(time.Now())
+		}
+		fm.armExpiry(session, deadline)
+	}
+	slog.Info("forwarding updated", "id", sessionID, "rule", rule.Description)
 	return nil
 }
 
+// startSession starts the listener for a session according to its rule type,
+// giving up connecting to the host when ctx is canceled
+func (fm *ForwardingManager) startSession(ctx context.Context, session *ForwardingSession) error {
+	switch session.Rule.Type {
+	case LocalForward:
+		return fm.startLocalForwarding(ctx, session, session.host, session.auth)
+	case RemoteForward:
+		return fm.startRemoteForwarding(ctx, session, session.host, session.auth)
+	case DynamicForward:
+		return fm.startDynamicForwarding(ctx, session, session.host, session.auth)
+	case RemoteDynamicForward:
+		return fm.startRemoteDynamicForwarding(ctx, session, session.host, session.auth)
+	default:
+		return fmt.Errorf("unsupported forwarding type: %v", session.Rule.Type)
+	}
+}
+
 // StopForwarding stops a port forwarding session
 func (fm *ForwardingManager) StopForwarding(sessionID string) error {
-	sessionInterface, exists := fm.sessions.Load(sessionID)
+	fm.mu.Lock()
+	session, exists := fm.sessions[sessionID]
+	delete(fm.sessions, sessionID)
+	fm.mu.Unlock()
 	if !exists {
 		return fmt.Errorf("session %s not found", sessionID)
 	}
 
-	session := sessionInterface.(*ForwardingSession)
 	session.SetActive(false)
-
-	// Close listener if exists
-	if session.listener != nil {
-		session.listener.Close()
-	}
-
-	// Signal shutdown
-	close(session.done)
-
-	// Remove from sessions
-	fm.sessions.Delete(sessionID)
+	session.halt()
+	fm.armExpiry(session, time.Time{})
+	session.StopCapture()
+	slog.Info("forwarding stopped", "id", sessionID)
+	session.record()
+	saveTotals(session)
 
 	return nil
 }
 
 // GetSession retrieves a forwarding session by ID
 func (fm *ForwardingManager) GetSession(sessionID string) (*ForwardingSession, bool) {
-	sessionInterface, exists := fm.sessions.Load(sessionID)
-	if !exists {
-		return nil, false
-	}
-	return sessionInterface.(*ForwardingSession), true
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	session, exists := fm.sessions[sessionID]
+	return session, exists
 }
 
-// GetAllSessions returns all active forwarding sessions
+// GetAllSessions returns all active forwarding sessions, oldest first
 func (fm *ForwardingManager) GetAllSessions() []*ForwardingSession {
-	var sessions []*ForwardingSession
-	fm.sessions.Range(func(key, value interface{}) bool {
-		session := value.(*ForwardingSession)
+	fm.mu.Lock()
+	sessions := make([]*ForwardingSession, 0, len(fm.sessions))
+	for _, session := range fm.sessions {
 		sessions = append(sessions, session)
-		return true
+	}
+	fm.mu.Unlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].Stats.StartTime.Equal(sessions[j].Stats.StartTime) {
+			return sessions[i].Stats.StartTime.Before(sessions[j].Stats.StartTime)
+		}
+		return sessions[i].Rule.ID < sessions[j].Rule.ID
 	})
 	return sessions
 }
 
+// RenameHost updates the sessions tunneling through the host oldName, or
+// through it as a jump host, after the host was renamed to newName
+func (fm *ForwardingManager) RenameHost(oldName, newName string) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	for _, session := range fm.sessions {
+		if session.host.Name == oldName {
+			session.host.Name = newName
+		}
+		session.host.ProxyJump = config.RenameJumpHop(session.host.ProxyJump, oldName, newName)
+		for i, hop := range session.Rule.Via {
+			session.Rule.Via[i] = config.RenameJumpHop(hop, oldName, newName)
+		}
+	}
+}
+
 // StopAll stops all forwarding sessions
 func (fm *ForwardingManager) StopAll() {
-	var sessionIDs []string
-	fm.sessions.Range(func(key, value interface{}) bool {
-		sessionIDs = append(sessionIDs, key.(string))
-		return true
-	})
-
-	for _, id := range sessionIDs {
-		fm.StopForwarding(id)
+	for _, session := range fm.GetAllSessions() {
+		fm.StopForwarding(session.Rule.ID)
 	}
 }
 
-// GetSSHClient gets or creates an SSH client for the host
-func (fm *ForwardingManager) getSSHClient(host config.SSHHost, keyPassword string) (*ssh.Client, error) {
-	clientKey := fmt.Sprintf("%s@%s:%s", host.User, host.Host, host.Port)
-	
-	// Check if client already exists
-	if clientInterface, exists := fm.sshClients.Load(clientKey); exists {
-		client := clientInterface.(*ssh.Client)
-		// Test if connection is still alive
-		_, _, err := client.SendRequest("keepalive@golang.org", true, nil)
-		if err == nil {
-			return client, nil
-		}
-		// Connection is dead, remove it
-		fm.sshClients.Delete(clientKey)
+// Shutdown stops all sessions and gives their open connections until ctx is
+// done to finish, then cuts the remaining ones and closes the SSH
+// connections. Connecting to a host for a session being started is given up
+// at once, and no session can be started afterwards. It returns ctx.Err()
+// when connections had to be cut.
+func (fm *ForwardingManager) Shutdown(ctx context.Context) error {
+	fm.mu.Lock()
+	fm.closed = true
+	fm.mu.Unlock()
+	fm.cancel()
+	fm.pending.Wait()
+	sessions := fm.GetAllSessions()
+	fm.StopAll()
+
+	drained := make(chan struct{})
+	go func() {
+		fm.running.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		slog.Warn("forwarding connections cut at shutdown", "error", err)
+	}
+	fm.abortAll()
+	<-drained
+	// Count what the connections sent since their sessions were stopped
+	saveTotals(sessions...)
+
+	fm.mu.Lock()
+	clients := make([]*sharedClient, 0, len(fm.clients))
+	for _, client := range fm.clients {
+		clients = append(clients, client)
+	}
+	fm.clients = map[string]*sharedClient{}
+	fm.mu.Unlock()
+	// Close the connections tunneled through hops before the hops
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].depth() > clients[j].depth()
+	})
+	for _, client := range clients {
 		client.Close()
 	}
+	return err
+}
 
-	// Create new SSH client
-	client, err := fm.createSSHClient(host, keyPassword)
-	if err != nil {
-		return nil, err
+// saveTotalsPeriodically stores the totals of the running sessions every
+// totalsInterval until Shutdown starts, so a crash loses little
+func (fm *ForwardingManager) saveTotalsPeriodically() {
+	defer fm.running.Done()
+	ticker := time.NewTicker(totalsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fm.ctx.Done():
+			return
+		case <-ticker.C:
+			saveTotals(fm.GetAllSessions()...)
+		}
 	}
+}
 
-	fm.sshClients.Store(clientKey, client)
-	return client, nil
+// saveTotals stores the totals of sessions. Failing to store them is only
+// logged, the sessions keep counting.
+func saveTotals(sessions ...*ForwardingSession) {
+	if len(sessions) == 0 {
+		return
+	}
+	totals := make([]config.ForwardTotals, 0, len(sessions))
+	for _, session := range sessions {
+		totals = append(totals, session.Totals())
+	}
+	if err := config.SaveForwardTotals(totals); err != nil {
+		slog.Warn("failed to save forwarding totals", "error", err)
+	}
 }
 
-// createSSHClient creates a new SSH client connection
-func (fm *ForwardingManager) createSSHClient(host config.SSHHost, keyPassword string) (*ssh.Client, error) {
-	var auth []ssh.AuthMethod
+// cutOnAbort closes conn when Shutdown stops waiting for connections, and
+// returns the function to call once the connection is done
+func (fm *ForwardingManager) cutOnAbort(conn net.Conn) func() bool {
+	return context.AfterFunc(fm.abort, func() { conn.Close() })
+}
 
-	if host.Identity != "" {
-		// Use key-based authentication
-		key, err := fm.loadPrivateKey(host.Identity, keyPassword)
+// MatchSessions returns the IDs of the sessions a pattern selects, oldest
+// first, as MatchID decides
+func (fm *ForwardingManager) MatchSessions(pattern string) ([]string, error) {
+	var ids []string
+	for _, session := range fm.GetAllSessions() {
+		matched, err := MatchID(pattern, session.Rule.ID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to load private key: %v", err)
+			return nil, err
+		}
+		if matched {
+			ids = append(ids, session.Rule.ID)
 		}
-		auth = append(auth, ssh.PublicKeys(key))
-	}
-
-	config := &ssh.ClientConfig{
-		User:            host.User,
-		Auth:            auth,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
 	}
+	return ids, nil
+}
 
-	client, err := ssh.Dial("tcp", host.Host+":"+host.Port, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to SSH server: %v", err)
+// MatchID reports whether a pattern selects the session with the given ID:
+// "all" selects every session, a pattern with glob characters (*, ?, [...])
+// is matched against the ID like a file name, and anything else must be the
+// exact ID
+func MatchID(pattern, id string) (bool, error) {
+	switch {
+	case pattern == "all":
+		return true, nil
+	case strings.ContainsAny(pattern, "*?["):
+		matched, err := path.Match(pattern, id)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return matched, nil
 	}
-
-	return client, nil
+	return id == pattern, nil
 }
 
-// loadPrivateKey loads and parses a private key with optional password
-func (fm *ForwardingManager) loadPrivateKey(keyPath, keyPassword string) (ssh.Signer, error) {
-	keyData, err := os.ReadFile(keyPath)
+// StopMatching stops the sessions selected by pattern, as in MatchSessions,
+// and returns their IDs
+func (fm *ForwardingManager) StopMatching(pattern string) ([]string, error) {
+	ids, err := fm.MatchSessions(pattern)
 	if err != nil {
 		return nil, err
 	}
-
-	var key ssh.Signer
-	if keyPassword != "" {
-		key, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(keyPassword))
-	} else {
-		key, err = ssh.ParsePrivateKey(keyData)
+	for _, id := range ids {
+		fm.StopForwarding(id)
 	}
-
-	return key, err
-}
\ No newline at end of file
+	return ids, nil
+}
@@ -0,0 +1,254 @@
+package forwarding
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
+	"xssh/internal/sshtest"
+)
+
+// testAuth logs in to the servers of newTestServer
+var testAuth = xssh.Auth{Password: "secret"}
+
+// useTempHome points HOME and the xssh config at temporary directories, so
+// tests write no totals, background sessions or audit events of the user
+func useTempHome(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(config.EnvConfigDir, filepath.Join(home, ".config", "xssh"))
+	t.Setenv(config.EnvConfig, filepath.Join(home, ".config", "xssh", "config.toml"))
+}
+
+// newTestServer starts an sshtest server accepting "tester" with password
+// "secret"
+func newTestServer(t *testing.T) *sshtest.Server {
+	t.Helper()
+	useTempHome(t)
+	server, err := sshtest.NewServer("tester", "secret")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// newTestManager returns a manager shut down when the test ends
+func newTestManager(t *testing.T, dialer xssh.Dialer) *ForwardingManager {
+	t.Helper()
+	fm := NewManagerWithDialer(dialer)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		fm.Shutdown(ctx)
+	})
+	return fm
+}
+
+// echoServer listens on 127.0.0.1 and writes back what its clients send
+func echoServer(t *testing.T) (string, int) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+// expectEcho sends message over conn and fails unless it comes back
+func expectEcho(t *testing.T, conn net.Conn, message string) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, message); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reply := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(reply) != message {
+		t.Fatalf("echo = %q, want %q", reply, message)
+	}
+}
+
+// eventually fails the test unless check passes within a few seconds
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// expectTraffic waits for the session to count sent and received bytes
+func expectTraffic(t *testing.T, session *ForwardingSession, sent, received int64) {
+	t.Helper()
+	eventually(t, "the traffic to be counted", func() bool {
+		return atomic.LoadInt64(&session.Stats.BytesSent) == sent &&
+			atomic.LoadInt64(&session.Stats.BytesReceived) == received
+	})
+}
+
+func TestLocalForwarding(t *testing.T) {
+	server := newTestServer(t)
+	echoHost, echoPort := echoServer(t)
+	fm := newTestManager(t, xssh.DefaultDialer)
+
+	rule := ForwardingRule{ID: "local", Type: LocalForward, LocalHost: "127.0.0.1", RemoteHost: echoHost, RemotePort: echoPort}
+	session, err := fm.StartForwarding(context.Background(), rule, server.Host("test"), testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEcho(t, conn, "hello through -L")
+	conn.Close()
+	expectTraffic(t, session, 16, 16)
+	if count := atomic.LoadInt64(&session.Stats.ConnectionCount); count != 1 {
+		t.Errorf("ConnectionCount = %d, want 1", count)
+	}
+
+	address := session.listener.Addr().String()
+	if err := fm.StopForwarding("local"); err != nil {
+		t.Fatalf("StopForwarding: %v", err)
+	}
+	if conn, err := net.Dial("tcp", address); err == nil {
+		conn.Close()
+		t.Error("the local port still accepts connections after StopForwarding")
+	}
+}
+
+func TestRemoteForwarding(t *testing.T) {
+	server := newTestServer(t)
+	echoHost, echoPort := echoServer(t)
+	fm := newTestManager(t, xssh.DefaultDialer)
+
+	rule := ForwardingRule{ID: "remote", Type: RemoteForward, LocalHost: echoHost, LocalPort: echoPort, RemoteHost: "127.0.0.1"}
+	session, err := fm.StartForwarding(context.Background(), rule, server.Host("test"), testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	// sshtest listens for tcpip-forward requests on this machine
+	_, port, err := net.SplitHostPort(session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := net.JoinHostPort("127.0.0.1", port)
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectEcho(t, conn, "hello through -R")
+	conn.Close()
+	expectTraffic(t, session, 16, 16)
+
+	if err := fm.StopForwarding("remote"); err != nil {
+		t.Fatalf("StopForwarding: %v", err)
+	}
+	eventually(t, "the remote listener to close", func() bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	})
+}
+
+func TestDynamicForwarding(t *testing.T) {
+	server := newTestServer(t)
+	echoHost, echoPort := echoServer(t)
+	fm := newTestManager(t, xssh.DefaultDialer)
+
+	rule := ForwardingRule{ID: "socks", Type: DynamicForward, LocalHost: "127.0.0.1"}
+	session, err := fm.StartForwarding(context.Background(), rule, server.Host("test"), testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	conn, err := net.Dial("tcp", session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if code, _ := socksRequest(t, conn, socks5Connect, net.JoinHostPort(echoHost, strconv.Itoa(echoPort))); code != socks5Succeeded {
+		t.Fatalf("CONNECT reply code = %d, want success", code)
+	}
+	expectEcho(t, conn, "hello through -D")
+}
+
+func TestDynamicForwardingUnreachableTarget(t *testing.T) {
+	server := newTestServer(t)
+	fm := newTestManager(t, xssh.DefaultDialer)
+
+	rule := ForwardingRule{ID: "socks", Type: DynamicForward, LocalHost: "127.0.0.1"}
+	session, err := fm.StartForwarding(context.Background(), rule, server.Host("test"), testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := listener.Addr().String()
+	listener.Close()
+
+	conn, err := net.Dial("tcp", session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if code, _ := socksRequest(t, conn, socks5Connect, closed); code != socks5Refused {
+		t.Fatalf("CONNECT reply code = %d, want %d (refused)", code, socks5Refused)
+	}
+	eventually(t, "the error to be counted", func() bool {
+		return atomic.LoadInt64(&session.Stats.ErrorCount) == 1
+	})
+}
+
+func TestStartForwardingWrongPassword(t *testing.T) {
+	server := newTestServer(t)
+	fm := newTestManager(t, xssh.DefaultDialer)
+
+	rule := ForwardingRule{ID: "local", Type: LocalForward, LocalHost: "127.0.0.1", RemoteHost: "127.0.0.1", RemotePort: 1}
+	if _, err := fm.StartForwarding(context.Background(), rule, server.Host("test"), xssh.Auth{Password: "wrong"}); err == nil {
+		t.Fatal("StartForwarding succeeded with a wrong password")
+	}
+	if _, exists := fm.GetSession("local"); exists {
+		t.Error("the failed session is still listed")
+	}
+}
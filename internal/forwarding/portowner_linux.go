@@ -0,0 +1,80 @@
+package forwarding
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the state of a listening socket in /proc/net/tcp
+const tcpListen = "0A"
+
+// portProcess returns the process listening on a local TCP port, on one of
+// ips or on all addresses when ips is nil, and its name, found through
+// /proc: the socket in /proc/net/tcp and tcp6, then the process holding it
+// among those readable by this user. It returns 0 when there is none.
+func portProcess(ips []net.IP, port int) (int, string) {
+	inodes := map[string]bool{}
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		file, err := os.Open(table)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Scan() // Header
+		for scanner.Scan() {
+			// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 || fields[3] != tcpListen {
+				continue
+			}
+			hexAddress, hexPort, _ := strings.Cut(fields[1], ":")
+			p, err := strconv.ParseInt(hexPort, 16, 32)
+			if err != nil || int(p) != port {
+				continue
+			}
+			if address := procAddress(hexAddress); address != nil && !address.IsUnspecified() && !addressesOverlap([]net.IP{address}, ips) {
+				continue
+			}
+			inodes["socket:["+fields[9]+"]"] = true
+		}
+		file.Close()
+	}
+	if len(inodes) == 0 {
+		return 0, ""
+	}
+
+	fds, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range fds {
+		if target, err := os.Readlink(fd); err != nil || !inodes[target] {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.Split(fd, "/")[2])
+		if err != nil {
+			continue
+		}
+		command, _ := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+		return pid, strings.TrimSpace(string(command))
+	}
+	return 0, ""
+}
+
+// procAddress decodes an address of /proc/net/tcp or tcp6, written as
+// 32-bit words in host byte order. It returns nil when the address cannot be
+// read.
+func procAddress(hexAddress string) net.IP {
+	raw, err := hex.DecodeString(hexAddress)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil
+	}
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		binary.BigEndian.PutUint32(ip[word:], binary.NativeEndian.Uint32(raw[word:]))
+	}
+	return ip
+}
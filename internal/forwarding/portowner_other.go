@@ -0,0 +1,45 @@
+//go:build !linux
+
+package forwarding
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os/exec"
+	"strconv"
+)
+
+// portProcess returns the process listening on a local TCP port, on one of
+// ips or on all addresses when ips is nil, and its name, asking lsof. It
+// returns 0 when there is none or lsof is missing.
+func portProcess(ips []net.IP, port int) (int, string) {
+	output, err := exec.Command("lsof", "-nP", "-iTCP:"+strconv.Itoa(port), "-sTCP:LISTEN", "-Fpcn").Output()
+	if err != nil {
+		return 0, ""
+	}
+	// One field per line, tagged by its first letter: p<pid> and c<command>
+	// for each process, then n<address>:<port> for each of its sockets
+	var pid int
+	var command string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			pid, _ = strconv.Atoi(line[1:])
+			command = ""
+		case 'c':
+			command = line[1:]
+		case 'n':
+			host, _, err := net.SplitHostPort(line[1:])
+			if err != nil || addressesOverlap(localIPs(host), ips) {
+				return pid, command
+			}
+		}
+	}
+	return 0, ""
+}
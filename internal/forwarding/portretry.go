@@ -0,0 +1,85 @@
+package forwarding
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"syscall"
+)
+
+// DefaultPortRetries is how many following ports a new rule tries when its
+// port is in use, set from [forwarding] port_retries
+var DefaultPortRetries = 0
+
+// maxPort is the highest port a forwarding can listen on
+const maxPort = 65535
+
+// ListenPort returns the port the rule listens on: the remote one for -R
+// forwardings, the local one otherwise
+func (r ForwardingRule) ListenPort() int {
+	if r.Type.ListensRemotely() {
+		return r.RemotePort
+	}
+	return r.LocalPort
+}
+
+// PortSubstitution describes the port the rule took because the one it asked
+// for was in use, empty when it listens on the port it asked for
+func (r ForwardingRule) PortSubstitution() string {
+	if r.RequestedPort == 0 || r.RequestedPort == r.ListenPort() {
+		return ""
+	}
+	return fmt.Sprintf("port %d was in use, listening on %d instead", r.RequestedPort, r.ListenPort())
+}
+
+// listenPort listens on the port of the session's rule or, when it is in
+// use, on the first of the following PortRetries ports that is free, which
+// then replaces the port in the rule. inUse tells the errors of a port in
+// use from the others, which are returned at once.
+func (fm *ForwardingManager) listenPort(session *ForwardingSession, bind string, listen func(addr string) (net.Listener, error), inUse func(error) bool) (net.Listener, error) {
+	rule := &session.Rule
+	requested := rule.ListenPort()
+	retries := rule.PortRetries
+	if requested == 0 {
+		retries = 0 // The system picks a free port
+	}
+
+	var firstErr error
+	for port := requested; port <= min(requested+retries, maxPort); port++ {
+		listener, err := listen(fmt.Sprintf("%s:%d", bind, port))
+		if err == nil {
+			if port != requested {
+				if rule.RequestedPort == 0 {
+					rule.RequestedPort = requested
+				}
+				if rule.Type.ListensRemotely() {
+					rule.RemotePort = port
+				} else {
+					rule.LocalPort = port
+				}
+				slog.Warn("forwarding port in use, took the next free one", "id", rule.ID, "requested", requested, "port", port)
+			}
+			return listener, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if !inUse(err) {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// localPortInUse reports whether a local listen failed on a port in use
+func localPortInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// remotePortInUse reports whether a remote listen may have failed on a port
+// in use. SSH servers refuse any port they cannot listen on without telling
+// why, so every refusal is taken for one.
+func remotePortInUse(error) bool {
+	return true
+}
@@ -0,0 +1,137 @@
+package forwarding
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// PortOwner is what holds a local port a forwarding wants to listen on
+type PortOwner struct {
+	SessionID string // xssh forwarding session listening on the port, empty for another program
+	PID       int    // Process listening on the port, 0 when unknown
+	Command   string // Name of that process, when known
+}
+
+// String describes the owner for error messages
+func (o PortOwner) String() string {
+	switch {
+	case o.SessionID != "" && o.PID != 0:
+		return fmt.Sprintf("xssh session %s (pid %d)", o.SessionID, o.PID)
+	case o.SessionID != "":
+		return "xssh session " + o.SessionID
+	case o.Command != "" && o.PID != 0:
+		return fmt.Sprintf("%s (pid %d)", o.Command, o.PID)
+	case o.PID != 0:
+		return fmt.Sprintf("pid %d", o.PID)
+	}
+	return "another program"
+}
+
+// PortCollisionError is returned when the local port of a rule is taken
+// before it starts. It matches syscall.EADDRINUSE with errors.Is.
+type PortCollisionError struct {
+	Address string // Address the rule listens on
+	Port    int
+	Owner   PortOwner
+}
+
+func (e *PortCollisionError) Error() string {
+	return fmt.Sprintf("port %d on %s is in use by %s", e.Port, e.Address, e.Owner)
+}
+
+func (e *PortCollisionError) Unwrap() error {
+	return syscall.EADDRINUSE
+}
+
+// checkLocalPort makes sure, before a rule connects, that the local port it
+// listens on is free, so that a collision fails at once and names what holds
+// the port. Rules that may take a following port, or let the system pick
+// one, are left to listenPort.
+func (fm *ForwardingManager) checkLocalPort(rule ForwardingRule) error {
+	if rule.Type.ListensRemotely() || rule.LocalPort == 0 || rule.PortRetries > 0 {
+		return nil
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", rule.LocalHost, rule.LocalPort))
+	if err == nil {
+		listener.Close()
+		return nil
+	}
+	if !localPortInUse(err) {
+		// Left for listenPort to report
+		return nil
+	}
+	return &PortCollisionError{Address: rule.LocalHost, Port: rule.LocalPort, Owner: fm.localPortOwner(rule)}
+}
+
+// localPortOwner finds what listens on the local address and port of rule: a
+// session of this manager, a background session, or failing those the
+// process the system reports
+func (fm *ForwardingManager) localPortOwner(rule ForwardingRule) PortOwner {
+	ips := localIPs(rule.LocalHost)
+	holds := func(other ForwardingRule) bool {
+		return !other.Type.ListensRemotely() && other.LocalPort == rule.LocalPort &&
+			addressesOverlap(localIPs(other.LocalHost), ips)
+	}
+
+	fm.mu.Lock()
+	for id, session := range fm.sessions {
+		if id != rule.ID && holds(session.Rule) {
+			fm.mu.Unlock()
+			return PortOwner{SessionID: id, PID: os.Getpid()}
+		}
+	}
+	fm.mu.Unlock()
+
+	if backgroundSessions, err := BackgroundSessions(); err == nil {
+		for _, session := range backgroundSessions {
+			// A background worker may already have recorded the session
+			if session.PID != os.Getpid() && holds(session.Rule) {
+				return PortOwner{SessionID: session.Rule.ID, PID: session.PID}
+			}
+		}
+	}
+
+	pid, command := portProcess(ips, rule.LocalPort)
+	return PortOwner{PID: pid, Command: command}
+}
+
+// localIPs returns the addresses a local bind address stands for: nil for
+// the wildcard address, which listens on all of them, and for a name that
+// cannot be resolved, which may be any of them
+func localIPs(host string) []net.IP {
+	host = strings.Trim(host, "[]")
+	if host == "" || host == "*" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsUnspecified() {
+			return nil
+		}
+		return []net.IP{ip}
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil
+	}
+	return ips
+}
+
+// addressesOverlap reports whether listeners on the addresses of localIPs
+// contend for the same port: one of them is the wildcard, or they share an
+// address
+func addressesOverlap(a, b []net.IP) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x.Equal(y) {
+				return true
+			}
+		}
+	}
+	return false
+}
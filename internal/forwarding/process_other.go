@@ -0,0 +1,36 @@
+//go:build !windows
+
+package forwarding
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// terminateProcess asks a process to exit; one that is gone already is not
+// an error
+func terminateProcess(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to stop process %d: %v", pid, err)
+	}
+	return nil
+}
+
+// renewProcess asks the process of a background session to push back its
+// expiry, with SIGUSR1
+func renewProcess(pid int) error {
+	if err := syscall.Kill(pid, syscall.SIGUSR1); err != nil {
+		return fmt.Errorf("failed to renew process %d: %v", pid, err)
+	}
+	return nil
+}
+
+// processAlive reports whether a process exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,38 @@
+package forwarding
+
+import (
+	"fmt"
+	"os"
+)
+
+// terminateProcess ends a process; Windows has no signal to ask it to exit
+func terminateProcess(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return nil
+	}
+	defer process.Release()
+	if err := process.Kill(); err != nil && err != os.ErrProcessDone {
+		return fmt.Errorf("failed to stop process %d: %v", pid, err)
+	}
+	return nil
+}
+
+// renewProcess fails, as Windows has no signal to reach the process of a
+// background session with
+func renewProcess(pid int) error {
+	return fmt.Errorf("renewing background process %d is not supported on Windows", pid)
+}
+
+// processAlive reports whether a process exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,59 @@
+package forwarding
+
+import (
+	"sync"
+	"time"
+)
+
+// rateBuckets is the number of seconds the current transfer rate looks back
+const rateBuckets = 10
+
+// rateMeter counts the bytes of the last rateBuckets seconds, one bucket per
+// second, for the current transfer rate of a session
+type rateMeter struct {
+	mu      sync.Mutex
+	buckets [rateBuckets]rateBucket
+}
+
+// rateBucket holds the bytes of one second
+type rateBucket struct {
+	second   int64 // Unix time of the second counted
+	received int64
+	sent     int64
+}
+
+// add counts bytes transferred at now
+func (r *rateMeter) add(now time.Time, received, sent int64) {
+	second := now.Unix()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bucket := &r.buckets[second%rateBuckets]
+	if bucket.second != second {
+		*bucket = rateBucket{second: second}
+	}
+	bucket.received += received
+	bucket.sent += sent
+}
+
+// rates returns the bytes per second received and sent over the seconds
+// before now, counting from since when that is more recent
+func (r *rateMeter) rates(now, since time.Time) (float64, float64) {
+	second := now.Unix()
+	var received, sent int64
+	r.mu.Lock()
+	for _, bucket := range r.buckets {
+		if bucket.second > second-rateBuckets && bucket.second <= second {
+			received += bucket.received
+			sent += bucket.sent
+		}
+	}
+	r.mu.Unlock()
+
+	// The window is the full seconds before this one and the part of this
+	// one that has passed
+	window := (rateBuckets-1)*time.Second + now.Sub(time.Unix(second, 0))
+	if age := now.Sub(since); age < window {
+		window = max(age, time.Second)
+	}
+	return float64(received) / window.Seconds(), float64(sent) / window.Seconds()
+}
@@ -0,0 +1,64 @@
+package forwarding
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolveTimeout is how long resolving the target of a local forwarding on
+// this machine may take
+const resolveTimeout = 5 * time.Second
+
+// ParseResolve parses where a local forwarding resolves its target: empty or
+// "remote" for the SSH server, as ssh -L does, or "local" for this machine
+func ParseResolve(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "remote":
+		return false, nil
+	case "local":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid resolve option %q, expected remote or local", value)
+	}
+}
+
+// ResolveString returns where the rule resolves its target, as ParseResolve
+// reads it
+func (r ForwardingRule) ResolveString() string {
+	if r.ResolveLocally {
+		return "local"
+	}
+	return "remote"
+}
+
+// dialTarget returns the address a connection of a local forwarding asks the
+// SSH server to connect to, and how the name in it was resolved, for the
+// error log. An address or unix socket needs no resolving.
+func (fm *ForwardingManager) dialTarget(rule ForwardingRule) (string, string, error) {
+	if rule.RemoteSocket != "" || net.ParseIP(rule.RemoteHost) != nil {
+		return rule.RemoteTarget(), "", nil
+	}
+	if !rule.ResolveLocally {
+		return rule.RemoteTarget(), "resolved by the SSH server", nil
+	}
+
+	ctx, cancel := context.WithTimeout(fm.ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, rule.RemoteHost)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve %s locally: %w", rule.RemoteHost, err)
+	}
+	// Prefer IPv4, which more servers forward to
+	ip := addrs[0].IP
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			ip = addr.IP
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(rule.RemotePort)), fmt.Sprintf("resolved locally to %s", ip), nil
+}
@@ -1,62 +1,95 @@
 package forwarding
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"time"
 
-	"golang.org/x/crypto/ssh"
 	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
 )
 
 // startLocalForwarding implements local port forwarding (-L)
 // Listens on local port and forwards connections to remote host:port through SSH
-func (fm *ForwardingManager) startLocalForwarding(session *ForwardingSession, host config.SSHHost, keyPassword string) error {
+func (fm *ForwardingManager) startLocalForwarding(ctx context.Context, session *ForwardingSession, host config.SSHHost, auth xssh.Auth) error {
 	rule := session.Rule
-	
+
+	// Load the certificate first, a bad one need not connect
+	var tlsConfig *tls.Config
+	if rule.TLS != nil {
+		var err error
+		tlsConfig, err = rule.TLS.config()
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+	}
+
 	// Get SSH client
-	sshClient, err := fm.getSSHClient(host, keyPassword)
+	sshClient, err := fm.acquireClient(ctx, session.Rule.Via, host, auth)
 	if err != nil {
-		return fmt.Errorf("failed to get SSH client: %v", err)
+		return fmt.Errorf("failed to get SSH client: %w", err)
 	}
+	fm.bindClient(session, sshClient)
 
 	// Listen on local port
 	localAddr := fmt.Sprintf("%s:%d", rule.LocalHost, rule.LocalPort)
-	listener, err := net.Listen("tcp", localAddr)
+	listener, err := fm.listenPort(session, rule.LocalHost, func(addr string) (net.Listener, error) {
+		return net.Listen("tcp", addr)
+	}, localPortInUse)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %v", localAddr, err)
+		fm.releaseClient(sshClient)
+		return fmt.Errorf("failed to listen on %s: %w", localAddr, err)
 	}
 
 	session.listener = listener
+	done := session.done
 
 	// Start accepting connections in a goroutine
+	fm.running.Add(1)
 	go func() {
+		defer fm.running.Done()
+		defer fm.releaseClient(sshClient)
 		defer listener.Close()
-		
+
 		for {
 			select {
-			case <-session.done:
+			case <-done:
 				return
 			default:
 				// Accept connection with timeout
 				if tcpListener, ok := listener.(*net.TCPListener); ok {
 					tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
 				}
-				
+
 				localConn, err := listener.Accept()
 				if err != nil {
 					if ne, ok := err.(net.Error); ok && ne.Timeout() {
 						continue // Timeout is expected for graceful shutdown
 					}
+					select {
+					case <-done:
+						return // Halted while accepting
+					default:
+					}
 					if session.IsActive() {
 						session.IncrementErrors(fmt.Sprintf("Accept error: %v", err))
 					}
 					continue
 				}
 
+				if tlsConfig != nil {
+					localConn = tls.Server(localConn, tlsConfig)
+				}
+
 				// Handle connection in separate goroutine
-				go fm.handleLocalForwardConnection(session, sshClient, localConn, rule.RemoteHost, rule.RemotePort)
+				fm.running.Add(1)
+				fm.retainConn(sshClient)
+				go fm.handleLocalForwardConnection(session, sshClient, localConn, rule)
 			}
 		}
 	}()
@@ -64,17 +97,44 @@ func (fm *ForwardingManager) startLocalForwarding(session *ForwardingSession, ho
 	return nil
 }
 
-// handleLocalForwardConnection handles a single local forward connection
-func (fm *ForwardingManager) handleLocalForwardConnection(session *ForwardingSession, sshClient *ssh.Client, localConn net.Conn, remoteHost string, remotePort int) {
+// handleLocalForwardConnection handles a single local forward connection,
+// connecting to the remote unix socket of the rule when it has one
+func (fm *ForwardingManager) handleLocalForwardConnection(session *ForwardingSession, sshClient *sharedClient, localConn net.Conn, rule ForwardingRule) {
+	defer fm.running.Done()
+	defer fm.releaseConn(sshClient)
 	defer localConn.Close()
-	
+	defer fm.cutOnAbort(localConn)()
+
 	session.IncrementConnections()
 	defer session.DecrementActiveConnections()
 
+	// Decrypt before connecting, so clients failing the handshake (as
+	// browsers rejecting the certificate do) cost no SSH channel
+	if tlsConn, ok := localConn.(*tls.Conn); ok {
+		ctx, cancel := context.WithTimeout(fm.ctx, tlsHandshakeTimeout)
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			session.IncrementErrors(fmt.Sprintf("TLS handshake failed: %v", err))
+			return
+		}
+	}
+
 	// Connect to remote host through SSH
-	remoteAddr := fmt.Sprintf("%s:%d", remoteHost, remotePort)
-	remoteConn, err := sshClient.Dial("tcp", remoteAddr)
+	network := "tcp"
+	if rule.RemoteSocket != "" {
+		network = "unix" // direct-streamlocal channel
+	}
+	remoteAddr, resolution, err := fm.dialTarget(rule)
+	if err != nil {
+		session.IncrementErrors(fmt.Sprintf("Failed to connect to %s: %v", rule.RemoteTarget(), err))
+		return
+	}
+	remoteConn, err := sshClient.DialContext(fm.ctx, network, remoteAddr)
 	if err != nil {
+		if resolution != "" {
+			remoteAddr = fmt.Sprintf("%s (%s)", rule.RemoteTarget(), resolution)
+		}
 		session.IncrementErrors(fmt.Sprintf("Failed to connect to %s: %v", remoteAddr, err))
 		return
 	}
@@ -86,34 +146,53 @@ func (fm *ForwardingManager) handleLocalForwardConnection(session *ForwardingSes
 
 // startRemoteForwarding implements remote port forwarding (-R)
 // Listens on remote port and forwards connections to local host:port
-func (fm *ForwardingManager) startRemoteForwarding(session *ForwardingSession, host config.SSHHost, keyPassword string) error {
+func (fm *ForwardingManager) startRemoteForwarding(ctx context.Context, session *ForwardingSession, host config.SSHHost, auth xssh.Auth) error {
 	rule := session.Rule
-	
+
 	// Get SSH client
-	sshClient, err := fm.getSSHClient(host, keyPassword)
+	sshClient, err := fm.acquireClient(ctx, session.Rule.Via, host, auth)
 	if err != nil {
-		return fmt.Errorf("failed to get SSH client: %v", err)
+		return fmt.Errorf("failed to get SSH client: %w", err)
 	}
+	fm.bindClient(session, sshClient)
 
 	// Listen on remote port through SSH
 	remoteAddr := fmt.Sprintf("%s:%d", rule.RemoteHost, rule.RemotePort)
-	listener, err := sshClient.Listen("tcp", remoteAddr)
+	listener, err := fm.listenPort(session, rule.RemoteHost, func(addr string) (net.Listener, error) {
+		return sshClient.Listen("tcp", addr)
+	}, remotePortInUse)
 	if err != nil {
-		return fmt.Errorf("failed to listen on remote %s: %v", remoteAddr, err)
+		fm.releaseClient(sshClient)
+		return fmt.Errorf("failed to listen on remote %s: %w", remoteAddr, err)
 	}
 
 	session.listener = listener
+	done := session.done
 
 	// Start accepting connections in a goroutine
+	fm.running.Add(1)
 	go func() {
+		defer fm.running.Done()
+		defer fm.releaseClient(sshClient)
 		defer listener.Close()
-		
+
 		for {
 			select {
-			case <-session.done:
+			case <-done:
 				return
 			default:
 				remoteConn, err := listener.Accept()
+				if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+					select {
+					case <-done:
+						// Stopped or restarted
+					default:
+						// The listener went away with the SSH connection
+						session.IncrementErrors("Remote listener closed")
+						fm.checkClientsSoon()
+					}
+					return
+				}
 				if err != nil {
 					if session.IsActive() {
 						session.IncrementErrors(fmt.Sprintf("Remote accept error: %v", err))
@@ -122,7 +201,9 @@ func (fm *ForwardingManager) startRemoteForwarding(session *ForwardingSession, h
 				}
 
 				// Handle connection in separate goroutine
-				go fm.handleRemoteForwardConnection(session, remoteConn, rule.LocalHost, rule.LocalPort)
+				fm.running.Add(1)
+				fm.retainConn(sshClient)
+				go fm.handleRemoteForwardConnection(session, sshClient, remoteConn, rule.LocalHost, rule.LocalPort)
 			}
 		}
 	}()
@@ -131,15 +212,19 @@ func (fm *ForwardingManager) startRemoteForwarding(session *ForwardingSession, h
 }
 
 // handleRemoteForwardConnection handles a single remote forward connection
-func (fm *ForwardingManager) handleRemoteForwardConnection(session *ForwardingSession, remoteConn net.Conn, localHost string, localPort int) {
+func (fm *ForwardingManager) handleRemoteForwardConnection(session *ForwardingSession, sshClient *sharedClient, remoteConn net.Conn, localHost string, localPort int) {
+	defer fm.running.Done()
+	defer fm.releaseConn(sshClient)
 	defer remoteConn.Close()
-	
+	defer fm.cutOnAbort(remoteConn)()
+
 	session.IncrementConnections()
 	defer session.DecrementActiveConnections()
 
 	// Connect to local host
-	localAddr := fmt.Sprintf("%s:%d", localHost, localPort)
-	localConn, err := net.Dial("tcp", localAddr)
+	localAddr := net.JoinHostPort(localHost, strconv.Itoa(localPort))
+	var dialer net.Dialer
+	localConn, err := dialer.DialContext(fm.ctx, "tcp", localAddr)
 	if err != nil {
 		session.IncrementErrors(fmt.Sprintf("Failed to connect to local %s: %v", localAddr, err))
 		return
@@ -152,43 +237,56 @@ func (fm *ForwardingManager) handleRemoteForwardConnection(session *ForwardingSe
 
 // startDynamicForwarding implements dynamic port forwarding (-D)
 // Creates a SOCKS5 proxy on the local port
-func (fm *ForwardingManager) startDynamicForwarding(session *ForwardingSession, host config.SSHHost, keyPassword string) error {
+func (fm *ForwardingManager) startDynamicForwarding(ctx context.Context, session *ForwardingSession, host config.SSHHost, auth xssh.Auth) error {
 	rule := session.Rule
-	
+
 	// Get SSH client
-	sshClient, err := fm.getSSHClient(host, keyPassword)
+	sshClient, err := fm.acquireClient(ctx, session.Rule.Via, host, auth)
 	if err != nil {
-		return fmt.Errorf("failed to get SSH client: %v", err)
+		return fmt.Errorf("failed to get SSH client: %w", err)
 	}
+	fm.bindClient(session, sshClient)
 
 	// Listen on local port for SOCKS5 connections
 	localAddr := fmt.Sprintf("%s:%d", rule.LocalHost, rule.LocalPort)
-	listener, err := net.Listen("tcp", localAddr)
+	listener, err := fm.listenPort(session, rule.LocalHost, func(addr string) (net.Listener, error) {
+		return net.Listen("tcp", addr)
+	}, localPortInUse)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %v", localAddr, err)
+		fm.releaseClient(sshClient)
+		return fmt.Errorf("failed to listen on %s: %w", localAddr, err)
 	}
 
 	session.listener = listener
+	done := session.done
 
 	// Start accepting connections in a goroutine
+	fm.running.Add(1)
 	go func() {
+		defer fm.running.Done()
+		defer fm.releaseClient(sshClient)
 		defer listener.Close()
-		
+
 		for {
 			select {
-			case <-session.done:
+			case <-done:
 				return
 			default:
 				// Accept connection with timeout
 				if tcpListener, ok := listener.(*net.TCPListener); ok {
 					tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
 				}
-				
+
 				localConn, err := listener.Accept()
 				if err != nil {
 					if ne, ok := err.(net.Error); ok && ne.Timeout() {
 						continue // Timeout is expected for graceful shutdown
 					}
+					select {
+					case <-done:
+						return // Halted while accepting
+					default:
+					}
 					if session.IsActive() {
 						session.IncrementErrors(fmt.Sprintf("SOCKS accept error: %v", err))
 					}
@@ -196,6 +294,8 @@ func (fm *ForwardingManager) startDynamicForwarding(session *ForwardingSession,
 				}
 
 				// Handle SOCKS5 connection in separate goroutine
+				fm.running.Add(1)
+				fm.retainConn(sshClient)
 				go fm.handleSOCKS5Connection(session, sshClient, localConn)
 			}
 		}
@@ -205,21 +305,28 @@ func (fm *ForwardingManager) startDynamicForwarding(session *ForwardingSession,
 }
 
 // handleSOCKS5Connection handles a SOCKS5 proxy connection
-func (fm *ForwardingManager) handleSOCKS5Connection(session *ForwardingSession, sshClient *ssh.Client, localConn net.Conn) {
+func (fm *ForwardingManager) handleSOCKS5Connection(session *ForwardingSession, sshClient *sharedClient, localConn net.Conn) {
+	defer fm.running.Done()
+	defer fm.releaseConn(sshClient)
 	defer localConn.Close()
-	
+	defer fm.cutOnAbort(localConn)()
+
 	session.IncrementConnections()
 	defer session.DecrementActiveConnections()
 
 	// Perform SOCKS5 handshake
-	targetAddr, err := fm.socks5Handshake(localConn)
+	command, targetAddr, err := fm.socks5Handshake(localConn)
 	if err != nil {
 		session.IncrementErrors(fmt.Sprintf("SOCKS5 handshake failed: %v", err))
 		return
 	}
+	if command == socks5Bind {
+		fm.socks5Bind(session, sshClient, localConn, targetAddr)
+		return
+	}
 
 	// Connect to target through SSH
-	remoteConn, err := sshClient.Dial("tcp", targetAddr)
+	remoteConn, err := sshClient.DialContext(fm.ctx, "tcp", targetAddr)
 	if err != nil {
 		session.IncrementErrors(fmt.Sprintf("Failed to connect to %s: %v", targetAddr, err))
 		// Send SOCKS5 error response
@@ -235,18 +342,129 @@ func (fm *ForwardingManager) handleSOCKS5Connection(session *ForwardingSession,
 	fm.forwardData(session, localConn, remoteConn)
 }
 
-// socks5Handshake performs SOCKS5 handshake and returns target address
-func (fm *ForwardingManager) socks5Handshake(conn net.Conn) (string, error) {
+// startRemoteDynamicForwarding implements remote dynamic forwarding (-R port)
+// Creates a SOCKS5 proxy on the remote port, whose connections are made from
+// this machine
+func (fm *ForwardingManager) startRemoteDynamicForwarding(ctx context.Context, session *ForwardingSession, host config.SSHHost, auth xssh.Auth) error {
+	rule := session.Rule
+
+	// Get SSH client
+	sshClient, err := fm.acquireClient(ctx, session.Rule.Via, host, auth)
+	if err != nil {
+		return fmt.Errorf("failed to get SSH client: %w", err)
+	}
+	fm.bindClient(session, sshClient)
+
+	// Listen on remote port for SOCKS5 connections through SSH
+	remoteAddr := fmt.Sprintf("%s:%d", rule.RemoteHost, rule.RemotePort)
+	listener, err := fm.listenPort(session, rule.RemoteHost, func(addr string) (net.Listener, error) {
+		return sshClient.Listen("tcp", addr)
+	}, remotePortInUse)
+	if err != nil {
+		fm.releaseClient(sshClient)
+		return fmt.Errorf("failed to listen on remote %s: %w", remoteAddr, err)
+	}
+
+	session.listener = listener
+	done := session.done
+
+	// Start accepting connections in a goroutine
+	fm.running.Add(1)
+	go func() {
+		defer fm.running.Done()
+		defer fm.releaseClient(sshClient)
+		defer listener.Close()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				remoteConn, err := listener.Accept()
+				if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+					select {
+					case <-done:
+						// Stopped or restarted
+					default:
+						// The listener went away with the SSH connection
+						session.IncrementErrors("Remote listener closed")
+						fm.checkClientsSoon()
+					}
+					return
+				}
+				if err != nil {
+					if session.IsActive() {
+						session.IncrementErrors(fmt.Sprintf("Remote SOCKS accept error: %v", err))
+					}
+					continue
+				}
+
+				// Handle SOCKS5 connection in separate goroutine
+				fm.running.Add(1)
+				fm.retainConn(sshClient)
+				go fm.handleRemoteSOCKS5Connection(session, sshClient, remoteConn)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// handleRemoteSOCKS5Connection handles a SOCKS5 proxy connection coming in
+// on the remote host, connecting to its target from this machine
+func (fm *ForwardingManager) handleRemoteSOCKS5Connection(session *ForwardingSession, sshClient *sharedClient, remoteConn net.Conn) {
+	defer fm.running.Done()
+	defer fm.releaseConn(sshClient)
+	defer remoteConn.Close()
+	defer fm.cutOnAbort(remoteConn)()
+
+	session.IncrementConnections()
+	defer session.DecrementActiveConnections()
+
+	// Perform SOCKS5 handshake
+	command, targetAddr, err := fm.socks5Handshake(remoteConn)
+	if err != nil {
+		session.IncrementErrors(fmt.Sprintf("SOCKS5 handshake failed: %v", err))
+		return
+	}
+	if command != socks5Connect {
+		// BIND would listen here, where the remote side cannot reach
+		session.IncrementErrors("SOCKS5 BIND is not supported by remote SOCKS proxies")
+		remoteConn.Write(socks5Reply(socks5NotSupported, nil))
+		return
+	}
+
+	// Connect to target from this machine
+	var dialer net.Dialer
+	localConn, err := dialer.DialContext(fm.ctx, "tcp", targetAddr)
+	if err != nil {
+		session.IncrementErrors(fmt.Sprintf("Failed to connect to %s: %v", targetAddr, err))
+		remoteConn.Write(socks5Reply(socks5Refused, nil))
+		return
+	}
+	defer localConn.Close()
+
+	if _, err := remoteConn.Write(socks5Reply(socks5Succeeded, localConn.LocalAddr())); err != nil {
+		return
+	}
+
+	// Start data forwarding
+	fm.forwardData(session, localConn, remoteConn)
+}
+
+// socks5Handshake performs SOCKS5 handshake and returns the command, CONNECT
+// or BIND, and its address
+func (fm *ForwardingManager) socks5Handshake(conn net.Conn) (byte, string, error) {
 	// Read initial request
 	buf := make([]byte, 256)
 	n, err := conn.Read(buf)
 	if err != nil {
-		return "", err
+		return 0, "", err
 	}
 
 	// Check SOCKS version
 	if n < 3 || buf[0] != 0x05 {
-		return "", fmt.Errorf("unsupported SOCKS version")
+		return 0, "", fmt.Errorf("unsupported SOCKS version")
 	}
 
 	// Send auth method response (no auth required)
@@ -255,11 +473,16 @@ func (fm *ForwardingManager) socks5Handshake(conn net.Conn) (string, error) {
 	// Read connection request
 	n, err = conn.Read(buf)
 	if err != nil {
-		return "", err
+		return 0, "", err
 	}
 
-	if n < 7 || buf[0] != 0x05 || buf[1] != 0x01 {
-		return "", fmt.Errorf("invalid SOCKS5 request")
+	if n < 7 || buf[0] != 0x05 {
+		return 0, "", fmt.Errorf("invalid SOCKS5 request")
+	}
+	command := buf[1]
+	if command != socks5Connect && command != socks5Bind {
+		conn.Write(socks5Reply(socks5NotSupported, nil))
+		return 0, "", fmt.Errorf("unsupported SOCKS5 command %d", command)
 	}
 
 	// Parse target address
@@ -267,36 +490,39 @@ func (fm *ForwardingManager) socks5Handshake(conn net.Conn) (string, error) {
 	switch buf[3] {
 	case 0x01: // IPv4
 		if n < 10 {
-			return "", fmt.Errorf("invalid IPv4 address")
+			return 0, "", fmt.Errorf("invalid IPv4 address")
 		}
 		targetAddr = fmt.Sprintf("%d.%d.%d.%d:%d", buf[4], buf[5], buf[6], buf[7], int(buf[8])<<8+int(buf[9]))
 	case 0x03: // Domain name
 		if n < 7 {
-			return "", fmt.Errorf("invalid domain name")
+			return 0, "", fmt.Errorf("invalid domain name")
 		}
 		domainLen := int(buf[4])
 		if n < 7+domainLen {
-			return "", fmt.Errorf("incomplete domain name")
+			return 0, "", fmt.Errorf("incomplete domain name")
 		}
 		domain := string(buf[5 : 5+domainLen])
 		port := int(buf[5+domainLen])<<8 + int(buf[6+domainLen])
 		targetAddr = fmt.Sprintf("%s:%d", domain, port)
 	default:
-		return "", fmt.Errorf("unsupported address type")
+		return 0, "", fmt.Errorf("unsupported address type")
 	}
 
-	return targetAddr, nil
+	return command, targetAddr, nil
 }
 
 // forwardData forwards data between two connections with statistics tracking
 func (fm *ForwardingManager) forwardData(session *ForwardingSession, conn1, conn2 net.Conn) {
 	done := make(chan struct{}, 2)
+	capture := session.captureConn(conn1)
+	defer capture.close()
 
 	// Forward conn1 -> conn2
 	go func() {
 		defer func() { done <- struct{}{} }()
-		written, err := fm.copyWithStats(conn2, conn1, func(bytes int64) {
-			session.AddBytesSent(bytes)
+		written, err := fm.copyWithStats(conn2, conn1, func(data []byte) {
+			session.AddBytesSent(int64(len(data)))
+			capture.record(captureSent, data)
 		})
 		if err != nil && session.IsActive() {
 			session.IncrementErrors(fmt.Sprintf("Forward error (sent %d bytes): %v", written, err))
@@ -306,8 +532,9 @@ func (fm *ForwardingManager) forwardData(session *ForwardingSession, conn1, conn
 	// Forward conn2 -> conn1
 	go func() {
 		defer func() { done <- struct{}{} }()
-		written, err := fm.copyWithStats(conn1, conn2, func(bytes int64) {
-			session.AddBytesReceived(bytes)
+		written, err := fm.copyWithStats(conn1, conn2, func(data []byte) {
+			session.AddBytesReceived(int64(len(data)))
+			capture.record(captureReceived, data)
 		})
 		if err != nil && session.IsActive() {
 			session.IncrementErrors(fmt.Sprintf("Forward error (received %d bytes): %v", written, err))
@@ -319,17 +546,17 @@ func (fm *ForwardingManager) forwardData(session *ForwardingSession, conn1, conn
 }
 
 // copyWithStats copies data between connections while tracking statistics
-func (fm *ForwardingManager) copyWithStats(dst, src net.Conn, statsCallback func(int64)) (int64, error) {
+func (fm *ForwardingManager) copyWithStats(dst, src net.Conn, statsCallback func([]byte)) (int64, error) {
 	buf := make([]byte, 32*1024) // 32KB buffer for better performance
 	var written int64
-	
+
 	for {
 		nr, er := src.Read(buf)
 		if nr > 0 {
 			nw, ew := dst.Write(buf[0:nr])
 			if nw > 0 {
 				written += int64(nw)
-				statsCallback(int64(nw))
+				statsCallback(buf[:nw])
 			}
 			if ew != nil {
 				return written, ew
@@ -346,4 +573,4 @@ func (fm *ForwardingManager) copyWithStats(dst, src net.Conn, statsCallback func
 		}
 	}
 	return written, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,155 @@
+package forwarding
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"xssh/internal/testsupport"
+)
+
+// newBareSession returns an active session that is not started, for
+// driving forwardData directly
+func newBareSession(id string) *ForwardingSession {
+	session := &ForwardingSession{
+		Rule:  ForwardingRule{ID: id, Type: LocalForward},
+		Stats: ForwardingStats{StartTime: time.Now()},
+		done:  make(chan struct{}),
+	}
+	session.SetActive(true)
+	return session
+}
+
+func TestForwardDataCountsBytes(t *testing.T) {
+	useTempHome(t)
+	fm := newTestManager(t, &testsupport.FakeDialer{})
+	session := newBareSession("copy")
+
+	// client <-> local end | forwardData | remote end <-> server
+	client, local := net.Pipe()
+	remote, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		fm.forwardData(session, local, remote)
+	}()
+
+	upload := bytes.Repeat([]byte("x"), 100*1024) // Several reads of the copy buffer
+	go client.Write(upload)
+	got := make([]byte, len(upload))
+	if _, err := io.ReadFull(server, got); err != nil {
+		t.Fatalf("server read: %v", err)
+	}
+	if !bytes.Equal(got, upload) {
+		t.Fatal("the upload was corrupted")
+	}
+
+	go server.Write([]byte("thanks"))
+	reply := make([]byte, len("thanks"))
+	if _, err := io.ReadFull(client, reply); err != nil {
+		t.Fatalf("client read: %v", err)
+	}
+
+	expectTraffic(t, session, int64(len(upload)), int64(len(reply)))
+
+	// Closing one side ends the copy
+	client.Close()
+	local.Close()
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("forwardData did not return after the client closed")
+	}
+	remote.Close()
+}
+
+func TestCopyWithStats(t *testing.T) {
+	fm := &ForwardingManager{}
+	src, srcPeer := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	defer dst.Close()
+	defer dstPeer.Close()
+
+	go func() {
+		srcPeer.Write([]byte("hello, "))
+		srcPeer.Write([]byte("world"))
+		srcPeer.Close()
+	}()
+	var received bytes.Buffer
+	go io.Copy(&received, dstPeer)
+
+	var counted int
+	var chunks []string
+	written, err := fm.copyWithStats(dst, src, func(data []byte) {
+		counted += len(data)
+		chunks = append(chunks, string(data))
+	})
+	if err != nil {
+		t.Fatalf("copyWithStats: %v", err)
+	}
+	if written != 12 || counted != 12 {
+		t.Errorf("written %d, counted %d, want 12", written, counted)
+	}
+	if joined := strings.Join(chunks, ""); joined != "hello, world" {
+		t.Errorf("callback saw %q", joined)
+	}
+}
+
+func TestCopyWithStatsWriteError(t *testing.T) {
+	fm := &ForwardingManager{}
+	src, srcPeer := net.Pipe()
+	dst, dstPeer := net.Pipe()
+	dstPeer.Close() // Writes to dst fail
+
+	go srcPeer.Write([]byte("lost"))
+	written, err := fm.copyWithStats(dst, src, func([]byte) {
+		t.Error("bytes that were not written were counted")
+	})
+	if err == nil {
+		t.Fatal("copyWithStats ignored the write error")
+	}
+	if written != 0 {
+		t.Errorf("written = %d, want 0", written)
+	}
+	src.Close()
+	srcPeer.Close()
+	dst.Close()
+}
+
+func TestForwardDataCountsErrorsWhileActive(t *testing.T) {
+	useTempHome(t)
+	fm := newTestManager(t, &testsupport.FakeDialer{})
+	session := newBareSession("broken")
+
+	client, local := net.Pipe()
+	remote, server := net.Pipe()
+	server.Close() // The remote end is gone
+
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		fm.forwardData(session, local, remote)
+	}()
+	go client.Write([]byte("data"))
+	select {
+	case <-returned:
+	case <-time.After(5 * time.Second):
+		t.Fatal("forwardData did not return after the remote end closed")
+	}
+	client.Close()
+	local.Close()
+	remote.Close()
+
+	eventually(t, "the error to be counted", func() bool {
+		return atomic.LoadInt64(&session.Stats.ErrorCount) >= 1
+	})
+	if atomic.LoadInt64(&session.Stats.BytesSent) != 0 {
+		t.Error("bytes that were not delivered were counted")
+	}
+}
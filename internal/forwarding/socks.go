@@ -0,0 +1,140 @@
+package forwarding
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 commands
+const (
+	socks5Connect byte = 0x01
+	socks5Bind    byte = 0x02
+)
+
+// SOCKS5 reply codes
+const (
+	socks5Succeeded    byte = 0x00
+	socks5Failure      byte = 0x01
+	socks5Refused      byte = 0x05
+	socks5NotSupported byte = 0x07
+)
+
+// bindTimeout is how long a BIND waits for the inbound connection
+const bindTimeout = 2 * time.Minute
+
+// socks5Bind serves a BIND request: it listens on a free port of the remote
+// host, tells the client where, and relays the first connection coming in
+// from expected, the address the client named in its request. Active-mode
+// FTP uses it for its data connections.
+func (fm *ForwardingManager) socks5Bind(session *ForwardingSession, sshClient *sharedClient, localConn net.Conn, expected string) {
+	listener, err := sshClient.Listen("tcp", "0.0.0.0:0")
+	if err != nil {
+		session.IncrementErrors(fmt.Sprintf("SOCKS5 BIND failed to listen on the remote host: %v", err))
+		localConn.Write(socks5Reply(socks5Failure, nil))
+		return
+	}
+	defer listener.Close()
+
+	bound := fm.bindAddress(session, listener.Addr())
+	if _, err := localConn.Write(socks5Reply(socks5Succeeded, bound)); err != nil {
+		return
+	}
+
+	// Give up waiting after bindTimeout or when xssh stops
+	timer := time.AfterFunc(bindTimeout, func() { listener.Close() })
+	defer timer.Stop()
+	defer context.AfterFunc(fm.ctx, func() { listener.Close() })()
+
+	for {
+		remoteConn, err := listener.Accept()
+		if err != nil {
+			if fm.ctx.Err() != nil {
+				return // xssh is stopping
+			}
+			session.IncrementErrors(fmt.Sprintf("SOCKS5 BIND got no connection on %s: %v", bound, err))
+			localConn.Write(socks5Reply(socks5Failure, nil))
+			return
+		}
+		if !bindPeerAllowed(expected, remoteConn.RemoteAddr()) {
+			session.IncrementErrors(fmt.Sprintf("SOCKS5 BIND refused a connection from %s, expecting %s", remoteConn.RemoteAddr(), expected))
+			remoteConn.Close()
+			continue
+		}
+
+		// One connection per BIND
+		listener.Close()
+		defer remoteConn.Close()
+		if _, err := localConn.Write(socks5Reply(socks5Succeeded, remoteConn.RemoteAddr())); err != nil {
+			return
+		}
+		fm.forwardData(session, localConn, remoteConn)
+		return
+	}
+}
+
+// bindAddress returns the address a client reaches a BIND listener at: the
+// listener's own, or the SSH host's when it listens on all interfaces
+func (fm *ForwardingManager) bindAddress(session *ForwardingSession, addr net.Addr) net.Addr {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() {
+		return addr
+	}
+	portNum, _ := strconv.Atoi(port)
+	ips, err := net.DefaultResolver.LookupIP(fm.ctx, "ip", session.host.Host)
+	if err != nil || len(ips) == 0 {
+		// Clients take an unspecified address for the proxy's own
+		return &net.TCPAddr{IP: net.IPv4zero, Port: portNum}
+	}
+	return &net.TCPAddr{IP: ips[0], Port: portNum}
+}
+
+// bindPeerAllowed reports whether a connection from peer fulfills a BIND
+// expecting one from expected. An unspecified address or a host name
+// expects any peer.
+func bindPeerAllowed(expected string, peer net.Addr) bool {
+	expectedHost, _, err := net.SplitHostPort(expected)
+	if err != nil {
+		return true
+	}
+	expectedIP := net.ParseIP(expectedHost)
+	if expectedIP == nil || expectedIP.IsUnspecified() {
+		return true
+	}
+	peerHost, _, err := net.SplitHostPort(peer.String())
+	if err != nil {
+		return false
+	}
+	return expectedIP.Equal(net.ParseIP(peerHost))
+}
+
+// socks5Reply returns a SOCKS5 reply with code rep and address addr, or
+// 0.0.0.0:0 when addr is nil
+func socks5Reply(rep byte, addr net.Addr) []byte {
+	reply := []byte{0x05, rep, 0x00}
+	host, port := "0.0.0.0", 0
+	if addr != nil {
+		if h, p, err := net.SplitHostPort(addr.String()); err == nil {
+			host = h
+			port, _ = strconv.Atoi(p)
+		}
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip.To4() != nil:
+		reply = append(reply, 0x01)
+		reply = append(reply, ip.To4()...)
+	case ip != nil:
+		reply = append(reply, 0x04)
+		reply = append(reply, ip.To16()...)
+	default:
+		reply = append(reply, 0x03, byte(len(host)))
+		reply = append(reply, host...)
+	}
+	return append(reply, byte(port>>8), byte(port))
+}
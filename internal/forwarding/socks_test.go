@@ -0,0 +1,290 @@
+package forwarding
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"xssh/internal/config"
+	"xssh/internal/testsupport"
+)
+
+// fakeHost is the host the sessions of a FakeDialer connect to
+var fakeHost = config.SSHHost{Name: "fake", Host: "127.0.0.1", Port: "22", User: "tester"}
+
+// echoHandler is a FakeConn.Handler writing back what is sent, which
+// reports the address of each connection on addrs when it is not nil
+func echoHandler(addrs chan<- string) func(addr string, conn net.Conn) {
+	return func(addr string, conn net.Conn) {
+		if addrs != nil {
+			addrs <- addr
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}
+}
+
+// startFakeSOCKS starts a SOCKS proxy on 127.0.0.1 whose SSH connections are
+// FakeConns with handler, and returns the session and the dialer
+func startFakeSOCKS(t *testing.T, handler func(addr string, conn net.Conn)) (*ForwardingSession, *testsupport.FakeDialer) {
+	t.Helper()
+	useTempHome(t)
+	dialer := &testsupport.FakeDialer{NewConn: func(config.SSHHost) *testsupport.FakeConn {
+		return &testsupport.FakeConn{Handler: handler}
+	}}
+	fm := newTestManager(t, dialer)
+	rule := ForwardingRule{ID: "socks", Type: DynamicForward, LocalHost: "127.0.0.1"}
+	session, err := fm.StartForwarding(context.Background(), rule, fakeHost, testAuth)
+	if err != nil {
+		t.Fatalf("StartForwarding: %v", err)
+	}
+	return session, dialer
+}
+
+// dialSOCKS connects to the proxy of session
+func dialSOCKS(t *testing.T, session *ForwardingSession) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", session.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// socksRequest greets the SOCKS5 proxy on conn, sends command for addr, an
+// IP or host name with a port, and returns the reply code and the address
+// of the reply
+func socksRequest(t *testing.T, conn net.Conn, command byte, addr string) (byte, string) {
+	t.Helper()
+	if _, err := conn.Write([]byte{0x05, 0x01, 0x00}); err != nil {
+		t.Fatalf("greeting: %v", err)
+	}
+	method := make([]byte, 2)
+	if _, err := io.ReadFull(conn, method); err != nil {
+		t.Fatalf("method: %v", err)
+	}
+	if !bytes.Equal(method, []byte{0x05, 0x00}) {
+		t.Fatalf("method reply = %v, want no authentication", method)
+	}
+
+	host, portText, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portText)
+	request := []byte{0x05, command, 0x00}
+	if ip := net.ParseIP(host).To4(); ip != nil {
+		request = append(append(request, 0x01), ip...)
+	} else {
+		request = append(append(request, 0x03, byte(len(host))), host...)
+	}
+	request = append(request, byte(port>>8), byte(port))
+	if _, err := conn.Write(request); err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	return readSOCKSReply(t, conn)
+}
+
+// readSOCKSReply reads a SOCKS5 reply and returns its code and address
+func readSOCKSReply(t *testing.T, conn net.Conn) (byte, string) {
+	t.Helper()
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	if header[0] != 0x05 {
+		t.Fatalf("reply version = %d", header[0])
+	}
+	var length int
+	switch header[3] {
+	case 0x01:
+		length = net.IPv4len
+	case 0x04:
+		length = net.IPv6len
+	case 0x03:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			t.Fatalf("reply: %v", err)
+		}
+		length = int(size[0])
+	default:
+		t.Fatalf("reply address type = %d", header[3])
+	}
+	rest := make([]byte, length+2)
+	if _, err := io.ReadFull(conn, rest); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	host := string(rest[:length])
+	if header[3] != 0x03 {
+		host = net.IP(rest[:length]).String()
+	}
+	port := int(rest[length])<<8 | int(rest[length+1])
+	return header[1], net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	addrs := make(chan string, 2)
+	session, _ := startFakeSOCKS(t, echoHandler(addrs))
+
+	for _, target := range []string{"10.0.0.7:5432", "db.internal:80"} {
+		conn := dialSOCKS(t, session)
+		code, _ := socksRequest(t, conn, socks5Connect, target)
+		if code != socks5Succeeded {
+			t.Fatalf("CONNECT %s reply code = %d, want success", target, code)
+		}
+		if addr := <-addrs; addr != target {
+			t.Errorf("connected to %s, want %s", addr, target)
+		}
+		expectEcho(t, conn, "ping "+target)
+	}
+}
+
+func TestSOCKS5ConnectRefused(t *testing.T) {
+	// Without a Handler the remote host refuses every connection
+	session, _ := startFakeSOCKS(t, nil)
+
+	conn := dialSOCKS(t, session)
+	if code, _ := socksRequest(t, conn, socks5Connect, "10.0.0.7:5432"); code != socks5Refused {
+		t.Fatalf("reply code = %d, want %d (refused)", code, socks5Refused)
+	}
+	eventually(t, "the error to be counted", func() bool {
+		return atomic.LoadInt64(&session.Stats.ErrorCount) == 1
+	})
+}
+
+func TestSOCKS5UnsupportedCommand(t *testing.T) {
+	session, _ := startFakeSOCKS(t, echoHandler(nil))
+
+	// UDP ASSOCIATE
+	conn := dialSOCKS(t, session)
+	if code, _ := socksRequest(t, conn, 0x03, "0.0.0.0:0"); code != socks5NotSupported {
+		t.Fatalf("reply code = %d, want %d (not supported)", code, socks5NotSupported)
+	}
+}
+
+func TestSOCKS5Bind(t *testing.T) {
+	session, dialer := startFakeSOCKS(t, echoHandler(nil))
+
+	conn := dialSOCKS(t, session)
+	code, bound := socksRequest(t, conn, socks5Bind, "0.0.0.0:0")
+	if code != socks5Succeeded {
+		t.Fatalf("first BIND reply code = %d, want success", code)
+	}
+	// The listener is on all interfaces, so the reply names the SSH host
+	if bound != "127.0.0.1:0" {
+		t.Errorf("bound address = %s, want 127.0.0.1:0", bound)
+	}
+
+	listener := dialer.Conns()[0].Listener("0.0.0.0:0")
+	if listener == nil {
+		t.Fatal("BIND did not listen on the remote host")
+	}
+	peer, err := listener.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer peer.Close()
+	peer.SetDeadline(time.Now().Add(5 * time.Second))
+	if code, _ := readSOCKSReply(t, conn); code != socks5Succeeded {
+		t.Fatalf("second BIND reply code = %d, want success", code)
+	}
+
+	// The inbound connection is relayed to the client
+	if _, err := io.WriteString(peer, "220 ready"); err != nil {
+		t.Fatal(err)
+	}
+	greeting := make([]byte, len("220 ready"))
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		t.Fatal(err)
+	}
+	if string(greeting) != "220 ready" {
+		t.Errorf("relayed %q", greeting)
+	}
+
+	// One connection per BIND
+	if dialer.Conns()[0].Listener("0.0.0.0:0") != nil {
+		t.Error("the BIND listener is still open after its connection")
+	}
+}
+
+func TestSOCKS5BindRefusesUnexpectedPeer(t *testing.T) {
+	session, dialer := startFakeSOCKS(t, echoHandler(nil))
+
+	conn := dialSOCKS(t, session)
+	if code, _ := socksRequest(t, conn, socks5Bind, "10.0.0.9:20"); code != socks5Succeeded {
+		t.Fatalf("first BIND reply code = %d, want success", code)
+	}
+	listener := dialer.Conns()[0].Listener("0.0.0.0:0")
+	if listener == nil {
+		t.Fatal("BIND did not listen on the remote host")
+	}
+
+	// A pipe has no address, so it is not the expected peer
+	peer, err := listener.Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer peer.Close()
+	peer.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := peer.Read(make([]byte, 1)); err == nil {
+		t.Error("the unexpected peer was not disconnected")
+	}
+	eventually(t, "the refusal to be counted", func() bool {
+		return atomic.LoadInt64(&session.Stats.ErrorCount) == 1
+	})
+	if dialer.Conns()[0].Listener("0.0.0.0:0") == nil {
+		t.Error("the BIND listener stopped waiting for the expected peer")
+	}
+}
+
+func TestSOCKS5Reply(t *testing.T) {
+	tests := []struct {
+		name string
+		addr net.Addr
+		want []byte
+	}{
+		{"nil", nil, []byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}},
+		{"IPv4", &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 2121}, []byte{0x05, 0x00, 0x00, 0x01, 192, 0, 2, 1, 0x08, 0x49}},
+		{"IPv6", &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 80},
+			append(append([]byte{0x05, 0x00, 0x00, 0x04}, net.ParseIP("2001:db8::1")...), 0x00, 0x50)},
+		{"host name", stringAddr("ftp.example.com:21"),
+			append(append([]byte{0x05, 0x00, 0x00, 0x03, 15}, "ftp.example.com"...), 0x00, 0x15)},
+	}
+	for _, tt := range tests {
+		if got := socks5Reply(socks5Succeeded, tt.addr); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: socks5Reply = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBindPeerAllowed(t *testing.T) {
+	peer := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 40000}
+	tests := []struct {
+		expected string
+		want     bool
+	}{
+		{"192.0.2.1:20", true},
+		{"192.0.2.2:20", false},
+		{"0.0.0.0:0", true},
+		{"ftp.example.com:20", true},
+		{"garbage", true},
+	}
+	for _, tt := range tests {
+		if got := bindPeerAllowed(tt.expected, peer); got != tt.want {
+			t.Errorf("bindPeerAllowed(%q, %s) = %v, want %v", tt.expected, peer, got, tt.want)
+		}
+	}
+}
+
+// stringAddr is a net.Addr given by its string
+type stringAddr string
+
+func (a stringAddr) Network() string { return "tcp" }
+func (a stringAddr) String() string  { return string(a) }
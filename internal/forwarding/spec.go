@@ -0,0 +1,175 @@
+package forwarding
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultBindAddress is the address local and dynamic forwardings listen on
+// when a rule names none, set from [forwarding] bind_address
+var DefaultBindAddress = "localhost"
+
+// ParseSpec parses a forwarding in the notation of ssh's -L, -R and -D
+// options into a rule without an ID:
+//
+//	LocalForward:         [bind_address:]port:host:hostport
+//	                      [bind_address:]port:remote_socket (a unix socket path on the server)
+//	RemoteForward:        [bind_address:]port:host:hostport (port listens on the server)
+//	DynamicForward:       [bind_address:]port
+//	RemoteDynamicForward: [bind_address:]port (SOCKS proxy on the server)
+//
+// As with ssh -R, a RemoteForward spec without host:hostport is a
+// RemoteDynamicForward.
+func ParseSpec(forwardingType ForwardingType, spec string) (ForwardingRule, error) {
+	parts := strings.Split(spec, ":")
+	if forwardingType == RemoteForward && len(parts) <= 2 {
+		forwardingType = RemoteDynamicForward
+	}
+
+	var bind string
+	switch forwardingType {
+	case DynamicForward:
+		if len(parts) == 2 {
+			bind, parts = parts[0], parts[1:]
+		}
+		if len(parts) != 1 {
+			return ForwardingRule{}, fmt.Errorf("invalid dynamic forwarding %q, expected [bind_address:]port", spec)
+		}
+		port, err := parseSpecPort(parts[0])
+		if err != nil {
+			return ForwardingRule{}, err
+		}
+		return ForwardingRule{
+			Type:        DynamicForward,
+			LocalHost:   specBindAddress(bind),
+			LocalPort:   port,
+			Description: fmt.Sprintf("SOCKS proxy on port %d", port),
+		}, nil
+
+	case RemoteDynamicForward:
+		if len(parts) == 2 {
+			bind, parts = parts[0], parts[1:]
+		}
+		if len(parts) != 1 {
+			return ForwardingRule{}, fmt.Errorf("invalid remote dynamic forwarding %q, expected [bind_address:]port", spec)
+		}
+		port, err := parseSpecPort(parts[0])
+		if err != nil {
+			return ForwardingRule{}, err
+		}
+		// The server listens on RemoteHost:RemotePort and connections are
+		// made from here to the addresses SOCKS clients ask for
+		return ForwardingRule{
+			Type:        RemoteDynamicForward,
+			RemoteHost:  remoteBindAddress(bind),
+			RemotePort:  port,
+			Description: fmt.Sprintf("Remote SOCKS proxy on port %d", port),
+		}, nil
+
+	case LocalForward, RemoteForward:
+		if forwardingType == LocalForward && len(parts) >= 2 && len(parts) <= 3 && strings.HasPrefix(parts[len(parts)-1], "/") {
+			return parseSocketSpec(spec, parts)
+		}
+		if len(parts) == 4 {
+			bind, parts = parts[0], parts[1:]
+		}
+		if len(parts) != 3 || parts[1] == "" {
+			return ForwardingRule{}, fmt.Errorf("invalid forwarding %q, expected [bind_address:]port:host:hostport", spec)
+		}
+		port, err := parseSpecPort(parts[0])
+		if err != nil {
+			return ForwardingRule{}, err
+		}
+		hostPort, err := parseSpecPort(parts[2])
+		if err != nil {
+			return ForwardingRule{}, err
+		}
+
+		if forwardingType == LocalForward {
+			return ForwardingRule{
+				Type:        LocalForward,
+				LocalHost:   specBindAddress(bind),
+				LocalPort:   port,
+				RemoteHost:  parts[1],
+				RemotePort:  hostPort,
+				Description: fmt.Sprintf("Local %d -> %s:%d", port, parts[1], hostPort),
+			}, nil
+		}
+		// The server listens on RemoteHost:RemotePort and connections are
+		// made from here to LocalHost:LocalPort
+		return ForwardingRule{
+			Type:        RemoteForward,
+			LocalHost:   parts[1],
+			LocalPort:   hostPort,
+			RemoteHost:  remoteBindAddress(bind),
+			RemotePort:  port,
+			Description: fmt.Sprintf("Remote %d -> %s:%d", port, parts[1], hostPort),
+		}, nil
+	}
+
+	return ForwardingRule{}, fmt.Errorf("unsupported forwarding type: %v", forwardingType)
+}
+
+// Spec returns the letter of the ssh option and the spec of the rule, as
+// ParseSpec reads them, with the addresses bound spelled out
+func (r ForwardingRule) Spec() (string, string) {
+	switch r.Type {
+	case LocalForward:
+		return "L", fmt.Sprintf("%s:%d:%s", r.LocalHost, r.LocalPort, r.RemoteTarget())
+	case RemoteForward:
+		return "R", fmt.Sprintf("%s:%d:%s:%d", r.RemoteHost, r.RemotePort, r.LocalHost, r.LocalPort)
+	case DynamicForward:
+		return "D", fmt.Sprintf("%s:%d", r.LocalHost, r.LocalPort)
+	case RemoteDynamicForward:
+		return "R", fmt.Sprintf("%s:%d", r.RemoteHost, r.RemotePort)
+	}
+	return "", ""
+}
+
+// parseSocketSpec parses the parts of a local forwarding to a unix socket,
+// [bind_address:]port:remote_socket
+func parseSocketSpec(spec string, parts []string) (ForwardingRule, error) {
+	var bind string
+	if len(parts) == 3 {
+		bind, parts = parts[0], parts[1:]
+	}
+	port, err := parseSpecPort(parts[0])
+	if err != nil {
+		return ForwardingRule{}, fmt.Errorf("invalid forwarding %q: %w", spec, err)
+	}
+	return ForwardingRule{
+		Type:         LocalForward,
+		LocalHost:    specBindAddress(bind),
+		LocalPort:    port,
+		RemoteSocket: parts[1],
+		Description:  fmt.Sprintf("Local %d -> %s", port, parts[1]),
+	}, nil
+}
+
+// parseSpecPort parses a port of a forwarding spec
+func parseSpecPort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", value)
+	}
+	return port, nil
+}
+
+// specBindAddress returns the local address to bind, DefaultBindAddress
+// when none is given
+func specBindAddress(bind string) string {
+	if bind == "" {
+		return DefaultBindAddress
+	}
+	return bind
+}
+
+// remoteBindAddress returns the address for the server to bind, localhost
+// when none is given
+func remoteBindAddress(bind string) string {
+	if bind == "" {
+		return "localhost"
+	}
+	return bind
+}
@@ -0,0 +1,170 @@
+package forwarding
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// Self-signed certificates of TLS listeners
+const (
+	selfSignedValidity = 365 * 24 * time.Hour // Lifetime of a new certificate
+	selfSignedRenewal  = 7 * 24 * time.Hour   // Replace a certificate expiring sooner
+)
+
+// tlsHandshakeTimeout is how long a TLS listener waits for a client to
+// finish its handshake
+const tlsHandshakeTimeout = 10 * time.Second
+
+// selfSignedMu serializes creating the self-signed certificate
+var selfSignedMu sync.Mutex
+
+// ListenerTLS makes a local forwarding listen with TLS and forward the
+// decrypted traffic, so a plain HTTP service can be opened as https://
+type ListenerTLS struct {
+	CertFile string // PEM certificate chain; empty uses a self-signed certificate
+	KeyFile  string // PEM private key of CertFile
+}
+
+// ParseListenerTLS parses the TLS option of a local forwarding: empty for
+// none, "self-signed", or "cert.pem,key.pem"
+func ParseListenerTLS(value string) (*ListenerTLS, error) {
+	switch value = strings.TrimSpace(value); value {
+	case "":
+		return nil, nil
+	case "self-signed":
+		return &ListenerTLS{}, nil
+	}
+	certFile, keyFile, ok := strings.Cut(value, ",")
+	if !ok || certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("invalid TLS option %q, expected self-signed or cert.pem,key.pem", value)
+	}
+	return &ListenerTLS{CertFile: certFile, KeyFile: keyFile}, nil
+}
+
+// String returns the option as ParseListenerTLS reads it, empty for nil
+func (t *ListenerTLS) String() string {
+	switch {
+	case t == nil:
+		return ""
+	case t.CertFile == "":
+		return "self-signed"
+	default:
+		return t.CertFile + "," + t.KeyFile
+	}
+}
+
+// config loads the certificate of the listener, creating the self-signed
+// one when needed
+func (t *ListenerTLS) config() (*tls.Config, error) {
+	var cert tls.Certificate
+	var err error
+	if t.CertFile != "" {
+		cert, err = tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	} else {
+		cert, err = selfSignedCertificate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// selfSignedCertificate returns the self-signed certificate for localhost
+// kept in the config directory, so browsers asked to trust it once keep
+// trusting it. It is replaced a week before it expires.
+func selfSignedCertificate() (tls.Certificate, error) {
+	selfSignedMu.Lock()
+	defer selfSignedMu.Unlock()
+
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certFile := filepath.Join(configDir, "tls", "self-signed.crt")
+	keyFile := filepath.Join(configDir, "tls", "self-signed.key")
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if time.Until(cert.Leaf.NotAfter) > selfSignedRenewal {
+			return cert, nil
+		}
+	}
+
+	certPEM, keyPEM, err := newSelfSigned()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	fingerprint := sha256.Sum256(cert.Certificate[0])
+	slog.Info("created self-signed certificate for TLS forwardings", "file", certFile, "sha256", fmt.Sprintf("%X", fingerprint))
+	return cert, nil
+}
+
+// newSelfSigned creates a certificate and key for localhost, the loopback
+// addresses and the name of this machine, in PEM
+func newSelfSigned() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		names = append(names, hostname)
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "xssh forwarding", Organization: []string{"xssh"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              names,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -1,18 +1,25 @@
 package forwarding
 
 import (
+	"fmt"
+	"log/slog"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
 )
 
 // ForwardingType represents the type of port forwarding
 type ForwardingType int
 
 const (
-	LocalForward ForwardingType = iota  // -L: Local port to remote host:port
-	RemoteForward                       // -R: Remote port to local host:port
-	DynamicForward                      // -D: SOCKS5 proxy
+	LocalForward         ForwardingType = iota // -L: Local port to remote host:port
+	RemoteForward                              // -R: Remote port to local host:port
+	DynamicForward                             // -D: SOCKS5 proxy
+	RemoteDynamicForward                       // -R port: SOCKS5 proxy on the remote host, connecting from here
 )
 
 func (ft ForwardingType) String() string {
@@ -23,41 +30,110 @@ func (ft ForwardingType) String() string {
 		return "Remote"
 	case DynamicForward:
 		return "Dynamic"
+	case RemoteDynamicForward:
+		return "RemoteDynamic"
 	default:
 		return "Unknown"
 	}
 }
 
+// ListensRemotely reports whether forwardings of the type listen on the
+// remote host, on RemotePort, rather than on LocalPort
+func (ft ForwardingType) ListensRemotely() bool {
+	return ft == RemoteForward || ft == RemoteDynamicForward
+}
+
 // ForwardingRule represents a port forwarding configuration
 type ForwardingRule struct {
-	ID          string         // Unique identifier
-	Type        ForwardingType // Type of forwarding
-	LocalHost   string         // Local host (usually "localhost" or "0.0.0.0")
-	LocalPort   int            // Local port
-	RemoteHost  string         // Remote host
-	RemotePort  int            // Remote port
-	Description string         // User description
+	ID             string         // Unique identifier
+	Type           ForwardingType // Type of forwarding
+	LocalHost      string         // Local host (usually "localhost" or "0.0.0.0")
+	LocalPort      int            // Local port
+	RemoteHost     string         // Remote host
+	RemotePort     int            // Remote port
+	RemoteSocket   string         // Unix socket on the remote host a local forwarding connects to instead of RemoteHost:RemotePort
+	ResolveLocally bool           // Resolve RemoteHost of a local forwarding on this machine rather than on the SSH server
+	TLS            *ListenerTLS   // TLS of the listener of a local forwarding, nil for plain TCP
+	Expiry         *Expiry        // When the forwarding stops on its own, nil for never
+	Via            []string       // Hosts tunneled through to reach the host, in order, as [user@]host[:port] like ProxyJump hops
+	Capture        *Capture       // Traffic dump the session starts with, nil for none
+	PortRetries    int            // Following ports tried when the port to listen on is in use, 0 for none
+	RequestedPort  int            // Port asked for when it was in use and a following one was taken, 0 otherwise
+	Labels         []string       // Lower-case labels to find the session by, such as "dev"
+	Description    string         // User description
+}
+
+// RemoteTarget returns where a local forwarding connects on the remote side:
+// its unix socket, or RemoteHost:RemotePort
+func (r ForwardingRule) RemoteTarget() string {
+	if r.RemoteSocket != "" {
+		return r.RemoteSocket
+	}
+	return fmt.Sprintf("%s:%d", r.RemoteHost, r.RemotePort)
+}
+
+// maxErrorLog is the number of recent errors kept per session
+const maxErrorLog = 100
+
+// ErrorLogEntry is one error recorded by a forwarding session
+type ErrorLogEntry struct {
+	Time    time.Time // When the error happened
+	Message string    // Error message
 }
 
 // ForwardingStats holds statistics for a forwarding session
 type ForwardingStats struct {
-	BytesReceived    int64     // Total bytes received
-	BytesSent        int64     // Total bytes sent
-	ConnectionCount  int64     // Number of connections handled
-	ActiveConnections int64    // Current active connections
-	StartTime        time.Time // When the forwarding started
-	LastActivity     time.Time // Last data transfer time
-	ErrorCount       int64     // Number of errors encountered
-	LastError        string    // Last error message
+	BytesReceived     int64     // Total bytes received
+	BytesSent         int64     // Total bytes sent
+	ConnectionCount   int64     // Number of connections handled
+	ActiveConnections int64     // Current active connections
+	StartTime         time.Time // When the forwarding started
+	LastActivity      time.Time // Last data transfer time, read with ForwardingSession.LastActivity
+	ErrorCount        int64     // Number of errors encountered
+	LastError         string    // Last error message, read with ForwardingSession.LastError
+	RestartCount      int64     // Number of times the rule was changed and re-applied
+	ReconnectCount    int64     // Number of times the session was moved to a new SSH connection
+}
+
+// FormatBytes formats a byte count, such as those of ForwardingStats, with a
+// binary unit suffix
+func FormatBytes(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024*1024:
+		return fmt.Sprintf("%.1fGB", float64(bytes)/(1024*1024*1024))
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
 }
 
 // ForwardingSession represents an active port forwarding session
 type ForwardingSession struct {
-	Rule     ForwardingRule // The forwarding rule
-	Stats    ForwardingStats // Statistics
-	listener net.Listener   // The listener for the session
-	done     chan struct{}  // Channel to signal shutdown
-	active   int32          // Atomic flag for active state
+	Rule        ForwardingRule       // The forwarding rule
+	Stats       ForwardingStats      // Statistics
+	listener    net.Listener         // The listener for the session
+	done        chan struct{}        // Channel to signal shutdown
+	active      int32                // Atomic flag for active state
+	host        config.SSHHost       // Host the session tunnels through
+	auth        xssh.Auth            // Key passphrase and password, kept to restart the session
+	errorMu     sync.Mutex           // Guards errorLog and Stats.LastError
+	activityMu  sync.Mutex           // Guards Stats.LastActivity
+	errorLog    []ErrorLogEntry      // Recent errors, oldest first
+	restartMu   sync.Mutex           // Serializes updates of the rule
+	carried     config.ForwardTotals // Totals of earlier runs of the tunnel, loaded when it starts
+	rate        rateMeter            // Bytes of the last seconds, for GetCurrentRate
+	client      *sharedClient        // SSH connection of the latest start, guarded by the manager's mu
+	haltMu      sync.Mutex           // Guards closing and replacing done
+	degraded    int32                // Atomic flag set while reconnecting
+	expiryMu    sync.Mutex           // Guards expiresAt and expiryTimer
+	expiresAt   time.Time            // When the session stops on its own, zero for never
+	expiryTimer *time.Timer          // Stops the session at expiresAt
+	captureMu   sync.Mutex           // Guards capture
+	capture     *sessionCapture      // Dump file of the traffic, nil when not capturing
+	manager     *ForwardingManager
 }
 
 // IsActive returns whether the session is currently active
@@ -65,6 +141,47 @@ func (fs *ForwardingSession) IsActive() bool {
 	return atomic.LoadInt32(&fs.active) == 1
 }
 
+// halt closes the session's listener and signals its goroutines to exit.
+// Halting a halted session does nothing.
+func (fs *ForwardingSession) halt() {
+	fs.haltMu.Lock()
+	defer fs.haltMu.Unlock()
+	// Signal first, so the accept loop sees done when Accept fails
+	select {
+	case <-fs.done:
+	default:
+		close(fs.done)
+	}
+	if fs.listener != nil {
+		fs.listener.Close()
+	}
+}
+
+// rearm gives a halted session a new done channel to start again with, and
+// returns it
+func (fs *ForwardingSession) rearm() chan struct{} {
+	fs.haltMu.Lock()
+	defer fs.haltMu.Unlock()
+	fs.done = make(chan struct{})
+	return fs.done
+}
+
+// Degraded reports whether the SSH connection of the session died and the
+// session is being reconnected
+func (fs *ForwardingSession) Degraded() bool {
+	return atomic.LoadInt32(&fs.degraded) == 1
+}
+
+// Stop stops the session, as StopForwarding does
+func (fs *ForwardingSession) Stop() error {
+	return fs.manager.StopForwarding(fs.Rule.ID)
+}
+
+// Host returns the SSH host the session tunnels through
+func (fs *ForwardingSession) Host() config.SSHHost {
+	return fs.host
+}
+
 // SetActive sets the active state of the session
 func (fs *ForwardingSession) SetActive(active bool) {
 	if active {
@@ -76,14 +193,32 @@ func (fs *ForwardingSession) SetActive(active bool) {
 
 // AddBytesReceived atomically adds to bytes received
 func (fs *ForwardingSession) AddBytesReceived(bytes int64) {
+	now := time.Now()
 	atomic.AddInt64(&fs.Stats.BytesReceived, bytes)
-	fs.Stats.LastActivity = time.Now()
+	fs.rate.add(now, bytes, 0)
+	fs.touch(now)
 }
 
 // AddBytesSent atomically adds to bytes sent
 func (fs *ForwardingSession) AddBytesSent(bytes int64) {
+	now := time.Now()
 	atomic.AddInt64(&fs.Stats.BytesSent, bytes)
-	fs.Stats.LastActivity = time.Now()
+	fs.rate.add(now, 0, bytes)
+	fs.touch(now)
+}
+
+// touch records now as the time of the last data transfer
+func (fs *ForwardingSession) touch(now time.Time) {
+	fs.activityMu.Lock()
+	defer fs.activityMu.Unlock()
+	fs.Stats.LastActivity = now
+}
+
+// LastActivity returns when data was last transferred, zero before any
+func (fs *ForwardingSession) LastActivity() time.Time {
+	fs.activityMu.Lock()
+	defer fs.activityMu.Unlock()
+	return fs.Stats.LastActivity
 }
 
 // IncrementConnections atomically increments connection count
@@ -97,10 +232,117 @@ func (fs *ForwardingSession) DecrementActiveConnections() {
 	atomic.AddInt64(&fs.Stats.ActiveConnections, -1)
 }
 
-// IncrementErrors atomically increments error count
+// IncrementErrors atomically increments error count and records the error
+// in the session's error log, dropping the oldest entry when it is full
 func (fs *ForwardingSession) IncrementErrors(err string) {
+	slog.Warn("forwarding error", "id", fs.Rule.ID, "error", err)
 	atomic.AddInt64(&fs.Stats.ErrorCount, 1)
+
+	fs.errorMu.Lock()
+	defer fs.errorMu.Unlock()
 	fs.Stats.LastError = err
+	if len(fs.errorLog) >= maxErrorLog {
+		fs.errorLog = append(fs.errorLog[:0], fs.errorLog[1:]...)
+	}
+	fs.errorLog = append(fs.errorLog, ErrorLogEntry{Time: time.Now(), Message: err})
+}
+
+// ErrorLog returns a copy of the session's recent errors, oldest first
+func (fs *ForwardingSession) ErrorLog() []ErrorLogEntry {
+	fs.errorMu.Lock()
+	defer fs.errorMu.Unlock()
+	return append([]ErrorLogEntry(nil), fs.errorLog...)
+}
+
+// LastError returns the message of the latest error, empty when there was
+// none since the errors were cleared
+func (fs *ForwardingSession) LastError() string {
+	fs.errorMu.Lock()
+	defer fs.errorMu.Unlock()
+	return fs.Stats.LastError
+}
+
+// ClearErrors empties the error log and resets the error statistics
+func (fs *ForwardingSession) ClearErrors() {
+	fs.errorMu.Lock()
+	defer fs.errorMu.Unlock()
+	fs.errorLog = nil
+	atomic.StoreInt64(&fs.Stats.ErrorCount, 0)
+	fs.Stats.LastError = ""
+}
+
+// record adds the stopped session to the session store. Failing to record it
+// is only logged, the forwarding has stopped anyway.
+func (fs *ForwardingSession) record() {
+	record := config.SessionRecord{
+		Kind:          config.SessionForward,
+		Host:          fs.host.Name,
+		Start:         fs.Stats.StartTime,
+		End:           time.Now(),
+		BytesSent:     atomic.LoadInt64(&fs.Stats.BytesSent),
+		BytesReceived: atomic.LoadInt64(&fs.Stats.BytesReceived),
+		Detail:        fs.Rule.Description,
+		Error:         fs.LastError(),
+	}
+	if err := config.RecordSession(record); err != nil {
+		slog.Warn("failed to record forwarding", "id", fs.Rule.ID, "error", err)
+	}
+	auditForwarding(config.AuditTunnelClosed, fs)
+}
+
+// tunnel describes the forwarding for the totals of its earlier runs, by
+// its type and addresses, which stay the same across restarts unlike its ID
+func (fs *ForwardingSession) tunnel() string {
+	rule := fs.Rule
+	switch rule.Type {
+	case DynamicForward:
+		return fmt.Sprintf("%s %s:%d", rule.Type, rule.LocalHost, rule.LocalPort)
+	case RemoteDynamicForward:
+		return fmt.Sprintf("%s %s:%d", rule.Type, rule.RemoteHost, rule.RemotePort)
+	}
+	return fmt.Sprintf("%s %s:%d %s", rule.Type, rule.LocalHost, rule.LocalPort, rule.RemoteTarget())
+}
+
+// loadTotals carries over the stored totals of the tunnel. Failing to read
+// them is only logged, the tunnel then counts from zero.
+func (fs *ForwardingSession) loadTotals() {
+	totals, err := config.LoadForwardTotals()
+	if err != nil {
+		slog.Warn("failed to load forwarding totals", "id", fs.Rule.ID, "error", err)
+		return
+	}
+	fs.carried = totals[config.ForwardTotalsKey(fs.host.Name, fs.tunnel())]
+}
+
+// Resumed reports whether the session carries over the totals of earlier
+// runs of its tunnel
+func (fs *ForwardingSession) Resumed() bool {
+	return !fs.carried.Since.IsZero()
+}
+
+// Totals returns the statistics of the tunnel over all its runs, this one
+// included
+func (fs *ForwardingSession) Totals() config.ForwardTotals {
+	totals := config.ForwardTotals{
+		Host:          fs.host.Name,
+		Tunnel:        fs.tunnel(),
+		BytesSent:     fs.carried.BytesSent + atomic.LoadInt64(&fs.Stats.BytesSent),
+		BytesReceived: fs.carried.BytesReceived + atomic.LoadInt64(&fs.Stats.BytesReceived),
+		Connections:   fs.carried.Connections + atomic.LoadInt64(&fs.Stats.ConnectionCount),
+		Since:         fs.carried.Since,
+	}
+	if totals.Since.IsZero() {
+		totals.Since = fs.Stats.StartTime
+	}
+	return totals
+}
+
+// auditForwarding adds an event about the session to the audit log
+func auditForwarding(action string, fs *ForwardingSession) {
+	event := config.AuditEvent{Action: action, Host: fs.host.Name, Detail: fs.Rule.Description}
+	if err := config.RecordAudit(event); err != nil {
+		slog.Warn("failed to write the audit log", "id", fs.Rule.ID, "error", err)
+	}
 }
 
 // GetUptime returns the duration since the session started
@@ -108,15 +350,22 @@ func (fs *ForwardingSession) GetUptime() time.Duration {
 	return time.Since(fs.Stats.StartTime)
 }
 
-// GetTransferRate returns the current transfer rate in bytes per second
+// GetTransferRate returns the average bytes per second received and sent
+// since the session started. See GetCurrentRate for the rate of the moment.
 func (fs *ForwardingSession) GetTransferRate() (float64, float64) {
 	uptime := fs.GetUptime().Seconds()
 	if uptime == 0 {
 		return 0, 0
 	}
-	
+
 	received := float64(atomic.LoadInt64(&fs.Stats.BytesReceived))
 	sent := float64(atomic.LoadInt64(&fs.Stats.BytesSent))
-	
+
 	return received / uptime, sent / uptime
-}
\ No newline at end of file
+}
+
+// GetCurrentRate returns the bytes per second received and sent over the
+// last 10 seconds, which drops to zero once the traffic stops
+func (fs *ForwardingSession) GetCurrentRate() (float64, float64) {
+	return fs.rate.rates(time.Now(), fs.Stats.StartTime)
+}
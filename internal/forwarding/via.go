@@ -0,0 +1,28 @@
+package forwarding
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseVia parses the hops of a forwarding, hosts separated by commas in the
+// order they are tunneled through, as in ProxyJump: empty for none
+func ParseVia(value string) ([]string, error) {
+	var via []string
+	for _, hop := range strings.Split(value, ",") {
+		hop = strings.TrimSpace(hop)
+		if hop == "" {
+			continue
+		}
+		if strings.ContainsAny(hop, " \t") {
+			return nil, fmt.Errorf("invalid hop %q, expected [user@]host[:port]", hop)
+		}
+		via = append(via, hop)
+	}
+	return via, nil
+}
+
+// ViaString returns the hops of the rule as ParseVia reads them
+func (r ForwardingRule) ViaString() string {
+	return strings.Join(r.Via, ",")
+}
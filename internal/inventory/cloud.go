@@ -0,0 +1,482 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// CloudProviders are the clouds "xssh import" queries for instances, and
+// "k8s" for the nodes of a Kubernetes cluster
+var CloudProviders = []string{"aws", "gcp", "hetzner", "k8s"}
+
+// CloudOptions select the instances of a cloud import and how they are
+// reached
+type CloudOptions struct {
+	Profile    string            // AWS profile, gcloud configuration, hcloud context or kubeconfig context
+	Kubeconfig string            // kubeconfig file; empty for kubectl's default
+	Region     string            // AWS region, or GCP zone
+	Project    string            // GCP project
+	Filters    map[string]string // Only instances carrying these tags (AWS) or labels (GCP, Hetzner, Kubernetes nodes)
+	User       string            // Login user of the hosts
+	KeyDir     string            // Where the private keys of AWS key pairs are looked up; ~/.ssh if empty
+	Private    bool              // Connect to private addresses instead of public ones
+	Bastion    string            // Jump host of the hosts, which are then reached at their private addresses
+}
+
+// Source identifies the instances of an import, so a later sync with the
+// same provider, profile and filters retires the hosts it no longer finds
+func (o CloudOptions) Source(provider string) string {
+	parts := []string{provider}
+	for _, field := range []struct{ name, value string }{
+		{"profile", o.Profile}, {"kubeconfig", o.Kubeconfig}, {"project", o.Project}, {"region", o.Region},
+	} {
+		if field.value != "" {
+			parts = append(parts, field.name+"="+field.value)
+		}
+	}
+	var filters []string
+	for key, value := range o.Filters {
+		filters = append(filters, key+"="+value)
+	}
+	sort.Strings(filters)
+	return strings.Join(append(parts, filters...), " ")
+}
+
+// cloudInstance is an instance as the provider CLIs describe it
+type cloudInstance struct {
+	ID        string
+	Name      string
+	PublicIP  string
+	PrivateIP string
+	Type      string
+	Zone      string
+	KeyName   string
+	Labels    map[string]string
+	Tags      []string // Tags besides the provider and zone; the labels are used when nil
+}
+
+// FetchCloud lists the running instances of a provider with its command line
+// tool (aws, gcloud or hcloud), which must be installed and logged in, and
+// returns them as hosts tagged with the provider, zone and instance tags
+func FetchCloud(provider string, opts CloudOptions) ([]Entry, error) {
+	var instances []cloudInstance
+	var err error
+	switch provider {
+	case "aws":
+		instances, err = fetchAWS(opts)
+	case "gcp":
+		instances, err = fetchGCP(opts)
+	case "hetzner":
+		instances, err = fetchHetzner(opts)
+	case "k8s":
+		instances, err = fetchKubernetes(opts)
+	default:
+		return nil, fmt.Errorf("unknown cloud provider %q, expected one of %s", provider, strings.Join(CloudProviders, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, instance := range instances {
+		if !matchLabels(instance.Labels, opts.Filters) {
+			continue
+		}
+		address := instance.PublicIP
+		if opts.Private || opts.Bastion != "" || address == "" {
+			address = instance.PrivateIP
+		}
+		if address == "" {
+			continue
+		}
+		entries = append(entries, instance.entry(provider, address, opts))
+	}
+	return entries, nil
+}
+
+// entry converts an instance to a host reached at address
+func (i cloudInstance) entry(provider, address string, opts CloudOptions) Entry {
+	name := i.Name
+	if name == "" {
+		name = i.ID
+	}
+	tags := []string{provider}
+	if i.Zone != "" {
+		tags = append(tags, i.Zone)
+	}
+	if i.Tags != nil {
+		tags = append(tags, i.Tags...)
+	} else {
+		var keys []string
+		for key := range i.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key != "Name" && i.Labels[key] != "" {
+				tags = append(tags, config.NormalizeTag(key+"="+i.Labels[key]))
+			}
+		}
+	}
+
+	return Entry{
+		Host: config.SSHHost{
+			Name:      cloudAlias(name),
+			Host:      address,
+			User:      opts.User,
+			Port:      "22",
+			Identity:  keyPairFile(i.KeyName, opts.KeyDir),
+			ProxyJump: opts.Bastion,
+		},
+		Tags:  tags,
+		Notes: strings.Join(strings.Fields(fmt.Sprintf("%s instance %s %s", provider, i.ID, i.Type)), " "),
+	}
+}
+
+// cloudAlias turns an instance name into an alias, replacing the characters
+// a Host line cannot hold
+func cloudAlias(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("*?!#, \t", r) {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(name))
+}
+
+// keyPairFile returns the private key of a key pair: keyName.pem or keyName
+// in keyDir, if one of them exists
+func keyPairFile(keyName, keyDir string) string {
+	if keyName == "" {
+		return ""
+	}
+	if keyDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		keyDir = filepath.Join(home, ".ssh")
+	}
+	for _, name := range []string{keyName + ".pem", keyName} {
+		keyPath := filepath.Join(keyDir, name)
+		if _, err := os.Stat(keyPath); err == nil {
+			return keyPath
+		}
+	}
+	return ""
+}
+
+// matchLabels reports whether labels carry every filter
+func matchLabels(labels, filters map[string]string) bool {
+	for key, value := range filters {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// runCloudCLI runs a provider's command line tool and decodes its JSON
+// output into v
+func runCloudCLI(v any, env []string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s is not installed: %v", name, err)
+	}
+	var stderr strings.Builder
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s failed: %s", name, message)
+		}
+		return fmt.Errorf("%s failed: %v", name, err)
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("cannot read the output of %s: %v", name, err)
+	}
+	return nil
+}
+
+// fetchAWS lists the running EC2 instances with "aws ec2 describe-instances"
+func fetchAWS(opts CloudOptions) ([]cloudInstance, error) {
+	args := []string{"ec2", "describe-instances", "--output", "json",
+		"--filters", "Name=instance-state-name,Values=running"}
+	for key, value := range opts.Filters {
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", key, value))
+	}
+	if opts.Profile != "" {
+		args = append(args, "--profile", opts.Profile)
+	}
+	if opts.Region != "" {
+		args = append(args, "--region", opts.Region)
+	}
+
+	var output struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID       string `json:"InstanceId"`
+				InstanceType     string
+				KeyName          string
+				PublicIPAddress  string `json:"PublicIpAddress"`
+				PrivateIPAddress string `json:"PrivateIpAddress"`
+				Placement        struct {
+					AvailabilityZone string
+				}
+				Tags []struct {
+					Key   string
+					Value string
+				}
+			}
+		}
+	}
+	if err := runCloudCLI(&output, nil, "aws", args...); err != nil {
+		return nil, err
+	}
+
+	var instances []cloudInstance
+	for _, reservation := range output.Reservations {
+		for _, i := range reservation.Instances {
+			instance := cloudInstance{
+				ID:        i.InstanceID,
+				PublicIP:  i.PublicIPAddress,
+				PrivateIP: i.PrivateIPAddress,
+				Type:      i.InstanceType,
+				Zone:      i.Placement.AvailabilityZone,
+				KeyName:   i.KeyName,
+				Labels:    map[string]string{},
+			}
+			for _, tag := range i.Tags {
+				instance.Labels[tag.Key] = tag.Value
+			}
+			instance.Name = instance.Labels["Name"]
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// fetchGCP lists the running Compute Engine instances with "gcloud compute
+// instances list"
+func fetchGCP(opts CloudOptions) ([]cloudInstance, error) {
+	filter := []string{"status=RUNNING"}
+	for key, value := range opts.Filters {
+		filter = append(filter, fmt.Sprintf("labels.%s=%s", key, value))
+	}
+	if opts.Region != "" {
+		filter = append(filter, "zone:"+opts.Region)
+	}
+	args := []string{"compute", "instances", "list", "--format=json", "--filter=" + strings.Join(filter, " AND ")}
+	if opts.Project != "" {
+		args = append(args, "--project", opts.Project)
+	}
+	if opts.Profile != "" {
+		args = append(args, "--configuration", opts.Profile)
+	}
+
+	var output []struct {
+		ID                string            `json:"id"`
+		Name              string            `json:"name"`
+		Zone              string            `json:"zone"`
+		MachineType       string            `json:"machineType"`
+		Labels            map[string]string `json:"labels"`
+		NetworkInterfaces []struct {
+			NetworkIP     string `json:"networkIP"`
+			AccessConfigs []struct {
+				NatIP string `json:"natIP"`
+			} `json:"accessConfigs"`
+		} `json:"networkInterfaces"`
+	}
+	if err := runCloudCLI(&output, nil, "gcloud", args...); err != nil {
+		return nil, err
+	}
+
+	var instances []cloudInstance
+	for _, i := range output {
+		instance := cloudInstance{
+			ID:     i.ID,
+			Name:   i.Name,
+			Type:   path.Base(i.MachineType),
+			Zone:   path.Base(i.Zone),
+			Labels: i.Labels,
+		}
+		for _, network := range i.NetworkInterfaces {
+			if instance.PrivateIP == "" {
+				instance.PrivateIP = network.NetworkIP
+			}
+			for _, access := range network.AccessConfigs {
+				if instance.PublicIP == "" {
+					instance.PublicIP = access.NatIP
+				}
+			}
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// fetchHetzner lists the running Hetzner Cloud servers with "hcloud server
+// list"
+func fetchHetzner(opts CloudOptions) ([]cloudInstance, error) {
+	args := []string{"server", "list", "--output", "json"}
+	var selector []string
+	for key, value := range opts.Filters {
+		selector = append(selector, key+"="+value)
+	}
+	if len(selector) > 0 {
+		sort.Strings(selector)
+		args = append(args, "--selector", strings.Join(selector, ","))
+	}
+	var env []string
+	if opts.Profile != "" {
+		env = append(env, "HCLOUD_CONTEXT="+opts.Profile)
+	}
+
+	var output []struct {
+		ID        int64  `json:"id"`
+		Name      string `json:"name"`
+		Status    string `json:"status"`
+		PublicNet struct {
+			IPv4 struct {
+				IP string `json:"ip"`
+			} `json:"ipv4"`
+		} `json:"public_net"`
+		PrivateNet []struct {
+			IP string `json:"ip"`
+		} `json:"private_net"`
+		ServerType struct {
+			Name string `json:"name"`
+		} `json:"server_type"`
+		Datacenter struct {
+			Name string `json:"name"`
+		} `json:"datacenter"`
+		Labels map[string]string `json:"labels"`
+	}
+	if err := runCloudCLI(&output, env, "hcloud", args...); err != nil {
+		return nil, err
+	}
+
+	var instances []cloudInstance
+	for _, s := range output {
+		if s.Status != "running" {
+			continue
+		}
+		instance := cloudInstance{
+			ID:       fmt.Sprint(s.ID),
+			Name:     s.Name,
+			PublicIP: s.PublicNet.IPv4.IP,
+			Type:     s.ServerType.Name,
+			Zone:     s.Datacenter.Name,
+			Labels:   s.Labels,
+		}
+		if len(s.PrivateNet) > 0 {
+			instance.PrivateIP = s.PrivateNet[0].IP
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// kubernetesRolePrefix starts the labels naming the roles of a node
+const kubernetesRolePrefix = "node-role.kubernetes.io/"
+
+// fetchKubernetes lists the nodes of the cluster of a kubeconfig context
+// with "kubectl get nodes", tagged with the cluster name and node roles
+func fetchKubernetes(opts CloudOptions) ([]cloudInstance, error) {
+	var global []string
+	if opts.Kubeconfig != "" {
+		global = append(global, "--kubeconfig", opts.Kubeconfig)
+	}
+	if opts.Profile != "" {
+		global = append(global, "--context", opts.Profile)
+	}
+
+	var kubeconfig struct {
+		Clusters []struct {
+			Name string `json:"name"`
+		} `json:"clusters"`
+	}
+	args := append([]string{"config", "view", "--minify", "-o", "json"}, global...)
+	if err := runCloudCLI(&kubeconfig, nil, "kubectl", args...); err != nil {
+		return nil, err
+	}
+	cluster := ""
+	if len(kubeconfig.Clusters) > 0 {
+		cluster = kubeconfig.Clusters[0].Name
+	}
+
+	args = append([]string{"get", "nodes", "-o", "json"}, global...)
+	var selector []string
+	for key, value := range opts.Filters {
+		selector = append(selector, key+"="+value)
+	}
+	if len(selector) > 0 {
+		sort.Strings(selector)
+		args = append(args, "--selector", strings.Join(selector, ","))
+	}
+
+	var output struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Addresses []struct {
+					Type    string `json:"type"`
+					Address string `json:"address"`
+				} `json:"addresses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := runCloudCLI(&output, nil, "kubectl", args...); err != nil {
+		return nil, err
+	}
+
+	var instances []cloudInstance
+	for _, node := range output.Items {
+		labels := node.Metadata.Labels
+		instance := cloudInstance{
+			Name:   node.Metadata.Name,
+			Type:   labels["node.kubernetes.io/instance-type"],
+			Zone:   labels["topology.kubernetes.io/zone"],
+			Labels: labels,
+			Tags:   []string{},
+		}
+		if cluster != "" {
+			instance.Tags = append(instance.Tags, config.NormalizeTag(cluster))
+		}
+		var roles []string
+		for key := range labels {
+			if role, ok := strings.CutPrefix(key, kubernetesRolePrefix); ok && role != "" {
+				roles = append(roles, config.NormalizeTag("role="+role))
+			}
+		}
+		sort.Strings(roles)
+		instance.Tags = append(instance.Tags, roles...)
+
+		for _, address := range node.Status.Addresses {
+			switch address.Type {
+			case "ExternalIP":
+				if instance.PublicIP == "" {
+					instance.PublicIP = address.Address
+				}
+			case "InternalIP":
+				if instance.PrivateIP == "" {
+					instance.PrivateIP = address.Address
+				}
+			}
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
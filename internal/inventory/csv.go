@@ -0,0 +1,103 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// Columns are the fields of a host, and the columns of the CSV format.
+// Reading CSV requires the header row but accepts the columns in any order
+// and ignores unknown ones.
+var Columns = []string{"name", "host", "user", "port", "identity", "proxy_jump", "tags", "color", "notes"}
+
+// csvTagSeparator separates the tags in the tags column
+const csvTagSeparator = ";"
+
+func readCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "host"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("invalid CSV: the header has no %q column", required)
+		}
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := Entry{
+			Host: config.SSHHost{
+				Name:      field("name"),
+				Host:      field("host"),
+				User:      field("user"),
+				Port:      field("port"),
+				Identity:  field("identity"),
+				ProxyJump: field("proxy_jump"),
+			},
+			Color: field("color"),
+			Notes: field("notes"),
+		}
+		for _, tag := range strings.Split(field("tags"), csvTagSeparator) {
+			if tag = config.NormalizeTag(tag); tag != "" {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func writeCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Columns); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Host.Name,
+			e.Host.Host,
+			e.Host.User,
+			e.Host.Port,
+			e.Host.Identity,
+			e.Host.ProxyJump,
+			strings.Join(e.Tags, csvTagSeparator),
+			e.Color,
+			e.Notes,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
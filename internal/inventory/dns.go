@@ -0,0 +1,149 @@
+package inventory
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// zoneHostTypes are the record types of a zone whose names are hosts
+var zoneHostTypes = []string{"A", "AAAA", "CNAME"}
+
+// DiscoverDNS finds hosts in DNS: the targets of the SRV records named in
+// srv, such as "_ssh._tcp.example.com", and the hosts of the list files in
+// files. Hosts found twice are returned once. Lookups and files that fail
+// are skipped and reported in the error.
+func DiscoverDNS(srv, files []string) ([]config.SSHHost, error) {
+	var hosts []config.SSHHost
+	var errs []error
+	seen := map[string]bool{}
+	add := func(found []config.SSHHost) {
+		for _, host := range found {
+			key := host.Host + ":" + host.Port
+			if !seen[key] {
+				seen[key] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	for _, name := range srv {
+		_, records, err := net.LookupSRV("", "", name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SRV %s: %v", name, err))
+			continue
+		}
+		var found []config.SSHHost
+		for _, record := range records {
+			found = append(found, dnsHost(record.Target, strconv.Itoa(int(record.Port))))
+		}
+		add(found)
+	}
+
+	for _, path := range files {
+		found, err := readHostList(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		add(found)
+	}
+
+	return hosts, errors.Join(errs...)
+}
+
+// readHostList reads a host list file
+func readHostList(path string) ([]config.SSHHost, error) {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, "~/") {
+		path = home + path[1:]
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseHostList(file)
+}
+
+// ParseHostList reads hosts from a zone, such as the output of "dig axfr",
+// or from a list with one "host" or "host:port" per line. The names of A,
+// AAAA and CNAME records become hosts on port 22, SRV records their target
+// on their port. Comments start with ";" or "#".
+func ParseHostList(r io.Reader) ([]config.SSHHost, error) {
+	var hosts []config.SSHHost
+	origin := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, ";#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+
+		case fields[0] == "$ORIGIN" && len(fields) > 1:
+			origin = strings.TrimSuffix(fields[1], ".")
+
+		case strings.HasPrefix(fields[0], "$"):
+			// $TTL and $INCLUDE name no hosts
+
+		case line[0] == ' ' || line[0] == '\t':
+			// Another record of the name above, which is listed already
+
+		case len(fields) == 1:
+			host, port, err := net.SplitHostPort(fields[0])
+			if err != nil {
+				host, port = fields[0], "22"
+			}
+			hosts = append(hosts, dnsHost(host, port))
+
+		default:
+			if host, ok := zoneRecordHost(fields, origin); ok {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, scanner.Err()
+}
+
+// zoneRecordHost returns the host named by a zone record line: "name [ttl]
+// [class] type data"
+func zoneRecordHost(fields []string, origin string) (config.SSHHost, bool) {
+	name := fields[0]
+	if name == "@" {
+		name = origin
+	} else if !strings.HasSuffix(name, ".") && origin != "" {
+		name += "." + origin
+	}
+
+	for i := 1; i < len(fields); i++ {
+		recordType := strings.ToUpper(fields[i])
+		if slices.Contains(zoneHostTypes, recordType) {
+			if strings.HasPrefix(name, "*") {
+				return config.SSHHost{}, false
+			}
+			return dnsHost(name, "22"), true
+		}
+		if recordType == "SRV" && len(fields) >= i+5 {
+			// SRV priority weight port target
+			return dnsHost(fields[i+4], fields[i+3]), true
+		}
+	}
+	return config.SSHHost{}, false
+}
+
+// dnsHost returns the host reached at a DNS name, with the name as alias
+func dnsHost(name, port string) config.SSHHost {
+	name = strings.TrimSuffix(name, ".")
+	return config.SSHHost{Name: name, Host: name, Port: port}
+}
@@ -0,0 +1,126 @@
+// Package inventory reads and writes lists of hosts in formats other tools
+// use, for "xssh import" and "xssh export".
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// Entry is a host with the metadata xssh keeps for it
+type Entry struct {
+	Host  config.SSHHost
+	Tags  []string
+	Color string
+	Notes string
+}
+
+// Formats are the supported formats, by name
+var Formats = []string{"csv", "json", "putty"}
+
+// DetectFormat guesses the format of a file from its extension; it returns
+// an empty string when the extension is not known
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	case ".reg":
+		return "putty"
+	}
+	return ""
+}
+
+// Read parses the hosts of r in the given format
+func Read(format string, r io.Reader) ([]Entry, error) {
+	switch format {
+	case "csv":
+		return readCSV(r)
+	case "json":
+		return readJSON(r)
+	case "putty":
+		return readPuTTY(r)
+	}
+	return nil, unknownFormat(format)
+}
+
+// Write writes the entries to w in the given format
+func Write(format string, w io.Writer, entries []Entry) error {
+	switch format {
+	case "csv":
+		return writeCSV(w, entries)
+	case "json":
+		return writeJSON(w, entries)
+	case "putty":
+		return writePuTTY(w, entries)
+	}
+	return unknownFormat(format)
+}
+
+func unknownFormat(format string) error {
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// jsonEntry is an entry in the JSON format, the same fields as
+// "xssh list --json" prints
+type jsonEntry struct {
+	Name      string   `json:"name"`
+	Host      string   `json:"host"`
+	User      string   `json:"user,omitempty"`
+	Port      string   `json:"port,omitempty"`
+	Identity  string   `json:"identity,omitempty"`
+	ProxyJump string   `json:"proxy_jump,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Color     string   `json:"color,omitempty"`
+	Notes     string   `json:"notes,omitempty"`
+}
+
+func readJSON(r io.Reader) ([]Entry, error) {
+	var list []jsonEntry
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	entries := make([]Entry, 0, len(list))
+	for _, e := range list {
+		entries = append(entries, Entry{
+			Host: config.SSHHost{
+				Name:      e.Name,
+				Host:      e.Host,
+				User:      e.User,
+				Port:      e.Port,
+				Identity:  e.Identity,
+				ProxyJump: e.ProxyJump,
+			},
+			Tags:  e.Tags,
+			Color: e.Color,
+			Notes: e.Notes,
+		})
+	}
+	return entries, nil
+}
+
+func writeJSON(w io.Writer, entries []Entry) error {
+	list := make([]jsonEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, jsonEntry{
+			Name:      e.Host.Name,
+			Host:      e.Host.Host,
+			User:      e.Host.User,
+			Port:      e.Host.Port,
+			Identity:  e.Host.Identity,
+			ProxyJump: e.Host.ProxyJump,
+			Tags:      e.Tags,
+			Color:     e.Color,
+			Notes:     e.Notes,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
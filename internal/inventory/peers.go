@@ -0,0 +1,216 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// Peer is another machine of a Tailscale tailnet or ZeroTier network the
+// local machine is part of
+type Peer struct {
+	Network string // "tailscale" or "zerotier"
+	Name    string // Host name, or member name on ZeroTier
+	DNSName string // MagicDNS name, empty without one
+	Address string // First address in the overlay network
+}
+
+// Host returns the peer as a host reached by its MagicDNS name, or by its
+// address without one
+func (p Peer) Host() config.SSHHost {
+	address := p.DNSName
+	if address == "" {
+		address = p.Address
+	}
+	return config.SSHHost{
+		Name: cloudAlias(p.Name),
+		Host: address,
+		Port: "22",
+	}
+}
+
+// peerNetworks are the overlay networks DiscoverPeers looks for, with the
+// command line tool of their client
+var peerNetworks = []struct {
+	name, cli string
+	discover  func() ([]Peer, error)
+}{
+	{"tailscale", "tailscale", tailscalePeers},
+	{"zerotier", "zerotier-cli", zerotierPeers},
+}
+
+// DiscoverPeers lists the online peers of the Tailscale and ZeroTier clients
+// running on this machine, sorted by name. Peers of one network are returned
+// even when the other fails; the error reports the failures.
+func DiscoverPeers() ([]Peer, error) {
+	var peers []Peer
+	var errs []error
+	found := false
+	for _, network := range peerNetworks {
+		if _, err := exec.LookPath(network.cli); err != nil {
+			continue
+		}
+		found = true
+		networkPeers, err := network.discover()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", network.name, err))
+		}
+		peers = append(peers, networkPeers...)
+	}
+	if !found {
+		return nil, fmt.Errorf("neither tailscale nor zerotier-cli is installed")
+	}
+
+	sort.SliceStable(peers, func(i, j int) bool {
+		return strings.ToLower(peers[i].Name) < strings.ToLower(peers[j].Name)
+	})
+	return peers, errors.Join(errs...)
+}
+
+// tailscalePeers lists the online peers of the tailnet with "tailscale
+// status --json"
+func tailscalePeers() ([]Peer, error) {
+	var status struct {
+		BackendState string
+		Peer         map[string]struct {
+			HostName     string
+			DNSName      string
+			TailscaleIPs []string
+			Online       bool
+		}
+	}
+	if err := runCloudCLI(&status, nil, "tailscale", "status", "--json"); err != nil {
+		return nil, err
+	}
+	if status.BackendState != "Running" {
+		return nil, fmt.Errorf("tailscaled is not connected (state %s)", status.BackendState)
+	}
+
+	var peers []Peer
+	for _, p := range status.Peer {
+		if !p.Online {
+			continue
+		}
+		peer := Peer{
+			Network: "tailscale",
+			Name:    p.HostName,
+			DNSName: strings.TrimSuffix(p.DNSName, "."),
+		}
+		if len(p.TailscaleIPs) > 0 {
+			peer.Address = p.TailscaleIPs[0]
+		}
+		if peer.Name == "" {
+			peer.Name, _, _ = strings.Cut(peer.DNSName, ".")
+		}
+		peers = append(peers, peer)
+	}
+	return peers, nil
+}
+
+// zerotierCentral is the ZeroTier Central API, which lists the members of
+// the networks the local node has joined
+const zerotierCentral = "https://api.zerotier.com/api/v1"
+
+// zerotierOnline is how recently a member must have been seen to count as
+// online
+const zerotierOnline = 5 * time.Minute
+
+// zerotierPeers lists the recently seen members of the joined ZeroTier
+// networks. The local client only knows its own addresses, so the members
+// are read from ZeroTier Central with the API token in
+// ZEROTIER_CENTRAL_TOKEN.
+func zerotierPeers() ([]Peer, error) {
+	var networks []struct {
+		ID     string `json:"nwid"`
+		Status string `json:"status"`
+	}
+	if err := runCloudCLI(&networks, nil, "zerotier-cli", "-j", "listnetworks"); err != nil {
+		return nil, err
+	}
+	var info struct {
+		Address string `json:"address"`
+	}
+	if err := runCloudCLI(&info, nil, "zerotier-cli", "-j", "info"); err != nil {
+		return nil, err
+	}
+
+	token := os.Getenv("ZEROTIER_CENTRAL_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("set ZEROTIER_CENTRAL_TOKEN to list the members of ZeroTier networks")
+	}
+
+	var peers []Peer
+	for _, network := range networks {
+		if network.Status != "OK" {
+			continue
+		}
+		members, err := zerotierMembers(network.ID, token)
+		if err != nil {
+			return peers, err
+		}
+		for _, member := range members {
+			if member.NodeID == info.Address || !member.Config.Authorized || len(member.Config.IPAssignments) == 0 {
+				continue
+			}
+			lastSeen := max(member.LastSeen, member.LastOnline)
+			if time.Since(time.UnixMilli(lastSeen)) > zerotierOnline {
+				continue
+			}
+			name := member.Name
+			if name == "" {
+				name = member.NodeID
+			}
+			peers = append(peers, Peer{
+				Network: "zerotier",
+				Name:    name,
+				Address: member.Config.IPAssignments[0],
+			})
+		}
+	}
+	return peers, nil
+}
+
+// zerotierMember is a member of a network as ZeroTier Central describes it
+type zerotierMember struct {
+	NodeID     string `json:"nodeId"`
+	Name       string `json:"name"`
+	LastOnline int64  `json:"lastOnline"`
+	LastSeen   int64  `json:"lastSeen"`
+	Config     struct {
+		Authorized    bool     `json:"authorized"`
+		IPAssignments []string `json:"ipAssignments"`
+	} `json:"config"`
+}
+
+// zerotierMembers reads the members of a network from ZeroTier Central
+func zerotierMembers(network, token string) ([]zerotierMember, error) {
+	req, err := http.NewRequest("GET", zerotierCentral+"/network/"+network+"/member", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+token)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ZeroTier Central returned %s for network %s", resp.Status, network)
+	}
+
+	var members []zerotierMember
+	if err := json.NewDecoder(resp.Body).Decode(&members); err != nil {
+		return nil, fmt.Errorf("cannot read the members of network %s: %v", network, err)
+	}
+	return members, nil
+}
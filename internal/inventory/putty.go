@@ -0,0 +1,137 @@
+package inventory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// puttySessionsKey is the registry key PuTTY keeps its saved sessions under
+const puttySessionsKey = `HKEY_CURRENT_USER\Software\SimonTatham\PuTTY\Sessions\`
+
+// readPuTTY reads the saved sessions of a registry export of PuTTY's
+// settings (regedit /e putty.reg HKEY_CURRENT_USER\Software\SimonTatham\PuTTY).
+// Sessions of other protocols than SSH are left out.
+func readPuTTY(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var current *Entry
+	var protocol string
+	flush := func() {
+		if current != nil && current.Host.Host != "" && (protocol == "" || protocol == "ssh") {
+			entries = append(entries, *current)
+		}
+		current, protocol = nil, ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// regedit writes UTF-16 with a BOM; a converted file may keep the BOM
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			key := line[1 : len(line)-1]
+			if !strings.HasPrefix(key, puttySessionsKey) {
+				continue
+			}
+			name, err := url.PathUnescape(strings.TrimPrefix(key, puttySessionsKey))
+			if err != nil || name == "" || name == "Default Settings" {
+				continue
+			}
+			current = &Entry{Host: config.SSHHost{Name: strings.Join(strings.Fields(name), "-")}}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name = strings.Trim(name, `"`)
+		switch name {
+		case "HostName":
+			host := puttyString(value)
+			// PuTTY accepts user@host in the host name
+			if user, h, found := strings.Cut(host, "@"); found {
+				current.Host.User, host = user, h
+			}
+			current.Host.Host = host
+		case "UserName":
+			if user := puttyString(value); user != "" {
+				current.Host.User = user
+			}
+		case "PortNumber":
+			if port, ok := puttyDword(value); ok {
+				current.Host.Port = strconv.Itoa(port)
+			}
+		case "PublicKeyFile":
+			current.Host.Identity = puttyString(value)
+		case "Protocol":
+			protocol = puttyString(value)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read PuTTY sessions: %v", err)
+	}
+	return entries, nil
+}
+
+// puttyString decodes a "..." registry string value
+func puttyString(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return ""
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(value[1:len(value)-1], `\\`, `\`), `\"`, `"`)
+}
+
+// puttyDword decodes a dword:0000xxxx registry value
+func puttyDword(value string) (int, bool) {
+	hex, ok := strings.CutPrefix(strings.TrimSpace(value), "dword:")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(hex, 16, 64)
+	return int(n), err == nil
+}
+
+// writePuTTY writes the entries as PuTTY sessions in the registry export
+// format, to be loaded with regedit. Tags, colors, notes and jump hosts have
+// no PuTTY counterpart and are left out.
+func writePuTTY(w io.Writer, entries []Entry) error {
+	sorted := append([]Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Host.Name < sorted[j].Host.Name })
+
+	var b strings.Builder
+	b.WriteString("Windows Registry Editor Version 5.00\r\n")
+	for _, e := range sorted {
+		port := 22
+		if p, err := strconv.Atoi(e.Host.Port); err == nil {
+			port = p
+		}
+		fmt.Fprintf(&b, "\r\n[%s%s]\r\n", puttySessionsKey, url.PathEscape(e.Host.Name))
+		fmt.Fprintf(&b, "\"HostName\"=%s\r\n", puttyQuote(e.Host.Host))
+		fmt.Fprintf(&b, "\"UserName\"=%s\r\n", puttyQuote(e.Host.User))
+		fmt.Fprintf(&b, "\"PortNumber\"=dword:%08x\r\n", port)
+		fmt.Fprintf(&b, "\"Protocol\"=\"ssh\"\r\n")
+		if e.Host.Identity != "" {
+			fmt.Fprintf(&b, "\"PublicKeyFile\"=%s\r\n", puttyQuote(e.Host.Identity))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// puttyQuote encodes a registry string value
+func puttyQuote(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
@@ -0,0 +1,103 @@
+// Package logging sets up the log/slog logger that the rest of xssh writes
+// its diagnostics to.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// levelOff disables logging; it is above every level slog defines
+const levelOff = slog.Level(100)
+
+// ParseLevel parses a level of [log] level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "off":
+		return levelOff, nil
+	case "error":
+		return slog.LevelError, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	}
+	return levelOff, fmt.Errorf("unknown log level %q, expected off, error, warn, info or debug", name)
+}
+
+// DefaultPath returns the log file used when [log] file is not set
+func DefaultPath() (string, error) {
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "xssh.log"), nil
+}
+
+// Options select the level and destination of the log
+type Options struct {
+	Config    config.LogConfig
+	Verbosity int  // 1 logs at least info (--verbose), 2 debug (--debug)
+	Terminal  bool // Whether the terminal is free, so -v can log to stderr
+}
+
+// Setup installs the default slog logger and returns a function that closes
+// the log file. Logs go to the configured file; when -v is given on a
+// command that does not run the TUI and no file is configured, they go to
+// stderr instead.
+func Setup(opts Options) (func(), error) {
+	level, err := ParseLevel(opts.Config.Level)
+	if err != nil {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return func() {}, err
+	}
+	switch {
+	case opts.Verbosity >= 2:
+		level = min(level, slog.LevelDebug)
+	case opts.Verbosity == 1:
+		level = min(level, slog.LevelInfo)
+	}
+	if level == levelOff {
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return func() {}, nil
+	}
+
+	var output io.Writer = os.Stderr
+	closeLog := func() {}
+	if opts.Config.File != "" || opts.Verbosity == 0 || !opts.Terminal {
+		path := opts.Config.File
+		if strings.HasPrefix(path, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, path[2:])
+			}
+		}
+		if path == "" {
+			if path, err = DefaultPath(); err != nil {
+				slog.SetDefault(slog.New(slog.DiscardHandler))
+				return closeLog, err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			slog.SetDefault(slog.New(slog.DiscardHandler))
+			return closeLog, fmt.Errorf("failed to create log directory: %v", err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			slog.SetDefault(slog.New(slog.DiscardHandler))
+			return closeLog, fmt.Errorf("failed to open log file: %v", err)
+		}
+		output = file
+		closeLog = func() { file.Close() }
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: level})))
+	return closeLog, nil
+}
@@ -0,0 +1,238 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recording formats
+const (
+	Asciicast  = "asciicast"  // asciicast v2, played by asciinema and xssh replay
+	Typescript = "typescript" // script(1) output with a scriptreplay timing file
+)
+
+// Formats lists the recording formats
+var Formats = []string{Asciicast, Typescript}
+
+// TimingSuffix is appended to the name of a typescript for its timing file
+const TimingSuffix = ".timing"
+
+// Options describe a recording
+type Options struct {
+	Format  string
+	Dir     string // Directory of the recordings, created if needed
+	Host    string // Alias of the host, part of the file name
+	Command string // Command recorded, stored in the header
+	Width   int    // Terminal size stored in the header; 80x24 when zero
+	Height  int
+}
+
+// Recorder writes the output of a session to a recording file. It is safe
+// for use by several goroutines, such as the readers of stdout and stderr.
+type Recorder struct {
+	mu      sync.Mutex
+	format  string
+	file    *os.File
+	timing  *os.File // Timing file of a typescript
+	started time.Time
+	last    time.Time // Time of the previous typescript chunk
+	path    string
+	err     error // First write error, reported by Close
+}
+
+// Start creates a recording file for a session starting now and writes its
+// header. The file is named after the host and the start time.
+func Start(opts Options) (*Recorder, error) {
+	if opts.Format != Asciicast && opts.Format != Typescript {
+		return nil, fmt.Errorf("unknown recording format %q, expected %s", opts.Format, strings.Join(Formats, " or "))
+	}
+	if opts.Width == 0 || opts.Height == 0 {
+		opts.Width, opts.Height = 80, 24
+	}
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	name := fmt.Sprintf("%s-%s", fileSafe(opts.Host), started.Format("20060102-150405.000"))
+	r := &Recorder{format: opts.Format, started: started, last: started}
+	if opts.Format == Asciicast {
+		r.path = filepath.Join(opts.Dir, name+".cast")
+	} else {
+		r.path = filepath.Join(opts.Dir, name+".typescript")
+	}
+
+	file, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	r.file = file
+
+	if opts.Format == Asciicast {
+		header, _ := json.Marshal(asciicastHeader{
+			Version:   2,
+			Width:     opts.Width,
+			Height:    opts.Height,
+			Timestamp: started.Unix(),
+			Title:     fmt.Sprintf("%s: %s", opts.Host, opts.Command),
+			Command:   opts.Command,
+		})
+		_, err = file.Write(append(header, '\n'))
+	} else {
+		_, err = fmt.Fprintf(file, "Script started on %s [COMMAND=%q HOST=%q]\n",
+			started.Format("2006-01-02 15:04:05-07:00"), opts.Command, opts.Host)
+		if err == nil {
+			r.timing, err = os.OpenFile(r.path+TimingSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		}
+	}
+	if err != nil {
+		r.Close()
+		os.Remove(r.path)
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the location of the recording
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Write records output of the session. Output read from a session without
+// a terminal ends lines with "\n" alone, which is turned into "\r\n" as a
+// terminal would. Write never fails, so a full disk does not stop the
+// session; Close reports the first error.
+func (r *Recorder) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	data := bytes.ReplaceAll(bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return len(p), nil
+	}
+	now := time.Now()
+	if r.format == Asciicast {
+		event, _ := json.Marshal([]any{now.Sub(r.started).Seconds(), "o", string(data)})
+		_, r.err = r.file.Write(append(event, '\n'))
+	} else {
+		_, r.err = r.file.Write(data)
+		if r.err == nil {
+			_, r.err = fmt.Fprintf(r.timing, "%.6f %d\n", now.Sub(r.last).Seconds(), len(data))
+		}
+		r.last = now
+	}
+	return len(p), nil
+}
+
+// Close finishes the recording, returning the first error met while
+// writing it
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.format == Typescript && r.err == nil {
+		_, r.err = fmt.Fprintf(r.file, "\nScript done on %s\n", time.Now().Format("2006-01-02 15:04:05-07:00"))
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.timing != nil {
+		if err := r.timing.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// asciicastHeader is the first line of an asciicast v2 file
+type asciicastHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Command   string `json:"command,omitempty"`
+}
+
+// fileSafe replaces the characters of a host alias that do not belong in a
+// file name
+func fileSafe(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' || r == '*' || r == '?' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// Entry is a recording found in the recordings directory
+type Entry struct {
+	Path    string
+	ModTime time.Time
+	Size    int64
+}
+
+// List returns the recordings in dir, newest first. A missing directory
+// holds no recordings.
+func List(dir string) ([]Entry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || (!strings.HasSuffix(name, ".cast") && !strings.HasSuffix(name, ".typescript")) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Path: filepath.Join(dir, name), ModTime: info.ModTime(), Size: info.Size()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.After(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Prune deletes the recordings in dir older than maxAge and those beyond
+// the newest maxFiles, together with their timing files. A zero limit is
+// not applied. It returns the number of recordings deleted.
+func Prune(dir string, maxAge time.Duration, maxFiles int) (int, error) {
+	if maxAge <= 0 && maxFiles <= 0 {
+		return 0, nil
+	}
+	entries, err := List(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for i, entry := range entries {
+		expired := maxAge > 0 && time.Since(entry.ModTime) > maxAge
+		if !expired && (maxFiles <= 0 || i < maxFiles) {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return deleted, err
+		}
+		os.Remove(entry.Path + TimingSuffix)
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,131 @@
+package recording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ReplayOptions control the playback of a recording
+type ReplayOptions struct {
+	Speed     float64       // Playback speed factor; 1 when zero
+	IdleLimit time.Duration // Longest pause played; 0 keeps the recorded pauses
+	Instant   bool          // Print the output at once, without pauses
+}
+
+// Replay plays the recording at path on w with its original timing. The
+// format is detected from the file: an asciicast v2 recording, or a
+// typescript played with the timing file next to it. A typescript without
+// a timing file is printed at once.
+func Replay(path string, w io.Writer, opts ReplayOptions) error {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	firstLine, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	var header asciicastHeader
+	if bytes.HasPrefix(firstLine, []byte("{")) && json.Unmarshal(firstLine, &header) == nil {
+		if header.Version != 2 {
+			return fmt.Errorf("unsupported asciicast version %d", header.Version)
+		}
+		return replayAsciicast(reader, w, opts)
+	}
+
+	timing, err := os.Open(path + TimingSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		opts.Instant = true
+	} else if err != nil {
+		return err
+	} else {
+		defer timing.Close()
+	}
+	if !strings.HasPrefix(string(firstLine), "Script started") {
+		// Not a script(1) header, so part of the output
+		reader = bufio.NewReader(io.MultiReader(bytes.NewReader(firstLine), reader))
+	}
+	if opts.Instant {
+		_, err := io.Copy(w, reader)
+		return err
+	}
+	return replayTypescript(reader, timing, w, opts)
+}
+
+// replayAsciicast plays the events following the header of an asciicast
+func replayAsciicast(reader *bufio.Reader, w io.Writer, opts ReplayOptions) error {
+	var previous float64
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) < 3 {
+			return fmt.Errorf("event %d: not an asciicast event", line)
+		}
+		at, ok := event[0].(float64)
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if !ok {
+			return fmt.Errorf("event %d: not an asciicast event", line)
+		}
+		if kind != "o" {
+			// Input, resize and marker events do not change the output
+			continue
+		}
+		pause(time.Duration((at-previous)*float64(time.Second)), opts)
+		previous = at
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// replayTypescript plays a typescript chunk by chunk, each timing line
+// giving the pause before the chunk and its length in bytes
+func replayTypescript(reader *bufio.Reader, timing io.Reader, w io.Writer, opts ReplayOptions) error {
+	scanner := bufio.NewScanner(timing)
+	for line := 1; scanner.Scan(); line++ {
+		var delay float64
+		var length int64
+		if _, err := fmt.Sscanf(scanner.Text(), "%f %d", &delay, &length); err != nil {
+			return fmt.Errorf("timing line %d: %v", line, err)
+		}
+		pause(time.Duration(delay*float64(time.Second)), opts)
+		if _, err := io.CopyN(w, reader, length); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// pause waits for a recorded pause, scaled by the playback speed and capped
+// by the idle limit
+func pause(d time.Duration, opts ReplayOptions) {
+	if opts.Instant || d <= 0 {
+		return
+	}
+	if opts.IdleLimit > 0 && d > opts.IdleLimit {
+		d = opts.IdleLimit
+	}
+	time.Sleep(time.Duration(float64(d) / opts.Speed))
+}
@@ -0,0 +1,122 @@
+// Package secrets resolves references to passwords kept in a password
+// manager, such as "op://vault/item/field", with the manager's command line
+// tool. xssh stores the references only, never the secrets they point to.
+package secrets
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// Reference formats, with the command resolving them:
+//
+//	op://vault/item/field   1Password: op read
+//	pass:path               pass: pass show, first line
+//	vault:path#field        HashiCorp Vault: vault kv get -field=field path
+const (
+	onePasswordPrefix = "op://"
+	passPrefix        = "pass:"
+	vaultPrefix       = "vault:"
+)
+
+// Formats lists the reference formats for messages
+const Formats = "op://vault/item/field, pass:path or vault:path#field"
+
+// IsReference reports whether value looks like a secret reference
+func IsReference(value string) bool {
+	for _, prefix := range []string{onePasswordPrefix, passPrefix, vaultPrefix} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check reports a reference that cannot be resolved for its form
+func Check(ref string) error {
+	switch {
+	case strings.HasPrefix(ref, onePasswordPrefix):
+		if strings.Count(strings.TrimPrefix(ref, onePasswordPrefix), "/") < 2 {
+			return fmt.Errorf("invalid 1Password reference %q, expected op://vault/item/field", ref)
+		}
+	case strings.HasPrefix(ref, passPrefix):
+		if strings.TrimPrefix(ref, passPrefix) == "" {
+			return fmt.Errorf("invalid pass reference %q, expected pass:path", ref)
+		}
+	case strings.HasPrefix(ref, vaultPrefix):
+		path, field, ok := strings.Cut(strings.TrimPrefix(ref, vaultPrefix), "#")
+		if !ok || path == "" || field == "" {
+			return fmt.Errorf("invalid Vault reference %q, expected vault:path#field", ref)
+		}
+	default:
+		return fmt.Errorf("%q is not a secret reference, expected %s", ref, Formats)
+	}
+	return nil
+}
+
+// Resolve reads the secret a reference points to
+func Resolve(ref string) (string, error) {
+	if err := Check(ref); err != nil {
+		return "", err
+	}
+
+	var name string
+	var args []string
+	switch {
+	case strings.HasPrefix(ref, onePasswordPrefix):
+		name, args = "op", []string{"read", "--no-newline", ref}
+	case strings.HasPrefix(ref, passPrefix):
+		name, args = "pass", []string{"show", strings.TrimPrefix(ref, passPrefix)}
+	default:
+		path, field, _ := strings.Cut(strings.TrimPrefix(ref, vaultPrefix), "#")
+		name, args = "vault", []string{"kv", "get", "-field=" + field, path}
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("cannot resolve %s: %s is not installed", ref, name)
+	}
+	var stderr strings.Builder
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("cannot resolve %s: %s", ref, message)
+		}
+		return "", fmt.Errorf("cannot resolve %s: %v", ref, err)
+	}
+
+	// pass keeps the password on the first line, notes below it
+	secret, _, _ := strings.Cut(string(output), "\n")
+	secret = strings.TrimRight(secret, "\r")
+	if secret == "" {
+		return "", fmt.Errorf("cannot resolve %s: the secret is empty", ref)
+	}
+	return secret, nil
+}
+
+// HostAuth resolves the password and key passphrase references of the host
+// called name. Secrets without a reference are left empty.
+func HostAuth(metadata *config.Metadata, name string) (ssh.Auth, error) {
+	var auth ssh.Auth
+	if metadata == nil {
+		return auth, nil
+	}
+	passwordRef, passphraseRef := metadata.SecretRefs(name)
+	var err error
+	if passwordRef != "" {
+		if auth.Password, err = Resolve(passwordRef); err != nil {
+			return auth, err
+		}
+	}
+	if passphraseRef != "" {
+		if auth.KeyPassword, err = Resolve(passphraseRef); err != nil {
+			return auth, err
+		}
+	}
+	return auth, nil
+}
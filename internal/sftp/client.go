@@ -0,0 +1,339 @@
+// Package sftp implements a minimal SFTP version 3 client on top of the
+// "sftp" subsystem of an SSH connection. Requests are sent one at a time.
+package sftp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// protocolVersion is the SFTP version requested from the server
+const protocolVersion = 3
+
+// maxPacketLength bounds the size of a packet accepted from the server
+const maxPacketLength = 256 * 1024
+
+// Client is an SFTP session over an SSH connection
+type Client struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+
+	mu     sync.Mutex // Serializes requests
+	nextID uint32
+}
+
+// NewClient starts the sftp subsystem on the connection and performs the
+// version handshake
+func NewClient(conn *ssh.Client) (*Client, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %v", err)
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("server does not support SFTP: %v", err)
+	}
+
+	c := &Client{
+		session: session,
+		stdin:   stdin,
+		stdout:  stdout,
+	}
+
+	if err := c.handshake(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Close ends the SFTP session. The SSH connection stays open.
+func (c *Client) Close() error {
+	return c.session.Close()
+}
+
+// handshake sends SSH_FXP_INIT and checks the server's version
+func (c *Client) handshake() error {
+	if err := c.writePacket(fxpInit, binary.BigEndian.AppendUint32(nil, protocolVersion)); err != nil {
+		return err
+	}
+
+	typ, payload, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if typ != fxpVersion {
+		return fmt.Errorf("sftp: expected version packet, got type %d", typ)
+	}
+
+	r := packetReader{data: payload}
+	version := r.readUint32()
+	if r.err != nil {
+		return r.err
+	}
+	if version < protocolVersion {
+		return fmt.Errorf("sftp: server speaks unsupported version %d", version)
+	}
+	return nil
+}
+
+// writePacket sends one packet of the given type
+func (c *Client) writePacket(typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 5+len(payload)), uint32(1+len(payload)))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := c.stdin.Write(packet)
+	return err
+}
+
+// readPacket reads one packet and returns its type and payload
+func (c *Client) readPacket() (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(c.stdout, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > maxPacketLength {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.stdout, body); err != nil {
+		return 0, nil, err
+	}
+	return body[0], body[1:], nil
+}
+
+// request sends a request and waits for its response. payload holds the
+// request fields after the request id; the returned reader is positioned
+// after the response id.
+func (c *Client) request(typ byte, payload []byte) (byte, *packetReader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(payload)), id)
+	if err := c.writePacket(typ, append(packet, payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	respType, respPayload, err := c.readPacket()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	r := &packetReader{data: respPayload}
+	if respID := r.readUint32(); r.err != nil || respID != id {
+		return 0, nil, fmt.Errorf("sftp: response id %d does not match request %d", respID, id)
+	}
+	return respType, r, nil
+}
+
+// requestStatus sends a request whose only expected response is a status
+func (c *Client) requestStatus(typ byte, payload []byte) error {
+	respType, r, err := c.request(typ, payload)
+	if err != nil {
+		return err
+	}
+	if respType != fxpStatus {
+		return unexpectedPacket(respType)
+	}
+	return r.readStatus()
+}
+
+// requestHandle sends a request that opens a file or directory handle
+func (c *Client) requestHandle(typ byte, payload []byte) (string, error) {
+	respType, r, err := c.request(typ, payload)
+	if err != nil {
+		return "", err
+	}
+	switch respType {
+	case fxpHandle:
+		handle := r.readString()
+		return handle, r.err
+	case fxpStatus:
+		if err := r.readStatus(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("sftp: server returned no handle")
+	default:
+		return "", unexpectedPacket(respType)
+	}
+}
+
+// requestAttrs sends a stat-like request and decodes the returned attributes
+func (c *Client) requestAttrs(typ byte, payload []byte, name string) (os.FileInfo, error) {
+	respType, r, err := c.request(typ, payload)
+	if err != nil {
+		return nil, err
+	}
+	switch respType {
+	case fxpAttrs:
+		info := r.readFileInfo(name)
+		return info, r.err
+	case fxpStatus:
+		if err := r.readStatus(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("sftp: server returned no attributes")
+	default:
+		return nil, unexpectedPacket(respType)
+	}
+}
+
+// closeHandle releases a file or directory handle
+func (c *Client) closeHandle(handle string) error {
+	return c.requestStatus(fxpClose, appendString(nil, handle))
+}
+
+// unexpectedPacket reports a response of the wrong type
+func unexpectedPacket(typ byte) error {
+	return fmt.Errorf("sftp: unexpected packet type %d", typ)
+}
+
+// RealPath resolves a path on the server to an absolute canonical path.
+// "." resolves to the login directory.
+func (c *Client) RealPath(p string) (string, error) {
+	respType, r, err := c.request(fxpRealpath, appendString(nil, p))
+	if err != nil {
+		return "", err
+	}
+	switch respType {
+	case fxpName:
+		if count := r.readUint32(); r.err == nil && count == 0 {
+			return "", fmt.Errorf("sftp: server returned no path for %s", p)
+		}
+		resolved := r.readString()
+		return resolved, r.err
+	case fxpStatus:
+		if err := r.readStatus(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("sftp: server returned no path for %s", p)
+	default:
+		return "", unexpectedPacket(respType)
+	}
+}
+
+// Stat returns the attributes of a file, following symbolic links
+func (c *Client) Stat(p string) (os.FileInfo, error) {
+	return c.requestAttrs(fxpStat, appendString(nil, p), path.Base(p))
+}
+
+// Lstat returns the attributes of a file without following symbolic links
+func (c *Client) Lstat(p string) (os.FileInfo, error) {
+	return c.requestAttrs(fxpLstat, appendString(nil, p), path.Base(p))
+}
+
+// ReadDir lists a directory, excluding "." and ".."
+func (c *Client) ReadDir(p string) ([]os.FileInfo, error) {
+	handle, err := c.requestHandle(fxpOpendir, appendString(nil, p))
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(handle)
+
+	var entries []os.FileInfo
+	for {
+		respType, r, err := c.request(fxpReaddir, appendString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+
+		switch respType {
+		case fxpName:
+			count := r.readUint32()
+			for i := uint32(0); i < count && r.err == nil; i++ {
+				name := r.readString()
+				r.readString() // longname, meant for display only
+				info := r.readFileInfo(name)
+				if name != "." && name != ".." {
+					entries = append(entries, info)
+				}
+			}
+			if r.err != nil {
+				return nil, r.err
+			}
+		case fxpStatus:
+			err := r.readStatus()
+			if statusErr, ok := err.(*StatusError); ok && statusErr.Code == statusEOF {
+				return entries, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, unexpectedPacket(respType)
+		}
+	}
+}
+
+// Remove deletes a file
+func (c *Client) Remove(p string) error {
+	return c.requestStatus(fxpRemove, appendString(nil, p))
+}
+
+// RemoveDir deletes an empty directory
+func (c *Client) RemoveDir(p string) error {
+	return c.requestStatus(fxpRmdir, appendString(nil, p))
+}
+
+// Mkdir creates a directory with the server's default permissions
+func (c *Client) Mkdir(p string) error {
+	payload := appendString(nil, p)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // No attributes
+	return c.requestStatus(fxpMkdir, payload)
+}
+
+// Rename renames a file or directory
+func (c *Client) Rename(oldPath, newPath string) error {
+	payload := appendString(nil, oldPath)
+	payload = appendString(payload, newPath)
+	return c.requestStatus(fxpRename, payload)
+}
+
+// Open opens a remote file for reading
+func (c *Client) Open(p string) (*File, error) {
+	return c.openFile(p, openRead)
+}
+
+// Create creates or truncates a remote file and opens it for writing
+func (c *Client) Create(p string) (*File, error) {
+	return c.openFile(p, openWrite|openCreate|openTruncate)
+}
+
+// openFile opens a remote file with the given SSH_FXP_OPEN flags
+func (c *Client) openFile(p string, flags uint32) (*File, error) {
+	payload := appendString(nil, p)
+	payload = binary.BigEndian.AppendUint32(payload, flags)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // No attributes
+
+	handle, err := c.requestHandle(fxpOpen, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &File{client: c, handle: handle, path: p}, nil
+}
@@ -0,0 +1,90 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path"
+)
+
+// maxDataLength is the largest chunk read or written per request. Servers
+// are only required to accept 32KB of data per packet.
+const maxDataLength = 32 * 1024
+
+// File is an open remote file. Reads and writes advance a shared offset.
+type File struct {
+	client *Client
+	handle string
+	path   string
+	offset uint64
+}
+
+// Read reads up to len(b) bytes from the file
+func (f *File) Read(b []byte) (int, error) {
+	if len(b) > maxDataLength {
+		b = b[:maxDataLength]
+	}
+
+	payload := appendString(nil, f.handle)
+	payload = binary.BigEndian.AppendUint64(payload, f.offset)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(len(b)))
+
+	respType, r, err := f.client.request(fxpRead, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	switch respType {
+	case fxpData:
+		data := r.readBytes()
+		if r.err != nil {
+			return 0, r.err
+		}
+		n := copy(b, data)
+		f.offset += uint64(n)
+		return n, nil
+	case fxpStatus:
+		err := r.readStatus()
+		if statusErr, ok := err.(*StatusError); ok && statusErr.Code == statusEOF {
+			return 0, io.EOF
+		}
+		if err == nil {
+			return 0, io.ErrNoProgress
+		}
+		return 0, err
+	default:
+		return 0, unexpectedPacket(respType)
+	}
+}
+
+// Write writes b to the file in chunks the server accepts
+func (f *File) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		chunk := b[written:]
+		if len(chunk) > maxDataLength {
+			chunk = chunk[:maxDataLength]
+		}
+
+		payload := appendString(nil, f.handle)
+		payload = binary.BigEndian.AppendUint64(payload, f.offset)
+		payload = appendString(payload, string(chunk))
+
+		if err := f.client.requestStatus(fxpWrite, payload); err != nil {
+			return written, err
+		}
+		f.offset += uint64(len(chunk))
+		written += len(chunk)
+	}
+	return written, nil
+}
+
+// Stat returns the attributes of the open file
+func (f *File) Stat() (os.FileInfo, error) {
+	return f.client.requestAttrs(fxpFstat, appendString(nil, f.handle), path.Base(f.path))
+}
+
+// Close releases the file handle
+func (f *File) Close() error {
+	return f.client.closeHandle(f.handle)
+}
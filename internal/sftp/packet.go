@@ -0,0 +1,224 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Packet types of SFTP protocol version 3 (draft-ietf-secsh-filexfer-02)
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpRead     = 5
+	fxpWrite    = 6
+	fxpLstat    = 7
+	fxpFstat    = 8
+	fxpOpendir  = 11
+	fxpReaddir  = 12
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpRmdir    = 15
+	fxpRealpath = 16
+	fxpStat     = 17
+	fxpRename   = 18
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpData     = 103
+	fxpName     = 104
+	fxpAttrs    = 105
+)
+
+// Status codes carried by SSH_FXP_STATUS
+const (
+	statusOK               = 0
+	statusEOF              = 1
+	statusNoSuchFile       = 2
+	statusPermissionDenied = 3
+)
+
+// Flags for SSH_FXP_OPEN
+const (
+	openRead     = 0x01
+	openWrite    = 0x02
+	openCreate   = 0x08
+	openTruncate = 0x10
+)
+
+// Flags describing which file attributes are present
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+	attrExtended    = 0x80000000
+)
+
+// errShortPacket is returned when a packet ends before all its fields
+var errShortPacket = errors.New("sftp: short packet")
+
+// StatusError is an error status returned by the server
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("sftp: %s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("sftp: request failed with code %d", e.Code)
+}
+
+// Is lets errors.Is match status errors against fs.ErrNotExist and
+// fs.ErrPermission
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case fs.ErrNotExist:
+		return e.Code == statusNoSuchFile
+	case fs.ErrPermission:
+		return e.Code == statusPermissionDenied
+	}
+	return false
+}
+
+// appendString appends an SFTP string (length-prefixed bytes)
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// packetReader decodes the fields of a packet payload in order. The first
+// decoding error is kept and later reads return zero values.
+type packetReader struct {
+	data []byte
+	err  error
+}
+
+func (r *packetReader) readUint32() uint32 {
+	if r.err != nil || len(r.data) < 4 {
+		r.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return v
+}
+
+func (r *packetReader) readUint64() uint64 {
+	if r.err != nil || len(r.data) < 8 {
+		r.err = errShortPacket
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data)
+	r.data = r.data[8:]
+	return v
+}
+
+func (r *packetReader) readBytes() []byte {
+	n := r.readUint32()
+	if r.err != nil || uint32(len(r.data)) < n {
+		r.err = errShortPacket
+		return nil
+	}
+	v := r.data[:n]
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *packetReader) readString() string {
+	return string(r.readBytes())
+}
+
+// readStatus decodes an SSH_FXP_STATUS payload, returning nil for OK
+func (r *packetReader) readStatus() error {
+	code := r.readUint32()
+	message := r.readString()
+	if r.err != nil {
+		return r.err
+	}
+	if code == statusOK {
+		return nil
+	}
+	return &StatusError{Code: code, Message: message}
+}
+
+// readFileInfo decodes a file attributes block for the named file
+func (r *packetReader) readFileInfo(name string) *fileInfo {
+	info := &fileInfo{name: name}
+
+	flags := r.readUint32()
+	if flags&attrSize != 0 {
+		info.size = int64(r.readUint64())
+	}
+	if flags&attrUIDGID != 0 {
+		r.readUint32()
+		r.readUint32()
+	}
+	if flags&attrPermissions != 0 {
+		info.mode = toFileMode(r.readUint32())
+	}
+	if flags&attrACModTime != 0 {
+		r.readUint32() // atime
+		info.modTime = time.Unix(int64(r.readUint32()), 0)
+	}
+	if flags&attrExtended != 0 {
+		count := r.readUint32()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			r.readString()
+			r.readString()
+		}
+	}
+	return info
+}
+
+// toFileMode converts POSIX st_mode bits to an os.FileMode
+func toFileMode(perm uint32) os.FileMode {
+	mode := os.FileMode(perm & 0777)
+
+	switch perm & 0170000 {
+	case 0040000:
+		mode |= os.ModeDir
+	case 0120000:
+		mode |= os.ModeSymlink
+	case 0010000:
+		mode |= os.ModeNamedPipe
+	case 0140000:
+		mode |= os.ModeSocket
+	case 0020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case 0060000:
+		mode |= os.ModeDevice
+	}
+
+	if perm&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+// fileInfo implements os.FileInfo for remote files
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,139 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// AgentKey describes a key loaded in the ssh-agent
+type AgentKey struct {
+	Comment     string
+	Type        string
+	Fingerprint string // SHA256 fingerprint as printed by ssh-add -l
+	PublicKey   ssh.PublicKey
+}
+
+// dialAgent connects to the agent listening on SSH_AUTH_SOCK
+func dialAgent() (agent.ExtendedAgent, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, fmt.Errorf("SSH_AUTH_SOCK is not set, is ssh-agent running?")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to ssh-agent: %v", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// ListAgentKeys returns the keys currently loaded in the ssh-agent
+func ListAgentKeys() ([]AgentKey, error) {
+	client, conn, err := dialAgent()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	loaded, err := client.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent keys: %v", err)
+	}
+
+	keys := make([]AgentKey, 0, len(loaded))
+	for _, key := range loaded {
+		keys = append(keys, AgentKey{
+			Comment:     key.Comment,
+			Type:        key.Type(),
+			Fingerprint: ssh.FingerprintSHA256(key),
+			PublicKey:   key,
+		})
+	}
+	return keys, nil
+}
+
+// AddKeyToAgent loads the private key at keyPath into the ssh-agent.
+// passphrase decrypts the key when it is encrypted; use IsPassphraseMissing
+// to detect that one is needed.
+func AddKeyToAgent(keyPath, passphrase string) error {
+	keyData, err := os.ReadFile(expandHome(keyPath))
+	if err != nil {
+		return err
+	}
+
+	var key interface{}
+	if passphrase != "" {
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(keyData, []byte(passphrase))
+	} else {
+		key, err = ssh.ParseRawPrivateKey(keyData)
+	}
+	if err != nil {
+		return err
+	}
+
+	client, conn, err := dialAgent()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Label the key like ssh-add does: the .pub comment, else the file name
+	comment := filepath.Base(keyPath)
+	if _, c, err := readPublicKey(keyPath); err == nil && c != "" {
+		comment = c
+	}
+
+	if err := client.Add(agent.AddedKey{PrivateKey: key, Comment: comment}); err != nil {
+		return fmt.Errorf("failed to add key to agent: %v", err)
+	}
+	return nil
+}
+
+// RemoveAgentKey removes a key from the ssh-agent
+func RemoveAgentKey(key ssh.PublicKey) error {
+	client, conn, err := dialAgent()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := client.Remove(key); err != nil {
+		return fmt.Errorf("failed to remove key from agent: %v", err)
+	}
+	return nil
+}
+
+// IsPassphraseMissing reports whether err means the key is encrypted and a
+// passphrase is required
+func IsPassphraseMissing(err error) bool {
+	var missing *ssh.PassphraseMissingError
+	return errors.As(err, &missing)
+}
+
+// readPublicKey parses the .pub file next to a private key
+func readPublicKey(keyPath string) (ssh.PublicKey, string, error) {
+	data, err := os.ReadFile(expandHome(keyPath) + ".pub")
+	if err != nil {
+		return nil, "", err
+	}
+	key, comment, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, comment, nil
+}
+
+// PublicKeyFingerprint returns the SHA256 fingerprint of the .pub file next
+// to a private key, or "" when it cannot be read
+func PublicKeyFingerprint(keyPath string) string {
+	key, _, err := readPublicKey(keyPath)
+	if err != nil {
+		return ""
+	}
+	return ssh.FingerprintSHA256(key)
+}
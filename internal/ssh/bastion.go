@@ -0,0 +1,13 @@
+package ssh
+
+import "xssh/internal/config"
+
+// bastionPolicy is the [bastions] section of the xssh config
+var bastionPolicy config.BastionPolicy
+
+// SetBastionPolicy makes the connections xssh opens, and the ssh, scp and
+// sftp commands it runs, go through the jump host the policy requires for
+// hosts that set none
+func SetBastionPolicy(policy config.BastionPolicy) {
+	bastionPolicy = policy
+}
@@ -1,20 +1,87 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/atotto/clipboard"
 	"xssh/internal/config"
 )
 
-// ConnectToHost connects to SSH host using system ssh command
-// This will properly handle terminal I/O and restore terminal state
-func ConnectToHost(host config.SSHHost) error {
-	args := []string{"ssh"}
+// commandWaitDelay is how long an interrupted ssh command has to exit
+const commandWaitDelay = 5 * time.Second
+
+// AskpassPasswordEnv holds the password for ssh to get from AskpassEnv's
+// program
+const AskpassPasswordEnv = "XSSH_ASKPASS_PASSWORD"
+
+// AskpassPassphraseEnv holds the key passphrase for ssh to get from
+// AskpassAuthEnv's program, which answers other prompts with the password
+const AskpassPassphraseEnv = "XSSH_ASKPASS_PASSPHRASE"
+
+// AskpassEnv returns the environment making ssh ask program, instead of the
+// terminal, for passwords and passphrases. program is expected to print
+// the value of AskpassPasswordEnv.
+func AskpassEnv(program, password string) []string {
+	return append(os.Environ(),
+		"SSH_ASKPASS="+program,
+		"SSH_ASKPASS_REQUIRE=force",
+		AskpassPasswordEnv+"="+password,
+	)
+}
+
+// AskpassAuthEnv returns the environment making ssh ask program for the
+// password and key passphrase of auth, like AskpassEnv
+func AskpassAuthEnv(program string, auth Auth) []string {
+	env := AskpassEnv(program, auth.Password)
+	if auth.KeyPassword != "" {
+		env = append(env, AskpassPassphraseEnv+"="+auth.KeyPassword)
+	}
+	return env
+}
+
+// Command returns an ssh command for the host that runs as a child process,
+// so the caller gets control back when the session ends. extraArgs are
+// given to ssh after the destination, as a remote command or further
+// options.
+func Command(host config.SSHHost, extraArgs ...string) (*exec.Cmd, error) {
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return nil, fmt.Errorf("ssh command not found: %v", err)
+	}
+	args := append(commandArgs(host), extraArgs...)
+	slog.Debug("running ssh", "path", sshPath, "args", args)
+	return exec.Command(sshPath, args...), nil
+}
+
+// CommandContext returns an ssh command like Command that is interrupted
+// when ctx is canceled, and killed if it has not exited commandWaitDelay
+// later
+func CommandContext(ctx context.Context, host config.SSHHost, extraArgs ...string) (*exec.Cmd, error) {
+	sshPath, err := exec.LookPath("ssh")
+	if err != nil {
+		return nil, fmt.Errorf("ssh command not found: %v", err)
+	}
+	args := append(commandArgs(host), extraArgs...)
+	slog.Debug("running ssh", "path", sshPath, "args", args)
+	cmd := exec.CommandContext(ctx, sshPath, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = commandWaitDelay
+	return cmd, nil
+}
+
+// commandArgs returns the ssh arguments, without the program name, for
+// connecting to a host
+func commandArgs(host config.SSHHost) []string {
+	host = bastionPolicy.Apply(host)
+	args := append(append(configFileArgs(), keepAliveArgs()...), retryArgs(host)...)
 
 	if host.User != "" {
 		args = append(args, "-l", host.User)
@@ -28,23 +95,18 @@ func ConnectToHost(host config.SSHHost) error {
 		args = append(args, "-i", host.Identity)
 	}
 
-	args = append(args, host.Host)
-
-	// Find ssh binary
-	sshPath, err := exec.LookPath("ssh")
-	if err != nil {
-		return fmt.Errorf("ssh command not found: %v", err)
+	if host.ProxyJump != "" {
+		args = append(args, "-J", host.ProxyJump)
 	}
 
-	// Use syscall.Exec to replace current process with SSH
-	// This ensures proper terminal handling and I/O
-	return syscall.Exec(sshPath, args, os.Environ())
+	return append(args, host.Host)
 }
 
 // BuildSSHCommand builds the SSH command string for a host
 func BuildSSHCommand(host config.SSHHost) string {
-	var parts []string
-	parts = append(parts, "ssh")
+	host = bastionPolicy.Apply(host)
+	parts := append(append([]string{"ssh"}, configFileArgs()...), keepAliveArgs()...)
+	parts = append(parts, retryArgs(host)...)
 
 	if host.User != "" {
 		parts = append(parts, "-l", host.User)
@@ -58,19 +120,119 @@ func BuildSSHCommand(host config.SSHHost) string {
 		parts = append(parts, "-i", host.Identity)
 	}
 
+	if host.ProxyJump != "" {
+		parts = append(parts, "-J", host.ProxyJump)
+	}
+
 	parts = append(parts, host.Host)
 
 	return strings.Join(parts, " ")
 }
 
+// ShareableCommand builds an ssh command for a host that runs without xssh
+// and its SSH config, for someone else to paste: the destination is spelled
+// user@address and jump hosts by their addresses rather than their aliases.
+// options go before the destination.
+func ShareableCommand(host config.SSHHost, options ...string) string {
+	host = bastionPolicy.Apply(host)
+	parts := append([]string{"ssh"}, options...)
+
+	if host.Port != "22" && host.Port != "" {
+		parts = append(parts, "-p", host.Port)
+	}
+
+	if host.Identity != "" {
+		parts = append(parts, "-i", host.Identity)
+	}
+
+	if host.ProxyJump != "" {
+		var hops []string
+		for _, hop := range strings.Split(host.ProxyJump, ",") {
+			jump := ResolveJumpHost(strings.TrimSpace(hop))
+			address := jump.Host
+			if jump.Port != "22" && jump.Port != "" {
+				address += ":" + jump.Port
+			}
+			hops = append(hops, jump.User+"@"+address)
+		}
+		parts = append(parts, "-J", strings.Join(hops, ","))
+	}
+
+	destination := host.Host
+	if host.User != "" {
+		destination = host.User + "@" + destination
+	}
+	return strings.Join(append(parts, destination), " ")
+}
+
+// configFileArgs points ssh, scp and sftp at the SSH config file given by
+// XSSH_SSH_CONFIG, so jump hosts are looked up there too
+func configFileArgs() []string {
+	if path := config.SSHConfigOverride(); path != "" {
+		return []string{"-F", path}
+	}
+	return nil
+}
+
+// fileTransferArgs returns the options scp and sftp take for a host, which
+// spell the port option -P
+func fileTransferArgs(host config.SSHHost) []string {
+	host = bastionPolicy.Apply(host)
+	args := append(append(configFileArgs(), keepAliveArgs()...), retryArgs(host)...)
+
+	if host.Port != "22" && host.Port != "" {
+		args = append(args, "-P", host.Port)
+	}
+
+	if host.Identity != "" {
+		args = append(args, "-i", host.Identity)
+	}
+
+	if host.ProxyJump != "" {
+		args = append(args, "-J", host.ProxyJump)
+	}
+
+	return args
+}
+
+// userAtHost returns user@host, or the host alone when no user is set
+func userAtHost(host config.SSHHost) string {
+	if host.User == "" {
+		return host.Host
+	}
+	return host.User + "@" + host.Host
+}
+
+// BuildSCPCommand builds an scp command copying a local file to the host,
+// with placeholders for the paths
+func BuildSCPCommand(host config.SSHHost) string {
+	parts := append([]string{"scp"}, fileTransferArgs(host)...)
+	parts = append(parts, "<local-file>", userAtHost(host)+":<remote-path>")
+	return strings.Join(parts, " ")
+}
+
+// BuildSFTPCommand builds the sftp command for a host
+func BuildSFTPCommand(host config.SSHHost) string {
+	parts := append([]string{"sftp"}, fileTransferArgs(host)...)
+	parts = append(parts, userAtHost(host))
+	return strings.Join(parts, " ")
+}
+
+// ReadPublicKey returns the contents of the .pub file next to a private key
+func ReadPublicKey(keyPath string) (string, error) {
+	data, err := os.ReadFile(expandHome(keyPath) + ".pub")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// CopyToClipboard copies text to the system clipboard
+func CopyToClipboard(text string) error {
+	return clipboard.WriteAll(text)
+}
+
 // CopySSHCommand copies SSH command to clipboard
 func CopySSHCommand(host config.SSHHost) error {
-	command := BuildSSHCommand(host)
-	return clipboard.WriteAll(command)
+	return CopyToClipboard(BuildSSHCommand(host))
 }
-
-// ExecSSH replaces current process with SSH connection
-// Deprecated: Use ConnectToHost instead
-func ExecSSH(host config.SSHHost) error {
-	return ConnectToHost(host)
-}
\ No newline at end of file
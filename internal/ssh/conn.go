@@ -0,0 +1,90 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+)
+
+// Conn is an open SSH connection, reduced to what xssh does with one:
+// dialing out of the remote host, listening on it and running commands.
+// NewConn adapts an *ssh.Client; internal/testsupport has in-memory fakes.
+type Conn interface {
+	// DialContext opens a connection from the remote host to addr
+	DialContext(ctx context.Context, n, addr string) (net.Conn, error)
+	// Listen listens on addr of the remote host
+	Listen(n, addr string) (net.Listener, error)
+	// OpenSession opens a session to run one command on the remote host
+	OpenSession() (Session, error)
+	// SendRequest sends a global request, as used to check the connection
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+	Close() error
+}
+
+// Session runs one command on the remote host
+type Session interface {
+	// Run runs cmd, writing its output to stdout and stderr when they are
+	// not nil. A command exiting with a non-zero status returns an error.
+	Run(cmd string, stdout, stderr io.Writer) error
+	Close() error
+}
+
+// Dialer opens SSH connections
+type Dialer interface {
+	// Dial connects to host, logging in with auth when its key alone is not
+	// enough, and gives up when ctx is canceled
+	Dial(ctx context.Context, host config.SSHHost, auth Auth) (Conn, error)
+}
+
+// DialerFunc adapts a function to a Dialer
+type DialerFunc func(ctx context.Context, host config.SSHHost, auth Auth) (Conn, error)
+
+// Dial calls f
+func (f DialerFunc) Dial(ctx context.Context, host config.SSHHost, auth Auth) (Conn, error) {
+	return f(ctx, host, auth)
+}
+
+// DefaultDialer connects with DialAuthContext
+var DefaultDialer Dialer = DialerFunc(func(ctx context.Context, host config.SSHHost, auth Auth) (Conn, error) {
+	client, err := DialAuthContext(ctx, host, auth)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(client), nil
+})
+
+// NewConn returns client as a Conn. Closing the Conn closes client.
+func NewConn(client *ssh.Client) Conn {
+	return clientConn{client}
+}
+
+// clientConn is the Conn of an *ssh.Client
+type clientConn struct {
+	*ssh.Client
+}
+
+func (c clientConn) OpenSession() (Session, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return clientSession{session}, nil
+}
+
+// clientSession is the Session of an *ssh.Session
+type clientSession struct {
+	session *ssh.Session
+}
+
+func (s clientSession) Run(cmd string, stdout, stderr io.Writer) error {
+	s.session.Stdout = stdout
+	s.session.Stderr = stderr
+	return s.session.Run(cmd)
+}
+
+func (s clientSession) Close() error {
+	return s.session.Close()
+}
@@ -0,0 +1,185 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+)
+
+// dialTimeout bounds how long Dial waits for the TCP connection and handshake
+const dialTimeout = 10 * time.Second
+
+// Auth holds the secrets for logging in to a host that its identity file
+// alone does not cover
+type Auth struct {
+	KeyPassword string `json:"key_password,omitempty"` // Decrypts the identity file when it is encrypted
+	Password    string `json:"password,omitempty"`     // Login password, tried after the key
+}
+
+// Dial opens an SSH connection to the host, authenticating with its identity
+// file. keyPassword decrypts the identity file when it is encrypted.
+func Dial(host config.SSHHost, keyPassword string) (*ssh.Client, error) {
+	return DialAuthContext(context.Background(), host, Auth{KeyPassword: keyPassword})
+}
+
+// DialContext opens an SSH connection like Dial, giving up when ctx is
+// canceled
+func DialContext(ctx context.Context, host config.SSHHost, keyPassword string) (*ssh.Client, error) {
+	return DialAuthContext(ctx, host, Auth{KeyPassword: keyPassword})
+}
+
+// DialAuth opens an SSH connection to the host like Dial, also logging in
+// with auth.Password, as a password or keyboard-interactive answer, when the
+// key is missing or refused. Failures to reach the host are retried as its
+// retry policy allows.
+func DialAuth(host config.SSHHost, auth Auth) (*ssh.Client, error) {
+	return DialAuthContext(context.Background(), host, auth)
+}
+
+// DialAuthContext opens an SSH connection like DialAuth, giving up, retries
+// included, when ctx is canceled. Canceling ctx once the connection is open
+// leaves it open.
+func DialAuthContext(ctx context.Context, host config.SSHHost, auth Auth) (*ssh.Client, error) {
+	config, err := clientConfig(host, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := withRetry(ctx, host, func() (*ssh.Client, error) {
+		conn, err := dialHost(ctx, host, dialTimeout)
+		if err != nil {
+			slog.Warn("SSH connection failed", "host", host.Name, "address", hostAddress(host), "error", err)
+			return nil, err
+		}
+		client, err := handshake(ctx, conn, hostAddress(host), config)
+		if err != nil {
+			slog.Warn("SSH handshake failed", "host", host.Name, "address", hostAddress(host), "error", err)
+			return nil, err
+		}
+		return client, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	slog.Debug("SSH connection established", "host", host.Name, "address", hostAddress(host))
+
+	go keepAlive(client, host.Name)
+	return client, nil
+}
+
+// DialThrough opens an SSH connection to host tunneled through via, the
+// open connection to the hop before it, logging in like DialAuthContext.
+// The host's own ProxyJump is not used. Closing the connection leaves via
+// open.
+func DialThrough(ctx context.Context, via Conn, host config.SSHHost, auth Auth) (Conn, error) {
+	config, err := clientConfig(host, auth)
+	if err != nil {
+		return nil, err
+	}
+	address := hostAddress(host)
+	conn, err := via.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach %s through the previous hop: %w", address, err)
+	}
+	client, err := handshake(ctx, conn, address, config)
+	if err != nil {
+		slog.Warn("SSH handshake failed", "host", host.Name, "address", address, "error", err)
+		return nil, fmt.Errorf("failed to connect to SSH server: %w", err)
+	}
+	slog.Debug("SSH connection established through a hop", "host", host.Name, "address", address)
+
+	go keepAlive(client, host.Name)
+	return NewConn(client), nil
+}
+
+// clientConfig returns the settings logging in to host with its identity
+// file and auth
+func clientConfig(host config.SSHHost, auth Auth) (*ssh.ClientConfig, error) {
+	var methods []ssh.AuthMethod
+
+	if host.Identity != "" {
+		key, err := loadPrivateKey(host.Identity, auth.KeyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load private key: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(key))
+	}
+	if auth.Password != "" {
+		password := auth.Password
+		methods = append(methods, ssh.Password(password),
+			ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+				answers := make([]string, len(questions))
+				for i := range answers {
+					answers[i] = password
+				}
+				return answers, nil
+			}))
+	}
+
+	return &ssh.ClientConfig{
+		User:            host.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         dialTimeout,
+	}, nil
+}
+
+// handshake logs in over conn, closing it when ctx is canceled before the
+// handshake is done
+func handshake(ctx context.Context, conn net.Conn, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if !stop() {
+		if err == nil {
+			clientConn.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// hostAddress returns the host:port address of a host, defaulting to port 22
+func hostAddress(host config.SSHHost) string {
+	port := host.Port
+	if port == "" {
+		port = "22"
+	}
+	return net.JoinHostPort(host.Host, port)
+}
+
+// loadPrivateKey loads and parses a private key with optional password
+func loadPrivateKey(keyPath, keyPassword string) (ssh.Signer, error) {
+	keyData, err := os.ReadFile(expandHome(keyPath))
+	if err != nil {
+		return nil, err
+	}
+
+	if keyPassword != "" {
+		return ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(keyPassword))
+	}
+	return ssh.ParsePrivateKey(keyData)
+}
+
+// expandHome replaces a leading ~ in a path with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -0,0 +1,74 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ListContainersCommand is the remote command listing the running Docker
+// containers, one JSON object per line
+const ListContainersCommand = `docker ps --format '{{json .}}'`
+
+// DefaultContainerShell is the shell opened in a container when none is
+// given
+const DefaultContainerShell = "sh"
+
+// Container is a running Docker container of a remote host
+type Container struct {
+	ID     string `json:"ID"`
+	Name   string `json:"Names"`
+	Image  string `json:"Image"`
+	Status string `json:"Status"`
+}
+
+// ListContainers lists the running Docker containers of the host conn is
+// connected to
+func ListContainers(conn Conn) ([]Container, error) {
+	session, err := conn.OpenSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	err = session.Run(ListContainersCommand, &stdout, &stderr)
+	output := stdout.Bytes()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("docker ps failed: %s", message)
+		}
+		return nil, fmt.Errorf("docker ps failed: %v", err)
+	}
+	return ParseContainers(output)
+}
+
+// ParseContainers reads the output of ListContainersCommand
+func ParseContainers(output []byte) ([]Container, error) {
+	var containers []Container
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var container Container
+		if err := json.Unmarshal(line, &container); err != nil {
+			return nil, fmt.Errorf("cannot read the output of docker ps: %v", err)
+		}
+		containers = append(containers, container)
+	}
+	return containers, scanner.Err()
+}
+
+// ContainerShellArgs returns the ssh arguments, after the destination, that
+// open shell in a container with a terminal
+func ContainerShellArgs(container, shell string) []string {
+	if shell == "" {
+		shell = DefaultContainerShell
+	}
+	return []string{"-t", fmt.Sprintf("docker exec -it %s %s", container, shell)}
+}
@@ -0,0 +1,55 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"xssh/internal/config"
+)
+
+// HostKeyMismatchError is returned when a server presents a different key
+// than the one recorded for it in known_hosts, typically after the host was
+// reinstalled
+type HostKeyMismatchError struct {
+	Host string // Address as passed to the dialer, e.g. "example.com:22"
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key for %s does not match the one in known_hosts (was the host reinstalled?)", e.Host)
+}
+
+// IsHostKeyMismatch reports whether err was caused by a changed host key
+func IsHostKeyMismatch(err error) bool {
+	var mismatch *HostKeyMismatchError
+	return errors.As(err, &mismatch)
+}
+
+// hostKeyCallback checks server keys against ~/.ssh/known_hosts. Hosts that
+// are not listed are accepted, but a key that differs from a recorded one is
+// rejected with a HostKeyMismatchError.
+func hostKeyCallback() ssh.HostKeyCallback {
+	knownHostsPath, err := config.KnownHostsPath()
+	if err != nil {
+		return ssh.InsecureIgnoreHostKey()
+	}
+	check, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		// No readable known_hosts file, nothing to compare against
+		return ssh.InsecureIgnoreHostKey()
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := check(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return nil
+			}
+			return &HostKeyMismatchError{Host: hostname}
+		}
+		return err
+	}
+}
@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+)
+
+// jumpConn is a connection tunneled through a jump host. Closing it also
+// closes the connection to the jump host.
+type jumpConn struct {
+	net.Conn
+	jump *ssh.Client
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	c.jump.Close()
+	return err
+}
+
+// dialHost opens the TCP connection to a host's SSH port, directly or, when
+// the host has a ProxyJump or the bastion policy requires one, through its
+// jump hosts
+func dialHost(ctx context.Context, host config.SSHHost, timeout time.Duration) (net.Conn, error) {
+	host = bastionPolicy.Apply(host)
+	address := hostAddress(host)
+	slog.Debug("dialing", "address", address, "jump", host.ProxyJump)
+	if host.ProxyJump == "" {
+		dialer := net.Dialer{Timeout: timeout}
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+
+	jump, err := dialJumpHosts(ctx, host.ProxyJump, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := jump.DialContext(ctx, "tcp", address)
+	if err != nil {
+		jump.Close()
+		return nil, fmt.Errorf("jump host could not reach %s: %w", address, err)
+	}
+	return &jumpConn{Conn: conn, jump: jump}, nil
+}
+
+// dialJumpHosts connects to the hosts of a ProxyJump value in turn, each
+// through the previous one, and returns the client of the last one
+func dialJumpHosts(ctx context.Context, spec string, timeout time.Duration) (*ssh.Client, error) {
+	var client *ssh.Client
+	for _, hop := range strings.Split(spec, ",") {
+		jump := ResolveJumpHost(strings.TrimSpace(hop))
+		address := hostAddress(jump)
+		slog.Debug("connecting through jump host", "hop", jump.Name, "address", address)
+
+		var auth []ssh.AuthMethod
+		if jump.Identity != "" {
+			key, err := loadPrivateKey(jump.Identity, "")
+			if err != nil {
+				closeClient(client)
+				return nil, fmt.Errorf("jump host %s: failed to load private key: %w", jump.Name, err)
+			}
+			auth = append(auth, ssh.PublicKeys(key))
+		}
+		clientConfig := &ssh.ClientConfig{
+			User:            jump.User,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback(),
+			Timeout:         timeout,
+		}
+
+		var conn net.Conn
+		var err error
+		if client == nil {
+			dialer := net.Dialer{Timeout: timeout}
+			conn, err = dialer.DialContext(ctx, "tcp", address)
+		} else {
+			conn, err = client.DialContext(ctx, "tcp", address)
+			if err == nil {
+				conn = &jumpConn{Conn: conn, jump: client}
+			}
+		}
+		if err != nil {
+			closeClient(client)
+			return nil, fmt.Errorf("jump host %s: %w", jump.Name, err)
+		}
+
+		client, err = handshake(ctx, conn, address, clientConfig)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", jump.Name, err)
+		}
+	}
+	return client, nil
+}
+
+// ResolveJumpHost turns one hop of a ProxyJump value or of the hops of a
+// forwarding, [user@]host[:port], into a host. A host that is an alias in
+// ~/.ssh/config takes its settings from there.
+func ResolveJumpHost(hop string) config.SSHHost {
+	user, hostPort, found := strings.Cut(hop, "@")
+	if !found {
+		user, hostPort = "", hop
+	}
+	name, port := hostPort, ""
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		name, port = h, p
+	}
+
+	jump := config.SSHHost{Name: name, Host: name}
+	if sshConfig, err := config.LoadSSHConfig(); err == nil {
+		if index := sshConfig.FindHost(name); index >= 0 {
+			jump = sshConfig.Hosts[index]
+		}
+	}
+	if user != "" {
+		jump.User = user
+	}
+	if port != "" {
+		jump.Port = port
+	}
+	if jump.User == "" {
+		jump.User = os.Getenv("USER")
+	}
+	return jump
+}
+
+// closeClient closes a client that may not have been created yet
+func closeClient(client *ssh.Client) {
+	if client != nil {
+		client.Close()
+	}
+}
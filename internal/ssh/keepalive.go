@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Keepalive settings from the [connection] section of the xssh config. A
+// zero interval leaves keepalives to ssh's own configuration.
+var (
+	keepAliveInterval time.Duration
+	keepAliveCountMax = 3
+)
+
+// SetKeepAlive makes connections send a keepalive request every interval
+// and give up after countMax of them go unanswered, both the connections
+// xssh opens itself and the ssh, scp and sftp commands it runs
+func SetKeepAlive(interval time.Duration, countMax int) {
+	keepAliveInterval = interval
+	if countMax > 0 {
+		keepAliveCountMax = countMax
+	}
+}
+
+// keepAliveArgs returns the ssh options for the keepalive settings
+func keepAliveArgs() []string {
+	if keepAliveInterval <= 0 {
+		return nil
+	}
+	seconds := max(1, int(keepAliveInterval.Round(time.Second).Seconds()))
+	return []string{
+		"-o", fmt.Sprintf("ServerAliveInterval=%d", seconds),
+		"-o", fmt.Sprintf("ServerAliveCountMax=%d", keepAliveCountMax),
+	}
+}
+
+// keepAlive sends keepalive requests on client until it is closed, and
+// closes it when the server stops answering
+func keepAlive(client *ssh.Client, name string) {
+	if keepAliveInterval <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	missed := 0
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			missed++
+			slog.Debug("keepalive failed", "host", name, "missed", missed, "error", err)
+			if missed >= keepAliveCountMax {
+				slog.Warn("server stopped answering keepalives, closing the connection", "host", name)
+				client.Close()
+				return
+			}
+			continue
+		}
+		missed = 0
+	}
+}
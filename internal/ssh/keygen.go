@@ -0,0 +1,107 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultRSABits is the RSA key size used when none is given
+const defaultRSABits = 4096
+
+// KeyGenOptions describes an SSH key pair to generate
+type KeyGenOptions struct {
+	Type       string // "ed25519" or "rsa"
+	Bits       int    // RSA key size, 0 for the default
+	Path       string // Private key path; the public key goes to Path + ".pub"
+	Passphrase string // Encrypts the private key when not empty
+	Comment    string
+}
+
+// GenerateKeyPair creates a new key pair in OpenSSH format. Existing files
+// are never overwritten.
+func GenerateKeyPair(opts KeyGenOptions) error {
+	publicKeyPath := opts.Path + ".pub"
+	for _, path := range []string{opts.Path, publicKeyPath} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+	switch opts.Type {
+	case "ed25519":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		privateKey, publicKey = priv, pub
+	case "rsa":
+		bits := opts.Bits
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return err
+		}
+		privateKey, publicKey = priv, &priv.PublicKey
+	default:
+		return fmt.Errorf("unsupported key type: %s", opts.Type)
+	}
+
+	var block *pem.Block
+	var err error
+	if opts.Passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(privateKey, opts.Comment, []byte(opts.Passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(privateKey, opts.Comment)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %v", err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %v", err)
+	}
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+	if opts.Comment != "" {
+		authorizedKey += " " + opts.Comment
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0700); err != nil {
+		return err
+	}
+	if err := writeNewFile(opts.Path, pem.EncodeToMemory(block), 0600); err != nil {
+		return err
+	}
+	if err := writeNewFile(publicKeyPath, []byte(authorizedKey+"\n"), 0644); err != nil {
+		os.Remove(opts.Path)
+		return err
+	}
+	return nil
+}
+
+// writeNewFile writes data to a file that must not exist yet
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,152 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+)
+
+// ProbeFailure tells why a probe of a host failed
+type ProbeFailure int
+
+const (
+	ProbeOK      ProbeFailure = iota
+	ProbeDNS                  // The host name does not resolve
+	ProbeRefused              // The connection was refused, or the host or network is unreachable
+	ProbeTimeout              // No answer in time
+	ProbeAuth                 // The server rejected every key
+	ProbeHostKey              // The host key differs from the one in known_hosts
+	ProbeOther
+)
+
+// String returns a short name for the failure, as used in JSON output
+func (f ProbeFailure) String() string {
+	switch f {
+	case ProbeOK:
+		return "ok"
+	case ProbeDNS:
+		return "dns"
+	case ProbeRefused:
+		return "refused"
+	case ProbeTimeout:
+		return "timeout"
+	case ProbeAuth:
+		return "auth"
+	case ProbeHostKey:
+		return "host-key"
+	default:
+		return "error"
+	}
+}
+
+// ProbeResult is the outcome of Probe
+type ProbeResult struct {
+	Failure  ProbeFailure
+	Err      error
+	Duration time.Duration
+}
+
+// defaultIdentities are the keys tried when a host has no IdentityFile, as
+// ssh does
+var defaultIdentities = []string{"~/.ssh/id_ed25519", "~/.ssh/id_ecdsa", "~/.ssh/id_rsa"}
+
+// DefaultIdentity returns the first of the default keys that exists, or
+// ~/.ssh/id_ed25519 when there is none yet
+func DefaultIdentity() string {
+	for _, identity := range defaultIdentities {
+		if _, err := os.Stat(expandHome(identity)); err == nil {
+			return identity
+		}
+	}
+	return defaultIdentities[0]
+}
+
+// KeyNeedsPassphrase reports whether the private key at keyPath is encrypted
+func KeyNeedsPassphrase(keyPath string) bool {
+	_, err := loadPrivateKey(keyPath, "")
+	return IsPassphraseMissing(err)
+}
+
+// Probe connects and authenticates to a host, through its jump hosts, without
+// opening a session. It authenticates with the host's identity file, or the
+// default keys when it has none, and the keys in ssh-agent. Encrypted keys
+// are skipped since there is nobody to ask for the passphrase.
+func Probe(ctx context.Context, host config.SSHHost, timeout time.Duration) ProbeResult {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var signers []ssh.Signer
+	identities := defaultIdentities
+	if host.Identity != "" {
+		identities = []string{host.Identity}
+	}
+	for _, identity := range identities {
+		if signer, err := loadPrivateKey(identity, ""); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if agentClient, conn, err := dialAgent(); err == nil {
+		defer conn.Close()
+		if agentSigners, err := agentClient.Signers(); err == nil {
+			signers = append(signers, agentSigners...)
+		}
+	}
+
+	user := host.User
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	clientConfig := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         timeout,
+	}
+
+	client, err := dialContext(ctx, host, clientConfig, nil)
+	result := ProbeResult{Duration: time.Since(started), Err: err}
+	if err != nil {
+		result.Failure = classifyProbeError(ctx, err)
+		return result
+	}
+	client.Close()
+	return result
+}
+
+// classifyProbeError finds out why a probe failed
+func classifyProbeError(ctx context.Context, err error) ProbeFailure {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case IsHostKeyMismatch(err):
+		return ProbeHostKey
+	case errors.As(err, &dnsErr) && !dnsErr.IsTimeout:
+		return ProbeDNS
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return ProbeRefused
+	case errors.Is(err, context.DeadlineExceeded), ctx.Err() != nil,
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ProbeTimeout
+	case strings.Contains(err.Error(), "unable to authenticate"):
+		return ProbeAuth
+	}
+	return ProbeOther
+}
+
+// ClassifyError finds out why connecting to a host failed, for connections
+// made outside Probe
+func ClassifyError(err error) ProbeFailure {
+	if err == nil {
+		return ProbeOK
+	}
+	return classifyProbeError(context.Background(), err)
+}
@@ -0,0 +1,150 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"xssh/internal/config"
+)
+
+// retryConfig is the retry policy handed over by SetRetryConfig
+var retryConfig = config.RetryConfig{Default: config.DefaultRetryPolicy()}
+
+// SetRetryConfig sets how often connections xssh opens, and the ssh, scp and
+// sftp commands it runs, try to reach a host, and when connections to a
+// failing host fail fast instead
+func SetRetryConfig(c config.RetryConfig) {
+	retryConfig = c
+}
+
+// CircuitOpenError is returned instead of connecting to a host whose last
+// connections all failed, until the breaker cooldown has passed
+type CircuitOpenError struct {
+	Address  string
+	Failures int
+	Until    time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%d connections to %s failed in a row, not trying again for %s",
+		e.Failures, e.Address, time.Until(e.Until).Round(time.Second))
+}
+
+// breaker counts the failed connections in a row to one address
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// breakers holds the circuit breakers of this process, keyed by address
+var breakers = struct {
+	sync.Mutex
+	hosts map[string]*breaker
+}{hosts: map[string]*breaker{}}
+
+// withRetry calls dial until it connects to host, as often as the retry
+// policy of the host allows. Only failures to reach the host are retried and
+// counted by its circuit breaker; a refused login or a changed host key
+// fails at once.
+func withRetry[T any](ctx context.Context, host config.SSHHost, dial func() (T, error)) (T, error) {
+	var client T
+	policy := retryConfig.For(host)
+	address := hostAddress(host)
+	if err := checkBreaker(address); err != nil {
+		return client, err
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		client, err = dial()
+		if err == nil || attempt >= policy.Attempts || !retryable(ctx, err) {
+			break
+		}
+		delay := jitter(policy.Delay(attempt), policy.Jitter)
+		slog.Info("connection failed, retrying", "host", host.Name, "attempt", attempt, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return client, err
+		case <-timer.C:
+		}
+	}
+	recordBreaker(address, policy, err, retryable(ctx, err))
+	return client, err
+}
+
+// retryable reports whether err is a failure to reach the host that may go
+// away by itself, as opposed to a refused login, a changed host key or an
+// unknown host name
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	switch classifyProbeError(ctx, err) {
+	case ProbeRefused, ProbeTimeout, ProbeOther:
+		return true
+	}
+	return false
+}
+
+// jitter shortens or lengthens delay by a random share of up to percent
+func jitter(delay time.Duration, percent int) time.Duration {
+	if percent <= 0 || delay <= 0 {
+		return delay
+	}
+	spread := int64(delay) * int64(percent) / 100
+	return delay + time.Duration(rand.Int64N(2*spread+1)-spread)
+}
+
+// checkBreaker returns a CircuitOpenError while the circuit of address is
+// open. Once the cooldown has passed one connection is let through, and the
+// circuit opens again if it fails.
+func checkBreaker(address string) error {
+	breakers.Lock()
+	defer breakers.Unlock()
+	b := breakers.hosts[address]
+	if b == nil || !time.Now().Before(b.openUntil) {
+		return nil
+	}
+	return &CircuitOpenError{Address: address, Failures: b.failures, Until: b.openUntil}
+}
+
+// recordBreaker counts the outcome of connecting to address. Failures that
+// say nothing about whether the host is reachable leave the count alone.
+func recordBreaker(address string, policy config.RetryPolicy, err error, unreachable bool) {
+	breakers.Lock()
+	defer breakers.Unlock()
+	if err == nil {
+		delete(breakers.hosts, address)
+		return
+	}
+	if !unreachable || policy.BreakerThreshold <= 0 {
+		return
+	}
+	b := breakers.hosts[address]
+	if b == nil {
+		b = &breaker{}
+		breakers.hosts[address] = b
+	}
+	b.failures++
+	if b.failures >= policy.BreakerThreshold {
+		b.openUntil = time.Now().Add(policy.BreakerCooldown)
+		slog.Warn("circuit opened", "address", address, "failures", b.failures, "cooldown", policy.BreakerCooldown)
+	}
+}
+
+// retryArgs returns the ssh option for the connection attempts of host.
+// ssh waits a second between attempts and keeps no circuit breaker, as each
+// command is a process of its own.
+func retryArgs(host config.SSHHost) []string {
+	attempts := retryConfig.For(host).Attempts
+	if attempts <= 1 {
+		return nil
+	}
+	return []string{"-o", fmt.Sprintf("ConnectionAttempts=%d", attempts)}
+}
@@ -1,10 +1,13 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -13,28 +16,67 @@ import (
 
 // SetupResult represents the result of SSH setup
 type SetupResult struct {
-	Success bool
-	Message string
-	Error   error
+	Success  bool
+	Message  string
+	Error    error
+	Identity string // Private key that authenticated, including one installed during setup
 }
 
-// TestConnection tests SSH connection and performs setup if needed
-func TestConnection(host config.SSHHost, password string) SetupResult {
+// SetupStep is a stage of the connection test
+type SetupStep int
+
+const (
+	StepDial SetupStep = iota
+	StepAuth
+	StepInstallKey
+	StepVerify
+)
+
+// String returns a human readable name for the step
+func (s SetupStep) String() string {
+	switch s {
+	case StepDial:
+		return "Connecting"
+	case StepAuth:
+		return "Authenticating"
+	case StepInstallKey:
+		return "Installing SSH key"
+	case StepVerify:
+		return "Verifying key login"
+	default:
+		return "Unknown"
+	}
+}
+
+// SetupProgress is called when the connection test moves to a new step
+type SetupProgress func(step SetupStep)
+
+// report calls progress if it is set
+func (p SetupProgress) report(step SetupStep) {
+	slog.Debug("connection test", "step", step.String())
+	if p != nil {
+		p(step)
+	}
+}
+
+// TestConnection tests SSH connection and performs setup if needed. Canceling
+// ctx aborts the test at any step.
+func TestConnection(ctx context.Context, host config.SSHHost, password string, progress SetupProgress) SetupResult {
 	// First, test if we can connect
 	if host.Identity != "" {
 		// Test key-based connection
-		return testKeyConnection(host)
+		return testKeyConnection(ctx, host, "", progress)
 	} else {
 		// Test password connection and set up keys
-		return testPasswordConnectionAndSetupKeys(host, password)
+		return testPasswordConnectionAndSetupKeys(ctx, host, password, progress)
 	}
 }
 
 // TestConnectionWithKeyPassword tests SSH connection with key password
-func TestConnectionWithKeyPassword(host config.SSHHost, keyPassword string) SetupResult {
+func TestConnectionWithKeyPassword(ctx context.Context, host config.SSHHost, keyPassword string, progress SetupProgress) SetupResult {
 	if host.Identity != "" {
 		// Test key-based connection with password
-		return testKeyConnectionWithPassword(host, keyPassword)
+		return testKeyConnection(ctx, host, keyPassword, progress)
 	} else {
 		return SetupResult{
 			Success: false,
@@ -44,37 +86,51 @@ func TestConnectionWithKeyPassword(host config.SSHHost, keyPassword string) Setu
 	}
 }
 
-// testKeyConnection tests SSH key-based connection
-func testKeyConnection(host config.SSHHost) SetupResult {
-	return testKeyConnectionWithPassword(host, "")
-}
+// dialContext connects and authenticates like ssh.Dial, going through the
+// host's jump hosts, and reports the dial and auth steps. Canceling ctx
+// closes the connection, which aborts the handshake or any session running
+// on the returned client.
+func dialContext(ctx context.Context, host config.SSHHost, clientConfig *ssh.ClientConfig, progress SetupProgress) (*ssh.Client, error) {
+	address := hostAddress(host)
 
-// testKeyConnectionWithPassword tests SSH key-based connection with optional password
-func testKeyConnectionWithPassword(host config.SSHHost, keyPassword string) SetupResult {
-	// Read private key
-	keyData, err := os.ReadFile(host.Identity)
+	progress.report(StepDial)
+	conn, err := dialHost(ctx, host, clientConfig.Timeout)
 	if err != nil {
-		return SetupResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to read private key: %v", err),
-			Error:   err,
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { conn.Close() })
+
+	progress.report(StepAuth)
+	conn.SetDeadline(time.Now().Add(clientConfig.Timeout))
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, clientConfig)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
 		}
+		return nil, err
 	}
+	conn.SetDeadline(time.Time{})
 
-	// Parse private key
-	var key ssh.Signer
-	if keyPassword != "" {
-		// Try to parse encrypted key with password
-		key, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(keyPassword))
-	} else {
-		// Try to parse unencrypted key
-		key, err = ssh.ParsePrivateKey(keyData)
+	return ssh.NewClient(clientConn, chans, reqs), nil
+}
+
+// canceledResult is returned when the test was canceled
+func canceledResult(ctx context.Context) SetupResult {
+	return SetupResult{
+		Success: false,
+		Message: "Connection test canceled",
+		Error:   ctx.Err(),
 	}
-	
+}
+
+// testKeyConnection tests SSH key-based connection with optional password
+func testKeyConnection(ctx context.Context, host config.SSHHost, keyPassword string, progress SetupProgress) SetupResult {
+	key, err := loadPrivateKey(host.Identity, keyPassword)
 	if err != nil {
 		return SetupResult{
 			Success: false,
-			Message: fmt.Sprintf("Failed to parse private key: %v", err),
+			Message: fmt.Sprintf("Failed to load private key: %v", err),
 			Error:   err,
 		}
 	}
@@ -85,13 +141,18 @@ func testKeyConnectionWithPassword(host config.SSHHost, keyPassword string) Setu
 		Auth: []ssh.AuthMethod{
 			ssh.PublicKeys(key),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // In production, use proper host key checking
+		HostKeyCallback: hostKeyCallback(),
 		Timeout:         10 * time.Second,
 	}
 
 	// Test connection
-	client, err := ssh.Dial("tcp", host.Host+":"+host.Port, config)
+	client, err := withRetry(ctx, host, func() (*ssh.Client, error) {
+		return dialContext(ctx, host, config, progress)
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			return canceledResult(ctx)
+		}
 		return SetupResult{
 			Success: false,
 			Message: fmt.Sprintf("Failed to connect with SSH key: %v", err),
@@ -100,70 +161,136 @@ func testKeyConnectionWithPassword(host config.SSHHost, keyPassword string) Setu
 	}
 	defer client.Close()
 
+	// Make sure the server lets us open a session, not just authenticate
+	progress.report(StepVerify)
+	session, err := NewConn(client).OpenSession()
+	if err != nil {
+		if ctx.Err() != nil {
+			return canceledResult(ctx)
+		}
+		return SetupResult{
+			Success: false,
+			Message: fmt.Sprintf("Failed to open SSH session: %v", err),
+			Error:   err,
+		}
+	}
+	session.Close()
+
 	return SetupResult{
-		Success: true,
-		Message: "SSH key connection successful",
+		Success:  true,
+		Message:  "SSH key connection successful",
+		Identity: host.Identity,
 	}
 }
 
 // testPasswordConnectionAndSetupKeys tests password connection and sets up SSH keys
-func testPasswordConnectionAndSetupKeys(host config.SSHHost, password string) SetupResult {
+func testPasswordConnectionAndSetupKeys(ctx context.Context, host config.SSHHost, password string, progress SetupProgress) SetupResult {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return SetupResult{
+			Success: false,
+			Message: fmt.Sprintf("Failed to get home directory: %v", err),
+			Error:   err,
+		}
+	}
+	return InstallKey(ctx, host, password, filepath.Join(homeDir, ".ssh", "id_rsa"), "", progress)
+}
+
+// InstallKey logs in to the host with the password, appends the public key
+// of privateKeyPath to its authorized_keys and checks that the key then
+// authenticates, decrypting it with keyPassword if needed. A key pair that
+// does not exist yet is generated first, without a passphrase.
+func InstallKey(ctx context.Context, host config.SSHHost, password, privateKeyPath, keyPassword string, progress SetupProgress) SetupResult {
 	// First, test password connection
 	config := &ssh.ClientConfig{
 		User: host.User,
 		Auth: []ssh.AuthMethod{
 			ssh.Password(password),
 		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // In production, use proper host key checking
-		Timeout:         10 * time.Second,
+		HostKeyCallback: hostKeyCallback(),
+		Timeout:         30 * time.Second,
 	}
 
-	client, err := ssh.Dial("tcp", host.Host+":"+host.Port, config)
+	client, err := withRetry(ctx, host, func() (*ssh.Client, error) {
+		return dialContext(ctx, host, config, progress)
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			return canceledResult(ctx)
+		}
 		return SetupResult{
 			Success: false,
 			Message: fmt.Sprintf("Failed to connect with password: %v", err),
 			Error:   err,
 		}
 	}
-	client.Close()
+	defer client.Close()
 
-	// If password connection works, set up SSH keys
-	return setupSSHKeys(host, password)
-}
+	// If password connection works, set up SSH keys over the same connection
+	progress.report(StepInstallKey)
+	result := setupSSHKeys(ctx, NewConn(client), privateKeyPath)
+	if !result.Success {
+		if ctx.Err() != nil {
+			return canceledResult(ctx)
+		}
+		return result
+	}
+	auditKeyInstalled(host, result.Identity)
 
-// setupSSHKeys sets up SSH key authentication
-func setupSSHKeys(host config.SSHHost, password string) SetupResult {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return SetupResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to get home directory: %v", err),
-			Error:   err,
+	// Test key-based connection
+	testHost := host
+	testHost.Identity = result.Identity
+	return testKeyConnection(ctx, testHost, keyPassword, func(step SetupStep) {
+		// The second login only counts as verification
+		if step == StepVerify {
+			progress.report(StepVerify)
 		}
+	})
+}
+
+// auditKeyInstalled adds the installation of a key on host to the audit log
+func auditKeyInstalled(host config.SSHHost, identity string) {
+	event := config.AuditEvent{Action: config.AuditKeyInstalled, Host: host.Name, Detail: identity + ".pub"}
+	if err := config.RecordAudit(event); err != nil {
+		slog.Warn("failed to write the audit log", "host", host.Name, "error", err)
 	}
+}
 
-	sshDir := filepath.Join(homeDir, ".ssh")
-	privateKeyPath := filepath.Join(sshDir, "id_rsa")
-	publicKeyPath := filepath.Join(sshDir, "id_rsa.pub")
+// setupSSHKeys sets up SSH key authentication with the key pair at
+// privateKeyPath, generating it if needed
+func setupSSHKeys(ctx context.Context, conn Conn, privateKeyPath string) SetupResult {
+	privateKeyPath = expandHome(privateKeyPath)
+	publicKeyPath := privateKeyPath + ".pub"
 
 	// Check if SSH key already exists
 	if _, err := os.Stat(privateKeyPath); os.IsNotExist(err) {
-		// Generate SSH key pair
-		result := generateSSHKeyPair(privateKeyPath, publicKeyPath)
-		if !result.Success {
-			return result
+		// Generate SSH key pair, of the type its name asks for
+		if strings.Contains(filepath.Base(privateKeyPath), "ed25519") {
+			if err := GenerateKeyPair(KeyGenOptions{Type: "ed25519", Path: privateKeyPath}); err != nil {
+				return SetupResult{
+					Success: false,
+					Message: fmt.Sprintf("Failed to generate SSH key: %v", err),
+					Error:   err,
+				}
+			}
+		} else {
+			result := generateSSHKeyPair(ctx, privateKeyPath, publicKeyPath)
+			if !result.Success {
+				return result
+			}
 		}
 	}
 
 	// Copy public key to remote server using ssh-copy-id equivalent
-	return copyPublicKey(host, password, publicKeyPath)
+	result := copyPublicKey(conn, publicKeyPath)
+	result.Identity = privateKeyPath
+	return result
 }
 
 // generateSSHKeyPair generates a new SSH key pair
-func generateSSHKeyPair(privateKeyPath, publicKeyPath string) SetupResult {
+func generateSSHKeyPair(ctx context.Context, privateKeyPath, publicKeyPath string) SetupResult {
 	// Use ssh-keygen command to generate key pair
-	cmd := exec.Command("ssh-keygen", "-t", "rsa", "-b", "2048", "-f", privateKeyPath, "-N", "")
+	cmd := exec.CommandContext(ctx, "ssh-keygen", "-t", "rsa", "-b", "2048", "-f", privateKeyPath, "-N", "")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return SetupResult{
@@ -179,8 +306,9 @@ func generateSSHKeyPair(privateKeyPath, publicKeyPath string) SetupResult {
 	}
 }
 
-// copyPublicKey copies the public key to the remote server
-func copyPublicKey(host config.SSHHost, password string, publicKeyPath string) SetupResult {
+// copyPublicKey appends the public key to authorized_keys on the server conn
+// is connected to
+func copyPublicKey(conn Conn, publicKeyPath string) SetupResult {
 	// Read public key
 	publicKey, err := os.ReadFile(publicKeyPath)
 	if err != nil {
@@ -191,37 +319,6 @@ func copyPublicKey(host config.SSHHost, password string, publicKeyPath string) S
 		}
 	}
 
-	// Connect to remote server with password
-	config := &ssh.ClientConfig{
-		User: host.User,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-
-	client, err := ssh.Dial("tcp", host.Host+":"+host.Port, config)
-	if err != nil {
-		return SetupResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to connect to remote server: %v", err),
-			Error:   err,
-		}
-	}
-	defer client.Close()
-
-	// Create SSH session
-	session, err := client.NewSession()
-	if err != nil {
-		return SetupResult{
-			Success: false,
-			Message: fmt.Sprintf("Failed to create SSH session: %v", err),
-			Error:   err,
-		}
-	}
-	defer session.Close()
-
 	// Create .ssh directory and authorized_keys file on remote server
 	commands := []string{
 		"mkdir -p ~/.ssh",
@@ -231,7 +328,7 @@ func copyPublicKey(host config.SSHHost, password string, publicKeyPath string) S
 	}
 
 	for _, cmd := range commands {
-		session, err := client.NewSession()
+		session, err := conn.OpenSession()
 		if err != nil {
 			return SetupResult{
 				Success: false,
@@ -240,7 +337,7 @@ func copyPublicKey(host config.SSHHost, password string, publicKeyPath string) S
 			}
 		}
 
-		err = session.Run(cmd)
+		err = session.Run(cmd, nil, nil)
 		session.Close()
 
 		if err != nil {
@@ -252,10 +349,8 @@ func copyPublicKey(host config.SSHHost, password string, publicKeyPath string) S
 		}
 	}
 
-	// Test key-based connection
-	privateKeyPath := filepath.Join(filepath.Dir(publicKeyPath), "id_rsa")
-	testHost := host
-	testHost.Identity = privateKeyPath
-
-	return testKeyConnection(testHost)
-}
\ No newline at end of file
+	return SetupResult{
+		Success: true,
+		Message: "Public key installed",
+	}
+}
@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+	"xssh/internal/sshtest"
+)
+
+// newTestServer starts an sshtest server accepting "tester" with password
+// "secret", with HOME and the xssh config in temporary directories so no
+// keys, known_hosts or settings of the machine are used
+func newTestServer(t *testing.T) *sshtest.Server {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SSH_AUTH_SOCK", "")
+	t.Setenv(config.EnvConfigDir, filepath.Join(home, ".config", "xssh"))
+	t.Setenv(config.EnvConfig, filepath.Join(home, ".config", "xssh", "config.toml"))
+
+	server, err := sshtest.NewServer("tester", "secret")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestInstallKey(t *testing.T) {
+	server := newTestServer(t)
+	host := server.Host("test")
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+
+	var steps []SetupStep
+	result := InstallKey(context.Background(), host, "secret", keyPath, "", func(step SetupStep) {
+		steps = append(steps, step)
+	})
+	if !result.Success {
+		t.Fatalf("InstallKey failed: %s (%v)", result.Message, result.Error)
+	}
+	if result.Identity != keyPath {
+		t.Errorf("Identity = %q, want %q", result.Identity, keyPath)
+	}
+	for _, want := range []SetupStep{StepDial, StepInstallKey, StepVerify} {
+		if !slices.Contains(steps, want) {
+			t.Errorf("progress reported %v, missing %v", steps, want)
+		}
+	}
+
+	// The generated key is the one the server authorized
+	publicKey, err := os.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized := server.AuthorizedKeys()
+	if len(authorized) != 1 || string(authorized[0].Marshal()) != string(generated.Marshal()) {
+		t.Fatalf("authorized keys = %d, want the generated key", len(authorized))
+	}
+
+	// And it logs in without the password
+	host.Identity = keyPath
+	client, err := DialAuthContext(context.Background(), host, Auth{})
+	if err != nil {
+		t.Fatalf("key login failed: %v", err)
+	}
+	client.Close()
+}
+
+func TestInstallKeyWrongPassword(t *testing.T) {
+	server := newTestServer(t)
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+
+	result := InstallKey(context.Background(), server.Host("test"), "wrong", keyPath, "", nil)
+	if result.Success {
+		t.Fatal("InstallKey succeeded with a wrong password")
+	}
+	if !strings.Contains(result.Message, "Failed to connect with password") {
+		t.Errorf("Message = %q", result.Message)
+	}
+	if keys := server.AuthorizedKeys(); len(keys) != 0 {
+		t.Errorf("%d keys were authorized", len(keys))
+	}
+}
+
+func TestInstallKeySetupCommandFails(t *testing.T) {
+	server := newTestServer(t)
+	var mu sync.Mutex
+	var commands []string
+	server.Exec = func(command string, stdout, stderr io.Writer) int {
+		mu.Lock()
+		commands = append(commands, command)
+		mu.Unlock()
+		if strings.HasPrefix(command, "echo ") {
+			io.WriteString(stderr, "disk full\n")
+			return 1
+		}
+		return server.RunSetupCommand(command, stdout, stderr)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+
+	result := InstallKey(context.Background(), server.Host("test"), "secret", keyPath, "", nil)
+	if result.Success {
+		t.Fatal("InstallKey succeeded although authorized_keys could not be written")
+	}
+	if keys := server.AuthorizedKeys(); len(keys) != 0 {
+		t.Errorf("%d keys were authorized", len(keys))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(commands) == 0 || commands[0] != "mkdir -p ~/.ssh" {
+		t.Errorf("setup commands = %q", commands)
+	}
+}
@@ -0,0 +1,120 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"xssh/internal/config"
+)
+
+// flagsWithArgument lists ssh options that consume the following argument
+const flagsWithArgument = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// ParseTarget parses a quick-connect target into a host. It accepts
+// [user@]host[:port], ssh://[user@]host[:port] and full ssh command lines
+// such as "ssh -p 2222 -i ~/.ssh/id_ed25519 deploy@example.com". The host's
+// Name is set to its address.
+func ParseTarget(input string) (config.SSHHost, error) {
+	fields := strings.Fields(input)
+	if len(fields) > 0 && fields[0] == "ssh" {
+		fields = fields[1:]
+	}
+
+	host := config.SSHHost{Port: "22"}
+	var target string
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if !strings.HasPrefix(field, "-") || len(field) < 2 {
+			if target != "" {
+				// Anything after the destination is a remote command
+				break
+			}
+			target = field
+			continue
+		}
+
+		flag := field[1]
+		if !strings.ContainsRune(flagsWithArgument, rune(flag)) {
+			continue
+		}
+
+		// The argument is either attached (-p2222) or the next field
+		value := field[2:]
+		if value == "" {
+			if i+1 >= len(fields) {
+				return config.SSHHost{}, fmt.Errorf("option -%c needs an argument", flag)
+			}
+			i++
+			value = fields[i]
+		}
+
+		switch flag {
+		case 'p':
+			host.Port = value
+		case 'l':
+			host.User = value
+		case 'i':
+			host.Identity = value
+		}
+	}
+
+	if target == "" {
+		return config.SSHHost{}, fmt.Errorf("no host given")
+	}
+	if err := parseDestination(target, &host); err != nil {
+		return config.SSHHost{}, err
+	}
+
+	if port, err := strconv.Atoi(host.Port); err != nil || port < 1 || port > 65535 {
+		return config.SSHHost{}, fmt.Errorf("invalid port %q", host.Port)
+	}
+
+	host.Name = host.Host
+	return host, nil
+}
+
+// parseDestination fills user, host and port from [ssh://][user@]host[:port]
+func parseDestination(target string, host *config.SSHHost) error {
+	hasScheme := strings.HasPrefix(target, "ssh://")
+	target = strings.TrimSuffix(strings.TrimPrefix(target, "ssh://"), "/")
+
+	if at := strings.LastIndex(target, "@"); at >= 0 {
+		host.User = target[:at]
+		target = target[at+1:]
+	}
+
+	// A port is only split off when it is unambiguous: bracketed IPv6
+	// addresses, URLs, or exactly one colon
+	switch {
+	case strings.HasPrefix(target, "["):
+		address, port, err := net.SplitHostPort(target)
+		if err != nil {
+			address = strings.Trim(target, "[]")
+			port = ""
+		}
+		host.Host = address
+		if port != "" {
+			host.Port = port
+		}
+	case hasScheme || strings.Count(target, ":") == 1:
+		address, port, err := net.SplitHostPort(target)
+		if err != nil {
+			host.Host = target
+			break
+		}
+		host.Host = address
+		host.Port = port
+	default:
+		host.Host = target
+	}
+
+	if host.Host == "" {
+		return fmt.Errorf("no host given")
+	}
+	if strings.ContainsAny(host.Host, " /") {
+		return fmt.Errorf("invalid host %q", host.Host)
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+// Package sshtest runs an SSH server inside the process, so port
+// forwarding, key setup and authentication can be exercised against
+// 127.0.0.1 instead of a real server. It implements what xssh uses: password
+// and public key logins, exec sessions, direct-tcpip channels for local and
+// dynamic forwarding, direct-streamlocal channels for forwarding to unix
+// sockets and tcpip-forward requests for remote forwarding.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+)
+
+// Server is an SSH server listening on a random port of 127.0.0.1
+type Server struct {
+	User     string // Only user allowed to log in
+	Password string // Password of User; empty refuses password logins
+
+	// Exec runs the command of an exec session and returns its exit status.
+	// Nil runs the commands of key setup, see RunSetupCommand.
+	Exec func(command string, stdout, stderr io.Writer) int
+
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.PublicKey
+
+	mu         sync.Mutex
+	authorized []ssh.PublicKey // Keys allowed to log in, as in authorized_keys
+	conns      map[net.Conn]bool
+	closed     bool
+	running    sync.WaitGroup
+}
+
+// NewServer starts a server with a new host key, accepting user with
+// password
+func NewServer(user, password string) (*Server, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		User:     user,
+		Password: password,
+		listener: listener,
+		hostKey:  signer.PublicKey(),
+		conns:    map[net.Conn]bool{},
+	}
+	s.config = &ssh.ServerConfig{
+		PasswordCallback:  s.checkPassword,
+		PublicKeyCallback: s.checkKey,
+	}
+	s.config.AddHostKey(signer)
+
+	s.running.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// HostKey returns the public host key of the server
+func (s *Server) HostKey() ssh.PublicKey {
+	return s.hostKey
+}
+
+// Host returns a host called name that connects to the server as its user
+func (s *Server) Host(name string) config.SSHHost {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	return config.SSHHost{Name: name, Host: host, Port: port, User: s.User}
+}
+
+// Authorize lets key log in, as a line of authorized_keys would
+func (s *Server) Authorize(key ssh.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorized = append(s.authorized, key)
+}
+
+// AuthorizedKeys returns the keys allowed to log in, including the ones
+// installed through exec sessions
+func (s *Server) AuthorizedKeys() []ssh.PublicKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ssh.PublicKey(nil), s.authorized...)
+}
+
+// Close stops the server, closing the connections it has open
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	err := s.listener.Close()
+	s.running.Wait()
+	return err
+}
+
+// checkPassword accepts the password of the server's user
+func (s *Server) checkPassword(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+	if s.Password != "" && meta.User() == s.User && string(password) == s.Password {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("password rejected for %s", meta.User())
+}
+
+// checkKey accepts the authorized keys for the server's user
+func (s *Server) checkKey(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	if meta.User() == s.User {
+		for _, authorized := range s.AuthorizedKeys() {
+			if string(authorized.Marshal()) == string(key.Marshal()) {
+				return nil, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("key rejected for %s", meta.User())
+}
+
+// serve accepts connections until the server is closed
+func (s *Server) serve() {
+	defer s.running.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.running.Add(1)
+		s.mu.Unlock()
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn runs one SSH connection
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.running.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		return
+	}
+	defer serverConn.Close()
+
+	forwards := &remoteForwards{conn: serverConn, listeners: map[string]net.Listener{}}
+	defer forwards.closeAll()
+	go forwards.serveRequests(requests)
+
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.handleSession(newChannel)
+		case "direct-tcpip":
+			go handleDirectTCPIP(newChannel)
+		case "direct-streamlocal@openssh.com":
+			go handleDirectStreamLocal(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+// handleSession runs the exec requests of a session channel. Shells and
+// terminals are refused.
+func (s *Server) handleSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	for request := range requests {
+		if request.Type != "exec" {
+			request.Reply(request.Type == "env", nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+			request.Reply(false, nil)
+			continue
+		}
+		request.Reply(true, nil)
+
+		exec := s.Exec
+		if exec == nil {
+			exec = s.RunSetupCommand
+		}
+		status := exec(payload.Command, channel, channel.Stderr())
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+		return
+	}
+}
+
+// RunSetupCommand runs the commands xssh sends to install a public key:
+// mkdir and chmod succeed, and echoing a key into authorized_keys authorizes
+// it. Other commands fail with status 127.
+func (s *Server) RunSetupCommand(command string, stdout, stderr io.Writer) int {
+	fields := strings.Fields(command)
+	switch {
+	case len(fields) == 0:
+		return 0
+	case fields[0] == "mkdir", fields[0] == "chmod", fields[0] == "true":
+		return 0
+	case fields[0] == "echo" && strings.HasSuffix(command, ">> ~/.ssh/authorized_keys"):
+		line := strings.TrimSuffix(strings.TrimPrefix(command, "echo "), ">> ~/.ssh/authorized_keys")
+		line = strings.Trim(strings.TrimSpace(line), "'")
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			fmt.Fprintf(stderr, "invalid key: %v\n", err)
+			return 1
+		}
+		s.Authorize(key)
+		return 0
+	}
+	fmt.Fprintf(stderr, "%s: command not supported by sshtest\n", fields[0])
+	return 127
+}
+
+// handleDirectTCPIP connects a direct-tcpip channel, as opened for local and
+// dynamic forwarding, to its destination
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid direct-tcpip request")
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipe(channel, conn)
+}
+
+// handleDirectStreamLocal connects a direct-streamlocal channel, as opened
+// for local forwarding to a unix socket, to its socket
+func handleDirectStreamLocal(newChannel ssh.NewChannel) {
+	var target struct {
+		SocketPath string
+		Reserved0  string
+		Reserved1  uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid direct-streamlocal request")
+		return
+	}
+	conn, err := net.Dial("unix", target.SocketPath)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipe(channel, conn)
+}
+
+// remoteForwards holds the listeners of the tcpip-forward requests of one
+// connection
+type remoteForwards struct {
+	conn      *ssh.ServerConn
+	mu        sync.Mutex
+	listeners map[string]net.Listener // Keyed by the requested host:port
+}
+
+// forwardRequest is the payload of tcpip-forward and cancel-tcpip-forward
+type forwardRequest struct {
+	Host string
+	Port uint32
+}
+
+// serveRequests answers the global requests of a connection
+func (f *remoteForwards) serveRequests(requests <-chan *ssh.Request) {
+	for request := range requests {
+		switch request.Type {
+		case "tcpip-forward":
+			var payload forwardRequest
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			port, err := f.listen(payload)
+			if err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+		case "cancel-tcpip-forward":
+			var payload forwardRequest
+			ssh.Unmarshal(request.Payload, &payload)
+			request.Reply(f.cancel(payload), nil)
+		default:
+			// Keepalives and anything else the server does not know
+			request.Reply(strings.HasPrefix(request.Type, "keepalive@"), nil)
+		}
+	}
+}
+
+// listen starts listening for a tcpip-forward request and returns the port
+func (f *remoteForwards) listen(payload forwardRequest) (uint32, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		return 0, err
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	key := net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+	f.mu.Lock()
+	f.listeners[key] = listener
+	f.mu.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.forward(payload.Host, port, conn)
+		}
+	}()
+	return port, nil
+}
+
+// forward opens a forwarded-tcpip channel back to the client for conn
+func (f *remoteForwards) forward(host string, port uint32, conn net.Conn) {
+	origin := conn.RemoteAddr().(*net.TCPAddr)
+	payload := ssh.Marshal(struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}{host, port, origin.IP.String(), uint32(origin.Port)})
+	channel, requests, err := f.conn.OpenChannel("forwarded-tcpip", payload)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipe(channel, conn)
+}
+
+// cancel stops listening for a cancel-tcpip-forward request
+func (f *remoteForwards) cancel(payload forwardRequest) bool {
+	key := net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port)))
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	listener, ok := f.listeners[key]
+	if ok {
+		listener.Close()
+		delete(f.listeners, key)
+	}
+	return ok
+}
+
+// closeAll stops every listener of the connection
+func (f *remoteForwards) closeAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key, listener := range f.listeners {
+		listener.Close()
+		delete(f.listeners, key)
+	}
+}
+
+// pipe copies between channel and conn until either side is done, then
+// closes both
+func pipe(channel ssh.Channel, conn net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, channel)
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+	channel.Close()
+	conn.Close()
+}
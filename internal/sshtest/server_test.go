@@ -0,0 +1,51 @@
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRunSetupCommand(t *testing.T) {
+	server, err := NewServer("tester", "secret")
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer server.Close()
+	var stderr strings.Builder
+
+	if status := server.RunSetupCommand("mkdir -p ~/.ssh", io.Discard, &stderr); status != 0 {
+		t.Errorf("mkdir exited with %d", status)
+	}
+	if status := server.RunSetupCommand("echo 'not a key' >> ~/.ssh/authorized_keys", io.Discard, &stderr); status != 1 {
+		t.Errorf("echo of an invalid key exited with %d, want 1", status)
+	}
+	if status := server.RunSetupCommand("rm -rf ~/.ssh", io.Discard, &stderr); status != 127 {
+		t.Errorf("rm exited with %d, want 127", status)
+	}
+	if len(server.AuthorizedKeys()) != 0 {
+		t.Error("a key was authorized")
+	}
+
+	// A key echoed into authorized_keys, as xssh sends it, is authorized
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(ssh.MarshalAuthorizedKey(key))
+	if status := server.RunSetupCommand("echo '"+line+"' >> ~/.ssh/authorized_keys", io.Discard, &stderr); status != 0 {
+		t.Fatalf("echo of a key exited with %d: %s", status, stderr.String())
+	}
+	authorized := server.AuthorizedKeys()
+	if len(authorized) != 1 || string(authorized[0].Marshal()) != string(key.Marshal()) {
+		t.Errorf("authorized keys = %d, want the echoed key", len(authorized))
+	}
+}
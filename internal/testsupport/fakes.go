@@ -0,0 +1,303 @@
+// Package testsupport has in-memory fakes of the SSH connections xssh opens,
+// so SOCKS handshakes, copy loops and reconnecting can be exercised without a
+// network or a server. FakeDialer stands in for ssh.Dialer and FakeConn for
+// ssh.Conn; internal/sshtest runs a real server where the protocol matters.
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"xssh/internal/config"
+	xssh "xssh/internal/ssh"
+)
+
+// ErrConnClosed is returned by a FakeConn that has been closed
+var ErrConnClosed = errors.New("testsupport: connection closed")
+
+// FakeDialer is an ssh.Dialer handing out FakeConns
+type FakeDialer struct {
+	// Failures are returned by the next dials, one each, before dials
+	// succeed, as a host that is down for a while would
+	Failures []error
+	// NewConn returns the connection of a successful dial. Nil returns a
+	// new FakeConn without a Handler.
+	NewConn func(host config.SSHHost) *FakeConn
+
+	mu    sync.Mutex
+	dials []config.SSHHost
+	conns []*FakeConn
+}
+
+// Dial fails with the next of Failures, or returns a new connection
+func (d *FakeDialer) Dial(ctx context.Context, host config.SSHHost, auth xssh.Auth) (xssh.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dials = append(d.dials, host)
+	if len(d.Failures) > 0 {
+		err := d.Failures[0]
+		d.Failures = d.Failures[1:]
+		return nil, err
+	}
+	conn := &FakeConn{}
+	if d.NewConn != nil {
+		conn = d.NewConn(host)
+	}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+// Dials returns the hosts dialed so far, including failed dials
+func (d *FakeDialer) Dials() []config.SSHHost {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]config.SSHHost(nil), d.dials...)
+}
+
+// Conns returns the connections handed out so far
+func (d *FakeDialer) Conns() []*FakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]*FakeConn(nil), d.conns...)
+}
+
+// FakeConn is an ssh.Conn whose remote host lives in memory
+type FakeConn struct {
+	// Handler serves the connections DialContext opens from the remote host
+	// to addr, getting the remote end of an in-memory pipe. Nil refuses
+	// every dial.
+	Handler func(addr string, conn net.Conn)
+	// Exec runs the commands of sessions and returns their exit status. Nil
+	// runs every command successfully without output.
+	Exec func(cmd string, stdout, stderr io.Writer) int
+
+	mu        sync.Mutex
+	closed    bool
+	dead      bool
+	listeners map[string]*FakeListener
+	commands  []string
+}
+
+// DialContext connects to addr through Handler
+func (c *FakeConn) DialContext(ctx context.Context, n, addr string) (net.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	if c.Handler == nil {
+		return nil, fmt.Errorf("testsupport: connection to %s refused", addr)
+	}
+	local, remote := net.Pipe()
+	go c.Handler(addr, remote)
+	return local, nil
+}
+
+// Listen returns a FakeListener for addr, which Listener finds again
+func (c *FakeConn) Listen(n, addr string) (net.Listener, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, taken := c.listeners[addr]; taken {
+		return nil, fmt.Errorf("testsupport: %s is already listened on", addr)
+	}
+	if c.listeners == nil {
+		c.listeners = map[string]*FakeListener{}
+	}
+	listener := newFakeListener(addr, func() {
+		c.mu.Lock()
+		delete(c.listeners, addr)
+		c.mu.Unlock()
+	})
+	c.listeners[addr] = listener
+	return listener, nil
+}
+
+// Listener returns the listener on addr of the remote host, or nil
+func (c *FakeConn) Listener(addr string) *FakeListener {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.listeners[addr]
+}
+
+// OpenSession returns a session running its command with Exec
+func (c *FakeConn) OpenSession() (xssh.Session, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return &fakeSession{conn: c}, nil
+}
+
+// SendRequest answers every request, as a live server answers keepalives,
+// until the connection is closed or killed
+func (c *FakeConn) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	if err := c.check(); err != nil {
+		return false, nil, err
+	}
+	return true, nil, nil
+}
+
+// Close closes the connection and its listeners
+func (c *FakeConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrConnClosed
+	}
+	c.closed = true
+	listeners := c.listeners
+	c.listeners = nil
+	c.mu.Unlock()
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	return nil
+}
+
+// Kill makes the connection fail every call from now on without being
+// closed, as a connection whose server went away does
+func (c *FakeConn) Kill() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dead = true
+}
+
+// Closed reports whether Close has been called
+func (c *FakeConn) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Commands returns the commands run in sessions so far
+func (c *FakeConn) Commands() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.commands...)
+}
+
+// check returns the error of a closed or killed connection
+func (c *FakeConn) check() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.dead {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// ExitError is returned by a session whose command exits with a non-zero
+// status
+type ExitError struct {
+	Status int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("testsupport: command exited with status %d", e.Status)
+}
+
+// fakeSession is the session of a FakeConn
+type fakeSession struct {
+	conn *FakeConn
+}
+
+func (s *fakeSession) Run(cmd string, stdout, stderr io.Writer) error {
+	if err := s.conn.check(); err != nil {
+		return err
+	}
+	s.conn.mu.Lock()
+	s.conn.commands = append(s.conn.commands, cmd)
+	s.conn.mu.Unlock()
+	if s.conn.Exec == nil {
+		return nil
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	if status := s.conn.Exec(cmd, stdout, stderr); status != 0 {
+		return &ExitError{Status: status}
+	}
+	return nil
+}
+
+func (s *fakeSession) Close() error {
+	return nil
+}
+
+// FakeListener is a listener on the remote host of a FakeConn. Connect
+// stands in for a client connecting to it.
+type FakeListener struct {
+	addr    fakeAddr
+	conns   chan net.Conn
+	done    chan struct{}
+	once    sync.Once
+	onClose func()
+}
+
+// newFakeListener returns a listener on addr calling onClose once closed
+func newFakeListener(addr string, onClose func()) *FakeListener {
+	return &FakeListener{
+		addr:    fakeAddr(addr),
+		conns:   make(chan net.Conn),
+		done:    make(chan struct{}),
+		onClose: onClose,
+	}
+}
+
+// Connect connects to the listener and returns the client end, once Accept
+// has taken the other one
+func (l *FakeListener) Connect(ctx context.Context) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Accept waits for Connect. Once the listener is closed it fails with
+// io.EOF, as the remote listeners of *ssh.Client do.
+func (l *FakeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, io.EOF
+	}
+}
+
+// Close stops the listener, failing Accept and Connect from then on
+func (l *FakeListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		l.onClose()
+	})
+	return nil
+}
+
+// Addr returns the address the listener was opened on
+func (l *FakeListener) Addr() net.Addr {
+	return l.addr
+}
+
+// fakeAddr is the address of a FakeListener
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "fake" }
+func (a fakeAddr) String() string  { return string(a) }
@@ -0,0 +1,165 @@
+// Package tmux opens hosts in tmux windows and panes, for "xssh tmux" and
+// the host list of the TUI.
+package tmux
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Layout is how the hosts opened in tmux are arranged
+type Layout int
+
+const (
+	// Windows opens a window per host
+	Windows Layout = iota
+	// Panes splits the current window into a pane per host
+	Panes
+	// Tiled opens one new window holding a pane per host
+	Tiled
+)
+
+// ParseLayout returns the layout called name: "windows", "panes" or "tiled"
+func ParseLayout(name string) (Layout, error) {
+	switch name {
+	case "windows", "window", "w":
+		return Windows, nil
+	case "panes", "pane", "p":
+		return Panes, nil
+	case "tiled", "t":
+		return Tiled, nil
+	}
+	return Windows, fmt.Errorf("unknown tmux layout %q, use windows, panes or tiled", name)
+}
+
+// Target is a host to open, with the command connecting to it
+type Target struct {
+	Name    string   // Window name, the host alias
+	Command []string // Run in the window or pane, e.g. xssh connect <alias>
+}
+
+// Options tune how the targets are opened
+type Options struct {
+	Layout Layout
+	// Synchronize sends the keys typed in one pane to all panes of the
+	// window, for the Panes and Tiled layouts
+	Synchronize bool
+}
+
+// Inside reports whether xssh runs inside a tmux session
+func Inside() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// Available reports whether the tmux binary is installed
+func Available() bool {
+	_, err := exec.LookPath("tmux")
+	return err == nil
+}
+
+// Open opens a window or pane per target. Inside tmux they are added to the
+// current session; outside, they go to a new detached session, whose name is
+// returned for Attach.
+func Open(targets []Target, opts Options) (string, error) {
+	if len(targets) == 0 {
+		return "", errors.New("no hosts to open")
+	}
+	if !Available() {
+		return "", errors.New("tmux is not installed")
+	}
+
+	// Outside tmux, the panes of the current window are those of the new
+	// session's window
+	layout := opts.Layout
+	inside := Inside()
+	if !inside && layout == Panes {
+		layout = Tiled
+	}
+
+	var session, window string
+	for i, target := range targets {
+		command := shellJoin(target.Command)
+		var args []string
+		switch {
+		case i == 0 && !inside:
+			args = []string{"new-session", "-d", "-P", "-F", "#{session_name}\t#{window_id}", "-n", target.Name, command}
+		case i == 0 && layout == Panes:
+			args = []string{"split-window", "-P", "-F", "\t#{window_id}", command}
+		case i == 0 || layout == Windows:
+			args = []string{"new-window", "-P", "-F", "\t#{window_id}", "-n", target.Name}
+			if session != "" {
+				args = append(args, "-t", session+":")
+			}
+			args = append(args, command)
+		default:
+			args = []string{"split-window", "-t", window, "-P", "-F", "\t#{window_id}", command}
+		}
+
+		output, err := run(args...)
+		if err != nil {
+			return session, fmt.Errorf("cannot open %s: %v", target.Name, err)
+		}
+		name, id, _ := strings.Cut(strings.TrimSpace(output), "\t")
+		if i == 0 && !inside {
+			session = name
+		}
+		window = id
+
+		// Re-tile after every split, or tmux runs out of room for the
+		// next pane
+		if layout != Windows {
+			if _, err := run("select-layout", "-t", window, "tiled"); err != nil {
+				return session, err
+			}
+		}
+	}
+
+	if opts.Synchronize && layout != Windows && len(targets) > 1 {
+		if _, err := run("set-window-option", "-t", window, "synchronize-panes", "on"); err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+// Attach attaches the terminal to session until the client detaches or the
+// session ends
+func Attach(session string) error {
+	attach := exec.Command("tmux", "attach-session", "-t", session)
+	attach.Stdin = os.Stdin
+	attach.Stdout = os.Stdout
+	attach.Stderr = os.Stderr
+	return attach.Run()
+}
+
+// run runs tmux with args and returns its output, or its error message as
+// the error
+func run(args ...string) (string, error) {
+	var stderr strings.Builder
+	cmd := exec.Command("tmux", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("tmux %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("tmux %s: %v", args[0], err)
+	}
+	return string(output), nil
+}
+
+// shellJoin quotes args for the shell tmux runs commands with
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@=+,%") == "" {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
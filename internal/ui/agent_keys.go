@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/ssh"
+)
+
+// agentScreen holds the state of the ssh-agent key overview
+type agentScreen struct {
+	keys          []ssh.AgentKey
+	cursor        int
+	confirmRemove bool
+
+	// Adding an identity
+	identities      []string // Configured identities first, then keys found in ~/.ssh
+	picking         bool
+	pickCursor      int
+	pendingIdentity string // Identity waiting for its passphrase
+	passphrase      textinput.Model
+
+	returnMode ViewMode
+}
+
+// openAgentKeys switches to the ssh-agent key overview
+func (m Model) openAgentKeys() (tea.Model, tea.Cmd) {
+	passphrase := textinput.New()
+	passphrase.Prompt = "Passphrase: "
+	passphrase.EchoMode = textinput.EchoPassword
+
+	m.agent = &agentScreen{
+		passphrase: passphrase,
+		returnMode: m.viewMode,
+	}
+	m.viewMode = ModeAgentKeys
+	m.reloadAgentKeys()
+	return m, nil
+}
+
+// reloadAgentKeys refreshes the list of keys loaded in the agent
+func (m *Model) reloadAgentKeys() {
+	keys, err := ssh.ListAgentKeys()
+	if err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+	}
+	m.agent.keys = keys
+	if m.agent.cursor >= len(keys) {
+		m.agent.cursor = max(0, len(keys)-1)
+	}
+}
+
+// agentIdentities lists identity files that can be added to the agent: the
+// ones used by configured hosts, followed by other keys in ~/.ssh
+func (m *Model) agentIdentities() []string {
+	seen := map[string]bool{}
+	var identities []string
+	add := func(path string) {
+		key := filepath.Clean(path)
+		if path == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		identities = append(identities, path)
+	}
+
+	for _, host := range m.hosts {
+		add(host.Identity)
+	}
+	m.loadSSHKeys()
+	for _, keyFile := range m.keyFiles {
+		add(keyFile)
+	}
+	return identities
+}
+
+// agentHasIdentity reports whether the identity's public key is loaded in
+// the agent
+func (s *agentScreen) agentHasIdentity(identity string) bool {
+	fingerprint := ssh.PublicKeyFingerprint(identity)
+	if fingerprint == "" {
+		return false
+	}
+	for _, key := range s.keys {
+		if key.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAgentKeysMode handles keys in the ssh-agent key overview
+func (m Model) handleAgentKeysMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	screen := m.agent
+	if screen == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	if screen.pendingIdentity != "" {
+		return m.handleAgentPassphrase(msg)
+	}
+	if screen.picking {
+		return m.handleAgentIdentityPicker(msg)
+	}
+
+	if screen.confirmRemove {
+		switch msg.String() {
+		case "y", "Y":
+			screen.confirmRemove = false
+			m.removeAgentKey()
+		case "n", "N", "esc":
+			screen.confirmRemove = false
+		}
+		return m, nil
+	}
+
+	m.message = ""
+	m.messageType = ""
+
+	switch msg.String() {
+	case "esc", "q":
+		m.viewMode = screen.returnMode
+		m.agent = nil
+
+	case "up", "k":
+		if screen.cursor > 0 {
+			screen.cursor--
+		}
+
+	case "down", "j":
+		if screen.cursor < len(screen.keys)-1 {
+			screen.cursor++
+		}
+
+	case "a":
+		// Pick an identity to add
+		screen.identities = m.agentIdentities()
+		screen.pickCursor = 0
+		screen.picking = true
+		if len(screen.identities) == 0 {
+			screen.picking = false
+			m.message = "No identity files configured or found in ~/.ssh"
+			m.messageType = "error"
+		}
+
+	case "d", "delete":
+		if len(screen.keys) > 0 {
+			screen.confirmRemove = true
+		}
+
+	case "R", "ctrl+r":
+		m.reloadAgentKeys()
+	}
+
+	return m, nil
+}
+
+// handleAgentIdentityPicker handles keys while choosing an identity to add
+func (m Model) handleAgentIdentityPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	screen := m.agent
+
+	switch msg.String() {
+	case "esc":
+		screen.picking = false
+
+	case "up", "k":
+		if screen.pickCursor > 0 {
+			screen.pickCursor--
+		}
+
+	case "down", "j":
+		if screen.pickCursor < len(screen.identities)-1 {
+			screen.pickCursor++
+		}
+
+	case "enter":
+		identity := screen.identities[screen.pickCursor]
+		screen.picking = false
+
+		err := ssh.AddKeyToAgent(identity, "")
+		if ssh.IsPassphraseMissing(err) {
+			// Ask for the passphrase once, then add the decrypted key
+			screen.pendingIdentity = identity
+			screen.passphrase.SetValue("")
+			m.message = ""
+			m.messageType = ""
+			return m, screen.passphrase.Focus()
+		}
+		m.finishAgentAdd(identity, err)
+	}
+
+	return m, nil
+}
+
+// handleAgentPassphrase handles keys while entering an identity's passphrase
+func (m Model) handleAgentPassphrase(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	screen := m.agent
+
+	switch msg.String() {
+	case "esc":
+		screen.pendingIdentity = ""
+		screen.passphrase.Blur()
+		screen.passphrase.SetValue("")
+
+	case "enter":
+		identity := screen.pendingIdentity
+		err := ssh.AddKeyToAgent(identity, screen.passphrase.Value())
+		screen.pendingIdentity = ""
+		screen.passphrase.Blur()
+		screen.passphrase.SetValue("")
+		m.finishAgentAdd(identity, err)
+
+	default:
+		var cmd tea.Cmd
+		screen.passphrase, cmd = screen.passphrase.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// finishAgentAdd reports the result of adding an identity and refreshes the
+// key list
+func (m *Model) finishAgentAdd(identity string, err error) {
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to add %s: %v", identity, err)
+		m.messageType = "error"
+		return
+	}
+	m.reloadAgentKeys()
+	m.message = fmt.Sprintf("Added %s to ssh-agent", identity)
+	m.messageType = "success"
+}
+
+// removeAgentKey removes the key under the cursor from the agent
+func (m *Model) removeAgentKey() {
+	screen := m.agent
+	if screen.cursor >= len(screen.keys) {
+		return
+	}
+	key := screen.keys[screen.cursor]
+
+	if err := ssh.RemoveAgentKey(key.PublicKey); err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+		return
+	}
+
+	m.reloadAgentKeys()
+	m.message = fmt.Sprintf("Removed %s from ssh-agent", key.Comment)
+	m.messageType = "success"
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderAgentKeysView renders the ssh-agent key overview
+func (m Model) renderAgentKeysView() string {
+	var content strings.Builder
+	screen := m.agent
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("SSH Agent Keys")
+	content.WriteString(header + "\n\n")
+
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	emptyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Italic(true)
+	selectedStyle := m.theme.SelectedStyle()
+	innerWidth := m.width - 8
+
+	if screen.picking {
+		content.WriteString(panelStyle.Render(m.renderAgentIdentityPicker(innerWidth)) + "\n")
+	} else {
+		// Loaded keys
+		var list strings.Builder
+		if len(screen.keys) == 0 {
+			list.WriteString(emptyStyle.Render("No keys loaded in ssh-agent"))
+		}
+		for i, key := range screen.keys {
+			cursor := "  "
+			if i == screen.cursor {
+				cursor = "▶ "
+			}
+
+			keyInfo := fmt.Sprintf("%-20s %s", key.Type, key.Fingerprint)
+			commentWidth := max(10, innerWidth-len(cursor)-lipgloss.Width(keyInfo)-2)
+			line := fmt.Sprintf("%s%s  %s", cursor, padAndTruncate(key.Comment, commentWidth), keyInfo)
+
+			if i == screen.cursor {
+				list.WriteString(selectedStyle.Render(line) + "\n")
+			} else {
+				list.WriteString(line + "\n")
+			}
+		}
+		content.WriteString(panelStyle.Render(strings.TrimRight(list.String(), "\n")) + "\n")
+	}
+
+	// Passphrase prompt, confirmation or message
+	if screen.pendingIdentity != "" {
+		promptStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Primary).
+			Bold(true)
+		content.WriteString(promptStyle.Render(fmt.Sprintf("%s is encrypted", screen.pendingIdentity)) + "\n")
+		content.WriteString(screen.passphrase.View() + "\n")
+	} else if screen.confirmRemove && screen.cursor < len(screen.keys) {
+		question := fmt.Sprintf("Remove %s from ssh-agent? (y/n)", screen.keys[screen.cursor].Comment)
+		content.WriteString(m.theme.MessageStyle("error", m.width).Render(question) + "\n")
+	} else {
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • a: add identity • d: remove key • R: refresh • ESC/q: back"
+	switch {
+	case screen.pendingIdentity != "":
+		help = "Type passphrase • Enter: add key • ESC: cancel"
+	case screen.picking:
+		help = "↑/k ↓/j: move • Enter: add to agent • ESC: cancel"
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// renderAgentIdentityPicker renders the identity files that can be added
+func (m Model) renderAgentIdentityPicker(width int) string {
+	var list strings.Builder
+	screen := m.agent
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	loadedStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Success)
+	list.WriteString(titleStyle.Render("Add identity to ssh-agent") + "\n\n")
+
+	selectedStyle := m.theme.SelectedStyle()
+	for i, identity := range screen.identities {
+		cursor := "  "
+		if i == screen.pickCursor {
+			cursor = "▶ "
+		}
+
+		loaded := ""
+		if screen.agentHasIdentity(identity) {
+			loaded = " (loaded)"
+		}
+		line := cursor + padAndTruncate(identity, max(10, width-len(cursor)-len(loaded)))
+
+		if i == screen.pickCursor {
+			list.WriteString(selectedStyle.Render(line+loaded) + "\n")
+		} else {
+			list.WriteString(line + loadedStyle.Render(loaded) + "\n")
+		}
+	}
+
+	return strings.TrimRight(list.String(), "\n")
+}
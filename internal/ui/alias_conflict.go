@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// aliasConflict holds a batch of hosts being added to the config and the
+// host whose alias is already taken, if any. A batch comes from the add form
+// (one host) or from an import.
+type aliasConflict struct {
+	existing   config.SSHHost   // Configured host with the conflicting alias
+	incoming   config.SSHHost   // Host being added
+	pending    []config.SSHHost // Hosts of the batch after incoming
+	renaming   bool
+	rename     textinput.Model
+	fromForm   bool     // The batch is the host of the add form
+	returnMode ViewMode // Mode to go back to when the form's host is skipped
+
+	// Outcome of the batch so far
+	added, overwritten, merged, skipped int
+	lastMessage                         string
+	copiedMetadata                      bool // Host metadata changed and must be saved
+}
+
+// total returns how many hosts of the batch were handled so far
+func (c *aliasConflict) total() int {
+	return c.added + c.overwritten + c.merged + c.skipped
+}
+
+// addHosts adds hosts to the config, asking how to resolve each alias that
+// is already taken. The config is saved once the whole batch is handled.
+func (m Model) addHosts(hosts []config.SSHHost, fromForm bool) (tea.Model, tea.Cmd) {
+	m.conflict = &aliasConflict{
+		pending:    hosts,
+		fromForm:   fromForm,
+		returnMode: m.viewMode,
+	}
+	return m.continueAddingHosts()
+}
+
+// continueAddingHosts adds the pending hosts of the batch until one has a
+// conflicting alias, or saves the config when none are left
+func (m Model) continueAddingHosts() (tea.Model, tea.Cmd) {
+	c := m.conflict
+	c.renaming = false
+
+	for len(c.pending) > 0 {
+		host := c.pending[0]
+		c.pending = c.pending[1:]
+
+		if index := m.sshConfig.FindHost(host.Name); index >= 0 {
+			c.existing = m.sshConfig.Hosts[index]
+			c.incoming = host
+			m.viewMode = ModeAliasConflict
+			m.message = ""
+			m.messageType = ""
+			return m, nil
+		}
+
+		m.reuseAlias(host.Name)
+		m.sshConfig.AddHost(host)
+		m.copyFormMetadata(host.Name)
+		m.hosts = m.sshConfig.Hosts
+		c.added++
+		c.lastMessage = fmt.Sprintf("Host '%s' added", host.Name)
+	}
+
+	return m.finishAddingHosts()
+}
+
+// copyFormMetadata gives the host added from the add form as name the
+// metadata of the host the form duplicates, if it duplicates one
+func (m *Model) copyFormMetadata(name string) {
+	if !m.conflict.fromForm || m.formData.CopyOf == "" {
+		return
+	}
+	m.metadata.CopyHost(m.formData.CopyOf, name)
+	m.conflict.copiedMetadata = true
+}
+
+// finishAddingHosts saves the config after a batch and reports the outcome
+func (m Model) finishAddingHosts() (tea.Model, tea.Cmd) {
+	c := m.conflict
+	m.conflict = nil
+
+	if c.total() == c.skipped {
+		// Nothing changed
+		if c.fromForm {
+			m.viewMode = c.returnMode
+			m.message = "Host not saved"
+			m.messageType = "info"
+			return m, nil
+		}
+		m.viewMode = ModeList
+		m.message = fmt.Sprintf("No hosts added, %d skipped", c.skipped)
+		m.messageType = "info"
+		return m, nil
+	}
+
+	// Keep the previous file around when existing hosts were changed
+	if c.overwritten+c.merged > 0 {
+		if err := m.sshConfig.Backup(); err != nil {
+			m.message = fmt.Sprintf("Failed to back up config: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+	}
+
+	if err := m.sshConfig.Save(); err != nil {
+		m.message = fmt.Sprintf("Failed to save config: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.message = c.lastMessage
+	if c.total() > 1 {
+		m.message = fmt.Sprintf("%d added, %d overwritten, %d merged, %d skipped",
+			c.added, c.overwritten, c.merged, c.skipped)
+	}
+	m.messageType = "success"
+	if c.copiedMetadata {
+		if err := m.metadata.Save(); err != nil {
+			m.message = fmt.Sprintf("%s, but failed to save host metadata: %v", m.message, err)
+			m.messageType = "error"
+		}
+	}
+
+	// Reload hosts and return to list
+	m.hosts = m.sshConfig.Hosts
+	m.filteredHosts = m.listedHosts()
+	m.viewMode = ModeList
+	m.editIndex = -1
+	return m, nil
+}
+
+// handleAliasConflictMode handles keys while an alias conflict is shown
+func (m Model) handleAliasConflictMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.conflict
+	if c == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	if c.renaming {
+		return m.handleAliasConflictRename(msg)
+	}
+
+	switch msg.String() {
+	case "o":
+		// Replace the existing host, keeping its position
+		m.sshConfig.UpdateHost(c.existing.Name, c.incoming)
+		c.overwritten++
+		c.lastMessage = fmt.Sprintf("Host '%s' overwritten", c.incoming.Name)
+		return m.continueAddingHosts()
+
+	case "m":
+		// Fill the existing host with the fields set on the new one
+		m.sshConfig.UpdateHost(c.existing.Name, config.MergeHosts(c.existing, c.incoming))
+		c.merged++
+		c.lastMessage = fmt.Sprintf("Host '%s' merged", c.incoming.Name)
+		return m.continueAddingHosts()
+
+	case "r":
+		c.renaming = true
+		c.rename = textinput.New()
+		c.rename.Prompt = "New alias: "
+		c.rename.CharLimit = 100
+		c.rename.SetValue(m.copyAlias(c.incoming.Name))
+		c.rename.CursorEnd()
+		return m, c.rename.Focus()
+
+	case "s", "esc":
+		c.skipped++
+		return m.continueAddingHosts()
+	}
+
+	return m, nil
+}
+
+// handleAliasConflictRename handles keys while a new alias is typed for the
+// incoming host
+func (m Model) handleAliasConflictRename(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.conflict
+
+	switch msg.String() {
+	case "esc":
+		c.renaming = false
+		c.rename.Blur()
+		m.message = ""
+		m.messageType = ""
+		return m, nil
+
+	case "enter":
+		alias := strings.TrimSpace(c.rename.Value())
+		err := config.ValidateAlias(alias)
+		if alias == "" {
+			err = fmt.Errorf("alias is required")
+		} else if m.sshConfig.FindHost(alias) >= 0 {
+			err = fmt.Errorf("host alias '%s' already exists", alias)
+		}
+		if err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return m, nil
+		}
+
+		c.incoming.Name = alias
+		m.reuseAlias(alias)
+		m.sshConfig.AddHost(c.incoming)
+		m.copyFormMetadata(alias)
+		m.hosts = m.sshConfig.Hosts
+		c.added++
+		c.lastMessage = fmt.Sprintf("Host '%s' added", alias)
+		return m.continueAddingHosts()
+	}
+
+	var cmd tea.Cmd
+	c.rename, cmd = c.rename.Update(msg)
+	return m, cmd
+}
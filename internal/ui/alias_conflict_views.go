@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// renderAliasConflictView renders the diff between the configured host and
+// the one being added, with the ways to resolve the conflict
+func (m Model) renderAliasConflictView() string {
+	var content strings.Builder
+	c := m.conflict
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Alias Conflict")
+	content.WriteString(header + "\n\n")
+
+	intro := fmt.Sprintf("A host named '%s' already exists.", c.incoming.Name)
+	if len(c.pending) > 0 {
+		intro += fmt.Sprintf(" %d more host(s) to add after this one.", len(c.pending))
+	}
+	content.WriteString(intro + "\n\n")
+
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	content.WriteString(panelStyle.Render(m.renderHostDiff(c.existing, c.incoming, m.width-8)) + "\n")
+
+	// Rename prompt or message
+	if c.renaming {
+		content.WriteString(c.rename.View() + "\n")
+	}
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "o: overwrite • m: merge • r: rename • s/ESC: skip"
+	if c.fromForm {
+		help = "o: overwrite • m: merge • r: rename • ESC: back"
+	}
+	if c.renaming {
+		help = "Type the new alias • Enter: add • ESC: cancel"
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// renderHostDiff renders the fields of the configured and the new host side
+// by side, along with the result of merging them. Changed fields stand out.
+func (m Model) renderHostDiff(existing, incoming config.SSHHost, width int) string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	oldStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	newStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	sameStyle := lipgloss.NewStyle().Foreground(m.theme.Subtle)
+
+	fieldWidth := 14
+	columnWidth := max(10, (width-fieldWidth-2)/3)
+	cell := func(value string) string {
+		return padAndTruncate(value, columnWidth-1) + " "
+	}
+	row := func(field, current, incoming, merged string) string {
+		return padAndTruncate(field, fieldWidth) + cell(current) + cell(incoming) + cell(merged)
+	}
+
+	var lines []string
+	lines = append(lines, titleStyle.Render(row("", "Current", "New", "Merged")))
+
+	merged := config.DiffHosts(existing, config.MergeHosts(existing, incoming))
+	for i, diff := range config.DiffHosts(existing, incoming) {
+		current, next := orNone(diff.Old), orNone(diff.New)
+		result := orNone(merged[i].New)
+		if !diff.Changed() {
+			lines = append(lines, sameStyle.Render(row(diff.Field, current, next, result)))
+			continue
+		}
+		line := padAndTruncate(diff.Field, fieldWidth) +
+			oldStyle.Render(cell(current)) +
+			newStyle.Render(cell(next)) +
+			cell(result)
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// orNone returns value, or a placeholder for an unset field
+func orNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// colorPicker holds the state of the label color picker
+type colorPicker struct {
+	hosts  []config.SSHHost // Hosts being labeled
+	cursor int              // Index into colorChoices
+}
+
+// colorChoices are the picker entries: no label, then every label color
+func colorChoices() []string {
+	return append([]string{""}, config.LabelColors...)
+}
+
+// openColorPicker picks a label color for the marked hosts, or for the
+// selected host
+func (m Model) openColorPicker() (tea.Model, tea.Cmd) {
+	picker := &colorPicker{hosts: m.markedOrSelectedHosts()}
+
+	// Start on the current color when all hosts share it
+	current := m.metadata.Color(picker.hosts[0].Name)
+	for _, host := range picker.hosts[1:] {
+		if m.metadata.Color(host.Name) != current {
+			current = ""
+		}
+	}
+	for i, color := range colorChoices() {
+		if color == current {
+			picker.cursor = i
+		}
+	}
+
+	m.colors = picker
+	m.viewMode = ModeColorLabel
+	m.message = ""
+	m.messageType = ""
+	return m, nil
+}
+
+// handleColorLabelMode handles keys in the label color picker
+func (m Model) handleColorLabelMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.colors
+	if picker == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+	choices := colorChoices()
+
+	switch key := msg.String(); key {
+	case "esc", "q":
+		m.viewMode = ModeList
+		m.colors = nil
+
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+
+	case "down", "j":
+		if picker.cursor < len(choices)-1 {
+			picker.cursor++
+		}
+
+	case "enter":
+		return m.applyColorLabel(choices[picker.cursor])
+
+	default:
+		// Digits pick an entry directly, 0 being no label
+		if len(key) == 1 && key[0] >= '0' && int(key[0]-'0') < len(choices) {
+			return m.applyColorLabel(choices[key[0]-'0'])
+		}
+	}
+
+	return m, nil
+}
+
+// applyColorLabel labels the picked hosts with color and returns to the list
+func (m Model) applyColorLabel(color string) (tea.Model, tea.Cmd) {
+	hosts := m.colors.hosts
+	m.colors = nil
+	m.viewMode = ModeList
+
+	for _, host := range hosts {
+		if err := m.metadata.SetColor(host.Name, color); err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return m, nil
+		}
+	}
+
+	if color == "" {
+		m.saveMetadata(fmt.Sprintf("Removed the color label from %d host(s)", len(hosts)))
+	} else {
+		m.saveMetadata(fmt.Sprintf("Labeled %d host(s) %s", len(hosts), color))
+	}
+	return m, nil
+}
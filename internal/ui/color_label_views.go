@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// labelColors maps label color names to terminal colors. They are the same
+// in every theme so a red host always looks red.
+var labelColors = map[string]lipgloss.Color{
+	"red":    "#D70000",
+	"orange": "#FF8700",
+	"yellow": "#D7AF00",
+	"green":  "#00AF00",
+	"blue":   "#0087FF",
+	"purple": "#AF5FD7",
+	"gray":   "#808080",
+}
+
+// hostLabelColor returns the terminal color of a host's label, if it has one
+func (m Model) hostLabelColor(name string) (lipgloss.Color, bool) {
+	color, ok := labelColors[m.metadata.Color(name)]
+	return color, ok
+}
+
+// renderColorLabelView renders the label color picker
+func (m Model) renderColorLabelView() string {
+	var content strings.Builder
+	picker := m.colors
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Color Label")
+	content.WriteString(header + "\n\n")
+
+	names := make([]string, len(picker.hosts))
+	for i, host := range picker.hosts {
+		names[i] = host.Name
+	}
+	content.WriteString("Hosts: " + padAndTruncate(strings.Join(names, ", "), max(10, m.width-8)) + "\n\n")
+
+	selectedStyle := m.theme.SelectedStyle()
+	for i, color := range colorChoices() {
+		cursor := "  "
+		if i == picker.cursor {
+			cursor = "▶ "
+		}
+
+		swatch := "  "
+		name := "none"
+		if color != "" {
+			swatch = lipgloss.NewStyle().Background(labelColors[color]).Render("  ")
+			name = color
+		}
+		line := fmt.Sprintf("%s%d. ", cursor, i)
+
+		if i == picker.cursor {
+			content.WriteString(selectedStyle.Render(line) + swatch + " " + selectedStyle.Render(name) + "\n")
+		} else {
+			content.WriteString(line + swatch + " " + name + "\n")
+		}
+	}
+	content.WriteString("\n")
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • Enter or 0-7: pick • ESC: cancel"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// ConnectionBanner returns the line printed before connecting to a host.
+// A labeled host gets a banner in its label color, so a production host is
+// hard to mistake for another one.
+func ConnectionBanner(host config.SSHHost, color string) string {
+	target := host.Host
+	if host.User != "" {
+		target = host.User + "@" + target
+	}
+	text := fmt.Sprintf("Connecting to %s (%s)...", host.Name, target)
+
+	background, ok := labelColors[color]
+	if !ok {
+		return text
+	}
+	foreground := lipgloss.Color("#FFFFFF")
+	if color == "yellow" || color == "orange" {
+		foreground = "#000000"
+	}
+	return lipgloss.NewStyle().
+		Bold(true).
+		Foreground(foreground).
+		Background(background).
+		Padding(0, 1).
+		Render(strings.ToUpper(color) + " │ " + text)
+}
@@ -0,0 +1,466 @@
+package ui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	gossh "golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// maxCommandOutputLines is the number of output lines kept in the runner
+const maxCommandOutputLines = 5000
+
+// errCommandCanceled is reported for hosts whose command was stopped
+var errCommandCanceled = errors.New("canceled")
+
+// commandRun is one command running on one or more hosts
+type commandRun struct {
+	command   string
+	started   time.Time
+	remaining int // Hosts that have not reported their exit yet
+	failed    int
+	messages  chan tea.Msg
+	ctx       context.Context // Canceled when the run is stopped or the TUI exits
+	cancel    context.CancelFunc
+	canceled  bool
+	tracked   *trackedSession // Entry of the run in the sessions view
+	recording recordingSettings
+}
+
+// stop asks every host of the run to give up
+func (r *commandRun) stop() {
+	if !r.canceled {
+		r.canceled = true
+		r.cancel()
+	}
+}
+
+// commandRunner holds the state of the remote command runner
+type commandRunner struct {
+	hosts        []config.SSHHost
+	input        textinput.Model
+	output       viewport.Model
+	lines        []string
+	run          *commandRun
+	history      []string // Previously run commands, oldest first
+	historyIndex int      // Position while browsing history; len(history) is the draft
+	draft        string   // Command typed before browsing history
+}
+
+// commandOutputMsg carries one line of output from a host
+type commandOutputMsg struct {
+	run    *commandRun
+	host   string
+	line   string
+	stderr bool
+}
+
+// commandExitMsg reports that the command finished on a host
+type commandExitMsg struct {
+	run  *commandRun
+	host string
+	err  error
+}
+
+// openCommandRunner switches to the command runner for the marked hosts, or
+// for the host under the cursor when none are marked
+func (m Model) openCommandRunner() (tea.Model, tea.Cmd) {
+	hosts := m.markedOrSelectedHosts()
+
+	history, err := config.LoadCommandHistory()
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to load command history: %v", err)
+		m.messageType = "error"
+	} else {
+		m.message = ""
+	}
+
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.Placeholder = "uptime"
+
+	m.runner = &commandRunner{
+		hosts:        hosts,
+		input:        input,
+		output:       viewport.New(0, 0),
+		history:      history,
+		historyIndex: len(history),
+	}
+	m.runner.resize(m.width, m.height)
+	m.viewMode = ModeCommandRunner
+	return m, m.runner.input.Focus()
+}
+
+// resize fits the output pane to the terminal, leaving room for the header,
+// host line, command input, message and help
+func (r *commandRunner) resize(width, height int) {
+	r.output.Width = max(10, width-6)
+	r.output.Height = max(3, height-14)
+}
+
+// handleCommandRunnerMode handles keys in the command runner
+func (m Model) handleCommandRunnerMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := m.runner
+	if r == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		if r.run != nil && r.run.remaining > 0 {
+			r.run.stop()
+			m.message = "Stopping command..."
+			m.messageType = "info"
+			return m, nil
+		}
+		r.input.Blur()
+		m.runner = nil
+		m.message = ""
+		m.viewMode = ModeList
+		return m, nil
+
+	case "ctrl+b":
+		// Leave the command running; it stays listed in the sessions view
+		r.input.Blur()
+		m.runner = nil
+		m.viewMode = ModeList
+		if r.run != nil && r.run.remaining > 0 {
+			m.message = "Command keeps running in the background • S: sessions"
+			m.messageType = "info"
+		}
+		return m, nil
+
+	case "enter":
+		return m.runCommand()
+
+	case "up":
+		r.browseHistory(-1)
+
+	case "down":
+		r.browseHistory(1)
+
+	case "pgup":
+		r.output.HalfPageUp()
+
+	case "pgdown":
+		r.output.HalfPageDown()
+
+	case "ctrl+home":
+		r.output.GotoTop()
+
+	case "ctrl+end":
+		r.output.GotoBottom()
+
+	case "ctrl+l":
+		r.lines = nil
+		r.output.SetContent("")
+
+	default:
+		var cmd tea.Cmd
+		r.input, cmd = r.input.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+// browseHistory moves through previously run commands like a shell does,
+// keeping the unfinished command as the newest entry
+func (r *commandRunner) browseHistory(step int) {
+	index := r.historyIndex + step
+	if index < 0 || index > len(r.history) {
+		return
+	}
+
+	if r.historyIndex == len(r.history) {
+		r.draft = r.input.Value()
+	}
+	r.historyIndex = index
+
+	if index == len(r.history) {
+		r.input.SetValue(r.draft)
+	} else {
+		r.input.SetValue(r.history[index])
+	}
+	r.input.CursorEnd()
+}
+
+// runCommand starts the typed command on every host of the runner
+func (m Model) runCommand() (tea.Model, tea.Cmd) {
+	r := m.runner
+
+	command := strings.TrimSpace(r.input.Value())
+	if command == "" {
+		return m, nil
+	}
+	if r.run != nil && r.run.remaining > 0 {
+		m.message = "A command is still running • ESC to stop it"
+		m.messageType = "error"
+		return m, nil
+	}
+
+	r.history = config.AppendCommandHistory(r.history, command)
+	r.historyIndex = len(r.history)
+	r.draft = ""
+	r.input.SetValue("")
+	if err := config.SaveCommandHistory(r.history); err != nil {
+		m.message = fmt.Sprintf("Failed to save command history: %v", err)
+		m.messageType = "error"
+	} else {
+		m.message = ""
+	}
+
+	run := &commandRun{
+		command:   command,
+		started:   time.Now(),
+		remaining: len(r.hosts),
+		messages:  make(chan tea.Msg),
+		recording: m.recording,
+	}
+	run.ctx, run.cancel = context.WithCancel(m.ctx)
+	r.run = run
+	hosts := make([]string, len(r.hosts))
+	for i, host := range r.hosts {
+		hosts[i] = host.Name
+	}
+	run.tracked = m.sessions.track("exec", strings.Join(hosts, ", "), command, run.stop)
+
+	r.appendOutput(fmt.Sprintf("$ %s", command))
+	for _, host := range r.hosts {
+		go runRemoteCommand(run, host, m.formData.KeyPassword)
+	}
+	return m, waitForCommand(run)
+}
+
+// waitForCommand delivers the next output or exit message of a run
+func waitForCommand(run *commandRun) tea.Cmd {
+	return func() tea.Msg {
+		return <-run.messages
+	}
+}
+
+// runRemoteCommand runs the command on one host, streaming its output as
+// messages and finishing with a commandExitMsg
+func runRemoteCommand(run *commandRun, host config.SSHHost, keyPassword string) {
+	started := time.Now()
+	recordAudit(config.AuditEvent{Action: config.AuditExec, Host: host.Name, Detail: run.command})
+	var received int64
+	var recordingPath string
+	err := func() error {
+		conn, err := ssh.DialContext(run.ctx, host, keyPassword)
+		if run.ctx.Err() != nil {
+			if err == nil {
+				conn.Close()
+			}
+			return errCommandCanceled
+		}
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		session, err := conn.NewSession()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+
+		stdout, err := session.StdoutPipe()
+		if err != nil {
+			return err
+		}
+		stderr, err := session.StderrPipe()
+		if err != nil {
+			return err
+		}
+
+		if err := session.Start(run.command); err != nil {
+			return err
+		}
+		if recorder := run.recording.start(host.Name, run.command); recorder != nil {
+			recordingPath = recorder.Path()
+			defer func() {
+				if err := recorder.Close(); err != nil {
+					slog.Warn("failed to write recording", "path", recordingPath, "error", err)
+				}
+			}()
+			stdout = io.TeeReader(stdout, recorder)
+			stderr = io.TeeReader(stderr, recorder)
+		}
+
+		var streams sync.WaitGroup
+		streams.Add(2)
+		go streamCommandOutput(run, host.Name, stdout, false, &received, &streams)
+		go streamCommandOutput(run, host.Name, stderr, true, &received, &streams)
+
+		finished := make(chan error, 1)
+		go func() {
+			streams.Wait()
+			finished <- session.Wait()
+		}()
+
+		select {
+		case err := <-finished:
+			return err
+		case <-run.ctx.Done():
+			// Closing the connection ends both output streams
+			session.Signal(gossh.SIGINT)
+			conn.Close()
+			<-finished
+			return errCommandCanceled
+		}
+	}()
+
+	recordRemoteCommand(run.command, host.Name, started, atomic.LoadInt64(&received), recordingPath, err)
+	run.messages <- commandExitMsg{run: run, host: host.Name, err: err}
+}
+
+// recordRemoteCommand adds the run of command on a host to the session
+// store, with the recording of its output if there is one. Failing to
+// record it is only logged.
+func recordRemoteCommand(command, host string, started time.Time, received int64, recordingPath string, err error) {
+	record := config.SessionRecord{
+		Kind:          config.SessionExec,
+		Host:          host,
+		Start:         started,
+		End:           time.Now(),
+		BytesReceived: received,
+		Detail:        command,
+		Recording:     recordingPath,
+	}
+	var exitErr *gossh.ExitError
+	if errors.As(err, &exitErr) {
+		record.ExitCode = exitErr.ExitStatus()
+	} else if err != nil {
+		record.ExitCode = -1
+		record.Error = err.Error()
+	}
+	if err := config.RecordSession(record); err != nil {
+		slog.Warn("failed to record command run", "host", host, "error", err)
+	}
+}
+
+// streamCommandOutput sends every line read from an output stream, adding
+// the bytes read to received
+func streamCommandOutput(run *commandRun, host string, stream io.Reader, stderr bool, received *int64, streams *sync.WaitGroup) {
+	defer streams.Done()
+
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		atomic.AddInt64(received, int64(len(scanner.Bytes())+1))
+		run.messages <- commandOutputMsg{run: run, host: host, line: scanner.Text(), stderr: stderr}
+	}
+}
+
+// handleCommandOutput appends a line of output to the pane
+func (m Model) handleCommandOutput(msg commandOutputMsg) (tea.Model, tea.Cmd) {
+	if m.runner == nil || m.runner.run != msg.run {
+		// Keep draining a run whose runner was closed
+		return m, waitForCommand(msg.run)
+	}
+
+	line := msg.line
+	if msg.stderr {
+		line = lipgloss.NewStyle().Foreground(m.theme.Error).Render(line)
+	}
+	m.runner.appendOutput(m.runner.hostPrefix(msg.host) + line)
+	return m, waitForCommand(msg.run)
+}
+
+// handleCommandExit records that a host finished and reports the run once
+// every host is done
+func (m Model) handleCommandExit(msg commandExitMsg) (tea.Model, tea.Cmd) {
+	run := msg.run
+	run.remaining--
+	if msg.err != nil {
+		run.failed++
+	}
+	if run.remaining == 0 {
+		switch {
+		case run.canceled:
+			run.tracked.finish(errCommandCanceled)
+		case run.failed > 0:
+			run.tracked.finish(fmt.Errorf("failed on %d host(s)", run.failed))
+		default:
+			run.tracked.finish(nil)
+		}
+	}
+
+	if m.runner == nil || m.runner.run != run {
+		if run.remaining > 0 {
+			return m, waitForCommand(run)
+		}
+		return m, nil
+	}
+
+	r := m.runner
+	var exitErr *gossh.ExitError
+	switch {
+	case msg.err == nil:
+		if len(r.hosts) > 1 {
+			r.appendOutput(r.hostPrefix(msg.host) + "✓ done")
+		}
+	case errors.As(msg.err, &exitErr):
+		r.appendOutput(r.hostPrefix(msg.host) + fmt.Sprintf("✗ exit status %d", exitErr.ExitStatus()))
+	default:
+		r.appendOutput(r.hostPrefix(msg.host) + fmt.Sprintf("✗ %v", msg.err))
+	}
+
+	if run.remaining > 0 {
+		return m, waitForCommand(run)
+	}
+
+	elapsed := time.Since(run.started).Round(time.Millisecond)
+	switch {
+	case run.canceled:
+		m.message = "Command stopped"
+		m.messageType = "info"
+	case run.failed > 0:
+		m.message = fmt.Sprintf("Command failed on %d of %d hosts (%v)", run.failed, len(r.hosts), elapsed)
+		m.messageType = "error"
+	default:
+		m.message = fmt.Sprintf("Command finished on %d host(s) in %v", len(r.hosts), elapsed)
+		m.messageType = "success"
+	}
+	return m, nil
+}
+
+// hostPrefix labels output lines with their host when running on several
+func (r *commandRunner) hostPrefix(host string) string {
+	if len(r.hosts) < 2 {
+		return ""
+	}
+	return fmt.Sprintf("[%s] ", host)
+}
+
+// appendOutput adds a line to the output pane, following the end of the
+// output unless the user scrolled up
+func (r *commandRunner) appendOutput(line string) {
+	following := r.output.AtBottom()
+
+	r.lines = append(r.lines, line)
+	if len(r.lines) > maxCommandOutputLines {
+		r.lines = r.lines[len(r.lines)-maxCommandOutputLines:]
+	}
+	r.output.SetContent(strings.Join(r.lines, "\n"))
+
+	if following {
+		r.output.GotoBottom()
+	}
+}
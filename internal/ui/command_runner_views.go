@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderCommandRunnerView renders the command input and the output pane
+func (m Model) renderCommandRunnerView() string {
+	var content strings.Builder
+	r := m.runner
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Run Remote Command")
+	content.WriteString(header + "\n\n")
+
+	// Target hosts
+	names := make([]string, 0, len(r.hosts))
+	for _, host := range r.hosts {
+		names = append(names, host.Name)
+	}
+	hostStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true).
+		Width(m.width)
+	content.WriteString(hostStyle.Render(fmt.Sprintf("Hosts (%d): %s", len(names), strings.Join(names, ", "))) + "\n")
+
+	// Command input
+	inputStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Accent).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	input := r.input
+	input.Width = max(1, m.width-12)
+	content.WriteString(inputStyle.Render(input.View()) + "\n")
+
+	// Output pane
+	outputStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	if len(r.lines) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle).
+			Italic(true).
+			Height(r.output.Height)
+		content.WriteString(outputStyle.Render(emptyStyle.Render("Output will appear here")) + "\n")
+	} else {
+		content.WriteString(outputStyle.Render(r.output.View()) + "\n")
+	}
+
+	// Status line
+	if r.run != nil && r.run.remaining > 0 {
+		statusStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Warning).
+			Width(m.width)
+		status := fmt.Sprintf("⏳ Running on %d of %d host(s)...", r.run.remaining, len(r.hosts))
+		content.WriteString(statusStyle.Render(status) + "\n")
+	}
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Enter: run • ↑/↓: history • PgUp/PgDn: scroll output • Ctrl+L: clear • Ctrl+B: background • ESC: stop/back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,202 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// connectionTest is one run of the add-host connection test
+type connectionTest struct {
+	host     config.SSHHost
+	keyAuth  bool            // Whether the test logs in with the identity file
+	steps    []ssh.SetupStep // Steps this test goes through, in order
+	step     ssh.SetupStep   // Step currently running
+	started  time.Time
+	finished time.Duration // Total duration once the test is over
+	failed   bool
+	result   ssh.SetupResult // Outcome once the test is over
+	spinner  spinner.Model
+	messages chan tea.Msg
+	cancel   context.CancelFunc
+}
+
+// running reports whether the test has not finished yet
+func (t *connectionTest) running() bool {
+	return t.finished == 0
+}
+
+// elapsed returns how long the test has been running, or took
+func (t *connectionTest) elapsed() time.Duration {
+	if t.running() {
+		return time.Since(t.started)
+	}
+	return t.finished
+}
+
+// connectionStepMsg reports that a connection test reached a new step
+type connectionStepMsg struct {
+	test *connectionTest
+	step ssh.SetupStep
+}
+
+// connectionDoneMsg carries the result of a connection test
+type connectionDoneMsg struct {
+	test   *connectionTest
+	result ssh.SetupResult
+}
+
+// startConnectionTest begins the connection test process
+func (m Model) startConnectionTest() (tea.Model, tea.Cmd) {
+	m.viewMode = ModeConnectTest
+	m.setupProgress = "Testing connection..."
+	m.isSetupDone = false
+	m.hostKeyMismatch = false
+	m.message = ""
+	m.messageType = ""
+
+	// Create host config for testing
+	host := config.SSHHost{
+		Name:      m.formData.Alias,
+		Host:      m.formData.Host,
+		User:      m.formData.User,
+		Port:      m.formData.Port,
+		Identity:  m.formData.Identity,
+		ProxyJump: m.formData.ProxyJump,
+	}
+	keyAuth := m.formData.AuthType == AuthKey && m.formData.Identity != ""
+
+	ctx, cancel := context.WithCancel(context.Background())
+	test := &connectionTest{
+		host:     host,
+		keyAuth:  keyAuth,
+		steps:    []ssh.SetupStep{ssh.StepDial, ssh.StepAuth, ssh.StepVerify},
+		step:     ssh.StepDial,
+		started:  time.Now(),
+		spinner:  spinner.New(spinner.WithSpinner(m.theme.spinner())),
+		messages: make(chan tea.Msg, 8),
+		cancel:   cancel,
+	}
+	if !keyAuth {
+		test.steps = []ssh.SetupStep{ssh.StepDial, ssh.StepAuth, ssh.StepInstallKey, ssh.StepVerify}
+	}
+	m.connTest = test
+
+	password := m.formData.Password
+	keyPassword := m.formData.KeyPassword
+	go func() {
+		progress := func(step ssh.SetupStep) {
+			test.messages <- connectionStepMsg{test: test, step: step}
+		}
+
+		// Test connection based on auth type
+		var result ssh.SetupResult
+		if keyAuth {
+			// Test key-based connection with or without password
+			result = ssh.TestConnectionWithKeyPassword(ctx, host, keyPassword, progress)
+		} else {
+			// Test password connection and set up keys
+			result = ssh.TestConnection(ctx, host, password, progress)
+		}
+		test.messages <- connectionDoneMsg{test: test, result: result}
+	}()
+
+	return m, tea.Batch(waitForConnectionTest(test), test.spinner.Tick)
+}
+
+// waitForConnectionTest waits for the next progress message of a test
+func waitForConnectionTest(test *connectionTest) tea.Cmd {
+	return func() tea.Msg {
+		return <-test.messages
+	}
+}
+
+// handleConnectionStep records the step a connection test reached
+func (m Model) handleConnectionStep(msg connectionStepMsg) (tea.Model, tea.Cmd) {
+	if msg.test != m.connTest {
+		// The test was canceled; its channel is buffered so it never blocks
+		return m, nil
+	}
+	msg.test.step = msg.step
+	m.setupProgress = msg.step.String() + "..."
+	return m, waitForConnectionTest(msg.test)
+}
+
+// handleConnectionDone shows the outcome of a connection test
+func (m Model) handleConnectionDone(msg connectionDoneMsg) (tea.Model, tea.Cmd) {
+	test := msg.test
+	test.cancel()
+	if test != m.connTest {
+		return m, nil
+	}
+	test.finished = time.Since(test.started)
+	test.result = msg.result
+
+	result := msg.result
+	slog.Info("connection test finished", "host", m.formData.Alias, "success", result.Success,
+		"duration", test.finished.Round(time.Millisecond), "message", result.Message)
+	if result.Success {
+		// Remember the key that authenticated, which setup may have created
+		if result.Identity != "" {
+			m.formData.Identity = result.Identity
+			m.formData.AuthType = AuthKey
+		}
+		m.setupProgress = "Connection successful! SSH keys configured."
+		m.isSetupDone = true
+		return m, nil
+	}
+
+	test.failed = true
+	m.setupProgress = fmt.Sprintf("Error: %s", result.Message)
+	m.message = result.Message
+	m.messageType = "error"
+	m.hostKeyMismatch = ssh.IsHostKeyMismatch(result.Error)
+	return m.openErrorDetail(connectionTestErrorDetail(test))
+}
+
+// cancelConnectionTest stops a running connection test
+func (m *Model) cancelConnectionTest() {
+	if m.connTest != nil {
+		m.connTest.cancel()
+		m.connTest = nil
+	}
+}
+
+// renderConnectionSteps renders the steps of the connection test with their
+// state and the elapsed time
+func (m Model) renderConnectionSteps() string {
+	test := m.connTest
+
+	doneStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	failedStyle := lipgloss.NewStyle().Foreground(m.theme.Error)
+	pendingStyle := lipgloss.NewStyle().Foreground(m.theme.Subtle)
+	currentStyle := lipgloss.NewStyle().Foreground(m.theme.Primary).Bold(true)
+
+	var lines []string
+	for _, step := range test.steps {
+		var line string
+		switch {
+		case step < test.step || (step == test.step && m.isSetupDone):
+			line = doneStyle.Render("✓ " + step.String())
+		case step == test.step && test.failed:
+			line = failedStyle.Render("✗ " + step.String())
+		case step == test.step && test.running():
+			line = currentStyle.Render(test.spinner.View() + step.String())
+		default:
+			line = pendingStyle.Render("· " + step.String())
+		}
+		lines = append(lines, "  "+line)
+	}
+
+	elapsed := fmt.Sprintf("Elapsed: %.1fs", test.elapsed().Seconds())
+	lines = append(lines, "", "  "+pendingStyle.Render(elapsed))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// containerPicker holds the state of the Docker container list of a host
+type containerPicker struct {
+	host       config.SSHHost
+	containers []ssh.Container
+	loading    bool
+	err        error
+	cursor     int
+	returnMode ViewMode
+}
+
+// containersListedMsg carries the containers running on a host
+type containersListedMsg struct {
+	host       string
+	containers []ssh.Container
+	err        error
+}
+
+// openContainers lists the running Docker containers of the selected host
+func (m Model) openContainers() (tea.Model, tea.Cmd) {
+	host := m.filteredHosts[m.cursor]
+	m.containers = &containerPicker{host: host, returnMode: m.viewMode}
+	m.viewMode = ModeContainers
+	return m, m.listContainers()
+}
+
+// listContainers runs "docker ps" on the picker's host in the background
+func (m *Model) listContainers() tea.Cmd {
+	ctx, host, keyPassword := m.ctx, m.containers.host, m.formData.KeyPassword
+	m.containers.loading = true
+	return func() tea.Msg {
+		conn, err := ssh.DialContext(ctx, host, keyPassword)
+		if err != nil {
+			return containersListedMsg{host: host.Name, err: err}
+		}
+		defer conn.Close()
+		containers, err := ssh.ListContainers(ssh.NewConn(conn))
+		return containersListedMsg{host: host.Name, containers: containers, err: err}
+	}
+}
+
+// handleContainersListed shows the containers of the host
+func (m Model) handleContainersListed(msg containersListedMsg) (tea.Model, tea.Cmd) {
+	picker := m.containers
+	if picker == nil || picker.host.Name != msg.host {
+		// The picker was closed, or opened for another host, meanwhile
+		return m, nil
+	}
+	picker.loading = false
+	picker.containers = msg.containers
+	picker.err = msg.err
+	if picker.cursor >= len(picker.containers) {
+		picker.cursor = max(0, len(picker.containers)-1)
+	}
+	return m, nil
+}
+
+// handleContainersMode handles keys in the container list
+func (m Model) handleContainersMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.containers
+	if picker == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc", "q":
+		m.viewMode = picker.returnMode
+		m.containers = nil
+
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+
+	case "down", "j":
+		if picker.cursor < len(picker.containers)-1 {
+			picker.cursor++
+		}
+
+	case "enter", "b":
+		// Connect to the host and open a shell in the container
+		if picker.cursor >= len(picker.containers) {
+			return m, nil
+		}
+		shell := ssh.DefaultContainerShell
+		if msg.String() == "b" {
+			shell = "bash"
+		}
+		container := picker.containers[picker.cursor]
+		m.selectedHost = &picker.host
+		m.selectedArgs = ssh.ContainerShellArgs(container.Name, shell)
+		return m.quit()
+
+	case "R", "ctrl+r":
+		if !picker.loading {
+			return m, m.listContainers()
+		}
+	}
+
+	return m, nil
+}
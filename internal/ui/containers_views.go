@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderContainersView renders the Docker container list of a host
+func (m Model) renderContainersView() string {
+	var content strings.Builder
+	picker := m.containers
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Containers on " + picker.host.Name)
+	content.WriteString(header + "\n\n")
+
+	// Container list
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	rows := max(3, m.height-10)
+	innerWidth := m.width - 8
+
+	emptyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Italic(true)
+
+	var list strings.Builder
+	if picker.loading && len(picker.containers) == 0 {
+		list.WriteString(emptyStyle.Render("Running docker ps on " + picker.host.Name + "..."))
+	} else if len(picker.containers) == 0 {
+		list.WriteString(emptyStyle.Render("No running containers"))
+	} else {
+		start := 0
+		if picker.cursor >= rows {
+			start = picker.cursor - rows + 1
+		}
+		end := min(len(picker.containers), start+rows)
+
+		selectedStyle := m.theme.SelectedStyle()
+		for i := start; i < end; i++ {
+			container := picker.containers[i]
+
+			cursor := "  "
+			if i == picker.cursor {
+				cursor = "▶ "
+			}
+
+			// Short ID, name, image and status
+			id := container.ID
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			statusWidth := max(10, innerWidth-len(cursor)-13-25-31)
+			line := fmt.Sprintf("%s%-12s %s %s %s", cursor, id,
+				padAndTruncate(container.Name, 24), padAndTruncate(container.Image, 30),
+				padAndTruncate(container.Status, statusWidth))
+
+			if i == picker.cursor {
+				list.WriteString(selectedStyle.Render(line) + "\n")
+			} else {
+				list.WriteString(line + "\n")
+			}
+		}
+	}
+	content.WriteString(panelStyle.Render(strings.TrimRight(list.String(), "\n")) + "\n")
+
+	// Failure of docker ps, or the last message
+	if picker.err != nil {
+		content.WriteString(m.theme.MessageStyle("error", m.width).Render(picker.err.Error()) + "\n")
+	} else {
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+	help := "↑/k ↓/j: move • Enter: shell (sh) in container • b: bash • R: refresh • ESC/q: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// copyAction is one entry of the copy menu
+type copyAction struct {
+	key   string // Key choosing the entry directly
+	label string
+	text  func(host config.SSHHost) (string, error)
+}
+
+// copyActions lists what the copy menu can put on the clipboard
+var copyActions = []copyAction{
+	{"c", "SSH command", func(host config.SSHHost) (string, error) {
+		return ssh.BuildSSHCommand(host), nil
+	}},
+	{"h", "Hostname/IP", func(host config.SSHHost) (string, error) {
+		return host.Host, nil
+	}},
+	{"s", "scp command template", func(host config.SSHHost) (string, error) {
+		return ssh.BuildSCPCommand(host), nil
+	}},
+	{"f", "sftp command", func(host config.SSHHost) (string, error) {
+		return ssh.BuildSFTPCommand(host), nil
+	}},
+	{"p", "Public key", func(host config.SSHHost) (string, error) {
+		if host.Identity == "" {
+			return "", fmt.Errorf("%s uses password authentication", host.Name)
+		}
+		return ssh.ReadPublicKey(host.Identity)
+	}},
+}
+
+// copyMenu is the menu of things to copy for a host
+type copyMenu struct {
+	host   config.SSHHost
+	cursor int
+}
+
+// openCopyMenu shows the copy menu for the selected host
+func (m Model) openCopyMenu() (tea.Model, tea.Cmd) {
+	m.copyMenu = &copyMenu{host: m.filteredHosts[m.cursor]}
+	m.viewMode = ModeCopyMenu
+	return m, nil
+}
+
+// copyToClipboard copies text and reports what was copied
+func (m *Model) copyToClipboard(what, text string) {
+	if err := ssh.CopyToClipboard(text); err != nil {
+		m.message = fmt.Sprintf("Failed to copy %s: %v", what, err)
+		m.messageType = "error"
+		return
+	}
+	m.message = fmt.Sprintf("%s copied to clipboard", what)
+	m.messageType = "success"
+}
+
+// runCopyAction copies the text of an entry and closes the menu
+func (m Model) runCopyAction(action copyAction) (tea.Model, tea.Cmd) {
+	host := m.copyMenu.host
+	m.copyMenu = nil
+	m.viewMode = ModeList
+
+	text, err := action.text(host)
+	if err != nil {
+		m.message = fmt.Sprintf("Cannot copy %s: %v", action.label, err)
+		m.messageType = "error"
+		return m, nil
+	}
+	m.copyToClipboard(action.label, text)
+	return m, nil
+}
+
+// handleCopyMenuMode handles keys in the copy menu
+func (m Model) handleCopyMenuMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	menu := m.copyMenu
+
+	switch msg.String() {
+	case "esc", "q":
+		m.copyMenu = nil
+		m.viewMode = ModeList
+
+	case "up", "k":
+		if menu.cursor > 0 {
+			menu.cursor--
+		}
+
+	case "down", "j":
+		if menu.cursor < len(copyActions)-1 {
+			menu.cursor++
+		}
+
+	case "enter":
+		return m.runCopyAction(copyActions[menu.cursor])
+
+	default:
+		for _, action := range copyActions {
+			if msg.String() == action.key {
+				return m.runCopyAction(action)
+			}
+		}
+	}
+
+	return m, nil
+}
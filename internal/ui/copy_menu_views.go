@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderCopyMenuView renders the copy menu with a preview of each entry
+func (m Model) renderCopyMenuView() string {
+	var content strings.Builder
+	menu := m.copyMenu
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Copy: " + menu.host.Name)
+	content.WriteString(header + "\n\n")
+
+	selectedStyle := m.theme.SelectedStyle()
+	keyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Accent).
+		Bold(true)
+	previewStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	for i, action := range copyActions {
+		cursor := "  "
+		if i == menu.cursor {
+			cursor = "▶ "
+		}
+
+		preview, err := action.text(menu.host)
+		if err != nil {
+			preview = err.Error()
+		}
+		preview = padAndTruncate(preview, max(10, m.width-30))
+
+		if i == menu.cursor {
+			line := fmt.Sprintf("%s%s  %-22s", cursor, action.key, action.label)
+			content.WriteString(selectedStyle.Render(line) + " " + previewStyle.Render(preview) + "\n")
+		} else {
+			content.WriteString(fmt.Sprintf("%s%s  %-22s", cursor, keyStyle.Render(action.key), action.label) +
+				" " + previewStyle.Render(preview) + "\n")
+		}
+	}
+	content.WriteString("\n")
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Key or Enter: copy • ↑/k ↓/j: move • ESC: cancel"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+	"xssh/internal/inventory"
+)
+
+// dnsDiscoveredMsg carries the hosts found in DNS
+type dnsDiscoveredMsg struct {
+	hosts []config.SSHHost
+	err   error
+}
+
+// discoverDNS looks up the [discovery] SRV records and host list files in
+// the background, or returns nil when none are configured
+func (m Model) discoverDNS() tea.Cmd {
+	discovery := m.discovery
+	if len(discovery.SRV) == 0 && len(discovery.Files) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		hosts, err := inventory.DiscoverDNS(discovery.SRV, discovery.Files)
+		return dnsDiscoveredMsg{hosts: hosts, err: err}
+	}
+}
+
+// handleDNSDiscovered lists the hosts found in DNS below the configured ones
+func (m Model) handleDNSDiscovered(msg dnsDiscoveredMsg) (tea.Model, tea.Cmd) {
+	m.discovered = msg.hosts
+	m.discoveredCursor = -1
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Host discovery: %v", msg.err)
+		m.messageType = "error"
+	}
+	return m, nil
+}
+
+// visibleDiscovered returns the discovered hosts that are not configured
+// yet and match the filter
+func (m Model) visibleDiscovered() []config.SSHHost {
+	terms := config.ParseSearchQuery(m.filterQuery)
+	var hosts []config.SSHHost
+	for _, host := range m.discovered {
+		if m.findHostIndex(host.Name) >= 0 || m.configuredAddress(host) {
+			continue
+		}
+		if m.filterQuery != "" && !m.metadata.HostMatches(host, terms) {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// configuredAddress reports whether a configured host reaches the address
+// and port of host
+func (m Model) configuredAddress(host config.SSHHost) bool {
+	for _, configured := range m.hosts {
+		if strings.EqualFold(configured.Host, host.Host) && defaultPort(configured.Port) == defaultPort(host.Port) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDiscoveredKey handles a key while the cursor is in the Discovered
+// section. Discovered hosts are read-only: they can be connected to or
+// added to the config. ok is false for keys the host list handles as usual.
+func (m Model) handleDiscoveredKey(pressed string) (model tea.Model, cmd tea.Cmd, ok bool) {
+	discovered := m.visibleDiscovered()
+	if m.discoveredCursor >= len(discovered) {
+		m.discoveredCursor = len(discovered) - 1
+	}
+	if m.discoveredCursor < 0 {
+		return m, nil, false
+	}
+	host := discovered[m.discoveredCursor]
+
+	switch pressed {
+	case "up", "k":
+		// Back to the configured hosts from the top of the section
+		m.discoveredCursor--
+		if m.discoveredCursor < 0 {
+			m.cursor = max(0, len(m.filteredHosts)-1)
+		}
+
+	case "down", "j":
+		if m.discoveredCursor < len(discovered)-1 {
+			m.discoveredCursor++
+		}
+
+	case "enter":
+		// Connect without adding the host
+		m.selectedHost = &host
+		model, cmd := m.quit()
+		return model, cmd, true
+
+	case "p":
+		// Promote the host to a managed host of the config
+		m.discoveredCursor = -1
+		model, cmd := m.addHosts([]config.SSHHost{host}, false)
+		return model, cmd, true
+
+	case "esc":
+		m.discoveredCursor = -1
+
+	case "q", "ctrl+c", "/", ":", "h", "N", "o", "a":
+		return m, nil, false
+
+	default:
+		m.message = "Discovered hosts are read-only, press p to add one to the config"
+		m.messageType = "info"
+	}
+	return m, nil, true
+}
+
+// renderDiscoveredHosts renders the Discovered section shown below the host
+// table
+func (m Model) renderDiscoveredHosts() string {
+	discovered := m.visibleDiscovered()
+	if len(discovered) == 0 {
+		return ""
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	rowStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+	selectedStyle := m.theme.SelectedStyle()
+
+	var section strings.Builder
+	section.WriteString(titleStyle.Render(fmt.Sprintf("Discovered (%d) • read-only, p: add to config", len(discovered))) + "\n")
+	for i, host := range discovered {
+		cursor := "  "
+		if i == m.discoveredCursor {
+			cursor = "▶ "
+		}
+		row := cursor + m.formatTableRow(host)
+		if i == m.discoveredCursor {
+			section.WriteString(selectedStyle.Render(row) + "\n")
+		} else {
+			section.WriteString(rowStyle.Render(row) + "\n")
+		}
+	}
+	return strings.TrimRight(section.String(), "\n")
+}
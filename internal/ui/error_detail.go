@@ -0,0 +1,182 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+)
+
+// hostKeyMismatchText is part of the message of a host key mismatch, for
+// errors that reach the UI only as text
+const hostKeyMismatchText = "does not match the one in known_hosts"
+
+// errorDetail is the modal showing a failed operation in full: the error,
+// what was attempted and how it might be fixed
+type errorDetail struct {
+	title       string
+	err         error
+	summary     string      // Message describing the failure, including the error
+	params      [][2]string // Label and value of each parameter used
+	command     string      // Equivalent ssh command line
+	suggestions []string
+	host        config.SSHHost // Host whose known_hosts entries "k" opens
+	mismatch    bool           // Whether the host key did not match known_hosts
+	returnMode  ViewMode
+}
+
+// message returns the full error text
+func (d *errorDetail) message() string {
+	if d.summary == "" && d.err != nil {
+		return d.err.Error()
+	}
+	return d.summary
+}
+
+// openErrorDetail shows the modal over the current view
+func (m Model) openErrorDetail(detail *errorDetail) (tea.Model, tea.Cmd) {
+	detail.mismatch = ssh.IsHostKeyMismatch(detail.err) || strings.Contains(detail.message(), hostKeyMismatchText)
+	detail.suggestions = errorSuggestions(detail.err, detail.summary)
+	detail.returnMode = m.viewMode
+	m.errorDetail = detail
+	m.viewMode = ModeErrorDetail
+	return m, nil
+}
+
+// connectionTestErrorDetail describes a failed connection test
+func connectionTestErrorDetail(test *connectionTest) *errorDetail {
+	host := test.host
+	auth := "Password"
+	if test.keyAuth {
+		auth = "Key " + host.Identity
+	}
+
+	return &errorDetail{
+		title:   "Connection test failed",
+		err:     test.result.Error,
+		summary: test.result.Message,
+		params: [][2]string{
+			{"Host", host.Host},
+			{"Port", host.Port},
+			{"User", host.User},
+			{"Auth", auth},
+			{"Step", test.step.String()},
+		},
+		command: ssh.BuildSSHCommand(host),
+		host:    host,
+	}
+}
+
+// forwardingErrorDetail describes a forwarding rule that could not be
+// started or re-applied
+func forwardingErrorDetail(title string, err error, rule forwarding.ForwardingRule, host config.SSHHost) *errorDetail {
+	params := [][2]string{
+		{"Type", rule.Type.String()},
+		{"Host", host.Name},
+	}
+	switch rule.Type {
+	case forwarding.LocalForward:
+		params = append(params,
+			[2]string{"Listen", net.JoinHostPort(rule.LocalHost, fmt.Sprint(rule.LocalPort))},
+			[2]string{"Target", rule.RemoteTarget()})
+		if rule.TLS != nil {
+			params = append(params, [2]string{"TLS", rule.TLS.String()})
+		}
+		if rule.RemoteSocket == "" {
+			params = append(params, [2]string{"Resolve", rule.ResolveString()})
+		}
+	case forwarding.RemoteForward:
+		params = append(params,
+			[2]string{"Listen", fmt.Sprintf("remote port %d", rule.RemotePort)},
+			[2]string{"Target", net.JoinHostPort(rule.LocalHost, fmt.Sprint(rule.LocalPort))})
+	case forwarding.DynamicForward:
+		params = append(params,
+			[2]string{"Listen", net.JoinHostPort(rule.LocalHost, fmt.Sprint(rule.LocalPort))})
+	case forwarding.RemoteDynamicForward:
+		params = append(params,
+			[2]string{"Listen", fmt.Sprintf("remote port %d", rule.RemotePort)})
+	}
+	if len(rule.Via) > 0 {
+		params = append(params, [2]string{"Via", rule.ViaString()})
+	}
+
+	return &errorDetail{
+		title:   title,
+		err:     err,
+		params:  params,
+		command: rule.SSHCommand(host),
+		host:    host,
+	}
+}
+
+// errorSuggestions returns likely fixes for an error, recognized by its type
+// or, for errors that lost their type on the way, by its text
+func errorSuggestions(err error, summary string) []string {
+	text := strings.ToLower(summary)
+	if err != nil {
+		text = strings.ToLower(err.Error()) + " " + text
+	}
+
+	var suggestions []string
+	suggest := func(matched bool, suggestion string) {
+		if matched {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	suggest(ssh.IsHostKeyMismatch(err) || strings.Contains(text, hostKeyMismatchText),
+		"The host key changed → press k to open the known_hosts manager and remove the outdated key")
+	suggest(ssh.IsPassphraseMissing(err) || strings.Contains(text, "passphrase"),
+		"The private key is encrypted → enter its password, or add it to ssh-agent with A")
+	suggest(errors.Is(err, os.ErrNotExist) || strings.Contains(text, "no such file"),
+		"A file was not found → check the path of the identity file")
+	suggest(errors.Is(err, syscall.ECONNREFUSED) || strings.Contains(text, "connection refused"),
+		"Nothing accepted the connection → check the port and that sshd is running")
+	suggest(strings.Contains(text, "no such host") || strings.Contains(text, "server misbehaving"),
+		"The host name did not resolve → check the spelling and your DNS")
+	suggest(strings.Contains(text, "timeout") || strings.Contains(text, "timed out"),
+		"The host did not answer in time → check that it is up and not blocked by a firewall or VPN")
+	suggest(strings.Contains(text, "unable to authenticate") || strings.Contains(text, "no supported methods remain"),
+		"Authentication was rejected → check the user name, password and key, and that the key is in authorized_keys")
+	suggest(errors.Is(err, syscall.EADDRINUSE) || strings.Contains(text, "address already in use"),
+		"The port is in use → pick another port, stop the program or tunnel using it, or set [forwarding] port_retries to take the next free one")
+	suggest(errors.Is(err, syscall.EACCES) || strings.Contains(text, "permission denied"),
+		"Permission denied → ports below 1024 need root; use a higher port")
+	suggest(strings.Contains(text, "already exists"),
+		"A tunnel with this rule is already running → edit or stop it in the forwarding list")
+	suggest(strings.Contains(text, "remote port forwarding") || strings.Contains(text, "tcpip-forward"),
+		"The server refused the remote port → check AllowTcpForwarding and GatewayPorts in its sshd_config")
+
+	if len(suggestions) == 0 {
+		suggestions = append(suggestions, "Run the command above in a terminal with -v to see what ssh does")
+	}
+	return suggestions
+}
+
+// handleErrorDetailMode handles keys in the error detail modal
+func (m Model) handleErrorDetailMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	detail := m.errorDetail
+
+	switch msg.String() {
+	case "esc", "enter", "q":
+		m.viewMode = detail.returnMode
+		m.errorDetail = nil
+
+	case "k":
+		if detail.mismatch {
+			// known_hosts returns to the view below the modal
+			m.viewMode = detail.returnMode
+			m.errorDetail = nil
+			return m.openKnownHosts(config.SSHHost{Host: detail.host.Host, Port: detail.host.Port})
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderErrorDetailView renders the error detail modal centered on screen
+func (m Model) renderErrorDetailView() string {
+	detail := m.errorDetail
+	width := min(max(40, m.width-8), 100)
+	textWidth := width - 6
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Error).
+		Bold(true)
+	sectionStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	labelStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Width(10)
+	wrapStyle := lipgloss.NewStyle().
+		Width(textWidth)
+
+	var content strings.Builder
+	content.WriteString(titleStyle.Render("✗ "+detail.title) + "\n\n")
+
+	// The whole error, wrapped instead of cut to one line
+	content.WriteString(wrapStyle.Render(detail.message()) + "\n")
+
+	if len(detail.params) > 0 || detail.command != "" {
+		content.WriteString("\n" + sectionStyle.Render("Attempted") + "\n")
+		for _, param := range detail.params {
+			if param[1] != "" {
+				content.WriteString(labelStyle.Render(param[0]) + padAndTruncate(param[1], textWidth-10) + "\n")
+			}
+		}
+		if detail.command != "" {
+			command := lipgloss.NewStyle().Width(textWidth - 10).Render(detail.command)
+			content.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, labelStyle.Render("Command"), command) + "\n")
+		}
+	}
+
+	content.WriteString("\n" + sectionStyle.Render("Suggestions") + "\n")
+	for _, suggestion := range detail.suggestions {
+		content.WriteString(wrapStyle.Render("• "+suggestion) + "\n")
+	}
+
+	help := "Enter/ESC: close"
+	if detail.mismatch {
+		help = "k: open known_hosts manager • " + help
+	}
+	content.WriteString("\n" + lipgloss.NewStyle().Foreground(m.theme.Muted).Render(help))
+
+	modal := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Error).
+		Padding(1, 2).
+		Width(width - 2).
+		Render(content.String())
+
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, modal)
+}
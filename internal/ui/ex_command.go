@@ -0,0 +1,378 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+)
+
+// exCommand is a command of the ":" command line
+type exCommand struct {
+	names       []string // Name, then aliases
+	usage       string
+	description string
+	// complete returns the candidates for argument n (0-based) given the
+	// arguments typed before it
+	complete func(m Model, n int, args []string) []string
+	run      func(m Model, args []string) (tea.Model, tea.Cmd)
+}
+
+// exCommands lists the commands of the ":" command line. Text whose first
+// word is not one of them filters the host list, as "/" does.
+var exCommands = []exCommand{
+	{
+		names:       []string{"connect", "c"},
+		usage:       "connect [alias]",
+		description: "Connect to a host, the selected one by default",
+		complete:    completeAliasAt(0),
+		run:         (Model).exConnect,
+	},
+	{
+		names:       []string{"forward", "fwd"},
+		usage:       "forward [-L|-R|-D] spec [alias]",
+		description: "Start a port forwarding, e.g. 8080:db:5432 bastion",
+		complete:    completeForward,
+		run:         (Model).exForward,
+	},
+	{
+		names:       []string{"add"},
+		usage:       "add [user@host[:port]]",
+		description: "Add a host, optionally prefilled",
+		run:         (Model).exAdd,
+	},
+	{
+		names:       []string{"quit", "q"},
+		usage:       "quit",
+		description: "Quit xssh",
+		run: func(m Model, args []string) (tea.Model, tea.Cmd) {
+			return m.quit()
+		},
+	},
+}
+
+// commandLine is the state of the ":" command line
+type commandLine struct {
+	text        string
+	prevFilter  string   // Filter active when the line was opened
+	completions []string // Candidates shown after an ambiguous Tab
+}
+
+// findExCommand returns the command named by the first word of a line
+func findExCommand(name string) *exCommand {
+	for i := range exCommands {
+		if slices.Contains(exCommands[i].names, name) {
+			return &exCommands[i]
+		}
+	}
+	return nil
+}
+
+// command returns the command typed on the line and its arguments, or nil
+// when the line is a filter
+func (c *commandLine) command() (*exCommand, []string) {
+	fields := strings.Fields(c.text)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return findExCommand(fields[0]), fields[1:]
+}
+
+// completeAliasAt completes host aliases at argument position n
+func completeAliasAt(n int) func(m Model, i int, args []string) []string {
+	return func(m Model, i int, args []string) []string {
+		if i != n {
+			return nil
+		}
+		return m.hostAliases()
+	}
+}
+
+// completeForward completes the options and the alias of :forward
+func completeForward(m Model, n int, args []string) []string {
+	if n == 0 {
+		return []string{"-L", "-R", "-D"}
+	}
+	if n == 1 && !strings.HasPrefix(args[0], "-") || n == 2 && strings.HasPrefix(args[0], "-") {
+		return m.hostAliases()
+	}
+	return nil
+}
+
+// hostAliases returns the aliases of every host
+func (m Model) hostAliases() []string {
+	aliases := make([]string, len(m.hosts))
+	for i, host := range m.hosts {
+		aliases[i] = host.Name
+	}
+	return aliases
+}
+
+// openCommandLine opens the ":" command line under the host list
+func (m Model) openCommandLine() (tea.Model, tea.Cmd) {
+	m.cmdLine = &commandLine{prevFilter: m.filterQuery}
+	return m, nil
+}
+
+// updateCommandLine filters the list by the typed text unless it is a command
+func (m *Model) updateCommandLine() {
+	c := m.cmdLine
+	c.completions = nil
+	if cmd, _ := c.command(); cmd != nil {
+		m.filterQuery = c.prevFilter
+	} else {
+		m.filterQuery = c.text
+	}
+	m.filterHosts()
+}
+
+// completeCommandLine completes the word at the end of the line: the command
+// name first, then its arguments
+func (m *Model) completeCommandLine() {
+	c := m.cmdLine
+	fields := strings.Fields(c.text)
+	if len(fields) == 0 || strings.HasSuffix(c.text, " ") {
+		fields = append(fields, "")
+	}
+	word := fields[len(fields)-1]
+
+	var candidates []string
+	if len(fields) == 1 {
+		for _, command := range exCommands {
+			candidates = append(candidates, command.names[0])
+		}
+	} else if command := findExCommand(fields[0]); command != nil && command.complete != nil {
+		args := fields[1 : len(fields)-1]
+		candidates = command.complete(*m, len(args), args)
+	}
+
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	completed := matches[0]
+	if len(matches) == 1 {
+		completed += " "
+	} else {
+		for _, match := range matches[1:] {
+			for !strings.HasPrefix(match, completed) {
+				completed = completed[:len(completed)-1]
+			}
+		}
+	}
+	c.text = strings.TrimSuffix(c.text, word) + completed
+	if len(matches) > 1 {
+		c.completions = matches
+	} else {
+		c.completions = nil
+	}
+	m.updateCommandLineFilter()
+}
+
+// updateCommandLineFilter re-applies the filter after a completion, keeping
+// the candidates on screen
+func (m *Model) updateCommandLineFilter() {
+	completions := m.cmdLine.completions
+	m.updateCommandLine()
+	m.cmdLine.completions = completions
+}
+
+// handleCommandLineMode handles keys while the ":" command line is open
+func (m Model) handleCommandLineMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.cmdLine
+	m.message = ""
+	m.messageType = ""
+
+	switch msg.String() {
+	case "esc":
+		// Keep whatever filter was typed, as the search does
+		m.cmdLine = nil
+
+	case "enter":
+		m.cmdLine = nil
+		if command, args := c.command(); command != nil {
+			m.filterQuery = c.prevFilter
+			m.filterHosts()
+			return command.run(m, args)
+		}
+
+	case "tab":
+		m.completeCommandLine()
+
+	case "backspace":
+		if c.text == "" {
+			m.cmdLine = nil
+			return m, nil
+		}
+		runes := []rune(c.text)
+		c.text = string(runes[:len(runes)-1])
+		m.updateCommandLine()
+
+	case "ctrl+c":
+		return m.quit()
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			c.text += string(msg.Runes)
+			m.updateCommandLine()
+		}
+	}
+
+	return m, nil
+}
+
+// commandLineHint describes what the command line will do with its text
+func (m Model) commandLineHint() string {
+	c := m.cmdLine
+	if len(c.completions) > 0 {
+		return strings.Join(c.completions, "  ")
+	}
+	if command, _ := c.command(); command != nil {
+		return command.usage + " • " + command.description
+	}
+
+	names := make([]string, len(exCommands))
+	for i, command := range exCommands {
+		names[i] = command.names[0]
+	}
+	return "Commands: " + strings.Join(names, ", ") + " • other text filters the list • Tab: complete"
+}
+
+// exTargetHost returns the host named by alias, or the selected host when
+// alias is empty
+func (m Model) exTargetHost(alias string) (config.SSHHost, error) {
+	if alias == "" {
+		if len(m.filteredHosts) == 0 {
+			return config.SSHHost{}, fmt.Errorf("no host selected")
+		}
+		return m.filteredHosts[m.cursor], nil
+	}
+	index := m.findHostIndex(alias)
+	if index < 0 {
+		return config.SSHHost{}, fmt.Errorf("unknown host %q", alias)
+	}
+	return m.hosts[index], nil
+}
+
+// exConnect implements ":connect [alias]"
+func (m Model) exConnect(args []string) (tea.Model, tea.Cmd) {
+	if len(args) > 1 {
+		m.message = "Usage: :connect [alias]"
+		m.messageType = "error"
+		return m, nil
+	}
+
+	alias := ""
+	if len(args) == 1 {
+		alias = args[0]
+	}
+	host, err := m.exTargetHost(alias)
+	if err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+		return m, nil
+	}
+	m.selectedHost = &host
+	return m.quit()
+}
+
+// exForward implements ":forward [-L|-R|-D] spec [alias]". -R with only a
+// port starts a SOCKS proxy on the remote host, as with ssh.
+func (m Model) exForward(args []string) (tea.Model, tea.Cmd) {
+	forwardingType := forwarding.LocalForward
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-L":
+		case "-R":
+			forwardingType = forwarding.RemoteForward
+		case "-D":
+			forwardingType = forwarding.DynamicForward
+		default:
+			m.message = fmt.Sprintf("Unknown option %s, expected -L, -R or -D", args[0])
+			m.messageType = "error"
+			return m, nil
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args) > 2 {
+		m.message = "Usage: :forward [-L|-R|-D] spec [alias]"
+		m.messageType = "error"
+		return m, nil
+	}
+
+	rule, err := forwarding.ParseSpec(forwardingType, args[0])
+	if err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+		return m, nil
+	}
+	port := rule.LocalPort
+	if rule.Type.ListensRemotely() {
+		port = rule.RemotePort
+	}
+	rule.ID = fmt.Sprintf("%s-%d-%d", rule.Type.String(), port, time.Now().Unix())
+	rule.PortRetries = forwarding.DefaultPortRetries
+
+	alias := ""
+	if len(args) == 2 {
+		alias = args[1]
+	}
+	host, err := m.exTargetHost(alias)
+	if err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+		return m, nil
+	}
+
+	session, err := m.startForwardingOn(rule, host)
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to start forwarding: %v", err)
+		m.messageType = "error"
+		return m.openErrorDetail(forwardingErrorDetail("Failed to start forwarding", err, rule, host))
+	}
+
+	m.message = fmt.Sprintf("Port forwarding started: %s via %s", rule.Description, host.Name)
+	if note := session.Rule.PortSubstitution(); note != "" {
+		m.message += fmt.Sprintf(" (%s)", note)
+	}
+	m.messageType = "success"
+	m.cursor = 0
+	m.viewMode = ModeForwardingList
+	return m, m.startForwardingRefresh()
+}
+
+// exAdd implements ":add [user@host[:port]]"
+func (m Model) exAdd(args []string) (tea.Model, tea.Cmd) {
+	m.formData = FormData{Port: "22", AuthType: AuthPassword}
+	if len(args) > 0 {
+		host, err := ssh.ParseTarget(strings.Join(args, " "))
+		if err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return m, nil
+		}
+		m.formData.Host = host.Host
+		m.formData.User = host.User
+		m.formData.Port = host.Port
+		m.formData.Identity = host.Identity
+		if host.Identity != "" {
+			m.formData.AuthType = AuthKey
+		}
+	}
+
+	m.viewMode = ModeAdd
+	m.editIndex = -1
+	m.loadFormInputs()
+	return m, m.focusField(FieldHost)
+}
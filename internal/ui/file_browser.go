@@ -0,0 +1,696 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	gossh "golang.org/x/crypto/ssh"
+	"xssh/internal/config"
+	"xssh/internal/forwarding"
+	"xssh/internal/sftp"
+	"xssh/internal/ssh"
+)
+
+// Panes of the file browser
+const (
+	paneLocal = iota
+	paneRemote
+)
+
+// transferProgressInterval limits how often a running transfer reports progress
+const transferProgressInterval = 100 * time.Millisecond
+
+// transferBufferSize is the chunk size used when copying files
+const transferBufferSize = 32 * 1024
+
+// errTransferCanceled is returned by a transfer stopped from the UI
+var errTransferCanceled = errors.New("transfer canceled")
+
+// browserPrompt is the question the file browser is currently asking
+type browserPrompt int
+
+const (
+	promptNone browserPrompt = iota
+	promptRename
+	promptDelete
+	promptOverwrite
+)
+
+// filePane is one side of the file browser
+type filePane struct {
+	dir     string
+	entries []os.FileInfo
+	cursor  int
+	err     error
+}
+
+// selected returns the entry under the cursor, if any
+func (p *filePane) selected() os.FileInfo {
+	if p.cursor < 0 || p.cursor >= len(p.entries) {
+		return nil
+	}
+	return p.entries[p.cursor]
+}
+
+// fileTransfer is an upload or download running in the background
+type fileTransfer struct {
+	name     string
+	upload   bool
+	total    int64
+	done     int64
+	started  time.Time
+	messages chan tea.Msg
+	cancel   chan struct{}
+	canceled bool
+}
+
+// stop asks the transfer goroutine to give up
+func (t *fileTransfer) stop() {
+	if !t.canceled {
+		t.canceled = true
+		close(t.cancel)
+	}
+}
+
+// fileBrowser holds the state of the SFTP file browser for one host
+type fileBrowser struct {
+	host       config.SSHHost
+	conn       *gossh.Client
+	client     *sftp.Client
+	connecting bool
+	panes      [2]filePane
+	active     int
+	prompt     browserPrompt
+	input      textinput.Model // Rename input
+	transfer   *fileTransfer
+}
+
+// sftpConnectedMsg reports the result of opening the SFTP session
+type sftpConnectedMsg struct {
+	conn   *gossh.Client
+	client *sftp.Client
+	home   string
+	err    error
+}
+
+// transferProgressMsg reports the bytes copied so far by a transfer
+type transferProgressMsg struct {
+	transfer *fileTransfer
+	done     int64
+}
+
+// transferDoneMsg reports that a transfer finished
+type transferDoneMsg struct {
+	transfer *fileTransfer
+	err      error
+}
+
+// openFileBrowser switches to the file browser for a host and starts
+// connecting to its SFTP subsystem
+func (m Model) openFileBrowser(host config.SSHHost) (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.Prompt = ""
+	input.Validate = validateFileName
+
+	browser := &fileBrowser{
+		host:       host,
+		connecting: true,
+		active:     paneRemote,
+		input:      input,
+	}
+
+	localDir, err := os.UserHomeDir()
+	if err != nil {
+		localDir = "."
+	}
+	browser.changeDir(paneLocal, localDir)
+
+	m.browser = browser
+	m.message = ""
+	m.viewMode = ModeFileBrowser
+	return m, connectSFTP(m.ctx, host, m.formData.KeyPassword)
+}
+
+// connectSFTP dials the host and starts an SFTP session, giving up when ctx
+// is canceled
+func connectSFTP(ctx context.Context, host config.SSHHost, keyPassword string) tea.Cmd {
+	return func() tea.Msg {
+		conn, err := ssh.DialContext(ctx, host, keyPassword)
+		if err != nil {
+			return sftpConnectedMsg{err: err}
+		}
+
+		client, err := sftp.NewClient(conn)
+		if err != nil {
+			conn.Close()
+			return sftpConnectedMsg{err: err}
+		}
+
+		home, err := client.RealPath(".")
+		if err != nil {
+			home = "/"
+		}
+		return sftpConnectedMsg{conn: conn, client: client, home: home}
+	}
+}
+
+// handleSFTPConnected stores the new SFTP session and lists the remote
+// login directory
+func (m Model) handleSFTPConnected(msg sftpConnectedMsg) (tea.Model, tea.Cmd) {
+	if m.browser == nil || m.viewMode != ModeFileBrowser {
+		// The browser was closed while connecting
+		if msg.client != nil {
+			msg.client.Close()
+			msg.conn.Close()
+		}
+		return m, nil
+	}
+
+	m.browser.connecting = false
+	if msg.err != nil {
+		m.browser.panes[paneRemote].err = msg.err
+		m.browser.active = paneLocal
+		m.message = fmt.Sprintf("SFTP connection failed: %v", msg.err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.browser.conn = msg.conn
+	m.browser.client = msg.client
+	m.browser.loadRemote(msg.home)
+	return m, nil
+}
+
+// closeFileBrowser cancels any running transfer, closes the SFTP session and
+// returns to the host list
+func (m Model) closeFileBrowser() (tea.Model, tea.Cmd) {
+	if m.browser != nil {
+		if m.browser.transfer != nil {
+			m.browser.transfer.stop()
+		}
+		if m.browser.client != nil {
+			m.browser.client.Close()
+			m.browser.conn.Close()
+		}
+	}
+	m.browser = nil
+	m.viewMode = ModeList
+	return m, nil
+}
+
+// handleFileBrowserMode handles keys in the file browser
+func (m Model) handleFileBrowserMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.browser
+	if b == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	if b.prompt != promptNone {
+		return m.handleFileBrowserPrompt(msg)
+	}
+
+	m.message = ""
+	m.messageType = ""
+
+	pane := &b.panes[b.active]
+
+	switch msg.String() {
+	case "esc", "q":
+		if b.transfer != nil && msg.String() == "esc" {
+			// ESC stops the running transfer before leaving the browser
+			b.transfer.stop()
+			return m, nil
+		}
+		return m.closeFileBrowser()
+
+	case "tab":
+		if b.active == paneLocal && b.client != nil {
+			b.active = paneRemote
+		} else {
+			b.active = paneLocal
+		}
+
+	case "up", "k":
+		if pane.cursor > 0 {
+			pane.cursor--
+		}
+
+	case "down", "j":
+		if pane.cursor < len(pane.entries)-1 {
+			pane.cursor++
+		}
+
+	case "home", "g":
+		pane.cursor = 0
+
+	case "end", "G":
+		pane.cursor = max(0, len(pane.entries)-1)
+
+	case "enter", "right", "l":
+		entry := pane.selected()
+		if entry == nil {
+			return m, nil
+		}
+		if !entry.IsDir() && entry.Mode()&os.ModeSymlink == 0 {
+			m.message = "Press c to copy files to the other pane"
+			m.messageType = "info"
+			return m, nil
+		}
+		b.changeDir(b.active, b.joinPath(b.active, entry.Name()))
+
+	case "backspace", "left", "h":
+		b.changeDir(b.active, b.parentDir(b.active))
+
+	case "r":
+		if entry := pane.selected(); entry != nil {
+			b.prompt = promptRename
+			b.input.SetValue(entry.Name())
+			b.input.CursorEnd()
+			return m, b.input.Focus()
+		}
+
+	case "d", "delete":
+		if pane.selected() != nil {
+			b.prompt = promptDelete
+		}
+
+	case "c", "f5":
+		return m.startCopy(false)
+
+	case "R", "ctrl+r":
+		b.refresh(b.active)
+	}
+
+	return m, nil
+}
+
+// handleFileBrowserPrompt handles keys while the browser asks a question
+func (m Model) handleFileBrowserPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.browser
+
+	switch b.prompt {
+	case promptRename:
+		switch msg.String() {
+		case "esc":
+			b.prompt = promptNone
+			b.input.Blur()
+		case "enter":
+			if b.input.Err != nil || b.input.Value() == "" {
+				return m, nil
+			}
+			b.prompt = promptNone
+			b.input.Blur()
+			m.renameSelected(b.input.Value())
+		default:
+			var cmd tea.Cmd
+			b.input, cmd = b.input.Update(msg)
+			return m, cmd
+		}
+
+	case promptDelete:
+		switch msg.String() {
+		case "y", "Y":
+			b.prompt = promptNone
+			m.deleteSelected()
+		case "n", "N", "esc":
+			b.prompt = promptNone
+		}
+
+	case promptOverwrite:
+		switch msg.String() {
+		case "y", "Y":
+			b.prompt = promptNone
+			return m.startCopy(true)
+		case "n", "N", "esc":
+			b.prompt = promptNone
+		}
+	}
+
+	return m, nil
+}
+
+// renameSelected renames the entry under the cursor within its directory
+func (m *Model) renameSelected(name string) {
+	b := m.browser
+	entry := b.panes[b.active].selected()
+	if entry == nil || name == entry.Name() {
+		return
+	}
+
+	oldPath := b.joinPath(b.active, entry.Name())
+	newPath := b.joinPath(b.active, name)
+
+	var err error
+	if b.active == paneLocal {
+		err = os.Rename(oldPath, newPath)
+	} else {
+		err = b.client.Rename(oldPath, newPath)
+	}
+
+	if err != nil {
+		m.message = fmt.Sprintf("Rename failed: %v", err)
+		m.messageType = "error"
+		return
+	}
+
+	b.refresh(b.active)
+	b.selectName(b.active, name)
+	m.message = fmt.Sprintf("Renamed %s to %s", entry.Name(), name)
+	m.messageType = "success"
+}
+
+// deleteSelected deletes the file or empty directory under the cursor
+func (m *Model) deleteSelected() {
+	b := m.browser
+	entry := b.panes[b.active].selected()
+	if entry == nil {
+		return
+	}
+
+	target := b.joinPath(b.active, entry.Name())
+
+	var err error
+	switch {
+	case b.active == paneLocal:
+		err = os.Remove(target)
+	case entry.IsDir():
+		err = b.client.RemoveDir(target)
+	default:
+		err = b.client.Remove(target)
+	}
+
+	if err != nil {
+		m.message = fmt.Sprintf("Delete failed: %v", err)
+		m.messageType = "error"
+		return
+	}
+
+	b.refresh(b.active)
+	m.message = fmt.Sprintf("Deleted %s", entry.Name())
+	m.messageType = "success"
+}
+
+// startCopy uploads or downloads the selected file into the directory of the
+// other pane. Unless overwrite is set, an existing destination file is
+// confirmed first.
+func (m Model) startCopy(overwrite bool) (tea.Model, tea.Cmd) {
+	b := m.browser
+
+	if b.client == nil {
+		m.message = "Not connected to the remote host"
+		m.messageType = "error"
+		return m, nil
+	}
+	if b.transfer != nil {
+		m.message = "A transfer is already running"
+		m.messageType = "error"
+		return m, nil
+	}
+
+	entry := b.panes[b.active].selected()
+	if entry == nil {
+		return m, nil
+	}
+	if !entry.Mode().IsRegular() {
+		m.message = "Only regular files can be copied"
+		m.messageType = "error"
+		return m, nil
+	}
+
+	other := paneRemote
+	if b.active == paneRemote {
+		other = paneLocal
+	}
+	source := b.joinPath(b.active, entry.Name())
+	destination := b.joinPath(other, entry.Name())
+
+	if !overwrite && b.hasEntry(other, entry.Name()) {
+		b.prompt = promptOverwrite
+		return m, nil
+	}
+
+	transfer := &fileTransfer{
+		name:     entry.Name(),
+		upload:   b.active == paneLocal,
+		total:    entry.Size(),
+		started:  time.Now(),
+		messages: make(chan tea.Msg),
+		cancel:   make(chan struct{}),
+	}
+	b.transfer = transfer
+
+	go runTransfer(b.client, transfer, source, destination)
+	return m, waitForTransfer(transfer)
+}
+
+// waitForTransfer delivers the next message of a running transfer
+func waitForTransfer(transfer *fileTransfer) tea.Cmd {
+	return func() tea.Msg {
+		return <-transfer.messages
+	}
+}
+
+// runTransfer copies a file between the local disk and the server, sending
+// progress and completion messages on the transfer's channel
+func runTransfer(client *sftp.Client, transfer *fileTransfer, source, destination string) {
+	var (
+		src io.ReadCloser
+		dst io.WriteCloser
+		err error
+	)
+
+	if transfer.upload {
+		if src, err = os.Open(source); err == nil {
+			dst, err = client.Create(destination)
+		}
+	} else {
+		if src, err = client.Open(source); err == nil {
+			dst, err = os.Create(destination)
+		}
+	}
+
+	if err == nil {
+		err = copyWithProgress(transfer, dst, src)
+		if closeErr := dst.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if src != nil {
+		src.Close()
+	}
+
+	transfer.messages <- transferDoneMsg{transfer: transfer, err: err}
+}
+
+// copyWithProgress copies src to dst, reporting progress at most every
+// transferProgressInterval and stopping when the transfer is canceled
+func copyWithProgress(transfer *fileTransfer, dst io.Writer, src io.Reader) error {
+	buf := make([]byte, transferBufferSize)
+	var copied int64
+	lastReport := time.Now()
+
+	for {
+		select {
+		case <-transfer.cancel:
+			return errTransferCanceled
+		default:
+		}
+
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return err
+			}
+			copied += int64(n)
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+
+		if time.Since(lastReport) >= transferProgressInterval {
+			lastReport = time.Now()
+			select {
+			case transfer.messages <- transferProgressMsg{transfer: transfer, done: copied}:
+			case <-transfer.cancel:
+				return errTransferCanceled
+			}
+		}
+	}
+}
+
+// handleTransferProgress records progress and waits for the next message
+func (m Model) handleTransferProgress(msg transferProgressMsg) (tea.Model, tea.Cmd) {
+	if m.browser == nil || m.browser.transfer != msg.transfer {
+		// Keep draining a transfer whose browser was closed
+		return m, waitForTransfer(msg.transfer)
+	}
+	msg.transfer.done = msg.done
+	return m, waitForTransfer(msg.transfer)
+}
+
+// handleTransferDone reports the result of a transfer and refreshes the
+// destination pane
+func (m Model) handleTransferDone(msg transferDoneMsg) (tea.Model, tea.Cmd) {
+	if m.browser == nil || m.browser.transfer != msg.transfer {
+		return m, nil
+	}
+
+	b := m.browser
+	b.transfer = nil
+
+	destination := paneLocal
+	verb := "Downloaded"
+	if msg.transfer.upload {
+		destination = paneRemote
+		verb = "Uploaded"
+	}
+
+	switch {
+	case errors.Is(msg.err, errTransferCanceled):
+		m.message = fmt.Sprintf("Transfer of %s canceled", msg.transfer.name)
+		m.messageType = "info"
+	case msg.err != nil:
+		m.message = fmt.Sprintf("Transfer of %s failed: %v", msg.transfer.name, msg.err)
+		m.messageType = "error"
+	default:
+		elapsed := time.Since(msg.transfer.started).Seconds()
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(msg.transfer.total) / elapsed
+		}
+		m.message = fmt.Sprintf("%s %s (%s, %s)", verb, msg.transfer.name,
+			forwarding.FormatBytes(msg.transfer.total), formatRate(rate))
+		m.messageType = "success"
+	}
+
+	b.refresh(destination)
+	return m, nil
+}
+
+// loadLocal lists a local directory into the local pane
+func (b *fileBrowser) loadLocal(dir string) error {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]os.FileInfo, 0, len(dirEntries))
+	for _, dirEntry := range dirEntries {
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, info)
+	}
+
+	b.setEntries(paneLocal, dir, entries)
+	return nil
+}
+
+// loadRemote lists a remote directory into the remote pane
+func (b *fileBrowser) loadRemote(dir string) error {
+	entries, err := b.client.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	b.setEntries(paneRemote, dir, entries)
+	return nil
+}
+
+// setEntries replaces the listing of a pane, directories first
+func (b *fileBrowser) setEntries(pane int, dir string, entries []os.FileInfo) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+
+	p := &b.panes[pane]
+	if p.dir != dir {
+		p.cursor = 0
+	}
+	p.dir = dir
+	p.entries = entries
+	p.err = nil
+	if p.cursor >= len(entries) {
+		p.cursor = max(0, len(entries)-1)
+	}
+}
+
+// changeDir lists another directory in a pane, keeping the current listing
+// and recording the error when it cannot be read
+func (b *fileBrowser) changeDir(pane int, dir string) {
+	var err error
+	if pane == paneLocal {
+		err = b.loadLocal(dir)
+	} else if b.client != nil {
+		err = b.loadRemote(dir)
+	}
+	b.panes[pane].err = err
+}
+
+// refresh re-lists the current directory of a pane
+func (b *fileBrowser) refresh(pane int) {
+	b.changeDir(pane, b.panes[pane].dir)
+}
+
+// joinPath joins a name to the directory of a pane using the pane's path
+// syntax
+func (b *fileBrowser) joinPath(pane int, name string) string {
+	if pane == paneLocal {
+		return filepath.Join(b.panes[pane].dir, name)
+	}
+	return path.Join(b.panes[pane].dir, name)
+}
+
+// parentDir returns the parent of the directory shown in a pane
+func (b *fileBrowser) parentDir(pane int) string {
+	if pane == paneLocal {
+		return filepath.Dir(b.panes[pane].dir)
+	}
+	return path.Dir(b.panes[pane].dir)
+}
+
+// hasEntry reports whether a pane lists an entry with the given name
+func (b *fileBrowser) hasEntry(pane int, name string) bool {
+	for _, entry := range b.panes[pane].entries {
+		if entry.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectName moves the cursor of a pane to the named entry
+func (b *fileBrowser) selectName(pane int, name string) {
+	for i, entry := range b.panes[pane].entries {
+		if entry.Name() == name {
+			b.panes[pane].cursor = i
+			return
+		}
+	}
+}
+
+// validateFileName rejects names that would leave the current directory
+func validateFileName(value string) error {
+	if value == "." || value == ".." || strings.ContainsAny(value, `/\`) {
+		return fmt.Errorf("must be a plain file name")
+	}
+	return nil
+}
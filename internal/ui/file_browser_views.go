@@ -0,0 +1,211 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/forwarding"
+)
+
+// renderFileBrowserView renders the two-pane SFTP file browser
+func (m Model) renderFileBrowserView() string {
+	var content strings.Builder
+	b := m.browser
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render(fmt.Sprintf("Files: %s (%s@%s)", b.host.Name, b.host.User, b.host.Host))
+	content.WriteString(header + "\n\n")
+
+	// Panes side by side, each taking half of the width
+	paneWidth := (m.width - 2) / 2
+	rows := max(3, m.height-12)
+
+	localPane := m.renderFilePane(paneLocal, "Local", paneWidth, rows)
+	remotePane := m.renderFilePane(paneRemote, "Remote", paneWidth, rows)
+	content.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, localPane, " ", remotePane) + "\n")
+
+	// Transfer progress
+	if b.transfer != nil {
+		content.WriteString(m.renderTransferProgress(b.transfer) + "\n")
+	}
+
+	// Prompt or message
+	switch b.prompt {
+	case promptRename:
+		promptStyle := lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(m.theme.Accent).
+			Padding(0, 1).
+			Width(min(60, m.width-4)).
+			Bold(true)
+		content.WriteString(m.renderBrowserInput("Rename to: ", promptStyle) + "\n")
+	case promptDelete:
+		if entry := b.panes[b.active].selected(); entry != nil {
+			question := fmt.Sprintf("Delete '%s'? (y/n)", entry.Name())
+			content.WriteString(m.theme.MessageStyle("error", m.width).Render(question) + "\n")
+		}
+	case promptOverwrite:
+		if entry := b.panes[b.active].selected(); entry != nil {
+			question := fmt.Sprintf("'%s' already exists in the other pane. Overwrite? (y/n)", entry.Name())
+			content.WriteString(m.theme.MessageStyle("info", m.width).Render(question) + "\n")
+		}
+	default:
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	var help string
+	switch {
+	case b.prompt == promptRename:
+		help = "Enter: rename • ESC: cancel"
+	case b.prompt != promptNone:
+		help = "Y: confirm • N/ESC: cancel"
+	case b.transfer != nil:
+		help = "Tab: switch pane • ↑/↓: move • ESC: cancel transfer • q: close"
+	default:
+		help = "Tab: switch pane • ↑/↓: move • Enter/→: open • ⌫/←: parent • c: copy to other pane • r: rename • d: delete • R: refresh • q/ESC: close"
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// renderFilePane renders one pane of the file browser with a window of rows
+// entries that keeps the cursor visible
+func (m Model) renderFilePane(pane int, title string, width, rows int) string {
+	b := m.browser
+	p := b.panes[pane]
+
+	borderColor := m.theme.Primary
+	if pane == b.active {
+		borderColor = m.theme.Accent
+	}
+	paneStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(borderColor).
+		Padding(0, 1).
+		Width(width - 2)
+
+	innerWidth := width - 6
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme.Primary)
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Italic(true)
+
+	var lines []string
+	lines = append(lines, titleStyle.Render(padAndTruncate(fmt.Sprintf("%s: %s", title, p.dir), innerWidth)))
+
+	switch {
+	case pane == paneRemote && b.connecting:
+		lines = append(lines, subtleStyle.Render("Connecting..."))
+	case p.err != nil:
+		errorStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error).
+			Width(innerWidth)
+		lines = append(lines, errorStyle.Render(p.err.Error()))
+	}
+
+	if len(p.entries) == 0 && p.err == nil && !(pane == paneRemote && b.connecting) {
+		lines = append(lines, subtleStyle.Render("(empty)"))
+	}
+
+	// Scroll so that the cursor stays inside the window
+	start := 0
+	if p.cursor >= rows {
+		start = p.cursor - rows + 1
+	}
+	end := min(len(p.entries), start+rows)
+
+	selectedStyle := m.theme.SelectedStyle()
+	sizeWidth := 9
+	for i := start; i < end; i++ {
+		entry := p.entries[i]
+
+		name := entry.Name()
+		size := forwarding.FormatBytes(entry.Size())
+		switch {
+		case entry.IsDir():
+			name += "/"
+			size = "<dir>"
+		case entry.Mode()&os.ModeSymlink != 0:
+			name += "@"
+			size = "<link>"
+		}
+
+		line := padAndTruncate(name, innerWidth-sizeWidth-1) + " " + fmt.Sprintf("%*s", sizeWidth, size)
+		if i == p.cursor && pane == b.active {
+			line = selectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	// Pad to a fixed height so both panes line up
+	for len(lines) < rows+1 {
+		lines = append(lines, "")
+	}
+
+	return paneStyle.Render(strings.Join(lines, "\n"))
+}
+
+// renderTransferProgress renders a progress bar for a running transfer
+func (m Model) renderTransferProgress(transfer *fileTransfer) string {
+	direction := "Downloading"
+	if transfer.upload {
+		direction = "Uploading"
+	}
+
+	fraction := 1.0
+	if transfer.total > 0 {
+		fraction = float64(transfer.done) / float64(transfer.total)
+	}
+
+	rate := 0.0
+	if elapsed := time.Since(transfer.started).Seconds(); elapsed > 0 {
+		rate = float64(transfer.done) / elapsed
+	}
+
+	label := fmt.Sprintf("%s %s  %s / %s  %s", direction, transfer.name,
+		forwarding.FormatBytes(transfer.done), forwarding.FormatBytes(transfer.total), formatRate(rate))
+
+	barWidth := max(10, m.width-14)
+	barStyle := lipgloss.NewStyle().Foreground(m.theme.Success)
+	bar := barStyle.Render(progressBar(fraction, barWidth)) + fmt.Sprintf(" %3.0f%%", fraction*100)
+
+	return label + "\n" + bar
+}
+
+// renderBrowserInput renders the rename input of the file browser
+func (m Model) renderBrowserInput(label string, style lipgloss.Style) string {
+	input := m.browser.input
+	input.Width = max(1, style.GetWidth()-style.GetHorizontalPadding()-lipgloss.Width(label)-1)
+
+	rendered := style.Render(label + input.View())
+	if input.Err != nil {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error).
+			MarginLeft(2)
+		rendered += "\n" + errorStyle.Render(input.Err.Error())
+	}
+	return rendered
+}
+
+// progressBar renders a horizontal bar filled to fraction of width
+func progressBar(fraction float64, width int) string {
+	if fraction < 0 {
+		fraction = 0
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * float64(width))
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
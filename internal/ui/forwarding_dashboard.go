@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/forwarding"
+)
+
+// forwardingRefreshInterval is how often the forwarding list re-samples stats
+const forwardingRefreshInterval = time.Second
+
+// trafficGraphSamples is the number of rate samples kept for the mini graph
+const trafficGraphSamples = 30
+
+// forwardingTickMsg triggers a refresh of the forwarding dashboard. The id
+// ties it to one refresh loop so that leaving and re-entering the list does
+// not start a second loop.
+type forwardingTickMsg struct {
+	id int
+}
+
+// forwardingTick schedules the next dashboard refresh for the given loop
+func forwardingTick(id int) tea.Cmd {
+	return tea.Tick(forwardingRefreshInterval, func(time.Time) tea.Msg {
+		return forwardingTickMsg{id: id}
+	})
+}
+
+// startForwardingRefresh starts a new refresh loop for the forwarding list,
+// superseding any loop that is still pending
+func (m *Model) startForwardingRefresh() tea.Cmd {
+	m.forwardingTickID++
+	m.sampleForwardingTraffic()
+	return forwardingTick(m.forwardingTickID)
+}
+
+// handleForwardingTick samples traffic and schedules the next refresh while
+// the forwarding list or a session's error log is on screen
+func (m Model) handleForwardingTick(msg forwardingTickMsg) (tea.Model, tea.Cmd) {
+	if msg.id != m.forwardingTickID || (m.viewMode != ModeForwardingList && m.viewMode != ModeForwardingErrors) {
+		// Stale loop or the list is no longer visible
+		return m, nil
+	}
+	m.sampleForwardingTraffic()
+	return m, forwardingTick(msg.id)
+}
+
+// sampleForwardingTraffic adds the current rate of every session, over its
+// rolling window, to the graph and drops the graphs of stopped sessions
+func (m *Model) sampleForwardingTraffic() {
+	if m.trafficHistory == nil {
+		m.trafficHistory = map[string][]float64{}
+	}
+
+	seen := map[string]bool{}
+	for _, session := range m.forwardingManager.GetAllSessions() {
+		id := session.Rule.ID
+		seen[id] = true
+
+		received, sent := session.GetCurrentRate()
+		rates := append(m.trafficHistory[id], received+sent)
+		if len(rates) > trafficGraphSamples {
+			rates = rates[len(rates)-trafficGraphSamples:]
+		}
+		m.trafficHistory[id] = rates
+	}
+
+	for id := range m.trafficHistory {
+		if !seen[id] {
+			delete(m.trafficHistory, id)
+		}
+	}
+}
+
+// sparkline renders rate samples as a row of block characters scaled to the
+// largest sample, padded on the left to width
+func sparkline(samples []float64, width int) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+
+	peak := 0.0
+	for _, sample := range samples {
+		if sample > peak {
+			peak = sample
+		}
+	}
+
+	var graph strings.Builder
+	for i := len(samples); i < width; i++ {
+		graph.WriteRune(' ')
+	}
+	for _, sample := range samples {
+		level := 0
+		if peak > 0 {
+			level = int(sample / peak * float64(len(blocks)-1))
+		}
+		graph.WriteRune(blocks[level])
+	}
+	return graph.String()
+}
+
+// formatRate formats a transfer rate in bytes per second
+func formatRate(bytesPerSecond float64) string {
+	return forwarding.FormatBytes(int64(bytesPerSecond)) + "/s"
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/forwarding"
+)
+
+// forwardingErrorLog is the error log view of one forwarding session
+type forwardingErrorLog struct {
+	sessionID string
+	scroll    int // Number of newest entries scrolled past
+}
+
+// openForwardingErrors shows the error log of a forwarding session, newest
+// first. The dashboard refresh keeps running so new errors appear.
+func (m Model) openForwardingErrors(session *forwarding.ForwardingSession) (tea.Model, tea.Cmd) {
+	m.forwardingErrors = &forwardingErrorLog{sessionID: session.Rule.ID}
+	m.viewMode = ModeForwardingErrors
+	return m, m.startForwardingRefresh()
+}
+
+// handleForwardingErrorsMode handles keys in a forwarding session's error log
+func (m Model) handleForwardingErrorsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	view := m.forwardingErrors
+	session, exists := m.forwardingManager.GetSession(view.sessionID)
+
+	switch msg.String() {
+	case "esc", "q", "E":
+		m.forwardingErrors = nil
+		m.viewMode = ModeForwardingList
+		return m, m.startForwardingRefresh()
+
+	case "up", "k":
+		if view.scroll > 0 {
+			view.scroll--
+		}
+
+	case "down", "j":
+		if exists && view.scroll < len(session.ErrorLog())-1 {
+			view.scroll++
+		}
+
+	case "c":
+		if exists {
+			session.ClearErrors()
+			view.scroll = 0
+			m.message = "Error log cleared"
+			m.messageType = "success"
+		}
+	}
+
+	return m, nil
+}
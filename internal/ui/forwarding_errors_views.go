@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/forwarding"
+)
+
+// renderForwardingErrorsView renders the error log of a forwarding session
+func (m Model) renderForwardingErrorsView() string {
+	var content strings.Builder
+	view := m.forwardingErrors
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	session, exists := m.forwardingManager.GetSession(view.sessionID)
+	title := "Forwarding Errors"
+	if exists {
+		title += ": " + forwardingSessionTitle(session)
+	}
+	content.WriteString(headerStyle.Render(title) + "\n\n")
+
+	emptyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Italic(true).
+		Align(lipgloss.Center).
+		Width(m.width)
+
+	var log []forwarding.ErrorLogEntry
+	if exists {
+		log = session.ErrorLog()
+	}
+
+	switch {
+	case !exists:
+		content.WriteString(emptyStyle.Render("The forwarding session is no longer running") + "\n\n")
+	case len(log) == 0:
+		content.WriteString(emptyStyle.Render("No errors recorded") + "\n\n")
+	default:
+		timeStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle)
+		errorStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error)
+
+		// Newest first, starting at the scroll position
+		var entries []string
+		rows := max(3, m.height-8)
+		for i := len(log) - 1 - view.scroll; i >= 0 && len(entries) < rows; i-- {
+			entry := log[i]
+			entries = append(entries, timeStyle.Render(entry.Time.Format("2006-01-02 15:04:05"))+"  "+
+				errorStyle.Render(padAndTruncate(entry.Message, max(10, m.width-23))))
+		}
+		content.WriteString(strings.Join(entries, "\n") + "\n\n")
+
+		countStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle)
+		count := fmt.Sprintf("Showing %d-%d of %d kept errors (%d in total)",
+			view.scroll+1, view.scroll+len(entries), len(log), session.Stats.ErrorCount)
+		content.WriteString(countStyle.Render(count) + "\n\n")
+	}
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: scroll • c: clear • ESC/q: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/forwarding"
+)
+
+// newForwardingSearch creates the filter input of the forwarding list
+func newForwardingSearch() textinput.Model {
+	search := textinput.New()
+	search.Prompt = "Filter: "
+	search.Placeholder = "label=dev, host=bastion, type=local, port=8080 or any word"
+	return search
+}
+
+// listedSessions returns the forwarding sessions shown in the forwarding
+// list: those matching its filter
+func (m Model) listedSessions() []*forwarding.ForwardingSession {
+	sessions := m.forwardingManager.GetAllSessions()
+	filter := forwarding.ParseSessionFilter(m.forwardingSearch.Value())
+	if len(filter) == 0 {
+		return sessions
+	}
+	var listed []*forwarding.ForwardingSession
+	for _, session := range sessions {
+		if filter.Matches(session.Rule, session.Host().Name) {
+			listed = append(listed, session)
+		}
+	}
+	return listed
+}
+
+// handleForwardingSearch handles keys while typing the filter of the
+// forwarding list
+func (m Model) handleForwardingSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.forwardingSearching = false
+		m.forwardingSearch.Blur()
+		m.forwardingSearch.SetValue("")
+		m.cursor = 0
+	case "enter":
+		m.forwardingSearching = false
+		m.forwardingSearch.Blur()
+	default:
+		var cmd tea.Cmd
+		m.forwardingSearch, cmd = m.forwardingSearch.Update(msg)
+		m.cursor = 0
+		return m, cmd
+	}
+	return m, nil
+}
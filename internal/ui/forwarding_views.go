@@ -12,218 +12,124 @@ import (
 // renderForwardingSelectView renders the forwarding type selection
 func (m Model) renderForwardingSelectView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Select Port Forwarding Type")
 	content.WriteString(header + "\n\n")
-	
+
 	// Host info
 	if m.selectedHostIndex >= 0 && m.selectedHostIndex < len(m.filteredHosts) {
 		host := m.filteredHosts[m.selectedHostIndex]
 		infoStyle := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#7D56F4")).
+			BorderForeground(m.theme.Primary).
 			Padding(1, 2).
 			Width(m.width - 4)
-		
+
 		info := fmt.Sprintf("Target Host: %s (%s@%s:%s)", host.Name, host.User, host.Host, host.Port)
 		content.WriteString(infoStyle.Render(info) + "\n\n")
 	}
-	
+
 	// Options
 	optionStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
-		Width(m.width - 8).
+		Width(m.width-8).
 		Margin(1, 2)
-	
+
 	option1 := optionStyle.Render("1. Local Forward (-L)\n   Forward local port to remote host through SSH tunnel")
 	option2 := optionStyle.Render("2. Remote Forward (-R)\n   Forward remote port to local host")
 	option3 := optionStyle.Render("3. Dynamic Forward (-D)\n   Create SOCKS5 proxy on local port")
+	option4 := optionStyle.Render("4. Remote Dynamic Forward (-R port)\n   Create SOCKS5 proxy on remote port, connecting from this machine")
 	optionList := optionStyle.Render("L. List Active Forwardings\n   View and manage active port forwarding sessions")
-	
+
 	content.WriteString(option1 + "\n")
 	content.WriteString(option2 + "\n")
 	content.WriteString(option3 + "\n")
+	content.WriteString(option4 + "\n")
 	content.WriteString(optionList + "\n\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
-	help := "1/2/3: select forwarding type • L: list active • ESC: back"
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "1/2/3/4: select forwarding type • L: list active • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderForwardingAddView renders the forwarding configuration form
 func (m Model) renderForwardingAddView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	title := fmt.Sprintf("Configure %s Forwarding", m.forwardingType.String())
+	if m.editingSessionID != "" {
+		title = fmt.Sprintf("Edit %s Forwarding", m.forwardingType.String())
+	}
 	header := headerStyle.Render(title)
 	content.WriteString(header + "\n\n")
-	
+
 	// Form fields
-	fieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(40)
-	
-	activeFieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
-		Padding(0, 1).
-		Width(40).
-		Bold(true)
-	
+	fieldStyle, activeFieldStyle := m.formFieldStyles()
+
 	// Show different fields based on forwarding type
 	switch m.forwardingType {
 	case forwarding.LocalForward:
-		// Local Port
-		localPortValue := m.formData.LocalPort
-		if m.currentField == FieldLocalPort {
-			localPortValue += "█"
-		}
-		localPortField := "Local Port: "
-		if m.currentField == FieldLocalPort {
-			localPortField = activeFieldStyle.Render(localPortField + localPortValue)
-		} else {
-			localPortField = fieldStyle.Render(localPortField + localPortValue)
-		}
-		content.WriteString(localPortField + "\n\n")
-		
-		// Remote Host
-		remoteHostValue := m.formData.RemoteHost
-		var remoteHostDisplay string
-		
+		content.WriteString(m.renderInputField("Local Port: ", FieldLocalPort, "", fieldStyle, activeFieldStyle) + "\n\n")
+
+		// Remote Host, annotated with the alias when picked from the host list
+		remoteHostSuffix := ""
 		if m.formData.UseExistingHost && m.formData.SelectedRemoteHostIndex < len(m.hosts) {
-			// Show selected host info
 			selectedHost := m.hosts[m.formData.SelectedRemoteHostIndex]
-			remoteHostDisplay = fmt.Sprintf("%s (%s)", remoteHostValue, selectedHost.Name)
-		} else if m.formData.RemoteHost != "" {
-			// Show manual input
-			remoteHostDisplay = remoteHostValue
-		} else {
-			// Show prompt to select
-			remoteHostDisplay = "Press Enter to select host"
-		}
-		
-		if m.currentField == FieldRemoteHost {
-			if m.formData.RemoteHost == "" {
-				remoteHostDisplay += " █"
-			} else {
-				remoteHostDisplay += "█"
-			}
-		}
-		
-		remoteHostField := "Remote Host: "
-		if m.currentField == FieldRemoteHost {
-			remoteHostField = activeFieldStyle.Render(remoteHostField + remoteHostDisplay)
-		} else {
-			remoteHostField = fieldStyle.Render(remoteHostField + remoteHostDisplay)
+			remoteHostSuffix = fmt.Sprintf(" (%s)", selectedHost.Name)
 		}
-		content.WriteString(remoteHostField + "\n\n")
-		
-		// Remote Port
-		remotePortValue := m.formData.RemotePort
-		if m.currentField == FieldRemotePort {
-			remotePortValue += "█"
-		}
-		remotePortField := "Remote Port: "
-		if m.currentField == FieldRemotePort {
-			remotePortField = activeFieldStyle.Render(remotePortField + remotePortValue)
-		} else {
-			remotePortField = fieldStyle.Render(remotePortField + remotePortValue)
-		}
-		content.WriteString(remotePortField + "\n\n")
-		
+		content.WriteString(m.renderInputField("Remote Host: ", FieldRemoteHost, remoteHostSuffix, fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Remote Port: ", FieldRemotePort, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Bind Address: ", FieldLocalHost, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("TLS: ", FieldTLS, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Resolve: ", FieldResolve, "", fieldStyle, activeFieldStyle) + "\n\n")
+
 	case forwarding.RemoteForward:
-		// Remote Port
-		remotePortValue := m.formData.RemotePort
-		if m.currentField == FieldRemotePort {
-			remotePortValue += "█"
-		}
-		remotePortField := "Remote Port: "
-		if m.currentField == FieldRemotePort {
-			remotePortField = activeFieldStyle.Render(remotePortField + remotePortValue)
-		} else {
-			remotePortField = fieldStyle.Render(remotePortField + remotePortValue)
-		}
-		content.WriteString(remotePortField + "\n\n")
-		
-		// Local Port
-		localPortValue := m.formData.LocalPort
-		if m.currentField == FieldLocalPort {
-			localPortValue += "█"
-		}
-		localPortField := "Local Port: "
-		if m.currentField == FieldLocalPort {
-			localPortField = activeFieldStyle.Render(localPortField + localPortValue)
-		} else {
-			localPortField = fieldStyle.Render(localPortField + localPortValue)
-		}
-		content.WriteString(localPortField + "\n\n")
-		
+		content.WriteString(m.renderInputField("Remote Port: ", FieldRemotePort, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Local Port: ", FieldLocalPort, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Remote Bind: ", FieldRemoteHost, "", fieldStyle, activeFieldStyle) + "\n\n")
+
 	case forwarding.DynamicForward:
-		// Local Port only
-		localPortValue := m.formData.LocalPort
-		if m.currentField == FieldLocalPort {
-			localPortValue += "█"
-		}
-		localPortField := "SOCKS5 Port: "
-		if m.currentField == FieldLocalPort {
-			localPortField = activeFieldStyle.Render(localPortField + localPortValue)
-		} else {
-			localPortField = fieldStyle.Render(localPortField + localPortValue)
-		}
-		content.WriteString(localPortField + "\n\n")
-	}
-	
-	// Description field (always shown)
-	descValue := m.formData.Description
-	if m.currentField == FieldDescription {
-		descValue += "█"
-	}
-	descField := "Description: "
-	if m.currentField == FieldDescription {
-		descField = activeFieldStyle.Render(descField + descValue)
-	} else {
-		descField = fieldStyle.Render(descField + descValue)
+		content.WriteString(m.renderInputField("SOCKS5 Port: ", FieldLocalPort, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Bind Address: ", FieldLocalHost, "", fieldStyle, activeFieldStyle) + "\n\n")
+
+	case forwarding.RemoteDynamicForward:
+		content.WriteString(m.renderInputField("SOCKS5 Port: ", FieldRemotePort, "", fieldStyle, activeFieldStyle) + "\n\n")
+		content.WriteString(m.renderInputField("Remote Bind: ", FieldRemoteHost, "", fieldStyle, activeFieldStyle) + "\n\n")
 	}
-	content.WriteString(descField + "\n\n")
-	
+
+	// Expiry, hops, labels and description fields (always shown)
+	content.WriteString(m.renderInputField("Expire: ", FieldExpiry, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Via: ", FieldVia, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Labels: ", FieldLabels, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Description: ", FieldDescription, "", fieldStyle, activeFieldStyle) + "\n\n")
+
 	// Example command
 	exampleStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#888888")).
+		BorderForeground(m.theme.Subtle).
 		Padding(1, 2).
-		Width(m.width - 4).
-		Foreground(lipgloss.Color("#888888"))
-	
+		Width(max(12, m.width-4)).
+		Foreground(m.theme.Subtle)
+
 	var example string
 	switch m.forwardingType {
 	case forwarding.LocalForward:
-		if m.formData.LocalPort != "" && m.formData.RemoteHost != "" && m.formData.RemotePort != "" {
+		if m.formData.LocalPort != "" && strings.HasPrefix(m.formData.RemoteHost, "/") && !m.formData.UseExistingHost {
+			example = fmt.Sprintf("Equivalent: ssh -L %s:%s user@host (unix socket, no remote port)",
+				m.formData.LocalPort, m.formData.RemoteHost)
+		} else if m.formData.LocalPort != "" && m.formData.RemoteHost != "" && m.formData.RemotePort != "" {
 			var hostInfo string
 			if m.formData.UseExistingHost && m.formData.SelectedRemoteHostIndex < len(m.hosts) {
 				selectedHost := m.hosts[m.formData.SelectedRemoteHostIndex]
@@ -231,10 +137,10 @@ func (m Model) renderForwardingAddView() string {
 			} else {
 				hostInfo = "via SSH tunnel"
 			}
-			example = fmt.Sprintf("Equivalent: ssh -L %s:%s:%s user@host (%s)", 
+			example = fmt.Sprintf("Equivalent: ssh -L %s:%s:%s user@host (%s)",
 				m.formData.LocalPort, m.formData.RemoteHost, m.formData.RemotePort, hostInfo)
 		} else {
-			example = "Example: ssh -L 8080:google.com:80 user@host"
+			example = "Example: ssh -L 8080:google.com:80 user@host, or a socket path as remote host"
 		}
 	case forwarding.RemoteForward:
 		if m.formData.RemotePort != "" && m.formData.LocalPort != "" {
@@ -248,257 +154,328 @@ func (m Model) renderForwardingAddView() string {
 		} else {
 			example = "Example: ssh -D 1080 user@host"
 		}
+	case forwarding.RemoteDynamicForward:
+		if m.formData.RemotePort != "" {
+			example = fmt.Sprintf("Equivalent: ssh -R %s user@host", m.formData.RemotePort)
+		} else {
+			example = "Example: ssh -R 1080 user@host"
+		}
 	}
 	content.WriteString(exampleStyle.Render(example) + "\n\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	var help string
 	if m.currentField == FieldRemoteHost && m.forwardingType == forwarding.LocalForward {
 		help = "Tab: next field • Enter: select remote host • ESC: back"
+	} else if m.editingSessionID != "" {
+		help = "Tab: next field • Enter: apply changes • ESC: back"
 	} else {
 		help = "Tab: next field • Enter: start forwarding • ESC: back"
 	}
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderForwardingListView renders the list of active forwarding sessions
 func (m Model) renderForwardingListView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Active Port Forwarding Sessions")
 	content.WriteString(header + "\n\n")
-	
+
+	// Filter
+	filtered := m.forwardingSearch.Value() != ""
+	if m.forwardingSearching || filtered {
+		content.WriteString(m.forwardingSearch.View() + "\n\n")
+	}
+
 	// Get active sessions
-	sessions := m.forwardingManager.GetAllSessions()
-	
+	sessions := m.listedSessions()
+
 	if len(sessions) == 0 {
 		emptyStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#999999")).
+			Foreground(m.theme.Subtle).
 			Italic(true).
 			Align(lipgloss.Center).
 			Width(m.width)
-		
-		content.WriteString(emptyStyle.Render("No active port forwarding sessions") + "\n\n")
+
+		empty := "No active port forwarding sessions"
+		if filtered {
+			empty = "No port forwarding sessions match the filter"
+		}
+		content.WriteString(emptyStyle.Render(empty) + "\n\n")
+	} else if m.isSplit() {
+		// Compact list with the selected session's stats beside it
+		content.WriteString(m.renderForwardingSplit(sessions) + "\n")
 	} else {
 		// Session list
-		selectedStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Background(lipgloss.Color("#7D56F4")).
-			Bold(true)
-		
+		selectedStyle := m.theme.SelectedStyle()
+
 		sessionStyle := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#7D56F4")).
+			BorderForeground(m.theme.Primary).
 			Padding(1, 2).
-			Width(m.width - 4).
+			Width(m.width-4).
 			Margin(0, 0, 1, 0)
-		
+
 		for i, session := range sessions {
 			cursor := "  "
 			if m.cursor == i {
 				cursor = "▶ "
 			}
-			
-			// Format session info
-			var sessionInfo string
-			switch session.Rule.Type {
-			case forwarding.LocalForward:
-				sessionInfo = fmt.Sprintf("%s%s: Local:%d → %s:%d",
-					cursor, session.Rule.Type.String(),
-					session.Rule.LocalPort, session.Rule.RemoteHost, session.Rule.RemotePort)
-			case forwarding.RemoteForward:
-				sessionInfo = fmt.Sprintf("%s%s: Remote:%d → Local:%d",
-					cursor, session.Rule.Type.String(),
-					session.Rule.RemotePort, session.Rule.LocalPort)
-			case forwarding.DynamicForward:
-				sessionInfo = fmt.Sprintf("%s%s: SOCKS5 on port %d",
-					cursor, session.Rule.Type.String(), session.Rule.LocalPort)
-			}
-			
-			if session.Rule.Description != "" {
-				sessionInfo += fmt.Sprintf(" (%s)", session.Rule.Description)
-			}
-			
-			// Add statistics
-			uptime := session.GetUptime()
-			rxRate, txRate := session.GetTransferRate()
-			statsInfo := fmt.Sprintf("\nUptime: %v | Connections: %d active, %d total",
-				uptime.Round(time.Second),
-				session.Stats.ActiveConnections,
-				session.Stats.ConnectionCount)
-			
-			if session.Stats.BytesReceived > 0 || session.Stats.BytesSent > 0 {
-				statsInfo += fmt.Sprintf("\nTraffic: ↓%.1fKB (%.1fKB/s) ↑%.1fKB (%.1fKB/s)",
-					float64(session.Stats.BytesReceived)/1024, rxRate/1024,
-					float64(session.Stats.BytesSent)/1024, txRate/1024)
-			}
-			
-			if session.Stats.ErrorCount > 0 {
-				statsInfo += fmt.Sprintf("\nErrors: %d (Last: %s)",
-					session.Stats.ErrorCount, session.Stats.LastError)
-			}
-			
+
+			sessionInfo := cursor + forwardingSessionTitle(session)
+			statsInfo := "\n" + m.forwardingSessionStats(session)
+
 			sessionDisplay := sessionInfo + statsInfo
-			
+
 			sessionBox := sessionStyle.Render(sessionDisplay)
 			if m.cursor == i {
 				sessionBox = selectedStyle.Render(sessionBox)
 			}
-			
+
 			content.WriteString(sessionBox + "\n")
 		}
 	}
-	
+
 	// Summary statistics if there are sessions
 	if len(sessions) > 0 {
 		summaryStyle := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#00FF00")).
+			BorderForeground(m.theme.Success).
 			Padding(1, 2).
 			Width(m.width - 4).
 			Bold(true)
-		
+
 		totalConnections := int64(0)
 		totalBytes := int64(0)
 		totalErrors := int64(0)
-		
+
 		for _, session := range sessions {
 			totalConnections += session.Stats.ConnectionCount
 			totalBytes += session.Stats.BytesReceived + session.Stats.BytesSent
 			totalErrors += session.Stats.ErrorCount
 		}
-		
+
 		summary := fmt.Sprintf("Summary: %d sessions | %d total connections | %.1f MB transferred | %d errors",
 			len(sessions), totalConnections, float64(totalBytes)/(1024*1024), totalErrors)
-		
+
+		// SSH connections the sessions share, closed when the last one stops
+		for _, client := range m.forwardingManager.Clients() {
+			summary += fmt.Sprintf("\nSSH %s (%s): %d sessions, %d open, up %v",
+				client.Address, client.Host, client.Sessions, client.Connections,
+				time.Since(client.Opened).Round(time.Second))
+		}
+
 		content.WriteString(summaryStyle.Render(summary) + "\n\n")
 	}
-	
+
 	// Message
-	if m.message != "" {
-		messageStyle := lipgloss.NewStyle().
-			Width(m.width).
-			Align(lipgloss.Center)
-		
-		var msgStyle lipgloss.Style
-		switch m.messageType {
-		case "success":
-			msgStyle = messageStyle.Foreground(lipgloss.Color("#00FF00"))
-		case "error":
-			msgStyle = messageStyle.Foreground(lipgloss.Color("#FF0000"))
-		default:
-			msgStyle = messageStyle.Foreground(lipgloss.Color("#FFFF00"))
-		}
-		content.WriteString(msgStyle.Render(m.message) + "\n")
-	}
-	
+	content.WriteString(m.renderToasts())
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
-	help := "↑/k: up • ↓/j: down • s: stop selected • a: add new • ESC/q: back"
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k: up • ↓/j: down • e: edit selected • s: stop selected • S: stop all • c: copy command • r: renew • d: capture • E: errors • /: filter • a: add new • ESC/q: back"
+	if m.forwardingSearching {
+		help = "Type to filter • Enter: keep filter • ESC: clear filter"
+	}
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
+// forwardingSessionTitle describes what a forwarding session forwards
+func forwardingSessionTitle(session *forwarding.ForwardingSession) string {
+	var title string
+	switch session.Rule.Type {
+	case forwarding.LocalForward:
+		title = fmt.Sprintf("%s: Local:%d → %s",
+			session.Rule.Type.String(),
+			session.Rule.LocalPort, session.Rule.RemoteTarget())
+	case forwarding.RemoteForward:
+		title = fmt.Sprintf("%s: Remote:%d → Local:%d",
+			session.Rule.Type.String(),
+			session.Rule.RemotePort, session.Rule.LocalPort)
+	case forwarding.DynamicForward:
+		title = fmt.Sprintf("%s: SOCKS5 on port %d",
+			session.Rule.Type.String(), session.Rule.LocalPort)
+	case forwarding.RemoteDynamicForward:
+		title = fmt.Sprintf("%s: SOCKS5 on remote port %d",
+			session.Rule.Type.String(), session.Rule.RemotePort)
+	}
+
+	if !session.Rule.Type.ListensRemotely() && session.Rule.LocalHost != "" && session.Rule.LocalHost != "localhost" {
+		title += fmt.Sprintf(" [bind %s]", session.Rule.LocalHost)
+	}
+
+	if session.Rule.TLS != nil {
+		title += " [TLS]"
+	}
+
+	if session.Rule.ResolveLocally {
+		title += " [local DNS]"
+	}
+
+	if len(session.Rule.Via) > 0 {
+		title += fmt.Sprintf(" [via %s]", session.Rule.ViaString())
+	}
+
+	if session.CapturePath() != "" {
+		title += " [capturing]"
+	}
+
+	if len(session.Rule.Labels) > 0 {
+		title += fmt.Sprintf(" [labels %s]", session.Rule.LabelsString())
+	}
+
+	if session.Rule.RequestedPort != 0 {
+		title += fmt.Sprintf(" [%d in use]", session.Rule.RequestedPort)
+	}
+
+	if session.Rule.Description != "" {
+		title += fmt.Sprintf(" (%s)", session.Rule.Description)
+	}
+	return title
+}
+
+// expiryWarning is the remaining time under which the expiry of a forwarding
+// is highlighted
+const expiryWarning = 5 * time.Minute
+
+// forwardingSessionStats renders the statistics of a forwarding session
+func (m Model) forwardingSessionStats(session *forwarding.ForwardingSession) string {
+	uptime := session.GetUptime()
+	avgRxRate, avgTxRate := session.GetTransferRate()
+	statsInfo := fmt.Sprintf("Uptime: %v | Connections: %d active, %d total",
+		uptime.Round(time.Second),
+		session.Stats.ActiveConnections,
+		session.Stats.ConnectionCount)
+	if session.Stats.RestartCount > 0 {
+		statsInfo += fmt.Sprintf(" | Restarted: %d", session.Stats.RestartCount)
+	}
+	if session.Stats.ReconnectCount > 0 {
+		statsInfo += fmt.Sprintf(" | Reconnected: %d", session.Stats.ReconnectCount)
+	}
+	if expiresAt := session.ExpiresAt(); !expiresAt.IsZero() {
+		remaining := time.Until(expiresAt).Round(time.Second)
+		expiry := fmt.Sprintf("Expires in %v", remaining)
+		if remaining < expiryWarning {
+			expiry = lipgloss.NewStyle().Foreground(m.theme.Warning).Render(expiry)
+		}
+		statsInfo += " | " + expiry
+	}
+	if session.Degraded() {
+		statsInfo = lipgloss.NewStyle().Foreground(m.theme.Warning).Render("⚠ SSH connection lost, reconnecting") + "\n" + statsInfo
+	}
+
+	if session.Stats.BytesReceived > 0 || session.Stats.BytesSent > 0 {
+		rxRate, txRate := session.GetCurrentRate()
+		statsInfo += fmt.Sprintf("\nTraffic: ↓%s ↑%s | now ↓%s ↑%s | avg ↓%s ↑%s",
+			forwarding.FormatBytes(session.Stats.BytesReceived),
+			forwarding.FormatBytes(session.Stats.BytesSent),
+			formatRate(rxRate), formatRate(txRate),
+			formatRate(avgRxRate), formatRate(avgTxRate))
+	}
+
+	// Graph of the rates sampled by the dashboard
+	if rates := m.trafficHistory[session.Rule.ID]; rates != nil {
+		statsInfo += fmt.Sprintf("\nLast %ds: %s", trafficGraphSamples,
+			sparkline(rates, trafficGraphSamples))
+	}
+
+	if session.Resumed() {
+		totals := session.Totals()
+		statsInfo += fmt.Sprintf("\nSince %s: %d connections | ↓%s ↑%s",
+			totals.Since.Format("2006-01-02"), totals.Connections,
+			forwarding.FormatBytes(totals.BytesReceived), forwarding.FormatBytes(totals.BytesSent))
+	}
+
+	if session.Stats.ErrorCount > 0 {
+		statsInfo += fmt.Sprintf("\nErrors: %d (Last: %s)",
+			session.Stats.ErrorCount, session.LastError())
+	}
+	return statsInfo
+}
+
 // renderRemoteHostSelectView renders the remote host selection view
 func (m Model) renderRemoteHostSelectView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Select Remote Host")
 	content.WriteString(header + "\n\n")
-	
+
 	// Instructions
 	infoStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
 		Width(m.width - 4)
-	
+
 	info := "Choose an existing SSH host as the remote host, or select 'Manual Input' to enter a custom host address."
 	content.WriteString(infoStyle.Render(info) + "\n\n")
-	
+
 	// Host list
-	selectedStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Bold(true)
-	
+	selectedStyle := m.theme.SelectedStyle()
+
 	hostStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(0, 1).
-		Width(m.width - 8).
+		Width(m.width-8).
 		Margin(0, 2)
-	
+
 	// Show existing hosts
 	for i, host := range m.hosts {
 		cursor := "  "
 		if m.cursor == i {
 			cursor = "▶ "
 		}
-		
+
 		hostDisplay := fmt.Sprintf("%s%s (%s@%s:%s)", cursor, host.Name, host.User, host.Host, host.Port)
-		
+
 		if m.cursor == i {
 			content.WriteString(selectedStyle.Render(hostStyle.Render(hostDisplay)) + "\n")
 		} else {
 			content.WriteString(hostStyle.Render(hostDisplay) + "\n")
 		}
 	}
-	
+
 	// Manual input option
 	cursor := "  "
 	if m.cursor == len(m.hosts) {
 		cursor = "▶ "
 	}
-	
+
 	manualOption := fmt.Sprintf("%s📝 Manual Input (Enter custom host address)", cursor)
 	manualStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
+		BorderForeground(m.theme.Accent).
 		Padding(0, 1).
-		Width(m.width - 8).
+		Width(m.width-8).
 		Margin(1, 2).
 		Italic(true)
-	
+
 	if m.cursor == len(m.hosts) {
 		content.WriteString(selectedStyle.Render(manualStyle.Render(manualOption)) + "\n\n")
 	} else {
 		content.WriteString(manualStyle.Render(manualOption) + "\n\n")
 	}
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	help := "↑/k: up • ↓/j: down • Enter: select • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
-}
\ No newline at end of file
+}
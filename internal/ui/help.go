@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// helpOverlay is the state of the open help overlay
+type helpOverlay struct {
+	query  string // Filter typed into the overlay
+	scroll int    // First line shown
+}
+
+// helpLine is one line of the help overlay: a section title, a shortcut or a
+// blank line between sections
+type helpLine struct {
+	section string // Title of the section the line belongs to
+	keys    string // Empty for a section title
+	desc    string
+}
+
+// allModesKeyMap appends the shortcuts of every other view mode to those of
+// the active one, listing sections shared by several modes once
+func (m Model) allModesKeyMap(sections []keySection) []keySection {
+	seen := map[string]bool{}
+	for _, section := range sections {
+		seen[section.title] = true
+	}
+
+	for mode := ModeList; mode < modeCount; mode++ {
+		other := m
+		other.viewMode = mode
+		for _, section := range other.modeKeyMap() {
+			if !seen[section.title] {
+				seen[section.title] = true
+				sections = append(sections, section)
+			}
+		}
+	}
+	return sections
+}
+
+// helpLines returns the lines of the help overlay. Without a query they are
+// the shortcuts of the active mode; with one, the shortcuts of every mode
+// whose keys, description or section title contain it.
+func (m Model) helpLines() []helpLine {
+	sections := m.modeKeyMap()
+	last := &sections[len(sections)-1]
+	last.bindings = append(last.bindings, helpKeys)
+
+	query := strings.ToLower(strings.TrimSpace(m.help.query))
+	if query != "" {
+		sections = m.allModesKeyMap(sections)
+	}
+
+	var lines []helpLine
+	for _, section := range sections {
+		sectionMatches := strings.Contains(strings.ToLower(section.title), query)
+		var items []helpLine
+		for _, binding := range section.bindings {
+			help := binding.Help()
+			if sectionMatches ||
+				strings.Contains(strings.ToLower(help.Key), query) ||
+				strings.Contains(strings.ToLower(help.Desc), query) {
+				items = append(items, helpLine{section: section.title, keys: help.Key, desc: help.Desc})
+			}
+		}
+		if len(items) > 0 {
+			if len(lines) > 0 {
+				lines = append(lines, helpLine{}) // Blank line between sections
+			}
+			lines = append(lines, helpLine{section: section.title})
+			lines = append(lines, items...)
+		}
+	}
+	return lines
+}
+
+// helpPageSize is the number of help lines that fit in the overlay
+func (m Model) helpPageSize() int {
+	// Border, padding, title, search line, scroll markers and footer
+	return max(3, m.height-19)
+}
+
+// openHelp opens the help overlay for the active mode
+func (m Model) openHelp() (tea.Model, tea.Cmd) {
+	m.help = &helpOverlay{}
+	return m, nil
+}
+
+// handleHelpKeys handles keys while the help overlay is open. Printable keys
+// filter the shortcuts.
+func (m Model) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	help := m.help
+	maxScroll := max(0, len(m.helpLines())-m.helpPageSize())
+
+	switch msg.String() {
+	case "ctrl+c", "f1":
+		m.help = nil
+
+	case "esc":
+		// Clear the filter first, close on the next ESC
+		if help.query != "" {
+			help.query = ""
+			help.scroll = 0
+		} else {
+			m.help = nil
+		}
+
+	case "?":
+		if help.query == "" {
+			m.help = nil
+		} else {
+			help.query += "?"
+			help.scroll = 0
+		}
+
+	case "up":
+		help.scroll = max(0, help.scroll-1)
+
+	case "down":
+		help.scroll = min(maxScroll, help.scroll+1)
+
+	case "pgup":
+		help.scroll = max(0, help.scroll-m.helpPageSize())
+
+	case "pgdown":
+		help.scroll = min(maxScroll, help.scroll+m.helpPageSize())
+
+	case "backspace":
+		if help.query != "" {
+			runes := []rune(help.query)
+			help.query = string(runes[:len(runes)-1])
+			help.scroll = 0
+		}
+
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			help.query += string(msg.Runes)
+			help.scroll = 0
+		}
+	}
+
+	return m, nil
+}
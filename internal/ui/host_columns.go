@@ -0,0 +1,302 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// tableColumn is a column the host table can show
+type tableColumn struct {
+	id       string // Name used in the config file
+	title    string
+	priority int  // Lower is more important; the least important go first when narrow
+	minWidth int  // Width below which the column is dropped rather than shrunk, at least the title's
+	maxWidth int  // Longest width the column takes, 0 for no limit
+	grow     bool // Whether the column takes a share of spare width
+	value    func(m Model, host config.SSHHost) string
+}
+
+// tableColumns lists every column of the host table
+var tableColumns = []tableColumn{
+	{id: "name", title: "NAME", priority: 0, minWidth: 8, grow: true,
+		value: func(m Model, host config.SSHHost) string { return host.Name }},
+	{id: "host", title: "HOST", priority: 1, minWidth: 8, grow: true,
+		value: func(m Model, host config.SSHHost) string { return host.Host }},
+	{id: "user", title: "USER", priority: 3, minWidth: 4, grow: true,
+		value: func(m Model, host config.SSHHost) string { return host.User }},
+	{id: "port", title: "PORT", priority: 4, minWidth: 4,
+		value: func(m Model, host config.SSHHost) string { return host.Port }},
+	{id: "auth", title: "AUTH", priority: 2, minWidth: 4,
+		value: func(m Model, host config.SSHHost) string {
+			if host.Identity != "" {
+				return "KEY"
+			}
+			return "PWD"
+		}},
+	{id: "identity", title: "IDENTITY", priority: 7, minWidth: 8, maxWidth: 30,
+		value: func(m Model, host config.SSHHost) string { return host.Identity }},
+	{id: "tags", title: "TAGS", priority: 5, minWidth: 6, maxWidth: 24,
+		value: func(m Model, host config.SSHHost) string {
+			return strings.Join(m.metadata.Tags(host.Name), ",")
+		}},
+	{id: "last_used", title: "LAST USED", priority: 8, minWidth: 8,
+		value: func(m Model, host config.SSHHost) string { return formatLastUsed(m.lastUsed[host.Name]) }},
+	{id: "jump", title: "JUMP HOST", priority: 6, minWidth: 8, maxWidth: 24,
+		value: func(m Model, host config.SSHHost) string { return host.ProxyJump }},
+}
+
+// defaultColumns are the columns shown when the config does not choose any
+var defaultColumns = []string{"name", "host", "user", "port", "auth"}
+
+// findTableColumn returns the column with the given id
+func findTableColumn(id string) (tableColumn, bool) {
+	for _, column := range tableColumns {
+		if column.id == id {
+			return column, true
+		}
+	}
+	return tableColumn{}, false
+}
+
+// resolveColumns returns the known columns of a configured list, or the
+// default columns when it names none. The name column is always shown.
+func resolveColumns(ids []string) []string {
+	var columns []string
+	for _, id := range ids {
+		if _, ok := findTableColumn(id); ok && !slices.Contains(columns, id) {
+			columns = append(columns, id)
+		}
+	}
+	if len(columns) == 0 {
+		return slices.Clone(defaultColumns)
+	}
+	if !slices.Contains(columns, "name") {
+		columns = append([]string{"name"}, columns...)
+	}
+	return columns
+}
+
+// visibleColumns returns the columns the host table shows, in order
+func (m Model) visibleColumns() []tableColumn {
+	columns := make([]tableColumn, 0, len(m.columns))
+	for _, id := range m.columns {
+		if column, ok := findTableColumn(id); ok {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// calculateColumnWidths fits columns into the list pane. Every column starts
+// as wide as its content; when they do not fit, the least important columns
+// are shrunk to their minimum and then dropped (width 0). Spare width goes
+// to the growing columns.
+func (m Model) calculateColumnWidths(columns []tableColumn) []int {
+	// Cursor, borders and padding
+	available := m.listPaneWidth() - 8 - 2
+
+	natural := make([]int, len(columns))
+	for i, column := range columns {
+		natural[i] = len(column.title)
+		for _, host := range m.filteredHosts {
+			natural[i] = max(natural[i], len(column.value(m, host)))
+		}
+		if column.maxWidth > 0 {
+			natural[i] = min(natural[i], column.maxWidth)
+		}
+		natural[i] = max(natural[i], 4)
+	}
+
+	// Least important first
+	order := make([]int, len(columns))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return columns[b].priority - columns[a].priority
+	})
+
+	total := func(widths []int) int {
+		sum, shown := 0, 0
+		for _, width := range widths {
+			if width > 0 {
+				sum += width
+				shown++
+			}
+		}
+		return sum + 3*max(0, shown-1) // " │ " between columns
+	}
+
+	// Drop the least important columns until the rest fit at their minimum
+	keep := make([]bool, len(columns))
+	for i := range keep {
+		keep[i] = true
+	}
+	minimums := make([]int, len(columns))
+	for i, column := range columns {
+		minimums[i] = min(max(column.minWidth, len(column.title)), natural[i])
+	}
+	for _, i := range order[:max(0, len(order)-1)] {
+		if total(minimums) <= available {
+			break
+		}
+		keep[i] = false
+		minimums[i] = 0
+	}
+
+	widths := make([]int, len(columns))
+	for i := range columns {
+		if keep[i] {
+			widths[i] = natural[i]
+		}
+	}
+
+	// Shrink the least important columns toward their minimum
+	for _, i := range order {
+		over := total(widths) - available
+		if over <= 0 {
+			break
+		}
+		if keep[i] {
+			widths[i] -= min(over, widths[i]-minimums[i])
+		}
+	}
+	if over := total(widths) - available; over > 0 {
+		// Not even the most important column fits; give it what is left
+		widths[order[len(order)-1]] = max(1, widths[order[len(order)-1]]-over)
+	}
+
+	// Share out spare width, the last growing column taking the remainder
+	var growing []int
+	for i, column := range columns {
+		if column.grow && widths[i] > 0 {
+			growing = append(growing, i)
+		}
+	}
+	if extra := available - total(widths); extra > 0 && len(growing) > 0 {
+		share := extra / len(growing)
+		for _, i := range growing {
+			widths[i] += share
+		}
+		widths[growing[len(growing)-1]] += extra - share*len(growing)
+	}
+
+	return widths
+}
+
+// formatLastUsed renders how long ago a host was connected to
+func formatLastUsed(last time.Time) string {
+	if last.IsZero() {
+		return "-"
+	}
+	since := time.Since(last)
+	switch {
+	case since < time.Minute:
+		return "just now"
+	case since < time.Hour:
+		return fmt.Sprintf("%dm ago", int(since.Minutes()))
+	case since < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(since.Hours()))
+	case since < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(since.Hours()/24))
+	}
+	return last.Format("2006-01-02")
+}
+
+// columnPicker is the screen choosing the columns of the host table
+type columnPicker struct {
+	cursor int
+}
+
+// columnPickerRows returns the ids listed by the column picker: the shown
+// columns in order, then the hidden ones
+func (m Model) columnPickerRows() []string {
+	rows := slices.Clone(m.columns)
+	for _, column := range tableColumns {
+		if !slices.Contains(rows, column.id) {
+			rows = append(rows, column.id)
+		}
+	}
+	return rows
+}
+
+// openColumnPicker shows the column picker
+func (m Model) openColumnPicker() (tea.Model, tea.Cmd) {
+	m.columnPicker = &columnPicker{}
+	m.viewMode = ModeColumns
+	return m, nil
+}
+
+// toggleColumn shows or hides a column of the host table and saves the
+// choice. The name column cannot be hidden.
+func (m *Model) toggleColumn(id string) {
+	if id == "name" {
+		m.message = "The name column is always shown"
+		m.messageType = "info"
+		return
+	}
+	if index := slices.Index(m.columns, id); index >= 0 {
+		m.columns = slices.Delete(slices.Clone(m.columns), index, index+1)
+	} else {
+		m.columns = append(slices.Clone(m.columns), id)
+	}
+	m.saveColumns()
+}
+
+// saveColumns stores the columns of the host table in the app config
+func (m *Model) saveColumns() {
+	if err := config.SaveListColumns(m.columns); err != nil {
+		m.message = fmt.Sprintf("Failed to save columns: %v", err)
+		m.messageType = "error"
+	}
+}
+
+// handleColumnsMode handles keys in the column picker
+func (m Model) handleColumnsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	picker := m.columnPicker
+	rows := m.columnPickerRows()
+
+	switch msg.String() {
+	case "esc", "q", "C":
+		m.columnPicker = nil
+		m.viewMode = ModeList
+
+	case "up", "k":
+		if picker.cursor > 0 {
+			picker.cursor--
+		}
+
+	case "down", "j":
+		if picker.cursor < len(rows)-1 {
+			picker.cursor++
+		}
+
+	case " ", "enter":
+		id := rows[picker.cursor]
+		m.toggleColumn(id)
+		// Keep the cursor on the column, which moved between the groups
+		picker.cursor = slices.Index(m.columnPickerRows(), id)
+
+	case "K", "J":
+		// Move a shown column left (up) or right (down) in the table
+		index := slices.Index(m.columns, rows[picker.cursor])
+		target := index - 1
+		if msg.String() == "J" {
+			target = index + 1
+		}
+		if index < 0 || target < 0 || target >= len(m.columns) {
+			return m, nil
+		}
+		m.columns = slices.Clone(m.columns)
+		m.columns[index], m.columns[target] = m.columns[target], m.columns[index]
+		picker.cursor = target
+		m.saveColumns()
+	}
+
+	return m, nil
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderColumnsView renders the column picker
+func (m Model) renderColumnsView() string {
+	var content strings.Builder
+	picker := m.columnPicker
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Columns")
+	content.WriteString(header + "\n\n")
+
+	selectedStyle := m.theme.SelectedStyle()
+	hiddenStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	for i, id := range m.columnPickerRows() {
+		column, _ := findTableColumn(id)
+
+		cursor := "  "
+		if i == picker.cursor {
+			cursor = "▶ "
+		}
+		check := "[ ]"
+		position := ""
+		if index := slices.Index(m.columns, id); index >= 0 {
+			check = "[x]"
+			position = fmt.Sprintf("%d", index+1)
+		}
+		line := fmt.Sprintf("%s%s %-2s %s", cursor, check, position, column.title)
+		if id == "name" {
+			line += " (always shown)"
+		}
+
+		switch {
+		case i == picker.cursor:
+			content.WriteString(selectedStyle.Render(line) + "\n")
+		case position == "":
+			content.WriteString(hiddenStyle.Render(line) + "\n")
+		default:
+			content.WriteString(line + "\n")
+		}
+	}
+	content.WriteString("\n")
+
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • Space/Enter: show/hide • K/J: move column left/right • ESC: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,55 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// highlightMatches renders text with base, and the parts matching any of the
+// search terms with match. Each part is rendered on its own so the base
+// style is not cut off after a highlight.
+func highlightMatches(text string, terms []config.SearchTerm, base, match lipgloss.Style) string {
+	lower := strings.ToLower(text)
+	if len(terms) == 0 || len(lower) != len(text) {
+		// Lower-casing changed the byte offsets; do not highlight
+		return base.Render(text)
+	}
+
+	// Collect the matched byte ranges, then merge overlapping ones
+	var ranges [][2]int
+	for _, term := range terms {
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term.Value)
+			if i < 0 {
+				break
+			}
+			ranges = append(ranges, [2]int{start + i, start + i + len(term.Value)})
+			start += i + len(term.Value)
+		}
+	}
+	if len(ranges) == 0 {
+		return base.Render(text)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	var out strings.Builder
+	pos := 0
+	for _, r := range ranges {
+		if r[1] <= pos {
+			continue
+		}
+		start := max(r[0], pos)
+		if start > pos {
+			out.WriteString(base.Render(text[pos:start]))
+		}
+		out.WriteString(match.Render(text[start:r[1]]))
+		pos = r[1]
+	}
+	if pos < len(text) {
+		out.WriteString(base.Render(text[pos:]))
+	}
+	return out.String()
+}
@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// inlineRows is the number of hosts the inline list shows at once
+const inlineRows = 10
+
+// WithInline returns the model set up to run without the alternate screen:
+// the host list is rendered compactly in place and the selection is left in
+// the scrollback when xssh exits
+func (m Model) WithInline() Model {
+	m.inline = true
+	return m
+}
+
+// quit ends the program, remembering the active filter, ending the undo of
+// a deletion and leaving the final frame behind in inline mode
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.rememberFilter()
+	m.forgetDeletedHost()
+	m.quitting = true
+	return m, tea.Quit
+}
+
+// renderInlineListView renders the host list as a few lines without borders,
+// scrolled to keep the cursor visible
+func (m Model) renderInlineListView() string {
+	var content strings.Builder
+
+	filterStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+	selectedStyle := m.theme.SelectedStyle()
+
+	// Filter line
+	switch {
+	case m.rename != nil:
+		content.WriteString(filterStyle.Render(fmt.Sprintf("Renaming '%s' • Enter: save • ESC: cancel", m.rename.oldName)))
+	case m.cmdLine != nil:
+		content.WriteString(filterStyle.Render(":"+m.cmdLine.text+"█") + "  " + subtleStyle.Render(m.commandLineHint()))
+	case m.searchMode:
+		content.WriteString(filterStyle.Render("/" + m.filterQuery + "█"))
+	case m.filterQuery != "":
+		content.WriteString(filterStyle.Render("Filtered by: " + m.filterQuery))
+	default:
+		content.WriteString(filterStyle.Render(fmt.Sprintf("xssh • %d hosts", len(m.filteredHosts))))
+	}
+	content.WriteString("\n")
+
+	if len(m.filteredHosts) == 0 {
+		if m.filterQuery == "" {
+			content.WriteString(subtleStyle.Render("  No SSH hosts configured") + "\n")
+		} else {
+			content.WriteString(subtleStyle.Render("  No hosts match your filter") + "\n")
+		}
+	} else {
+		start := max(0, min(m.cursor-inlineRows/2, len(m.filteredHosts)-inlineRows))
+		end := min(len(m.filteredHosts), start+inlineRows)
+
+		terms := config.ParseSearchQuery(m.filterQuery)
+		for i := start; i < end; i++ {
+			host := m.filteredHosts[i]
+			cursor := " "
+			if m.cursor == i {
+				cursor = "▶"
+			}
+			if m.markedHosts[host.Name] {
+				cursor += "●"
+			} else {
+				cursor += " "
+			}
+			row := cursor + m.formatTableRow(host)
+
+			rowStyle := lipgloss.NewStyle()
+			if m.cursor == i {
+				rowStyle = selectedStyle
+			} else if label, labeled := m.hostLabelColor(host.Name); labeled {
+				rowStyle = rowStyle.Foreground(label)
+			}
+			matchStyle := rowStyle.Underline(true).Bold(true)
+			content.WriteString(highlightMatches(row, terms, rowStyle, matchStyle) + "\n")
+		}
+		if hidden := len(m.filteredHosts) - (end - start); hidden > 0 {
+			content.WriteString(subtleStyle.Render(fmt.Sprintf("  %d/%d • %d more", m.cursor+1, len(m.filteredHosts), hidden)) + "\n")
+		}
+	}
+
+	content.WriteString(m.renderToasts())
+	content.WriteString(subtleStyle.Render("↑/↓: move • Enter: connect • /: search • :: commands • ?: help • q: quit"))
+	return content.String()
+}
+
+// renderInlineResult is the last frame of inline mode, which stays in the
+// scrollback: the host that was picked, or nothing
+func (m Model) renderInlineResult() string {
+	if m.selectedHost == nil {
+		return ""
+	}
+	host := m.selectedHost
+	target := host.Host
+	if host.User != "" {
+		target = host.User + "@" + target
+	}
+	if host.Port != "" && host.Port != "22" {
+		target += ":" + host.Port
+	}
+	return fmt.Sprintf("xssh → %s (%s)\n", host.Name, target)
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// formFieldCount is the number of FormField values, used to size Model.inputs
+const formFieldCount = int(FieldKeyPassphraseConfirm) + 1
+
+// newFormInputs creates one text input per form field
+func newFormInputs() [formFieldCount]textinput.Model {
+	var inputs [formFieldCount]textinput.Model
+	for i := range inputs {
+		input := textinput.New()
+		input.Prompt = ""
+		inputs[i] = input
+	}
+
+	inputs[FieldHost].Placeholder = "example.com or 10.0.0.1"
+	inputs[FieldHost].Validate = config.ValidateHostname
+	inputs[FieldUser].Placeholder = "root"
+	inputs[FieldUser].Validate = validateNoSpaces
+	inputs[FieldPort].Placeholder = "22"
+	inputs[FieldPort].CharLimit = 5
+	inputs[FieldPort].Validate = config.ValidatePort
+	inputs[FieldAlias].Placeholder = "my-server"
+	inputs[FieldAlias].Validate = config.ValidateAlias
+
+	inputs[FieldKeyFile].Placeholder = "id_ed25519"
+	inputs[FieldKeyFile].Validate = validateFileName
+	inputs[FieldKeyComment].Placeholder = "user@host"
+	inputs[FieldKeyPassphrase].Placeholder = "empty for no passphrase"
+
+	for _, field := range []FormField{FieldPassword, FieldKeyPassword, FieldKeyPassphrase, FieldKeyPassphraseConfirm} {
+		inputs[field].EchoMode = textinput.EchoPassword
+		inputs[field].EchoCharacter = '*'
+	}
+
+	inputs[FieldLocalHost].Placeholder = "localhost"
+	inputs[FieldLocalHost].Validate = validateNoSpaces
+	inputs[FieldLocalPort].CharLimit = 5
+	inputs[FieldLocalPort].Validate = config.ValidatePort
+	inputs[FieldRemoteHost].Placeholder = "Press Enter to select host"
+	inputs[FieldRemoteHost].Validate = validateNoSpaces
+	inputs[FieldRemotePort].CharLimit = 5
+	inputs[FieldRemotePort].Validate = config.ValidatePort
+	inputs[FieldTLS].Placeholder = "empty, self-signed or cert.pem,key.pem"
+	inputs[FieldResolve].Placeholder = "remote (by the SSH server) or local"
+	inputs[FieldExpiry].Placeholder = "never, or 2h, 30m, 18:00"
+	inputs[FieldExpiry].Validate = validateNoSpaces
+	inputs[FieldVia].Placeholder = "none, or bastion1,bastion2 in order"
+	inputs[FieldVia].Validate = validateNoSpaces
+	inputs[FieldLabels].Placeholder = "none, or dev,db"
+
+	return inputs
+}
+
+// validateNoSpaces rejects values containing whitespace
+func validateNoSpaces(value string) error {
+	if strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("must not contain spaces")
+	}
+	return nil
+}
+
+// focusField moves keyboard focus to the input of the given field
+func (m *Model) focusField(field FormField) tea.Cmd {
+	for i := range m.inputs {
+		m.inputs[i].Blur()
+	}
+	m.currentField = field
+	m.inputs[field].CursorEnd()
+	return m.inputs[field].Focus()
+}
+
+// loadFormInputs copies the form data into the text inputs
+func (m *Model) loadFormInputs() {
+	m.inputs[FieldHost].SetValue(m.formData.Host)
+	m.inputs[FieldUser].SetValue(m.formData.User)
+	m.inputs[FieldPort].SetValue(m.formData.Port)
+	m.inputs[FieldAlias].SetValue(m.formData.Alias)
+	m.inputs[FieldPassword].SetValue(m.formData.Password)
+	m.inputs[FieldKeyPassword].SetValue(m.formData.KeyPassword)
+	m.inputs[FieldLocalHost].SetValue(m.formData.LocalHost)
+	m.inputs[FieldLocalPort].SetValue(m.formData.LocalPort)
+	m.inputs[FieldRemoteHost].SetValue(m.formData.RemoteHost)
+	m.inputs[FieldRemotePort].SetValue(m.formData.RemotePort)
+	m.inputs[FieldTLS].SetValue(m.formData.TLS)
+	m.inputs[FieldResolve].SetValue(m.formData.Resolve)
+	m.inputs[FieldExpiry].SetValue(m.formData.Expiry)
+	m.inputs[FieldVia].SetValue(m.formData.Via)
+	m.inputs[FieldLabels].SetValue(m.formData.Labels)
+	m.inputs[FieldDescription].SetValue(m.formData.Description)
+	m.inputs[FieldKeyFile].SetValue(m.formData.KeyFile)
+	m.inputs[FieldKeyComment].SetValue(m.formData.KeyComment)
+	m.inputs[FieldKeyPassphrase].SetValue(m.formData.KeyPassphrase)
+	m.inputs[FieldKeyPassphraseConfirm].SetValue(m.formData.KeyPassphraseConfirm)
+}
+
+// syncFormData copies the text input values back into the form data
+func (m *Model) syncFormData() {
+	m.formData.Host = m.inputs[FieldHost].Value()
+	m.formData.User = m.inputs[FieldUser].Value()
+	m.formData.Port = m.inputs[FieldPort].Value()
+	m.formData.Alias = m.inputs[FieldAlias].Value()
+	m.formData.Password = m.inputs[FieldPassword].Value()
+	m.formData.KeyPassword = m.inputs[FieldKeyPassword].Value()
+	m.formData.LocalHost = m.inputs[FieldLocalHost].Value()
+	m.formData.LocalPort = m.inputs[FieldLocalPort].Value()
+	m.formData.RemoteHost = m.inputs[FieldRemoteHost].Value()
+	m.formData.RemotePort = m.inputs[FieldRemotePort].Value()
+	m.formData.TLS = m.inputs[FieldTLS].Value()
+	m.formData.Resolve = m.inputs[FieldResolve].Value()
+	m.formData.Expiry = m.inputs[FieldExpiry].Value()
+	m.formData.Via = m.inputs[FieldVia].Value()
+	m.formData.Labels = m.inputs[FieldLabels].Value()
+	m.formData.Description = m.inputs[FieldDescription].Value()
+	m.formData.KeyFile = m.inputs[FieldKeyFile].Value()
+	m.formData.KeyComment = m.inputs[FieldKeyComment].Value()
+	m.formData.KeyPassphrase = m.inputs[FieldKeyPassphrase].Value()
+	m.formData.KeyPassphraseConfirm = m.inputs[FieldKeyPassphraseConfirm].Value()
+}
+
+// updateFocusedInput passes a message to the input of the current field and
+// copies the edited value back into the form data
+func (m *Model) updateFocusedInput(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	m.inputs[m.currentField], cmd = m.inputs[m.currentField].Update(msg)
+	m.syncFormData()
+	return cmd
+}
+
+// isEditingText reports whether the current mode has a focused text input
+func (m Model) isEditingText() bool {
+	switch m.viewMode {
+	case ModeAdd, ModeEdit, ModePasswordInput, ModeKeyPasswordInput, ModeForwardingAdd, ModeKeyGen:
+		return true
+	}
+	return false
+}
+
+// requiredFields lists host form fields that cannot be left empty
+var requiredFields = map[FormField]string{
+	FieldHost:  "host address is required",
+	FieldAlias: "alias is required",
+}
+
+// checkField re-validates a field, including whether a required field is
+// empty, and reports whether it is valid. The error is shown under the field.
+func (m *Model) checkField(field FormField) bool {
+	input := &m.inputs[field]
+	if input.Validate != nil {
+		input.Err = input.Validate(input.Value())
+	}
+	if input.Err == nil && input.Value() == "" && requiredFields[field] != "" {
+		input.Err = errors.New(requiredFields[field])
+	}
+	return input.Err == nil
+}
+
+// validateHostForm checks every field of the add/edit host form before the
+// connection test. It focuses the first invalid field, or reports a missing
+// identity file in the status message, and returns false if anything is
+// wrong.
+func (m *Model) validateHostForm() (bool, tea.Cmd) {
+	for _, field := range []FormField{FieldHost, FieldUser, FieldPort, FieldAlias} {
+		if !m.checkField(field) {
+			return false, m.focusField(field)
+		}
+	}
+	if m.formData.AuthType == AuthKey {
+		if err := config.ValidateIdentityFile(m.formData.Identity); err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// fieldError returns the validation error of a field, if any
+func (m Model) fieldError(field FormField) error {
+	return m.inputs[field].Err
+}
+
+// formFieldWidth returns the width of the field boxes of forms: nearly all of
+// a narrow terminal, half of a wider one, but no more than is readable
+func (m Model) formFieldWidth() int {
+	return max(12, min(m.width-4, min(max(40, m.width/2), 80)))
+}
+
+// formFieldStyles returns the styles of the field boxes of forms and of the
+// focused one, sized for the terminal
+func (m Model) formFieldStyles() (style, activeStyle lipgloss.Style) {
+	style = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.formFieldWidth())
+
+	activeStyle = style.
+		BorderForeground(m.theme.Accent).
+		Bold(true)
+	return style, activeStyle
+}
+
+// renderInputField renders a labelled input box for a form field, followed by
+// its validation error when there is one. Values too long for the box are
+// wrapped while the field is not focused, and scroll while it is.
+func (m Model) renderInputField(label string, field FormField, suffix string, style, activeStyle lipgloss.Style) string {
+	input := m.inputs[field]
+
+	boxStyle := style
+	if m.currentField == field {
+		boxStyle = activeStyle
+	}
+
+	// Leave room for the label, the suffix and the cursor inside the box
+	input.Width = boxStyle.GetWidth() - boxStyle.GetHorizontalPadding() -
+		lipgloss.Width(label) - lipgloss.Width(suffix) - 1
+	if input.Width < 1 {
+		input.Width = 1
+	}
+
+	value := input.View()
+	if m.currentField != field && input.EchoMode == textinput.EchoNormal &&
+		lipgloss.Width(input.Value()) > input.Width {
+		value = input.Value()
+	}
+	rendered := boxStyle.Render(label + value + suffix)
+	if input.Err != nil {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error).
+			MarginLeft(2)
+		rendered += "\n" + errorStyle.Render(input.Err.Error())
+	}
+	return rendered
+}
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// jumpHostChoices returns the ProxyJump values offered by the jump host
+// step: none, a value typed into the config by hand, then every other host
+func (m Model) jumpHostChoices() []string {
+	editing := ""
+	if m.editIndex >= 0 && m.editIndex < len(m.hosts) {
+		editing = m.hosts[m.editIndex].Name
+	}
+
+	choices := []string{""}
+	if jump := m.formData.ProxyJump; jump != "" && m.findHostIndex(jump) < 0 {
+		choices = append(choices, jump)
+	}
+	for _, host := range m.hosts {
+		if host.Name != editing {
+			choices = append(choices, host.Name)
+		}
+	}
+	return choices
+}
+
+// openJumpHostSelect shows the jump host step of the add/edit form, with the
+// current choice selected
+func (m Model) openJumpHostSelect() (tea.Model, tea.Cmd) {
+	m.jumpCursor = max(0, slices.Index(m.jumpHostChoices(), m.formData.ProxyJump))
+	m.viewMode = ModeJumpHostSelect
+	return m, nil
+}
+
+// handleJumpHostSelectMode handles the choice of a jump host
+func (m Model) handleJumpHostSelectMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	choices := m.jumpHostChoices()
+
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ModeAdd
+		if m.editIndex >= 0 {
+			m.viewMode = ModeEdit
+		}
+		return m, m.focusField(FieldPort)
+
+	case "up", "k":
+		if m.jumpCursor > 0 {
+			m.jumpCursor--
+		}
+
+	case "down", "j":
+		if m.jumpCursor < len(choices)-1 {
+			m.jumpCursor++
+		}
+
+	case "enter":
+		m.formData.ProxyJump = choices[m.jumpCursor]
+		m.viewMode = ModeAuthSelect
+	}
+
+	return m, nil
+}
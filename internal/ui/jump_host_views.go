@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderJumpHostSelectView renders the jump host step of the add/edit form
+func (m Model) renderJumpHostSelectView() string {
+	var content strings.Builder
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Connect via Jump Host")
+	content.WriteString(header + "\n\n")
+
+	// Instructions
+	infoStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Width(m.width - 4)
+
+	target := m.formData.Host
+	if target == "" {
+		target = "the new host"
+	}
+	info := fmt.Sprintf("Choose a host to reach %s through (ProxyJump), or connect directly.", target)
+	content.WriteString(infoStyle.Render(info) + "\n\n")
+
+	selectedStyle := m.theme.SelectedStyle()
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	for i, choice := range m.jumpHostChoices() {
+		cursor := "  "
+		if i == m.jumpCursor {
+			cursor = "▶ "
+		}
+
+		name, detail := choice, ""
+		switch index := m.findHostIndex(choice); {
+		case choice == "":
+			name = "Direct connection (no jump host)"
+		case index >= 0:
+			host := m.hosts[index]
+			detail = fmt.Sprintf("(%s@%s:%s)", host.User, host.Host, host.Port)
+		default:
+			detail = "(from config)"
+		}
+
+		if i == m.jumpCursor {
+			content.WriteString(selectedStyle.Render(strings.TrimSpace(cursor+name+" "+detail)) + "\n")
+		} else {
+			content.WriteString(cursor + name + " " + subtleStyle.Render(detail) + "\n")
+		}
+	}
+	content.WriteString("\n")
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • Enter: select • ESC: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,213 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/ssh"
+)
+
+// keyGenFields are the fields of the key generation wizard in tab order
+var keyGenFields = []FormField{FieldKeyType, FieldKeyFile, FieldKeyComment, FieldKeyPassphrase, FieldKeyPassphraseConfirm}
+
+// keyGeneratedMsg reports the result of generating a key pair
+type keyGeneratedMsg struct {
+	path string
+	err  error
+}
+
+// openKeyGenWizard switches to the key generation wizard, prefilled with an
+// ed25519 key named after the host being added
+func (m Model) openKeyGenWizard() (tea.Model, tea.Cmd) {
+	m.formData.KeyType = "ed25519"
+	m.formData.KeyFile = suggestKeyFileName("ed25519", m.formData.Host)
+	m.formData.KeyComment = defaultKeyComment()
+	m.formData.KeyPassphrase = ""
+	m.formData.KeyPassphraseConfirm = ""
+	m.loadFormInputs()
+
+	m.keyGenRunning = false
+	m.viewMode = ModeKeyGen
+	return m, m.focusField(FieldKeyType)
+}
+
+// handleKeyGenMode handles the key generation wizard
+func (m Model) handleKeyGenMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.keyGenRunning {
+		// Wait for the key to be written
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.message = ""
+		if len(m.keyFiles) > 0 {
+			m.viewMode = ModeKeySelect
+		} else {
+			m.viewMode = ModeAuthSelect
+		}
+		return m, nil
+
+	case "enter":
+		return m.startKeyGeneration()
+
+	case "tab", "down":
+		if m.fieldError(m.currentField) != nil {
+			return m, nil
+		}
+		for i, field := range keyGenFields {
+			if field == m.currentField && i < len(keyGenFields)-1 {
+				return m, m.focusField(keyGenFields[i+1])
+			}
+		}
+
+	case "shift+tab", "up":
+		if m.fieldError(m.currentField) != nil {
+			return m, nil
+		}
+		for i, field := range keyGenFields {
+			if field == m.currentField && i > 0 {
+				return m, m.focusField(keyGenFields[i-1])
+			}
+		}
+
+	default:
+		if m.currentField == FieldKeyType {
+			switch msg.String() {
+			case "left", "right", "h", "l", " ":
+				m.toggleKeyType()
+			}
+			return m, nil
+		}
+		return m, m.updateFocusedInput(msg)
+	}
+
+	return m, nil
+}
+
+// toggleKeyType switches between ed25519 and RSA, renaming the key file
+// when it still has the suggested name
+func (m *Model) toggleKeyType() {
+	previous := m.formData.KeyType
+	next := "rsa"
+	if previous == "rsa" {
+		next = "ed25519"
+	}
+
+	if m.formData.KeyFile == suggestKeyFileName(previous, m.formData.Host) {
+		m.inputs[FieldKeyFile].SetValue(suggestKeyFileName(next, m.formData.Host))
+		m.syncFormData()
+	}
+	m.formData.KeyType = next
+}
+
+// startKeyGeneration validates the wizard and generates the key in the
+// background
+func (m Model) startKeyGeneration() (tea.Model, tea.Cmd) {
+	for _, field := range keyGenFields {
+		if err := m.fieldError(field); err != nil {
+			m.message = fmt.Sprintf("Please fix the highlighted field: %v", err)
+			m.messageType = "error"
+			return m, m.focusField(field)
+		}
+	}
+
+	if m.formData.KeyFile == "" {
+		m.message = "Key file name is required"
+		m.messageType = "error"
+		return m, m.focusField(FieldKeyFile)
+	}
+	if m.formData.KeyPassphrase != m.formData.KeyPassphraseConfirm {
+		m.message = "Passphrases do not match"
+		m.messageType = "error"
+		return m, m.focusField(FieldKeyPassphraseConfirm)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to find home directory: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	opts := ssh.KeyGenOptions{
+		Type:       m.formData.KeyType,
+		Path:       filepath.Join(homeDir, ".ssh", m.formData.KeyFile),
+		Passphrase: m.formData.KeyPassphrase,
+		Comment:    m.formData.KeyComment,
+	}
+
+	m.keyGenRunning = true
+	m.message = ""
+	return m, func() tea.Msg {
+		return keyGeneratedMsg{path: opts.Path, err: ssh.GenerateKeyPair(opts)}
+	}
+}
+
+// handleKeyGenerated selects the new key and continues the add/edit form
+func (m Model) handleKeyGenerated(msg keyGeneratedMsg) (tea.Model, tea.Cmd) {
+	m.keyGenRunning = false
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Failed to generate key: %v", msg.err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.loadSSHKeys()
+	m.formData.AuthType = AuthKey
+	m.formData.Identity = msg.path
+	m.formData.KeyPassword = m.formData.KeyPassphrase
+	m.formData.KeyPassphrase = ""
+	m.formData.KeyPassphraseConfirm = ""
+	m.loadFormInputs()
+
+	m.message = fmt.Sprintf("Generated %s (public key: %s.pub)", msg.path, filepath.Base(msg.path))
+	m.messageType = "success"
+	m.viewMode = ModeAdd
+	if m.editIndex >= 0 {
+		m.viewMode = ModeEdit
+	}
+	return m, m.focusField(FieldAlias)
+}
+
+// suggestKeyFileName returns id_<type>, or id_<type>_<host> when the plain
+// name is already taken in ~/.ssh
+func suggestKeyFileName(keyType, host string) string {
+	name := "id_" + keyType
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return name
+	}
+	if _, err := os.Stat(filepath.Join(homeDir, ".ssh", name)); os.IsNotExist(err) {
+		return name
+	}
+
+	suffix := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return r
+		}
+		return '_'
+	}, host)
+	if suffix == "" {
+		return name
+	}
+	return name + "_" + suffix
+}
+
+// defaultKeyComment returns user@hostname for the local machine
+func defaultKeyComment() string {
+	name := "xssh"
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return name + "@" + hostname
+	}
+	return name
+}
@@ -0,0 +1,535 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// keySection is a titled group of shortcuts in the help overlay
+type keySection struct {
+	title    string
+	bindings []key.Binding
+}
+
+// bind describes a shortcut for the help overlay. keys is shown as written,
+// e.g. "↑/k, ↓/j".
+func bind(keys, description string) key.Binding {
+	return key.NewBinding(key.WithHelp(keys, description))
+}
+
+// helpKeys are the shortcuts that open and close the help overlay
+var helpKeys = bind("?, F1", "Toggle this help (type to search it)")
+
+// modeKeyMap returns the shortcuts of the active view mode
+func (m Model) modeKeyMap() []keySection {
+	navigation := bind("↑/k, ↓/j", "Move up/down")
+
+	switch m.viewMode {
+	case ModeAdd, ModeEdit:
+		return []keySection{
+			{"FORM", []key.Binding{
+				bind("Tab, ↓", "Next field"),
+				bind("Shift+Tab, ↑", "Previous field"),
+				bind("Enter", "Next field, or continue on the last one"),
+				bind("ESC", "Cancel"),
+			}},
+		}
+
+	case ModeDelete:
+		return []keySection{
+			{"DELETE HOST", []key.Binding{
+				bind("y", "Delete the host"),
+				bind("n, ESC", "Keep it"),
+			}},
+		}
+
+	case ModeAuthSelect:
+		return []keySection{
+			{"AUTHENTICATION", []key.Binding{
+				bind("1", "Password"),
+				bind("2", "SSH key"),
+				bind("ESC", "Back to the jump host step"),
+			}},
+		}
+
+	case ModeSavedFilters:
+		if m.filterMenu != nil && m.filterMenu.naming {
+			return []keySection{
+				{"SAVE FILTER", []key.Binding{
+					bind("Enter", "Save the active filter under the typed name"),
+					bind("ESC", "Cancel"),
+				}},
+			}
+		}
+		return []keySection{
+			{"SAVED FILTERS", []key.Binding{
+				navigation,
+				bind("Enter", "Apply the selected filter"),
+				bind("s, a", "Save the active filter under a name"),
+				bind("d, x", "Delete the selected filter"),
+				bind("ESC, q, F", "Back to the host list"),
+			}},
+		}
+
+	case ModeSessions:
+		return []keySection{
+			{"SESSIONS", []key.Binding{
+				navigation,
+				bind("x, d", "Terminate the selected command run or daemon terminal"),
+				bind("c", "Clear ended sessions"),
+				bind("ESC, q, S", "Back to the host list"),
+			}},
+		}
+
+	case ModeOnboarding:
+		return []keySection{
+			{"ONBOARDING", []key.Binding{
+				bind("Tab, ↓", "Next field"),
+				bind("Shift+Tab, ↑", "Previous field"),
+				bind("Space", "Toggle \"managed by xssh\""),
+				bind("Enter", "Save and go to the next host"),
+				bind("ESC", "Finish, leaving the remaining hosts as they are"),
+			}},
+		}
+
+	case ModeForwardingErrors:
+		return []keySection{
+			{"FORWARDING ERRORS", []key.Binding{
+				bind("↑/k, ↓/j", "Scroll"),
+				bind("c", "Clear the log"),
+				bind("ESC, q, E", "Back to the forwarding list"),
+			}},
+		}
+
+	case ModeCopyMenu:
+		return []keySection{
+			{"COPY", []key.Binding{
+				bind("c", "SSH command"),
+				bind("h", "Hostname/IP"),
+				bind("s", "scp command template"),
+				bind("f", "sftp command"),
+				bind("p", "Public key of the identity file"),
+				bind("Enter", "Copy the selected entry"),
+				bind("ESC, q", "Cancel"),
+			}},
+		}
+
+	case ModeJumpHostSelect:
+		return []keySection{
+			{"JUMP HOST", []key.Binding{
+				navigation,
+				bind("Enter", "Connect through the selected host, or directly"),
+				bind("ESC", "Back to the form"),
+			}},
+		}
+
+	case ModeKeySelect:
+		return []keySection{
+			{"SSH KEY", []key.Binding{
+				navigation,
+				bind("Enter", "Use the selected key"),
+				bind("g", "Generate a new key"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeKeyGen:
+		return []keySection{
+			{"KEY GENERATION", []key.Binding{
+				bind("←/→, Space", "Change key type"),
+				bind("Tab, ↓", "Next field"),
+				bind("Shift+Tab, ↑", "Previous field"),
+				bind("Enter", "Generate the key"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModePasswordInput, ModeKeyPasswordInput:
+		return []keySection{
+			{"PASSWORD", []key.Binding{
+				bind("Enter", "Continue"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeConnectTest, ModeKeySetup:
+		return []keySection{
+			{"CONNECTION TEST", []key.Binding{
+				bind("Enter", "Save the host once the test passed"),
+				bind("k", "Review known_hosts after a host key mismatch"),
+				bind("e", "Show the details of a failed test"),
+				bind("ESC", "Cancel the test, or save when it passed"),
+			}},
+		}
+
+	case ModeForwardingSelect:
+		return []keySection{
+			{"PORT FORWARDING", []key.Binding{
+				bind("1", "Local forwarding (-L)"),
+				bind("2", "Remote forwarding (-R)"),
+				bind("3", "Dynamic SOCKS proxy (-D)"),
+				bind("4", "SOCKS proxy on the remote host (-R port)"),
+				bind("l", "List active forwardings"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeForwardingAdd:
+		return []keySection{
+			{"FORWARDING RULE", []key.Binding{
+				bind("Tab, ↓", "Next field"),
+				bind("Shift+Tab, ↑", "Previous field"),
+				bind("Enter", "Select remote host, or start the forwarding"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeForwardingList:
+		return []keySection{
+			{"ACTIVE FORWARDINGS", []key.Binding{
+				navigation,
+				bind("e", "Edit the selected rule"),
+				bind("s", "Stop the selected forwarding"),
+				bind("S", "Stop all forwardings (press twice)"),
+				bind("c", "Copy the equivalent ssh -L/-R/-D command"),
+				bind("r", "Renew the expiry of the selected forwarding"),
+				bind("d", "Start or stop dumping the traffic of the selected forwarding to a file"),
+				bind("E", "Show the error log of the selected forwarding"),
+				bind("/", "Filter by label=, host=, type=, port= or any word"),
+				bind("a", "Add a forwarding"),
+				bind("ESC, q", "Back"),
+			}},
+		}
+
+	case ModeRemoteHostSelect:
+		return []keySection{
+			{"REMOTE HOST", []key.Binding{
+				navigation,
+				bind("Enter", "Use the selected host"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeFileBrowser:
+		return []keySection{
+			{"NAVIGATION", []key.Binding{
+				bind("Tab", "Switch between local and remote pane"),
+				navigation,
+				bind("g/Home, G/End", "First/last entry"),
+				bind("Enter, →, l", "Open directory"),
+				bind("Backspace, ←, h", "Parent directory"),
+			}},
+			{"FILES", []key.Binding{
+				bind("c, F5", "Copy to the other pane"),
+				bind("r", "Rename"),
+				bind("d, Delete", "Delete"),
+				bind("R, Ctrl+R", "Refresh"),
+				bind("ESC", "Cancel a transfer, or close"),
+				bind("q", "Close"),
+			}},
+		}
+
+	case ModeCommandRunner:
+		return []keySection{
+			{"COMMAND", []key.Binding{
+				bind("Enter", "Run on all target hosts"),
+				bind("↑/↓", "Browse command history"),
+				bind("PgUp/PgDn", "Scroll output"),
+				bind("Ctrl+Home/End", "Jump to start/end of output"),
+				bind("Ctrl+L", "Clear output"),
+				bind("Ctrl+B", "Back, leaving the command running (see S)"),
+				bind("ESC", "Stop the command, or back"),
+			}},
+		}
+
+	case ModeKnownHosts:
+		return []keySection{
+			{"KNOWN HOSTS", []key.Binding{
+				navigation,
+				bind("/, :", "Search"),
+				bind("a", "Toggle between this host and all entries"),
+				bind("d, Delete", "Delete the selected entry"),
+				bind("R, Ctrl+R", "Reload the file"),
+				bind("ESC, q", "Back"),
+			}},
+		}
+
+	case ModeAgentKeys:
+		return []keySection{
+			{"SSH AGENT", []key.Binding{
+				navigation,
+				bind("a", "Add an identity to the agent"),
+				bind("d, Delete", "Remove the selected key"),
+				bind("R, Ctrl+R", "Refresh"),
+				bind("ESC, q", "Back"),
+			}},
+		}
+
+	case ModeContainers:
+		return []keySection{
+			{"CONTAINERS", []key.Binding{
+				navigation,
+				bind("Enter", "Connect and open sh in the container"),
+				bind("b", "Connect and open bash in the container"),
+				bind("R, Ctrl+R", "List the containers again"),
+				bind("ESC, q", "Back"),
+			}},
+		}
+
+	case ModePeers:
+		return []keySection{
+			{"PEERS", []key.Binding{
+				navigation,
+				bind("Enter, a", "Add the selected peer as a host"),
+				bind("A", "Add every peer not configured yet"),
+				bind("R, Ctrl+R", "Discover again"),
+				bind("ESC, q", "Back"),
+			}},
+		}
+
+	case ModeQuickConnect:
+		return []keySection{
+			{"QUICK CONNECT", []key.Binding{
+				bind("Enter", "Connect"),
+				bind("Ctrl+S", "Save as a new host without connecting"),
+				bind("s", "Save as a new host after the session"),
+				bind("r", "Reconnect after the session"),
+				bind("e", "Edit the target after the session"),
+				bind("ESC", "Back"),
+			}},
+		}
+
+	case ModeAliasConflict:
+		return []keySection{
+			{"ALIAS CONFLICT", []key.Binding{
+				bind("o", "Overwrite the existing host"),
+				bind("m", "Merge the new fields into the existing host"),
+				bind("r", "Add under a different alias"),
+				bind("s, ESC", "Skip this host"),
+			}},
+		}
+
+	case ModeTagEditor:
+		return []keySection{
+			{"TAGS", []key.Binding{
+				bind("Enter", "Add the typed tag, or close when empty"),
+				bind("Tab", "Complete to an existing tag"),
+				bind("←/→", "Select a tag"),
+				bind("Backspace", "Select the last tag, then remove it"),
+				bind("ESC", "Close"),
+			}},
+		}
+
+	case ModeColorLabel:
+		return []keySection{
+			{"COLOR LABEL", []key.Binding{
+				navigation,
+				bind("Enter", "Use the selected color"),
+				bind("0-7", "Pick a color directly, 0 for none"),
+				bind("ESC, q", "Cancel"),
+			}},
+		}
+
+	case ModeColumns:
+		return []keySection{
+			{"COLUMNS", []key.Binding{
+				navigation,
+				bind("Space, Enter", "Show or hide the selected column"),
+				bind("K/J", "Move the column left/right"),
+				bind("ESC, q", "Back to the host list"),
+			}},
+		}
+
+	case ModeErrorDetail:
+		return []keySection{
+			{"ERROR DETAILS", []key.Binding{
+				bind("k", "Open known_hosts after a host key mismatch"),
+				bind("Enter, ESC", "Close"),
+			}},
+		}
+
+	case ModeNotifications:
+		return []keySection{
+			{"NOTIFICATIONS", []key.Binding{
+				bind("↑/k, ↓/j", "Scroll"),
+				bind("c", "Clear the history"),
+				bind("ESC, q", "Back to the host list"),
+			}},
+		}
+	}
+
+	return []keySection{
+		{"NAVIGATION", []key.Binding{
+			navigation,
+			m.listBind("connect", "Enter", "Connect to selected host"),
+			bind("1-5", "Connect to a recent host"),
+			m.listBind("quick_connect", "o", "Quick connect to user@host:port"),
+			m.listBind("clear", "ESC", "Clear filter and marks"),
+		}},
+		{"HOST MANAGEMENT", []key.Binding{
+			m.listBind("add", "a", "Add new host"),
+			m.listBind("edit", "e", "Edit selected host"),
+			m.listBind("rename", "r", "Rename the selected host in place"),
+			m.listBind("duplicate", "D", "Duplicate selected host"),
+			m.listBind("delete", "d", "Delete selected host"),
+			m.listBind("undo", "u", "Undo the last delete (for 10s)"),
+			m.listBind("copy", "c", "Copy the SSH command, address, scp/sftp command or public key"),
+			m.listBind("tags", "t", "Edit tags of marked/selected hosts"),
+			m.listBind("toggle_tags", "T", "Show or hide the tags column"),
+			m.listBind("columns", "C", "Choose the columns of the host list"),
+			m.listBind("label", "L", "Color label of marked/selected hosts"),
+			m.listBind("known_hosts", "K", "Manage known_hosts entries"),
+			m.listBind("agent", "A", "Manage ssh-agent keys"),
+			m.listBind("peers", "P", "Add Tailscale/ZeroTier peers as hosts"),
+			bind("p", "Add the selected discovered host to the config"),
+			m.listBind("onboarding", "O", "Review incomplete, pattern and hidden hosts"),
+		}},
+		{"ADVANCED FEATURES", []key.Binding{
+			m.listBind("forward", "f", "Port forwarding menu"),
+			m.listBind("files", "b", "Browse files over SFTP"),
+			m.listBind("containers", "i", "Open a shell in a Docker container of the host"),
+			m.listBind("mark", "Space", "Mark host for running commands"),
+			m.listBind("run", "x", "Run a command on marked/selected hosts"),
+			m.listBind("tmux_windows", "w", "Open marked/selected hosts in tmux windows"),
+			m.listBind("tmux_tiled", "W", "Open marked/selected hosts tiled in a tmux window"),
+			m.listBind("sessions", "S", "Sessions, command runs and daemon terminals"),
+			m.listBind("search", "/", "Search/filter hosts"),
+			m.listBind("filters", "F", "Saved filters"),
+			m.listBind("command_line", ":", "Command line (connect, forward, add, quit) or filter"),
+		}},
+		{"COMMAND LINE", []key.Binding{
+			bind(":connect [alias]", "Connect to a host, the selected one by default"),
+			bind(":forward [-L|-R|-D] spec [alias]", "Start a port forwarding, e.g. 8080:db:5432 bastion"),
+			bind(":add [user@host[:port]]", "Add a host, optionally prefilled"),
+			bind(":q", "Quit"),
+			bind("Tab", "Complete the command or host alias"),
+		}},
+		{"GENERAL", []key.Binding{
+			m.listBind("notifications", "N", "Notification history"),
+			m.listBind("quit", "q, Ctrl+C", "Quit application"),
+		}},
+	}
+}
+
+// acceptsTyping reports whether printable keys are typed into a text input
+// in the current mode, in which case "?" does not open the help
+func (m Model) acceptsTyping() bool {
+	if m.isEditingText() {
+		return true
+	}
+	switch m.viewMode {
+	case ModeList:
+		return m.searchMode || m.rename != nil || m.cmdLine != nil
+	case ModeFileBrowser:
+		return m.browser != nil && m.browser.prompt == promptRename
+	case ModeForwardingList:
+		return m.forwardingSearching
+	case ModeCommandRunner:
+		return true
+	case ModeKnownHosts:
+		return m.knownHosts != nil && m.knownHosts.searching
+	case ModeAgentKeys:
+		return m.agent != nil && m.agent.pendingIdentity != ""
+	case ModeQuickConnect:
+		return m.quick != nil && m.quick.finished == nil
+	case ModeAliasConflict:
+		return m.conflict != nil && m.conflict.renaming
+	case ModeTagEditor:
+		return true
+	case ModeOnboarding:
+		return m.onboarding != nil && m.onboarding.field < onboardingManaged
+	case ModeSavedFilters:
+		return m.filterMenu != nil && m.filterMenu.naming
+	}
+	return false
+}
+
+// renderDetailedHelp renders the shortcuts of the active mode for the help
+// overlay, filtered by the typed query and scrolled to fit the screen
+func (m Model) renderDetailedHelp() string {
+	var content strings.Builder
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width).
+		Align(lipgloss.Center)
+
+	content.WriteString(headerStyle.Render("KEYBOARD SHORTCUTS") + "\n\n")
+
+	// Filter
+	filterStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Accent)
+
+	if m.help.query == "" {
+		content.WriteString(filterStyle.Render("Type to search all shortcuts") + "\n")
+	} else {
+		content.WriteString(filterStyle.Render("Search: "+m.help.query+"█") + "\n")
+	}
+	content.WriteString("\n")
+
+	// Create sections
+	sectionStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(m.theme.Primary)
+
+	itemStyle := lipgloss.NewStyle().
+		MarginLeft(2)
+
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	lines := m.helpLines()
+	if len(lines) == 0 {
+		content.WriteString("\n" + subtleStyle.Render("No shortcuts match") + "\n")
+	}
+
+	pageSize := m.helpPageSize()
+	start := min(m.help.scroll, max(0, len(lines)-pageSize))
+	end := min(len(lines), start+pageSize)
+	if start > 0 {
+		content.WriteString(subtleStyle.Render(fmt.Sprintf("  ↑ %d more", start)) + "\n")
+	}
+	for _, line := range lines[start:end] {
+		if line.section == "" {
+			content.WriteString("\n")
+			continue
+		}
+		if line.keys == "" {
+			content.WriteString(sectionStyle.Render(line.section) + "\n")
+			continue
+		}
+		padding := strings.Repeat(" ", max(1, 17-lipgloss.Width(line.keys)))
+		content.WriteString(itemStyle.Render(line.keys+padding+line.desc) + "\n")
+	}
+	if end < len(lines) {
+		content.WriteString(subtleStyle.Render(fmt.Sprintf("  ↓ %d more", len(lines)-end)) + "\n")
+	}
+
+	// Footer
+	footerStyle := m.theme.HelpStyle(m.width).
+		Align(lipgloss.Center).
+		MarginTop(1)
+
+	content.WriteString(footerStyle.Render("↑/↓ PgUp/PgDn: scroll • ESC: clear search/close • ?/F1: close"))
+
+	return content.String()
+}
+
+// renderHelpOverlay renders the help overlay centered on the screen
+func (m Model) renderHelpOverlay() string {
+	overlayStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Background(m.theme.OverlayBackground).
+		Padding(2).
+		Width(m.width - 8).
+		MaxHeight(m.height - 4)
+
+	overlay := overlayStyle.Render(m.renderDetailedHelp())
+
+	// Position overlay in center of screen
+	startY := max(0, (m.height-lipgloss.Height(overlay))/2)
+	return strings.Repeat("\n", startY) + overlay
+}
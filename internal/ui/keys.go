@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// listActions maps the names of host list actions, as used in the [keys]
+// section of the xssh config, to the key that triggers them by default
+var listActions = map[string]string{
+	"quit":          "q",
+	"up":            "k",
+	"down":          "j",
+	"search":        "/",
+	"command_line":  ":",
+	"add":           "a",
+	"edit":          "e",
+	"rename":        "r",
+	"duplicate":     "D",
+	"delete":        "d",
+	"forward":       "f",
+	"mark":          " ",
+	"run":           "x",
+	"tags":          "t",
+	"toggle_tags":   "T",
+	"columns":       "C",
+	"label":         "L",
+	"notifications": "N",
+	"known_hosts":   "K",
+	"agent":         "A",
+	"peers":         "P",
+	"filters":       "F",
+	"sessions":      "S",
+	"onboarding":    "O",
+	"undo":          "u",
+	"quick_connect": "o",
+	"files":         "b",
+	"containers":    "i",
+	"tmux_windows":  "w",
+	"tmux_tiled":    "W",
+	"connect":       "enter",
+	"copy":          "c",
+	"clear":         "esc",
+	"help":          "h",
+}
+
+// keyNames spells keys in the config as bubbletea names them
+var keyNames = map[string]string{
+	"space": " ",
+	"Space": " ",
+	"ESC":   "esc",
+	"Enter": "enter",
+}
+
+// newListKeys turns the [keys] section of the xssh config into a map from
+// the configured keys to the default keys of their actions. The default
+// keys keep working unless another action is bound to them.
+func newListKeys(bindings map[string]string) (map[string]string, error) {
+	keys := map[string]string{}
+	var unknown []string
+	for action, pressed := range bindings {
+		defaultKey, ok := listActions[action]
+		if !ok {
+			unknown = append(unknown, action)
+			continue
+		}
+		if name, ok := keyNames[pressed]; ok {
+			pressed = name
+		}
+		if pressed != "" {
+			keys[pressed] = defaultKey
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return keys, fmt.Errorf("unknown actions in [keys]: %s", strings.Join(unknown, ", "))
+	}
+	return keys, nil
+}
+
+// listKey returns the default key of the action bound to the pressed key, or
+// the pressed key itself when it is not bound to an action
+func (m Model) listKey(pressed string) string {
+	if defaultKey, ok := m.listKeys[pressed]; ok {
+		return defaultKey
+	}
+	return pressed
+}
+
+// listBind describes a host list shortcut for the help overlay, adding the
+// key the config binds to the action
+func (m Model) listBind(action, keys, description string) key.Binding {
+	var bound []string
+	for pressed, defaultKey := range m.listKeys {
+		if defaultKey == listActions[action] {
+			if pressed == " " {
+				pressed = "Space"
+			}
+			bound = append(bound, pressed)
+		}
+	}
+	if len(bound) > 0 {
+		sort.Strings(bound)
+		keys = strings.Join(bound, ", ") + ", " + keys
+	}
+	return bind(keys, description)
+}
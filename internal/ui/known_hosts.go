@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// knownHostsScreen holds the state of the known_hosts manager
+type knownHostsScreen struct {
+	path          string
+	entries       []config.KnownHostEntry
+	visible       []config.KnownHostEntry // Entries passing the host and search filters
+	cursor        int
+	search        textinput.Model
+	searching     bool
+	host          config.SSHHost // Host whose entries are shown, empty for all
+	showAll       bool           // Ignore the host filter
+	confirmDelete bool
+	returnMode    ViewMode
+}
+
+// openKnownHosts switches to the known_hosts manager. When host is not empty
+// only its entries are listed at first.
+func (m Model) openKnownHosts(host config.SSHHost) (tea.Model, tea.Cmd) {
+	search := textinput.New()
+	search.Prompt = "Search: "
+	search.Placeholder = "host, key type or fingerprint"
+
+	screen := &knownHostsScreen{
+		search:     search,
+		host:       host,
+		showAll:    host.Host == "",
+		returnMode: m.viewMode,
+	}
+
+	path, err := config.KnownHostsPath()
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to locate known_hosts: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	screen.path = path
+
+	m.knownHosts = screen
+	m.viewMode = ModeKnownHosts
+	m.reloadKnownHosts()
+	return m, nil
+}
+
+// reloadKnownHosts re-reads the known_hosts file and reapplies the filters
+func (m *Model) reloadKnownHosts() {
+	screen := m.knownHosts
+	entries, err := config.LoadKnownHosts(screen.path)
+	if err != nil {
+		m.message = fmt.Sprintf("Failed to read %s: %v", screen.path, err)
+		m.messageType = "error"
+	}
+	screen.entries = entries
+	screen.applyFilter()
+}
+
+// applyFilter rebuilds the visible entries from the host and search filters
+func (s *knownHostsScreen) applyFilter() {
+	query := strings.ToLower(strings.TrimSpace(s.search.Value()))
+
+	s.visible = nil
+	for _, entry := range s.entries {
+		if !s.showAll && !entry.MatchesHost(s.host.Host, s.host.Port) {
+			continue
+		}
+		if query != "" && !knownHostMatchesQuery(entry, query) {
+			continue
+		}
+		s.visible = append(s.visible, entry)
+	}
+
+	if s.cursor >= len(s.visible) {
+		s.cursor = max(0, len(s.visible)-1)
+	}
+}
+
+// knownHostMatchesQuery matches a lower-case query against the readable
+// parts of an entry, and against hashed hosts by exact host name
+func knownHostMatchesQuery(entry config.KnownHostEntry, query string) bool {
+	searchable := strings.ToLower(strings.Join([]string{
+		strings.Join(entry.Hosts, ","), entry.KeyType, entry.Fingerprint, entry.Comment, entry.Marker,
+	}, " "))
+	if strings.Contains(searchable, query) {
+		return true
+	}
+	return entry.Hashed && entry.MatchesHost(query, "")
+}
+
+// handleKnownHostsMode handles keys in the known_hosts manager
+func (m Model) handleKnownHostsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	screen := m.knownHosts
+	if screen == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	if screen.confirmDelete {
+		switch msg.String() {
+		case "y", "Y":
+			screen.confirmDelete = false
+			m.deleteKnownHost()
+		case "n", "N", "esc":
+			screen.confirmDelete = false
+		}
+		return m, nil
+	}
+
+	if screen.searching {
+		switch msg.String() {
+		case "esc":
+			screen.searching = false
+			screen.search.Blur()
+			screen.search.SetValue("")
+			screen.applyFilter()
+		case "enter":
+			screen.searching = false
+			screen.search.Blur()
+		default:
+			var cmd tea.Cmd
+			screen.search, cmd = screen.search.Update(msg)
+			screen.applyFilter()
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	m.message = ""
+	m.messageType = ""
+
+	switch msg.String() {
+	case "esc", "q":
+		m.viewMode = screen.returnMode
+		m.knownHosts = nil
+
+	case "up", "k":
+		if screen.cursor > 0 {
+			screen.cursor--
+		}
+
+	case "down", "j":
+		if screen.cursor < len(screen.visible)-1 {
+			screen.cursor++
+		}
+
+	case "/", ":":
+		screen.searching = true
+		return m, screen.search.Focus()
+
+	case "a":
+		// Toggle between the host's entries and the whole file
+		if screen.host.Host != "" {
+			screen.showAll = !screen.showAll
+			screen.applyFilter()
+		}
+
+	case "d", "delete":
+		if len(screen.visible) > 0 {
+			screen.confirmDelete = true
+		}
+
+	case "R", "ctrl+r":
+		m.reloadKnownHosts()
+	}
+
+	return m, nil
+}
+
+// deleteKnownHost removes the entry under the cursor from known_hosts
+func (m *Model) deleteKnownHost() {
+	screen := m.knownHosts
+	if screen.cursor >= len(screen.visible) {
+		return
+	}
+	entry := screen.visible[screen.cursor]
+
+	if err := config.RemoveKnownHostLines(screen.path, []int{entry.Line}); err != nil {
+		m.message = fmt.Sprintf("Failed to remove entry: %v", err)
+		m.messageType = "error"
+		return
+	}
+
+	m.reloadKnownHosts()
+	m.message = fmt.Sprintf("Removed %s key (line %d); previous file saved as known_hosts.old", entry.KeyType, entry.Line)
+	m.messageType = "success"
+}
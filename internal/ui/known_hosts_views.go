@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderKnownHostsView renders the known_hosts manager
+func (m Model) renderKnownHostsView() string {
+	var content strings.Builder
+	screen := m.knownHosts
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Known Hosts")
+	content.WriteString(header + "\n\n")
+
+	// Scope and search
+	scopeStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+
+	scope := fmt.Sprintf("All entries in %s", screen.path)
+	if !screen.showAll {
+		scope = fmt.Sprintf("Entries for %s (port %s) • a: show all", screen.host.Host, defaultPort(screen.host.Port))
+	} else if screen.host.Host != "" {
+		scope += fmt.Sprintf(" • a: only %s", screen.host.Host)
+	}
+	content.WriteString(scopeStyle.Render(scope) + "\n")
+
+	if screen.searching || screen.search.Value() != "" {
+		content.WriteString(screen.search.View() + "\n")
+	}
+	content.WriteString("\n")
+
+	// Entry list
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	rows := max(3, m.height-12)
+	innerWidth := m.width - 8
+
+	var list strings.Builder
+	if len(screen.visible) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle).
+			Italic(true)
+		if len(screen.entries) == 0 {
+			list.WriteString(emptyStyle.Render("known_hosts is empty or missing"))
+		} else {
+			list.WriteString(emptyStyle.Render("No matching entries"))
+		}
+	} else {
+		start := 0
+		if screen.cursor >= rows {
+			start = screen.cursor - rows + 1
+		}
+		end := min(len(screen.visible), start+rows)
+
+		selectedStyle := m.theme.SelectedStyle()
+		for i := start; i < end; i++ {
+			entry := screen.visible[i]
+
+			cursor := "  "
+			if i == screen.cursor {
+				cursor = "▶ "
+			}
+
+			hosts := strings.Join(entry.Hosts, ",")
+			if entry.Hashed {
+				hosts = "(hashed)"
+			}
+			if entry.Marker != "" {
+				hosts = entry.Marker + " " + hosts
+			}
+
+			// Line number, hosts, key type and fingerprint
+			keyInfo := fmt.Sprintf("%-20s %s", entry.KeyType, entry.Fingerprint)
+			hostsWidth := max(10, innerWidth-len(cursor)-7-lipgloss.Width(keyInfo)-2)
+			line := fmt.Sprintf("%s%5d  %s  %s", cursor, entry.Line, padAndTruncate(hosts, hostsWidth), keyInfo)
+
+			if i == screen.cursor {
+				list.WriteString(selectedStyle.Render(line) + "\n")
+			} else {
+				list.WriteString(line + "\n")
+			}
+		}
+	}
+	content.WriteString(panelStyle.Render(strings.TrimRight(list.String(), "\n")) + "\n")
+
+	// Confirmation or message
+	if screen.confirmDelete && screen.cursor < len(screen.visible) {
+		entry := screen.visible[screen.cursor]
+		question := fmt.Sprintf("Remove the %s key on line %d? (y/n)", entry.KeyType, entry.Line)
+		content.WriteString(m.theme.MessageStyle("error", m.width).Render(question) + "\n")
+	} else {
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • /: search • d: delete entry • R: reload • ESC/q: back"
+	if screen.searching {
+		help = "Type to search • Enter: keep filter • ESC: clear search"
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// defaultPort returns port, or "22" when it is empty
+func defaultPort(port string) string {
+	if port == "" {
+		return "22"
+	}
+	return port
+}
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// maxVisibleToasts is how many notifications are stacked on screen
+	maxVisibleToasts = 3
+
+	// maxNotificationHistory is how many notifications the history keeps
+	maxNotificationHistory = 100
+)
+
+// toastLifetime returns how long a notification of a level stays on screen.
+// Errors stay longer so they can be read.
+func toastLifetime(level string) time.Duration {
+	if level == "error" {
+		return 10 * time.Second
+	}
+	return 4 * time.Second
+}
+
+// notification is one status message with its level ("success", "error" or
+// "info")
+type notification struct {
+	id    int
+	level string
+	text  string
+	time  time.Time
+}
+
+// notificationCenter holds the notifications on screen and the history of
+// all of them
+type notificationCenter struct {
+	active  []notification // On screen, oldest first
+	history []notification // Oldest first
+	nextID  int
+	scroll  int // First history entry shown in the history view
+}
+
+// toastExpiredMsg removes a notification from the screen
+type toastExpiredMsg struct {
+	id int
+}
+
+// push adds a notification and returns the command that expires it. Posting
+// the text of the newest notification again only restarts its timer.
+func (c *notificationCenter) push(level, text string) tea.Cmd {
+	c.nextID++
+	id := c.nextID
+
+	if n := len(c.active); n > 0 && c.active[n-1].level == level && c.active[n-1].text == text {
+		c.active[n-1].id = id
+	} else {
+		note := notification{id: id, level: level, text: text, time: time.Now()}
+		c.active = append(c.active, note)
+		c.history = append(c.history, note)
+		if len(c.history) > maxNotificationHistory {
+			c.history = c.history[len(c.history)-maxNotificationHistory:]
+		}
+	}
+
+	return tea.Tick(toastLifetime(level), func(time.Time) tea.Msg {
+		return toastExpiredMsg{id: id}
+	})
+}
+
+// expire removes the notification with the given id from the screen
+func (c *notificationCenter) expire(id int) {
+	c.active = slices.DeleteFunc(c.active, func(n notification) bool { return n.id == id })
+}
+
+// dismiss removes the notifications matching a condition from the screen
+func (c *notificationCenter) dismiss(match func(notification) bool) {
+	c.active = slices.DeleteFunc(c.active, match)
+}
+
+// postMessage moves the status message a handler set into the notification
+// queue, so it is shown until it expires instead of until the next key
+func (m Model) postMessage(cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	if m.message == "" {
+		return m, cmd
+	}
+
+	level := m.messageType
+	if level == "" {
+		level = "info"
+	}
+	if level == "error" {
+		slog.Error(m.message)
+	} else {
+		slog.Debug(m.message, "level", level)
+	}
+	expire := m.notifications.push(level, m.message)
+	m.message = ""
+	m.messageType = ""
+	return m, tea.Batch(cmd, expire)
+}
+
+// openNotificationHistory shows all notifications, newest first
+func (m Model) openNotificationHistory() (tea.Model, tea.Cmd) {
+	m.notifications.scroll = 0
+	m.viewMode = ModeNotifications
+	return m, nil
+}
+
+// handleNotificationsMode handles keys in the notification history
+func (m Model) handleNotificationsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	center := m.notifications
+
+	switch msg.String() {
+	case "esc", "q", "N":
+		m.viewMode = ModeList
+
+	case "up", "k":
+		if center.scroll > 0 {
+			center.scroll--
+		}
+
+	case "down", "j":
+		if center.scroll < len(center.history)-1 {
+			center.scroll++
+		}
+
+	case "c":
+		// Clear the history and whatever is on screen
+		center.history = nil
+		center.active = nil
+		center.scroll = 0
+	}
+
+	return m, nil
+}
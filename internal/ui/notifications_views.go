@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// notificationIcons marks the level of a notification in the history
+var notificationIcons = map[string]string{
+	"success": "✓",
+	"error":   "✗",
+	"info":    "•",
+}
+
+// renderToasts renders the notifications on screen, newest last, one line
+// each. Only the newest few are stacked; the rest are counted.
+func (m Model) renderToasts() string {
+	if m.notifications == nil || len(m.notifications.active) == 0 {
+		return ""
+	}
+	active := m.notifications.active
+
+	var content strings.Builder
+	if hidden := len(active) - maxVisibleToasts; hidden > 0 {
+		more := fmt.Sprintf("+%d more notification(s) • N: history", hidden)
+		content.WriteString(m.theme.HelpStyle(m.width).Align(lipgloss.Center).Render(more) + "\n")
+		active = active[hidden:]
+	}
+	for _, note := range active {
+		content.WriteString(m.theme.MessageStyle(note.level, m.width).Render(note.text) + "\n")
+	}
+	return content.String()
+}
+
+// renderNotificationsView renders the history of notifications, newest first
+func (m Model) renderNotificationsView() string {
+	var content strings.Builder
+	center := m.notifications
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render(fmt.Sprintf("Notifications (%d)", len(center.history)))
+	content.WriteString(header + "\n\n")
+
+	if len(center.history) == 0 {
+		content.WriteString(m.theme.HelpStyle(m.width).Render("No notifications yet") + "\n\n")
+	}
+
+	timeStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	// Newest first, starting at the scroll position
+	rows := max(3, m.height-6)
+	shown := 0
+	for i := len(center.history) - 1 - center.scroll; i >= 0 && shown < rows; i-- {
+		note := center.history[i]
+		levelStyle := m.theme.MessageStyle(note.level, 0).Width(0).Align(lipgloss.Left)
+		line := timeStyle.Render(note.time.Format("15:04:05")) + "  " +
+			levelStyle.Render(notificationIcons[note.level]+" "+padAndTruncate(note.text, max(10, m.width-14)))
+		content.WriteString(line + "\n")
+		shown++
+	}
+	content.WriteString("\n")
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: scroll • c: clear • ESC: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,240 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// onboardingField is a field of the onboarding step for one host
+type onboardingField int
+
+const (
+	onboardingUser onboardingField = iota
+	onboardingIdentity
+	onboardingTags
+	onboardingManaged
+)
+
+// onboarding walks through the hosts of ~/.ssh/config that need attention
+type onboarding struct {
+	hosts    []config.SSHHost // Hosts to review, in config order
+	index    int              // Host being reviewed
+	field    onboardingField
+	inputs   [onboardingManaged]textinput.Model // User, identity file and tags
+	managed  bool                               // Whether the host shows in the list
+	err      error                              // Why the current host could not be applied
+	backedUp bool                               // Whether the config was backed up before the first change
+	updated  int                                // Number of hosts changed
+}
+
+// onboardingIssues returns what is missing or unusual about a host
+func onboardingIssues(host config.SSHHost) []string {
+	var issues []string
+	if config.IsPattern(host.Name) {
+		issues = append(issues, "pattern matching several hosts")
+	}
+	if host.User == "" {
+		issues = append(issues, "no user")
+	}
+	if host.Identity == "" {
+		issues = append(issues, "no identity file")
+	}
+	return issues
+}
+
+// onboardingCandidates returns the hosts worth reviewing: those with issues
+// and those hidden from the list
+func onboardingCandidates(hosts []config.SSHHost, metadata *config.Metadata) []config.SSHHost {
+	var candidates []config.SSHHost
+	for _, host := range hosts {
+		if len(onboardingIssues(host)) > 0 || !metadata.Managed(host.Name) {
+			candidates = append(candidates, host)
+		}
+	}
+	return candidates
+}
+
+// startOnboarding opens the onboarding wizard over hosts, doing nothing when
+// there are none
+func (m *Model) startOnboarding(hosts []config.SSHHost) tea.Cmd {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	o := &onboarding{hosts: hosts}
+	for i := range o.inputs {
+		input := textinput.New()
+		input.Prompt = ""
+		input.Width = max(20, m.width-24)
+		o.inputs[i] = input
+	}
+	o.inputs[onboardingUser].Placeholder = "root"
+	o.inputs[onboardingUser].Validate = validateNoSpaces
+	o.inputs[onboardingIdentity].Placeholder = "~/.ssh/id_ed25519"
+	o.inputs[onboardingTags].Placeholder = "prod, web"
+
+	m.onboarding = o
+	m.viewMode = ModeOnboarding
+	return m.loadOnboardingHost()
+}
+
+// openOnboarding reopens the wizard from the host list
+func (m Model) openOnboarding() (tea.Model, tea.Cmd) {
+	cmd := m.startOnboarding(onboardingCandidates(m.hosts, m.metadata))
+	if m.onboarding == nil {
+		m.message = "No hosts need attention"
+		m.messageType = "info"
+	}
+	return m, cmd
+}
+
+// loadOnboardingHost fills the wizard with the host being reviewed. Pattern
+// hosts that were never reviewed default to not being managed by xssh.
+func (m *Model) loadOnboardingHost() tea.Cmd {
+	o := m.onboarding
+	host := o.hosts[o.index]
+
+	o.inputs[onboardingUser].SetValue(host.User)
+	o.inputs[onboardingIdentity].SetValue(host.Identity)
+	o.inputs[onboardingTags].SetValue("")
+	_, known := m.metadata.Hosts[host.Name]
+	o.managed = m.metadata.Managed(host.Name) && (known || !config.IsPattern(host.Name))
+	o.err = nil
+	return m.focusOnboardingField(onboardingUser)
+}
+
+// focusOnboardingField moves the focus to a field of the wizard
+func (m *Model) focusOnboardingField(field onboardingField) tea.Cmd {
+	o := m.onboarding
+	o.field = field
+	for i := range o.inputs {
+		o.inputs[i].Blur()
+	}
+	if field < onboardingManaged {
+		return o.inputs[field].Focus()
+	}
+	return nil
+}
+
+// applyOnboardingHost writes the changes made to the host being reviewed
+func (m *Model) applyOnboardingHost() error {
+	o := m.onboarding
+	host := o.hosts[o.index]
+
+	user := strings.TrimSpace(o.inputs[onboardingUser].Value())
+	if err := validateNoSpaces(user); err != nil {
+		return fmt.Errorf("user: %w", err)
+	}
+	identity := strings.TrimSpace(o.inputs[onboardingIdentity].Value())
+	if identity != "" && identity != host.Identity {
+		if err := config.ValidateIdentityFile(identity); err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	if user != host.User || identity != host.Identity {
+		if !o.backedUp {
+			if err := m.sshConfig.Backup(); err != nil {
+				return fmt.Errorf("failed to back up config: %w", err)
+			}
+			o.backedUp = true
+		}
+		updated := host
+		updated.User = user
+		updated.Identity = identity
+		m.sshConfig.UpdateHost(host.Name, updated)
+		if err := m.sshConfig.Save(); err != nil {
+			m.sshConfig.UpdateHost(host.Name, host)
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		m.hosts = m.sshConfig.Hosts
+		changed = true
+	}
+
+	metadataChanged := false
+	tags := strings.FieldsFunc(o.inputs[onboardingTags].Value(), func(r rune) bool { return r == ',' })
+	for _, tag := range tags {
+		if tag = config.NormalizeTag(tag); tag != "" && !m.metadata.HasTag(host.Name, tag) {
+			m.metadata.AddTag(host.Name, tag)
+			metadataChanged = true
+		}
+	}
+	if o.managed != m.metadata.Managed(host.Name) {
+		m.metadata.SetManaged(host.Name, o.managed)
+		metadataChanged = true
+	}
+	if metadataChanged {
+		if err := m.metadata.Save(); err != nil {
+			return fmt.Errorf("failed to save host metadata: %w", err)
+		}
+	}
+
+	if changed || metadataChanged {
+		o.updated++
+	}
+	return nil
+}
+
+// finishOnboarding closes the wizard and records that it has been completed
+func (m Model) finishOnboarding() (tea.Model, tea.Cmd) {
+	updated := m.onboarding.updated
+	m.onboarding = nil
+	m.viewMode = ModeList
+	m.filterHosts()
+
+	if err := config.SaveOnboardingDone(); err != nil {
+		m.message = fmt.Sprintf("Failed to save xssh config: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Onboarding finished, %d host(s) updated • O: review again", updated)
+	m.messageType = "success"
+	return m, nil
+}
+
+// handleOnboardingMode handles keys in the onboarding wizard
+func (m Model) handleOnboardingMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	o := m.onboarding
+
+	switch msg.String() {
+	case "esc":
+		// Leave the remaining hosts as they are
+		return m.finishOnboarding()
+
+	case "tab", "down":
+		return m, m.focusOnboardingField((o.field + 1) % (onboardingManaged + 1))
+
+	case "shift+tab", "up":
+		return m, m.focusOnboardingField((o.field + onboardingManaged) % (onboardingManaged + 1))
+
+	case "enter":
+		if err := m.applyOnboardingHost(); err != nil {
+			o.err = err
+			return m, nil
+		}
+		if o.index == len(o.hosts)-1 {
+			return m.finishOnboarding()
+		}
+		o.index++
+		return m, m.loadOnboardingHost()
+
+	case " ":
+		if o.field == onboardingManaged {
+			o.managed = !o.managed
+			return m, nil
+		}
+	}
+
+	if o.field == onboardingManaged {
+		return m, nil
+	}
+	var cmd tea.Cmd
+	o.inputs[o.field], cmd = o.inputs[o.field].Update(msg)
+	o.err = nil
+	return m, cmd
+}
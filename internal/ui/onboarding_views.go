@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// renderOnboardingView renders the onboarding step for one host
+func (m Model) renderOnboardingView() string {
+	var content strings.Builder
+	o := m.onboarding
+	host := o.hosts[o.index]
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render(fmt.Sprintf("Review SSH Config (%d of %d)", o.index+1, len(o.hosts)))
+	content.WriteString(header + "\n\n")
+
+	// What was parsed and what needs attention
+	infoStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 2).
+		Width(m.width - 4)
+	warnStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Warning)
+
+	info := fmt.Sprintf("Host %s\nHostName %s, port %s", host.Name, host.Host, host.Port)
+	if host.ProxyJump != "" {
+		info += ", via " + host.ProxyJump
+	}
+	if tags := m.metadata.Tags(host.Name); len(tags) > 0 {
+		info += "\nTags: " + strings.Join(tags, ", ")
+	}
+	if issues := onboardingIssues(host); len(issues) > 0 {
+		info += "\n" + warnStyle.Render("⚠ "+strings.Join(issues, " • "))
+	}
+	if config.IsPattern(host.Name) {
+		info += "\nSettings of a pattern apply to every host it matches; it cannot be connected to by itself."
+	}
+	content.WriteString(infoStyle.Render(info) + "\n\n")
+
+	// Fields
+	fieldStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(min(60, m.width-4))
+	activeFieldStyle := fieldStyle.
+		BorderForeground(m.theme.Accent).
+		Bold(true)
+
+	labels := [onboardingManaged]string{"User: ", "Identity file: ", "Add tags: "}
+	for field, label := range labels {
+		style := fieldStyle
+		if o.field == onboardingField(field) {
+			style = activeFieldStyle
+		}
+		input := o.inputs[field]
+		input.Width = max(1, style.GetWidth()-style.GetHorizontalPadding()-lipgloss.Width(label)-1)
+		content.WriteString(style.Render(label+input.View()) + "\n")
+	}
+
+	managed := "[ ] Managed by xssh (hidden from the host list)"
+	if o.managed {
+		managed = "[x] Managed by xssh (shown in the host list)"
+	}
+	style := fieldStyle
+	if o.field == onboardingManaged {
+		style = activeFieldStyle
+	}
+	content.WriteString(style.Render(managed) + "\n\n")
+
+	if o.err != nil {
+		errorStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error).
+			MarginLeft(2)
+		content.WriteString(errorStyle.Render(o.err.Error()) + "\n\n")
+	}
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Tab/↑↓: field • Space: toggle managed • Enter: save & next • ESC: finish"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
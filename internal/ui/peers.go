@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/inventory"
+)
+
+// peersScreen holds the state of the Tailscale/ZeroTier peer list
+type peersScreen struct {
+	peers      []inventory.Peer
+	loading    bool
+	err        error // Failure of one or both networks
+	cursor     int
+	returnMode ViewMode
+}
+
+// peersDiscoveredMsg carries the outcome of a peer discovery
+type peersDiscoveredMsg struct {
+	peers []inventory.Peer
+	err   error
+}
+
+// openPeers switches to the peer list and starts discovering the peers
+func (m Model) openPeers() (tea.Model, tea.Cmd) {
+	m.peers = &peersScreen{returnMode: m.viewMode}
+	m.viewMode = ModePeers
+	return m, m.discoverPeers()
+}
+
+// discoverPeers asks the Tailscale and ZeroTier clients for their peers in
+// the background
+func (m *Model) discoverPeers() tea.Cmd {
+	m.peers.loading = true
+	return func() tea.Msg {
+		peers, err := inventory.DiscoverPeers()
+		return peersDiscoveredMsg{peers: peers, err: err}
+	}
+}
+
+// handlePeersDiscovered shows the discovered peers
+func (m Model) handlePeersDiscovered(msg peersDiscoveredMsg) (tea.Model, tea.Cmd) {
+	screen := m.peers
+	if screen == nil {
+		return m, nil
+	}
+	screen.loading = false
+	screen.peers = msg.peers
+	screen.err = msg.err
+	if screen.cursor >= len(screen.peers) {
+		screen.cursor = max(0, len(screen.peers)-1)
+	}
+	return m, nil
+}
+
+// configuredPeer returns the configured host reaching a peer, by MagicDNS
+// name or address
+func (m Model) configuredPeer(peer inventory.Peer) (config.SSHHost, bool) {
+	for _, host := range m.hosts {
+		if host.Host == "" {
+			continue
+		}
+		if strings.EqualFold(host.Host, peer.DNSName) || host.Host == peer.Address {
+			return host, true
+		}
+	}
+	return config.SSHHost{}, false
+}
+
+// handlePeersMode handles keys in the peer list
+func (m Model) handlePeersMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	screen := m.peers
+	if screen == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	m.message = ""
+	m.messageType = ""
+
+	switch msg.String() {
+	case "esc", "q":
+		m.viewMode = screen.returnMode
+		m.peers = nil
+
+	case "up", "k":
+		if screen.cursor > 0 {
+			screen.cursor--
+		}
+
+	case "down", "j":
+		if screen.cursor < len(screen.peers)-1 {
+			screen.cursor++
+		}
+
+	case "enter", "a":
+		// Adopt the selected peer
+		if screen.cursor >= len(screen.peers) {
+			return m, nil
+		}
+		peer := screen.peers[screen.cursor]
+		if host, ok := m.configuredPeer(peer); ok {
+			m.message = fmt.Sprintf("%s is already configured as '%s'", peer.Name, host.Name)
+			m.messageType = "info"
+			return m, nil
+		}
+		m.peers = nil
+		return m.addHosts([]config.SSHHost{peer.Host()}, false)
+
+	case "A":
+		// Adopt every peer that is not configured yet
+		var hosts []config.SSHHost
+		for _, peer := range screen.peers {
+			if _, ok := m.configuredPeer(peer); !ok {
+				hosts = append(hosts, peer.Host())
+			}
+		}
+		if len(hosts) == 0 {
+			m.message = "All peers are configured already"
+			m.messageType = "info"
+			return m, nil
+		}
+		m.peers = nil
+		return m.addHosts(hosts, false)
+
+	case "R", "ctrl+r":
+		if !screen.loading {
+			return m, m.discoverPeers()
+		}
+	}
+
+	return m, nil
+}
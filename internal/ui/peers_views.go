@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderPeersView renders the Tailscale/ZeroTier peer list
+func (m Model) renderPeersView() string {
+	var content strings.Builder
+	screen := m.peers
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Tailscale / ZeroTier Peers")
+	content.WriteString(header + "\n\n")
+
+	// Peer list
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	rows := max(3, m.height-10)
+	innerWidth := m.width - 8
+
+	emptyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Italic(true)
+
+	var list strings.Builder
+	if screen.loading && len(screen.peers) == 0 {
+		list.WriteString(emptyStyle.Render("Asking tailscale and zerotier-cli for peers..."))
+	} else if len(screen.peers) == 0 {
+		list.WriteString(emptyStyle.Render("No online peers found"))
+	} else {
+		start := 0
+		if screen.cursor >= rows {
+			start = screen.cursor - rows + 1
+		}
+		end := min(len(screen.peers), start+rows)
+
+		selectedStyle := m.theme.SelectedStyle()
+		subtleStyle := lipgloss.NewStyle().Foreground(m.theme.Subtle)
+		for i := start; i < end; i++ {
+			peer := screen.peers[i]
+
+			cursor := "  "
+			if i == screen.cursor {
+				cursor = "▶ "
+			}
+
+			address := peer.DNSName
+			if address == "" {
+				address = peer.Address
+			}
+			state := ""
+			if host, ok := m.configuredPeer(peer); ok {
+				state = "configured as " + host.Name
+			}
+
+			// Network, name, address and whether a host reaches it already
+			addressWidth := max(10, innerWidth-len(cursor)-11-22-lipgloss.Width(state))
+			line := fmt.Sprintf("%s%-10s %s %s%s", cursor, peer.Network,
+				padAndTruncate(peer.Name, 21), padAndTruncate(address, addressWidth), state)
+
+			switch {
+			case i == screen.cursor:
+				list.WriteString(selectedStyle.Render(line) + "\n")
+			case state != "":
+				list.WriteString(subtleStyle.Render(line) + "\n")
+			default:
+				list.WriteString(line + "\n")
+			}
+		}
+	}
+	content.WriteString(panelStyle.Render(strings.TrimRight(list.String(), "\n")) + "\n")
+
+	// Failures of a network, or the last message
+	if screen.err != nil {
+		content.WriteString(m.theme.MessageStyle("error", m.width).Render(screen.err.Error()) + "\n")
+	} else {
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+	help := "↑/k ↓/j: move • Enter/a: add as host • A: add all new peers • R: refresh • ESC/q: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
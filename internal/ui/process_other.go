@@ -0,0 +1,13 @@
+//go:build !windows
+
+package ui
+
+import (
+	"os"
+	"syscall"
+)
+
+// hangUp ends an interactive process as a closed terminal would
+func hangUp(process *os.Process) {
+	process.Signal(syscall.SIGHUP)
+}
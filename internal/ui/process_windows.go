@@ -0,0 +1,8 @@
+package ui
+
+import "os"
+
+// hangUp ends an interactive process; Windows has no hangup signal
+func hangUp(process *os.Process) {
+	process.Kill()
+}
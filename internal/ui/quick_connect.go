@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+	"xssh/internal/ssh"
+)
+
+// quickConnectBar holds the state of the quick-connect mode
+type quickConnectBar struct {
+	input      textinput.Model
+	finished   *config.SSHHost // Host of the session that just ended, offered for saving
+	savedAs    string          // Alias of a configured host matching finished
+	returnMode ViewMode
+}
+
+// quickConnectDoneMsg reports that a quick-connect ssh session ended
+type quickConnectDoneMsg struct {
+	host    config.SSHHost
+	err     error
+	tracked *trackedSession
+}
+
+// openQuickConnect switches to the quick-connect bar
+func (m Model) openQuickConnect() (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.Prompt = "ssh "
+	input.Placeholder = "user@host:port, ssh://host or a full ssh command"
+	input.CharLimit = 512
+	input.Width = max(20, m.width-14)
+
+	m.quick = &quickConnectBar{
+		input:      input,
+		returnMode: m.viewMode,
+	}
+	m.viewMode = ModeQuickConnect
+	m.message = ""
+	m.messageType = ""
+	return m, m.quick.input.Focus()
+}
+
+// handleQuickConnectMode handles keys in the quick-connect bar
+func (m Model) handleQuickConnectMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	bar := m.quick
+	if bar == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+
+	if bar.finished != nil {
+		return m.handleQuickConnectFinished(msg)
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.viewMode = bar.returnMode
+		m.quick = nil
+		return m, nil
+
+	case "enter":
+		host, err := ssh.ParseTarget(bar.input.Value())
+		if err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return m, nil
+		}
+		return m.quickConnect(host)
+
+	case "ctrl+s":
+		// Save without connecting
+		host, err := ssh.ParseTarget(bar.input.Value())
+		if err != nil {
+			m.message = err.Error()
+			m.messageType = "error"
+			return m, nil
+		}
+		return m.saveQuickConnectHost(host)
+	}
+
+	m.message = ""
+	m.messageType = ""
+	var cmd tea.Cmd
+	bar.input, cmd = bar.input.Update(msg)
+	return m, cmd
+}
+
+// handleQuickConnectFinished handles keys after a quick-connect session
+func (m Model) handleQuickConnectFinished(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	bar := m.quick
+	host := *bar.finished
+
+	switch msg.String() {
+	case "s":
+		if bar.savedAs == "" {
+			return m.saveQuickConnectHost(host)
+		}
+
+	case "r", "enter":
+		// Connect again
+		return m.quickConnect(host)
+
+	case "e":
+		// Back to the bar to edit the target
+		bar.finished = nil
+		m.message = ""
+		m.messageType = ""
+		return m, bar.input.Focus()
+
+	case "esc", "q":
+		m.viewMode = bar.returnMode
+		m.quick = nil
+		m.message = ""
+		m.messageType = ""
+	}
+
+	return m, nil
+}
+
+// quickConnect runs ssh for the host and returns to xssh when it exits
+func (m Model) quickConnect(host config.SSHHost) (tea.Model, tea.Cmd) {
+	cmd, err := ssh.Command(host)
+	if err != nil {
+		m.message = err.Error()
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.quick.input.Blur()
+	// ssh owns the terminal until it exits; hanging it up ends the session
+	tracked := m.sessions.track("shell", host.Host, ssh.BuildSSHCommand(host), func() {
+		if cmd.Process != nil {
+			hangUp(cmd.Process)
+		}
+	})
+	recordAudit(config.AuditEvent{Action: config.AuditConnect, Host: host.Name, Detail: tracked.command})
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return quickConnectDoneMsg{host: host, err: err, tracked: tracked}
+	})
+}
+
+// handleQuickConnectDone offers to save the host once its session ended
+func (m Model) handleQuickConnectDone(msg quickConnectDoneMsg) (tea.Model, tea.Cmd) {
+	msg.tracked.finish(msg.err)
+	recordQuickConnect(msg.host, msg.tracked)
+	if m.quick == nil {
+		return m, nil
+	}
+
+	host := msg.host
+	m.quick.finished = &host
+	m.quick.savedAs = m.findMatchingHost(host)
+
+	if msg.err != nil {
+		m.message = fmt.Sprintf("ssh exited: %v", msg.err)
+		m.messageType = "error"
+	} else {
+		m.message = fmt.Sprintf("Session with %s ended", host.Host)
+		m.messageType = "info"
+	}
+	return m, nil
+}
+
+// recordQuickConnect adds a finished quick-connect session to the session
+// store, under the address of the host as it has no alias. Failing to record
+// it is only logged.
+func recordQuickConnect(host config.SSHHost, tracked *trackedSession) {
+	record := config.SessionRecord{
+		Kind:   config.SessionConnect,
+		Host:   host.Name,
+		Start:  tracked.started,
+		End:    tracked.ended,
+		Detail: tracked.command,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(tracked.err, &exitErr) {
+		record.ExitCode = exitErr.ExitCode()
+	} else if tracked.err != nil {
+		record.ExitCode = -1
+		record.Error = tracked.err.Error()
+	}
+	if err := config.RecordSession(record); err != nil {
+		slog.Warn("failed to record quick connect", "host", host.Name, "error", err)
+	}
+}
+
+// recordAudit adds an event to the audit log. Failing to write it is only
+// logged, as the TUI cannot report it anywhere useful.
+func recordAudit(event config.AuditEvent) {
+	if err := config.RecordAudit(event); err != nil {
+		slog.Warn("failed to write the audit log", "action", event.Action, "host", event.Host, "error", err)
+	}
+}
+
+// saveQuickConnectHost opens the add form prefilled with a quick-connect host
+func (m Model) saveQuickConnectHost(host config.SSHHost) (tea.Model, tea.Cmd) {
+	alias := host.Host
+	if m.findHostIndex(alias) >= 0 {
+		alias = m.copyAlias(alias)
+	}
+
+	m.quick = nil
+	m.viewMode = ModeAdd
+	m.editIndex = -1
+	m.message = ""
+	m.messageType = ""
+	m.formData = FormData{
+		Host:     host.Host,
+		User:     host.User,
+		Port:     host.Port,
+		Identity: host.Identity,
+		Alias:    alias,
+		AuthType: AuthPassword,
+	}
+	if host.Identity != "" {
+		m.formData.AuthType = AuthKey
+	}
+	m.loadFormInputs()
+	return m, m.focusField(FieldAlias)
+}
+
+// findMatchingHost returns the alias of a configured host with the same
+// address, user and port, or "" when there is none
+func (m Model) findMatchingHost(host config.SSHHost) string {
+	for _, configured := range m.hosts {
+		if configured.Host == host.Host &&
+			configured.User == host.User &&
+			defaultPort(configured.Port) == defaultPort(host.Port) {
+			return configured.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/ssh"
+)
+
+// renderQuickConnectView renders the quick-connect bar
+func (m Model) renderQuickConnectView() string {
+	var content strings.Builder
+	bar := m.quick
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Quick Connect")
+	content.WriteString(header + "\n\n")
+
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	var panel strings.Builder
+	if bar.finished != nil {
+		host := *bar.finished
+		panel.WriteString(fmt.Sprintf("Last session: %s\n", ssh.BuildSSHCommand(host)))
+		if bar.savedAs != "" {
+			panel.WriteString(subtleStyle.Render(fmt.Sprintf("Already saved as '%s'", bar.savedAs)))
+		} else {
+			panel.WriteString(subtleStyle.Render("Not in your config yet • press s to save it as a new host"))
+		}
+	} else {
+		panel.WriteString(bar.input.View() + "\n")
+
+		// Preview what will be run
+		if strings.TrimSpace(bar.input.Value()) != "" {
+			if host, err := ssh.ParseTarget(bar.input.Value()); err == nil {
+				panel.WriteString(subtleStyle.Render("→ " + ssh.BuildSSHCommand(host)))
+			}
+		}
+	}
+	content.WriteString(panelStyle.Render(strings.TrimRight(panel.String(), "\n")) + "\n")
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Enter: connect • Ctrl+S: save as host • ESC: back"
+	if bar.finished != nil {
+		help = "r/Enter: reconnect • e: edit target • ESC/q: back"
+		if bar.savedAs == "" {
+			help = "s: save as host • " + help
+		}
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
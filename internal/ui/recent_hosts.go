@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// recentHostsLimit is the number of hosts in the Recent section
+const recentHostsLimit = 5
+
+// visibleRecentHosts returns the recent hosts that are still configured. The
+// section is hidden while the list is filtered.
+func (m Model) visibleRecentHosts() []config.SSHHost {
+	if m.filterQuery != "" {
+		return nil
+	}
+	var hosts []config.SSHHost
+	for _, name := range m.recentHosts {
+		if index := m.findHostIndex(name); index >= 0 {
+			hosts = append(hosts, m.hosts[index])
+		}
+	}
+	return hosts
+}
+
+// connectRecent connects to the n-th recent host, counting from 1
+func (m Model) connectRecent(n int) (tea.Model, tea.Cmd) {
+	recent := m.visibleRecentHosts()
+	if n < 1 || n > len(recent) {
+		return m, nil
+	}
+	host := recent[n-1]
+	m.selectedHost = &host
+	return m.quit()
+}
+
+// renderRecentHosts renders the Recent section shown above the host table
+func (m Model) renderRecentHosts() string {
+	recent := m.visibleRecentHosts()
+	if len(recent) == 0 {
+		return ""
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	keyStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	entries := make([]string, len(recent))
+	for i, host := range recent {
+		name := host.Name
+		if label, ok := m.hostLabelColor(host.Name); ok {
+			name = lipgloss.NewStyle().Foreground(label).Render(name)
+		}
+		entries[i] = keyStyle.Render(fmt.Sprintf("%d ", i+1)) + name
+	}
+	return titleStyle.Render("Recent: ") + strings.Join(entries, "   ")
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"log/slog"
+
+	"xssh/internal/config"
+	"xssh/internal/recording"
+)
+
+// recordingSettings holds the [recording] section of the xssh config with
+// the directory it resolves to
+type recordingSettings struct {
+	config.RecordingConfig
+	dir string
+}
+
+// newRecordingSettings reads the recording settings of appConfig. Recording
+// is turned off when the recordings directory cannot be found.
+func newRecordingSettings(appConfig *config.AppConfig) recordingSettings {
+	dir, err := appConfig.RecordingDir()
+	if err != nil {
+		return recordingSettings{}
+	}
+	return recordingSettings{RecordingConfig: appConfig.Recording, dir: dir}
+}
+
+// start begins recording the output of command on the host called host, or
+// returns nil when the host is not recorded. Old recordings are pruned
+// first. A recording that cannot be started is only logged, so the command
+// still runs.
+func (s recordingSettings) start(host, command string) *recording.Recorder {
+	if s.dir == "" || !s.Records(host) {
+		return nil
+	}
+	if _, err := recording.Prune(s.dir, s.MaxAge, s.MaxFiles); err != nil {
+		slog.Warn("failed to prune recordings", "dir", s.dir, "error", err)
+	}
+	recorder, err := recording.Start(recording.Options{
+		Format:  s.Format,
+		Dir:     s.dir,
+		Host:    host,
+		Command: command,
+	})
+	if err != nil {
+		slog.Warn("failed to start recording", "host", host, "error", err)
+		return nil
+	}
+	return recorder
+}
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// inlineRename is the alias being edited in place in the host table
+type inlineRename struct {
+	oldName string
+	input   textinput.Model
+}
+
+// openInlineRename starts editing the alias of the selected host in its row
+func (m Model) openInlineRename() (tea.Model, tea.Cmd) {
+	oldName := m.filteredHosts[m.cursor].Name
+
+	input := textinput.New()
+	input.Prompt = ""
+	input.CharLimit = 64
+	input.SetValue(oldName)
+	input.CursorEnd()
+	input.Validate = func(value string) error {
+		value = strings.TrimSpace(value)
+		if err := config.ValidateAlias(value); err != nil {
+			return err
+		}
+		if value != oldName && m.findHostIndex(value) >= 0 {
+			return fmt.Errorf("alias %q is already in use", value)
+		}
+		return nil
+	}
+
+	m.rename = &inlineRename{oldName: oldName, input: input}
+	return m, m.rename.input.Focus()
+}
+
+// handleRenameMode handles keys while an alias is edited in place
+func (m Model) handleRenameMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rename := m.rename
+
+	switch msg.String() {
+	case "esc":
+		m.rename = nil
+		return m, nil
+
+	case "enter":
+		if rename.input.Err != nil {
+			m.message = fmt.Sprintf("Cannot rename: %v", rename.input.Err)
+			m.messageType = "error"
+			return m, nil
+		}
+		newName := strings.TrimSpace(rename.input.Value())
+		m.rename = nil
+		if newName == rename.oldName {
+			return m, nil
+		}
+		return m.renameHost(rename.oldName, newName)
+	}
+
+	var cmd tea.Cmd
+	rename.input, cmd = rename.input.Update(msg)
+	return m, cmd
+}
+
+// renameHost changes the alias of a host in the config and keeps the
+// selection on it
+func (m Model) renameHost(oldName, newName string) (tea.Model, tea.Cmd) {
+	index := m.findHostIndex(oldName)
+	if index < 0 {
+		return m, nil
+	}
+	host := m.hosts[index]
+
+	if err := m.sshConfig.Backup(); err != nil {
+		m.message = fmt.Sprintf("Failed to back up config: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+	renamed := host
+	renamed.Name = newName
+	m.sshConfig.UpdateHost(oldName, renamed)
+	m.sshConfig.RenameJumpHost(oldName, newName)
+	if err := m.sshConfig.Save(); err != nil {
+		m.sshConfig.UpdateHost(newName, host)
+		m.sshConfig.RenameJumpHost(newName, oldName)
+		m.message = fmt.Sprintf("Failed to save config: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.message = fmt.Sprintf("Host '%s' renamed to '%s'", oldName, newName)
+	m.messageType = "success"
+	if err := m.renameReferences(oldName, newName); err != nil {
+		m.message = fmt.Sprintf("Host renamed, but %v", err)
+		m.messageType = "error"
+	}
+
+	m.hosts = m.sshConfig.Hosts
+	m.filterHosts()
+	for i, h := range m.filteredHosts {
+		if h.Name == newName {
+			m.cursor = i
+			break
+		}
+	}
+	return m, nil
+}
+
+// renameReferences moves what xssh keeps about a host under its alias, its
+// metadata, connection history, marks, running forwardings and the tunnels
+// and bastions of the config file, to the new alias
+func (m *Model) renameReferences(oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	m.reuseAlias(newName)
+
+	if m.markedHosts[oldName] {
+		delete(m.markedHosts, oldName)
+		m.markedHosts[newName] = true
+	}
+	for i, name := range m.recentHosts {
+		if name == oldName {
+			m.recentHosts[i] = newName
+		}
+	}
+	if last, ok := m.lastUsed[oldName]; ok {
+		m.lastUsed[newName] = last
+		delete(m.lastUsed, oldName)
+	}
+	m.forwardingManager.RenameHost(oldName, newName)
+	for i := range m.bastions {
+		if m.bastions[i].Bastion == oldName {
+			m.bastions[i].Bastion = newName
+		}
+	}
+	// The policy stays ordered by alias, as the config loads it
+	sort.Slice(m.bastions, func(i, j int) bool { return m.bastions[i].Bastion < m.bastions[j].Bastion })
+
+	m.metadata.RenameHost(oldName, newName)
+	if err := m.metadata.Save(); err != nil {
+		return fmt.Errorf("failed to save host metadata: %w", err)
+	}
+	if err := config.RenameConnectionHistory(oldName, newName); err != nil {
+		return fmt.Errorf("failed to update connection history: %w", err)
+	}
+	if err := config.RenameConfigHost(oldName, newName); err != nil {
+		return fmt.Errorf("failed to update the tunnels and bastions of config.toml: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// filterMenu holds the state of the saved filters menu
+type filterMenu struct {
+	cursor int
+	naming bool            // Whether the name of the active filter is being typed
+	input  textinput.Model // Name to save the active filter under
+}
+
+// savedFilterNames returns the names of the saved filters, sorted
+func (m Model) savedFilterNames() []string {
+	names := make([]string, 0, len(m.savedFilters))
+	for name := range m.savedFilters {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	return names
+}
+
+// openFilterMenu shows the saved filters, starting on the active one
+func (m Model) openFilterMenu() (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.Prompt = "Name: "
+	input.Placeholder = "prod web servers"
+	input.CharLimit = 64
+	input.Width = max(20, m.width-14)
+	input.Validate = config.ValidateFilterName
+
+	menu := &filterMenu{input: input}
+	for i, name := range m.savedFilterNames() {
+		if m.savedFilters[name] == m.filterQuery {
+			menu.cursor = i
+		}
+	}
+
+	m.filterMenu = menu
+	m.viewMode = ModeSavedFilters
+	m.message = ""
+	m.messageType = ""
+	return m, nil
+}
+
+// applyFilter makes query the active filter of the host list
+func (m *Model) applyFilter(query string) {
+	m.filterQuery = query
+	m.searchMode = false
+	m.filterHosts()
+}
+
+// rememberFilter stores the active filter for the next run when it changed.
+// Like the connection history, failing to write it is not worth reporting
+// on the way out.
+func (m Model) rememberFilter() {
+	if m.filterQuery != m.lastFilter {
+		config.SaveLastFilter(m.filterQuery)
+	}
+}
+
+// handleSavedFiltersMode handles keys in the saved filters menu
+func (m Model) handleSavedFiltersMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	menu := m.filterMenu
+	if menu == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+	if menu.naming {
+		return m.handleFilterNaming(msg)
+	}
+	names := m.savedFilterNames()
+
+	switch msg.String() {
+	case "esc", "q", "F":
+		m.viewMode = ModeList
+		m.filterMenu = nil
+
+	case "up", "k":
+		if menu.cursor > 0 {
+			menu.cursor--
+		}
+
+	case "down", "j":
+		if menu.cursor < len(names)-1 {
+			menu.cursor++
+		}
+
+	case "enter":
+		if menu.cursor < len(names) {
+			name := names[menu.cursor]
+			m.applyFilter(m.savedFilters[name])
+			m.viewMode = ModeList
+			m.filterMenu = nil
+			m.message = fmt.Sprintf("Filter '%s' applied", name)
+			m.messageType = "info"
+		}
+
+	case "s", "a":
+		// Save the active filter under a name
+		if m.filterQuery == "" {
+			m.message = "No active filter to save, search with '/' first"
+			m.messageType = "error"
+			return m, nil
+		}
+		menu.naming = true
+		menu.input.SetValue("")
+		return m, menu.input.Focus()
+
+	case "d", "x":
+		if menu.cursor >= len(names) {
+			return m, nil
+		}
+		name := names[menu.cursor]
+		if err := config.DeleteFilter(name); err != nil {
+			m.message = fmt.Sprintf("Failed to delete filter: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		delete(m.savedFilters, name)
+		if menu.cursor > 0 && menu.cursor >= len(names)-1 {
+			menu.cursor--
+		}
+		m.message = fmt.Sprintf("Filter '%s' deleted", name)
+		m.messageType = "success"
+	}
+
+	return m, nil
+}
+
+// handleFilterNaming handles keys while the name of a filter is typed
+func (m Model) handleFilterNaming(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	menu := m.filterMenu
+
+	switch msg.String() {
+	case "esc":
+		menu.naming = false
+		menu.input.Blur()
+		return m, nil
+
+	case "enter":
+		name := strings.TrimSpace(menu.input.Value())
+		if name == "" {
+			menu.input.Err = fmt.Errorf("name is required")
+			return m, nil
+		}
+		if err := config.ValidateFilterName(name); err != nil {
+			menu.input.Err = err
+			return m, nil
+		}
+		if err := config.SaveFilter(name, m.filterQuery); err != nil {
+			m.message = fmt.Sprintf("Failed to save filter: %v", err)
+			m.messageType = "error"
+			return m, nil
+		}
+		m.savedFilters[name] = m.filterQuery
+		menu.naming = false
+		menu.input.Blur()
+		for i, saved := range m.savedFilterNames() {
+			if saved == name {
+				menu.cursor = i
+			}
+		}
+		m.message = fmt.Sprintf("Filter saved as '%s'", name)
+		m.messageType = "success"
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	menu.input, cmd = menu.input.Update(msg)
+	return m, cmd
+}
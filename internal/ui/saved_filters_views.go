@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderSavedFiltersView renders the saved filters menu
+func (m Model) renderSavedFiltersView() string {
+	var content strings.Builder
+	menu := m.filterMenu
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Saved Filters")
+	content.WriteString(header + "\n\n")
+
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	active := "none"
+	if m.filterQuery != "" {
+		active = m.filterQuery
+	}
+	content.WriteString("Active filter: " + active + "\n\n")
+
+	names := m.savedFilterNames()
+	if len(names) == 0 {
+		emptyStyle := subtleStyle.
+			Italic(true).
+			Align(lipgloss.Center).
+			Width(m.width)
+
+		content.WriteString(emptyStyle.Render("No saved filters yet, press 's' to save the active one") + "\n\n")
+	} else {
+		selectedStyle := m.theme.SelectedStyle()
+
+		nameWidth := 0
+		for _, name := range names {
+			nameWidth = max(nameWidth, len(name))
+		}
+		nameWidth = min(nameWidth, 30)
+
+		for i, name := range names {
+			cursor := "  "
+			if i == menu.cursor {
+				cursor = "▶ "
+			}
+			line := fmt.Sprintf("%s  %s", padAndTruncate(name, nameWidth), m.savedFilters[name])
+			line = padAndTruncate(line, max(10, m.width-4))
+
+			if i == menu.cursor {
+				content.WriteString(selectedStyle.Render(cursor+line) + "\n")
+			} else {
+				content.WriteString(cursor + line + "\n")
+			}
+		}
+		content.WriteString("\n")
+	}
+
+	// Name of the filter being saved
+	if menu.naming {
+		content.WriteString(menu.input.View() + "\n")
+		if err := menu.input.Err; err != nil {
+			errorStyle := lipgloss.NewStyle().
+				Foreground(m.theme.Error)
+			content.WriteString(errorStyle.Render(err.Error()) + "\n")
+		}
+		content.WriteString("\n")
+	}
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • Enter: apply • s: save active filter • d: delete • ESC: back"
+	if menu.naming {
+		help = "Type a name • Enter: save • ESC: cancel"
+	}
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
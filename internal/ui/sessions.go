@@ -0,0 +1,277 @@
+package ui
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+)
+
+// maxFinishedSessions is the number of ended sessions kept in the list
+const maxFinishedSessions = 50
+
+// trackedSession is a connection or command xssh started
+type trackedSession struct {
+	id      int
+	kind    string // "shell" for interactive sessions, "exec" for command runs, "term" for terminals of the daemon
+	hosts   string // Host, or hosts of a run on several
+	command string // Command of an exec run
+	started time.Time
+	ended   time.Time // Zero while the session is live
+	err     error     // Why the session failed, nil on success
+	stop    func()    // Terminates the session; nil when xssh cannot
+	term    int       // ID of the terminal of the daemon, 0 for others
+}
+
+// live reports whether the session has not ended yet
+func (s *trackedSession) live() bool {
+	return s.ended.IsZero()
+}
+
+// duration returns how long the session ran, or has been running
+func (s *trackedSession) duration() time.Duration {
+	if s.live() {
+		return time.Since(s.started)
+	}
+	return s.ended.Sub(s.started)
+}
+
+// finish records that the session ended
+func (s *trackedSession) finish(err error) {
+	if s.live() {
+		s.ended = time.Now()
+		s.err = err
+	}
+}
+
+// sessionTracker lists what xssh started, live sessions first
+type sessionTracker struct {
+	sessions []*trackedSession // Oldest first
+	nextID   int
+	cursor   int
+	tickID   int // Identifies the active refresh loop of the sessions view
+}
+
+// sessionsTickMsg refreshes the durations shown in the sessions view
+type sessionsTickMsg struct {
+	id int
+}
+
+// Terminal is a terminal the xssh daemon opened to connect to a host
+type Terminal struct {
+	ID      int
+	Host    string
+	Command string
+	Started time.Time
+}
+
+// TerminalSource lists the terminals of the xssh daemon and hangs them up
+type TerminalSource interface {
+	Terminals() ([]Terminal, error)
+	CloseTerminal(id int) error
+}
+
+// terminalsMsg carries the terminals of the daemon to the sessions view
+type terminalsMsg struct {
+	terminals []Terminal
+	err       error
+}
+
+// WithTerminals returns the model listing the terminals of source in the
+// sessions view, so they can be hung up from there
+func (m Model) WithTerminals(source TerminalSource) Model {
+	m.terminals = source
+	return m
+}
+
+// track adds a live session and drops the oldest ended ones beyond the limit
+func (t *sessionTracker) track(kind, hosts, command string, stop func()) *trackedSession {
+	t.nextID++
+	session := &trackedSession{
+		id:      t.nextID,
+		kind:    kind,
+		hosts:   hosts,
+		command: command,
+		started: time.Now(),
+		stop:    stop,
+	}
+	t.sessions = append(t.sessions, session)
+
+	finished := 0
+	for i := len(t.sessions) - 1; i >= 0; i-- {
+		if t.sessions[i].live() {
+			continue
+		}
+		if finished++; finished > maxFinishedSessions {
+			t.sessions = append(t.sessions[:i], t.sessions[i+1:]...)
+		}
+	}
+	return session
+}
+
+// ordered returns the live sessions, newest first, then the ended ones
+func (t *sessionTracker) ordered() []*trackedSession {
+	var live, ended []*trackedSession
+	for i := len(t.sessions) - 1; i >= 0; i-- {
+		if t.sessions[i].live() {
+			live = append(live, t.sessions[i])
+		} else {
+			ended = append(ended, t.sessions[i])
+		}
+	}
+	return append(live, ended...)
+}
+
+// liveCount returns the number of live sessions
+func (t *sessionTracker) liveCount() int {
+	count := 0
+	for _, session := range t.sessions {
+		if session.live() {
+			count++
+		}
+	}
+	return count
+}
+
+// clearFinished forgets the sessions that have ended
+func (t *sessionTracker) clearFinished() {
+	var live []*trackedSession
+	for _, session := range t.sessions {
+		if session.live() {
+			live = append(live, session)
+		}
+	}
+	t.sessions = live
+	t.cursor = 0
+}
+
+// syncTerminals lists the terminals the daemon has open, ending the entries
+// of those it no longer has
+func (t *sessionTracker) syncTerminals(terminals []Terminal, source TerminalSource) {
+	open := make(map[int]bool, len(terminals))
+	for _, terminal := range terminals {
+		open[terminal.ID] = true
+	}
+	tracked := make(map[int]bool)
+	for _, session := range t.sessions {
+		if session.term == 0 || !session.live() {
+			continue
+		}
+		if open[session.term] {
+			tracked[session.term] = true
+		} else {
+			session.finish(nil)
+		}
+	}
+
+	for _, terminal := range terminals {
+		if tracked[terminal.ID] {
+			continue
+		}
+		id := terminal.ID
+		session := t.track("term", terminal.Host, terminal.Command, func() {
+			if err := source.CloseTerminal(id); err != nil {
+				slog.Warn("failed to hang up daemon terminal", "id", id, "error", err)
+			}
+		})
+		session.term = id
+		session.started = terminal.Started
+	}
+}
+
+// sessionsTick schedules the next refresh of the sessions view
+func sessionsTick(id int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return sessionsTickMsg{id: id}
+	})
+}
+
+// fetchTerminals asks the daemon for its terminals, when the model has a
+// source for them
+func (m Model) fetchTerminals() tea.Cmd {
+	source := m.terminals
+	if source == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		terminals, err := source.Terminals()
+		return terminalsMsg{terminals: terminals, err: err}
+	}
+}
+
+// openSessions shows the sessions xssh started
+func (m Model) openSessions() (tea.Model, tea.Cmd) {
+	m.sessions.cursor = 0
+	m.sessions.tickID++
+	m.viewMode = ModeSessions
+	return m, tea.Batch(sessionsTick(m.sessions.tickID), m.fetchTerminals())
+}
+
+// handleSessionsTick keeps durations and the terminals of the daemon current
+// while the view is on screen
+func (m Model) handleSessionsTick(msg sessionsTickMsg) (tea.Model, tea.Cmd) {
+	if msg.id != m.sessions.tickID || m.viewMode != ModeSessions {
+		return m, nil
+	}
+	return m, tea.Batch(sessionsTick(msg.id), m.fetchTerminals())
+}
+
+// handleTerminals merges the terminals of the daemon into the sessions view.
+// When the daemon cannot be asked, the list is left as it was.
+func (m Model) handleTerminals(msg terminalsMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		slog.Debug("failed to list daemon terminals", "error", msg.err)
+		return m, nil
+	}
+	m.sessions.syncTerminals(msg.terminals, m.terminals)
+	if m.sessions.cursor >= len(m.sessions.sessions) {
+		m.sessions.cursor = max(0, len(m.sessions.sessions)-1)
+	}
+	return m, nil
+}
+
+// handleSessionsMode handles keys in the sessions view
+func (m Model) handleSessionsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := m.sessions
+	sessions := t.ordered()
+
+	switch msg.String() {
+	case "esc", "q", "S":
+		m.viewMode = ModeList
+
+	case "up", "k":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+
+	case "down", "j":
+		if t.cursor < len(sessions)-1 {
+			t.cursor++
+		}
+
+	case "x", "d":
+		// Terminate the selected session
+		if t.cursor >= len(sessions) {
+			return m, nil
+		}
+		session := sessions[t.cursor]
+		switch {
+		case !session.live():
+			m.message = "The session has already ended"
+			m.messageType = "info"
+		case session.stop == nil:
+			m.message = "xssh cannot terminate this session"
+			m.messageType = "error"
+		default:
+			session.stop()
+			m.message = fmt.Sprintf("Terminating %s on %s...", session.kind, session.hosts)
+			m.messageType = "info"
+		}
+
+	case "c":
+		t.clearFinished()
+	}
+
+	return m, nil
+}
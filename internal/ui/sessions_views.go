@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// renderSessionsView renders the sessions and command runs xssh started
+func (m Model) renderSessionsView() string {
+	var content strings.Builder
+	t := m.sessions
+	sessions := t.ordered()
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render(fmt.Sprintf("Sessions (%d live)", t.liveCount()))
+	content.WriteString(header + "\n\n")
+
+	if len(sessions) == 0 {
+		emptyStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle).
+			Italic(true).
+			Align(lipgloss.Center).
+			Width(m.width)
+
+		content.WriteString(emptyStyle.Render("No sessions started from xssh yet") + "\n\n")
+	} else {
+		selectedStyle := m.theme.SelectedStyle()
+		liveStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Success)
+		failedStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Error)
+		subtleStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Subtle)
+
+		titleStyle := lipgloss.NewStyle().
+			Bold(true).
+			Foreground(m.theme.Primary)
+		content.WriteString(titleStyle.Render(fmt.Sprintf("  %-8s %-6s %-9s %-20s %s", "STATE", "KIND", "DURATION", "HOSTS", "COMMAND")) + "\n")
+
+		for i, session := range sessions {
+			cursor := "  "
+			if i == t.cursor {
+				cursor = "▶ "
+			}
+
+			state, style := "live", liveStyle
+			switch {
+			case session.live():
+			case errors.Is(session.err, errCommandCanceled):
+				state, style = "stopped", subtleStyle
+			case session.err != nil:
+				state, style = "failed", failedStyle
+			default:
+				state, style = "done", subtleStyle
+			}
+
+			command := session.command
+			if !session.live() && session.err != nil && !errors.Is(session.err, errCommandCanceled) {
+				command += "  (" + session.err.Error() + ")"
+			}
+			line := fmt.Sprintf("%-6s %-9s %-20s %s", session.kind, formatDuration(session.duration()),
+				padAndTruncate(session.hosts, 20), command)
+			line = padAndTruncate(line, max(10, m.width-13))
+
+			if i == t.cursor {
+				content.WriteString(selectedStyle.Render(cursor+fmt.Sprintf("%-8s ", state)+line) + "\n")
+			} else {
+				content.WriteString(cursor + style.Render(fmt.Sprintf("%-8s ", state)) + line + "\n")
+			}
+		}
+		content.WriteString("\n")
+	}
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k ↓/j: move • x: terminate • c: clear ended • ESC: back"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
+
+// formatDuration renders a session duration as 42s, 3m05s or 1h02m
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
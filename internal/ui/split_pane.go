@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/forwarding"
+	"xssh/internal/ssh"
+)
+
+// splitPaneMinWidth is the terminal width from which lists get a preview
+// pane on their right
+const splitPaneMinWidth = 120
+
+// isSplit reports whether the terminal is wide enough for the split layout
+func (m Model) isSplit() bool {
+	return !m.inline && m.width >= splitPaneMinWidth
+}
+
+// listPaneWidth returns the width available to a list: the whole terminal,
+// or its left part in the split layout
+func (m Model) listPaneWidth() int {
+	if m.isSplit() {
+		return m.width * 55 / 100
+	}
+	return m.width
+}
+
+// previewPaneWidth returns the width of the preview pane in the split layout
+func (m Model) previewPaneWidth() int {
+	return m.width - m.listPaneWidth()
+}
+
+// previewStyle is the bordered panel of the preview pane
+func (m Model) previewStyle(height int) lipgloss.Style {
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Subtle).
+		Padding(1, 2).
+		Height(height).
+		Width(m.previewPaneWidth() - 2)
+}
+
+// renderHostPreview renders the details of the selected host and the
+// tunnels running through it
+func (m Model) renderHostPreview(height int) string {
+	style := m.previewStyle(height)
+	if len(m.filteredHosts) == 0 {
+		return style.Render("")
+	}
+	host := m.filteredHosts[m.cursor]
+	width := m.previewPaneWidth() - 8
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	labelStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle).
+		Width(10)
+	if label, ok := m.hostLabelColor(host.Name); ok {
+		titleStyle = titleStyle.Foreground(label)
+	}
+
+	var content strings.Builder
+	title := host.Name
+	if color := m.metadata.Color(host.Name); color != "" {
+		title += " (" + color + ")"
+	}
+	content.WriteString(titleStyle.Render(padAndTruncate(title, width)) + "\n\n")
+
+	field := func(label, value string) {
+		if value != "" {
+			content.WriteString(labelStyle.Render(label) + padAndTruncate(value, width-10) + "\n")
+		}
+	}
+	auth := "Password"
+	if host.Identity != "" {
+		auth = "Key " + host.Identity
+	}
+	field("Host", host.Host)
+	field("User", host.User)
+	field("Port", host.Port)
+	field("Auth", auth)
+	if bastion := m.bastions.Bastion(host); bastion != "" && host.ProxyJump == "" {
+		field("Jump", bastion+" (bastion policy)")
+	} else {
+		field("Jump", host.ProxyJump)
+	}
+	if err := m.bastions.Check(host); err != nil {
+		content.WriteString(labelStyle.Render("Policy") + lipgloss.NewStyle().Foreground(m.theme.Warning).Render(padAndTruncate(err.Error(), width-10)) + "\n")
+	}
+	field("Command", ssh.BuildSSHCommand(host))
+	field("Notes", m.metadata.Notes(host.Name))
+	field("Forwards", strings.Join(m.metadata.AutoForwards(host.Name), ", "))
+	if tags := m.metadata.Tags(host.Name); len(tags) > 0 {
+		content.WriteString(labelStyle.Render("Tags") + m.renderTagChips(tags) + "\n")
+	}
+
+	// Tunnels through this host
+	var tunnels []string
+	for _, session := range m.forwardingManager.GetAllSessions() {
+		if session.Host().Name != host.Name {
+			continue
+		}
+		tunnels = append(tunnels, fmt.Sprintf("%s  ↓%s ↑%s",
+			forwardingSessionTitle(session),
+			forwarding.FormatBytes(session.Stats.BytesReceived),
+			forwarding.FormatBytes(session.Stats.BytesSent)))
+	}
+	if len(tunnels) > 0 {
+		content.WriteString("\n" + titleStyle.Render("Tunnels") + "\n")
+		for _, tunnel := range tunnels {
+			content.WriteString(padAndTruncate(tunnel, width) + "\n")
+		}
+	}
+
+	return style.Render(clipLines(strings.TrimRight(content.String(), "\n"), height-2))
+}
+
+// renderForwardingSplit renders the forwarding sessions as a compact list
+// with the live stats of the selected one in the preview pane
+func (m Model) renderForwardingSplit(sessions []*forwarding.ForwardingSession) string {
+	height := max(5, m.height-14)
+	listWidth := m.listPaneWidth()
+
+	listStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(1, 2).
+		Height(height).
+		Width(listWidth - 4)
+	selectedStyle := m.theme.SelectedStyle()
+
+	var list strings.Builder
+	for i, session := range sessions {
+		cursor := "  "
+		if m.cursor == i {
+			cursor = "▶ "
+		}
+		line := cursor + padAndTruncate(forwardingSessionTitle(session), listWidth-10)
+		if m.cursor == i {
+			list.WriteString(selectedStyle.Render(line) + "\n")
+		} else {
+			list.WriteString(line + "\n")
+		}
+	}
+
+	// Stats of the selected session, refreshed by the dashboard tick
+	var preview strings.Builder
+	if m.cursor < len(sessions) {
+		session := sessions[m.cursor]
+		titleStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Primary).
+			Bold(true)
+
+		preview.WriteString(titleStyle.Render(forwardingSessionTitle(session)) + "\n")
+		if host := session.Host(); host.Name != "" {
+			preview.WriteString(fmt.Sprintf("via %s\n", host.Name))
+		}
+		preview.WriteString("\n" + strings.ReplaceAll(m.forwardingSessionStats(session), " | ", "\n"))
+	}
+
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		listStyle.Render(clipLines(strings.TrimRight(list.String(), "\n"), height-2)),
+		m.previewStyle(height).Render(clipLines(preview.String(), height-2)))
+}
+
+// clipLines keeps the first n lines of s, so a pane does not grow past the
+// height it was given; the padding takes the other lines
+func clipLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[:max(0, n)]
+	}
+	return strings.Join(lines, "\n")
+}
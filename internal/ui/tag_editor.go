@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// tagEditor holds the state of the tag editor for one or more hosts
+type tagEditor struct {
+	hosts      []config.SSHHost // Hosts whose tags are edited
+	input      textinput.Model
+	selected   int // Tag chip selected for removal, -1 while typing
+	suggestion int // Next suggestion Tab completes to
+}
+
+// openTagEditor edits the tags of the marked hosts, or of the selected host
+func (m Model) openTagEditor() (tea.Model, tea.Cmd) {
+	input := textinput.New()
+	input.Prompt = "Tag: "
+	input.Placeholder = "type a tag, Tab to complete"
+	input.CharLimit = 64
+	input.Width = max(20, m.width-14)
+
+	m.tags = &tagEditor{
+		hosts:    m.markedOrSelectedHosts(),
+		input:    input,
+		selected: -1,
+	}
+	m.viewMode = ModeTagEditor
+	m.message = ""
+	m.messageType = ""
+	return m, m.tags.input.Focus()
+}
+
+// markedOrSelectedHosts returns the marked hosts, or the selected host when
+// none are marked
+func (m Model) markedOrSelectedHosts() []config.SSHHost {
+	var hosts []config.SSHHost
+	for _, host := range m.hosts {
+		if m.markedHosts[host.Name] {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 && len(m.filteredHosts) > 0 {
+		hosts = append(hosts, m.filteredHosts[m.cursor])
+	}
+	return hosts
+}
+
+// editedTags returns the tags carried by any of the edited hosts, in the
+// order they were first added
+func (m Model) editedTags() []string {
+	var tags []string
+	for _, host := range m.tags.hosts {
+		for _, tag := range m.metadata.Tags(host.Name) {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// tagCount returns how many of the edited hosts carry a tag
+func (m Model) tagCount(tag string) int {
+	count := 0
+	for _, host := range m.tags.hosts {
+		if m.metadata.HasTag(host.Name, tag) {
+			count++
+		}
+	}
+	return count
+}
+
+// tagSuggestions returns the existing tags starting with the typed text that
+// not every edited host has yet
+func (m Model) tagSuggestions() []string {
+	prefix := strings.ToLower(strings.TrimSpace(m.tags.input.Value()))
+
+	var suggestions []string
+	for _, tag := range m.metadata.AllTags() {
+		if !strings.HasPrefix(strings.ToLower(tag), prefix) {
+			continue
+		}
+		if m.tagCount(tag) < len(m.tags.hosts) {
+			suggestions = append(suggestions, tag)
+		}
+	}
+	return suggestions
+}
+
+// handleTagEditorMode handles keys in the tag editor
+func (m Model) handleTagEditorMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	editor := m.tags
+	if editor == nil {
+		m.viewMode = ModeList
+		return m, nil
+	}
+	tags := m.editedTags()
+	typing := editor.input.Value() != ""
+
+	switch msg.String() {
+	case "esc":
+		m.viewMode = ModeList
+		m.tags = nil
+		return m, nil
+
+	case "enter":
+		tag := config.NormalizeTag(editor.input.Value())
+		if tag == "" {
+			// Nothing typed, the editing is done
+			m.viewMode = ModeList
+			m.tags = nil
+			return m, nil
+		}
+		if strings.Contains(tag, ",") {
+			m.message = "Tags cannot contain ','"
+			m.messageType = "error"
+			return m, nil
+		}
+		for _, host := range editor.hosts {
+			m.metadata.AddTag(host.Name, tag)
+		}
+		editor.input.SetValue("")
+		editor.suggestion = 0
+		m.saveMetadata(fmt.Sprintf("Tagged %d host(s) with '%s'", len(editor.hosts), tag))
+		return m, nil
+
+	case "tab":
+		// Complete to the next matching tag
+		suggestions := m.tagSuggestions()
+		if len(suggestions) > 0 {
+			if editor.suggestion >= len(suggestions) {
+				editor.suggestion = 0
+			}
+			editor.input.SetValue(suggestions[editor.suggestion])
+			editor.input.CursorEnd()
+			editor.suggestion++
+		}
+		return m, nil
+
+	case "left":
+		if !typing && len(tags) > 0 {
+			if editor.selected < 0 {
+				editor.selected = len(tags) - 1
+			} else if editor.selected > 0 {
+				editor.selected--
+			}
+			return m, nil
+		}
+
+	case "right":
+		if !typing && editor.selected >= 0 {
+			editor.selected++
+			if editor.selected >= len(tags) {
+				editor.selected = -1
+			}
+			return m, nil
+		}
+
+	case "backspace", "delete":
+		if !typing && len(tags) > 0 {
+			if editor.selected < 0 {
+				// The first press selects the last tag, the next one removes it
+				editor.selected = len(tags) - 1
+				return m, nil
+			}
+			tag := tags[min(editor.selected, len(tags)-1)]
+			for _, host := range editor.hosts {
+				m.metadata.RemoveTag(host.Name, tag)
+			}
+			editor.selected = min(editor.selected, len(tags)-2)
+			m.saveMetadata(fmt.Sprintf("Removed '%s' from %d host(s)", tag, len(editor.hosts)))
+			return m, nil
+		}
+	}
+
+	editor.selected = -1
+	editor.suggestion = 0
+	var cmd tea.Cmd
+	editor.input, cmd = editor.input.Update(msg)
+	return m, cmd
+}
+
+// saveMetadata writes the host metadata and reports success or failure
+func (m *Model) saveMetadata(success string) {
+	if err := m.metadata.Save(); err != nil {
+		m.message = fmt.Sprintf("Failed to save host metadata: %v", err)
+		m.messageType = "error"
+		return
+	}
+	m.message = success
+	m.messageType = "success"
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tagColors are the chip backgrounds; each tag always gets the same one
+var tagColors = []lipgloss.Color{
+	"#5F87D7", "#D7875F", "#5FAF5F", "#AF5FAF", "#5FAFAF", "#D75F87", "#87875F", "#875FD7",
+}
+
+// tagColor picks the chip color of a tag from its name
+func tagColor(tag string) lipgloss.Color {
+	h := fnv.New32a()
+	h.Write([]byte(tag))
+	return tagColors[h.Sum32()%uint32(len(tagColors))]
+}
+
+// renderTagChip renders a tag as a colored chip
+func (m Model) renderTagChip(tag string) string {
+	return lipgloss.NewStyle().
+		Foreground(m.theme.HeaderText).
+		Background(tagColor(tag)).
+		Padding(0, 1).
+		Render(tag)
+}
+
+// renderTagChips renders tags as chips separated by spaces
+func (m Model) renderTagChips(tags []string) string {
+	chips := make([]string, len(tags))
+	for i, tag := range tags {
+		chips[i] = m.renderTagChip(tag)
+	}
+	return strings.Join(chips, " ")
+}
+
+// renderHostDetail renders the selected host with its tags below the host
+// list
+func (m Model) renderHostDetail() string {
+	if len(m.filteredHosts) == 0 {
+		return ""
+	}
+	host := m.filteredHosts[m.cursor]
+
+	nameStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Primary).
+		Bold(true)
+	if label, ok := m.hostLabelColor(host.Name); ok {
+		nameStyle = nameStyle.Foreground(label)
+	}
+	infoStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	target := host.Host
+	if host.User != "" {
+		target = host.User + "@" + target
+	}
+	if host.Port != "" && host.Port != "22" {
+		target += ":" + host.Port
+	}
+
+	detail := " " + nameStyle.Render(host.Name) + "  " + infoStyle.Render(target)
+	if tags := m.metadata.Tags(host.Name); len(tags) > 0 {
+		detail += "  " + m.renderTagChips(tags)
+	}
+	return detail
+}
+
+// renderTagEditorView renders the tag editor
+func (m Model) renderTagEditorView() string {
+	var content strings.Builder
+	editor := m.tags
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Edit Tags")
+	content.WriteString(header + "\n\n")
+
+	panelStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(m.width - 4)
+	subtleStyle := lipgloss.NewStyle().
+		Foreground(m.theme.Subtle)
+
+	var panel strings.Builder
+
+	// Hosts being edited
+	names := make([]string, len(editor.hosts))
+	for i, host := range editor.hosts {
+		names[i] = host.Name
+	}
+	panel.WriteString("Hosts: " + padAndTruncate(strings.Join(names, ", "), max(10, m.width-15)) + "\n\n")
+
+	// Current tags; a tag only some of the hosts have shows how many
+	tags := m.editedTags()
+	if len(tags) == 0 {
+		panel.WriteString(subtleStyle.Italic(true).Render("No tags yet") + "\n")
+	} else {
+		chips := make([]string, len(tags))
+		for i, tag := range tags {
+			chip := m.renderTagChip(tag)
+			if count := m.tagCount(tag); count < len(editor.hosts) {
+				chip += subtleStyle.Render(fmt.Sprintf(" %d/%d", count, len(editor.hosts)))
+			}
+			if i == editor.selected {
+				chip = lipgloss.NewStyle().Underline(true).Bold(true).Render("▶") + chip
+			}
+			chips[i] = chip
+		}
+		panel.WriteString(strings.Join(chips, "  ") + "\n")
+	}
+
+	panel.WriteString("\n" + editor.input.View())
+
+	// Existing tags matching what is typed
+	if suggestions := m.tagSuggestions(); len(suggestions) > 0 {
+		panel.WriteString("\n" + subtleStyle.Render("Existing: "+strings.Join(suggestions, " ")))
+	}
+
+	content.WriteString(panelStyle.Render(panel.String()) + "\n")
+
+	// Message
+	content.WriteString(m.renderToasts())
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Enter: add tag • Tab: complete • ←/→: select tag • Backspace: remove tag • ESC: done"
+	content.WriteString(helpStyle.Render(help))
+
+	return content.String()
+}
@@ -0,0 +1,209 @@
+package ui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
+)
+
+// Theme holds the colors used by every view
+type Theme struct {
+	Name              string
+	Primary           lipgloss.Color // Headers, borders and selection background
+	HeaderText        lipgloss.Color // Text on top of Primary
+	Accent            lipgloss.Color // Active form fields and destructive actions
+	Muted             lipgloss.Color // Help lines
+	Subtle            lipgloss.Color // Placeholders and secondary text
+	Success           lipgloss.Color
+	Error             lipgloss.Color
+	Warning           lipgloss.Color
+	OverlayBackground lipgloss.Color // Background of the help overlay
+	Monochrome        bool           // No colors: headers and selection are drawn in reverse video
+	ASCII             bool           // Symbols and borders are replaced by ASCII characters
+}
+
+// builtinThemes are the themes selectable by name in the xssh config file
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Name:              "dark",
+		Primary:           lipgloss.Color("#7D56F4"),
+		HeaderText:        lipgloss.Color("#FAFAFA"),
+		Accent:            lipgloss.Color("#FF6B6B"),
+		Muted:             lipgloss.Color("#626262"),
+		Subtle:            lipgloss.Color("#999999"),
+		Success:           lipgloss.Color("#00FF00"),
+		Error:             lipgloss.Color("#FF0000"),
+		Warning:           lipgloss.Color("#FFFF00"),
+		OverlayBackground: lipgloss.Color("#1a1a1a"),
+	},
+	"light": {
+		Name:              "light",
+		Primary:           lipgloss.Color("#5A3FC0"),
+		HeaderText:        lipgloss.Color("#FFFFFF"),
+		Accent:            lipgloss.Color("#D7005F"),
+		Muted:             lipgloss.Color("#808080"),
+		Subtle:            lipgloss.Color("#6C6C6C"),
+		Success:           lipgloss.Color("#008700"),
+		Error:             lipgloss.Color("#D70000"),
+		Warning:           lipgloss.Color("#AF8700"),
+		OverlayBackground: lipgloss.Color("#EEEEEE"),
+	},
+	"solarized": {
+		Name:              "solarized",
+		Primary:           lipgloss.Color("#268BD2"),
+		HeaderText:        lipgloss.Color("#FDF6E3"),
+		Accent:            lipgloss.Color("#CB4B16"),
+		Muted:             lipgloss.Color("#586E75"),
+		Subtle:            lipgloss.Color("#657B83"),
+		Success:           lipgloss.Color("#859900"),
+		Error:             lipgloss.Color("#DC322F"),
+		Warning:           lipgloss.Color("#B58900"),
+		OverlayBackground: lipgloss.Color("#002B36"),
+	},
+	"high-contrast": {
+		Name:              "high-contrast",
+		Primary:           lipgloss.Color("#FFFFFF"),
+		HeaderText:        lipgloss.Color("#000000"),
+		Accent:            lipgloss.Color("#FFFF00"),
+		Muted:             lipgloss.Color("#FFFFFF"),
+		Subtle:            lipgloss.Color("#D0D0D0"),
+		Success:           lipgloss.Color("#00FF00"),
+		Error:             lipgloss.Color("#FF5F5F"),
+		Warning:           lipgloss.Color("#FFFF00"),
+		OverlayBackground: lipgloss.Color("#000000"),
+	},
+	"mono": {
+		Name:       "mono",
+		Monochrome: true,
+	},
+}
+
+// asciiGlyphs replaces the symbols and box drawing characters of the views
+// with ASCII fallbacks for screen readers and limited terminals
+var asciiGlyphs = strings.NewReplacer(
+	"▶", ">", "█", "_", "●", "*", "•", "|", "·", "-",
+	"↑", "^", "↓", "v", "←", "<", "→", ">",
+	"✓", "+", "✗", "x", "⏳", "~", "⚠", "!", "📝", "*", "⌫", "<",
+	"░", ".", "▁", "_", "▂", "_", "▃", "-", "▄", "-", "▅", "=", "▆", "=", "▇", "#",
+	"─", "-", "━", "-", "│", "|", "┃", "|",
+	"╭", "+", "╮", "+", "╰", "+", "╯", "+",
+	"┌", "+", "┐", "+", "└", "+", "┘", "+",
+	"├", "+", "┤", "+", "┬", "+", "┴", "+", "┼", "+",
+)
+
+// spinner returns the spinner of progress indicators
+func (t Theme) spinner() spinner.Spinner {
+	if t.ASCII {
+		return spinner.Line
+	}
+	return spinner.Dot
+}
+
+// Glyphs returns s with its symbols replaced by ASCII in ASCII mode
+func (t Theme) Glyphs(s string) string {
+	if !t.ASCII {
+		return s
+	}
+	return asciiGlyphs.Replace(s)
+}
+
+// ResolveTheme builds the theme selected by the app config, applying any
+// color overrides on top of the named built-in theme. Unknown theme names
+// fall back to "dark". NO_COLOR (https://no-color.org) selects "mono"
+// whatever the config says, and a dumb terminal gets ASCII glyphs.
+func ResolveTheme(cfg config.ThemeConfig) Theme {
+	theme, ok := builtinThemes[strings.ToLower(cfg.Name)]
+	if !ok {
+		theme = builtinThemes["dark"]
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		theme = builtinThemes["mono"]
+	}
+	theme.ASCII = cfg.ASCII || os.Getenv("TERM") == "dumb"
+	if theme.Monochrome {
+		return theme
+	}
+
+	for name, value := range cfg.Colors {
+		color := lipgloss.Color(value)
+		switch strings.ToLower(name) {
+		case "primary":
+			theme.Primary = color
+		case "header_text":
+			theme.HeaderText = color
+		case "accent":
+			theme.Accent = color
+		case "muted":
+			theme.Muted = color
+		case "subtle":
+			theme.Subtle = color
+		case "success":
+			theme.Success = color
+		case "error":
+			theme.Error = color
+		case "warning":
+			theme.Warning = color
+		case "overlay_background":
+			theme.OverlayBackground = color
+		}
+	}
+
+	return theme
+}
+
+// HeaderStyle returns the style of the title bar at the top of each view
+func (t Theme) HeaderStyle(width int) lipgloss.Style {
+	if t.Monochrome {
+		return lipgloss.NewStyle().
+			Bold(true).
+			Reverse(true).
+			Padding(0, 1).
+			Width(width)
+	}
+	return lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.HeaderText).
+		Background(t.Primary).
+		Padding(0, 1).
+		Width(width)
+}
+
+// SelectedStyle returns the style of the highlighted row in a list
+func (t Theme) SelectedStyle() lipgloss.Style {
+	if t.Monochrome {
+		return lipgloss.NewStyle().
+			Reverse(true).
+			Bold(true)
+	}
+	return lipgloss.NewStyle().
+		Foreground(t.HeaderText).
+		Background(t.Primary).
+		Bold(true)
+}
+
+// HelpStyle returns the style of the key hint line at the bottom of each view
+func (t Theme) HelpStyle(width int) lipgloss.Style {
+	return lipgloss.NewStyle().
+		Foreground(t.Muted).
+		Width(width)
+}
+
+// MessageStyle returns the style of a status message of the given type
+// ("success", "error" or "info")
+func (t Theme) MessageStyle(messageType string, width int) lipgloss.Style {
+	style := lipgloss.NewStyle().
+		Width(width).
+		Align(lipgloss.Center)
+
+	switch messageType {
+	case "success":
+		return style.Foreground(t.Success)
+	case "error":
+		return style.Foreground(t.Error)
+	default:
+		return style.Foreground(t.Warning)
+	}
+}
@@ -0,0 +1,56 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/tmux"
+)
+
+// tmuxOpenedMsg reports the hosts opened in tmux
+type tmuxOpenedMsg struct {
+	count int
+	err   error
+}
+
+// openInTmux opens the marked hosts, or the selected host, in new tmux
+// windows or in a tiled window of the tmux session xssh runs in
+func (m Model) openInTmux(layout tmux.Layout) (tea.Model, tea.Cmd) {
+	if !tmux.Inside() {
+		m.message = "Not running inside tmux, use 'xssh tmux <alias...>' to start a session"
+		m.messageType = "error"
+		return m, nil
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		m.message = fmt.Sprintf("Cannot find the xssh binary: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	var targets []tmux.Target
+	for _, host := range m.markedOrSelectedHosts() {
+		targets = append(targets, tmux.Target{
+			Name:    host.Name,
+			Command: []string{executable, "connect", host.Name},
+		})
+	}
+	m.markedHosts = nil
+	return m, func() tea.Msg {
+		_, err := tmux.Open(targets, tmux.Options{Layout: layout})
+		return tmuxOpenedMsg{count: len(targets), err: err}
+	}
+}
+
+// handleTmuxOpened reports the outcome of openInTmux
+func (m Model) handleTmuxOpened(msg tmuxOpenedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.message = fmt.Sprintf("Failed to open in tmux: %v", msg.err)
+		m.messageType = "error"
+		return m, nil
+	}
+	m.message = fmt.Sprintf("Opened %d host(s) in tmux", msg.count)
+	m.messageType = "success"
+	return m, nil
+}
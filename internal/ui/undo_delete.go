@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbletea"
+	"xssh/internal/config"
+)
+
+// undoGracePeriod is how long a deleted host can be restored with "u"
+const undoGracePeriod = 10 * time.Second
+
+// undoHint ends the status message shown after a deletion
+const undoHint = "press u to undo"
+
+// deletedHost remembers the last deleted host so the deletion can be undone
+type deletedHost struct {
+	id    int
+	host  config.SSHHost
+	index int // Position in the config before deletion
+}
+
+// undoExpiredMsg ends the undo grace period of one deletion
+type undoExpiredMsg struct {
+	id int
+}
+
+// rememberDeletedHost keeps a deleted host for undo and starts its grace
+// period
+func (m *Model) rememberDeletedHost(host config.SSHHost, index int) tea.Cmd {
+	// Only the last deletion can be undone
+	m.forgetDeletedHost()
+	m.deleteSeq++
+	id := m.deleteSeq
+	m.lastDeleted = &deletedHost{id: id, host: host, index: index}
+
+	return tea.Tick(undoGracePeriod, func(time.Time) tea.Msg {
+		return undoExpiredMsg{id: id}
+	})
+}
+
+// handleUndoExpired forgets the deleted host once its grace period is over
+func (m Model) handleUndoExpired(msg undoExpiredMsg) (tea.Model, tea.Cmd) {
+	if m.lastDeleted == nil || m.lastDeleted.id != msg.id {
+		// A newer deletion is pending or it was already undone
+		return m, nil
+	}
+	m.forgetDeletedHost()
+	m.notifications.dismiss(func(n notification) bool {
+		return strings.HasSuffix(n.text, undoHint)
+	})
+	return m, nil
+}
+
+// forgetDeletedHost ends the undo of the last deletion and removes the
+// host's metadata, so a host added later under its alias does not inherit
+// its tags, color, hooks or forwardings
+func (m *Model) forgetDeletedHost() {
+	deleted := m.lastDeleted
+	if deleted == nil {
+		return
+	}
+	m.lastDeleted = nil
+	m.metadata.RemoveHost(deleted.host.Name)
+	if err := m.metadata.Save(); err != nil {
+		m.message = fmt.Sprintf("Failed to save host metadata: %v", err)
+		m.messageType = "error"
+	}
+}
+
+// reuseAlias forgets the last deletion when a host takes its alias, which
+// also rules out undoing it
+func (m *Model) reuseAlias(name string) {
+	if m.lastDeleted != nil && m.lastDeleted.host.Name == name {
+		m.forgetDeletedHost()
+	}
+}
+
+// undoDelete restores the last deleted host to its original position
+func (m Model) undoDelete() (tea.Model, tea.Cmd) {
+	deleted := m.lastDeleted
+	if deleted == nil {
+		return m, nil
+	}
+
+	if m.findHostIndex(deleted.host.Name) >= 0 {
+		m.message = fmt.Sprintf("Cannot restore '%s': a host with that name exists", deleted.host.Name)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.sshConfig.InsertHost(deleted.index, deleted.host)
+	if err := m.sshConfig.Save(); err != nil {
+		m.sshConfig.RemoveHost(deleted.host.Name)
+		m.message = fmt.Sprintf("Failed to save config: %v", err)
+		m.messageType = "error"
+		return m, nil
+	}
+
+	m.lastDeleted = nil
+	m.hosts = m.sshConfig.Hosts
+	m.filterHosts()
+	for i, host := range m.filteredHosts {
+		if host.Name == deleted.host.Name {
+			m.cursor = i
+			break
+		}
+	}
+
+	m.message = fmt.Sprintf("Host '%s' restored", deleted.host.Name)
+	m.messageType = "success"
+	return m, nil
+}
@@ -6,80 +6,31 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"xssh/internal/config"
 )
 
 // renderFormView renders the Add/Edit form
 func (m Model) renderFormView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	title := "Add New Host"
 	if m.viewMode == ModeEdit {
 		title = "Edit Host"
 	}
 	header := headerStyle.Render(title)
 	content.WriteString(header + "\n\n")
-	
+
 	// Form fields
-	fieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(40)
-	
-	activeFieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
-		Padding(0, 1).
-		Width(40).
-		Bold(true)
-	
-	// Host field
-	hostValue := m.formData.Host
-	if m.currentField == FieldHost {
-		hostValue += "█"
-	}
-	hostField := "Host Address: "
-	if m.currentField == FieldHost {
-		hostField = activeFieldStyle.Render(hostField + hostValue)
-	} else {
-		hostField = fieldStyle.Render(hostField + hostValue)
-	}
-	content.WriteString(hostField + "\n\n")
-	
-	// User field
-	userValue := m.formData.User
-	if m.currentField == FieldUser {
-		userValue += "█"
-	}
-	userField := "Username: "
-	if m.currentField == FieldUser {
-		userField = activeFieldStyle.Render(userField + userValue)
-	} else {
-		userField = fieldStyle.Render(userField + userValue)
-	}
-	content.WriteString(userField + "\n\n")
-	
-	// Port field
-	portValue := m.formData.Port
-	if m.currentField == FieldPort {
-		portValue += "█"
-	}
-	portField := "Port: "
-	if m.currentField == FieldPort {
-		portField = activeFieldStyle.Render(portField + portValue)
-	} else {
-		portField = fieldStyle.Render(portField + portValue)
-	}
-	content.WriteString(portField + "\n\n")
-	
+	fieldStyle, activeFieldStyle := m.formFieldStyles()
+
+	// Host, user and port fields
+	content.WriteString(m.renderInputField("Host Address: ", FieldHost, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Username: ", FieldUser, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Port: ", FieldPort, "", fieldStyle, activeFieldStyle) + "\n\n")
+
 	// Show authentication info
 	authInfo := "Authentication: "
 	if m.formData.AuthType == AuthKey && m.formData.Identity != "" {
@@ -87,331 +38,362 @@ func (m Model) renderFormView() string {
 	} else {
 		authInfo += "Password"
 	}
-	content.WriteString(fieldStyle.Render(authInfo) + "\n\n")
-	
-	// Alias field
-	aliasValue := m.formData.Alias
-	if m.currentField == FieldAlias {
-		aliasValue += "█"
+	content.WriteString(fieldStyle.Render(authInfo) + "\n")
+	if m.formData.AuthType == AuthKey {
+		if err := config.ValidateIdentityFile(m.formData.Identity); err != nil {
+			errorStyle := lipgloss.NewStyle().
+				Foreground(m.theme.Error).
+				MarginLeft(2)
+			content.WriteString(errorStyle.Render(err.Error()) + "\n")
+		}
 	}
-	aliasField := "Alias: "
-	if m.currentField == FieldAlias {
-		aliasField = activeFieldStyle.Render(aliasField + aliasValue)
-	} else {
-		aliasField = fieldStyle.Render(aliasField + aliasValue)
+
+	// Jump host chosen in the jump host step
+	if m.formData.ProxyJump != "" {
+		content.WriteString(fieldStyle.Render("Via jump host: "+m.formData.ProxyJump) + "\n")
 	}
-	content.WriteString(aliasField + "\n\n")
-	
+	content.WriteString("\n")
+
+	// Alias field
+	content.WriteString(m.renderInputField("Alias: ", FieldAlias, "", fieldStyle, activeFieldStyle) + "\n\n")
+
+	// Message
+	content.WriteString(m.renderToasts())
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	help := "Tab/↓: next field • Shift+Tab/↑: prev field • Enter: save • ESC: cancel"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderDeleteView renders the delete confirmation
 func (m Model) renderDeleteView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#FF6B6B")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width).
+		Background(m.theme.Accent)
+
 	header := headerStyle.Render("Delete Host")
 	content.WriteString(header + "\n\n")
-	
+
 	if len(m.filteredHosts) > 0 {
 		host := m.filteredHosts[m.cursor]
-		
+
 		warningStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF6B6B")).
+			Foreground(m.theme.Accent).
 			Bold(true).
 			Align(lipgloss.Center).
 			Width(m.width)
-		
+
 		warning := fmt.Sprintf("Are you sure you want to delete '%s'?", host.Name)
 		content.WriteString(warningStyle.Render(warning) + "\n\n")
-		
+
 		// Show host details
 		detailStyle := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#FF6B6B")).
+			BorderForeground(m.theme.Accent).
 			Padding(1, 2).
 			Width(m.width - 4)
-		
+
 		details := fmt.Sprintf("Host: %s\nUser: %s\nPort: %s", host.Host, host.User, host.Port)
 		if host.Identity != "" {
 			details += fmt.Sprintf("\nKey: %s", host.Identity)
 		}
-		
+
 		content.WriteString(detailStyle.Render(details) + "\n\n")
 	}
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width).
+	helpStyle := m.theme.HelpStyle(m.width).
 		Align(lipgloss.Center)
-	
+
 	help := "Y: confirm delete • N/ESC: cancel"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderAuthSelectView renders authentication type selection
 func (m Model) renderAuthSelectView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Select Authentication Method")
 	content.WriteString(header + "\n\n")
-	
+
 	// Options
 	optionStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
-		Width(40).
+		Width(m.formFieldWidth()).
 		Margin(1, 0)
-	
+
 	option1 := optionStyle.Render("1. Password Authentication")
 	option2 := optionStyle.Render("2. SSH Key Authentication")
-	
+
 	content.WriteString(option1 + "\n")
 	content.WriteString(option2 + "\n\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	help := "1: password • 2: SSH key • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderKeySelectView renders SSH key selection
 func (m Model) renderKeySelectView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Select SSH Key")
 	content.WriteString(header + "\n\n")
-	
+
 	// Key list
-	selectedStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Bold(true)
-	
+	selectedStyle := m.theme.SelectedStyle()
+
 	for i, keyFile := range m.keyFiles {
 		cursor := "  "
 		if m.keyCursor == i {
 			cursor = "▶ "
 		}
-		
+
 		keyName := filepath.Base(keyFile)
 		keyDisplay := fmt.Sprintf("%s%s", cursor, keyName)
-		
+
 		if m.keyCursor == i {
 			content.WriteString(selectedStyle.Render(keyDisplay) + "\n")
 		} else {
 			content.WriteString(keyDisplay + "\n")
 		}
 	}
-	
+
 	content.WriteString("\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
-	help := "↑/k: up • ↓/j: down • Enter: select • ESC: back"
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "↑/k: up • ↓/j: down • Enter: select • g: generate new key • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
+	return content.String()
+}
+
+// renderKeyGenView renders the SSH key generation wizard
+func (m Model) renderKeyGenView() string {
+	var content strings.Builder
+
+	// Header
+	headerStyle := m.theme.HeaderStyle(m.width)
+
+	header := headerStyle.Render("Generate SSH Key")
+	content.WriteString(header + "\n\n")
+
+	// Form fields
+	fieldStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Primary).
+		Padding(0, 1).
+		Width(50)
+
+	activeFieldStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(m.theme.Accent).
+		Padding(0, 1).
+		Width(50).
+		Bold(true)
+
+	// Key type selector
+	typeStyle := fieldStyle
+	if m.currentField == FieldKeyType {
+		typeStyle = activeFieldStyle
+	}
+	ed25519Option, rsaOption := "( ) ed25519", "( ) RSA 4096"
+	if m.formData.KeyType == "rsa" {
+		rsaOption = "(•) RSA 4096"
+	} else {
+		ed25519Option = "(•) ed25519"
+	}
+	content.WriteString(typeStyle.Render("Key Type: "+ed25519Option+"  "+rsaOption) + "\n\n")
+
+	content.WriteString(m.renderInputField("File: ~/.ssh/", FieldKeyFile, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Comment: ", FieldKeyComment, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Passphrase: ", FieldKeyPassphrase, "", fieldStyle, activeFieldStyle) + "\n\n")
+	content.WriteString(m.renderInputField("Confirm: ", FieldKeyPassphraseConfirm, "", fieldStyle, activeFieldStyle) + "\n\n")
+
+	// Progress or message
+	if m.keyGenRunning {
+		progressStyle := lipgloss.NewStyle().
+			Foreground(m.theme.Warning).
+			Width(m.width)
+		content.WriteString(progressStyle.Render("⏳ Generating key...") + "\n")
+	} else {
+		content.WriteString(m.renderToasts())
+	}
+
+	// Help
+	helpStyle := m.theme.HelpStyle(m.width)
+
+	help := "Tab/↓: next field • Shift+Tab/↑: prev field • Enter: generate • ESC: back"
+	if m.currentField == FieldKeyType {
+		help = "←/→/Space: change key type • " + help
+	}
+	content.WriteString(helpStyle.Render(help))
+
 	return content.String()
 }
 
 // renderPasswordInputView renders password input form
 func (m Model) renderPasswordInputView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Enter Password")
 	content.WriteString(header + "\n\n")
-	
+
 	// Form info
 	infoStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
 		Width(m.width - 4)
-	
-	info := fmt.Sprintf("Host: %s\nUser: %s\nPort: %s", 
+
+	info := fmt.Sprintf("Host: %s\nUser: %s\nPort: %s",
 		m.formData.Host, m.formData.User, m.formData.Port)
 	content.WriteString(infoStyle.Render(info) + "\n\n")
-	
+
 	// Password field
-	fieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
-		Padding(0, 1).
-		Width(40).
-		Bold(true)
-	
-	// Show asterisks for password
-	passwordDisplay := strings.Repeat("*", len(m.formData.Password)) + "█"
-	passwordField := fieldStyle.Render("Password: " + passwordDisplay)
+	_, fieldStyle := m.formFieldStyles()
+
+	// Password input echoes asterisks
+	passwordField := m.renderInputField("Password: ", FieldPassword, "", fieldStyle, fieldStyle)
 	content.WriteString(passwordField + "\n\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	help := "Type password • Enter: test connection • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderKeyPasswordInputView renders SSH private key password input form
 func (m Model) renderKeyPasswordInputView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Enter SSH Key Password")
 	content.WriteString(header + "\n\n")
-	
+
 	// Form info
 	infoStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
 		Width(m.width - 4)
-	
-	info := fmt.Sprintf("SSH Key: %s\nHost: %s\nUser: %s\nPort: %s", 
+
+	info := fmt.Sprintf("SSH Key: %s\nHost: %s\nUser: %s\nPort: %s",
 		filepath.Base(m.formData.Identity), m.formData.Host, m.formData.User, m.formData.Port)
 	content.WriteString(infoStyle.Render(info) + "\n\n")
-	
+
 	// Password field
-	fieldStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#FF6B6B")).
-		Padding(0, 1).
-		Width(40).
-		Bold(true)
-	
-	// Show asterisks for password
-	passwordDisplay := strings.Repeat("*", len(m.formData.KeyPassword)) + "█"
-	passwordField := fieldStyle.Render("Key Password: " + passwordDisplay)
+	_, fieldStyle := m.formFieldStyles()
+
+	// Password input echoes asterisks
+	passwordField := m.renderInputField("Key Password: ", FieldKeyPassword, "", fieldStyle, fieldStyle)
 	content.WriteString(passwordField + "\n\n")
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	help := "Type password • Enter: continue • ESC: back"
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
 }
 
 // renderConnectTestView renders connection test and setup progress
 func (m Model) renderConnectTestView() string {
 	var content strings.Builder
-	
+
 	// Header
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#FAFAFA")).
-		Background(lipgloss.Color("#7D56F4")).
-		Padding(0, 1).
-		Width(m.width)
-	
+	headerStyle := m.theme.HeaderStyle(m.width)
+
 	header := headerStyle.Render("Setting up SSH Connection")
 	content.WriteString(header + "\n\n")
-	
+
 	// Host info
 	infoStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#7D56F4")).
+		BorderForeground(m.theme.Primary).
 		Padding(1, 2).
 		Width(m.width - 4)
-	
-	info := fmt.Sprintf("Host: %s\nUser: %s\nPort: %s\nAuth: %s", 
+
+	info := fmt.Sprintf("Host: %s\nUser: %s\nPort: %s\nAuth: %s",
 		m.formData.Host, m.formData.User, m.formData.Port,
 		map[AuthType]string{AuthPassword: "Password", AuthKey: "SSH Key"}[m.formData.AuthType])
+	if m.formData.ProxyJump != "" {
+		info += "\nVia: " + m.formData.ProxyJump
+	}
 	content.WriteString(infoStyle.Render(info) + "\n\n")
-	
+
 	// Progress
 	progressStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#00FF00")).
+		BorderForeground(m.theme.Success).
 		Padding(1, 2).
 		Width(m.width - 4).
 		Align(lipgloss.Center)
-	
+
 	if m.isSetupDone {
-		progressStyle = progressStyle.BorderForeground(lipgloss.Color("#00FF00"))
+		progressStyle = progressStyle.BorderForeground(m.theme.Success)
 		content.WriteString(progressStyle.Render("✓ Setup completed successfully!") + "\n\n")
 	} else {
-		progressStyle = progressStyle.BorderForeground(lipgloss.Color("#FFFF00"))
-		content.WriteString(progressStyle.Render("⏳ " + m.setupProgress) + "\n\n")
+		progressStyle = progressStyle.BorderForeground(m.theme.Warning)
+		if m.connTest != nil && m.connTest.failed {
+			progressStyle = progressStyle.BorderForeground(m.theme.Error)
+		}
+		content.WriteString(progressStyle.Render("⏳ "+m.setupProgress) + "\n\n")
+	}
+
+	// Steps
+	if m.connTest != nil {
+		content.WriteString(m.renderConnectionSteps() + "\n\n")
 	}
-	
+
 	// Help
-	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Width(m.width)
-	
+	helpStyle := m.theme.HelpStyle(m.width)
+
 	var help string
 	if m.isSetupDone {
 		help = "Enter: save and continue • ESC: cancel"
+	} else if m.hostKeyMismatch {
+		help = "k: review known_hosts entries for this host • e: error details • ESC: cancel"
 	} else {
-		help = "Please wait... • ESC: cancel"
+		help = "Please wait... • ESC: cancel test"
+		if m.connTest != nil && m.connTest.failed {
+			help = "e: error details • ESC: back to form"
+		} else if m.connTest != nil && !m.connTest.running() {
+			help = "ESC: back to form"
+		}
 	}
 	content.WriteString(helpStyle.Render(help))
-	
+
 	return content.String()
-}
\ No newline at end of file
+}
@@ -0,0 +1,210 @@
+// Package update replaces the running xssh binary with the latest GitHub
+// release, for "xssh self-update".
+package update
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// releasesURL is the GitHub API endpoint of the latest release
+var releasesURL = "https://api.github.com/repos/xieisabug/xssh/releases/latest"
+
+// checksumsAsset is the release file listing the SHA-256 of every binary,
+// in the format of sha256sum
+const checksumsAsset = "checksums.txt"
+
+// Release is a published release of xssh
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	URL    string  `json:"html_url"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// AssetName returns the name of the release binary for this platform,
+// e.g. xssh_linux_amd64
+func AssetName() string {
+	name := fmt.Sprintf("xssh_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Latest fetches the latest release
+func Latest(ctx context.Context) (*Release, error) {
+	body, err := get(ctx, releasesURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for releases: %w", err)
+	}
+	defer body.Close()
+
+	var release Release
+	if err := json.NewDecoder(body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release information: %v", err)
+	}
+	return &release, nil
+}
+
+// asset returns the asset called name
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, asset := range r.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Newer reports whether the release is newer than version. Versions are
+// compared as vMAJOR.MINOR.PATCH; anything else is never older.
+func (r *Release) Newer(version string) bool {
+	latest, ok := parseVersion(r.Tag)
+	if !ok {
+		return false
+	}
+	current, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	for i := range latest {
+		if latest[i] != current[i] {
+			return latest[i] > current[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses v1.2.3, ignoring a pre-release or build suffix
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// Install downloads the binary of the release for this platform, checks it
+// against the release checksums and replaces executable with it. The new
+// binary is written next to executable and renamed over it, so an
+// interrupted update leaves the old binary in place.
+func Install(ctx context.Context, release *Release, executable string) error {
+	name := AssetName()
+	binary, ok := release.asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	checksums, ok := release.asset(checksumsAsset)
+	if !ok {
+		return fmt.Errorf("release %s has no %s to verify the download", release.Tag, checksumsAsset)
+	}
+	want, err := expectedChecksum(ctx, checksums.URL, name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(executable), ".xssh-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %v", executable, err)
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	slog.Info("downloading release", "tag", release.Tag, "url", binary.URL)
+	body, err := get(ctx, binary.URL)
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), body)
+	body.Close()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, want, got)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", executable, err)
+	}
+	slog.Info("updated xssh", "tag", release.Tag, "path", executable)
+	return nil
+}
+
+// expectedChecksum finds the SHA-256 of name in the checksums file at url
+func expectedChecksum(ctx context.Context, url, name string) (string, error) {
+	body, err := get(ctx, url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", checksumsAsset, err)
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		// sha256sum writes "<hash>  <name>", with "*" before the name in
+		// binary mode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", checksumsAsset, err)
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", checksumsAsset, name)
+}
+
+// get performs a GET request and returns the body of a successful response
+func get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "xssh-self-update")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
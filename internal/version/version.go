@@ -0,0 +1,61 @@
+// Package version holds the version of the xssh build. Release builds set
+// the variables with the linker:
+//
+//	go build -ldflags "-X xssh/internal/version.Version=v1.2.0 \
+//	  -X xssh/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X xssh/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with -ldflags "-X ..."
+var (
+	Version = "dev" // Release tag, e.g. v1.2.0
+	Commit  = ""    // Git commit the binary was built from
+	Date    = ""    // Build time, RFC 3339
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// Get returns the version of the running binary. A build without -ldflags
+// falls back to the commit and time Go records from the git checkout.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" && len(setting.Value) >= 7 {
+					info.Commit = setting.Value[:7]
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
+
+// IsRelease reports whether the binary was built for a release, as opposed
+// to a development build
+func IsRelease() bool {
+	return Version != "dev"
+}